@@ -1,25 +0,0 @@
-package config
-
-import (
-	"fmt"
-
-	"github.com/spf13/viper"
-)
-
-// NewViper is a function to load config from config.json
-// You can change the implementation, for example load from env file, consul, etcd, etc
-func NewViper() *viper.Viper {
-	config := viper.New()
-
-	config.SetConfigName("config")
-	config.SetConfigType("json")
-	config.AddConfigPath("./../")
-	config.AddConfigPath("./")
-	err := config.ReadInConfig()
-
-	if err != nil {
-		panic(fmt.Errorf("Fatal error config file: %w \n", err))
-	}
-
-	return config
-}
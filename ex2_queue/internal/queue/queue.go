@@ -1,23 +0,0 @@
-package queue
-
-// import (
-// 	"time"
-// )
-
-// // QueueProcessor defines the interface for queue processing
-// type QueueProcessor interface {
-// 	EnqueueItem(item interface{}) bool
-// 	Start()
-// 	Stop()
-// 	GetQueueSize() int
-// 	GetProcessingCount() int
-// }
-
-// // QueueMetrics tracks metrics for queue operations
-// type QueueMetrics struct {
-// 	EnqueueCount   int64
-// 	DequeueCount   int64
-// 	ProcessingTime time.Duration
-// 	WaitTime       time.Duration
-// 	MaxQueueLength int
-// }
@@ -1,136 +0,0 @@
-package scrape
-
-import (
-	"crawler/baseline/internal/utils"
-	"fmt"
-	"strings"
-
-	"github.com/gocolly/colly/v2"
-	"github.com/sirupsen/logrus"
-)
-
-type CommitScrape struct {
-	Log   *logrus.Logger
-	Colly *colly.Collector
-}
-
-func NewCommitScrape(log *logrus.Logger, colly *colly.Collector) *CommitScrape {
-	return &CommitScrape{
-		Log:   log,
-		Colly: colly,
-	}
-}
-
-func (s *CommitScrape) CrawlCommit(repoOwner string, repoName string, releaseTag string) []string {
-	log := s.Log
-
-	commits := s.tryBranch(repoOwner, repoName, releaseTag, "master", log)
-
-	if len(commits) == 0 {
-		log.Info("No commits found with master branch, trying main branch")
-		commits = s.tryBranch(repoOwner, repoName, releaseTag, "main", log)
-	}
-
-	log.Infof("Total unique commits found: %d", len(commits))
-	return commits
-}
-
-func (s *CommitScrape) tryBranch(repoOwner string, repoName string, releaseTag string, branchName string, log *logrus.Logger) []string {
-	c := s.Colly
-	releaseURL := "https://github.com/" + repoOwner + "/" + repoName + "/releases/tag/" + releaseTag
-	commitCount := utils.GetNumCommitRelease(releaseURL)
-
-	baseURL := fmt.Sprintf("https://github.com/%s/%s/compare/commit-list?range=%s...%s",
-		repoOwner, repoName, releaseTag, branchName)
-
-	log.Infof("Trying to crawl commits with branch: %s", branchName)
-
-	c.OnResponse(func(r *colly.Response) {
-		log.Info("Received response with status: ", r.StatusCode)
-	})
-
-	c.OnRequest(func(req *colly.Request) {
-		// log.Info("Visiting: ", req.URL.String())
-	})
-
-	commitMap := make(map[string]string)
-
-	c.OnHTML("div.TimelineItem-body", func(e *colly.HTMLElement) {
-		commitHash := ""
-		commitMsg := ""
-
-		e.ForEach("p.mb-1 a.Link--primary", func(_ int, link *colly.HTMLElement) {
-			href := link.Attr("href")
-			if strings.Contains(href, "/commit/") {
-				parts := strings.Split(href, "/commit/")
-				if len(parts) > 1 {
-					commitHash = parts[1]
-					commitMsg = strings.TrimSpace(link.Text)
-
-					if commitHash != "" {
-						if existingMsg, ok := commitMap[commitHash]; ok {
-							commitMap[commitHash] = existingMsg + " | " + commitMsg
-							log.Infof("Updated commit %s with additional message: %s", commitHash, commitMsg)
-						} else {
-							commitMap[commitHash] = commitMsg
-							log.Infof("Found new commit: %s - %s", commitHash, commitMsg)
-						}
-					}
-				}
-			}
-		})
-	})
-
-	hasCommits := true
-	c.OnHTML("div.blankslate", func(e *colly.HTMLElement) {
-		if strings.Contains(e.Text, "There aren't any commits") {
-			hasCommits = false
-			log.Infof("No commits found with branch: %s", branchName)
-		}
-	})
-
-	c.OnHTML("div.Box-header", func(e *colly.HTMLElement) {
-		countText := e.ChildText("span.text-emphasized")
-		log.Info("Commit count info: ", countText)
-	})
-
-	c.OnHTML("div.js-navigation-container", func(e *colly.HTMLElement) {
-		log.Info("Found commit container with child count: ", len(e.DOM.Children().Nodes))
-	})
-
-	page := 1
-	maxPages := (commitCount + 49) / 50 // Each page has ~50 commits
-
-	err := c.Visit(baseURL)
-	if err != nil {
-		log.Errorf("Error visiting URL with branch %s: %v", branchName, err)
-		return []string{}
-	}
-
-	if !hasCommits {
-		return []string{}
-	}
-
-	for page < maxPages {
-		page++
-		commitURL := fmt.Sprintf("%s&page=%d", baseURL, page)
-
-		// log.Infof("Visiting page %d of %d", page, maxPages)
-		err := c.Visit(commitURL)
-		if err != nil {
-			log.Error("Error visiting commit URL: ", err)
-			break
-		}
-
-		log.Infof("Completed page %d", page)
-	}
-
-	commits := make([]string, 0, len(commitMap))
-	for hash, message := range commitMap {
-		commitInfo := fmt.Sprintf("Hash: %s - Message: %s", hash, message)
-		commits = append(commits, commitInfo)
-	}
-
-	log.Infof("Found %d commits with branch: %s", len(commits), branchName)
-	return commits
-}
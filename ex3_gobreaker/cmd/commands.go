@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crawler/baseline/internal/config"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newRootCommand creates the crawler command with all its subcommands. Without a command the
+// server runs in the mode of CRAWLER_MODE, serve by default, as a container entrypoint would.
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "crawler",
+		Short:        "Crawl GitHub repositories, releases and commits",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mode, err := config.ParseRunMode(os.Getenv("CRAWLER_MODE"))
+			if err != nil {
+				return err
+			}
+			runServer(mode, "", false)
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&config.ConfigFile, "config", "",
+		"config file, instead of the config.json of the working directory or its parent")
+
+	root.AddCommand(
+		newServeCommand(config.ModeServe, "Run the API, workers and scheduler in one process"),
+		newServeCommand(config.ModeAPI, "Run the HTTP API only, queueing onboarding jobs for the workers"),
+		newServeCommand(config.ModeWorker, "Run the job workers of the shared queue only"),
+		newServeCommand(config.ModeScheduler, "Run the coordinator, alerts and digests only"),
+		newServeCommand(config.ModeScrape, "Run the server, publishing the crawled batches to NATS"),
+		newServeCommand(config.ModePersist, "Store the batches scrape processes publish to NATS"),
+		newCrawlCommand(),
+		newReparseCommand(),
+		newMigrateCommand(),
+		newCompressReleasesCommand(),
+		newParseReleasesCommand(),
+		newGoldenCommand(),
+	)
+	return root
+}
+
+// newServeCommand runs the server in mode until it is interrupted
+func newServeCommand(mode config.RunMode, short string) *cobra.Command {
+	var experiment string
+	var embedded bool
+	serve := &cobra.Command{
+		Use:   string(mode),
+		Short: short,
+		Long: short + `.
+--mode switches on the features of one of the experiments, over those of the config files.
+--embedded keeps everything in a local SQLite file, so no other service is needed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runServer(mode, experiment, embedded)
+			return nil
+		},
+	}
+	serve.Flags().BoolVar(&embedded, "embedded", false, "use a local SQLite database, database.path or crawler.db, instead of PostgreSQL")
+	serve.Flags().StringVar(&experiment, "mode", "", "experiment to run: "+experimentNames()+", the features of the config files by default")
+	return serve
+}
+
+// crawlStages are the stages that crawl triggers, by argument
+var crawlStages = map[string]string{
+	"repos":    "/api/repos/crawl",
+	"releases": "/api/releases/crawl",
+	"commits":  "/api/commits/crawl",
+}
+
+func newCrawlCommand() *cobra.Command {
+	var addr, apiKey string
+	var timeout time.Duration
+	crawl := &cobra.Command{
+		Use:   "crawl repos|releases|commits",
+		Short: "Trigger a crawl stage on a running server",
+		Long: `Trigger a crawl stage on a running server and print its response. "crawler crawl repo"
+crawls a single repository without a server instead.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"repos", "releases", "commits"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, ok := crawlStages[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown stage %q, expected repos, releases or commits", args[0])
+			}
+			if strings.HasPrefix(addr, ":") {
+				addr = "localhost" + addr
+			}
+			if !strings.Contains(addr, "://") {
+				addr = "http://" + addr
+			}
+
+			request, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, addr+path, nil)
+			if err != nil {
+				return err
+			}
+			if apiKey != "" {
+				request.Header.Set("X-API-Key", apiKey)
+			}
+			response, err := (&http.Client{Timeout: timeout}).Do(request)
+			if err != nil {
+				return fmt.Errorf("triggering %s crawl: %w", args[0], err)
+			}
+			defer response.Body.Close()
+
+			if _, err := io.Copy(os.Stdout, response.Body); err != nil {
+				return err
+			}
+			if response.StatusCode >= 300 {
+				return fmt.Errorf("%s crawl failed with status %s", args[0], response.Status)
+			}
+			return nil
+		},
+	}
+	crawl.Flags().StringVar(&addr, "addr", ":8081", "address of the server")
+	crawl.Flags().StringVar(&apiKey, "api-key", os.Getenv("CRAWLER_API_KEY"), "API key sent to the server, from CRAWLER_API_KEY by default")
+	crawl.Flags().DurationVar(&timeout, "timeout", 0, "give up after this long; 0 waits for the crawl to end")
+	crawl.AddCommand(newCrawlRepoCommand())
+	return crawl
+}
+
+// addCrawlRepoFlags defines the flags shared by "crawl repo" and reparse
+func addCrawlRepoFlags(cmd *cobra.Command, options *crawlRepoOptions) {
+	cmd.Flags().BoolVar(&options.releases, "releases", false, "crawl the releases of the repository")
+	cmd.Flags().BoolVar(&options.commits, "commits", false, "crawl the commits of each release, implies --releases")
+	cmd.Flags().StringVar(&options.output, "output", crawlOutputJSON, "format of the results, json or text")
+	cmd.Flags().BoolVar(&options.store, "store", false, "save the results to the configured database")
+}
+
+func newCrawlRepoCommand() *cobra.Command {
+	var options crawlRepoOptions
+	repo := &cobra.Command{
+		Use:   "repo OWNER/NAME",
+		Short: "Crawl one repository without a server",
+		Long: `Crawl one repository with the scrapers of the server, without starting it, and print what
+was found on stdout. The repository is OWNER/NAME or its GitHub URL. With --store the results
+are also saved to the configured database, as an onboarding would; otherwise no database is
+needed, which suits batch jobs and cron.`,
+		Example: "  crawler crawl repo opencv/opencv --releases --commits --output=json",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			options.target = args[0]
+			crawlRepo(options)
+		},
+	}
+	addCrawlRepoFlags(repo, &options)
+	return repo
+}
+
+func newReparseCommand() *cobra.Command {
+	var options crawlRepoOptions
+	var at string
+	reparseCmd := &cobra.Command{
+		Use:   "reparse OWNER/NAME",
+		Short: "Crawl one repository again from the archived pages",
+		Long: `Crawl one repository as "crawl repo" does, but read its pages from the object storage of
+scrape.archive instead of GitHub, so the scrapers extract them again with the current selectors.
+--at picks the versions of the pages archived at or before an RFC 3339 time, the latest ones by
+default. With --store the results are saved to the configured database.`,
+		Example: "  crawler reparse opencv/opencv --releases --at=2026-01-01T00:00:00Z --store",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			options.target = args[0]
+			reparse(options, at)
+		},
+	}
+	reparseCmd.Flags().StringVar(&at, "at", "", "read the pages as archived at this RFC 3339 time, the latest version by default")
+	addCrawlRepoFlags(reparseCmd, &options)
+	return reparseCmd
+}
+
+func newMigrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Create the missing tables and columns of the configured database",
+		Long: `Create the missing tables and columns of the configured database from the entities, after
+merging the duplicates that would break its unique indexes. Every experiment shares the schema.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			migrate()
+		},
+	}
+}
+
+func newCompressReleasesCommand() *cobra.Command {
+	var batch int
+	compress := &cobra.Command{
+		Use:   "compress-releases",
+		Short: "Store the content of every release again as set by database.compression",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			compressReleases(batch)
+		},
+	}
+	compress.Flags().IntVar(&batch, "batch", 500, "releases read per query")
+	return compress
+}
+
+func newParseReleasesCommand() *cobra.Command {
+	var batch int
+	parse := &cobra.Command{
+		Use:   "parse-releases",
+		Short: "Parse the notes of every release again into their structured tables",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			parseReleases(batch)
+		},
+	}
+	parse.Flags().IntVar(&batch, "batch", 500, "releases parsed per transaction")
+	return parse
+}
+
+func newGoldenCommand() *cobra.Command {
+	var options goldenOptions
+	goldenCmd := &cobra.Command{
+		Use:   "golden",
+		Short: "Check the scrapers against the golden cases",
+		Long: `Check the scrapers against the saved GitHub pages of the golden cases, and exit with status 1
+when an output differs from the expected one.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkGoldens(options)
+		},
+	}
+	goldenCmd.Flags().StringVar(&options.dir, "dir", "testdata/golden", "directory of the golden cases")
+	goldenCmd.Flags().StringVar(&options.fixtures, "fixtures", "testdata/github", "directory of the saved GitHub pages")
+	goldenCmd.Flags().StringVar(&options.only, "case", "", "only run the case of this name")
+	goldenCmd.Flags().BoolVar(&options.update, "update", false, "save the current outputs as expected")
+	goldenCmd.Flags().BoolVar(&options.refresh, "refresh", false, "fetch the pages again from GitHub, then save the outputs as expected")
+	return goldenCmd
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/config"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Formats of the results of crawl repo
+const (
+	crawlOutputJSON = "json"
+	crawlOutputText = "text"
+)
+
+// crawledRepo is what crawl repo found for a repository
+type crawledRepo struct {
+	Repo          string            `json:"repo"`
+	DefaultBranch string            `json:"defaultBranch"`
+	Releases      []*crawledRelease `json:"releases,omitempty"`
+}
+
+type crawledRelease struct {
+	*model.CreateReleaseRequest
+	Commits []*model.CreateCommitRequest `json:"commits,omitempty"`
+}
+
+// crawlRepoStore saves the results of crawl repo through the usecases
+type crawlRepoStore struct {
+	repos    *usecase.RepoUsecase
+	releases *usecase.ReleaseUsecase
+	commits  *usecase.CommitUsecase
+}
+
+// crawlRepoOptions are the arguments and flags of "crawl repo" and reparse
+type crawlRepoOptions struct {
+	// target is OWNER/NAME or the GitHub URL of the repository
+	target   string
+	releases bool
+	commits  bool
+	output   string
+	store    bool
+}
+
+// crawlRepo runs "crawler crawl repo OWNER/NAME [--releases] [--commits] [--output json|text]
+// [--store]", which scrapes one repository without the HTTP server, prints what it found and,
+// with --store, saves it to the configured database, then exits
+func crawlRepo(options crawlRepoOptions) {
+	runCrawlRepo(options, config.NewViper)
+}
+
+// runCrawlRepo crawls the repository of options with the config loadConfig returns
+func runCrawlRepo(options crawlRepoOptions, loadConfig func() *viper.Viper) {
+	if options.output != crawlOutputJSON && options.output != crawlOutputText {
+		log.Fatalf("--output must be %s or %s", crawlOutputJSON, crawlOutputText)
+	}
+	owner, name, err := utils.ParseRepoURL(options.target)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	settings, err := config.NewConfig(loadConfig())
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// Logs go to stderr, which leaves stdout to the results
+	settings.Log.Output = config.LogOutputStderr
+	logConfig := config.NewLogger(settings.Log)
+
+	var saver *crawlRepoStore
+	var db *gorm.DB
+	if options.store {
+		saver = newCrawlRepoStore(settings, logConfig)
+		db = saver.repos.DB
+	} else {
+		// Visits and cached pages are kept in the database, which is not opened without --store
+		settings.Visits.Enabled = false
+		settings.Scrape.PageCache.Enabled = false
+	}
+	collector, _, pacer, _ := config.NewColly(settings, logConfig, db, nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := crawlOneRepo(ctx, settings, logConfig, collector, pacer, saver, owner, name,
+		options.releases || options.commits, options.commits)
+	if result != nil {
+		if err := writeCrawledRepo(os.Stdout, result, options.output); err != nil {
+			log.Fatalf("Writing results failed: %v", err)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Crawling %s/%s failed: %v", owner, name, err)
+	}
+}
+
+func newCrawlRepoStore(settings *config.Config, log *logrus.Logger) *crawlRepoStore {
+	db := config.NewDatabase(settings.Database, log)
+	// Validate already checked the ID settings
+	ids, _ := idgen.New(settings.Database.IDs.Strategy, settings.Database.IDs.Node)
+
+	releaseRepository := repository.NewReleaseRepository(log)
+	store := &crawlRepoStore{
+		repos:    usecase.NewRepoUsecase(db, log, repository.NewRepoRepository(log)),
+		releases: usecase.NewReleaseUsecase(db, log, releaseRepository, nil),
+		commits:  usecase.NewCommitUsecase(db, log, repository.NewCommitRepository(log)),
+	}
+	store.repos.IDs = ids
+	store.releases.IDs = ids
+	store.commits.IDs = ids
+	if settings.Notes.Enabled {
+		store.releases.Notes = usecase.NewReleaseNoteUsecase(db, log, repository.NewReleaseNoteRepository(log),
+			releaseRepository)
+	}
+	return store
+}
+
+// crawlOneRepo scrapes a repository and, as asked, its releases and their commits, within the
+// repo_policies entry of the repository. It returns what was found so far along with the error
+// that stopped the crawl.
+func crawlOneRepo(ctx context.Context, settings *config.Config, log *logrus.Logger, collector *colly.Collector,
+	pacer *scrape.RepoPacer, store *crawlRepoStore, owner string, name string, withReleases bool,
+	withCommits bool) (*crawledRepo, error) {
+	repoScrape := scrape.NewRepoScrape(log, collector)
+	repoScrape.Token = settings.GitHub.Token
+	githubRepo, err := repoScrape.LookupRepo(owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("looking up repository: %w", err)
+	}
+	result := &crawledRepo{
+		Repo:          githubRepo.UserName + "/" + githubRepo.RepoName,
+		DefaultBranch: githubRepo.DefaultBranch,
+	}
+
+	var repoID int64
+	if store != nil {
+		repoEntity, err := store.repos.Onboard(ctx, githubRepo, "default")
+		if err != nil && !errors.Is(err, apperrors.ErrConflict) {
+			return result, fmt.Errorf("saving repository: %w", err)
+		}
+		repoID = repoEntity.ID
+	}
+	if !withReleases {
+		return result, nil
+	}
+
+	policy, _ := service.NewRepoPolicies(settings.RepoPolicies).For(githubRepo.UserName, githubRepo.RepoName)
+	limits := scrape.RepoLimits{
+		MaxReleases:       policy.MaxReleases,
+		MaxCommits:        policy.MaxCommitsPerRelease,
+		RequestsPerMinute: policy.RequestsPerMinute,
+	}
+	releaseScrape := scrape.NewReleaseScrape(log, collector)
+	releaseScrape.Pacer = pacer
+	releaseScrape.Token = settings.GitHub.Token
+	releases, err := releaseScrape.CrawlReleases(ctx, githubRepo.UserName, githubRepo.RepoName, limits)
+	// The releases found are still saved and crawled, the error is returned with them
+	var listErr error
+	if errors.Is(err, scrape.ErrIncompleteListing) {
+		listErr, err = fmt.Errorf("crawling releases: %w", err), nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("crawling releases: %w", err)
+	}
+	requests := make([]*model.CreateReleaseRequest, 0, len(releases))
+	for tag, data := range releases {
+		requests = append(requests, &model.CreateReleaseRequest{
+			TagName:     tag,
+			Content:     data.Content,
+			Title:       data.Title,
+			PublishedAt: data.PublishedAt,
+			Author:      data.Author,
+			Prerelease:  data.Prerelease,
+			Assets:      data.Assets,
+			RepoID:      repoID,
+		})
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].TagName < requests[j].TagName })
+	for _, request := range requests {
+		result.Releases = append(result.Releases, &crawledRelease{CreateReleaseRequest: request})
+	}
+
+	releaseIDs := make(map[string]int64)
+	if store != nil {
+		responses, err := store.releases.BatchCreate(ctx, requests)
+		if err != nil {
+			return result, fmt.Errorf("saving releases: %w", err)
+		}
+		for _, response := range responses {
+			releaseIDs[response.TagName] = response.ID
+		}
+	}
+	if !withCommits {
+		return result, listErr
+	}
+	if policy.SkipCommits {
+		log.WithField("repo", result.Repo).Warn("The policy of the repository skips its commits")
+		return result, listErr
+	}
+
+	commitScrape := scrape.NewCommitScrape(log, collector)
+	commitScrape.FetchStats = settings.Scrape.CommitStats
+	commitScrape.Pacer = pacer
+	for _, release := range result.Releases {
+		releaseID := releaseIDs[release.TagName]
+		_, err := commitScrape.StreamCommits(ctx, githubRepo.UserName, githubRepo.RepoName, release.TagName,
+			githubRepo.DefaultBranch, limits, func(commitStrings []string) error {
+				commits := parseCommits(ctx, log, commitScrape, githubRepo, commitStrings, releaseID)
+				release.Commits = append(release.Commits, commits...)
+				if store == nil || releaseID == 0 {
+					return nil
+				}
+				_, err := store.commits.BatchCreate(ctx, commits)
+				return err
+			})
+		if errors.Is(err, scrape.ErrNotFound) {
+			log.WithField("release", release.TagName).Warn("Release is gone, skipping its commits")
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("crawling commits of release %s: %w", release.TagName, err)
+		}
+	}
+	return result, listErr
+}
+
+// parseCommits reads the "Hash: <hash> - Message: <message>" commits of the scraper and
+// attaches their stats when stats scraping is enabled
+func parseCommits(ctx context.Context, log *logrus.Logger, commitScrape *scrape.CommitScrape,
+	githubRepo *model.GitHubRepo, commitStrings []string, releaseID int64) []*model.CreateCommitRequest {
+	commits := make([]*model.CreateCommitRequest, 0, len(commitStrings))
+	for _, commitStr := range commitStrings {
+		hash, message, found := strings.Cut(commitStr, " - Message: ")
+		if !found {
+			log.WithField("commit_str", commitStr).Warn("Invalid commit string format")
+			continue
+		}
+		commit := &model.CreateCommitRequest{
+			Hash:      strings.TrimPrefix(hash, "Hash: "),
+			Message:   message,
+			ReleaseID: releaseID,
+		}
+		if commitScrape.StatsEnabled() && ctx.Err() == nil {
+			stats, err := commitScrape.CrawlCommitStats(ctx, githubRepo.UserName, githubRepo.RepoName, commit.Hash)
+			if err != nil {
+				log.WithError(err).WithField("hash", commit.Hash).Warn("Error fetching commit stats")
+			} else {
+				commit.FilesChanged = &stats.FilesChanged
+				commit.Additions = &stats.Additions
+				commit.Deletions = &stats.Deletions
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits
+}
+
+// writeCrawledRepo prints the results as indented JSON, or as one line per release and commit
+func writeCrawledRepo(w io.Writer, result *crawledRepo, output string) error {
+	if output == crawlOutputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	if _, err := fmt.Fprintf(w, "%s (default branch %s)\n", result.Repo, result.DefaultBranch); err != nil {
+		return err
+	}
+	for _, release := range result.Releases {
+		published := "-"
+		if release.PublishedAt != nil {
+			published = release.PublishedAt.Format("2006-01-02")
+		}
+		if _, err := fmt.Fprintf(w, "  %s\t%s\t%s\t%d commits\n", release.TagName, published, release.Title,
+			len(release.Commits)); err != nil {
+			return err
+		}
+		for _, commit := range release.Commits {
+			message, _, _ := strings.Cut(commit.Message, "\n")
+			if _, err := fmt.Fprintf(w, "    %s %s\n", commit.Hash, message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// experiment is the setup of one of the four experiments of the repository, all run by this
+// server with their features switched over its config files
+type experiment struct {
+	name string
+	// env sets the config of the experiment as CRAWLER_* environment variables, so a reload of
+	// the config files keeps it
+	env map[string]string
+}
+
+var experiments = []experiment{
+	// Scrapes one repository and release at a time and stores every row on its own
+	{name: "baseline", env: features(false, false, false, map[string]string{
+		"CRAWLER_CRAWL_REPO_CONCURRENCY":    "1",
+		"CRAWLER_CRAWL_RELEASE_CONCURRENCY": "1",
+		"CRAWLER_COLLY_PARALLELISM":         "1",
+	})},
+	{name: "batch", env: features(true, false, false, nil)},
+	{name: "queue", env: features(true, true, false, nil)},
+	{name: "breaker", env: features(true, false, true, nil)},
+}
+
+// features returns the environment setting the features of an experiment, along with extra
+func features(batching, queueing, circuitBreaking bool, extra map[string]string) map[string]string {
+	env := map[string]string{
+		"CRAWLER_FEATURES_BATCHING":         fmt.Sprint(batching),
+		"CRAWLER_FEATURES_QUEUEING":         fmt.Sprint(queueing),
+		"CRAWLER_FEATURES_CIRCUIT_BREAKING": fmt.Sprint(circuitBreaking),
+	}
+	for key, value := range extra {
+		env[key] = value
+	}
+	return env
+}
+
+// experimentNames lists the experiments, for help messages
+func experimentNames() string {
+	names := make([]string, len(experiments))
+	for i, e := range experiments {
+		names[i] = e.name
+	}
+	return strings.Join(names, "|")
+}
+
+// setExperiment sets the environment of the experiment named name; empty keeps the config files
+func setExperiment(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, e := range experiments {
+		if e.name != name {
+			continue
+		}
+		for key, value := range e.env {
+			if err := os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q, expected %s", name, experimentNames())
+}
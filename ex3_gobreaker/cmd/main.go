@@ -1,8 +1,15 @@
 package main
 
 import (
+	"crawler/baseline/internal/auth"
 	"crawler/baseline/internal/config"
+	"crawler/baseline/internal/golden"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/repository"
 	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/usecase"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,13 +17,66 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-func startCircuitBreakerCoordinator(baseURL string, interval int) {
+func startCircuitBreakerCoordinator(coordinator *service.CrawlingCoordinator, interval int, stopChan <-chan struct{}) {
 	log.Printf("Starting circuit breaker coordinator with interval: %d seconds", interval)
 
-	// Create coordinator with circuit breaker protection
-	coordinator := service.NewCrawlingCoordinator(baseURL)
+	// Initial crawl to populate caches, unless starting during a blackout window
+	if until, blackedOut := coordinator.InBlackout(time.Now()); blackedOut {
+		log.Printf("Blackout window until %s, skipping initial data crawl", until.Format(time.Kitchen))
+	} else {
+		log.Println("Running initial data crawl...")
+		coordinator.CrawlAll()
+	}
+
+	// Start periodic crawling
+	log.Printf("Starting periodic monitoring every %d seconds", interval)
+	coordinator.StartPeriodicCrawling(time.Duration(interval)*time.Second, stopChan)
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// runServer runs the server in mode until SIGINT or SIGTERM, with the features of experiment
+// over the config files when set. Embedded stores everything in a local SQLite database.
+func runServer(mode config.RunMode, experiment string, embedded bool) {
+	if err := setExperiment(experiment); err != nil {
+		log.Fatal(err)
+	}
+	viperConfig := config.NewViper()
+	if embedded {
+		// The coordinator and alerts already run in this process, only the database is external
+		viperConfig.Set("database.driver", "sqlite")
+		if !viperConfig.IsSet("database.path") {
+			viperConfig.Set("database.path", "crawler.db")
+		}
+		log.Printf("Embedded mode, using SQLite database %s", viperConfig.GetString("database.path"))
+	}
+	log.Printf("Running in %s mode", mode)
+
+	settings, err := config.NewConfig(viperConfig)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	logConfig := config.NewLogger(settings.Log)
+	dbConfig := config.NewDatabase(settings.Database, logConfig)
+	notifiers := notifier.NewNotifier(settings.Notifiers, settings.Webhooks, logConfig)
+	authKeys, err := auth.NewKeyStore(settings.Auth.Enabled, settings.Auth.Keys)
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
+	collyConfig, collyLimit, collyPacer, collyStats := config.NewColly(settings, logConfig, dbConfig, notifiers)
+	blackouts, err := service.NewBlackouts(settings.Scheduler.Blackouts, settings.Scheduler.Timezone)
+	if err != nil {
+		log.Fatalf("Invalid blackout configuration: %v", err)
+	}
 
 	// Setup signal handling for graceful shutdown
 	stopChan := make(chan struct{})
@@ -25,38 +85,324 @@ func startCircuitBreakerCoordinator(baseURL string, interval int) {
 
 	go func() {
 		<-sigChan
-		log.Println("Shutdown signal received for coordinator")
+		log.Println("Shutdown signal received")
 		close(stopChan)
 	}()
 
-	// Initial crawl to populate caches
-	log.Println("Running initial data crawl...")
-	coordinator.CrawlAll()
+	var coordinator *service.CrawlingCoordinator
+	var alerts *service.AlertEngine
+	var leader *service.LeaderElector
+	if mode.RunsScheduler() {
+		leader = newLeaderElector(settings.Scheduler, dbConfig)
+		coordinator, alerts = startScheduler(settings, notifiers, leader, blackouts)
+	}
 
-	// Start periodic crawling
-	log.Printf("Starting periodic monitoring every %d seconds", interval)
-	coordinator.StartPeriodicCrawling(time.Duration(interval)*time.Second, stopChan)
+	jobs := service.NewJobManager(notifiers)
+	jobs.SetBlackouts(blackouts)
+	r := config.Bootstrap(&config.BootstrapConfig{
+		DB:          dbConfig,
+		Log:         logConfig,
+		Config:      settings,
+		Colly:       collyConfig,
+		CollyLimit:  collyLimit,
+		CollyPacer:  collyPacer,
+		CollyStats:  collyStats,
+		Coordinator: coordinator,
+		Alerts:      alerts,
+		Jobs:        jobs,
+		Notifier:    notifiers,
+		Auth:        authKeys,
+		Mode:        mode,
+		Leader:      leader,
+		Blackouts:   blackouts,
+		Stop:        stopChan,
+	})
+	if leader != nil {
+		go leader.Run(stopChan)
+	}
+	if addr := settings.Coordinator.MetricsAddr; coordinator != nil && addr != "" {
+		go serveCoordinatorMetrics(addr, config.CoordinatorMetrics(logConfig, coordinator))
+	}
+
+	if mode.RunsWorkers() && jobs.HasStore() {
+		id := workerID(settings.Jobs.WorkerID)
+		// Jobs left running by a crashed instance would otherwise stay running forever
+		recovery, err := jobs.RecoverOrphans(context.Background(), id, settings.Jobs.MaxAttempts)
+		if err != nil {
+			log.Printf("Failed to recover orphaned jobs: %v", err)
+		} else if len(recovery.Requeued)+len(recovery.Failed) > 0 {
+			log.Printf("Recovered orphaned jobs: %d requeued, %d failed", len(recovery.Requeued), len(recovery.Failed))
+		}
+		jobs.StartWorkers(id, settings.Jobs.Workers, settings.Jobs.PollInterval, stopChan)
+	}
+
+	if !mode.ServesAPI() {
+		<-stopChan
+		// Let running jobs finish and the instance deregister before exiting
+		jobs.Wait()
+		return
+	}
+	fmt.Printf("Starting HTTP server on %s\n", settings.Server.Addr)
+	serveHTTP(settings.Server.Addr, r, stopChan)
+	jobs.Wait()
 }
 
-func main() {
-	fmt.Println("Hello, World!")
-	viperConfig := config.NewViper()
-	logConfig := config.NewLogger(viperConfig)
-	dbConfig := config.NewDatabase(viperConfig, logConfig)
-	collyConfig := config.NewColly(viperConfig, logConfig)
+// shutdownTimeout bounds how long the HTTP server waits for the requests in progress once stopped
+const shutdownTimeout = 30 * time.Second
 
-	// Start circuit breaker coordinator in the background
-	go startCircuitBreakerCoordinator("http://localhost:8081/api", 60)
+// serveHTTP serves handler on addr until stopChan is closed, then stops accepting connections
+// and returns once the requests in progress are done, or after shutdownTimeout
+func serveHTTP(addr string, handler http.Handler, stopChan <-chan struct{}) {
+	server := &http.Server{Addr: addr, Handler: handler}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stopChan
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("HTTP server did not stop cleanly: %v", err)
+		}
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("HTTP server failed: %v", err)
+	}
+	<-stopped
+	log.Println("HTTP server stopped")
+}
 
-	r := config.Bootstrap(&config.BootstrapConfig{
-		DB:     dbConfig,
-		Log:    logConfig,
-		Config: viperConfig,
-		Colly:  collyConfig,
-	})
+// compressReleases runs "crawler compress-releases [--batch N]", which stores the content of
+// every release again as set by database.compression, then exits
+func compressReleases(batch int) {
+	if batch <= 0 {
+		log.Fatal("--batch must be positive")
+	}
+
+	settings, err := config.NewConfig(config.NewViper())
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	logConfig := config.NewLogger(settings.Log)
+	db := config.NewDatabase(settings.Database, logConfig)
+	releaseUsecase := usecase.NewReleaseUsecase(db, logConfig, repository.NewReleaseRepository(logConfig), nil)
+
+	startTime := time.Now()
+	rewritten, err := releaseUsecase.RecompressContents(context.Background(), batch)
+	if err != nil {
+		log.Fatalf("Compressing releases failed after %d releases: %v", rewritten, err)
+	}
+	log.Printf("Stored %d release contents with compression %s in %s",
+		rewritten, settings.Database.Compression.Algorithm, time.Since(startTime).Round(time.Millisecond))
+}
+
+// parseReleases runs "crawler parse-releases [--batch N]", which parses the notes of every release
+// again into their structured tables, then exits
+func parseReleases(batch int) {
+	if batch <= 0 {
+		log.Fatal("--batch must be positive")
+	}
+
+	settings, err := config.NewConfig(config.NewViper())
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if !settings.Notes.Enabled {
+		log.Fatal("release_notes.enabled is off, structured release notes are not stored")
+	}
+	logConfig := config.NewLogger(settings.Log)
+	db := config.NewDatabase(settings.Database, logConfig)
+	releaseNoteUsecase := usecase.NewReleaseNoteUsecase(db, logConfig,
+		repository.NewReleaseNoteRepository(logConfig), repository.NewReleaseRepository(logConfig))
+
+	startTime := time.Now()
+	parsed, err := releaseNoteUsecase.ParseAll(context.Background(), batch)
+	if err != nil {
+		log.Fatalf("Parsing releases failed after %d releases: %v", parsed, err)
+	}
+	log.Printf("Parsed the notes of %d releases in %s", parsed, time.Since(startTime).Round(time.Millisecond))
+}
+
+// migrate runs "crawler migrate", which creates the missing tables and columns of the
+// configured database from the entities, then exits
+func migrate() {
+	settings, err := config.NewConfig(config.NewViper())
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	logConfig := config.NewLogger(settings.Log)
+	db := config.NewDatabase(settings.Database, logConfig)
+	if err := config.Migrate(db); err != nil {
+		log.Fatalf("Migrating the database failed: %v", err)
+	}
+	log.Printf("Migrated the %s database", settings.Database.Driver)
+}
+
+// goldenOptions are the flags of "crawler golden"
+type goldenOptions struct {
+	dir      string
+	fixtures string
+	only     string
+	update   bool
+	refresh  bool
+}
+
+// checkGoldens runs "crawler golden [--update] [--refresh] [--case NAME]", which checks the
+// scrapers against the golden cases and exits with status 1 when one fails
+func checkGoldens(options goldenOptions) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	goldens := golden.New(options.dir, options.fixtures, logger)
+	cases, err := goldens.Load(options.only)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if options.refresh {
+		failed := 0
+		for _, result := range goldens.Refresh(ctx, cases) {
+			if result.Err != nil {
+				failed++
+				log.Printf("FAIL %s: %v", result.Name, result.Err)
+			}
+		}
+		log.Printf("Refreshed %d of %d golden cases", len(cases)-failed, len(cases))
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	results := goldens.Check(ctx, cases)
+	if options.update {
+		if err := goldens.Update(cases, results); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Updated %d golden cases", len(cases))
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			log.Printf("FAIL %s: %v", result.Name, result.Err)
+		case result.Diff != "":
+			log.Printf("FAIL %s: %s", result.Name, result.Diff)
+		default:
+			continue
+		}
+		failed++
+	}
+	log.Printf("%d of %d golden cases passed", len(cases)-failed, len(cases))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// serveCoordinatorMetrics serves the coordinator's metrics and health check on their own listener
+func serveCoordinatorMetrics(addr string, handler http.Handler) {
+	log.Printf("Serving coordinator metrics on %s", addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log.Printf("Coordinator metrics server stopped: %v", err)
+	}
+}
+
+// newLeaderElector elects one scheduler among the instances sharing the PostgreSQL database
+// when "scheduler.leader_election" is on; otherwise this instance always schedules
+func newLeaderElector(scheduler config.SchedulerSettings, db *gorm.DB) *service.LeaderElector {
+	retry := scheduler.ElectionInterval
+	name := scheduler.LockName
+
+	if !scheduler.LeaderElection {
+		return service.NewLeaderElector(nil, name, retry)
+	}
+	if db.Dialector.Name() != "postgres" {
+		log.Printf("Leader election needs PostgreSQL, scheduling on this instance only")
+		return service.NewLeaderElector(nil, name, retry)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database connection for leader election: %v", err)
+	}
+	return service.NewLeaderElector(sqlDB, name, retry)
+}
+
+// startScheduler creates the coordinator and alert engine, which run while this instance leads
+func startScheduler(settings *config.Config, notifiers notifier.Notifier, leader *service.LeaderElector,
+	blackouts *service.Blackouts) (*service.CrawlingCoordinator, *service.AlertEngine) {
+	// Create coordinator with circuit breaker protection
+	stages, err := service.NewStageConfigs(settings.Coordinator.Stages)
+	if err != nil {
+		log.Fatalf("Invalid coordinator stage configuration: %v", err)
+	}
+	// A separately deployed scheduler reaches the API instances through coordinator.api_url
+	coordinator, err := service.NewCrawlingCoordinator(settings.Coordinator.APIURL, stages)
+	if err != nil {
+		log.Fatalf("Failed to create coordinator: %v", err)
+	}
+	if threshold := settings.Coordinator.StabilityThreshold; threshold != nil {
+		coordinator.SetStabilityThreshold(*threshold)
+	}
+	if key := settings.Coordinator.APIKey; key != "" {
+		coordinator.SetAPIKey(key)
+	}
+	if secret := settings.Auth.Signing.Secret; secret != "" {
+		coordinator.SetSigningSecret(secret)
+	}
+	coordinator.SetMaxPause(settings.Coordinator.MaxPause)
+	coordinator.SetBreakerSettings(settings.Coordinator.Breaker)
+	if !settings.Features.CircuitBreaking {
+		coordinator.DisableBreakers()
+	}
+	coordinator.SetBlackouts(blackouts)
+
+	alertRules, err := service.NewAlertRules(settings.Alerts.Rules)
+	if err != nil {
+		log.Fatalf("Invalid alert rule configuration: %v", err)
+	}
+	alerts := service.NewAlertEngine(alertRules, notifiers)
+	alerts.AddSource(coordinator.MetricValues)
+	coordinator.SetNotifier(notifiers)
+
+	// Start circuit breaker coordinator and alert evaluation in the background. With decay
+	// re-crawls the repositories are re-crawled one by one instead, and with cron schedules the
+	// stages run at their scheduled times; the coordinator otherwise only runs stages on demand.
+	// Cron schedules are run by the schedule usecase set up in config.Bootstrap
+	for name := range settings.Schedule {
+		if name != usecase.ScheduleAll && !coordinator.HasStage(name) {
+			log.Fatalf("Invalid schedule configuration: unknown stage %s", name)
+		}
+	}
+	switch {
+	case settings.Scheduler.Decay.Enabled:
+		log.Printf("Decay re-crawls enabled, not starting periodic coordinator cycles")
+	case len(settings.Schedule) > 0:
+		log.Printf("Cron schedules configured, not starting periodic coordinator cycles")
+	default:
+		leader.OnElected(func(stop <-chan struct{}) {
+			startCircuitBreakerCoordinator(coordinator, 60, stop)
+		})
+	}
+	if len(alertRules) > 0 {
+		alertInterval := settings.Alerts.Interval
+		leader.OnElected(func(stop <-chan struct{}) {
+			alerts.StartEvaluating(alertInterval, stop)
+		})
+	}
+	return coordinator, alerts
+}
 
-	fmt.Println("Starting HTTP server on :8081")
-	http.ListenAndServe(":8081", r)
+// workerID names this instance's workers in the job queue, by default after the host and process
+func workerID(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
 }
 
 // func main() {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crawler/baseline/internal/config"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// reparse runs "crawler reparse OWNER/NAME [--at TIME] [--releases] [--commits] [--output json|text]
+// [--store]", which crawls one repository as crawl repo does, but from the pages kept in
+// scrape.archive instead of GitHub, so the scrapers extract them again with the current selectors
+func reparse(options crawlRepoOptions, at string) {
+	runCrawlRepo(options, func() *viper.Viper {
+		viperConfig := config.NewViper()
+		if viperConfig.GetString("scrape.archive.bucket") == "" {
+			log.Fatal("reparse needs scrape.archive.bucket")
+		}
+		if at != "" {
+			if _, err := time.Parse(time.RFC3339, at); err != nil {
+				log.Fatalf("--at must be an RFC 3339 time: %v", err)
+			}
+			viperConfig.Set("scrape.archive.replay_at", at)
+		}
+		// Nothing else may serve the pages, nor archive them again
+		viperConfig.Set("scrape.replay", true)
+		viperConfig.Set("scrape.record_dir", "")
+		viperConfig.Set("scrape.fixtures", "")
+		viperConfig.Set("scrape.archive.enabled", false)
+		return viperConfig
+	})
+}
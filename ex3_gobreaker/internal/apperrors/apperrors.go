@@ -0,0 +1,148 @@
+// Package apperrors maps the errors of the crawler to the HTTP status and machine-readable code
+// the API reports them with. Usecases return or wrap the errors listed here, and controllers look
+// them up with Classify instead of deciding the status themselves.
+package apperrors
+
+import (
+	"context"
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Domain errors of the usecases, wrapped with the details of the failure
+var (
+	ErrNotFound = errors.New("not found")
+	ErrInvalid  = errors.New("invalid request")
+	ErrConflict = errors.New("already exists")
+)
+
+// Codes of the errors reported by the API. The generic ones are the snake_case names of the HTTP
+// statuses, as for any other error response.
+const (
+	CodeNotFound       = "not_found"
+	CodeBadRequest     = "bad_request"
+	CodeConflict       = "conflict"
+	CodeBadGateway     = "bad_gateway"
+	CodeGatewayTimeout = "gateway_timeout"
+	CodeInternal       = "internal_server_error"
+
+	CodeGitHubNotFound   = "github_not_found"
+	CodeGitHubPrivate    = "github_private"
+	CodeGitHubBlocked    = "github_blocked"
+	CodeBlackout         = "blackout"
+	CodeJobNotFound      = "job_not_found"
+	CodeUnknownJobKind   = "unknown_job_kind"
+	CodeBenchRunning     = "bench_running"
+	CodeInvalidBench     = "invalid_bench"
+	CodeSelfCheckRunning = "selfcheck_running"
+	CodeUnsigned         = "unsigned_request"
+	CodeBadSignature     = "bad_signature"
+	CodeExpiredSignature = "expired_signature"
+)
+
+// Mapping is the status and code of the errors matching Err
+type Mapping struct {
+	Err    error
+	Status int
+	Code   string
+}
+
+// mappings are tried in order, so an error comes before the errors it wraps
+var mappings = []Mapping{
+	{ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrInvalid, http.StatusBadRequest, CodeBadRequest},
+	{ErrConflict, http.StatusConflict, CodeConflict},
+	{gorm.ErrRecordNotFound, http.StatusNotFound, CodeNotFound},
+	{gorm.ErrDuplicatedKey, http.StatusConflict, CodeConflict},
+
+	// scrape.ErrRepoNotFound wraps scrape.ErrNotFound
+	{scrape.ErrNotFound, http.StatusNotFound, CodeGitHubNotFound},
+	{scrape.ErrRepoPrivate, http.StatusForbidden, CodeGitHubPrivate},
+	{scrape.ErrBlocked, http.StatusServiceUnavailable, CodeGitHubBlocked},
+
+	{service.ErrBlackout, http.StatusServiceUnavailable, CodeBlackout},
+	{service.ErrJobNotFound, http.StatusNotFound, CodeJobNotFound},
+	{service.ErrUnknownJobKind, http.StatusBadRequest, CodeUnknownJobKind},
+	{service.ErrBenchRunning, http.StatusConflict, CodeBenchRunning},
+	{service.ErrInvalidBench, http.StatusBadRequest, CodeInvalidBench},
+	{service.ErrSelfCheckRunning, http.StatusConflict, CodeSelfCheckRunning},
+
+	{auth.ErrUnsigned, http.StatusUnauthorized, CodeUnsigned},
+	{auth.ErrBadSignature, http.StatusUnauthorized, CodeBadSignature},
+	{auth.ErrExpiredSignature, http.StatusUnauthorized, CodeExpiredSignature},
+	{auth.ErrReplayedSignature, http.StatusUnauthorized, CodeExpiredSignature},
+
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeGatewayTimeout},
+}
+
+// Error is a failure reported with its own status, code and message, such as a rejected
+// onboarding. Err is its cause, if any.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error
+}
+
+// New reports err, classified as by Classify, with message
+func New(err error, message string) *Error {
+	status, code := Classify(err)
+	return &Error{Status: status, Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the status and code of err: those of the *Error it wraps, else of the first
+// mapping it matches, else 500 internal_server_error
+func Classify(err error) (status int, code string) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Status, appErr.Code
+	}
+	for _, mapping := range mappings {
+		if errors.Is(err, mapping.Err) {
+			return mapping.Status, mapping.Code
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// Mapped tells whether Classify knows err, that is whether it is not an internal error
+func Mapped(err error) bool {
+	status, _ := Classify(err)
+	return status != http.StatusInternalServerError
+}
+
+// IsNotFound tells whether err is reported as 404, whatever was not found
+func IsNotFound(err error) bool {
+	status, _ := Classify(err)
+	return status == http.StatusNotFound
+}
+
+// RetryAfter is how long to wait before retrying what failed with err: until the end of a
+// blackout window, or until GitHub stops blocking the crawler. It is 0 for other errors.
+func RetryAfter(err error, now time.Time) time.Duration {
+	var blackout *service.BlackoutError
+	if errors.As(err, &blackout) {
+		return blackout.Until.Sub(now)
+	}
+	if errors.Is(err, scrape.ErrBlocked) {
+		return scrape.RetryAfter(err)
+	}
+	return 0
+}
@@ -0,0 +1,209 @@
+// Package archive keeps the raw pages the scrapers fetch in an S3 compatible object storage,
+// such as AWS S3 or MinIO, so that a crawl can be parsed again after a selector fix.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Compressions of the archived pages
+const (
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+// timestampLayout names the archived versions of a page; fixed width, so the lexical order S3
+// lists the keys in is their chronological order
+const timestampLayout = "20060102T150405.000000000Z"
+
+// Settings is the "scrape.archive" config section; a zero value keeps the default of its field
+type Settings struct {
+	// Enabled archives every page fetched by the scrapers
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// Endpoint is the URL of the object storage, https://s3.amazonaws.com by default
+	Endpoint string `mapstructure:"endpoint" json:"endpoint"`
+	// Region signs the requests, us-east-1 by default
+	Region    string `mapstructure:"region" json:"region"`
+	Bucket    string `mapstructure:"bucket" json:"bucket"`
+	AccessKey string `mapstructure:"access_key" json:"access_key"`
+	SecretKey string `mapstructure:"secret_key" json:"secret_key"`
+	// Prefix starts the keys of the archived pages, "pages" by default
+	Prefix string `mapstructure:"prefix" json:"prefix"`
+	// Compression is CompressionGzip (default) or CompressionNone
+	Compression string `mapstructure:"compression" json:"compression"`
+	// Timeout bounds each request to the object storage, 30s by default
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+	// ReplayAt is an RFC 3339 time picking, when scrape.replay serves the archived pages, the
+	// latest version of each page archived at or before it; empty picks the latest version
+	ReplayAt string `mapstructure:"replay_at" json:"replay_at"`
+}
+
+// Validate rejects an unknown compression, a negative timeout, an endpoint that is not a URL,
+// a replay_at that is not an RFC 3339 time and an enabled archive without a bucket
+func (s Settings) Validate() error {
+	switch s.Compression {
+	case "", CompressionGzip, CompressionNone:
+	default:
+		return fmt.Errorf("unknown compression %q, expected %s or %s", s.Compression, CompressionGzip, CompressionNone)
+	}
+	if s.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	if s.Endpoint != "" {
+		if _, err := url.ParseRequestURI(s.Endpoint); err != nil {
+			return fmt.Errorf("endpoint: %w", err)
+		}
+	}
+	if s.ReplayAt != "" {
+		if _, err := time.Parse(time.RFC3339, s.ReplayAt); err != nil {
+			return fmt.Errorf("replay_at: %w", err)
+		}
+	}
+	if s.Enabled && s.Bucket == "" {
+		return errors.New("bucket is required when enabled")
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left zero
+func (s Settings) WithDefaults() Settings {
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3.amazonaws.com"
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Prefix == "" {
+		s.Prefix = "pages"
+	}
+	if s.Compression == "" {
+		s.Compression = CompressionGzip
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 30 * time.Second
+	}
+	return s
+}
+
+// ReplayTime is ReplayAt parsed, zero when it is empty or invalid
+func (s Settings) ReplayTime() time.Time {
+	at, _ := time.Parse(time.RFC3339, s.ReplayAt)
+	return at
+}
+
+// Page is a fetched page as archived
+type Page struct {
+	URL       string      `json:"url"`
+	FetchedAt time.Time   `json:"fetchedAt"`
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body"`
+}
+
+// Archive stores the versions of the fetched pages in a bucket, each under
+// "<prefix>/<SHA-256 of the URL>/<fetch time>.json[.gz]"
+type Archive struct {
+	settings Settings
+	client   *s3Client
+}
+
+func New(settings Settings) *Archive {
+	settings = settings.WithDefaults()
+	return &Archive{
+		settings: settings,
+		client: &s3Client{
+			endpoint:  settings.Endpoint,
+			region:    settings.Region,
+			bucket:    settings.Bucket,
+			accessKey: settings.AccessKey,
+			secretKey: settings.SecretKey,
+			client:    &http.Client{Timeout: settings.Timeout},
+		},
+	}
+}
+
+// Put archives a version of a page, returning its key
+func (a *Archive) Put(ctx context.Context, page *Page) (string, error) {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return "", err
+	}
+	key := a.pagePrefix(page.URL) + page.FetchedAt.UTC().Format(timestampLayout) + ".json"
+	contentType := "application/json"
+	if a.settings.Compression == CompressionGzip {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write(data)
+		if err := writer.Close(); err != nil {
+			return "", err
+		}
+		data = compressed.Bytes()
+		key += ".gz"
+		contentType = "application/gzip"
+	}
+	if err := a.client.put(ctx, key, data, contentType); err != nil {
+		return "", fmt.Errorf("archiving %s: %w", page.URL, err)
+	}
+	return key, nil
+}
+
+// Latest returns the version of the page of rawURL archived last at or before at, or the last
+// one for a zero at; ErrNoObject when there is none
+func (a *Archive) Latest(ctx context.Context, rawURL string, at time.Time) (*Page, error) {
+	keys, err := a.client.list(ctx, a.pagePrefix(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("listing the versions of %s: %w", rawURL, err)
+	}
+	latest := ""
+	for _, key := range keys {
+		name := path.Base(key)
+		if len(name) < len(timestampLayout) {
+			continue
+		}
+		if !at.IsZero() && name[:len(timestampLayout)] > at.UTC().Format(timestampLayout) {
+			break
+		}
+		latest = key
+	}
+	if latest == "" {
+		return nil, ErrNoObject
+	}
+
+	data, err := a.client.get(ctx, latest)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", latest, err)
+	}
+	if strings.HasSuffix(latest, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", latest, err)
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", latest, err)
+		}
+	}
+	var page Page
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", latest, err)
+	}
+	return &page, nil
+}
+
+// pagePrefix is the part of the keys shared by the versions of a page
+func (a *Archive) pagePrefix(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return strings.Trim(a.settings.Prefix, "/") + "/" + hex.EncodeToString(sum[:]) + "/"
+}
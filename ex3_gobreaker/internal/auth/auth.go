@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Roles in increasing order of privilege; each role may do everything the previous one can
+const (
+	// RoleReader can call read-only endpoints
+	RoleReader = "reader"
+	// RoleOperator can also trigger crawls and other calls that reach GitHub
+	RoleOperator = "operator"
+	// RoleAdmin can also change coordinator thresholds and pauses
+	RoleAdmin = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleReader:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Allows reports whether a key with role have may call an endpoint requiring role need
+func Allows(have string, need string) bool {
+	return roleRanks[have] >= roleRanks[need] && roleRanks[have] > 0
+}
+
+// APIKey is one configured key. The key is given either in plain text or, preferably,
+// as the hex SHA-256 of the key so the config file does not hold the secret.
+type APIKey struct {
+	Name      string `mapstructure:"name" json:"name"`
+	Key       string `mapstructure:"key" json:"key"`
+	KeySHA256 string `mapstructure:"key_sha256" json:"key_sha256"`
+	Role      string `mapstructure:"role" json:"role"`
+}
+
+// Principal is the caller identified by an API key
+type Principal struct {
+	Name string
+	Role string
+}
+
+// KeyStore resolves API keys to principals
+type KeyStore struct {
+	enabled bool
+	keys    map[string]Principal
+}
+
+// NewKeyStore validates the keys of the "auth" config section. Authentication is off unless enabled is set.
+func NewKeyStore(enabled bool, keys []APIKey) (*KeyStore, error) {
+	store := &KeyStore{
+		enabled: enabled,
+		keys:    make(map[string]Principal),
+	}
+
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("auth key %d has no name", i+1)
+		}
+		if _, ok := roleRanks[key.Role]; !ok {
+			return nil, fmt.Errorf("auth key %s has unknown role %q", key.Name, key.Role)
+		}
+
+		hash := strings.ToLower(key.KeySHA256)
+		if key.Key != "" {
+			hash = HashKey(key.Key)
+		}
+		if len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("auth key %s needs a key or a hex key_sha256", key.Name)
+		}
+		if _, exists := store.keys[hash]; exists {
+			return nil, fmt.Errorf("auth key %s duplicates another key", key.Name)
+		}
+		store.keys[hash] = Principal{Name: key.Name, Role: key.Role}
+	}
+
+	if store.enabled && len(store.keys) == 0 {
+		return nil, fmt.Errorf("auth is enabled but no keys are configured")
+	}
+	return store, nil
+}
+
+// Enabled reports whether requests must carry an API key
+func (s *KeyStore) Enabled() bool {
+	return s != nil && s.enabled
+}
+
+// Lookup returns the principal of a key; keys are compared by their hash
+func (s *KeyStore) Lookup(key string) (Principal, bool) {
+	if s == nil || key == "" {
+		return Principal{}, false
+	}
+	principal, ok := s.keys[HashKey(key)]
+	return principal, ok
+}
+
+// HashKey returns the hex SHA-256 of a key, the form used in auth.keys[].key_sha256
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores the authenticated caller in the context
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated caller, if any
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,160 @@
+// Package budget bounds the scraping goroutines of the crawl stages and the heap they may start
+// them at, so a misconfigured concurrency or several crawls of one stage piling up fail with a
+// clear error instead of exhausting the process.
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"sort"
+	"sync"
+)
+
+// ErrExceeded is wrapped by the errors of a refused goroutine
+var ErrExceeded = errors.New("budget exceeded")
+
+// heapMetric is the memory of live and not yet swept heap objects, read without stopping the world
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// Limits are the budget of one stage; zero leaves a limit off
+type Limits struct {
+	// MaxGoroutines bounds the goroutines the stage runs at once, across its crawls
+	MaxGoroutines int
+	// MaxHeapBytes is the heap of the process above which the stage starts no goroutine
+	MaxHeapBytes uint64
+}
+
+// Usage is the state of the budget of a stage
+type Usage struct {
+	Stage         string `json:"stage"`
+	Goroutines    int    `json:"goroutines"`
+	MaxGoroutines int    `json:"maxGoroutines,omitempty"`
+	Peak          int    `json:"peak"`
+	HeapBytes     uint64 `json:"heapBytes"`
+	MaxHeapBytes  uint64 `json:"maxHeapBytes,omitempty"`
+	Refused       int64  `json:"refused"`
+}
+
+// Budget counts the goroutines a stage started and refuses new ones beyond its limits.
+// A nil Budget allows everything.
+type Budget struct {
+	stage  string
+	limits Limits
+
+	mutex   sync.Mutex
+	running int
+	peak    int
+	refused int64
+}
+
+func New(stage string, limits Limits) *Budget {
+	return &Budget{stage: stage, limits: limits}
+}
+
+// Spawn reserves a goroutine of the stage, to be given back by calling done when it ends. It
+// fails with an error wrapping ErrExceeded when the stage already runs MaxGoroutines or the
+// heap is above MaxHeapBytes.
+func (b *Budget) Spawn() (done func(), err error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.limits.MaxGoroutines > 0 && b.running >= b.limits.MaxGoroutines {
+		b.refused++
+		return nil, fmt.Errorf("%w: %s stage already runs %d goroutines, the most its budget allows",
+			ErrExceeded, b.stage, b.running)
+	}
+	b.running++
+	b.peak = max(b.peak, b.running)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			b.running--
+			b.mutex.Unlock()
+		})
+	}, nil
+}
+
+// Check fails with an error wrapping ErrExceeded when the heap is above MaxHeapBytes, for work
+// handed to goroutines already started
+func (b *Budget) Check() error {
+	if b == nil || b.limits.MaxHeapBytes == 0 {
+		return nil
+	}
+	if heap := HeapBytes(); heap > b.limits.MaxHeapBytes {
+		b.mutex.Lock()
+		b.refused++
+		b.mutex.Unlock()
+		return fmt.Errorf("%w: heap of %d MiB is above the %d MiB limit of the %s stage", ErrExceeded,
+			heap>>20, b.limits.MaxHeapBytes>>20, b.stage)
+	}
+	return nil
+}
+
+// Usage returns the goroutines the stage runs, its peak and the current heap
+func (b *Budget) Usage() Usage {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return Usage{
+		Stage:         b.stage,
+		Goroutines:    b.running,
+		MaxGoroutines: b.limits.MaxGoroutines,
+		Peak:          b.peak,
+		HeapBytes:     HeapBytes(),
+		MaxHeapBytes:  b.limits.MaxHeapBytes,
+		Refused:       b.refused,
+	}
+}
+
+// HeapBytes returns the memory of the heap objects of the process
+func HeapBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// Set holds the budgets of the stages
+type Set struct {
+	budgets map[string]*Budget
+}
+
+// NewSet creates the budget of every stage of limits
+func NewSet(limits map[string]Limits) *Set {
+	s := &Set{budgets: make(map[string]*Budget, len(limits))}
+	for stage, stageLimits := range limits {
+		s.budgets[stage] = New(stage, stageLimits)
+	}
+	return s
+}
+
+// For returns the budget of stage, nil when it has none
+func (s *Set) For(stage string) *Budget {
+	if s == nil {
+		return nil
+	}
+	return s.budgets[stage]
+}
+
+// Usages returns the state of every budget, by stage name
+func (s *Set) Usages() []Usage {
+	usages := make([]Usage, 0)
+	if s == nil {
+		return usages
+	}
+	for _, b := range s.budgets {
+		usages = append(usages, b.Usage())
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Stage < usages[j].Stage })
+	return usages
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// PurgeOnWrite calls purge after every write to one of tables through db: updates, deletes, soft
+// deletes included, and inserts that update the existing rows on conflict. The rows a write
+// touches are not known in general, so the cache is purged whole. Writes made by other
+// processes are not seen; their values are served until they expire.
+func PurgeOnWrite(db *gorm.DB, name string, purge func(), tables ...string) error {
+	callback := func(tx *gorm.DB) {
+		if slices.Contains(tables, tx.Statement.Table) {
+			purge()
+		}
+	}
+	upsertCallback := func(tx *gorm.DB) {
+		// Plain inserts add rows that no cached value can be about
+		if _, upsert := tx.Statement.Clauses["ON CONFLICT"]; upsert {
+			callback(tx)
+		}
+	}
+
+	callbackName := "cache:purge_" + name
+	if err := db.Callback().Update().After("gorm:update").Register(callbackName, callback); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register(callbackName, callback); err != nil {
+		return err
+	}
+	return db.Callback().Create().After("gorm:create").Register(callbackName, upsertCallback)
+}
@@ -1,29 +1,66 @@
 package config
 
 import (
+	"context"
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/budget"
+	"crawler/baseline/internal/cache"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/experiment"
+	"crawler/baseline/internal/graph"
+	"crawler/baseline/internal/grpc"
 	"crawler/baseline/internal/http/controller"
 	"crawler/baseline/internal/http/route"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/queue"
 	"crawler/baseline/internal/repository"
 	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/spool"
 	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gocolly/colly/v2"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
 type BootstrapConfig struct {
 	DB     *gorm.DB
 	Log    *logrus.Logger
-	Config *viper.Viper
+	Config *Config
 	Colly  *colly.Collector
+	// CollyLimit caps the requests of Colly at once, resized when colly.parallelism is reloaded
+	CollyLimit *scrape.ParallelismLimit
+	// CollyPacer paces the requests about repositories whose policy sets requests per minute
+	CollyPacer *scrape.RepoPacer
+	// CollyStats counts the requests of Colly for the live metrics; nil counts nothing
+	CollyStats *scrape.RequestStats
+
+	Coordinator *service.CrawlingCoordinator
+	Alerts      *service.AlertEngine
+	Jobs        *service.JobManager
+	Notifier    notifier.Notifier
+	// Auth holds the API keys; nil leaves the API open
+	Auth *auth.KeyStore
+	// Mode decides which background work Bootstrap starts; empty runs everything
+	Mode RunMode
+	// Leader runs scheduled work, such as release digests, only while this instance leads; nil runs it here
+	Leader *service.LeaderElector
+	// Blackouts pause scheduled crawls and refuse new crawl jobs; nil never pauses them
+	Blackouts *service.Blackouts
+
+	// Stop ends background work started by Bootstrap, such as release digests
+	Stop <-chan struct{}
 }
 
 func Bootstrap(config *BootstrapConfig) *chi.Mux {
 	// Set up loggers
-	logConfig := SetupLoggers()
+	logConfig := SetupLoggers(config.Config.Log)
 
 	// Store main logger in config
 	config.Log = logConfig.MainLogger
@@ -32,28 +69,484 @@ func Bootstrap(config *BootstrapConfig) *chi.Mux {
 	repoRepository := repository.NewRepoRepository(logConfig.RepoLogger)
 	releaseRepository := repository.NewReleaseRepository(logConfig.ReleaseLogger)
 	commitRepository := repository.NewCommitRepository(logConfig.CommitLogger)
+	tagRepository := repository.NewTagRepository(logConfig.TagLogger)
+	watchlistRepository := repository.NewWatchlistRepository(logConfig.MainLogger)
+	keywordRepository := repository.NewKeywordRepository(logConfig.MainLogger)
+
+	if config.Notifier == nil {
+		config.Notifier = notifier.NewNotifier(config.Config.Notifiers, config.Config.Webhooks, logConfig.MainLogger)
+	}
 
 	// Initialize usecases
 	repoUsecase := usecase.NewRepoUsecase(config.DB, logConfig.RepoLogger, repoRepository)
-	releaseUsecase := usecase.NewReleaseUsecase(config.DB, logConfig.ReleaseLogger, releaseRepository)
+	releaseUsecase := usecase.NewReleaseUsecase(config.DB, logConfig.ReleaseLogger, releaseRepository, config.Notifier)
 	commitUsecase := usecase.NewCommitUsecase(config.DB, logConfig.CommitLogger, commitRepository)
+	tagUsecase := usecase.NewTagUsecase(config.DB, logConfig.TagLogger, tagRepository)
+	watchlistUsecase := usecase.NewWatchlistUsecase(config.DB, logConfig.MainLogger, watchlistRepository, repoRepository)
+	feedUsecase := usecase.NewFeedUsecase(config.DB, logConfig.MainLogger, repoRepository, watchlistRepository)
+	statsUsecase := usecase.NewStatsUsecase(config.DB, logConfig.MainLogger)
+	keywordUsecase := usecase.NewKeywordUsecase(config.DB, logConfig.MainLogger, keywordRepository, config.Notifier)
+	releaseUsecase.Keywords = keywordUsecase
+	commitUsecase.Keywords = keywordUsecase
+	go keywordUsecase.StartMatching(config.Stop)
+	// The structured notes are only stored where their tables were created
+	var releaseNoteRepository *repository.ReleaseNoteRepository
+	if config.Config.Notes.Enabled {
+		releaseNoteRepository = repository.NewReleaseNoteRepository(logConfig.ReleaseLogger)
+	}
+	releaseNoteUsecase := usecase.NewReleaseNoteUsecase(config.DB, logConfig.ReleaseLogger,
+		releaseNoteRepository, releaseRepository)
+	if releaseNoteRepository != nil {
+		releaseUsecase.Notes = releaseNoteUsecase
+	}
+
+	// Validate already checked the ID settings
+	ids, _ := idgen.New(config.Config.Database.IDs.Strategy, config.Config.Database.IDs.Node)
+	repoUsecase.IDs = ids
+	releaseUsecase.IDs = ids
+	commitUsecase.IDs = ids
+	tagUsecase.IDs = ids
+	entity.SetNotFoundThreshold(config.Config.Crawl.NotFoundThreshold)
+	features := config.Config.Features
+	repoUsecase.RowByRow = !features.Batching
+	releaseUsecase.RowByRow = !features.Batching
+	commitUsecase.RowByRow = !features.Batching
+	tagUsecase.RowByRow = !features.Batching
+
+	if settings := config.Config.Kafka; settings.Enabled {
+		producer := newKafkaProducer(logConfig.MainLogger, settings, config.Stop)
+		repoUsecase.Kafka = producer
+		releaseUsecase.Kafka = producer
+		commitUsecase.Kafka = producer
+		if config.Alerts != nil {
+			config.Alerts.AddSource(kafkaMetrics(producer))
+		}
+	}
+
+	// Stored releases and commits are announced to the webhook subscriptions through the outbox
+	if settings := config.Config.Outbox; settings.Enabled {
+		outboxUsecase := usecase.NewOutboxUsecase(config.DB, logConfig.MainLogger,
+			repository.NewOutboxRepository(logConfig.MainLogger),
+			notifier.NewWebhooks(config.Config.Webhooks, logConfig.MainLogger), settings)
+		releaseUsecase.Outbox = outboxUsecase
+		commitUsecase.Outbox = outboxUsecase
+		go outboxUsecase.StartDispatching(config.Stop)
+		if config.Alerts != nil {
+			config.Alerts.AddSource(outboxMetrics(outboxUsecase))
+		}
+	}
+
+	// Crawl results are buffered on local disk while the database is unreachable
+	if settings := config.Config.Database.Spool; settings.Dir != "" {
+		crawlSpool, err := spool.New(settings.Dir, settings.MaxBytes)
+		if err != nil {
+			logConfig.MainLogger.WithError(err).Error("Error opening spool, crawl results are not buffered")
+		} else {
+			repoUsecase.Spool = crawlSpool
+			releaseUsecase.Spool = crawlSpool
+			commitUsecase.Spool = crawlSpool
+			tagUsecase.Spool = crawlSpool
+			spoolUsecase := usecase.NewSpoolUsecase(config.DB, logConfig.MainLogger, crawlSpool,
+				repoUsecase, releaseUsecase, commitUsecase, tagUsecase)
+			go spoolUsecase.StartReplaying(settings.ReplayInterval, config.Stop)
+		}
+	}
+
+	// Scrape processes hand their crawled batches to the persist processes through NATS
+	if config.Mode.PublishesItems() || config.Mode.PersistsItems() {
+		bus := newItemBus(logConfig.MainLogger, config.Config.NATS, config.Mode, config.Stop)
+		if config.Mode.PublishesItems() {
+			// The crawled rows get their IDs here, so new releases can have their commits crawled
+			// before a persist process stores them
+			if ids == nil {
+				logConfig.MainLogger.Fatalf("the scrape mode needs database.ids.strategy %q", idgen.StrategySnowflake)
+			}
+			repoUsecase.Items = bus
+			releaseUsecase.Items = bus
+			commitUsecase.Items = bus
+			tagUsecase.Items = bus
+		} else {
+			persistUsecase := usecase.NewPersistUsecase(logConfig.MainLogger, bus,
+				repoUsecase, releaseUsecase, commitUsecase, tagUsecase)
+			if err := persistUsecase.Start(); err != nil {
+				logConfig.MainLogger.Fatalf("failed to subscribe to the published batches: %v", err)
+			}
+		}
+		if config.Alerts != nil {
+			config.Alerts.AddSource(natsMetrics(bus))
+		}
+	}
+
+	digestUsecase := usecase.NewDigestUsecase(config.DB, logConfig.MainLogger, watchlistRepository, config.Notifier)
+	if config.Config.Digest.Enabled && config.Mode.RunsScheduler() {
+		period := config.Config.Digest.Period
+		if config.Leader != nil {
+			config.Leader.OnElected(func(stop <-chan struct{}) {
+				digestUsecase.StartDigests(period, stop)
+			})
+		} else {
+			go digestUsecase.StartDigests(period, config.Stop)
+		}
+	}
 
 	repoScrape := scrape.NewRepoScrape(logConfig.RepoLogger, config.Colly)
+	repoScrape.Token = config.Config.GitHub.Token
+	orgScrape := scrape.NewOrgScrape(logConfig.RepoLogger, config.Colly)
+	orgScrape.Token = config.Config.GitHub.Token
 	releaseScrape := scrape.NewReleaseScrape(logConfig.ReleaseLogger, config.Colly)
+	releaseScrape.Pacer = config.CollyPacer
+	releaseScrape.Token = config.Config.GitHub.Token
 	commitScrape := scrape.NewCommitScrape(logConfig.CommitLogger, config.Colly)
+	commitScrape.FetchStats = config.Config.Scrape.CommitStats
+	commitScrape.Pacer = config.CollyPacer
+	tagScrape := scrape.NewTagScrape(logConfig.TagLogger, config.Colly)
+	branchScrape := scrape.NewBranchScrape(logConfig.RepoLogger, config.Colly)
+	branchScrape.Token = config.Config.GitHub.Token
+
+	repoPolicyUsecase := usecase.NewRepoPolicyUsecase(config.DB, logConfig.MainLogger,
+		repository.NewRepoPolicyRepository(logConfig.MainLogger), repoRepository, service.NewRepoPolicies(config.Config.RepoPolicies))
 
 	// Initialize controllers
-	repoController := controller.NewRepoController(logConfig.RepoLogger, config.DB, repoUsecase, repoScrape)
-	releaseController := controller.NewReleaseController(logConfig.ReleaseLogger, config.DB, releaseUsecase, releaseScrape)
-	commitController := controller.NewCommitController(logConfig.CommitLogger, config.DB, commitUsecase, commitScrape)
+	cacheSettings := config.Config.Cache
+	repoResponses := newResponseCache[*model.RepoResponse](logConfig.MainLogger, config.DB, "repo",
+		cacheSettings.Repo, "repositories")
+	releaseResponses := newResponseCache[*model.ReleaseResponse](logConfig.MainLogger, config.DB, "release",
+		cacheSettings.Release, "releases", "release_assets")
+	commitResponses := newResponseCache[*model.CommitResponse](logConfig.MainLogger, config.DB, "commit",
+		cacheSettings.Commit, "commits")
+	caches := make(map[string]cache.StatsSource)
+	if repoResponses != nil {
+		caches["repo"] = repoResponses
+	}
+	if releaseResponses != nil {
+		caches["release"] = releaseResponses
+	}
+	if commitResponses != nil {
+		caches["commit"] = commitResponses
+	}
+	if config.Alerts != nil && len(caches) > 0 {
+		config.Alerts.AddSource(cacheMetrics(caches))
+	}
+
+	repoController := controller.NewRepoController(logConfig.RepoLogger, config.DB, repoUsecase, repoScrape, branchScrape,
+		repoResponses)
+	budgets := budget.NewSet(config.Config.Crawl.BudgetLimits())
+	var repoBreakers *utils.BreakerGroup
+	if features.CircuitBreaking {
+		repoBreakers = newRepoBreakers(logConfig.MainLogger, config.Config.Crawl.RepoBreaker)
+	}
+	if config.Alerts != nil && repoBreakers != nil {
+		config.Alerts.AddSource(repoBreakerMetrics(repoBreakers))
+	}
+	var commitQueue *queue.CommitQueueProcessor
+	var backpressure *queue.Backpressure
+	if features.Queueing {
+		commitQueue = newCommitQueue(logConfig.CommitLogger, commitUsecase, config.Config.Queue, config.Stop)
+		if config.Alerts != nil {
+			config.Alerts.AddSource(queueMetrics(commitQueue))
+		}
+		backpressure = queue.NewBackpressure(logConfig.MainLogger, config.Config.Queue.Backpressure.RetryAfter)
+		backpressure.Track("commits", func() int { return commitQueue.Snapshot().Size }, config.Config.Queue)
+	}
+	releaseController := controller.NewReleaseController(logConfig.ReleaseLogger, config.DB, releaseUsecase, releaseScrape,
+		repoPolicyUsecase, config.Config.Crawl.RepoConcurrency, budgets.For("releases"), releaseResponses,
+		repoBreakers)
+	commitController := controller.NewCommitController(logConfig.CommitLogger, config.DB, commitUsecase, commitScrape, branchScrape,
+		repoPolicyUsecase, config.Config.Crawl.ReleaseConcurrency, budgets.For("commits"), commitResponses,
+		repoBreakers, commitQueue)
+	tagController := controller.NewTagController(logConfig.TagLogger, config.DB, tagUsecase, tagScrape)
+
+	visitController := controller.NewVisitController(logConfig.MainLogger, config.DB)
+	exportController := controller.NewExportController(logConfig.MainLogger, config.DB)
+	watchlistController := controller.NewWatchlistController(logConfig.MainLogger, watchlistUsecase, digestUsecase, keywordUsecase)
+	feedController := controller.NewFeedController(logConfig.MainLogger, feedUsecase)
+	statsController := controller.NewStatsController(logConfig.MainLogger, statsUsecase)
+	crawlRunController := controller.NewCrawlRunController(logConfig.MainLogger, config.DB)
+	crawlRuns := controller.NewCrawlRunRecorder(logConfig.MainLogger, config.DB)
+
+	policies := service.NewCrawlPolicies(config.Config.Policies)
+	if config.Jobs == nil {
+		config.Jobs = service.NewJobManager(config.Notifier)
+	}
+	config.Jobs.SetStop(config.Stop)
+	if config.Mode.SharesQueue() || config.Config.Jobs.Backend == "db" {
+		jobRepository := repository.NewJobRepository(logConfig.MainLogger)
+		workerRepository := repository.NewWorkerRepository(logConfig.MainLogger)
+		config.Jobs.SetStore(usecase.NewJobUsecase(config.DB, logConfig.MainLogger, jobRepository, workerRepository))
+	}
+	onboardController := controller.NewOnboardController(logConfig.RepoLogger,
+		repoUsecase, releaseUsecase, commitUsecase, tagUsecase,
+		repoScrape, releaseScrape, commitScrape, tagScrape, policies, repoPolicyUsecase, config.Jobs, crawlRuns)
+	jobController := controller.NewJobController(logConfig.MainLogger, config.Jobs, budgets)
+	crawlErrorController := controller.NewCrawlErrorController(logConfig.CommitLogger, config.DB, commitController, config.Jobs, crawlRuns)
+
+	// Every instance can show the re-crawl calendar, only schedulers start the re-crawls
+	var recrawlUsecase *usecase.RecrawlUsecase
+	decay := config.Config.Scheduler.Decay
+	if decay.Enabled {
+		recrawlUsecase = usecase.NewRecrawlUsecase(config.DB, logConfig.MainLogger, repoRepository, releaseRepository, decay.DecayCurve, config.Blackouts)
+	}
+	if decay.Enabled && config.Mode.RunsScheduler() {
+		if config.Leader != nil {
+			config.Leader.OnElected(func(stop <-chan struct{}) {
+				recrawlUsecase.StartRecrawling(decay.Tick, decay.Batch, onboardController.Recrawl, stop)
+			})
+		} else {
+			go recrawlUsecase.StartRecrawling(decay.Tick, decay.Batch, onboardController.Recrawl, config.Stop)
+		}
+	}
+
+	var coordinatorController *controller.CoordinatorController
+	if config.Coordinator != nil {
+		if config.Config.Coordinator.Mode == CoordinatorInProcess {
+			stages := config.Coordinator.UseInProcess(inProcessAPI(repoController, releaseController, commitController, statsUsecase, crawlRuns))
+			logConfig.MainLogger.WithField("stages", stages).Info("Coordinator stages run in process")
+		}
+		coordinatorController = controller.NewCoordinatorController(logConfig.MainLogger, config.Coordinator)
+	}
+
+	// Every instance can list and edit the cron schedules, only schedulers run them
+	scheduleUsecase := usecase.NewScheduleUsecase(config.DB, logConfig.MainLogger,
+		repository.NewScheduleRepository(logConfig.MainLogger), config.Blackouts)
+	if schedules := config.Config.Schedule; len(schedules) > 0 && config.Coordinator != nil {
+		if err := scheduleUsecase.Seed(context.Background(), schedules, time.Now()); err != nil {
+			logConfig.MainLogger.WithError(err).Error("Error seeding cron schedules")
+		}
+		tick := config.Config.Scheduler.CronTick
+		run := scheduledRun(config.Coordinator)
+		if config.Leader != nil {
+			config.Leader.OnElected(func(stop <-chan struct{}) {
+				scheduleUsecase.StartScheduling(tick, run, stop)
+			})
+		} else {
+			go scheduleUsecase.StartScheduling(tick, run, config.Stop)
+		}
+	}
+
+	var alertController *controller.AlertController
+	if config.Alerts != nil {
+		alertController = controller.NewAlertController(logConfig.MainLogger, config.Alerts)
+	}
+
+	var benchController *controller.BenchController
+	benchTargets, err := service.NewBenchTargets(config.Config.Bench.Targets)
+	if err != nil {
+		logConfig.MainLogger.WithError(err).Error("Invalid bench configuration, benchmark endpoint disabled")
+	} else {
+		benchController = controller.NewBenchController(logConfig.MainLogger, service.NewBenchRunner(benchTargets, model.BenchRequest{
+			Requests:    config.Config.Bench.Requests,
+			Concurrency: config.Config.Bench.Concurrency,
+			MaxID:       config.Config.Bench.MaxID,
+			Seed:        config.Config.Bench.Seed,
+		}))
+	}
+
+	// The synthetic commits of the batch experiment are stored here, without being published,
+	// announced or matched against keywords
+	var experimentController *controller.ExperimentController
+	if !config.Mode.PublishesItems() {
+		experimentCommits := usecase.NewCommitUsecase(config.DB, logConfig.CommitLogger, commitRepository)
+		experimentCommits.IDs = ids
+		experimentCommits.RowByRow = !features.Batching
+		experimentController = controller.NewExperimentController(logConfig.MainLogger,
+			experiment.NewBatchExperiment(logConfig.MainLogger, config.DB, experimentCommits,
+				config.Config.Experiment, ExperimentFile()))
+	}
+
+	metricsController := controller.NewMetricsController(logConfig.MainLogger, commitQueue, config.CollyStats,
+		config.Config.Metrics.WSInterval)
+
+	connection, err := config.DB.DB()
+	if err != nil {
+		logConfig.MainLogger.Fatalf("failed to get the database connection pool: %v", err)
+	}
+	slowQueries := slowQueryLog(config.DB)
+	if config.Alerts != nil {
+		config.Alerts.AddSource(databaseMetrics(connection, slowQueries))
+	}
+
+	selfCheck := service.NewScrapeSelfCheck(logConfig.MainLogger, config.Config.Scrape.SelfCheck, config.CollyPacer)
+	selfCheck.Token = config.Config.GitHub.Token
+	if config.Alerts != nil {
+		config.Alerts.AddSource(selfCheck.MetricValues)
+	}
+
+	graphQL, err := graph.NewHandler(logConfig.MainLogger, config.DB)
+	if err != nil {
+		logConfig.MainLogger.Fatalf("failed to parse the GraphQL schema: %v", err)
+	}
+
 	// Setup routes
 	route := route.RouteConfig{
-		App:               chi.NewRouter(),
-		RepoController:    repoController,
-		ReleaseController: releaseController,
-		CommitController:  commitController,
+		App:                   chi.NewRouter(),
+		RepoController:        repoController,
+		OrgController:         controller.NewOrgController(logConfig.RepoLogger, repoUsecase, orgScrape),
+		RepoPolicyController:  controller.NewRepoPolicyController(logConfig.MainLogger, repoPolicyUsecase),
+		ReleaseController:     releaseController,
+		ReleaseNoteController: controller.NewReleaseNoteController(logConfig.ReleaseLogger, releaseNoteUsecase),
+		CommitController:      commitController,
+		TagController:         tagController,
+		VisitController:       visitController,
+		ExportController:      exportController,
+		FeedController:        feedController,
+		StatsController:       statsController,
+		CrawlRunController:    crawlRunController,
+		CrawlErrorController:  crawlErrorController,
+		WatchlistController:   watchlistController,
+		OnboardController:     onboardController,
+		JobController:         jobController,
+		CoordinatorController: coordinatorController,
+		AlertController:       alertController,
+		BenchController:       benchController,
+		ExperimentController:  experimentController,
+		HealthController:      controller.NewHealthController(logConfig.MainLogger, backpressure),
+		MetricsController:     metricsController,
+		SelfCheckController:   controller.NewSelfCheckController(logConfig.MainLogger, selfCheck),
+		CacheController:       controller.NewCacheController(logConfig.MainLogger, caches),
+		BreakerController:     controller.NewBreakerController(logConfig.MainLogger, repoBreakers),
+		FeatureController:     controller.NewFeatureController(logConfig.MainLogger, features, commitQueue),
+		AdminController:       controller.NewAdminController(logConfig.MainLogger, config.Config.Redacted()),
+		DatabaseController:    controller.NewDatabaseController(logConfig.MainLogger, connection, slowQueries),
+		ScheduleController:    controller.NewScheduleController(logConfig.MainLogger, config.Blackouts, config.Coordinator, recrawlUsecase, scheduleUsecase),
+		GraphQL:               graphQL,
+	}
+	route.CrawlRuns = crawlRuns
+	if debug := config.Config.Server.Debug; debug.Query || debug.Always {
+		route.Debug = &debug
+	}
+	if config.Auth.Enabled() {
+		route.Auth = controller.NewAuthMiddleware(logConfig.MainLogger, config.Auth)
+	}
+	if signing := config.Config.Auth.Signing; signing.Secret != "" {
+		route.Signature = controller.NewSignatureMiddleware(logConfig.MainLogger,
+			auth.NewVerifier(signing.Secret, signing.MaxSkew), signing.Required)
+	}
+	if config.Config.RateLimit.Enabled {
+		route.RateLimit = newRateLimiter(logConfig.MainLogger, "default", config.Config.RateLimit.Default)
+		route.CrawlRateLimit = newRateLimiter(logConfig.MainLogger, "crawl", config.Config.RateLimit.Crawl)
+	}
+
+	if config.Config.Reload.Enabled {
+		reloader := &runtimeReloader{
+			log:               logConfig.MainLogger,
+			current:           config.Config,
+			collyLimit:        config.CollyLimit,
+			releaseController: releaseController,
+			commitController:  commitController,
+			repoBreakers:      repoBreakers,
+			commitQueue:       commitQueue,
+			rateLimit:         route.RateLimit,
+			crawlRateLimit:    route.CrawlRateLimit,
+			coordinator:       config.Coordinator,
+			connection:        connection,
+			slowQueries:       slowQueries,
+		}
+		WatchConfig(logConfig.MainLogger, reloader.apply)
+	}
+
+	if port := config.Config.GRPC.Port; port > 0 && config.Mode.ServesAPI() {
+		server := grpc.NewServer(logConfig.MainLogger, config.DB, config.Auth, crawlRuns,
+			route.RateLimit, route.CrawlRateLimit, backpressure, repoController, releaseController, commitController)
+		if err := server.Start(port, config.Stop); err != nil {
+			logConfig.MainLogger.Fatalf("failed to serve gRPC on port %d: %v", port, err)
+		}
 	}
 
 	r := route.Setup()
 	return r
 }
+
+// CoordinatorMetrics builds the coordinator's own /metrics and /healthz handler, served on
+// coordinator.metrics_addr apart from the crawler API
+func CoordinatorMetrics(log *logrus.Logger, coordinator *service.CrawlingCoordinator) *chi.Mux {
+	return route.SetupCoordinatorMetrics(controller.NewCoordinatorController(log, coordinator))
+}
+
+// newRateLimiter builds the limit "rate_limit.<name>"; a limit that is not configured stays off
+func newRateLimiter(log *logrus.Logger, name string, limit *controller.RateLimit) *controller.RateLimiter {
+	if limit == nil {
+		return nil
+	}
+
+	limiter, err := controller.NewRateLimiter(log, name, *limit)
+	if err != nil {
+		log.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+	return limiter
+}
+
+// inProcessAPI serves the crawl and summary paths of the default coordinator stages
+// from the controllers, without the coordinator calling this process over HTTP. Each crawl
+// is recorded in crawl_runs as a coordinator run.
+func inProcessAPI(repoController *controller.RepoController, releaseController *controller.ReleaseController,
+	commitController *controller.CommitController, statsUsecase *usecase.StatsUsecase,
+	crawlRuns *controller.CrawlRunRecorder) service.InProcessAPI {
+	return service.InProcessAPI{
+		Crawls: map[string]func(ctx context.Context) (int, error){
+			"/repos/crawl": recordedCrawl(crawlRuns, "/repos/crawl", func(ctx context.Context) (int, error) {
+				repos, err := repoController.CrawlRepos(ctx, scrape.DefaultRepoLimit, scrape.DefaultRepoPages)
+				return len(repos), err
+			}),
+			"/releases/crawl": recordedCrawl(crawlRuns, "/releases/crawl", func(ctx context.Context) (int, error) {
+				releases, err := releaseController.CrawlReleases(ctx, 0, 0)
+				return len(releases), err
+			}),
+			"/commits/crawl": recordedCrawl(crawlRuns, "/commits/crawl", func(ctx context.Context) (int, error) {
+				result, err := commitController.CrawlCommits(ctx, 0)
+				if result == nil {
+					return 0, err
+				}
+				return result.Saved, err
+			}),
+		},
+		Summaries: map[string]func(ctx context.Context) (*service.StageSummary, error){
+			"/repos/summary":    stageSummary(statsUsecase, usecase.DatasetRepos),
+			"/releases/summary": stageSummary(statsUsecase, usecase.DatasetReleases),
+			"/commits/summary":  stageSummary(statsUsecase, usecase.DatasetCommits),
+		},
+	}
+}
+
+// recordedCrawl records an in-process crawl of the coordinator under "in_process <path>"
+func recordedCrawl(crawlRuns *controller.CrawlRunRecorder, path string,
+	crawl func(ctx context.Context) (int, error)) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		var items int
+		err := crawlRuns.Run(ctx, entity.CrawlSourceCoordinator, "in_process "+path, func(ctx context.Context) error {
+			var err error
+			items, err = crawl(ctx)
+			return err
+		})
+		return items, err
+	}
+}
+
+// scheduledRun runs a cron schedule: a full coordinator cycle for "all", otherwise its stage
+func scheduledRun(coordinator *service.CrawlingCoordinator) func(name string) error {
+	return func(name string) error {
+		if name == usecase.ScheduleAll {
+			coordinator.CrawlAll()
+			return nil
+		}
+		return coordinator.RunScheduledStage(name)
+	}
+}
+
+// stageSummary returns the summary of a dataset in the form the coordinator caches
+func stageSummary(statsUsecase *usecase.StatsUsecase, dataset string) func(ctx context.Context) (*service.StageSummary, error) {
+	return func(ctx context.Context) (*service.StageSummary, error) {
+		summary, err := statsUsecase.Summary(ctx, dataset)
+		if err != nil {
+			return nil, err
+		}
+
+		stageSummary := &service.StageSummary{Count: summary.Count, Checksum: summary.Checksum}
+		if len(summary.Repos) > 0 {
+			stageSummary.Repos = make(map[int64]service.RepoSummary, len(summary.Repos))
+			for id, repo := range summary.Repos {
+				stageSummary.Repos[id] = service.RepoSummary{Count: repo.Count, LatestTag: repo.LatestTag}
+			}
+		}
+		return stageSummary, nil
+	}
+}
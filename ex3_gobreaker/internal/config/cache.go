@@ -0,0 +1,47 @@
+package config
+
+import (
+	"crawler/baseline/internal/cache"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// newResponseCache returns the cache of an entity type, purged on every write to its tables, or
+// nil when it is disabled
+func newResponseCache[V any](log *logrus.Logger, db *gorm.DB, name string, settings EntityCacheSettings,
+	tables ...string) *cache.LRU[int64, V] {
+	if !settings.Enabled {
+		return nil
+	}
+
+	responses := cache.NewLRU[int64, V](settings.MaxEntries, settings.TTL)
+	if err := cache.PurgeOnWrite(db, name, responses.Purge, tables...); err != nil {
+		log.WithError(err).WithField("cache", name).Error("Error watching writes, response cache disabled")
+		return nil
+	}
+	log.WithFields(logrus.Fields{
+		"cache":       name,
+		"max_entries": settings.MaxEntries,
+		"ttl":         settings.TTL,
+	}).Info("Caching responses")
+	return responses
+}
+
+// cacheMetrics exposes the counters of the response caches to the alert rules, as
+// "cache.<entity>.hits", ".misses", ".evictions", ".invalidations" and ".entries"
+func cacheMetrics(caches map[string]cache.StatsSource) func() map[string]float64 {
+	return func() map[string]float64 {
+		values := make(map[string]float64, 5*len(caches))
+		for name, source := range caches {
+			stats := source.Stats()
+			prefix := "cache." + name + "."
+			values[prefix+"hits"] = float64(stats.Hits)
+			values[prefix+"misses"] = float64(stats.Misses)
+			values[prefix+"evictions"] = float64(stats.Evictions)
+			values[prefix+"invalidations"] = float64(stats.Invalidations)
+			values[prefix+"entries"] = float64(stats.Entries)
+		}
+		return values
+	}
+}
@@ -1,16 +1,91 @@
 package config
 
 import (
+	"crawler/baseline/internal/archive"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/utils"
+	"net/http"
+	"os"
+
 	"github.com/gocolly/colly/v2"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
-func NewColly(viper *viper.Viper, log *logrus.Logger) *colly.Collector {
+// NewColly creates the shared collector, sending colly.parallelism requests at once through the
+// returned limit, which a config reload resizes. The returned pacer spaces out the requests about
+// the repositories whose policy sets requests per minute, and the returned stats count the
+// requests for the live metrics. It also applies scrape.selectors, which every collector of the
+// process reads pages with.
+func NewColly(config *Config, log *logrus.Logger, db *gorm.DB, notifier notifier.Notifier) (*colly.Collector,
+	*scrape.ParallelismLimit, *scrape.RepoPacer, *scrape.RequestStats) {
 	c := colly.NewCollector(
 		colly.Async(true),
 	)
-	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 4})
 
-	return c
+	if err := utils.SetSelectors(config.Scrape.Selectors); err != nil {
+		log.Fatalf("Invalid scrape.selectors: %v", err)
+	}
+	if len(config.Scrape.Selectors) > 0 {
+		log.WithField("data_points", len(config.Scrape.Selectors)).Info("Using configured scraper selectors")
+	}
+
+	// Only the requests sent to GitHub are hedged, not the pages served back
+	hedge := scrape.NewHedgeTransport(http.DefaultTransport)
+	var transport http.RoundTripper = hedge
+	switch dir := config.Scrape.RecordDir; {
+	case config.Scrape.Fixtures != "":
+		log.WithField("dir", config.Scrape.Fixtures).Warn("Serving recorded fixtures instead of GitHub")
+		transport = scrape.NewFixtureTransport(config.Scrape.Fixtures)
+	case dir != "" && config.Scrape.Replay:
+		log.WithField("dir", dir).Warn("Replaying recorded pages instead of GitHub")
+		transport = scrape.NewReplayTransport(dir)
+	case config.Scrape.Replay:
+		log.WithFields(logrus.Fields{
+			"bucket": config.Scrape.Archive.Bucket,
+			"at":     config.Scrape.Archive.ReplayAt,
+		}).Warn("Replaying archived pages instead of GitHub")
+		transport = scrape.NewArchiveReplayTransport(archive.New(config.Scrape.Archive), config.Scrape.Archive.ReplayTime())
+	case dir != "":
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Fatalf("Failed to create record directory %s: %v", dir, err)
+		}
+		log.WithField("dir", dir).Info("Recording fetched pages")
+		transport = scrape.NewRecordTransport(dir, transport)
+	}
+
+	// Record fetched URLs in the visits table, all of them unless a sample rate is set
+	if config.Visits.Enabled {
+		sampleRate := 1.0
+		if config.Visits.SampleRate != nil {
+			sampleRate = *config.Visits.SampleRate
+		}
+		recorder := scrape.NewVisitRecorder(log, db, transport, sampleRate)
+		recorder.Notifier = notifier
+		transport = recorder
+	}
+
+	// The cache goes above the recorder so that the visits show the 304 responses
+	if cache := config.Scrape.PageCache; cache.Enabled {
+		log.WithField("retention", cache.Retention).Info("Fetching cached pages with conditional requests")
+		transport = scrape.NewPageCache(log, db, transport, int64(cache.MaxPageKB)*1024, cache.Retention)
+	}
+
+	// The archive goes above the cache so that it keeps the pages the scrapers read
+	if settings := config.Scrape.Archive; settings.Enabled && !config.Scrape.Replay && config.Scrape.Fixtures == "" {
+		log.WithFields(logrus.Fields{
+			"bucket": settings.Bucket,
+			"prefix": settings.Prefix,
+		}).Info("Archiving fetched pages")
+		transport = scrape.NewArchiveTransport(log, archive.New(settings), transport)
+	}
+
+	stats := scrape.NewRequestStats(transport)
+	limit := scrape.NewParallelismLimit(stats, config.Colly.Parallelism)
+	// A hedged request waits for a slot too, so hedging does not raise the parallelism
+	hedge.Limit = limit
+	pacer := scrape.NewRepoPacer(limit)
+	c.WithTransport(pacer)
+	return c, limit, pacer, stats
 }
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"crawler/baseline/internal/queue"
+	"crawler/baseline/internal/usecase"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newCommitQueue starts the commit queue of features.queueing, stopped once stop is closed
+func newCommitQueue(log *logrus.Logger, commitUsecase *usecase.CommitUsecase, settings queue.Settings,
+	stop <-chan struct{}) *queue.CommitQueueProcessor {
+	commitQueue := queue.NewCommitQueueProcessor(log, commitUsecase, settings)
+	commitQueue.Start()
+	if stop != nil {
+		go func() {
+			<-stop
+			commitQueue.Stop()
+		}()
+	}
+	return commitQueue
+}
+
+// queueMetrics exposes the commit queue to the alert rules, as "queue.commits.size",
+// ".processing", ".enqueued", ".created", ".skipped", ".failed", ".dropped", ".rejected" and ".deduped"
+func queueMetrics(commitQueue *queue.CommitQueueProcessor) func() map[string]float64 {
+	return func() map[string]float64 {
+		snapshot := commitQueue.Snapshot()
+		prefix := "queue." + snapshot.Name + "."
+		return map[string]float64{
+			prefix + "size":       float64(snapshot.Size),
+			prefix + "processing": float64(snapshot.Processing),
+			prefix + "enqueued":   float64(snapshot.Enqueued),
+			prefix + "created":    float64(snapshot.Created),
+			prefix + "skipped":    float64(snapshot.Skipped),
+			prefix + "failed":     float64(snapshot.Failed),
+			prefix + "dropped":    float64(snapshot.Dropped),
+			prefix + "rejected":   float64(snapshot.Rejected),
+			prefix + "deduped":    float64(snapshot.Deduped),
+		}
+	}
+}
@@ -0,0 +1,760 @@
+package config
+
+import (
+	"crawler/baseline/internal/archive"
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/budget"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/experiment"
+	"crawler/baseline/internal/http/controller"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/kafka"
+	"crawler/baseline/internal/nats"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/queue"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// How the coordinator reaches the crawl stages, set by "coordinator.mode"
+const (
+	// CoordinatorHTTP calls the crawler API at coordinator.api_url
+	CoordinatorHTTP = "http"
+	// CoordinatorInProcess calls the crawl code of this process directly
+	CoordinatorInProcess = "in_process"
+)
+
+// Config is the typed content of config.json. It is decoded and validated once at startup
+// by NewConfig, and every component takes its section from it.
+type Config struct {
+	App         AppSettings                    `mapstructure:"app" json:"app"`
+	Server      ServerSettings                 `mapstructure:"server" json:"server"`
+	GRPC        GRPCSettings                   `mapstructure:"grpc" json:"grpc"`
+	Log         LogSettings                    `mapstructure:"log" json:"log"`
+	Database    DatabaseSettings               `mapstructure:"database" json:"database"`
+	GitHub      GitHubSettings                 `mapstructure:"github" json:"github"`
+	Colly       CollySettings                  `mapstructure:"colly" json:"colly"`
+	Scrape      ScrapeSettings                 `mapstructure:"scrape" json:"scrape"`
+	Crawl       CrawlSettings                  `mapstructure:"crawl" json:"crawl"`
+	Features    controller.Features            `mapstructure:"features" json:"features"`
+	Queue       queue.Settings                 `mapstructure:"queue" json:"queue"`
+	Outbox      usecase.OutboxSettings         `mapstructure:"outbox" json:"outbox"`
+	Kafka       kafka.Settings                 `mapstructure:"kafka" json:"kafka"`
+	NATS        nats.Settings                  `mapstructure:"nats" json:"nats"`
+	Visits      VisitsSettings                 `mapstructure:"visits" json:"visits"`
+	Notes       ReleaseNotesSettings           `mapstructure:"release_notes" json:"release_notes"`
+	Cache       CacheSettings                  `mapstructure:"cache" json:"cache"`
+	Policies    map[string]service.CrawlPolicy `mapstructure:"policies" json:"policies"`
+	Notifiers   notifier.Settings              `mapstructure:"notifiers" json:"notifiers"`
+	Webhooks    []notifier.WebhookSubscription `mapstructure:"webhooks" json:"webhooks"`
+	Digest      DigestSettings                 `mapstructure:"digest" json:"digest"`
+	Alerts      AlertSettings                  `mapstructure:"alerts" json:"alerts"`
+	Auth        AuthSettings                   `mapstructure:"auth" json:"auth"`
+	RateLimit   RateLimitSettings              `mapstructure:"rate_limit" json:"rate_limit"`
+	Jobs        JobSettings                    `mapstructure:"jobs" json:"jobs"`
+	Scheduler   SchedulerSettings              `mapstructure:"scheduler" json:"scheduler"`
+	Coordinator CoordinatorSettings            `mapstructure:"coordinator" json:"coordinator"`
+	Bench       BenchSettings                  `mapstructure:"bench" json:"bench"`
+	Experiment  experiment.Settings            `mapstructure:"experiment" json:"experiment"`
+	Metrics     MetricsSettings                `mapstructure:"metrics" json:"metrics"`
+	Reload      ReloadSettings                 `mapstructure:"reload" json:"reload"`
+
+	// RepoPolicies bound the crawl of large repositories; a policy stored through the API
+	// replaces the entry of its repository
+	RepoPolicies []service.ConfiguredRepoPolicy `mapstructure:"repo_policies" json:"repo_policies"`
+
+	// Schedule holds cron expressions by coordinator stage, or "all" for full cycles. It replaces
+	// the 60-second coordinator cycle; expressions edited through the API win over these.
+	Schedule map[string]string `mapstructure:"schedule" json:"schedule"`
+}
+
+type AppSettings struct {
+	Name string `mapstructure:"name" json:"name"`
+}
+
+type ServerSettings struct {
+	// Addr is the HTTP listen address, ":8081" by default
+	Addr string `mapstructure:"addr" json:"addr"`
+	// Debug pretty-prints JSON responses with timing and the request ID, off by default
+	Debug controller.DebugMode `mapstructure:"debug" json:"debug"`
+}
+
+type GRPCSettings struct {
+	// Port serves the gRPC services of proto/crawler.proto on this port when set, off by default
+	Port int `mapstructure:"port" json:"port"`
+}
+
+type LogSettings struct {
+	// Level is a logrus level name, such as "info" or "debug", or its number from 0 (panic)
+	// to 6 (trace); debug also logs every page the scrapers visit
+	Level string `mapstructure:"level" json:"level"`
+	// Format is LogFormatJSON (default) or LogFormatText
+	Format string `mapstructure:"format" json:"format"`
+	// Output is LogOutputStdout, LogOutputStderr, LogOutputFile or LogOutputBoth; empty keeps
+	// the default of each logger, stdout for the main logger and both for the crawl loggers
+	Output string `mapstructure:"output" json:"output"`
+}
+
+type DatabaseSettings struct {
+	// Driver is "postgres" (default) or "sqlite", which keeps everything in the file at Path
+	Driver   string       `mapstructure:"driver" json:"driver"`
+	Path     string       `mapstructure:"path" json:"path"`
+	Username string       `mapstructure:"username" json:"username"`
+	Password string       `mapstructure:"password" json:"password"`
+	Host     string       `mapstructure:"host" json:"host"`
+	Port     int          `mapstructure:"port" json:"port"`
+	Name     string       `mapstructure:"name" json:"name"`
+	Pool     PoolSettings `mapstructure:"pool" json:"pool"`
+	// DSN, usually from CRAWLER_DATABASE_DSN, replaces the connection settings of PostgreSQL
+	DSN string `mapstructure:"dsn" json:"dsn"`
+	// Compression stores big release contents compressed
+	Compression CompressionSettings `mapstructure:"compression" json:"compression"`
+	// Spool buffers crawl results on disk while the database is unreachable
+	Spool SpoolSettings `mapstructure:"spool" json:"spool"`
+	// IDs chooses how the keys of crawled data are assigned
+	IDs IDSettings `mapstructure:"ids" json:"ids"`
+	// QueryTimeout bounds each repository call besides the deadline of its request, zero for
+	// no bound. On PostgreSQL it is also the statement_timeout of the connections.
+	QueryTimeout time.Duration `mapstructure:"query_timeout" json:"query_timeout"`
+	// SlowQuery logs the statements running longer than its threshold
+	SlowQuery SlowQuerySettings `mapstructure:"slow_query" json:"slow_query"`
+}
+
+type PoolSettings struct {
+	Idle int `mapstructure:"idle" json:"idle"`
+	Max  int `mapstructure:"max" json:"max"`
+	// Lifetime is the maximum connection lifetime in seconds
+	Lifetime int `mapstructure:"lifetime" json:"lifetime"`
+	// IdleTime is how long, in seconds, a connection stays idle before it is closed; zero keeps it
+	IdleTime int `mapstructure:"idle_time" json:"idle_time"`
+}
+
+type SlowQuerySettings struct {
+	// Threshold is the duration from which a statement is logged and counted; zero turns it off
+	Threshold time.Duration `mapstructure:"threshold" json:"threshold"`
+	// Keep is how many of the latest slow statements GET /api/admin/database returns, 50 by default
+	Keep int `mapstructure:"keep" json:"keep"`
+}
+
+type CompressionSettings struct {
+	// Algorithm is "none" (default) or "gzip"
+	Algorithm string `mapstructure:"algorithm" json:"algorithm"`
+	// MinSize is the content size in bytes from which contents are compressed, 1024 by default
+	MinSize int `mapstructure:"min_size" json:"min_size"`
+}
+
+type SpoolSettings struct {
+	// Dir holds the buffered batches; empty disables the spool, so crawl results are lost
+	// while the database is unreachable
+	Dir string `mapstructure:"dir" json:"dir"`
+	// MaxBytes bounds the size of the buffered batches, 256 MiB by default
+	MaxBytes int64 `mapstructure:"max_bytes" json:"max_bytes"`
+	// ReplayInterval is how often the database is checked to store the buffered batches
+	ReplayInterval time.Duration `mapstructure:"replay_interval" json:"replay_interval"`
+}
+
+type IDSettings struct {
+	// Strategy is "database" (default), which uses the table sequences, or "snowflake", which
+	// generates time-ordered IDs in the application so several writers can share a database
+	Strategy string `mapstructure:"strategy" json:"strategy"`
+	// Node tells apart the writers generating snowflake IDs, from 0 to 1023; each needs its own
+	Node int64 `mapstructure:"node" json:"node"`
+}
+
+type GitHubSettings struct {
+	Token string `mapstructure:"token" json:"token"`
+}
+
+type CollySettings struct {
+	// Parallelism is how many requests the collector sends at once, 4 by default
+	Parallelism int `mapstructure:"parallelism" json:"parallelism"`
+}
+
+type ScrapeSettings struct {
+	CommitStats bool `mapstructure:"commit_stats" json:"commit_stats"`
+	// Fixtures is a directory of recorded pages served instead of GitHub, see scrape.FixtureTransport
+	Fixtures string `mapstructure:"fixtures" json:"fixtures"`
+	// RecordDir saves every fetched page, or serves them back when Replay is set,
+	// see scrape.RecordTransport and scrape.ReplayTransport
+	RecordDir string `mapstructure:"record_dir" json:"record_dir"`
+	// Replay without RecordDir serves the pages of Archive, see scrape.ArchiveReplayTransport
+	Replay bool `mapstructure:"replay" json:"replay"`
+	// Archive keeps every fetched page in an object storage, see scrape.ArchiveTransport
+	Archive archive.Settings `mapstructure:"archive" json:"archive"`
+
+	// Selectors override the fallback chains of the selectors the scrapers read pages with,
+	// keyed by data point, see utils.Selectors
+	Selectors utils.Selectors `mapstructure:"selectors" json:"selectors"`
+	// PageCache fetches the pages crawled before with conditional requests, see scrape.PageCache
+	PageCache PageCacheSettings `mapstructure:"page_cache" json:"page_cache"`
+	// SelfCheck is the repository and release GET /api/scrape/selfcheck scrapes,
+	// gocolly/colly at v2.1.0 by default
+	SelfCheck service.SelfCheckTarget `mapstructure:"selfcheck" json:"selfcheck"`
+}
+
+type PageCacheSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// MaxPageKB is the size above which a page is not cached, 2048 by default
+	MaxPageKB int `mapstructure:"max_page_kb" json:"max_page_kb"`
+	// Retention is how long a page GitHub has not confirmed is kept, 168h by default
+	Retention time.Duration `mapstructure:"retention" json:"retention"`
+}
+
+type CrawlSettings struct {
+	// RepoConcurrency is how many repositories a release crawl scrapes at once, 4 by default;
+	// colly.parallelism still bounds the requests they send together
+	RepoConcurrency int `mapstructure:"repo_concurrency" json:"repo_concurrency"`
+	// ReleaseConcurrency is how many releases a commit crawl scrapes at once, 4 by default
+	ReleaseConcurrency int `mapstructure:"release_concurrency" json:"release_concurrency"`
+	// Budgets bounds the scraping goroutines of the releases and commits stages, none by default
+	Budgets map[string]BudgetSettings `mapstructure:"budgets" json:"budgets"`
+	// RepoBreaker gives each repository its own circuit breaker in the release and commit crawls
+	RepoBreaker RepoBreakerSettings `mapstructure:"repo_breaker" json:"repo_breaker"`
+	// NotFoundThreshold is how many crawls answered 404 in a row archive a repository, 3 by default
+	NotFoundThreshold int `mapstructure:"not_found_threshold" json:"not_found_threshold"`
+}
+
+// RepoBreakerSettings tune the per-repository circuit breakers, which skip a repository failing
+// on every scrape, such as a deleted or renamed one, without tripping the stage breaker for all
+type RepoBreakerSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// IdleTTL is how long the breaker of a repository that is not crawled is kept, 1h by default
+	IdleTTL               time.Duration `mapstructure:"idle_ttl" json:"idle_ttl"`
+	utils.BreakerSettings `mapstructure:",squash"`
+}
+
+// BudgetSettings is the budget of a crawl stage; zero leaves a limit off
+type BudgetSettings struct {
+	MaxGoroutines int `mapstructure:"max_goroutines" json:"max_goroutines"`
+	// MaxHeapMB is the heap of the process above which the stage starts no goroutine
+	MaxHeapMB int `mapstructure:"max_heap_mb" json:"max_heap_mb"`
+}
+
+// BudgetLimits returns the limits of the configured stage budgets
+func (c CrawlSettings) BudgetLimits() map[string]budget.Limits {
+	limits := make(map[string]budget.Limits, len(c.Budgets))
+	for stage, settings := range c.Budgets {
+		limits[stage] = budget.Limits{
+			MaxGoroutines: settings.MaxGoroutines,
+			MaxHeapBytes:  uint64(settings.MaxHeapMB) << 20,
+		}
+	}
+	return limits
+}
+
+type VisitsSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// SampleRate is the share of visits recorded, from 0 to 1; all of them by default
+	SampleRate *float64 `mapstructure:"sample_rate" json:"sample_rate"`
+}
+
+// ReleaseNotesSettings stores the notes of new releases parsed into the release_note_items,
+// release_note_references and release_note_mentions tables. Without them the structured notes
+// are parsed on each request.
+type ReleaseNotesSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+}
+
+// CacheSettings caches in memory the responses of GET /api/repos/{repoID},
+// /api/releases/{releaseID} and /api/commits/{commitID}, by entity type
+type CacheSettings struct {
+	Repo    EntityCacheSettings `mapstructure:"repo" json:"repo"`
+	Release EntityCacheSettings `mapstructure:"release" json:"release"`
+	Commit  EntityCacheSettings `mapstructure:"commit" json:"commit"`
+}
+
+type EntityCacheSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// MaxEntries is how many responses are kept, 1000 by default
+	MaxEntries int `mapstructure:"max_entries" json:"max_entries"`
+	// TTL is how long a response is served, 1m by default; writes of other instances are only
+	// seen once it expires
+	TTL time.Duration `mapstructure:"ttl" json:"ttl"`
+}
+
+type DigestSettings struct {
+	Enabled bool          `mapstructure:"enabled" json:"enabled"`
+	Period  time.Duration `mapstructure:"period" json:"period"`
+}
+
+type AlertSettings struct {
+	Interval time.Duration       `mapstructure:"interval" json:"interval"`
+	Rules    []service.AlertRule `mapstructure:"rules" json:"rules"`
+}
+
+type AuthSettings struct {
+	Enabled bool            `mapstructure:"enabled" json:"enabled"`
+	Keys    []auth.APIKey   `mapstructure:"keys" json:"keys"`
+	Signing SigningSettings `mapstructure:"signing" json:"signing"`
+}
+
+// SigningSettings configures the HMAC signatures of the coordinator's calls to the crawler API.
+// The coordinator signs with Secret and the API verifies with it; with Required, crawl
+// triggers only accept signed requests. Signatures older or newer than MaxSkew are rejected.
+type SigningSettings struct {
+	Secret   string        `mapstructure:"secret" json:"secret"`
+	Required bool          `mapstructure:"required" json:"required"`
+	MaxSkew  time.Duration `mapstructure:"max_skew" json:"max_skew"`
+}
+
+type RateLimitSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// Default applies to every request, Crawl to operator routes too; a missing limit is off
+	Default *controller.RateLimit `mapstructure:"default" json:"default"`
+	Crawl   *controller.RateLimit `mapstructure:"crawl" json:"crawl"`
+}
+
+type JobSettings struct {
+	// Backend is "memory" (default) or "db", the shared queue that split run modes always use
+	Backend      string        `mapstructure:"backend" json:"backend"`
+	Workers      int           `mapstructure:"workers" json:"workers"`
+	PollInterval time.Duration `mapstructure:"poll_interval" json:"poll_interval"`
+	WorkerID     string        `mapstructure:"worker_id" json:"worker_id"`
+	// MaxAttempts is how many times a job orphaned by a crashed worker is claimed before it
+	// is failed instead of requeued, 3 by default
+	MaxAttempts int `mapstructure:"max_attempts" json:"max_attempts"`
+}
+
+type SchedulerSettings struct {
+	LeaderElection   bool          `mapstructure:"leader_election" json:"leader_election"`
+	LockName         string        `mapstructure:"lock_name" json:"lock_name"`
+	ElectionInterval time.Duration `mapstructure:"election_interval" json:"election_interval"`
+	Decay            DecaySettings `mapstructure:"decay" json:"decay"`
+	// Blackouts are the daily windows without crawling, read in Timezone (the server's by default)
+	Blackouts []service.BlackoutWindow `mapstructure:"blackouts" json:"blackouts"`
+	Timezone  string                   `mapstructure:"timezone" json:"timezone"`
+	// CronTick is how often the cron schedules are checked for due runs
+	CronTick time.Duration `mapstructure:"cron_tick" json:"cron_tick"`
+}
+
+// DecaySettings replaces the coordinator's periodic crawl cycle with per-repository re-crawls
+// spaced out on a decay curve; every Tick up to Batch due repositories are re-crawled
+type DecaySettings struct {
+	Enabled            bool          `mapstructure:"enabled" json:"enabled"`
+	Tick               time.Duration `mapstructure:"tick" json:"tick"`
+	Batch              int           `mapstructure:"batch" json:"batch"`
+	service.DecayCurve `mapstructure:",squash"`
+}
+
+type CoordinatorSettings struct {
+	Mode   string `mapstructure:"mode" json:"mode"`
+	APIURL string `mapstructure:"api_url" json:"api_url"`
+	APIKey string `mapstructure:"api_key" json:"api_key"`
+	// StabilityThreshold is nil to keep the coordinator's default
+	StabilityThreshold *int          `mapstructure:"stability_threshold" json:"stability_threshold"`
+	MaxPause           time.Duration `mapstructure:"max_pause" json:"max_pause"`
+	// Breaker tunes the circuit breaker of every stage
+	Breaker utils.BreakerSettings `mapstructure:"breaker" json:"breaker"`
+	// MetricsAddr serves the coordinator's /metrics and /healthz, such as ":9091"; empty disables it
+	MetricsAddr string                `mapstructure:"metrics_addr" json:"metrics_addr"`
+	Stages      []service.StageConfig `mapstructure:"stages" json:"stages"`
+}
+
+// ReloadSettings watches the config files and applies the runtime settings when they change,
+// without restarting the server
+type ReloadSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+}
+
+type BenchSettings struct {
+	Requests    int                   `mapstructure:"requests" json:"requests"`
+	Concurrency int                   `mapstructure:"concurrency" json:"concurrency"`
+	MaxID       int                   `mapstructure:"max_id" json:"max_id"`
+	Seed        int64                 `mapstructure:"seed" json:"seed"`
+	Targets     []service.BenchTarget `mapstructure:"targets" json:"targets"`
+}
+
+// MetricsSettings tune the live metrics stream of /ws/metrics
+type MetricsSettings struct {
+	// WSInterval is how often a sample is pushed to the clients, 5s by default
+	WSInterval time.Duration `mapstructure:"ws_interval" json:"ws_interval"`
+}
+
+// NewConfig decodes the config read by NewViper, fills in defaults and validates it
+func NewConfig(v *viper.Viper) (*Config, error) {
+	// Features absent from the files keep these
+	config := &Config{Features: controller.Features{Batching: true, CircuitBreaking: true}}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.Server.Addr == "" {
+		c.Server.Addr = ":8081"
+	}
+	if c.Database.Driver == "" {
+		c.Database.Driver = "postgres"
+	}
+	if c.Database.Compression.Algorithm == "" {
+		c.Database.Compression.Algorithm = entity.CompressionNone
+	}
+	if c.Database.Compression.MinSize <= 0 {
+		c.Database.Compression.MinSize = 1024
+	}
+	if c.Colly.Parallelism <= 0 {
+		c.Colly.Parallelism = 4
+	}
+	if c.Scrape.PageCache.MaxPageKB <= 0 {
+		c.Scrape.PageCache.MaxPageKB = 2048
+	}
+	if c.Scrape.PageCache.Retention <= 0 {
+		c.Scrape.PageCache.Retention = 7 * 24 * time.Hour
+	}
+	c.Scrape.Archive = c.Scrape.Archive.WithDefaults()
+	if c.Scrape.SelfCheck.Repo == "" {
+		c.Scrape.SelfCheck = service.SelfCheckTarget{Repo: "gocolly/colly", Tag: "v2.1.0"}
+	}
+	for _, cache := range []*EntityCacheSettings{&c.Cache.Repo, &c.Cache.Release, &c.Cache.Commit} {
+		if cache.MaxEntries <= 0 {
+			cache.MaxEntries = 1000
+		}
+		if cache.TTL <= 0 {
+			cache.TTL = time.Minute
+		}
+	}
+	if c.Digest.Period <= 0 {
+		c.Digest.Period = 24 * time.Hour
+	}
+	if c.Alerts.Interval <= 0 {
+		c.Alerts.Interval = time.Minute
+	}
+	if c.Jobs.Backend == "" {
+		c.Jobs.Backend = "memory"
+	}
+	if c.Jobs.Workers <= 0 {
+		c.Jobs.Workers = 2
+	}
+	if c.Jobs.PollInterval <= 0 {
+		c.Jobs.PollInterval = 2 * time.Second
+	}
+	if c.Jobs.MaxAttempts <= 0 {
+		c.Jobs.MaxAttempts = 3
+	}
+	if c.Auth.Signing.MaxSkew <= 0 {
+		c.Auth.Signing.MaxSkew = 5 * time.Minute
+	}
+	if c.Crawl.RepoConcurrency <= 0 {
+		c.Crawl.RepoConcurrency = 4
+	}
+	if c.Crawl.ReleaseConcurrency <= 0 {
+		c.Crawl.ReleaseConcurrency = 4
+	}
+	if c.Crawl.RepoBreaker.IdleTTL == 0 {
+		c.Crawl.RepoBreaker.IdleTTL = time.Hour
+	}
+	if c.Crawl.NotFoundThreshold == 0 {
+		c.Crawl.NotFoundThreshold = entity.DefaultNotFoundThreshold
+	}
+	c.Queue = c.Queue.WithDefaults()
+	c.Experiment = c.Experiment.WithDefaults()
+	if c.Metrics.WSInterval == 0 {
+		c.Metrics.WSInterval = 5 * time.Second
+	}
+	c.Outbox = c.Outbox.WithDefaults()
+	c.Kafka = c.Kafka.WithDefaults()
+	c.NATS = c.NATS.WithDefaults()
+	if c.Database.Spool.MaxBytes <= 0 {
+		c.Database.Spool.MaxBytes = 256 << 20
+	}
+	if c.Database.Spool.ReplayInterval <= 0 {
+		c.Database.Spool.ReplayInterval = 30 * time.Second
+	}
+	if c.Database.SlowQuery.Keep <= 0 {
+		c.Database.SlowQuery.Keep = 50
+	}
+	if c.Database.IDs.Strategy == "" {
+		c.Database.IDs.Strategy = idgen.StrategyDatabase
+	}
+	if c.Scheduler.LockName == "" {
+		c.Scheduler.LockName = "crawler-scheduler"
+	}
+	if c.Scheduler.ElectionInterval <= 0 {
+		c.Scheduler.ElectionInterval = 10 * time.Second
+	}
+	if c.Scheduler.CronTick <= 0 {
+		c.Scheduler.CronTick = 30 * time.Second
+	}
+	if c.Scheduler.Decay.Tick <= 0 {
+		c.Scheduler.Decay.Tick = time.Minute
+	}
+	if c.Scheduler.Decay.Batch <= 0 {
+		c.Scheduler.Decay.Batch = 20
+	}
+	curve := service.DefaultDecayCurve()
+	if c.Scheduler.Decay.MinInterval <= 0 {
+		c.Scheduler.Decay.MinInterval = curve.MinInterval
+	}
+	if c.Scheduler.Decay.MaxInterval <= 0 {
+		c.Scheduler.Decay.MaxInterval = curve.MaxInterval
+	}
+	if c.Scheduler.Decay.HalfLife <= 0 {
+		c.Scheduler.Decay.HalfLife = curve.HalfLife
+	}
+	if c.Coordinator.Mode == "" {
+		c.Coordinator.Mode = CoordinatorHTTP
+	}
+	if c.Coordinator.APIURL == "" {
+		c.Coordinator.APIURL = "http://localhost:8081/api"
+	}
+}
+
+// Validate checks the settings that have no constructor of their own to reject them;
+// the list sections (stages, alert rules, keys, ...) are validated by their constructors
+func (c *Config) Validate() error {
+	var errs []error
+	if _, err := ParseLogLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	switch c.Log.Format {
+	case "", LogFormatJSON, LogFormatText:
+	default:
+		errs = append(errs, fmt.Errorf("unknown log.format %q, expected %s or %s", c.Log.Format, LogFormatJSON, LogFormatText))
+	}
+	switch c.Log.Output {
+	case "", LogOutputStdout, LogOutputStderr, LogOutputFile, LogOutputBoth:
+	default:
+		errs = append(errs, fmt.Errorf("unknown log.output %q, expected %s, %s, %s or %s", c.Log.Output,
+			LogOutputStdout, LogOutputStderr, LogOutputFile, LogOutputBoth))
+	}
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.DSN == "" && (c.Database.Host == "" || c.Database.Port <= 0 || c.Database.Name == "") {
+			errs = append(errs, errors.New("database needs a dsn, or a host, port and name"))
+		}
+	case "sqlite":
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path is required for sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown database.driver %q", c.Database.Driver))
+	}
+	if c.Database.Pool.Idle < 0 || c.Database.Pool.Max < 0 || c.Database.Pool.Lifetime < 0 || c.Database.Pool.IdleTime < 0 {
+		errs = append(errs, errors.New("database.pool values must not be negative"))
+	}
+	if c.Database.QueryTimeout < 0 {
+		errs = append(errs, errors.New("database.query_timeout must not be negative"))
+	}
+	if c.Database.SlowQuery.Threshold < 0 {
+		errs = append(errs, errors.New("database.slow_query.threshold must not be negative"))
+	}
+	switch c.Database.Compression.Algorithm {
+	case entity.CompressionNone, entity.CompressionGzip:
+	default:
+		errs = append(errs, fmt.Errorf("unknown database.compression.algorithm %q, expected %s or %s",
+			c.Database.Compression.Algorithm, entity.CompressionNone, entity.CompressionGzip))
+	}
+	if _, err := idgen.New(c.Database.IDs.Strategy, c.Database.IDs.Node); err != nil {
+		errs = append(errs, fmt.Errorf("database.ids: %w", err))
+	}
+	seenRepos := make(map[string]bool, len(c.RepoPolicies))
+	for i, policy := range c.RepoPolicies {
+		owner, name, found := strings.Cut(policy.Repo, "/")
+		if !found || owner == "" || name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("repo_policies[%d].repo must be owner/name, got %q", i, policy.Repo))
+			continue
+		}
+		if seenRepos[strings.ToLower(policy.Repo)] {
+			errs = append(errs, fmt.Errorf("repo_policies[%d]: %s has several policies", i, policy.Repo))
+		}
+		seenRepos[strings.ToLower(policy.Repo)] = true
+		if err := policy.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("repo_policies[%d]: %w", i, err))
+		}
+	}
+	if c.Scrape.Fixtures != "" {
+		if info, err := os.Stat(c.Scrape.Fixtures); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("scrape.fixtures %q is not a directory", c.Scrape.Fixtures))
+		}
+		if c.Scrape.RecordDir != "" {
+			errs = append(errs, errors.New("scrape.fixtures and scrape.record_dir cannot be used together"))
+		}
+	}
+	if c.Scrape.PageCache.Enabled && (c.Scrape.Fixtures != "" || c.Scrape.RecordDir != "") {
+		// Recorded 304 responses would replay as empty pages
+		errs = append(errs, errors.New("scrape.page_cache cannot be used with scrape.fixtures or scrape.record_dir"))
+	}
+	if err := utils.ValidateSelectors(c.Scrape.Selectors); err != nil {
+		errs = append(errs, fmt.Errorf("scrape.selectors: %w", err))
+	}
+	if owner, name, found := strings.Cut(c.Scrape.SelfCheck.Repo, "/"); !found || owner == "" || name == "" || strings.Contains(name, "/") {
+		errs = append(errs, fmt.Errorf("scrape.selfcheck.repo must be owner/name, got %q", c.Scrape.SelfCheck.Repo))
+	}
+	if c.Scrape.SelfCheck.Tag == "" {
+		errs = append(errs, errors.New("scrape.selfcheck.tag is required"))
+	}
+	if err := c.Scrape.Archive.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("scrape.archive: %w", err))
+	}
+	if c.Scrape.Replay {
+		if c.Scrape.RecordDir == "" && c.Scrape.Archive.Bucket == "" {
+			errs = append(errs, errors.New("scrape.replay needs scrape.record_dir or scrape.archive.bucket"))
+		} else if c.Scrape.RecordDir == "" {
+			if c.Scrape.Archive.Enabled {
+				errs = append(errs, errors.New("scrape.archive.enabled cannot be used when replaying the archive"))
+			}
+		} else if info, err := os.Stat(c.Scrape.RecordDir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("scrape.record_dir %q is not a directory", c.Scrape.RecordDir))
+		}
+	}
+	if rate := c.Visits.SampleRate; rate != nil && (*rate < 0 || *rate > 1) {
+		errs = append(errs, errors.New("visits.sample_rate must be between 0 and 1"))
+	}
+	if c.Jobs.Backend != "memory" && c.Jobs.Backend != "db" {
+		errs = append(errs, fmt.Errorf("unknown jobs.backend %q", c.Jobs.Backend))
+	}
+	if err := c.Scheduler.Decay.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("scheduler.decay: %w", err))
+	}
+	for name, expression := range c.Schedule {
+		if _, err := service.ParseCron(expression); err != nil {
+			errs = append(errs, fmt.Errorf("schedule.%s: %w", name, err))
+		}
+	}
+	if c.Auth.Signing.Required && c.Auth.Signing.Secret == "" {
+		errs = append(errs, errors.New("auth.signing.required needs auth.signing.secret"))
+	}
+	if _, err := service.NewBlackouts(c.Scheduler.Blackouts, c.Scheduler.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("scheduler.blackouts: %w", err))
+	}
+	if c.Coordinator.Mode != CoordinatorHTTP && c.Coordinator.Mode != CoordinatorInProcess {
+		errs = append(errs, fmt.Errorf("unknown coordinator.mode %q", c.Coordinator.Mode))
+	}
+	if threshold := c.Coordinator.StabilityThreshold; threshold != nil && *threshold < 1 {
+		errs = append(errs, errors.New("coordinator.stability_threshold must be at least 1"))
+	}
+	if c.Coordinator.MaxPause < 0 {
+		errs = append(errs, errors.New("coordinator.max_pause must not be negative"))
+	}
+	if err := c.Coordinator.Breaker.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("coordinator.breaker: %w", err))
+	}
+	if c.Crawl.RepoBreaker.IdleTTL < 0 {
+		errs = append(errs, errors.New("crawl.repo_breaker.idle_ttl must not be negative"))
+	}
+	if c.Crawl.NotFoundThreshold < 0 {
+		errs = append(errs, errors.New("crawl.not_found_threshold must be positive"))
+	}
+	if err := c.Crawl.RepoBreaker.BreakerSettings.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("crawl.repo_breaker: %w", err))
+	}
+	if err := c.Queue.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("queue: %w", err))
+	}
+	if err := c.Outbox.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("outbox: %w", err))
+	}
+	if err := c.Kafka.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("kafka: %w", err))
+	}
+	if err := c.NATS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("nats: %w", err))
+	}
+	if c.GRPC.Port < 0 || c.GRPC.Port > 65535 {
+		errs = append(errs, fmt.Errorf("grpc.port %d is not a port", c.GRPC.Port))
+	}
+	if addr := c.Coordinator.MetricsAddr; addr != "" && addr == c.Server.Addr {
+		errs = append(errs, errors.New("coordinator.metrics_addr must differ from server.addr"))
+	}
+	for stage, settings := range c.Crawl.Budgets {
+		concurrency := 0
+		switch stage {
+		case "releases":
+			concurrency = c.Crawl.RepoConcurrency
+		case "commits":
+			concurrency = c.Crawl.ReleaseConcurrency
+		default:
+			errs = append(errs, fmt.Errorf("crawl.budgets has unknown stage %q, expected releases or commits", stage))
+			continue
+		}
+		if settings.MaxGoroutines < 0 || settings.MaxHeapMB < 0 {
+			errs = append(errs, fmt.Errorf("crawl.budgets.%s values must not be negative", stage))
+		}
+		// A crawl would be refused its own workers
+		if settings.MaxGoroutines > 0 && settings.MaxGoroutines < concurrency {
+			errs = append(errs, fmt.Errorf("crawl.budgets.%s.max_goroutines %d is below the stage concurrency %d",
+				stage, settings.MaxGoroutines, concurrency))
+		}
+	}
+	if c.Bench.Requests < 0 || c.Bench.Concurrency < 0 || c.Bench.MaxID < 0 {
+		errs = append(errs, errors.New("bench values must not be negative"))
+	}
+	if err := c.Experiment.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("experiment: %w", err))
+	}
+	if c.Metrics.WSInterval < 0 {
+		errs = append(errs, errors.New("metrics.ws_interval must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// redacted replaces a configured secret so the dump shows it is set without revealing it
+func redacted(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// redactedURL keeps the scheme and host of a webhook URL, whose path and query often carry its
+// token, as with Slack and Discord hooks
+func redactedURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return redacted(rawURL)
+	}
+	if parsed.User == nil && (parsed.Path == "" || parsed.Path == "/") && parsed.RawQuery == "" {
+		return parsed.Scheme + "://" + parsed.Host
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/[redacted]"
+}
+
+// Redacted returns a copy of the config without passwords, tokens, API keys, webhook secrets and
+// the paths of webhook URLs
+func (c *Config) Redacted() *Config {
+	copied := *c
+	copied.Database.Password = redacted(c.Database.Password)
+	copied.Database.DSN = redacted(c.Database.DSN)
+	copied.GitHub.Token = redacted(c.GitHub.Token)
+	copied.Scrape.Archive.SecretKey = redacted(c.Scrape.Archive.SecretKey)
+	copied.Notifiers.Email.Password = redacted(c.Notifiers.Email.Password)
+	copied.Coordinator.APIKey = redacted(c.Coordinator.APIKey)
+	copied.NATS.Password = redacted(c.NATS.Password)
+	copied.NATS.Token = redacted(c.NATS.Token)
+
+	copied.Notifiers.Webhooks = make([]string, len(c.Notifiers.Webhooks))
+	for i, webhook := range c.Notifiers.Webhooks {
+		copied.Notifiers.Webhooks[i] = redactedURL(webhook)
+	}
+	copied.Webhooks = append([]notifier.WebhookSubscription(nil), c.Webhooks...)
+	for i := range copied.Webhooks {
+		copied.Webhooks[i].URL = redactedURL(copied.Webhooks[i].URL)
+		copied.Webhooks[i].Secret = redacted(copied.Webhooks[i].Secret)
+	}
+	copied.Auth.Keys = append([]auth.APIKey(nil), c.Auth.Keys...)
+	for i := range copied.Auth.Keys {
+		copied.Auth.Keys[i].Key = redacted(copied.Auth.Keys[i].Key)
+		copied.Auth.Keys[i].KeySHA256 = redacted(copied.Auth.Keys[i].KeySHA256)
+	}
+	copied.Auth.Signing.Secret = redacted(c.Auth.Signing.Secret)
+	copied.Bench.Targets = append([]service.BenchTarget(nil), c.Bench.Targets...)
+	for i := range copied.Bench.Targets {
+		copied.Bench.Targets[i].APIKey = redacted(copied.Bench.Targets[i].APIKey)
+	}
+	return &copied
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// uniqueKey is a unique index that databases created before it may break with duplicate rows
+type uniqueKey struct {
+	index string
+	table string
+	// columns are the indexed expressions
+	columns string
+	// duplicates names the temporary table mapping each duplicate ID to the kept one
+	duplicates string
+	// merge moves what refers to the duplicates to the kept rows, before they are deleted
+	merge func(tx *gorm.DB, duplicates string) error
+}
+
+// uniqueKeys are deduplicated parents first, so the children moved to a kept parent are
+// deduplicated in turn
+var uniqueKeys = []uniqueKey{
+	{index: "repositories_name_key", table: "repositories", columns: "LOWER(username), LOWER(reponame)",
+		duplicates: "repository_duplicates", merge: mergeRepositories},
+	{index: "releases_repoid_tagname_key", table: "releases", columns: "repoid, tagname",
+		duplicates: "release_duplicates", merge: mergeReleases},
+	{index: "tags_repoid_name_key", table: "tags", columns: "repoid, name", duplicates: "tag_duplicates"},
+	{index: "commits_releaseid_hash_key", table: "commits", columns: "releaseid, hash", duplicates: "commit_duplicates"},
+}
+
+// dedupeUniqueKeys removes the rows that would break the unique indexes of the entities, in a
+// database where those indexes do not exist yet, so they can be created. Of each group of
+// duplicates the row with the lowest ID is kept, and the rows referring to the others are
+// moved to it.
+func dedupeUniqueKeys(db *gorm.DB) error {
+	// An embedded SQLite database is always created with the indexes
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range uniqueKeys {
+			// Where the index exists there is nothing to remove, where the table does not
+			// AutoMigrate creates both
+			indexed, err := relationExists(tx, key.index)
+			if err != nil {
+				return fmt.Errorf("checking index %s: %w", key.index, err)
+			}
+			stored, err := relationExists(tx, key.table)
+			if err != nil {
+				return fmt.Errorf("checking table %s: %w", key.table, err)
+			}
+			if indexed || !stored {
+				continue
+			}
+			if err := key.dedupe(tx); err != nil {
+				return fmt.Errorf("deduplicating %s: %w", key.table, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (k uniqueKey) dedupe(tx *gorm.DB) error {
+	err := tx.Exec(fmt.Sprintf(`CREATE TEMPORARY TABLE %[1]s ON COMMIT DROP AS
+		SELECT id, keep FROM (SELECT id, MIN(id) OVER (PARTITION BY %[3]s) AS keep FROM %[2]s) grouped
+		WHERE id <> keep`, k.duplicates, k.table, k.columns)).Error
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := tx.Table(k.duplicates).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	if k.merge != nil {
+		if err := k.merge(tx, k.duplicates); err != nil {
+			return err
+		}
+	}
+	return tx.Exec(fmt.Sprintf("DELETE FROM %s USING %s d WHERE %[1]s.id = d.id", k.table, k.duplicates)).Error
+}
+
+func mergeRepositories(tx *gorm.DB, duplicates string) error {
+	// Releases and tags are deduplicated next
+	if err := moveChildren(tx, duplicates, "releases", "repoid", ""); err != nil {
+		return err
+	}
+	if err := moveChildren(tx, duplicates, "tags", "repoid", ""); err != nil {
+		return err
+	}
+	if err := moveChildSets(tx, duplicates, "repo_policies", "repoid", "", ""); err != nil {
+		return err
+	}
+	return moveChildSets(tx, duplicates, "watchlist_repos", "repoid", "watchlistid", "")
+}
+
+func mergeReleases(tx *gorm.DB, duplicates string) error {
+	// Commits are deduplicated next
+	if err := moveChildren(tx, duplicates, "commits", "releaseid", ""); err != nil {
+		return err
+	}
+	for _, table := range []string{"release_assets", "release_note_items", "release_note_references", "release_note_mentions"} {
+		if err := moveChildSets(tx, duplicates, table, "releaseid", "", ""); err != nil {
+			return err
+		}
+	}
+	return moveChildSets(tx, duplicates, "crawl_errors", "entityid", "entitytype", "entitytype = 'release'")
+}
+
+// moveChildren repoints column of table, for the rows matching where when set, from the
+// duplicates to their kept row
+func moveChildren(tx *gorm.DB, duplicates, table, column, where string) error {
+	if exists, err := relationExists(tx, table); err != nil || !exists {
+		return err
+	}
+	if where != "" {
+		where = " AND " + table + "." + where
+	}
+	err := tx.Exec(fmt.Sprintf("UPDATE %[2]s SET %[3]s = d.keep FROM %[1]s d WHERE %[2]s.%[3]s = d.id%[4]s",
+		duplicates, table, column, where)).Error
+	if err != nil {
+		return fmt.Errorf("moving %s: %w", table, err)
+	}
+	return nil
+}
+
+// moveChildSets moves the rows of table referring to the duplicates, such as the assets of a
+// release, as a whole: those of the kept row stay when it has some, otherwise those of its
+// lowest duplicate having some are moved, and the others are deleted. With scope, the rows are
+// compared among those with the same scope column, such as the repositories of one watchlist.
+func moveChildSets(tx *gorm.DB, duplicates, table, column, scope, where string) error {
+	if exists, err := relationExists(tx, table); err != nil || !exists {
+		return err
+	}
+	sameScope := func(alias string) string {
+		if scope == "" {
+			return ""
+		}
+		return fmt.Sprintf(" AND %s.%s = c.%s", alias, scope, scope)
+	}
+	condition := ""
+	if where != "" {
+		condition = " AND c." + where
+	}
+	err := tx.Exec(fmt.Sprintf(`DELETE FROM %[2]s c USING %[1]s d WHERE c.%[3]s = d.id%[4]s AND (
+		EXISTS (SELECT 1 FROM %[2]s k WHERE k.%[3]s = d.keep%[5]s)
+		OR EXISTS (SELECT 1 FROM %[2]s o JOIN %[1]s od ON o.%[3]s = od.id
+			WHERE od.keep = d.keep AND od.id < d.id%[6]s))`,
+		duplicates, table, column, condition, sameScope("k"), sameScope("o"))).Error
+	if err != nil {
+		return fmt.Errorf("deleting duplicate %s: %w", table, err)
+	}
+	return moveChildren(tx, duplicates, table, column, where)
+}
+
+// relationExists reports whether the table or index name exists
+func relationExists(tx *gorm.DB, name string) (bool, error) {
+	var exists bool
+	err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", name).Scan(&exists).Error
+	return exists, err
+}
@@ -1,30 +1,52 @@
 package config
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+
+	"github.com/glebarez/sqlite"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func NewDatabase(viper *viper.Viper, log *logrus.Logger) *gorm.DB {
-	username := viper.GetString("database.username")
-	password := viper.GetString("database.password")
-	host := viper.GetString("database.host")
-	port := viper.GetInt("database.port")
-	database := viper.GetString("database.name")
-	idleConnection := viper.GetInt("database.pool.idle")
-	maxConnection := viper.GetInt("database.pool.max")
-	maxLifeTimeConnection := viper.GetInt("database.pool.lifetime")
+// NewDatabase connects to the database selected by "database.driver": postgres (default)
+// or sqlite, which stores everything in the file at "database.path". Release contents are
+// stored as set by "database.compression".
+func NewDatabase(settings DatabaseSettings, log *logrus.Logger) *gorm.DB {
+	username := settings.Username
+	password := settings.Password
+	host := settings.Host
+	port := settings.Port
+	database := settings.Name
+	pool := settings.Pool
 
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Bangkok",
 		host, username, password, database, port)
+	if timeout := settings.QueryTimeout; timeout > 0 {
+		// The server also cancels the statements outliving their caller, such as those of a dead pod
+		dsn += fmt.Sprintf(" statement_timeout=%d", timeout.Milliseconds())
+	}
+	if settings.DSN != "" {
+		dsn = settings.DSN
+	}
 	// fmt.Println(dsn)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	dialector := postgres.Open(dsn)
+	driver := settings.Driver
+	if driver == "sqlite" {
+		dialector = sqlite.Open(settings.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+		// SQLite allows one writer at a time
+		pool.Max = 1
+	} else if driver != "" && driver != "postgres" {
+		log.Fatalf("unknown database driver %q", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.New(&logrusWriter{Logger: log}, logger.Config{
 			SlowThreshold:             time.Second * 5,
 			Colorful:                  false,
@@ -42,13 +64,91 @@ func NewDatabase(viper *viper.Viper, log *logrus.Logger) *gorm.DB {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	connection.SetMaxIdleConns(idleConnection)
-	connection.SetMaxOpenConns(maxConnection)
-	connection.SetConnMaxLifetime(time.Second * time.Duration(maxLifeTimeConnection))
+	applyPool(connection, pool)
+	slowQueries := repository.NewSlowQueryLog(log, settings.SlowQuery.Threshold, settings.SlowQuery.Keep)
+	if err := db.Use(slowQueries); err != nil {
+		log.Fatalf("failed to register the slow query log: %v", err)
+	}
+	if driver == "sqlite" {
+		// There is no init script for the embedded database, the tables come from the entities
+		if err := Migrate(db); err != nil {
+			log.Fatalf("failed to create tables: %v", err)
+		}
+	}
+	repository.SetQueryTimeout(settings.QueryTimeout)
+	entity.SetContentCompression(entity.ContentCompression{
+		Algorithm: settings.Compression.Algorithm,
+		MinSize:   settings.Compression.MinSize,
+	})
 	fmt.Println("Connected to database")
 	return db
 }
 
+// applyPool sizes the connection pool; it can be called again while the pool is in use
+func applyPool(connection *sql.DB, pool PoolSettings) {
+	connection.SetMaxIdleConns(pool.Idle)
+	connection.SetMaxOpenConns(pool.Max)
+	connection.SetConnMaxLifetime(time.Second * time.Duration(pool.Lifetime))
+	connection.SetConnMaxIdleTime(time.Second * time.Duration(pool.IdleTime))
+}
+
+// slowQueryLog returns the slow query log NewDatabase registered on db, nil for another db
+func slowQueryLog(db *gorm.DB) *repository.SlowQueryLog {
+	slowQueries, _ := db.Config.Plugins[repository.SlowQueryPluginName].(*repository.SlowQueryLog)
+	return slowQueries
+}
+
+// databaseMetrics exposes the connection pool and the slow queries to the alert rules, as
+// "db.pool.open", ".in_use", ".idle", ".wait_count", ".wait_seconds", "db.slow_queries.total"
+// and ".max_ms"
+func databaseMetrics(connection *sql.DB, slowQueries *repository.SlowQueryLog) func() map[string]float64 {
+	return func() map[string]float64 {
+		pool := connection.Stats()
+		values := map[string]float64{
+			"db.pool.open":         float64(pool.OpenConnections),
+			"db.pool.in_use":       float64(pool.InUse),
+			"db.pool.idle":         float64(pool.Idle),
+			"db.pool.wait_count":   float64(pool.WaitCount),
+			"db.pool.wait_seconds": pool.WaitDuration.Seconds(),
+		}
+		if slowQueries != nil {
+			stats := slowQueries.Stats()
+			values["db.slow_queries.total"] = float64(stats.Total)
+			values["db.slow_queries.max_ms"] = float64(stats.MaxMs)
+		}
+		return values
+	}
+}
+
+// Migrate creates the tables of setup-data/init-scripts/schema.sql from the entities, after
+// removing the duplicate rows that would keep their unique indexes from being created
+func Migrate(db *gorm.DB) error {
+	if err := dedupeUniqueKeys(db); err != nil {
+		return err
+	}
+	return db.AutoMigrate(
+		&entity.Repository{},
+		&entity.RepoPolicy{},
+		&entity.Release{},
+		&entity.ReleaseAsset{},
+		&entity.ReleaseNoteItem{},
+		&entity.ReleaseNoteReference{},
+		&entity.ReleaseNoteMention{},
+		&entity.Tag{},
+		&entity.Commit{},
+		&entity.Visit{},
+		&entity.CachedPage{},
+		&entity.Watchlist{},
+		&entity.WatchlistKeyword{},
+		&entity.CrawlJob{},
+		&entity.CrawlWorker{},
+		&entity.CrawlRun{},
+		&entity.CrawlError{},
+		&entity.CrawlSchedule{},
+		&entity.OutboxEvent{},
+	)
+}
+
 type logrusWriter struct {
 	Logger *logrus.Logger
 }
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"crawler/baseline/internal/kafka"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newKafkaProducer starts the producer of the "kafka" section, which flushes its buffer and
+// stops once stop is closed
+func newKafkaProducer(log *logrus.Logger, settings kafka.Settings, stop <-chan struct{}) *kafka.Producer {
+	producer := kafka.NewProducer(log, settings)
+	producer.Start()
+	if stop != nil {
+		go func() {
+			<-stop
+			producer.Stop()
+		}()
+	}
+	return producer
+}
+
+// kafkaMetrics exposes the Kafka producer to the alert rules, as "kafka.published", ".dropped"
+// and ".failed"
+func kafkaMetrics(producer *kafka.Producer) func() map[string]float64 {
+	return func() map[string]float64 {
+		stats := producer.Stats()
+		return map[string]float64{
+			"kafka.published": float64(stats.Published),
+			"kafka.dropped":   float64(stats.Dropped),
+			"kafka.failed":    float64(stats.Failed),
+		}
+	}
+}
@@ -1,10 +1,6 @@
 package config
 
 import (
-	"io"
-	"os"
-	"path/filepath"
-
 	"github.com/sirupsen/logrus"
 )
 
@@ -14,56 +10,31 @@ type LogConfig struct {
 	RepoLogger    *logrus.Logger
 	ReleaseLogger *logrus.Logger
 	CommitLogger  *logrus.Logger
+	TagLogger     *logrus.Logger
 }
 
-// SetupLoggers initializes all loggers
-func SetupLoggers() *LogConfig {
-	// Ensure log directory exists
-	logDir := "./logs"
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		panic(err)
-	}
-
+// SetupLoggers initializes all loggers with the level, format and output of settings
+func SetupLoggers(settings LogSettings) *LogConfig {
 	// Main application logger
-	mainLogger := logrus.New()
-	mainLogger.SetFormatter(&logrus.JSONFormatter{})
-	mainLogger.SetLevel(logrus.InfoLevel)
+	mainLogger := newLogger(settings, "app.log", LogOutputStdout)
 
 	// Repository crawler logger
-	repoLogger := createLogger(filepath.Join(logDir, "repo_crawl.log"))
+	repoLogger := createLogger(settings, "repo_crawl.log")
 
 	// Release crawler logger
-	releaseLogger := createLogger(filepath.Join(logDir, "release_crawl.log"))
-	commitLogger := createLogger(filepath.Join(logDir, "commit_crawl.log"))
+	releaseLogger := createLogger(settings, "release_crawl.log")
+	commitLogger := createLogger(settings, "commit_crawl.log")
+	tagLogger := createLogger(settings, "tag_crawl.log")
 	return &LogConfig{
 		MainLogger:    mainLogger,
 		RepoLogger:    repoLogger,
 		ReleaseLogger: releaseLogger,
 		CommitLogger:  commitLogger,
+		TagLogger:     tagLogger,
 	}
 }
 
-// createLogger creates a logger with file output
-func createLogger(filename string) *logrus.Logger {
-	logger := logrus.New()
-
-	// Configure formatter
-	logger.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: "2006-01-02 15:04:05",
-	})
-
-	// Set log level
-	logger.SetLevel(logrus.InfoLevel)
-
-	// Create log file
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		logger.Fatalf("Failed to open log file %s: %v", filename, err)
-	}
-
-	// Multi-writer for console and file
-	mw := io.MultiWriter(os.Stdout, file)
-	logger.SetOutput(mw)
-
-	return logger
+// createLogger creates a crawl logger, writing to both the console and its file by default
+func createLogger(settings LogSettings, filename string) *logrus.Logger {
+	return newLogger(settings, filename, LogOutputBoth)
 }
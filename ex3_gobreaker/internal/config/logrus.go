@@ -1,15 +1,114 @@
 package config
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 )
 
-func NewLogger(viper *viper.Viper) *logrus.Logger {
-	log := logrus.New()
+// Formats and outputs of the log section of config.json
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+	LogOutputFile   = "file"
+	LogOutputBoth   = "both"
+)
+
+const (
+	logDir             = "./logs"
+	logTimestampFormat = "2006-01-02 15:04:05"
+)
+
+// NewLogger creates the main logger. The standard logrus logger, used by the scrapers and
+// helpers that have no logger of their own, follows the same settings.
+func NewLogger(settings LogSettings) *logrus.Logger {
+	log := newLogger(settings, "app.log", LogOutputStdout)
 
-	log.SetLevel(logrus.Level(viper.GetInt32("log.level")))
-	log.SetFormatter(&logrus.JSONFormatter{})
+	std := logrus.StandardLogger()
+	std.SetLevel(log.GetLevel())
+	std.SetFormatter(log.Formatter)
+	std.SetOutput(log.Out)
 
 	return log
 }
+
+// newLogger creates a logger with the level, format and output of settings, writing to
+// filename in the log directory when the output includes a file
+func newLogger(settings LogSettings, filename string, defaultOutput string) *logrus.Logger {
+	logger := logrus.New()
+
+	level, err := ParseLogLevel(settings.Level)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid log level, using info")
+	}
+	logger.SetLevel(level)
+
+	switch settings.Format {
+	case "", LogFormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: logTimestampFormat})
+	case LogFormatText:
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true, TimestampFormat: logTimestampFormat})
+	default:
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: logTimestampFormat})
+		logger.Warnf("Invalid log format %q, using json", settings.Format)
+	}
+
+	output := settings.Output
+	switch output {
+	case "":
+		output = defaultOutput
+	case LogOutputStdout, LogOutputStderr, LogOutputFile, LogOutputBoth:
+	default:
+		logger.Warnf("Invalid log output %q, using %s", output, defaultOutput)
+		output = defaultOutput
+	}
+	switch output {
+	case LogOutputStdout:
+		logger.SetOutput(os.Stdout)
+		return logger
+	case LogOutputStderr:
+		// logrus writes to stderr unless told otherwise
+		return logger
+	}
+
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		logger.Fatalf("Failed to create log directory %s: %v", logDir, err)
+	}
+	path := filepath.Join(logDir, filename)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		logger.Fatalf("Failed to open log file %s: %v", path, err)
+	}
+	if output == LogOutputFile {
+		logger.SetOutput(file)
+	} else {
+		logger.SetOutput(io.MultiWriter(os.Stdout, file))
+	}
+
+	return logger
+}
+
+// ParseLogLevel parses a logrus level name or number; an empty level is info
+func ParseLogLevel(level string) (logrus.Level, error) {
+	if level == "" {
+		return logrus.InfoLevel, nil
+	}
+	if number, err := strconv.Atoi(level); err == nil {
+		if number < int(logrus.PanicLevel) || number > int(logrus.TraceLevel) {
+			return logrus.InfoLevel, fmt.Errorf("log level %d out of range 0-6", number)
+		}
+		return logrus.Level(number), nil
+	}
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return logrus.InfoLevel, err
+	}
+	return parsed, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import "fmt"
+
+// RunMode selects which parts of the crawler a process runs, so the same binary can be
+// deployed as separate API, worker and scheduler instances
+type RunMode string
+
+const (
+	// ModeServe runs everything in one process
+	ModeServe RunMode = "serve"
+	// ModeAPI serves the HTTP API and enqueues crawl jobs for the workers
+	ModeAPI RunMode = "serve-api"
+	// ModeWorker only runs jobs from the shared queue
+	ModeWorker RunMode = "worker-only"
+	// ModeScheduler only runs the coordinator, alerts and digests
+	ModeScheduler RunMode = "scheduler-only"
+	// ModeScrape runs like ModeServe, but publishes the crawled batches to NATS instead of
+	// storing them
+	ModeScrape RunMode = "scrape"
+	// ModePersist only stores the batches the scrape processes publish to NATS
+	ModePersist RunMode = "persist"
+)
+
+// ParseRunMode accepts a mode name; an empty name is ModeServe
+func ParseRunMode(name string) (RunMode, error) {
+	switch mode := RunMode(name); mode {
+	case "":
+		return ModeServe, nil
+	case ModeServe, ModeAPI, ModeWorker, ModeScheduler, ModeScrape, ModePersist:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q, expected %s, %s, %s, %s, %s or %s", name,
+			ModeServe, ModeAPI, ModeWorker, ModeScheduler, ModeScrape, ModePersist)
+	}
+}
+
+func (m RunMode) ServesAPI() bool {
+	return m == ModeServe || m == ModeScrape || m == ModeAPI || m == ""
+}
+
+func (m RunMode) RunsWorkers() bool {
+	return m == ModeServe || m == ModeScrape || m == ModeWorker || m == ""
+}
+
+func (m RunMode) RunsScheduler() bool {
+	return m == ModeServe || m == ModeScrape || m == ModeScheduler || m == ""
+}
+
+// SharesQueue reports whether jobs must go through the shared queue, because the
+// instance that accepts them is not the one that runs them
+func (m RunMode) SharesQueue() bool {
+	return m == ModeAPI || m == ModeWorker
+}
+
+// PublishesItems reports whether crawled batches go to NATS instead of the database
+func (m RunMode) PublishesItems() bool {
+	return m == ModeScrape
+}
+
+// PersistsItems reports whether the process stores the batches published to NATS
+func (m RunMode) PersistsItems() bool {
+	return m == ModePersist
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"crawler/baseline/internal/nats"
+	"crawler/baseline/internal/usecase"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newItemBus connects to the server of the "nats" section, closing the connection once stop
+// is closed. Scrape and persist processes cannot work without it, so failing to connect is fatal.
+func newItemBus(log *logrus.Logger, settings nats.Settings, mode RunMode, stop <-chan struct{}) *usecase.ItemBus {
+	conn, err := nats.Connect(log, settings, "crawler-"+string(mode))
+	if err != nil {
+		log.Fatalf("failed to connect to NATS at %s: %v", settings.URL, err)
+	}
+	if stop != nil {
+		go func() {
+			<-stop
+			conn.Close()
+		}()
+	}
+	log.WithField("url", settings.URL).Info("Connected to NATS")
+	return &usecase.ItemBus{Conn: conn, Settings: settings}
+}
+
+// natsMetrics exposes the NATS connection to the alert rules, as "nats.published", ".received",
+// ".reconnects", ".connected", ".redelivered" and ".dropped"
+func natsMetrics(bus *usecase.ItemBus) func() map[string]float64 {
+	return func() map[string]float64 {
+		stats := bus.Conn.Stats()
+		connected := 0.0
+		if stats.Connected {
+			connected = 1
+		}
+		return map[string]float64{
+			"nats.published":  float64(stats.Published),
+			"nats.received":   float64(stats.Received),
+			"nats.reconnects": float64(stats.Reconnects),
+			"nats.connected":  connected,
+			// Batches a persist process failed to store, given back or given up
+			"nats.redelivered": float64(stats.Redelivered),
+			"nats.dropped":     float64(stats.Dropped),
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+import "crawler/baseline/internal/usecase"
+
+// outboxMetrics exposes the deliveries of the outbox dispatcher to the alert rules, as
+// "outbox.delivered", ".failed" and ".abandoned"
+func outboxMetrics(outbox *usecase.OutboxUsecase) func() map[string]float64 {
+	return func() map[string]float64 {
+		stats := outbox.Stats()
+		return map[string]float64{
+			"outbox.delivered": float64(stats.Delivered),
+			"outbox.failed":    float64(stats.Failed),
+			"outbox.abandoned": float64(stats.Abandoned),
+		}
+	}
+}
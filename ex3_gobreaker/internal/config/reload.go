@@ -0,0 +1,183 @@
+package config
+
+import (
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/http/controller"
+	"crawler/baseline/internal/queue"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/utils"
+	"database/sql"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// runtimeReloader applies the settings that a running server can change when the config files
+// change: colly parallelism, scraper selectors, crawl concurrency, the repository 404 threshold,
+// the per-repository circuit breakers, the commit queue's workers and batch size, the database
+// pool, query timeout and slow query threshold, API rate limits, and the coordinator's stability
+// thresholds, max pause and circuit breakers. The other settings take effect on the next start.
+type runtimeReloader struct {
+	log     *logrus.Logger
+	current *Config
+
+	collyLimit        *scrape.ParallelismLimit
+	releaseController *controller.ReleaseController
+	commitController  *controller.CommitController
+	// repoBreakers is nil when crawl.repo_breaker or features.circuit_breaking is off
+	repoBreakers *utils.BreakerGroup
+	// commitQueue is nil when features.queueing is off
+	commitQueue    *queue.CommitQueueProcessor
+	rateLimit      *controller.RateLimiter
+	crawlRateLimit *controller.RateLimiter
+	// coordinator is nil on instances that do not schedule crawls
+	coordinator *service.CrawlingCoordinator
+	connection  *sql.DB
+	// slowQueries is nil when the database was not opened by NewDatabase
+	slowQueries *repository.SlowQueryLog
+}
+
+// apply decodes the changed config and applies the runtime settings that differ from the
+// running ones. An invalid config is logged and skipped as a whole.
+func (r *runtimeReloader) apply(v *viper.Viper) {
+	settings, err := NewConfig(v)
+	if err != nil {
+		r.log.WithError(err).Error("Invalid config after change, keeping the running settings")
+		return
+	}
+
+	if r.collyLimit != nil && settings.Colly.Parallelism != r.current.Colly.Parallelism {
+		r.collyLimit.SetLimit(settings.Colly.Parallelism)
+		r.log.WithField("parallelism", settings.Colly.Parallelism).Info("Colly parallelism changed")
+	}
+	if !reflect.DeepEqual(settings.Scrape.Selectors, r.current.Scrape.Selectors) {
+		if err := utils.SetSelectors(settings.Scrape.Selectors); err != nil {
+			r.log.WithError(err).Error("Invalid scraper selectors after change, keeping the running ones")
+		} else {
+			r.log.WithField("data_points", len(settings.Scrape.Selectors)).Info("Scraper selectors changed")
+		}
+	}
+	if settings.Crawl.RepoConcurrency != r.current.Crawl.RepoConcurrency {
+		r.releaseController.SetRepoConcurrency(settings.Crawl.RepoConcurrency)
+		r.log.WithField("repo_concurrency", settings.Crawl.RepoConcurrency).Info("Release crawl concurrency changed")
+	}
+	if settings.Crawl.ReleaseConcurrency != r.current.Crawl.ReleaseConcurrency {
+		r.commitController.SetReleaseConcurrency(settings.Crawl.ReleaseConcurrency)
+		r.log.WithField("release_concurrency", settings.Crawl.ReleaseConcurrency).Info("Commit crawl concurrency changed")
+	}
+	if settings.Crawl.NotFoundThreshold != r.current.Crawl.NotFoundThreshold {
+		entity.SetNotFoundThreshold(settings.Crawl.NotFoundThreshold)
+		r.log.WithField("not_found_threshold", settings.Crawl.NotFoundThreshold).Info("Repository 404 threshold changed")
+	}
+	r.applyDatabase(settings.Database)
+	if settings.Features != r.current.Features {
+		r.log.WithField("features", settings.Features).Warn("features changed, restart to apply them")
+	}
+	r.applyRepoBreakers(settings.Crawl.RepoBreaker)
+	r.applyQueue(settings.Queue)
+	r.applyRateLimits(settings.RateLimit)
+	if r.coordinator != nil {
+		r.applyCoordinator(settings.Coordinator)
+	}
+
+	r.current = settings
+}
+
+// applyDatabase resizes the connection pool and changes the query timeout and the slow query
+// threshold in place; the other database settings need a restart
+func (r *runtimeReloader) applyDatabase(settings DatabaseSettings) {
+	previous := r.current.Database
+	if settings.Pool != previous.Pool && settings.Driver != "sqlite" {
+		applyPool(r.connection, settings.Pool)
+		r.log.WithField("pool", settings.Pool).Info("Database connection pool resized")
+	}
+	if settings.QueryTimeout != previous.QueryTimeout {
+		repository.SetQueryTimeout(settings.QueryTimeout)
+		r.log.WithField("query_timeout", settings.QueryTimeout).Info("Database query timeout changed")
+	}
+	if settings.SlowQuery.Threshold != previous.SlowQuery.Threshold && r.slowQueries != nil {
+		r.slowQueries.SetThreshold(settings.SlowQuery.Threshold)
+		r.log.WithField("threshold", settings.SlowQuery.Threshold).Info("Slow query threshold changed")
+	}
+}
+
+// applyRepoBreakers reconfigures the per-repository breakers in place; turning them on or off,
+// or changing their idle_ttl, needs a restart
+func (r *runtimeReloader) applyRepoBreakers(settings RepoBreakerSettings) {
+	previous := r.current.Crawl.RepoBreaker
+	if settings.Enabled != previous.Enabled || settings.IdleTTL != previous.IdleTTL {
+		r.log.WithField("enabled", settings.Enabled).Warn("crawl.repo_breaker changed, restart to apply it")
+		return
+	}
+	if settings.BreakerSettings != previous.BreakerSettings {
+		r.repoBreakers.Configure(settings.BreakerSettings)
+		r.log.Info("Repository circuit breakers reconfigured")
+	}
+}
+
+// applyQueue resizes the commit queue in place; its other settings need a restart
+func (r *runtimeReloader) applyQueue(settings queue.Settings) {
+	if r.commitQueue == nil {
+		return
+	}
+	r.commitQueue.Resize(settings.Workers)
+	r.commitQueue.SetBatchSize(settings.BatchSize)
+}
+
+// applyRateLimits resizes the rate limits in place; turning them on or off changes the routes,
+// which needs a restart
+func (r *runtimeReloader) applyRateLimits(settings RateLimitSettings) {
+	if settings.Enabled != r.current.RateLimit.Enabled {
+		r.log.WithField("enabled", settings.Enabled).Warn("rate_limit.enabled changed, restart to apply it")
+		return
+	}
+
+	limits := []struct {
+		limiter *controller.RateLimiter
+		limit   *controller.RateLimit
+	}{
+		{r.rateLimit, settings.Default},
+		{r.crawlRateLimit, settings.Crawl},
+	}
+	for _, limit := range limits {
+		if limit.limiter == nil || limit.limit == nil {
+			continue
+		}
+		if err := limit.limiter.SetLimit(*limit.limit); err != nil {
+			r.log.WithError(err).Error("Invalid rate limit after change, keeping the running one")
+		}
+	}
+}
+
+func (r *runtimeReloader) applyCoordinator(settings CoordinatorSettings) {
+	if threshold := settings.StabilityThreshold; threshold != nil {
+		if previous := r.current.Coordinator.StabilityThreshold; previous == nil || *previous != *threshold {
+			r.coordinator.SetStabilityThreshold(*threshold)
+		}
+	}
+
+	previous := make(map[string]int, len(r.current.Coordinator.Stages))
+	for _, stage := range r.current.Coordinator.Stages {
+		previous[stage.Name] = stage.StabilityThreshold
+	}
+	for _, stage := range settings.Stages {
+		if threshold, ok := previous[stage.Name]; ok && threshold == stage.StabilityThreshold {
+			continue
+		}
+		// Stages added to the config are only registered on the next start
+		if !r.coordinator.HasStage(stage.Name) {
+			continue
+		}
+		if err := r.coordinator.SetStageStabilityThreshold(stage.Name, stage.StabilityThreshold); err != nil {
+			r.log.WithError(err).WithField("stage", stage.Name).Error("Error changing stage stability threshold")
+		}
+	}
+
+	if settings.MaxPause != r.current.Coordinator.MaxPause {
+		r.coordinator.SetMaxPause(settings.MaxPause)
+	}
+	r.coordinator.SetBreakerSettings(settings.Breaker)
+}
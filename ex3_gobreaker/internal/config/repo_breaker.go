@@ -0,0 +1,37 @@
+package config
+
+import (
+	"crawler/baseline/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newRepoBreakers returns the per-repository circuit breakers of the release and commit crawls,
+// or nil when they are disabled
+func newRepoBreakers(log *logrus.Logger, settings RepoBreakerSettings) *utils.BreakerGroup {
+	if !settings.Enabled {
+		return nil
+	}
+
+	log.WithField("idle_ttl", settings.IdleTTL).Info("Circuit breakers per repository enabled")
+	return utils.NewBreakerGroup("repo", settings.BreakerSettings, settings.IdleTTL, func(name string, from string, to string) {
+		log.WithFields(logrus.Fields{
+			"breaker": name,
+			"from":    from,
+			"to":      to,
+		}).Warn("Repository circuit breaker changed state")
+	})
+}
+
+// repoBreakerMetrics exposes the per-repository breakers to the alert rules, as
+// "repo_breakers.breakers", ".open" and ".half_open"
+func repoBreakerMetrics(breakers *utils.BreakerGroup) func() map[string]float64 {
+	return func() map[string]float64 {
+		stats := breakers.Stats()
+		return map[string]float64{
+			"repo_breakers.breakers":  float64(stats.Breakers),
+			"repo_breakers.open":      float64(stats.Open),
+			"repo_breakers.half_open": float64(stats.HalfOpen),
+		}
+	}
+}
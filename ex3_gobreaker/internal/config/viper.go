@@ -1,25 +1,156 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// ConfigFile, when set, is read as the base config instead of the config.json found in ./ or ./../.
+// The --config flag of the crawler command sets it.
+var ConfigFile string
+
+// envPrefix starts the environment variables overriding config keys, named after the key in
+// upper case with "_" for ".": CRAWLER_DATABASE_PORT overrides database.port
+const envPrefix = "CRAWLER"
+
+// ExperimentOverlay is the overlay the batch experiment writes its recommendation to. It is
+// merged after config.<APP_ENV> and before config.local, so a local setting still wins.
+const ExperimentOverlay = "config.experiment"
+
+// configExtensions are the formats of the overlay files, tried in this order
+var configExtensions = []string{"json", "yaml", "yml"}
+
+// envKeys are looked up in the environment even when no config file has them. Other keys must
+// be present in a config file to be overridden, since viper only decodes the keys it knows.
+var envKeys = []string{"database.driver", "database.path", "database.dsn", "database.host", "database.port",
+	"database.username", "database.password", "database.name"}
+
+// requiredKeys must have a value once every layer is applied, unless database.dsn replaces them
+// or the database is SQLite
+var requiredKeys = []string{"database.host", "database.port", "database.username", "database.name"}
+
+// configDir is the directory of the base config, where the overlays are looked up
+var configDir string
+
 // NewViper is a function to load config from config.json
 // You can change the implementation, for example load from env file, consul, etcd, etc
+//
+// Settings are layered: config.json (or the file passed to --config) holds the base,
+// config.<APP_ENV>.json next to it overrides it for one environment, config.experiment.json
+// holds what the batch experiment recorded, and config.local.json (not committed) overrides
+// them all for one machine. Only the keys present in an overlay replace the base values.
+// Overlays may also be YAML (config.<APP_ENV>.yaml), and CRAWLER_* environment variables
+// override every file.
 func NewViper() *viper.Viper {
+	config, _, err := loadViper()
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+// loadViper reads every layer of the config, returning it with the files it was read from
+func loadViper() (*viper.Viper, []string, error) {
 	config := viper.New()
 
-	config.SetConfigName("config")
-	config.SetConfigType("json")
-	config.AddConfigPath("./../")
-	config.AddConfigPath("./")
+	if ConfigFile != "" {
+		config.SetConfigFile(ConfigFile)
+	} else {
+		config.SetConfigName("config")
+		config.SetConfigType("json")
+		config.AddConfigPath("./../")
+		config.AddConfigPath("./")
+	}
 	err := config.ReadInConfig()
 
 	if err != nil {
-		panic(fmt.Errorf("Fatal error config file: %w \n", err))
+		return nil, nil, fmt.Errorf("Fatal error config file: %w \n", err)
 	}
 
-	return config
+	files := []string{config.ConfigFileUsed()}
+	dir := filepath.Dir(config.ConfigFileUsed())
+	configDir = dir
+	if env := os.Getenv("APP_ENV"); env != "" {
+		// An environment that was asked for must exist, a typo would silently run with the base config
+		file, err := mergeConfig(config, dir, "config."+env, true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Fatal error config file for APP_ENV %s: %w \n", env, err)
+		}
+		files = append(files, file)
+	}
+	file, err := mergeConfig(config, dir, ExperimentOverlay, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Fatal error experiment config file: %w \n", err)
+	}
+	if file != "" {
+		files = append(files, file)
+	}
+	file, err = mergeConfig(config, dir, "config.local", false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Fatal error local config file: %w \n", err)
+	}
+	if file != "" {
+		files = append(files, file)
+	}
+
+	config.SetEnvPrefix(envPrefix)
+	config.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	config.AutomaticEnv()
+	for _, key := range envKeys {
+		config.BindEnv(key)
+	}
+
+	if err := checkRequired(config); err != nil {
+		return nil, nil, fmt.Errorf("Fatal error config: %w \n", err)
+	}
+
+	return config, files, nil
+}
+
+// ExperimentFile is where the batch experiment writes its overlay, next to the base config
+func ExperimentFile() string {
+	return filepath.Join(configDir, ExperimentOverlay+".json")
+}
+
+// mergeConfig merges the overlay named name in dir, in the first format of configExtensions
+// found, into config and returns its path; a missing optional overlay is skipped
+func mergeConfig(config *viper.Viper, dir string, name string, required bool) (string, error) {
+	for _, extension := range configExtensions {
+		path := filepath.Join(dir, name+"."+extension)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		config.SetConfigFile(path)
+		return path, config.MergeInConfig()
+	}
+
+	if required {
+		return "", fmt.Errorf("no %s.%s in %s", name, strings.Join(configExtensions, ", ."), dir)
+	}
+	return "", nil
+}
+
+// checkRequired fails listing the required keys that have no value
+func checkRequired(config *viper.Viper) error {
+	if config.GetString("database.dsn") != "" || config.GetString("database.driver") == "sqlite" {
+		return nil
+	}
+
+	missing := make([]string, 0)
+	for _, key := range requiredKeys {
+		if config.GetString(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required keys %s, set them in a config file or as %s_* environment variables",
+			strings.Join(missing, ", "), envPrefix)
+	}
+	return nil
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay lets an editor finish saving a config file before all the layers are read again
+const reloadDelay = 500 * time.Millisecond
+
+// WatchConfig reads every layer of the config again whenever one of its files changes, and
+// passes the new config to apply. Each file is watched with viper.WatchConfig, but the layers
+// are merged again as NewViper does rather than taking the changed file alone. A config that
+// no longer loads is logged and skipped, leaving the running settings as they are.
+func WatchConfig(log *logrus.Logger, apply func(config *viper.Viper)) {
+	_, files, err := loadViper()
+	if err != nil {
+		log.WithError(err).Error("Failed to read config, not watching it for changes")
+		return
+	}
+
+	var mutex sync.Mutex
+	var pending *time.Timer
+	var applying sync.Mutex
+	reload := func(event fsnotify.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if pending != nil {
+			pending.Stop()
+		}
+		pending = time.AfterFunc(reloadDelay, func() {
+			applying.Lock()
+			defer applying.Unlock()
+
+			config, _, err := loadViper()
+			if err != nil {
+				log.WithError(err).WithField("file", event.Name).Error("Config changed but failed to load, keeping the running settings")
+				return
+			}
+			log.WithField("file", event.Name).Info("Config changed, applying runtime settings")
+			apply(config)
+		})
+	}
+
+	// The batch experiment may write its overlay after start, so it is watched even when missing
+	if !slices.ContainsFunc(files, isExperimentOverlay) {
+		files = append(files, ExperimentFile())
+	}
+	for _, file := range files {
+		watcher := viper.New()
+		watcher.SetConfigFile(file)
+		watcher.OnConfigChange(reload)
+		watcher.WatchConfig()
+	}
+	log.WithField("files", files).Info("Watching config files for runtime settings")
+}
+
+// isExperimentOverlay reports whether file is the batch experiment overlay, in any format
+func isExperimentOverlay(file string) bool {
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) == ExperimentOverlay
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// CachedPage is the last page fetched from a URL with the validators the server sent with it,
+// so that the page is fetched again with a conditional request. Body is gzipped.
+type CachedPage struct {
+	URL          string    `gorm:"column:url;primaryKey"`
+	ETag         string    `gorm:"column:etag"`
+	LastModified string    `gorm:"column:lastmodified"`
+	ContentType  string    `gorm:"column:contenttype"`
+	Body         []byte    `gorm:"column:body"`
+	FetchedAt    time.Time `gorm:"column:fetchedat"`
+	// CheckedAt is when the server last answered the page had not changed, or FetchedAt
+	CheckedAt time.Time `gorm:"column:checkedat"`
+}
@@ -1,9 +1,12 @@
 package entity
 
 type Commit struct {
-	ID        int64   `gorm:"column:id;primaryKey"`
-	Hash      string  `gorm:"column:hash"`
-	Message   string  `gorm:"column:message"`
-	ReleaseID int64   `gorm:"column:releaseid"`
-	Release   Release `gorm:"foreignKey:releaseid;references:id"`
+	ID           int64   `gorm:"column:id;primaryKey"`
+	Hash         string  `gorm:"column:hash;uniqueIndex:commits_releaseid_hash_key,priority:2"`
+	Message      string  `gorm:"column:message"`
+	FilesChanged *int    `gorm:"column:fileschanged"`
+	Additions    *int    `gorm:"column:additions"`
+	Deletions    *int    `gorm:"column:deletions"`
+	ReleaseID    int64   `gorm:"column:releaseid;uniqueIndex:commits_releaseid_hash_key,priority:1"`
+	Release      Release `gorm:"foreignKey:releaseid;references:id"`
 }
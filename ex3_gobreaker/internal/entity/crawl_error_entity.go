@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// Entity types of crawl errors
+const (
+	CrawlErrorRelease = "release"
+)
+
+// CrawlError is the last failed crawl of an entity, such as the commit crawl of a release.
+// There is one per entity; it is removed once the entity crawls successfully again.
+type CrawlError struct {
+	ID         int64  `gorm:"column:id;primaryKey"`
+	EntityType string `gorm:"column:entitytype;uniqueIndex:crawl_errors_entity_key,priority:1"`
+	EntityID   int64  `gorm:"column:entityid;uniqueIndex:crawl_errors_entity_key,priority:2"`
+	Message    string `gorm:"column:message"`
+	// HTTPStatus is the status GitHub answered, 0 when the crawl failed without a response
+	HTTPStatus int `gorm:"column:httpstatus"`
+	// RetryCount counts the crawls that failed again after the first failure
+	RetryCount    int       `gorm:"column:retrycount"`
+	FirstFailedAt time.Time `gorm:"column:firstfailedat"`
+	LastFailedAt  time.Time `gorm:"column:lastfailedat"`
+}
@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+// Sources of crawl runs
+const (
+	// CrawlSourceAPI is a crawl endpoint called with an API key, or with none when auth is off
+	CrawlSourceAPI = "api"
+	// CrawlSourceCoordinator is a coordinator stage, over signed HTTP calls or in process
+	CrawlSourceCoordinator = "coordinator"
+	// CrawlSourceJob is a background job, such as an onboarding crawl
+	CrawlSourceJob = "job"
+)
+
+// CrawlRun is the audit record of one crawl operation
+type CrawlRun struct {
+	ID       int64  `gorm:"column:id;primaryKey"`
+	Source   string `gorm:"column:source"`
+	Endpoint string `gorm:"column:endpoint"`
+	// APIKey is the name of the API key that started the run, empty when auth is off
+	APIKey     string    `gorm:"column:apikey"`
+	StartedAt  time.Time `gorm:"column:startedat"`
+	DurationMs int64     `gorm:"column:durationms"`
+	// StatusCode is the HTTP status of the run; in-process runs report 200 or 500
+	StatusCode int    `gorm:"column:statuscode"`
+	Found      int    `gorm:"column:found"`
+	Saved      int    `gorm:"column:saved"`
+	Errored    int    `gorm:"column:errored"`
+	Error      string `gorm:"column:error"`
+}
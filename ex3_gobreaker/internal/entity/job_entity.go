@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// CrawlJob is a background job in the shared queue, picked up by any worker instance
+type CrawlJob struct {
+	ID         int64      `gorm:"column:id;primaryKey"`
+	Kind       string     `gorm:"column:kind"`
+	Payload    string     `gorm:"column:payload"` // JSON
+	Status     string     `gorm:"column:status"`
+	Progress   string     `gorm:"column:progress"`
+	Error      string     `gorm:"column:error"`
+	Attempts   int        `gorm:"column:attempts"`
+	WorkerID   string     `gorm:"column:workerid"`
+	CreatedAt  time.Time  `gorm:"column:createdat"`
+	StartedAt  *time.Time `gorm:"column:startedat"`
+	FinishedAt *time.Time `gorm:"column:finishedat"`
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// OutboxEvent is an event stored in the same transaction as the rows it is about, so it is
+// delivered, at least once, exactly when those rows were committed
+type OutboxEvent struct {
+	ID      int64  `gorm:"column:id;primaryKey"`
+	Event   string `gorm:"column:event"`
+	Payload string `gorm:"column:payload"` // JSON notification
+	// Attempts counts the failed deliveries; the event is given up after outbox.max_attempts
+	Attempts      int       `gorm:"column:attempts"`
+	LastError     string    `gorm:"column:lasterror"`
+	CreatedAt     time.Time `gorm:"column:createdat"`
+	NextAttemptAt time.Time `gorm:"column:nextattemptat;index:outbox_events_due_idx"`
+	// LockedUntil is the end of the lease of the dispatcher delivering the event
+	LockedUntil *time.Time `gorm:"column:lockeduntil"`
+	DeliveredAt *time.Time `gorm:"column:deliveredat"`
+}
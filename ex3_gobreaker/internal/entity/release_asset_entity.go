@@ -0,0 +1,9 @@
+package entity
+
+type ReleaseAsset struct {
+	ID            int64  `gorm:"column:id;primaryKey"`
+	Name          string `gorm:"column:name"`
+	Size          int64  `gorm:"column:size"`
+	DownloadCount int64  `gorm:"column:downloadcount"`
+	ReleaseID     int64  `gorm:"column:releaseid"`
+}
@@ -0,0 +1,134 @@
+package entity
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// Content compression algorithms
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// gzipContentPrefix marks a content stored gzipped, then base64 encoded to fit the TEXT column
+const gzipContentPrefix = "gzip:"
+
+// ContentCompression is how release contents are stored. Contents shorter than MinSize are
+// kept as they are, as compressing them saves little or nothing.
+type ContentCompression struct {
+	Algorithm string
+	MinSize   int
+}
+
+var (
+	contentCompressionMutex sync.RWMutex
+	contentCompression      = ContentCompression{Algorithm: CompressionNone}
+)
+
+// SetContentCompression sets how release contents are stored from now on. Stored contents are
+// read back whatever the setting, so it can be switched at any time.
+func SetContentCompression(compression ContentCompression) {
+	contentCompressionMutex.Lock()
+	defer contentCompressionMutex.Unlock()
+	contentCompression = compression
+}
+
+func currentContentCompression() ContentCompression {
+	contentCompressionMutex.RLock()
+	defer contentCompressionMutex.RUnlock()
+	return contentCompression
+}
+
+// IsCompressedContent tells whether a stored content is compressed
+func IsCompressedContent(stored string) bool {
+	return strings.HasPrefix(stored, gzipContentPrefix)
+}
+
+// EncodeContent returns the stored form of a release content under the current compression
+// setting. A content that looks compressed is always compressed, so it reads back unchanged.
+func EncodeContent(content string) (string, error) {
+	compression := currentContentCompression()
+	if !IsCompressedContent(content) &&
+		(compression.Algorithm != CompressionGzip || len(content) < compression.MinSize) {
+		return content, nil
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := io.WriteString(writer, content); err != nil {
+		return "", fmt.Errorf("compressing content: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("compressing content: %w", err)
+	}
+
+	stored := gzipContentPrefix + base64.StdEncoding.EncodeToString(compressed.Bytes())
+	if len(stored) >= len(content) && !IsCompressedContent(content) {
+		return content, nil
+	}
+	return stored, nil
+}
+
+// DecodeContent returns the release content of its stored form, compressed or not
+func DecodeContent(stored string) (string, error) {
+	if !IsCompressedContent(stored) {
+		return stored, nil
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, gzipContentPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decoding compressed content: %w", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", fmt.Errorf("decompressing content: %w", err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("decompressing content: %w", err)
+	}
+	return string(content), nil
+}
+
+// contentSerializer stores a string field with EncodeContent and reads it with DecodeContent
+type contentSerializer struct{}
+
+func (contentSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch value := dbValue.(type) {
+	case nil:
+	case string:
+		stored = value
+	case []byte:
+		stored = string(value)
+	default:
+		return fmt.Errorf("unsupported content value %T", dbValue)
+	}
+
+	content, err := DecodeContent(stored)
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).SetString(content)
+	return nil
+}
+
+func (contentSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	content, _ := fieldValue.(string)
+	return EncodeContent(content)
+}
+
+func init() {
+	schema.RegisterSerializer("content", contentSerializer{})
+}
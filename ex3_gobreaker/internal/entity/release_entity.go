@@ -1,10 +1,22 @@
 package entity
 
+import "time"
+
 type Release struct {
-	ID         int64      `gorm:"column:id;primaryKey"`
-	TagName    string     `gorm:"column:tagname"`
-	Content    string     `gorm:"column:content"`
-	RepoID     int64      `gorm:"column:repoid"`
-	Repository Repository `gorm:"foreignKey:repoid;references:id"`
-	Commits    []Commit   `gorm:"foreignKey:releaseid;references:id"`
+	ID          int64      `gorm:"column:id;primaryKey"`
+	TagName     string     `gorm:"column:tagname;uniqueIndex:releases_repoid_tagname_key,priority:2"`
+	Content     string     `gorm:"column:content;serializer:content"`
+	Title       string     `gorm:"column:title"`
+	PublishedAt *time.Time `gorm:"column:publishedat"`
+	Author      string     `gorm:"column:author"`
+	Prerelease  bool       `gorm:"column:prerelease"`
+	CreatedAt   time.Time  `gorm:"column:createdat"`
+	RepoID      int64      `gorm:"column:repoid;uniqueIndex:releases_repoid_tagname_key,priority:1"`
+	Status      string     `gorm:"column:status;default:active"`
+	StatusAt    *time.Time `gorm:"column:statusat"`
+	// TombstonedAt is when the release was found gone from GitHub; it is kept, but hidden by default
+	TombstonedAt *time.Time     `gorm:"column:tombstonedat"`
+	Repository   Repository     `gorm:"foreignKey:repoid;references:id"`
+	Commits      []Commit       `gorm:"foreignKey:releaseid;references:id"`
+	Assets       []ReleaseAsset `gorm:"foreignKey:releaseid;references:id"`
 }
@@ -0,0 +1,29 @@
+package entity
+
+// ReleaseNoteItem is an entry of the notes of a release, such as a feature or a bug fix
+type ReleaseNoteItem struct {
+	ID        int64 `gorm:"column:id;primaryKey"`
+	ReleaseID int64 `gorm:"column:releaseid;index:release_note_items_releaseid_idx"`
+	// Position orders the items of a release as they appear in its notes
+	Position int    `gorm:"column:position"`
+	Kind     string `gorm:"column:kind"` // a releasenotes kind: feature, fix, breaking or other
+	Section  string `gorm:"column:section"`
+	Text     string `gorm:"column:text"`
+}
+
+// ReleaseNoteReference is an issue or pull request linked from the notes of a release
+type ReleaseNoteReference struct {
+	ID        int64 `gorm:"column:id;primaryKey"`
+	ReleaseID int64 `gorm:"column:releaseid;index:release_note_references_releaseid_idx"`
+	// Repo is "owner/name", empty for a "#123" reference to the release's own repository
+	Repo   string `gorm:"column:repo"`
+	Number int64  `gorm:"column:number"`
+	Type   string `gorm:"column:type"` // issue, pull or empty when the notes do not tell
+}
+
+// ReleaseNoteMention is a user mentioned in the notes of a release, usually a contributor
+type ReleaseNoteMention struct {
+	ID        int64  `gorm:"column:id;primaryKey"`
+	ReleaseID int64  `gorm:"column:releaseid;index:release_note_mentions_releaseid_idx"`
+	Username  string `gorm:"column:username;index:release_note_mentions_username_idx"`
+}
@@ -1,8 +1,77 @@
 package entity
 
+import (
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Crawl statuses of repositories and releases
+const (
+	StatusActive = "active"
+	// StatusMissing is set when GitHub answers 404; missing entities are no longer crawled
+	StatusMissing = "missing"
+	// StatusBlocked is set when GitHub refuses the crawl; the repository is crawled again after RetryAt
+	StatusBlocked = "blocked"
+)
+
+// DefaultNotFoundThreshold is the number of consecutive 404s after which a repository is
+// archived, unless crawl.not_found_threshold sets another
+const DefaultNotFoundThreshold = 3
+
+var notFoundThreshold atomic.Int64
+
+func init() {
+	notFoundThreshold.Store(DefaultNotFoundThreshold)
+}
+
+// SetNotFoundThreshold sets the number of consecutive 404s after which a repository is archived
+func SetNotFoundThreshold(threshold int) {
+	notFoundThreshold.Store(int64(threshold))
+}
+
+// NotFoundThreshold is the number of consecutive 404s after which a repository is archived
+func NotFoundThreshold() int {
+	return int(notFoundThreshold.Load())
+}
+
 type Repository struct {
-	ID       int64     `gorm:"column:id;primaryKey"`
-	UserName string    `gorm:"column:username"`
-	RepoName string    `gorm:"column:reponame"`
-	Releases []Release `gorm:"foreignKey:repoid;references:id"`
+	ID            int64      `gorm:"column:id;primaryKey"`
+	UserName      string     `gorm:"column:username;uniqueIndex:repositories_name_key,expression:LOWER(username)"`
+	RepoName      string     `gorm:"column:reponame;uniqueIndex:repositories_name_key,expression:LOWER(reponame)"`
+	DefaultBranch string     `gorm:"column:defaultbranch"`
+	Policy        string     `gorm:"column:policy"`
+	Description   string     `gorm:"column:description"`
+	Stars         int64      `gorm:"column:stars"`
+	Forks         int64      `gorm:"column:forks"`
+	Language      string     `gorm:"column:language"`
+	Topics        string     `gorm:"column:topics"` // comma separated
+	License       string     `gorm:"column:license"`
+	EnrichedAt    *time.Time `gorm:"column:enrichedat"`
+	Status        string     `gorm:"column:status;default:active"`
+	StatusAt      *time.Time `gorm:"column:statusat"`
+	RetryAt       *time.Time `gorm:"column:retryat"`
+	// NextCrawlAt is when the decay scheduler re-checks the repository; nil means as soon as possible
+	NextCrawlAt *time.Time `gorm:"column:nextcrawlat"`
+	// NotFoundCount counts the crawls answered 404 in a row; at NotFoundThreshold() the repository
+	// is Archived and soft deleted, which hides it from crawls and lists until it is restored
+	NotFoundCount int            `gorm:"column:notfoundcount"`
+	Archived      bool           `gorm:"column:archived"`
+	DeletedAt     gorm.DeletedAt `gorm:"column:deletedat;index:repositories_deletedat_idx"`
+	Releases      []Release      `gorm:"foreignKey:repoid;references:id"`
+}
+
+// Crawlable reports whether the repository should be crawled at now
+func (r *Repository) Crawlable(now time.Time) bool {
+	if r.Archived || r.DeletedAt.Valid {
+		return false
+	}
+	switch r.Status {
+	case StatusMissing:
+		return false
+	case StatusBlocked:
+		return r.RetryAt == nil || !now.Before(*r.RetryAt)
+	}
+	return true
 }
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// RepoPolicy overrides how a repository is crawled, set through the API. It replaces the
+// repo_policies entry of the config for the repository; zero values leave the crawl unbounded.
+type RepoPolicy struct {
+	RepoID               int64     `gorm:"column:repoid;primaryKey"`
+	MaxReleases          int       `gorm:"column:maxreleases"`
+	MaxCommitsPerRelease int       `gorm:"column:maxcommitsperrelease"`
+	SkipCommits          bool      `gorm:"column:skipcommits"`
+	RequestsPerMinute    float64   `gorm:"column:requestsperminute"`
+	UpdatedAt            time.Time `gorm:"column:updatedat"`
+}
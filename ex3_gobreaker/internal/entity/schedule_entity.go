@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// CrawlSchedule is the cron schedule of a coordinator stage, or of the full cycle named
+// "all". Schedules are seeded from the config and can be edited at runtime.
+type CrawlSchedule struct {
+	Name       string `gorm:"column:name;primaryKey"`
+	Expression string `gorm:"column:expression"`
+	// NextRunAt is persisted before each run, so a restarted scheduler neither repeats
+	// a run nor catches up on the ones it missed
+	NextRunAt  time.Time  `gorm:"column:nextrunat"`
+	LastRunAt  *time.Time `gorm:"column:lastrunat"`
+	LastStatus string     `gorm:"column:laststatus"`
+	LastError  string     `gorm:"column:lasterror"`
+	UpdatedAt  time.Time  `gorm:"column:updatedat"`
+}
@@ -0,0 +1,9 @@
+package entity
+
+type Tag struct {
+	ID         int64      `gorm:"column:id;primaryKey"`
+	Name       string     `gorm:"column:name;uniqueIndex:tags_repoid_name_key,priority:2"`
+	CommitSHA  string     `gorm:"column:commitsha"`
+	RepoID     int64      `gorm:"column:repoid;uniqueIndex:tags_repoid_name_key,priority:1"`
+	Repository Repository `gorm:"foreignKey:repoid;references:id"`
+}
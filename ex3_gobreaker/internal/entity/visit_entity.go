@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type Visit struct {
+	ID         int64     `gorm:"column:id;primaryKey"`
+	URL        string    `gorm:"column:url"`
+	Method     string    `gorm:"column:method"`
+	StatusCode int       `gorm:"column:statuscode"`
+	Bytes      int64     `gorm:"column:bytes"`
+	DurationMs int64     `gorm:"column:durationms"`
+	Error      string    `gorm:"column:error"`
+	VisitedAt  time.Time `gorm:"column:visitedat"`
+}
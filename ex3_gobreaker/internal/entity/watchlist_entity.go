@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+type Watchlist struct {
+	ID           int64        `gorm:"column:id;primaryKey"`
+	Name         string       `gorm:"column:name"`
+	Repositories []Repository `gorm:"many2many:watchlist_repos;joinForeignKey:watchlistid;joinReferences:repoid"`
+}
+
+// WatchlistKeyword is a keyword looked for in the new release notes and commit messages of the
+// repositories of a watchlist. Keywords are stored lowercase with single spaces.
+type WatchlistKeyword struct {
+	ID          int64     `gorm:"column:id;primaryKey"`
+	WatchlistID int64     `gorm:"column:watchlistid;uniqueIndex:watchlist_keywords_key,priority:1"`
+	Keyword     string    `gorm:"column:keyword;uniqueIndex:watchlist_keywords_key,priority:2"`
+	CreatedAt   time.Time `gorm:"column:createdat"`
+}
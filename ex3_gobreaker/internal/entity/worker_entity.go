@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// CrawlWorker is a worker instance registered with the shared job queue
+type CrawlWorker struct {
+	ID          string    `gorm:"column:id;primaryKey"`
+	Host        string    `gorm:"column:host"`
+	Workers     int       `gorm:"column:workers"`
+	Running     int64     `gorm:"column:running"`
+	Processed   int64     `gorm:"column:processed"`
+	Failed      int64     `gorm:"column:failed"`
+	StartedAt   time.Time `gorm:"column:startedat"`
+	HeartbeatAt time.Time `gorm:"column:heartbeatat"`
+}
@@ -0,0 +1,382 @@
+// Package experiment runs the batch experiment, which compares worker counts and batch sizes
+// of the commit queue on a synthetic workload and recommends the fastest combination.
+package experiment
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/queue"
+	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	maxExperimentItems  = 100000
+	maxExperimentTrials = 50
+
+	// phaseBatch is the recorder phase holding batch insert durations
+	phaseBatch = "batch"
+)
+
+var (
+	// ErrExperimentRunning is returned when an experiment is started while another one runs
+	ErrExperimentRunning = errors.New("an experiment is already running")
+	// ErrInvalidExperiment wraps errors about the requested items, worker counts or batch sizes
+	ErrInvalidExperiment = errors.New("invalid experiment")
+)
+
+// Settings are the defaults of the requests leaving out the workload or the combinations
+type Settings struct {
+	// Items is the number of synthetic commits, 2000 by default
+	Items int `mapstructure:"items" json:"items"`
+	// Workers are the worker counts compared, 1, 2, 4 and 8 by default
+	Workers []int `mapstructure:"workers" json:"workers"`
+	// BatchSizes are the batch sizes compared, 10, 50, 100 and 500 by default
+	BatchSizes []int `mapstructure:"batch_sizes" json:"batch_sizes"`
+}
+
+// Validate rejects negative numbers
+func (s Settings) Validate() error {
+	if s.Items < 0 {
+		return errors.New("items must not be negative")
+	}
+	for _, workers := range s.Workers {
+		if workers <= 0 {
+			return errors.New("workers must be positive")
+		}
+	}
+	for _, batchSize := range s.BatchSizes {
+		if batchSize <= 0 {
+			return errors.New("batch_sizes must be positive")
+		}
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left empty
+func (s Settings) WithDefaults() Settings {
+	if s.Items == 0 {
+		s.Items = 2000
+	}
+	if len(s.Workers) == 0 {
+		s.Workers = []int{1, 2, 4, 8}
+	}
+	if len(s.BatchSizes) == 0 {
+		s.BatchSizes = []int{10, 50, 100, 500}
+	}
+	return s
+}
+
+// BatchExperiment runs the same commit ingestion workload through the commit queue with
+// several worker counts and batch sizes, measures throughput and batch latency, and stores
+// the best combination in its own config overlay.
+type BatchExperiment struct {
+	log *logrus.Logger
+	db  *gorm.DB
+	// commitUsecase stores the synthetic commits; it should not publish or announce them
+	commitUsecase *usecase.CommitUsecase
+	defaults      Settings
+	// file is the config overlay the recommendation is written to
+	file string
+
+	// running allows one experiment at a time, the trials would skew each other
+	running sync.Mutex
+}
+
+func NewBatchExperiment(log *logrus.Logger, db *gorm.DB, commitUsecase *usecase.CommitUsecase,
+	defaults Settings, file string) *BatchExperiment {
+	return &BatchExperiment{
+		log:           log,
+		db:            db,
+		commitUsecase: commitUsecase,
+		defaults:      defaults.WithDefaults(),
+		file:          file,
+	}
+}
+
+// Run executes every worker count and batch size combination of the request. The synthetic
+// commits are attached to a temporary repository and release that are removed afterwards.
+func (e *BatchExperiment) Run(ctx context.Context, request *model.BatchExperimentRequest) (*model.BatchExperimentResponse, error) {
+	if !e.running.TryLock() {
+		return nil, ErrExperimentRunning
+	}
+	defer e.running.Unlock()
+
+	e.applyDefaults(request)
+	if err := validateRequest(request); err != nil {
+		return nil, err
+	}
+
+	repo := &entity.Repository{
+		ID:       idgen.Next(e.commitUsecase.IDs),
+		UserName: "experiment",
+		RepoName: fmt.Sprintf("batch-%d", time.Now().Unix()),
+	}
+	if err := e.db.WithContext(ctx).Create(repo).Error; err != nil {
+		e.log.WithError(err).Error("Error creating experiment repository")
+		return nil, err
+	}
+	release := &entity.Release{ID: idgen.Next(e.commitUsecase.IDs), TagName: "experiment", RepoID: repo.ID}
+	if err := e.db.WithContext(ctx).Create(release).Error; err != nil {
+		e.db.Unscoped().Delete(repo)
+		e.log.WithError(err).Error("Error creating experiment release")
+		return nil, err
+	}
+	defer e.cleanup(repo, release)
+
+	workload := make([]*model.CreateCommitRequest, request.Items)
+	for i := range workload {
+		workload[i] = &model.CreateCommitRequest{
+			Hash:      fmt.Sprintf("%040x", i+1),
+			Message:   fmt.Sprintf("experiment commit %d", i+1),
+			ReleaseID: release.ID,
+		}
+	}
+
+	response := &model.BatchExperimentResponse{
+		Trials: make([]model.BatchTrial, 0, len(request.Workers)*len(request.BatchSizes)),
+	}
+	for _, workers := range request.Workers {
+		for _, batchSize := range request.BatchSizes {
+			trial, err := e.runTrial(ctx, workload, workers, batchSize)
+			if err != nil {
+				return nil, err
+			}
+			response.Trials = append(response.Trials, *trial)
+
+			// Each trial starts from an empty release
+			if err := e.db.Where("releaseid = ?", release.ID).Delete(&entity.Commit{}).Error; err != nil {
+				e.log.WithError(err).Error("Error clearing experiment commits")
+				return nil, err
+			}
+		}
+	}
+
+	response.Recommendation = recommend(response.Trials)
+	if err := e.store(response.Recommendation, request.Apply); err != nil {
+		e.log.WithError(err).Error("Error storing experiment recommendation")
+		return nil, err
+	}
+	response.ConfigFile = e.file
+	response.Applied = request.Apply
+
+	e.log.WithFields(logrus.Fields{
+		"trials":     len(response.Trials),
+		"items":      request.Items,
+		"workers":    response.Recommendation.Workers,
+		"batch_size": response.Recommendation.BatchSize,
+		"throughput": response.Recommendation.Throughput,
+		"applied":    request.Apply,
+	}).Info("Batch experiment completed")
+
+	return response, nil
+}
+
+// runTrial pushes the workload through a dedicated commit queue and waits until it is drained
+func (e *BatchExperiment) runTrial(ctx context.Context, workload []*model.CreateCommitRequest, workers int, batchSize int) (*model.BatchTrial, error) {
+	recorder := utils.NewPhaseRecorder()
+	var errorMutex sync.Mutex
+	errorCount := 0
+
+	kind := queue.Kind{Name: "experiment", Label: "Experiment commit", Plural: "experiment commits"}
+	processor := queue.NewProcessor(kind, e.log, func(ctx context.Context, commits []*model.CreateCommitRequest) (queue.Summary, error) {
+		startTime := time.Now()
+		responses, err := e.commitUsecase.BatchCreate(ctx, commits)
+		recorder.Record(phaseBatch, time.Since(startTime))
+		if err != nil {
+			errorMutex.Lock()
+			errorCount++
+			errorMutex.Unlock()
+			return queue.Summary{Failed: len(commits)}, err
+		}
+		return queue.Summary{Created: len(responses)}, nil
+	}, queue.Settings{MaxSize: len(workload), Workers: workers, BatchSize: batchSize})
+
+	processor.Start()
+	defer processor.Stop()
+
+	startTime := time.Now()
+	if _, err := processor.EnqueueAll(ctx, workload); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		snapshot := processor.Snapshot()
+		if snapshot.Size == 0 && snapshot.Processing == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	duration := time.Since(startTime)
+
+	stats := recorder.Stats(phaseBatch)
+	trial := &model.BatchTrial{
+		Workers:    workers,
+		BatchSize:  batchSize,
+		Items:      len(workload),
+		Batches:    stats.Count,
+		Errors:     errorCount,
+		DurationMs: duration.Milliseconds(),
+		Throughput: float64(len(workload)) / duration.Seconds(),
+		BatchP50Ms: stats.P50.Milliseconds(),
+		BatchP95Ms: stats.P95.Milliseconds(),
+		BatchP99Ms: stats.P99.Milliseconds(),
+	}
+
+	e.log.WithFields(logrus.Fields{
+		"workers":      workers,
+		"batch_size":   batchSize,
+		"duration_ms":  trial.DurationMs,
+		"throughput":   trial.Throughput,
+		"batch_p95_ms": trial.BatchP95Ms,
+		"errors":       errorCount,
+	}).Info("Batch experiment trial completed")
+
+	return trial, nil
+}
+
+// applyDefaults fills empty request fields from the "experiment" config section
+func (e *BatchExperiment) applyDefaults(request *model.BatchExperimentRequest) {
+	if request.Items == 0 {
+		request.Items = e.defaults.Items
+	}
+	if len(request.Workers) == 0 {
+		request.Workers = e.defaults.Workers
+	}
+	if len(request.BatchSizes) == 0 {
+		request.BatchSizes = e.defaults.BatchSizes
+	}
+}
+
+func validateRequest(request *model.BatchExperimentRequest) error {
+	if request.Items <= 0 || request.Items > maxExperimentItems {
+		return fmt.Errorf("%w: items must be between 1 and %d", ErrInvalidExperiment, maxExperimentItems)
+	}
+	if len(request.Workers)*len(request.BatchSizes) > maxExperimentTrials {
+		return fmt.Errorf("%w: at most %d worker and batch size combinations are allowed", ErrInvalidExperiment, maxExperimentTrials)
+	}
+	for _, workers := range request.Workers {
+		if workers <= 0 {
+			return fmt.Errorf("%w: worker counts must be positive", ErrInvalidExperiment)
+		}
+	}
+	for _, batchSize := range request.BatchSizes {
+		if batchSize <= 0 {
+			return fmt.Errorf("%w: batch sizes must be positive", ErrInvalidExperiment)
+		}
+	}
+	return nil
+}
+
+// recommend picks the error-free trial with the highest throughput,
+// preferring fewer workers when throughput is the same
+func recommend(trials []model.BatchTrial) model.BatchRecommendation {
+	var best *model.BatchTrial
+	for i := range trials {
+		trial := &trials[i]
+		if trial.Errors > 0 {
+			continue
+		}
+		if best == nil || trial.Throughput > best.Throughput ||
+			(trial.Throughput == best.Throughput && trial.Workers < best.Workers) {
+			best = trial
+		}
+	}
+	if best == nil {
+		return model.BatchRecommendation{}
+	}
+	return model.BatchRecommendation{
+		Workers:    best.Workers,
+		BatchSize:  best.BatchSize,
+		Throughput: best.Throughput,
+	}
+}
+
+// store writes the recommendation to "experiment.recommendation" of the overlay file; with
+// apply, the commit queue settings "queue.workers" and "queue.batch_size" are written too and
+// take effect on the next start, or at once when reload is enabled. Only these keys go in the
+// file, so it never copies the settings or secrets of the other config layers. Keys already in
+// the file are kept.
+func (e *BatchExperiment) store(recommendation model.BatchRecommendation, apply bool) error {
+	if recommendation.Workers == 0 {
+		return errors.New("every trial had errors, no recommendation")
+	}
+
+	overlay := make(map[string]map[string]interface{})
+	data, err := os.ReadFile(e.file)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &overlay); err != nil {
+			return fmt.Errorf("reading %s: %w", e.file, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	section := func(name string) map[string]interface{} {
+		if overlay[name] == nil {
+			overlay[name] = make(map[string]interface{})
+		}
+		return overlay[name]
+	}
+	section("experiment")["recommendation"] = map[string]interface{}{
+		"workers":     recommendation.Workers,
+		"batch_size":  recommendation.BatchSize,
+		"throughput":  recommendation.Throughput,
+		"recorded_at": time.Now().Format(time.RFC3339),
+	}
+	if apply {
+		section("queue")["workers"] = recommendation.Workers
+		section("queue")["batch_size"] = recommendation.BatchSize
+	}
+
+	data, err = json.MarshalIndent(overlay, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it, so a config reload never reads half an overlay
+	tmp, err := os.CreateTemp(filepath.Dir(e.file), filepath.Base(e.file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), e.file)
+}
+
+// cleanup removes the experiment's repository, release and any commits left by a failed trial
+func (e *BatchExperiment) cleanup(repo *entity.Repository, release *entity.Release) {
+	if err := e.db.Where("releaseid = ?", release.ID).Delete(&entity.Commit{}).Error; err != nil {
+		e.log.WithError(err).Warn("Error removing experiment commits")
+	}
+	if err := e.db.Delete(release).Error; err != nil {
+		e.log.WithError(err).Warn("Error removing experiment release")
+	}
+	if err := e.db.Unscoped().Delete(repo).Error; err != nil {
+		e.log.WithError(err).Warn("Error removing experiment repository")
+	}
+}
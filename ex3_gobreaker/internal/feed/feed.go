@@ -0,0 +1,244 @@
+package feed
+
+import (
+	"crawler/baseline/internal/model"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Feed formats and their content types
+const (
+	FormatAtom = "atom"
+	FormatRSS  = "rss"
+	FormatICal = "ical"
+)
+
+var contentTypes = map[string]string{
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatICal: "text/calendar; charset=utf-8",
+}
+
+// ContentType returns the content type of a feed format, or "" if the format is unknown
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Write renders a feed in the given format. selfURL is the URL the feed is served from.
+func Write(w io.Writer, format string, feed *model.ReleaseFeed, selfURL string) error {
+	switch format {
+	case FormatAtom:
+		return WriteAtom(w, feed, selfURL)
+	case FormatRSS:
+		return WriteRSS(w, feed, selfURL)
+	case FormatICal:
+		return WriteICal(w, feed)
+	}
+	return fmt.Errorf("unknown feed format %q", format)
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Link      atomLink    `xml:"link"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// WriteAtom renders a feed as Atom 1.0
+func WriteAtom(w io.Writer, feed *model.ReleaseFeed, selfURL string) error {
+	out := atomFeed{
+		ID:      selfURL,
+		Title:   feed.Title,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}},
+		Author:  atomAuthor{Name: "github-repo-crawler"},
+		Entries: make([]atomEntry, 0, len(feed.Items)),
+	}
+	if feed.Link != "" {
+		out.Links = append(out.Links, atomLink{Href: feed.Link, Rel: "alternate", Type: "text/html"})
+	}
+
+	for _, item := range feed.Items {
+		published := item.Published.UTC().Format(time.RFC3339)
+		entry := atomEntry{
+			ID:        item.Link,
+			Title:     itemTitle(item),
+			Updated:   published,
+			Published: published,
+			Link:      atomLink{Href: item.Link, Rel: "alternate", Type: "text/html"},
+		}
+		if item.Author != "" {
+			entry.Author = &atomAuthor{Name: item.Author}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "text", Body: item.Content}
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+
+	return writeXML(w, out)
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Author      string  `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Description string  `xml:"description,omitempty"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// WriteRSS renders a feed as RSS 2.0
+func WriteRSS(w io.Writer, feed *model.ReleaseFeed, selfURL string) error {
+	link := feed.Link
+	if link == "" {
+		link = selfURL
+	}
+
+	out := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          link,
+			Description:   feed.Title,
+			LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+			Self:          atomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+			Items:         make([]rssItem, 0, len(feed.Items)),
+		},
+	}
+
+	for _, item := range feed.Items {
+		out.Channel.Items = append(out.Channel.Items, rssItem{
+			Title:       itemTitle(item),
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.Link},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Author:      item.Author,
+			Description: item.Content,
+		})
+	}
+
+	return writeXML(w, out)
+}
+
+// WriteICal renders a feed as an iCalendar with an all-day event on each release date
+func WriteICal(w io.Writer, feed *model.ReleaseFeed) error {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var sb strings.Builder
+	writeICalLine(&sb, "BEGIN:VCALENDAR")
+	writeICalLine(&sb, "VERSION:2.0")
+	writeICalLine(&sb, "PRODID:-//github-repo-crawler//releases//EN")
+	writeICalLine(&sb, "CALSCALE:GREGORIAN")
+	writeICalLine(&sb, "X-WR-CALNAME:"+escapeICal(feed.Title))
+
+	for _, item := range feed.Items {
+		day := item.Published.UTC()
+		writeICalLine(&sb, "BEGIN:VEVENT")
+		writeICalLine(&sb, "UID:"+item.ID+"@github-repo-crawler")
+		writeICalLine(&sb, "DTSTAMP:"+stamp)
+		writeICalLine(&sb, "DTSTART;VALUE=DATE:"+day.Format("20060102"))
+		writeICalLine(&sb, "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+		writeICalLine(&sb, "SUMMARY:"+escapeICal(itemTitle(item)))
+		writeICalLine(&sb, "URL:"+item.Link)
+		writeICalLine(&sb, "DESCRIPTION:"+escapeICal(item.Link))
+		writeICalLine(&sb, "END:VEVENT")
+	}
+
+	writeICalLine(&sb, "END:VCALENDAR")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func itemTitle(item model.FeedItem) string {
+	if item.Prerelease {
+		return item.Title + " (pre-release)"
+	}
+	return item.Title
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(v)
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICal(value string) string {
+	return icalEscaper.Replace(value)
+}
+
+// writeICalLine writes a content line folded at 75 octets, as RFC 5545 requires
+func writeICalLine(sb *strings.Builder, line string) {
+	// Continuation lines start with a space, which counts towards their length
+	maxOctets := 75
+	for len(line) > maxOctets {
+		cut := maxOctets
+		// Do not split a multi-byte UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		maxOctets = 74
+	}
+	sb.WriteString(line)
+	sb.WriteString("\r\n")
+}
@@ -0,0 +1,245 @@
+// Package golden checks the scrapers against saved GitHub pages. Each case runs one scraper on
+// the fixture pages served by scrape.FixtureTransport and compares what it extracts with the
+// output saved in the case, so a selector broken by a change of the scrapers or of GitHub's
+// markup fails the check instead of leaving tables empty in production.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"crawler/baseline/internal/scrape"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Scrapers run by the cases
+const (
+	ScraperRepos         = "repos"
+	ScraperRepoMetadata  = "repo_metadata"
+	ScraperRepoLookup    = "repo_lookup"
+	ScraperReleases      = "releases"
+	ScraperCommits       = "commits"
+	ScraperCommitStats   = "commit_stats"
+	ScraperTags          = "tags"
+	ScraperBranches      = "branches"
+	ScraperDefaultBranch = "default_branch"
+)
+
+// Case is one golden test, saved as <name>.json in the golden directory. Scraper picks what
+// runs and the other arguments are passed to it; Expected and ExpectedError are what it
+// extracted when the case was last updated.
+type Case struct {
+	Name    string `json:"-"`
+	Scraper string `json:"scraper"`
+	Owner   string `json:"owner,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+
+	Expected      json.RawMessage `json:"expected"`
+	ExpectedError string          `json:"expectedError,omitempty"`
+}
+
+// Result is the outcome of checking one case. Diff describes the first difference between the
+// expected and the actual output, and is empty when they match.
+type Result struct {
+	Name string
+	Diff string
+	// Err is set when the case could not be run at all
+	Err error
+
+	output      json.RawMessage
+	outputError string
+}
+
+// Passed reports whether the scraper still extracts the expected output
+func (r *Result) Passed() bool {
+	return r.Err == nil && r.Diff == ""
+}
+
+// Golden runs the cases of Dir against the fixture pages of Fixtures
+type Golden struct {
+	Dir      string
+	Fixtures string
+	Log      *logrus.Logger
+}
+
+func New(dir string, fixtures string, log *logrus.Logger) *Golden {
+	return &Golden{Dir: dir, Fixtures: fixtures, Log: log}
+}
+
+// Load reads the cases of the golden directory, sorted by name. A non-empty only keeps the
+// case of that name.
+func (g *Golden) Load(only string) ([]*Case, error) {
+	files, err := filepath.Glob(filepath.Join(g.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	cases := make([]*Case, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if only != "" && name != only {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		c := &Case{Name: name}
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("reading golden case %s: %w", name, err)
+		}
+		cases = append(cases, c)
+	}
+	if only != "" && len(cases) == 0 {
+		return nil, fmt.Errorf("no golden case named %q in %s", only, g.Dir)
+	}
+	return cases, nil
+}
+
+// Check runs every case on the fixture pages and compares the outputs with the expected ones
+func (g *Golden) Check(ctx context.Context, cases []*Case) []*Result {
+	results := make([]*Result, len(cases))
+	for i, c := range cases {
+		results[i] = g.check(ctx, c, scrape.NewFixtureCollector(g.Fixtures))
+	}
+	return results
+}
+
+// Refresh fetches the pages of every case from GitHub into the fixture directory, replacing
+// the saved ones, then saves the new outputs as expected. Review the changes of both
+// directories before committing them.
+func (g *Golden) Refresh(ctx context.Context, cases []*Case) []*Result {
+	results := make([]*Result, len(cases))
+	for i, c := range cases {
+		collector := colly.NewCollector(colly.Async(true))
+		collector.WithTransport(scrape.NewFixtureRecordTransport(g.Fixtures, http.DefaultTransport))
+		results[i] = g.check(ctx, c, collector)
+		if results[i].Err == nil {
+			results[i].Err = g.save(c, results[i])
+		}
+	}
+	return results
+}
+
+// Update saves the outputs of results as the expected outputs of their cases, for a change of
+// the scrapers that alters them on purpose
+func (g *Golden) Update(cases []*Case, results []*Result) error {
+	for i, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("golden case %s: %w", result.Name, result.Err)
+		}
+		if result.Diff == "" {
+			continue
+		}
+		if err := g.save(cases[i], result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Golden) check(ctx context.Context, c *Case, collector *colly.Collector) *Result {
+	result := &Result{Name: c.Name}
+	output, err := g.run(ctx, c, collector)
+	if errors.Is(err, errUnknownScraper) {
+		result.Err = err
+		return result
+	}
+	if err != nil {
+		result.outputError = err.Error()
+	}
+
+	result.output, result.Err = json.MarshalIndent(output, "", "  ")
+	if result.Err != nil {
+		return result
+	}
+	if result.outputError != c.ExpectedError {
+		result.Diff = fmt.Sprintf("error: expected %q, got %q", c.ExpectedError, result.outputError)
+		return result
+	}
+
+	var expected bytes.Buffer
+	if len(c.Expected) > 0 {
+		if err := json.Indent(&expected, c.Expected, "", "  "); err != nil {
+			result.Err = fmt.Errorf("reading expected output: %w", err)
+			return result
+		}
+	} else {
+		expected.WriteString("null")
+	}
+	result.Diff = diff(expected.String(), string(result.output))
+	return result
+}
+
+var errUnknownScraper = errors.New("unknown scraper")
+
+// run runs the scraper of c with its own collector, since a collector does not visit a page twice
+func (g *Golden) run(ctx context.Context, c *Case, collector *colly.Collector) (interface{}, error) {
+	switch c.Scraper {
+	case ScraperRepos:
+		return scrape.NewRepoScrape(g.Log, collector).CrawlAllRepos(scrape.DefaultRepoLimit, scrape.DefaultRepoPages)
+	case ScraperRepoMetadata:
+		return scrape.NewRepoScrape(g.Log, collector).CrawlRepoMetadata(c.Owner, c.Repo)
+	case ScraperRepoLookup:
+		return scrape.NewRepoScrape(g.Log, collector).LookupRepo(c.Owner, c.Repo)
+	case ScraperReleases:
+		return scrape.NewReleaseScrape(g.Log, collector).CrawlReleases(ctx, c.Owner, c.Repo, scrape.RepoLimits{})
+	case ScraperCommits:
+		return scrape.NewCommitScrape(g.Log, collector).CrawlCommit(ctx, c.Owner, c.Repo, c.Tag, c.Branch)
+	case ScraperCommitStats:
+		return scrape.NewCommitScrape(g.Log, collector).CrawlCommitStats(ctx, c.Owner, c.Repo, c.Hash)
+	case ScraperTags:
+		return scrape.NewTagScrape(g.Log, collector).CrawlTags(c.Owner, c.Repo)
+	case ScraperBranches:
+		return scrape.NewBranchScrape(g.Log, collector).ListBranches(c.Owner, c.Repo)
+	case ScraperDefaultBranch:
+		return scrape.NewBranchScrape(g.Log, collector).DetectDefaultBranch(c.Owner, c.Repo)
+	default:
+		return nil, fmt.Errorf("%w %q in golden case %s", errUnknownScraper, c.Scraper, c.Name)
+	}
+}
+
+func (g *Golden) save(c *Case, result *Result) error {
+	c.Expected = result.output
+	c.ExpectedError = result.outputError
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(g.Dir, c.Name+".json"), append(data, '\n'), 0o644)
+}
+
+// diff describes the first line where actual differs from expected
+func diff(expected string, actual string) string {
+	if expected == actual {
+		return ""
+	}
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < max(len(expectedLines), len(actualLines)); i++ {
+		var want, got string
+		if i < len(expectedLines) {
+			want = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if want != got {
+			return fmt.Sprintf("line %d: expected %q, got %q", i+1, strings.TrimSpace(want), strings.TrimSpace(got))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package golden
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// update saves the current outputs as expected, like "crawler golden --update":
+//
+//	go test ./internal/golden -update
+var update = flag.Bool("update", false, "save the current outputs of the scrapers as expected")
+
+func TestGoldens(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	goldens := New(filepath.Join("..", "..", "testdata", "golden"), filepath.Join("..", "..", "testdata", "github"), log)
+	cases, err := goldens.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("no golden cases in %s", goldens.Dir)
+	}
+
+	results := goldens.Check(context.Background(), cases)
+	if *update {
+		if err := goldens.Update(cases, results); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("updated %d golden cases", len(cases))
+		return
+	}
+
+	for _, result := range results {
+		t.Run(result.Name, func(t *testing.T) {
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if result.Diff != "" {
+				t.Error(result.Diff)
+			}
+		})
+	}
+}
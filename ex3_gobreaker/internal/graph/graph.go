@@ -0,0 +1,41 @@
+// Package graph serves /graphql, the nested view of internal/graph/schema.graphqls over the
+// crawled data. The lists nested in a query are loaded through per-request dataloaders, so the
+// releases of N repositories or the commits of N releases take one query rather than N.
+package graph
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+//go:embed schema.graphqls
+var schema string
+
+// maxDepth bounds the nesting of a query, as the types refer to each other
+const maxDepth = 8
+
+// Handler answers the GraphQL queries POSTed as JSON
+type Handler struct {
+	log   *logrus.Logger
+	db    *gorm.DB
+	relay *relay.Handler
+}
+
+// NewHandler parses the schema against the resolvers; it fails when they do not match
+func NewHandler(log *logrus.Logger, db *gorm.DB) (*Handler, error) {
+	parsed, err := graphql.ParseSchema(schema, &Resolver{log: log, db: db}, graphql.MaxDepth(maxDepth))
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{log: log, db: db, relay: &relay.Handler{Schema: parsed}}, nil
+}
+
+// ServeHTTP runs the query with dataloaders of its own, so nothing is cached across requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.relay.ServeHTTP(w, r.WithContext(withLoaders(r.Context(), h.log, h.db)))
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestSchemaMatchesResolvers(t *testing.T) {
+	if _, err := NewHandler(logrus.New(), nil); err != nil {
+		t.Fatalf("schema does not match the resolvers: %v", err)
+	}
+}
+
+// findCall is one query of a page loader
+type findCall struct {
+	parents []int64
+	after   int64
+	limit   int
+}
+
+func TestSiblingPagesShareOneQuery(t *testing.T) {
+	// The database is never reached: the find function stands in for the repository
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	var calls []findCall
+	find := func(ctx context.Context, db *gorm.DB, commits *[]entity.Commit, column string, parentIDs []int64, afterID int64, limit int) error {
+		mutex.Lock()
+		calls = append(calls, findCall{parents: append([]int64(nil), parentIDs...), after: afterID, limit: limit})
+		mutex.Unlock()
+		for _, parent := range parentIDs {
+			for i := int64(1); i <= int64(limit); i++ {
+				*commits = append(*commits, entity.Commit{ID: parent*100 + afterID + i, ReleaseID: parent})
+			}
+		}
+		return nil
+	}
+	loader := newLoader(byPage(db, "releaseid", find, func(commit *entity.Commit) int64 { return commit.ReleaseID }))
+
+	firstPage := page{first: 2, filter: noFilter{}}
+	secondPage := page{first: 2, after: 2, filter: noFilter{}}
+	keys := []pageKey{{parent: 1, page: firstPage}, {parent: 2, page: firstPage}, {parent: 3, page: secondPage}}
+	thunks := make([]dataloader.Thunk, len(keys))
+	for i, key := range keys {
+		thunks[i] = loader.Load(context.Background(), key)
+	}
+	for i, thunk := range thunks {
+		data, err := thunk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits := data.([]entity.Commit)
+		if len(commits) != 3 {
+			t.Fatalf("key %v got %d commits, want the page and one more", keys[i], len(commits))
+		}
+		for _, commit := range commits {
+			if commit.ReleaseID != keys[i].parent {
+				t.Errorf("key %v got commit %d of release %d", keys[i], commit.ID, commit.ReleaseID)
+			}
+		}
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("got %d queries, want one per distinct page: %+v", len(calls), calls)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].after < calls[j].after })
+	sort.Slice(calls[0].parents, func(i, j int) bool { return calls[0].parents[i] < calls[0].parents[j] })
+	if got := calls[0]; len(got.parents) != 2 || got.parents[0] != 1 || got.parents[1] != 2 || got.limit != 3 {
+		t.Errorf("first page query = %+v, want releases 1 and 2 with limit 3", got)
+	}
+	if got := calls[1]; len(got.parents) != 1 || got.parents[0] != 3 || got.after != 2 {
+		t.Errorf("second page query = %+v, want release 3 after 2", got)
+	}
+}
+
+func TestConnectionPaging(t *testing.T) {
+	tags := []entity.Tag{{ID: 4}, {ID: 7}, {ID: 9}}
+	resolver := func(tag *entity.Tag) *tagResolver { return &tagResolver{tag: tag} }
+
+	c := newConnection(tags, page{first: 2}, resolver)
+	if len(c.Nodes()) != 2 || !c.PageInfo().HasNextPage() || *c.PageInfo().EndCursor() != "7" {
+		t.Errorf("page of 2 = %d nodes, next %v, cursor %v; want 2, true, 7",
+			len(c.Nodes()), c.PageInfo().HasNextPage(), *c.PageInfo().EndCursor())
+	}
+	c = newConnection(tags, page{first: 3}, resolver)
+	if len(c.Nodes()) != 3 || c.PageInfo().HasNextPage() {
+		t.Errorf("page of 3 = %d nodes, next %v; want 3, false", len(c.Nodes()), c.PageInfo().HasNextPage())
+	}
+	c = newConnection([]entity.Tag{}, page{first: 3}, resolver)
+	if len(c.Nodes()) != 0 || c.PageInfo().EndCursor() != nil {
+		t.Errorf("empty page has a cursor")
+	}
+}
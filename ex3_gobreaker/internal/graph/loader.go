@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// loadWait is how long a loader collects the keys of sibling fields before querying them together
+const loadWait = 5 * time.Millisecond
+
+type loadersKey struct{}
+
+// loaders batch the lookups of one request
+type loaders struct {
+	// repos finds repositories by ID, archived ones included
+	repos *dataloader.Loader
+	// releases finds releases by ID, gone ones included
+	releases *dataloader.Loader
+	// assets finds the assets of releases
+	assets *dataloader.Loader
+	// repoReleases, releaseCommits and repoTags find pages of the children of their parents
+	repoReleases   *dataloader.Loader
+	releaseCommits *dataloader.Loader
+	repoTags       *dataloader.Loader
+}
+
+func withLoaders(ctx context.Context, log *logrus.Logger, db *gorm.DB) context.Context {
+	repos := repository.NewRepoRepository(log)
+	releases := repository.NewReleaseRepository(log)
+	commits := repository.NewCommitRepository(log)
+	tags := repository.NewTagRepository(log)
+	assets := &repository.Repository[entity.ReleaseAsset]{}
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		repos: newLoader(byID(db, unscoped, repos.FindByIds,
+			func(repo *entity.Repository) int64 { return repo.ID })),
+		releases: newLoader(byID(db, unfiltered, releases.FindByIds,
+			func(release *entity.Release) int64 { return release.ID })),
+		assets: newLoader(byParent(db, "releaseid", assets.FindByParents,
+			func(asset *entity.ReleaseAsset) int64 { return asset.ReleaseID })),
+		repoReleases: newLoader(byPage(db, "repoid", releases.FindAfterByParents,
+			func(release *entity.Release) int64 { return release.RepoID })),
+		releaseCommits: newLoader(byPage(db, "releaseid", commits.FindAfterByParents,
+			func(commit *entity.Commit) int64 { return commit.ReleaseID })),
+		repoTags: newLoader(byPage(db, "repoid", tags.FindAfterByParents,
+			func(tag *entity.Tag) int64 { return tag.RepoID })),
+	})
+}
+
+func loadersOf(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func newLoader(batch dataloader.BatchFunc) *dataloader.Loader {
+	return dataloader.NewBatchedLoader(batch, dataloader.WithWait(loadWait))
+}
+
+// load waits for the value of key, of type T
+func load[T any](ctx context.Context, loader *dataloader.Loader, key dataloader.Key) (T, error) {
+	data, err := loader.Load(ctx, key)()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return data.(T), nil
+}
+
+// idKey is the ID of an entity, or of the parent of entities
+type idKey int64
+
+func (k idKey) String() string   { return strconv.FormatInt(int64(k), 10) }
+func (k idKey) Raw() interface{} { return int64(k) }
+
+// pageKey is a page of the children of parent
+type pageKey struct {
+	parent int64
+	page   page
+}
+
+func (k pageKey) String() string   { return fmt.Sprintf("%d/%s", k.parent, k.page) }
+func (k pageKey) Raw() interface{} { return k }
+
+func unscoped(db *gorm.DB) *gorm.DB   { return db.Unscoped() }
+func unfiltered(db *gorm.DB) *gorm.DB { return db }
+
+// byID loads the entities of idKeys as *T, nil for the unknown ones
+func byID[T any](db *gorm.DB, scope func(*gorm.DB) *gorm.DB,
+	find func(ctx context.Context, db *gorm.DB, entities *[]T, ids []int64) error,
+	id func(*T) int64) dataloader.BatchFunc {
+	return func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+		ids := make([]int64, len(keys))
+		for i, key := range keys {
+			ids[i] = key.Raw().(int64)
+		}
+		var rows []T
+		err := find(ctx, db.Scopes(scope), &rows, ids)
+		found := make(map[int64]*T, len(rows))
+		for i := range rows {
+			found[id(&rows[i])] = &rows[i]
+		}
+		results := make([]*dataloader.Result, len(keys))
+		for i := range keys {
+			results[i] = &dataloader.Result{Data: found[ids[i]], Error: err}
+		}
+		return results
+	}
+}
+
+// byParent loads, as []T, all the children of the parents of idKeys, column holding the parent
+func byParent[T any](db *gorm.DB, column string,
+	find func(ctx context.Context, db *gorm.DB, entities *[]T, column string, parentIDs []int64) error,
+	parent func(*T) int64) dataloader.BatchFunc {
+	return func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+		parents := make([]int64, len(keys))
+		for i, key := range keys {
+			parents[i] = key.Raw().(int64)
+		}
+		var rows []T
+		err := find(ctx, db, &rows, column, parents)
+		children := groupByParent(rows, parent)
+		results := make([]*dataloader.Result, len(keys))
+		for i := range keys {
+			results[i] = &dataloader.Result{Data: children[parents[i]], Error: err}
+		}
+		return results
+	}
+}
+
+// byPage loads, as []T, the pages of the children of the parents of pageKeys. The keys asking
+// for the same page, the children of sibling fields with the same arguments, take one query.
+// A page holds one more row than asked, which tells whether another follows.
+func byPage[T any](db *gorm.DB, column string,
+	find func(ctx context.Context, db *gorm.DB, entities *[]T, column string, parentIDs []int64, afterID int64, limit int) error,
+	parent func(*T) int64) dataloader.BatchFunc {
+	return func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+		pages := make(map[string][]int)
+		for i, key := range keys {
+			page := key.Raw().(pageKey).page.String()
+			pages[page] = append(pages[page], i)
+		}
+		results := make([]*dataloader.Result, len(keys))
+		for _, indexes := range pages {
+			page := keys[indexes[0]].Raw().(pageKey).page
+			parents := make([]int64, len(indexes))
+			for j, i := range indexes {
+				parents[j] = keys[i].Raw().(pageKey).parent
+			}
+			var rows []T
+			err := find(ctx, db.Scopes(page.filter.scope), &rows, column, parents, page.after, page.first+1)
+			children := groupByParent(rows, parent)
+			for j, i := range indexes {
+				results[i] = &dataloader.Result{Data: children[parents[j]], Error: err}
+			}
+		}
+		return results
+	}
+}
+
+func groupByParent[T any](rows []T, parent func(*T) int64) map[int64][]T {
+	children := make(map[int64][]T)
+	for i := range rows {
+		children[parent(&rows[i])] = append(children[parent(&rows[i])], rows[i])
+	}
+	return children
+}
@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"crawler/baseline/internal/repository"
+	"fmt"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+// page selects up to first rows with an ID above after, among those of filter
+type page struct {
+	first int
+	after int64
+	filter
+}
+
+func (p page) String() string {
+	return fmt.Sprintf("%d/%d/%s", p.first, p.after, p.filter)
+}
+
+// filter holds the conditions of a list argument; String names them, so the lists of sibling
+// fields with the same conditions are loaded together
+type filter interface {
+	scope(db *gorm.DB) *gorm.DB
+	String() string
+}
+
+// pageOf checks the paging arguments of a list; first has the default of the schema
+func pageOf(first int32, after *graphql.ID, filter filter) (page, error) {
+	p := page{first: int(first), filter: filter}
+	if first < 0 || first > maxFirst {
+		return p, fmt.Errorf("first must be between 0 and %d", maxFirst)
+	}
+	if after != nil {
+		id, err := parseID(*after)
+		if err != nil {
+			return p, err
+		}
+		p.after = id
+	}
+	return p, nil
+}
+
+type noFilter struct{}
+
+func (noFilter) scope(db *gorm.DB) *gorm.DB { return db }
+func (noFilter) String() string             { return "" }
+
+// repoFilter is the RepoFilter input; every field set must match
+type repoFilter struct {
+	Owner    *string
+	Language *string
+	Topic    *string
+	Policy   *string
+}
+
+func (f *repoFilter) scope(db *gorm.DB) *gorm.DB {
+	if f == nil {
+		return db
+	}
+	if f.Owner != nil {
+		db = db.Where("LOWER(username) = LOWER(?)", *f.Owner)
+	}
+	if f.Language != nil {
+		db = db.Where("LOWER(language) = LOWER(?)", *f.Language)
+	}
+	if f.Topic != nil {
+		// Topics are stored comma separated
+		db = db.Where("',' || LOWER(topics) || ',' LIKE ?", "%,"+strings.ToLower(*f.Topic)+",%")
+	}
+	if f.Policy != nil {
+		db = db.Where("policy = ?", *f.Policy)
+	}
+	return db
+}
+
+func (f *repoFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", valueOf(f.Owner), valueOf(f.Language), valueOf(f.Topic), valueOf(f.Policy))
+}
+
+// releaseFilter is the ReleaseFilter input; the releases gone from GitHub are always left out
+type releaseFilter struct {
+	Prerelease      *bool
+	PublishedAfter  *graphql.Time
+	PublishedBefore *graphql.Time
+}
+
+func (f releaseFilter) scope(db *gorm.DB) *gorm.DB {
+	db = db.Scopes(repository.NotTombstoned)
+	if f.Prerelease != nil {
+		db = db.Where("prerelease = ?", *f.Prerelease)
+	}
+	if f.PublishedAfter != nil {
+		db = db.Where("publishedat >= ?", f.PublishedAfter.Time)
+	}
+	if f.PublishedBefore != nil {
+		db = db.Where("publishedat < ?", f.PublishedBefore.Time)
+	}
+	return db
+}
+
+func (f releaseFilter) String() string {
+	return fmt.Sprintf("%s|%s|%s", valueOf(f.Prerelease), valueOf(f.PublishedAfter), valueOf(f.PublishedBefore))
+}
+
+// valueOf formats an optional argument, "" when unset
+func valueOf[T any](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(*value)
+}
+
+type node interface {
+	ID() graphql.ID
+}
+
+// connection is a page of a list
+type connection[N node] struct {
+	nodes []N
+	info  pageInfo
+}
+
+// newConnection makes the page of rows, which hold one more row than page when another follows
+func newConnection[T any, N node](rows []T, page page, resolver func(*T) N) *connection[N] {
+	c := &connection[N]{nodes: make([]N, 0, min(len(rows), page.first))}
+	for i := range rows {
+		if i == page.first {
+			c.info.hasNextPage = true
+			break
+		}
+		c.nodes = append(c.nodes, resolver(&rows[i]))
+	}
+	if len(c.nodes) > 0 {
+		cursor := c.nodes[len(c.nodes)-1].ID()
+		c.info.endCursor = &cursor
+	}
+	return c
+}
+
+func (c *connection[N]) Nodes() []N         { return c.nodes }
+func (c *connection[N]) PageInfo() pageInfo { return c.info }
+
+type pageInfo struct {
+	hasNextPage bool
+	// endCursor is the ID of the last node, the after argument of the next page
+	endCursor *graphql.ID
+}
+
+func (p pageInfo) HasNextPage() bool      { return p.hasNextPage }
+func (p pageInfo) EndCursor() *graphql.ID { return p.endCursor }
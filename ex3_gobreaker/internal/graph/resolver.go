@@ -0,0 +1,306 @@
+package graph
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxFirst bounds the page size of a list; the schema sets the default ones
+const maxFirst = 100
+
+// errQuery is reported for the failed database queries, which are logged
+var errQuery = errors.New("Error querying database")
+
+// Resolver resolves the fields of Query
+type Resolver struct {
+	log *logrus.Logger
+	db  *gorm.DB
+}
+
+type idArgs struct {
+	ID graphql.ID
+}
+
+type pageArgs struct {
+	First int32
+	After *graphql.ID
+}
+
+type repoPageArgs struct {
+	Filter *repoFilter
+	First  int32
+	After  *graphql.ID
+}
+
+type releasePageArgs struct {
+	Filter *releaseFilter
+	First  int32
+	After  *graphql.ID
+}
+
+// Repo returns a repository, archived ones included as GET /api/repos/{repoID}
+func (r *Resolver) Repo(ctx context.Context, args idArgs) (*repoResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := load[*entity.Repository](ctx, loadersOf(ctx).repos, idKey(id))
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	if repo == nil {
+		return nil, nil
+	}
+	return &repoResolver{root: r, repo: repo}, nil
+}
+
+// Repos returns a page of the tracked repositories matching filter
+func (r *Resolver) Repos(ctx context.Context, args repoPageArgs) (*connection[*repoResolver], error) {
+	page, err := pageOf(args.First, args.After, args.Filter)
+	if err != nil {
+		return nil, err
+	}
+	var repos []entity.Repository
+	err = repository.NewRepoRepository(r.log).FindAfter(ctx, r.db.Scopes(page.filter.scope), &repos, page.after, page.first+1)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	return newConnection(repos, page, func(repo *entity.Repository) *repoResolver {
+		return &repoResolver{root: r, repo: repo}
+	}), nil
+}
+
+// Release returns a release, gone ones included as GET /api/releases/{releaseID}
+func (r *Resolver) Release(ctx context.Context, args idArgs) (*releaseResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	release, err := load[*entity.Release](ctx, loadersOf(ctx).releases, idKey(id))
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	if release == nil {
+		return nil, nil
+	}
+	return &releaseResolver{root: r, release: release}, nil
+}
+
+// Commit returns a commit, as GET /api/commits/{commitID}
+func (r *Resolver) Commit(ctx context.Context, args idArgs) (*commitResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	commit := &entity.Commit{}
+	err = repository.NewCommitRepository(r.log).FindById(ctx, r.db, commit, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	return &commitResolver{root: r, commit: commit}, nil
+}
+
+// queryError logs err and hides it from the client, as the HTTP API does
+func (r *Resolver) queryError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	r.log.WithError(err).Error("GraphQL query failed")
+	return errQuery
+}
+
+type repoResolver struct {
+	root *Resolver
+	repo *entity.Repository
+}
+
+func (r *repoResolver) ID() graphql.ID            { return idOf(r.repo.ID) }
+func (r *repoResolver) UserName() string          { return r.repo.UserName }
+func (r *repoResolver) RepoName() string          { return r.repo.RepoName }
+func (r *repoResolver) DefaultBranch() string     { return r.repo.DefaultBranch }
+func (r *repoResolver) Policy() string            { return r.repo.Policy }
+func (r *repoResolver) Description() string       { return r.repo.Description }
+func (r *repoResolver) Stars() int32              { return int32Of(r.repo.Stars) }
+func (r *repoResolver) Forks() int32              { return int32Of(r.repo.Forks) }
+func (r *repoResolver) Language() string          { return r.repo.Language }
+func (r *repoResolver) License() string           { return r.repo.License }
+func (r *repoResolver) EnrichedAt() *graphql.Time { return timeOf(r.repo.EnrichedAt) }
+
+func (r *repoResolver) Topics() []string {
+	if r.repo.Topics == "" {
+		return []string{}
+	}
+	return strings.Split(r.repo.Topics, ",")
+}
+
+// Releases returns a page of the releases of the repository still on GitHub
+func (r *repoResolver) Releases(ctx context.Context, args releasePageArgs) (*connection[*releaseResolver], error) {
+	filter := releaseFilter{}
+	if args.Filter != nil {
+		filter = *args.Filter
+	}
+	page, err := pageOf(args.First, args.After, filter)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := load[[]entity.Release](ctx, loadersOf(ctx).repoReleases, pageKey{parent: r.repo.ID, page: page})
+	if err != nil {
+		return nil, r.root.queryError(err)
+	}
+	return newConnection(releases, page, func(release *entity.Release) *releaseResolver {
+		return &releaseResolver{root: r.root, release: release}
+	}), nil
+}
+
+// Tags returns a page of the tags of the repository
+func (r *repoResolver) Tags(ctx context.Context, args pageArgs) ([]*tagResolver, error) {
+	page, err := pageOf(args.First, args.After, noFilter{})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := load[[]entity.Tag](ctx, loadersOf(ctx).repoTags, pageKey{parent: r.repo.ID, page: page})
+	if err != nil {
+		return nil, r.root.queryError(err)
+	}
+	return newConnection(tags, page, func(tag *entity.Tag) *tagResolver { return &tagResolver{tag: tag} }).nodes, nil
+}
+
+type releaseResolver struct {
+	root    *Resolver
+	release *entity.Release
+}
+
+func (r *releaseResolver) ID() graphql.ID             { return idOf(r.release.ID) }
+func (r *releaseResolver) TagName() string            { return r.release.TagName }
+func (r *releaseResolver) Title() string              { return r.release.Title }
+func (r *releaseResolver) Content() string            { return r.release.Content }
+func (r *releaseResolver) PublishedAt() *graphql.Time { return timeOf(r.release.PublishedAt) }
+func (r *releaseResolver) Author() string             { return r.release.Author }
+func (r *releaseResolver) Prerelease() bool           { return r.release.Prerelease }
+func (r *releaseResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.release.CreatedAt} }
+
+// Repo returns the repository of the release, archived or not
+func (r *releaseResolver) Repo(ctx context.Context) (*repoResolver, error) {
+	repo, err := load[*entity.Repository](ctx, loadersOf(ctx).repos, idKey(r.release.RepoID))
+	if err != nil {
+		return nil, r.root.queryError(err)
+	}
+	if repo == nil {
+		return nil, fmt.Errorf("repository %d of release %d not found", r.release.RepoID, r.release.ID)
+	}
+	return &repoResolver{root: r.root, repo: repo}, nil
+}
+
+func (r *releaseResolver) Assets(ctx context.Context) ([]*assetResolver, error) {
+	assets, err := load[[]entity.ReleaseAsset](ctx, loadersOf(ctx).assets, idKey(r.release.ID))
+	if err != nil {
+		return nil, r.root.queryError(err)
+	}
+	resolvers := make([]*assetResolver, len(assets))
+	for i := range assets {
+		resolvers[i] = &assetResolver{asset: &assets[i]}
+	}
+	return resolvers, nil
+}
+
+// Commits returns a page of the commits of the release
+func (r *releaseResolver) Commits(ctx context.Context, args pageArgs) (*connection[*commitResolver], error) {
+	page, err := pageOf(args.First, args.After, noFilter{})
+	if err != nil {
+		return nil, err
+	}
+	commits, err := load[[]entity.Commit](ctx, loadersOf(ctx).releaseCommits, pageKey{parent: r.release.ID, page: page})
+	if err != nil {
+		return nil, r.root.queryError(err)
+	}
+	return newConnection(commits, page, func(commit *entity.Commit) *commitResolver {
+		return &commitResolver{root: r.root, commit: commit}
+	}), nil
+}
+
+type commitResolver struct {
+	root   *Resolver
+	commit *entity.Commit
+}
+
+func (r *commitResolver) ID() graphql.ID       { return idOf(r.commit.ID) }
+func (r *commitResolver) Hash() string         { return r.commit.Hash }
+func (r *commitResolver) Message() string      { return r.commit.Message }
+func (r *commitResolver) FilesChanged() *int32 { return statOf(r.commit.FilesChanged) }
+func (r *commitResolver) Additions() *int32    { return statOf(r.commit.Additions) }
+func (r *commitResolver) Deletions() *int32    { return statOf(r.commit.Deletions) }
+
+// Release returns the release of the commit, gone from GitHub or not
+func (r *commitResolver) Release(ctx context.Context) (*releaseResolver, error) {
+	release, err := load[*entity.Release](ctx, loadersOf(ctx).releases, idKey(r.commit.ReleaseID))
+	if err != nil {
+		return nil, r.root.queryError(err)
+	}
+	if release == nil {
+		return nil, fmt.Errorf("release %d of commit %d not found", r.commit.ReleaseID, r.commit.ID)
+	}
+	return &releaseResolver{root: r.root, release: release}, nil
+}
+
+type tagResolver struct {
+	tag *entity.Tag
+}
+
+func (r *tagResolver) ID() graphql.ID    { return idOf(r.tag.ID) }
+func (r *tagResolver) Name() string      { return r.tag.Name }
+func (r *tagResolver) CommitSHA() string { return r.tag.CommitSHA }
+
+type assetResolver struct {
+	asset *entity.ReleaseAsset
+}
+
+func (r *assetResolver) Name() string         { return r.asset.Name }
+func (r *assetResolver) Size() int32          { return int32Of(r.asset.Size) }
+func (r *assetResolver) DownloadCount() int32 { return int32Of(r.asset.DownloadCount) }
+
+func idOf(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
+
+func parseID(id graphql.ID) (int64, error) {
+	parsed, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid ID %q", id)
+	}
+	return parsed, nil
+}
+
+func timeOf(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+// int32Of converts a count for the Int type of GraphQL, capped at its maximum
+func int32Of(value int64) int32 {
+	return int32(min(value, math.MaxInt32))
+}
+
+// statOf converts an optional commit stat, nil staying null
+func statOf(value *int) *int32 {
+	if value == nil {
+		return nil
+	}
+	converted := int32Of(int64(*value))
+	return &converted
+}
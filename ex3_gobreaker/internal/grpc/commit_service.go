@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/grpc/pb"
+	"crawler/baseline/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type commitService struct {
+	pb.UnimplementedCommitServiceServer
+	server *Server
+}
+
+// Get returns a commit, as GET /api/commits/{commitID}
+func (s *commitService) Get(ctx context.Context, request *pb.GetRequest) (*pb.Commit, error) {
+	commit := &entity.Commit{}
+	if err := repository.NewCommitRepository(s.server.log).FindById(ctx, s.server.db, commit, request.Id); err != nil {
+		return nil, s.server.statusError(err, "Commit not found")
+	}
+	return commitMessage(commit), nil
+}
+
+// List returns a page of the commits, of one release when parent_id is set
+func (s *commitService) List(ctx context.Context, request *pb.ListRequest) (*pb.ListCommitsResponse, error) {
+	limit, err := pageLimit(request)
+	if err != nil {
+		return nil, err
+	}
+	var commits []entity.Commit
+	err = repository.NewCommitRepository(s.server.log).FindAfter(ctx, s.commits(request.ParentId), &commits, request.AfterId, limit)
+	if err != nil {
+		return nil, s.server.statusError(err, "Error querying database")
+	}
+	response := &pb.ListCommitsResponse{Commits: make([]*pb.Commit, len(commits))}
+	for i := range commits {
+		response.Commits[i] = commitMessage(&commits[i])
+	}
+	return response, nil
+}
+
+// Crawl saves the commits of one release, or of every release as GET /api/commits/crawl
+func (s *commitService) Crawl(ctx context.Context, request *pb.CrawlRequest) (*pb.CrawlResponse, error) {
+	return s.server.crawl(ctx, pb.CommitService_Crawl_FullMethodName, func(ctx context.Context) error {
+		if request.ParentId != 0 {
+			return s.server.commitController.CrawlRelease(ctx, request.ParentId)
+		}
+		_, err := s.server.commitController.CrawlCommits(ctx, 0)
+		return err
+	})
+}
+
+// Stream sends every commit after after_id, of one release when parent_id is set
+func (s *commitService) Stream(request *pb.ListRequest, stream pb.CommitService_StreamServer) error {
+	if _, err := pageLimit(request); err != nil {
+		return err
+	}
+	err := streamRows(stream.Context(), s.commits(request.ParentId), request.AfterId,
+		repository.NewCommitRepository(s.server.log).FindAfter,
+		func(commit *entity.Commit) int64 { return commit.ID },
+		func(commit *entity.Commit) error { return stream.Send(commitMessage(commit)) })
+	if err != nil {
+		return s.server.statusError(err, "Error streaming commits")
+	}
+	return nil
+}
+
+// commits selects the commits of releaseID, or of every release when 0
+func (s *commitService) commits(releaseID int64) *gorm.DB {
+	if releaseID != 0 {
+		return s.server.db.Where("releaseid = ?", releaseID)
+	}
+	return s.server.db
+}
+
+func commitMessage(commit *entity.Commit) *pb.Commit {
+	return &pb.Commit{
+		Id:           commit.ID,
+		Hash:         commit.Hash,
+		Message:      commit.Message,
+		FilesChanged: int32Of(commit.FilesChanged),
+		Additions:    int32Of(commit.Additions),
+		Deletions:    int32Of(commit.Deletions),
+		ReleaseId:    commit.ReleaseID,
+	}
+}
+
+// int32Of converts an optional stat, nil staying unset
+func int32Of(value *int) *int32 {
+	if value == nil {
+		return nil
+	}
+	converted := int32(*value)
+	return &converted
+}
@@ -0,0 +1,944 @@
+// Typed API of the crawler for internal services, mirroring the HTTP endpoints under /api.
+// The messages follow internal/model; IDs are the database IDs used by the HTTP API.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: crawler.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Repo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserName      string                 `protobuf:"bytes,2,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	RepoName      string                 `protobuf:"bytes,3,opt,name=repo_name,json=repoName,proto3" json:"repo_name,omitempty"`
+	DefaultBranch string                 `protobuf:"bytes,4,opt,name=default_branch,json=defaultBranch,proto3" json:"default_branch,omitempty"`
+	Policy        string                 `protobuf:"bytes,5,opt,name=policy,proto3" json:"policy,omitempty"`
+	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Stars         int64                  `protobuf:"varint,7,opt,name=stars,proto3" json:"stars,omitempty"`
+	Forks         int64                  `protobuf:"varint,8,opt,name=forks,proto3" json:"forks,omitempty"`
+	Language      string                 `protobuf:"bytes,9,opt,name=language,proto3" json:"language,omitempty"`
+	Topics        []string               `protobuf:"bytes,10,rep,name=topics,proto3" json:"topics,omitempty"`
+	License       string                 `protobuf:"bytes,11,opt,name=license,proto3" json:"license,omitempty"`
+	EnrichedAt    *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=enriched_at,json=enrichedAt,proto3" json:"enriched_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Repo) Reset() {
+	*x = Repo{}
+	mi := &file_crawler_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Repo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Repo) ProtoMessage() {}
+
+func (x *Repo) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Repo.ProtoReflect.Descriptor instead.
+func (*Repo) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Repo) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Repo) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *Repo) GetRepoName() string {
+	if x != nil {
+		return x.RepoName
+	}
+	return ""
+}
+
+func (x *Repo) GetDefaultBranch() string {
+	if x != nil {
+		return x.DefaultBranch
+	}
+	return ""
+}
+
+func (x *Repo) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *Repo) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Repo) GetStars() int64 {
+	if x != nil {
+		return x.Stars
+	}
+	return 0
+}
+
+func (x *Repo) GetForks() int64 {
+	if x != nil {
+		return x.Forks
+	}
+	return 0
+}
+
+func (x *Repo) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *Repo) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+func (x *Repo) GetLicense() string {
+	if x != nil {
+		return x.License
+	}
+	return ""
+}
+
+func (x *Repo) GetEnrichedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EnrichedAt
+	}
+	return nil
+}
+
+type ReleaseAsset struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	DownloadCount int64                  `protobuf:"varint,3,opt,name=download_count,json=downloadCount,proto3" json:"download_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseAsset) Reset() {
+	*x = ReleaseAsset{}
+	mi := &file_crawler_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseAsset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseAsset) ProtoMessage() {}
+
+func (x *ReleaseAsset) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseAsset.ProtoReflect.Descriptor instead.
+func (*ReleaseAsset) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReleaseAsset) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ReleaseAsset) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ReleaseAsset) GetDownloadCount() int64 {
+	if x != nil {
+		return x.DownloadCount
+	}
+	return 0
+}
+
+type Release struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	TagName       string                 `protobuf:"bytes,2,opt,name=tag_name,json=tagName,proto3" json:"tag_name,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Title         string                 `protobuf:"bytes,4,opt,name=title,proto3" json:"title,omitempty"`
+	PublishedAt   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=published_at,json=publishedAt,proto3" json:"published_at,omitempty"`
+	Author        string                 `protobuf:"bytes,6,opt,name=author,proto3" json:"author,omitempty"`
+	Prerelease    bool                   `protobuf:"varint,7,opt,name=prerelease,proto3" json:"prerelease,omitempty"`
+	Assets        []*ReleaseAsset        `protobuf:"bytes,8,rep,name=assets,proto3" json:"assets,omitempty"`
+	RepoId        int64                  `protobuf:"varint,9,opt,name=repo_id,json=repoId,proto3" json:"repo_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Release) Reset() {
+	*x = Release{}
+	mi := &file_crawler_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Release) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Release) ProtoMessage() {}
+
+func (x *Release) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Release.ProtoReflect.Descriptor instead.
+func (*Release) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Release) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Release) GetTagName() string {
+	if x != nil {
+		return x.TagName
+	}
+	return ""
+}
+
+func (x *Release) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Release) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Release) GetPublishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishedAt
+	}
+	return nil
+}
+
+func (x *Release) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Release) GetPrerelease() bool {
+	if x != nil {
+		return x.Prerelease
+	}
+	return false
+}
+
+func (x *Release) GetAssets() []*ReleaseAsset {
+	if x != nil {
+		return x.Assets
+	}
+	return nil
+}
+
+func (x *Release) GetRepoId() int64 {
+	if x != nil {
+		return x.RepoId
+	}
+	return 0
+}
+
+type Commit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hash          string                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	FilesChanged  *int32                 `protobuf:"varint,4,opt,name=files_changed,json=filesChanged,proto3,oneof" json:"files_changed,omitempty"`
+	Additions     *int32                 `protobuf:"varint,5,opt,name=additions,proto3,oneof" json:"additions,omitempty"`
+	Deletions     *int32                 `protobuf:"varint,6,opt,name=deletions,proto3,oneof" json:"deletions,omitempty"`
+	ReleaseId     int64                  `protobuf:"varint,7,opt,name=release_id,json=releaseId,proto3" json:"release_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Commit) Reset() {
+	*x = Commit{}
+	mi := &file_crawler_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Commit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Commit) ProtoMessage() {}
+
+func (x *Commit) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Commit.ProtoReflect.Descriptor instead.
+func (*Commit) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Commit) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Commit) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Commit) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Commit) GetFilesChanged() int32 {
+	if x != nil && x.FilesChanged != nil {
+		return *x.FilesChanged
+	}
+	return 0
+}
+
+func (x *Commit) GetAdditions() int32 {
+	if x != nil && x.Additions != nil {
+		return *x.Additions
+	}
+	return 0
+}
+
+func (x *Commit) GetDeletions() int32 {
+	if x != nil && x.Deletions != nil {
+		return *x.Deletions
+	}
+	return 0
+}
+
+func (x *Commit) GetReleaseId() int64 {
+	if x != nil {
+		return x.ReleaseId
+	}
+	return 0
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_crawler_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// ListRequest pages by ID: the next page starts after the last ID of the previous one
+type ListRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Parent filters releases by repository and commits by release; 0 lists everything. Repos
+	// have no parent.
+	ParentId int64 `protobuf:"varint,1,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	AfterId  int64 `protobuf:"varint,2,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	// Limit is 100 when 0, at most 1000; Stream ignores it
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_crawler_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListRequest) GetParentId() int64 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+func (x *ListRequest) GetAfterId() int64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+func (x *ListRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// CrawlRequest crawls the releases of one repository, or the commits of one release, or those
+// of every repository or release when parent_id is 0. Repos are crawled from the GitHub ranking
+// only, with parent_id 0.
+type CrawlRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ParentId      int64                  `protobuf:"varint,1,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrawlRequest) Reset() {
+	*x = CrawlRequest{}
+	mi := &file_crawler_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrawlRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrawlRequest) ProtoMessage() {}
+
+func (x *CrawlRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrawlRequest.ProtoReflect.Descriptor instead.
+func (*CrawlRequest) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CrawlRequest) GetParentId() int64 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+type CrawlResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Items is how many repos, releases or commits were saved, stored ones included
+	Items      int64 `protobuf:"varint,1,opt,name=items,proto3" json:"items,omitempty"`
+	DurationMs int64 `protobuf:"varint,2,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	// Errors counts the repositories, releases or commits that failed to crawl or save
+	Errors        int64 `protobuf:"varint,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrawlResponse) Reset() {
+	*x = CrawlResponse{}
+	mi := &file_crawler_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrawlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrawlResponse) ProtoMessage() {}
+
+func (x *CrawlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrawlResponse.ProtoReflect.Descriptor instead.
+func (*CrawlResponse) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CrawlResponse) GetItems() int64 {
+	if x != nil {
+		return x.Items
+	}
+	return 0
+}
+
+func (x *CrawlResponse) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *CrawlResponse) GetErrors() int64 {
+	if x != nil {
+		return x.Errors
+	}
+	return 0
+}
+
+type ListReposResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repos         []*Repo                `protobuf:"bytes,1,rep,name=repos,proto3" json:"repos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReposResponse) Reset() {
+	*x = ListReposResponse{}
+	mi := &file_crawler_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReposResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReposResponse) ProtoMessage() {}
+
+func (x *ListReposResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReposResponse.ProtoReflect.Descriptor instead.
+func (*ListReposResponse) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListReposResponse) GetRepos() []*Repo {
+	if x != nil {
+		return x.Repos
+	}
+	return nil
+}
+
+type ListReleasesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Releases      []*Release             `protobuf:"bytes,1,rep,name=releases,proto3" json:"releases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReleasesResponse) Reset() {
+	*x = ListReleasesResponse{}
+	mi := &file_crawler_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReleasesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReleasesResponse) ProtoMessage() {}
+
+func (x *ListReleasesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReleasesResponse.ProtoReflect.Descriptor instead.
+func (*ListReleasesResponse) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListReleasesResponse) GetReleases() []*Release {
+	if x != nil {
+		return x.Releases
+	}
+	return nil
+}
+
+type ListCommitsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Commits       []*Commit              `protobuf:"bytes,1,rep,name=commits,proto3" json:"commits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommitsResponse) Reset() {
+	*x = ListCommitsResponse{}
+	mi := &file_crawler_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommitsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommitsResponse) ProtoMessage() {}
+
+func (x *ListCommitsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommitsResponse.ProtoReflect.Descriptor instead.
+func (*ListCommitsResponse) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListCommitsResponse) GetCommits() []*Commit {
+	if x != nil {
+		return x.Commits
+	}
+	return nil
+}
+
+var File_crawler_proto protoreflect.FileDescriptor
+
+const file_crawler_proto_rawDesc = "" +
+	"\n" +
+	"\rcrawler.proto\x12\n" +
+	"crawler.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe8\x02\n" +
+	"\x04Repo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1b\n" +
+	"\tuser_name\x18\x02 \x01(\tR\buserName\x12\x1b\n" +
+	"\trepo_name\x18\x03 \x01(\tR\brepoName\x12%\n" +
+	"\x0edefault_branch\x18\x04 \x01(\tR\rdefaultBranch\x12\x16\n" +
+	"\x06policy\x18\x05 \x01(\tR\x06policy\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05stars\x18\a \x01(\x03R\x05stars\x12\x14\n" +
+	"\x05forks\x18\b \x01(\x03R\x05forks\x12\x1a\n" +
+	"\blanguage\x18\t \x01(\tR\blanguage\x12\x16\n" +
+	"\x06topics\x18\n" +
+	" \x03(\tR\x06topics\x12\x18\n" +
+	"\alicense\x18\v \x01(\tR\alicense\x12;\n" +
+	"\venriched_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"enrichedAt\"]\n" +
+	"\fReleaseAsset\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12%\n" +
+	"\x0edownload_count\x18\x03 \x01(\x03R\rdownloadCount\"\xa6\x02\n" +
+	"\aRelease\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\btag_name\x18\x02 \x01(\tR\atagName\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x12\x14\n" +
+	"\x05title\x18\x04 \x01(\tR\x05title\x12=\n" +
+	"\fpublished_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vpublishedAt\x12\x16\n" +
+	"\x06author\x18\x06 \x01(\tR\x06author\x12\x1e\n" +
+	"\n" +
+	"prerelease\x18\a \x01(\bR\n" +
+	"prerelease\x120\n" +
+	"\x06assets\x18\b \x03(\v2\x18.crawler.v1.ReleaseAssetR\x06assets\x12\x17\n" +
+	"\arepo_id\x18\t \x01(\x03R\x06repoId\"\x83\x02\n" +
+	"\x06Commit\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12(\n" +
+	"\rfiles_changed\x18\x04 \x01(\x05H\x00R\ffilesChanged\x88\x01\x01\x12!\n" +
+	"\tadditions\x18\x05 \x01(\x05H\x01R\tadditions\x88\x01\x01\x12!\n" +
+	"\tdeletions\x18\x06 \x01(\x05H\x02R\tdeletions\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"release_id\x18\a \x01(\x03R\treleaseIdB\x10\n" +
+	"\x0e_files_changedB\f\n" +
+	"\n" +
+	"_additionsB\f\n" +
+	"\n" +
+	"_deletions\"\x1c\n" +
+	"\n" +
+	"GetRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"[\n" +
+	"\vListRequest\x12\x1b\n" +
+	"\tparent_id\x18\x01 \x01(\x03R\bparentId\x12\x19\n" +
+	"\bafter_id\x18\x02 \x01(\x03R\aafterId\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"+\n" +
+	"\fCrawlRequest\x12\x1b\n" +
+	"\tparent_id\x18\x01 \x01(\x03R\bparentId\"^\n" +
+	"\rCrawlResponse\x12\x14\n" +
+	"\x05items\x18\x01 \x01(\x03R\x05items\x12\x1f\n" +
+	"\vduration_ms\x18\x02 \x01(\x03R\n" +
+	"durationMs\x12\x16\n" +
+	"\x06errors\x18\x03 \x01(\x03R\x06errors\";\n" +
+	"\x11ListReposResponse\x12&\n" +
+	"\x05repos\x18\x01 \x03(\v2\x10.crawler.v1.RepoR\x05repos\"G\n" +
+	"\x14ListReleasesResponse\x12/\n" +
+	"\breleases\x18\x01 \x03(\v2\x13.crawler.v1.ReleaseR\breleases\"C\n" +
+	"\x13ListCommitsResponse\x12,\n" +
+	"\acommits\x18\x01 \x03(\v2\x12.crawler.v1.CommitR\acommits2\xf3\x01\n" +
+	"\vRepoService\x12/\n" +
+	"\x03Get\x12\x16.crawler.v1.GetRequest\x1a\x10.crawler.v1.Repo\x12>\n" +
+	"\x04List\x12\x17.crawler.v1.ListRequest\x1a\x1d.crawler.v1.ListReposResponse\x12<\n" +
+	"\x05Crawl\x12\x18.crawler.v1.CrawlRequest\x1a\x19.crawler.v1.CrawlResponse\x125\n" +
+	"\x06Stream\x12\x17.crawler.v1.ListRequest\x1a\x10.crawler.v1.Repo0\x012\xff\x01\n" +
+	"\x0eReleaseService\x122\n" +
+	"\x03Get\x12\x16.crawler.v1.GetRequest\x1a\x13.crawler.v1.Release\x12A\n" +
+	"\x04List\x12\x17.crawler.v1.ListRequest\x1a .crawler.v1.ListReleasesResponse\x12<\n" +
+	"\x05Crawl\x12\x18.crawler.v1.CrawlRequest\x1a\x19.crawler.v1.CrawlResponse\x128\n" +
+	"\x06Stream\x12\x17.crawler.v1.ListRequest\x1a\x13.crawler.v1.Release0\x012\xfb\x01\n" +
+	"\rCommitService\x121\n" +
+	"\x03Get\x12\x16.crawler.v1.GetRequest\x1a\x12.crawler.v1.Commit\x12@\n" +
+	"\x04List\x12\x17.crawler.v1.ListRequest\x1a\x1f.crawler.v1.ListCommitsResponse\x12<\n" +
+	"\x05Crawl\x12\x18.crawler.v1.CrawlRequest\x1a\x19.crawler.v1.CrawlResponse\x127\n" +
+	"\x06Stream\x12\x17.crawler.v1.ListRequest\x1a\x12.crawler.v1.Commit0\x01B#Z!crawler/baseline/internal/grpc/pbb\x06proto3"
+
+var (
+	file_crawler_proto_rawDescOnce sync.Once
+	file_crawler_proto_rawDescData []byte
+)
+
+func file_crawler_proto_rawDescGZIP() []byte {
+	file_crawler_proto_rawDescOnce.Do(func() {
+		file_crawler_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_crawler_proto_rawDesc), len(file_crawler_proto_rawDesc)))
+	})
+	return file_crawler_proto_rawDescData
+}
+
+var file_crawler_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_crawler_proto_goTypes = []any{
+	(*Repo)(nil),                  // 0: crawler.v1.Repo
+	(*ReleaseAsset)(nil),          // 1: crawler.v1.ReleaseAsset
+	(*Release)(nil),               // 2: crawler.v1.Release
+	(*Commit)(nil),                // 3: crawler.v1.Commit
+	(*GetRequest)(nil),            // 4: crawler.v1.GetRequest
+	(*ListRequest)(nil),           // 5: crawler.v1.ListRequest
+	(*CrawlRequest)(nil),          // 6: crawler.v1.CrawlRequest
+	(*CrawlResponse)(nil),         // 7: crawler.v1.CrawlResponse
+	(*ListReposResponse)(nil),     // 8: crawler.v1.ListReposResponse
+	(*ListReleasesResponse)(nil),  // 9: crawler.v1.ListReleasesResponse
+	(*ListCommitsResponse)(nil),   // 10: crawler.v1.ListCommitsResponse
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_crawler_proto_depIdxs = []int32{
+	11, // 0: crawler.v1.Repo.enriched_at:type_name -> google.protobuf.Timestamp
+	11, // 1: crawler.v1.Release.published_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: crawler.v1.Release.assets:type_name -> crawler.v1.ReleaseAsset
+	0,  // 3: crawler.v1.ListReposResponse.repos:type_name -> crawler.v1.Repo
+	2,  // 4: crawler.v1.ListReleasesResponse.releases:type_name -> crawler.v1.Release
+	3,  // 5: crawler.v1.ListCommitsResponse.commits:type_name -> crawler.v1.Commit
+	4,  // 6: crawler.v1.RepoService.Get:input_type -> crawler.v1.GetRequest
+	5,  // 7: crawler.v1.RepoService.List:input_type -> crawler.v1.ListRequest
+	6,  // 8: crawler.v1.RepoService.Crawl:input_type -> crawler.v1.CrawlRequest
+	5,  // 9: crawler.v1.RepoService.Stream:input_type -> crawler.v1.ListRequest
+	4,  // 10: crawler.v1.ReleaseService.Get:input_type -> crawler.v1.GetRequest
+	5,  // 11: crawler.v1.ReleaseService.List:input_type -> crawler.v1.ListRequest
+	6,  // 12: crawler.v1.ReleaseService.Crawl:input_type -> crawler.v1.CrawlRequest
+	5,  // 13: crawler.v1.ReleaseService.Stream:input_type -> crawler.v1.ListRequest
+	4,  // 14: crawler.v1.CommitService.Get:input_type -> crawler.v1.GetRequest
+	5,  // 15: crawler.v1.CommitService.List:input_type -> crawler.v1.ListRequest
+	6,  // 16: crawler.v1.CommitService.Crawl:input_type -> crawler.v1.CrawlRequest
+	5,  // 17: crawler.v1.CommitService.Stream:input_type -> crawler.v1.ListRequest
+	0,  // 18: crawler.v1.RepoService.Get:output_type -> crawler.v1.Repo
+	8,  // 19: crawler.v1.RepoService.List:output_type -> crawler.v1.ListReposResponse
+	7,  // 20: crawler.v1.RepoService.Crawl:output_type -> crawler.v1.CrawlResponse
+	0,  // 21: crawler.v1.RepoService.Stream:output_type -> crawler.v1.Repo
+	2,  // 22: crawler.v1.ReleaseService.Get:output_type -> crawler.v1.Release
+	9,  // 23: crawler.v1.ReleaseService.List:output_type -> crawler.v1.ListReleasesResponse
+	7,  // 24: crawler.v1.ReleaseService.Crawl:output_type -> crawler.v1.CrawlResponse
+	2,  // 25: crawler.v1.ReleaseService.Stream:output_type -> crawler.v1.Release
+	3,  // 26: crawler.v1.CommitService.Get:output_type -> crawler.v1.Commit
+	10, // 27: crawler.v1.CommitService.List:output_type -> crawler.v1.ListCommitsResponse
+	7,  // 28: crawler.v1.CommitService.Crawl:output_type -> crawler.v1.CrawlResponse
+	3,  // 29: crawler.v1.CommitService.Stream:output_type -> crawler.v1.Commit
+	18, // [18:30] is the sub-list for method output_type
+	6,  // [6:18] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_crawler_proto_init() }
+func file_crawler_proto_init() {
+	if File_crawler_proto != nil {
+		return
+	}
+	file_crawler_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_crawler_proto_rawDesc), len(file_crawler_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_crawler_proto_goTypes,
+		DependencyIndexes: file_crawler_proto_depIdxs,
+		MessageInfos:      file_crawler_proto_msgTypes,
+	}.Build()
+	File_crawler_proto = out.File
+	file_crawler_proto_goTypes = nil
+	file_crawler_proto_depIdxs = nil
+}
@@ -0,0 +1,690 @@
+// Typed API of the crawler for internal services, mirroring the HTTP endpoints under /api.
+// The messages follow internal/model; IDs are the database IDs used by the HTTP API.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: crawler.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RepoService_Get_FullMethodName    = "/crawler.v1.RepoService/Get"
+	RepoService_List_FullMethodName   = "/crawler.v1.RepoService/List"
+	RepoService_Crawl_FullMethodName  = "/crawler.v1.RepoService/Crawl"
+	RepoService_Stream_FullMethodName = "/crawler.v1.RepoService/Stream"
+)
+
+// RepoServiceClient is the client API for RepoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Get and List need no API key. Crawl needs an operator key when auth is enabled, sent as the
+// "x-api-key" metadata or "authorization: Bearer <key>", as on the HTTP API.
+// Stream sends every matching row in ID order, without paging
+type RepoServiceClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Repo, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListReposResponse, error)
+	Crawl(ctx context.Context, in *CrawlRequest, opts ...grpc.CallOption) (*CrawlResponse, error)
+	Stream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repo], error)
+}
+
+type repoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRepoServiceClient(cc grpc.ClientConnInterface) RepoServiceClient {
+	return &repoServiceClient{cc}
+}
+
+func (c *repoServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Repo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Repo)
+	err := c.cc.Invoke(ctx, RepoService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *repoServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListReposResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListReposResponse)
+	err := c.cc.Invoke(ctx, RepoService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *repoServiceClient) Crawl(ctx context.Context, in *CrawlRequest, opts ...grpc.CallOption) (*CrawlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CrawlResponse)
+	err := c.cc.Invoke(ctx, RepoService_Crawl_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *repoServiceClient) Stream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repo], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RepoService_ServiceDesc.Streams[0], RepoService_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, Repo]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RepoService_StreamClient = grpc.ServerStreamingClient[Repo]
+
+// RepoServiceServer is the server API for RepoService service.
+// All implementations must embed UnimplementedRepoServiceServer
+// for forward compatibility.
+//
+// Get and List need no API key. Crawl needs an operator key when auth is enabled, sent as the
+// "x-api-key" metadata or "authorization: Bearer <key>", as on the HTTP API.
+// Stream sends every matching row in ID order, without paging
+type RepoServiceServer interface {
+	Get(context.Context, *GetRequest) (*Repo, error)
+	List(context.Context, *ListRequest) (*ListReposResponse, error)
+	Crawl(context.Context, *CrawlRequest) (*CrawlResponse, error)
+	Stream(*ListRequest, grpc.ServerStreamingServer[Repo]) error
+	mustEmbedUnimplementedRepoServiceServer()
+}
+
+// UnimplementedRepoServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRepoServiceServer struct{}
+
+func (UnimplementedRepoServiceServer) Get(context.Context, *GetRequest) (*Repo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedRepoServiceServer) List(context.Context, *ListRequest) (*ListReposResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedRepoServiceServer) Crawl(context.Context, *CrawlRequest) (*CrawlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Crawl not implemented")
+}
+func (UnimplementedRepoServiceServer) Stream(*ListRequest, grpc.ServerStreamingServer[Repo]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedRepoServiceServer) mustEmbedUnimplementedRepoServiceServer() {}
+func (UnimplementedRepoServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRepoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RepoServiceServer will
+// result in compilation errors.
+type UnsafeRepoServiceServer interface {
+	mustEmbedUnimplementedRepoServiceServer()
+}
+
+func RegisterRepoServiceServer(s grpc.ServiceRegistrar, srv RepoServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRepoServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RepoService_ServiceDesc, srv)
+}
+
+func _RepoService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RepoServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RepoService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RepoServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RepoService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RepoServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RepoService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RepoServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RepoService_Crawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RepoServiceServer).Crawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RepoService_Crawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RepoServiceServer).Crawl(ctx, req.(*CrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RepoService_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RepoServiceServer).Stream(m, &grpc.GenericServerStream[ListRequest, Repo]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RepoService_StreamServer = grpc.ServerStreamingServer[Repo]
+
+// RepoService_ServiceDesc is the grpc.ServiceDesc for RepoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RepoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crawler.v1.RepoService",
+	HandlerType: (*RepoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _RepoService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _RepoService_List_Handler,
+		},
+		{
+			MethodName: "Crawl",
+			Handler:    _RepoService_Crawl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _RepoService_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawler.proto",
+}
+
+const (
+	ReleaseService_Get_FullMethodName    = "/crawler.v1.ReleaseService/Get"
+	ReleaseService_List_FullMethodName   = "/crawler.v1.ReleaseService/List"
+	ReleaseService_Crawl_FullMethodName  = "/crawler.v1.ReleaseService/Crawl"
+	ReleaseService_Stream_FullMethodName = "/crawler.v1.ReleaseService/Stream"
+)
+
+// ReleaseServiceClient is the client API for ReleaseService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReleaseServiceClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Release, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListReleasesResponse, error)
+	Crawl(ctx context.Context, in *CrawlRequest, opts ...grpc.CallOption) (*CrawlResponse, error)
+	Stream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Release], error)
+}
+
+type releaseServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReleaseServiceClient(cc grpc.ClientConnInterface) ReleaseServiceClient {
+	return &releaseServiceClient{cc}
+}
+
+func (c *releaseServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Release, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Release)
+	err := c.cc.Invoke(ctx, ReleaseService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *releaseServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListReleasesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListReleasesResponse)
+	err := c.cc.Invoke(ctx, ReleaseService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *releaseServiceClient) Crawl(ctx context.Context, in *CrawlRequest, opts ...grpc.CallOption) (*CrawlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CrawlResponse)
+	err := c.cc.Invoke(ctx, ReleaseService_Crawl_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *releaseServiceClient) Stream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Release], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReleaseService_ServiceDesc.Streams[0], ReleaseService_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, Release]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReleaseService_StreamClient = grpc.ServerStreamingClient[Release]
+
+// ReleaseServiceServer is the server API for ReleaseService service.
+// All implementations must embed UnimplementedReleaseServiceServer
+// for forward compatibility.
+type ReleaseServiceServer interface {
+	Get(context.Context, *GetRequest) (*Release, error)
+	List(context.Context, *ListRequest) (*ListReleasesResponse, error)
+	Crawl(context.Context, *CrawlRequest) (*CrawlResponse, error)
+	Stream(*ListRequest, grpc.ServerStreamingServer[Release]) error
+	mustEmbedUnimplementedReleaseServiceServer()
+}
+
+// UnimplementedReleaseServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReleaseServiceServer struct{}
+
+func (UnimplementedReleaseServiceServer) Get(context.Context, *GetRequest) (*Release, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedReleaseServiceServer) List(context.Context, *ListRequest) (*ListReleasesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedReleaseServiceServer) Crawl(context.Context, *CrawlRequest) (*CrawlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Crawl not implemented")
+}
+func (UnimplementedReleaseServiceServer) Stream(*ListRequest, grpc.ServerStreamingServer[Release]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedReleaseServiceServer) mustEmbedUnimplementedReleaseServiceServer() {}
+func (UnimplementedReleaseServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeReleaseServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReleaseServiceServer will
+// result in compilation errors.
+type UnsafeReleaseServiceServer interface {
+	mustEmbedUnimplementedReleaseServiceServer()
+}
+
+func RegisterReleaseServiceServer(s grpc.ServiceRegistrar, srv ReleaseServiceServer) {
+	// If the following call pancis, it indicates UnimplementedReleaseServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ReleaseService_ServiceDesc, srv)
+}
+
+func _ReleaseService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReleaseServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReleaseService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReleaseServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReleaseService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReleaseServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReleaseService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReleaseServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReleaseService_Crawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReleaseServiceServer).Crawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReleaseService_Crawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReleaseServiceServer).Crawl(ctx, req.(*CrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReleaseService_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReleaseServiceServer).Stream(m, &grpc.GenericServerStream[ListRequest, Release]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReleaseService_StreamServer = grpc.ServerStreamingServer[Release]
+
+// ReleaseService_ServiceDesc is the grpc.ServiceDesc for ReleaseService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReleaseService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crawler.v1.ReleaseService",
+	HandlerType: (*ReleaseServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _ReleaseService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _ReleaseService_List_Handler,
+		},
+		{
+			MethodName: "Crawl",
+			Handler:    _ReleaseService_Crawl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _ReleaseService_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawler.proto",
+}
+
+const (
+	CommitService_Get_FullMethodName    = "/crawler.v1.CommitService/Get"
+	CommitService_List_FullMethodName   = "/crawler.v1.CommitService/List"
+	CommitService_Crawl_FullMethodName  = "/crawler.v1.CommitService/Crawl"
+	CommitService_Stream_FullMethodName = "/crawler.v1.CommitService/Stream"
+)
+
+// CommitServiceClient is the client API for CommitService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CommitServiceClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Commit, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListCommitsResponse, error)
+	Crawl(ctx context.Context, in *CrawlRequest, opts ...grpc.CallOption) (*CrawlResponse, error)
+	Stream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Commit], error)
+}
+
+type commitServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCommitServiceClient(cc grpc.ClientConnInterface) CommitServiceClient {
+	return &commitServiceClient{cc}
+}
+
+func (c *commitServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Commit, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Commit)
+	err := c.cc.Invoke(ctx, CommitService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commitServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListCommitsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCommitsResponse)
+	err := c.cc.Invoke(ctx, CommitService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commitServiceClient) Crawl(ctx context.Context, in *CrawlRequest, opts ...grpc.CallOption) (*CrawlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CrawlResponse)
+	err := c.cc.Invoke(ctx, CommitService_Crawl_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commitServiceClient) Stream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Commit], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CommitService_ServiceDesc.Streams[0], CommitService_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, Commit]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CommitService_StreamClient = grpc.ServerStreamingClient[Commit]
+
+// CommitServiceServer is the server API for CommitService service.
+// All implementations must embed UnimplementedCommitServiceServer
+// for forward compatibility.
+type CommitServiceServer interface {
+	Get(context.Context, *GetRequest) (*Commit, error)
+	List(context.Context, *ListRequest) (*ListCommitsResponse, error)
+	Crawl(context.Context, *CrawlRequest) (*CrawlResponse, error)
+	Stream(*ListRequest, grpc.ServerStreamingServer[Commit]) error
+	mustEmbedUnimplementedCommitServiceServer()
+}
+
+// UnimplementedCommitServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCommitServiceServer struct{}
+
+func (UnimplementedCommitServiceServer) Get(context.Context, *GetRequest) (*Commit, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCommitServiceServer) List(context.Context, *ListRequest) (*ListCommitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedCommitServiceServer) Crawl(context.Context, *CrawlRequest) (*CrawlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Crawl not implemented")
+}
+func (UnimplementedCommitServiceServer) Stream(*ListRequest, grpc.ServerStreamingServer[Commit]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedCommitServiceServer) mustEmbedUnimplementedCommitServiceServer() {}
+func (UnimplementedCommitServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeCommitServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CommitServiceServer will
+// result in compilation errors.
+type UnsafeCommitServiceServer interface {
+	mustEmbedUnimplementedCommitServiceServer()
+}
+
+func RegisterCommitServiceServer(s grpc.ServiceRegistrar, srv CommitServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCommitServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CommitService_ServiceDesc, srv)
+}
+
+func _CommitService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommitServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommitService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommitServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommitService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommitServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommitService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommitServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommitService_Crawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommitServiceServer).Crawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommitService_Crawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommitServiceServer).Crawl(ctx, req.(*CrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommitService_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CommitServiceServer).Stream(m, &grpc.GenericServerStream[ListRequest, Commit]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CommitService_StreamServer = grpc.ServerStreamingServer[Commit]
+
+// CommitService_ServiceDesc is the grpc.ServiceDesc for CommitService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CommitService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crawler.v1.CommitService",
+	HandlerType: (*CommitServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _CommitService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _CommitService_List_Handler,
+		},
+		{
+			MethodName: "Crawl",
+			Handler:    _CommitService_Crawl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _CommitService_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawler.proto",
+}
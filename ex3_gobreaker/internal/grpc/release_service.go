@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/grpc/pb"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+type releaseService struct {
+	pb.UnimplementedReleaseServiceServer
+	server *Server
+}
+
+// Get returns a release still on GitHub with its assets; the message cannot mark a gone one
+func (s *releaseService) Get(ctx context.Context, request *pb.GetRequest) (*pb.Release, error) {
+	release := &entity.Release{}
+	err := repository.NewReleaseRepository(s.server.log).FindById(ctx, s.releases(0), release, request.Id)
+	if err != nil {
+		return nil, s.server.statusError(err, "Release not found")
+	}
+	return releaseMessage(release), nil
+}
+
+// List returns a page of the releases still on GitHub, of one repository when parent_id is set
+func (s *releaseService) List(ctx context.Context, request *pb.ListRequest) (*pb.ListReleasesResponse, error) {
+	limit, err := pageLimit(request)
+	if err != nil {
+		return nil, err
+	}
+	var releases []entity.Release
+	err = repository.NewReleaseRepository(s.server.log).FindAfter(ctx, s.releases(request.ParentId), &releases, request.AfterId, limit)
+	if err != nil {
+		return nil, s.server.statusError(err, "Error querying database")
+	}
+	response := &pb.ListReleasesResponse{Releases: make([]*pb.Release, len(releases))}
+	for i := range releases {
+		response.Releases[i] = releaseMessage(&releases[i])
+	}
+	return response, nil
+}
+
+// Crawl saves the releases of one repository, or of every repository as GET /api/releases/crawl
+func (s *releaseService) Crawl(ctx context.Context, request *pb.CrawlRequest) (*pb.CrawlResponse, error) {
+	return s.server.crawl(ctx, pb.ReleaseService_Crawl_FullMethodName, func(ctx context.Context) error {
+		var err error
+		if request.ParentId != 0 {
+			_, err = s.server.releaseController.CrawlRepo(ctx, request.ParentId)
+		} else {
+			_, err = s.server.releaseController.CrawlReleases(ctx, 0, 0)
+		}
+		return err
+	})
+}
+
+// Stream sends every release still on GitHub after after_id, of one repository when parent_id is set
+func (s *releaseService) Stream(request *pb.ListRequest, stream pb.ReleaseService_StreamServer) error {
+	if _, err := pageLimit(request); err != nil {
+		return err
+	}
+	err := streamRows(stream.Context(), s.releases(request.ParentId), request.AfterId,
+		repository.NewReleaseRepository(s.server.log).FindAfter,
+		func(release *entity.Release) int64 { return release.ID },
+		func(release *entity.Release) error { return stream.Send(releaseMessage(release)) })
+	if err != nil {
+		return s.server.statusError(err, "Error streaming releases")
+	}
+	return nil
+}
+
+// releases selects the releases still on GitHub, with their assets, of repoID or of every
+// repository when 0
+func (s *releaseService) releases(repoID int64) *gorm.DB {
+	db := s.server.db.Model(&entity.Release{}).Scopes(repository.NotTombstoned).Preload("Assets")
+	if repoID != 0 {
+		db = db.Where("repoid = ?", repoID)
+	}
+	return db
+}
+
+func releaseMessage(release *entity.Release) *pb.Release {
+	response := usecase.ReleaseToResponse(release)
+	message := &pb.Release{
+		Id:          response.ID,
+		TagName:     response.TagName,
+		Content:     response.Content,
+		Title:       response.Title,
+		PublishedAt: timestamp(response.PublishedAt),
+		Author:      response.Author,
+		Prerelease:  response.Prerelease,
+		RepoId:      response.RepoID,
+	}
+	for _, asset := range response.Assets {
+		message.Assets = append(message.Assets, &pb.ReleaseAsset{
+			Name:          asset.Name,
+			Size:          asset.Size,
+			DownloadCount: asset.DownloadCount,
+		})
+	}
+	return message
+}
@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/grpc/pb"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/usecase"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type repoService struct {
+	pb.UnimplementedRepoServiceServer
+	server *Server
+}
+
+// Get returns a repository, archived ones included as GET /api/repos/{repoID}
+func (s *repoService) Get(ctx context.Context, request *pb.GetRequest) (*pb.Repo, error) {
+	repo := &entity.Repository{}
+	err := repository.NewRepoRepository(s.server.log).FindByIdWithArchived(ctx, s.server.db, repo, request.Id)
+	if err != nil {
+		return nil, s.server.statusError(err, "Repository not found")
+	}
+	return repoMessage(repo), nil
+}
+
+// List returns a page of the tracked repositories; they have no parent
+func (s *repoService) List(ctx context.Context, request *pb.ListRequest) (*pb.ListReposResponse, error) {
+	limit, err := pageLimit(request)
+	if err != nil {
+		return nil, err
+	}
+	if request.ParentId != 0 {
+		return nil, status.Error(codes.InvalidArgument, "repositories have no parent")
+	}
+	var repos []entity.Repository
+	if err := repository.NewRepoRepository(s.server.log).FindAfter(ctx, s.server.db, &repos, request.AfterId, limit); err != nil {
+		return nil, s.server.statusError(err, "Error querying database")
+	}
+	response := &pb.ListReposResponse{Repos: make([]*pb.Repo, len(repos))}
+	for i := range repos {
+		response.Repos[i] = repoMessage(&repos[i])
+	}
+	return response, nil
+}
+
+// Crawl saves the top repositories of gitstar-ranking.com, as GET /api/repos/crawl
+func (s *repoService) Crawl(ctx context.Context, request *pb.CrawlRequest) (*pb.CrawlResponse, error) {
+	if request.ParentId != 0 {
+		return nil, status.Error(codes.InvalidArgument, "repositories are crawled from the ranking only, without parent")
+	}
+	return s.server.crawl(ctx, pb.RepoService_Crawl_FullMethodName, func(ctx context.Context) error {
+		_, err := s.server.repoController.CrawlRepos(ctx, scrape.DefaultRepoLimit, scrape.DefaultRepoPages)
+		return err
+	})
+}
+
+// Stream sends every tracked repository after after_id
+func (s *repoService) Stream(request *pb.ListRequest, stream pb.RepoService_StreamServer) error {
+	if _, err := pageLimit(request); err != nil {
+		return err
+	}
+	if request.ParentId != 0 {
+		return status.Error(codes.InvalidArgument, "repositories have no parent")
+	}
+	err := streamRows(stream.Context(), s.server.db, request.AfterId, repository.NewRepoRepository(s.server.log).FindAfter,
+		func(repo *entity.Repository) int64 { return repo.ID },
+		func(repo *entity.Repository) error { return stream.Send(repoMessage(repo)) })
+	if err != nil {
+		return s.server.statusError(err, "Error streaming repositories")
+	}
+	return nil
+}
+
+func repoMessage(repo *entity.Repository) *pb.Repo {
+	response := usecase.RepoToResponse(repo)
+	return &pb.Repo{
+		Id:            response.ID,
+		UserName:      response.UserName,
+		RepoName:      response.RepoName,
+		DefaultBranch: response.DefaultBranch,
+		Policy:        response.Policy,
+		Description:   response.Description,
+		Stars:         response.Stars,
+		Forks:         response.Forks,
+		Language:      response.Language,
+		Topics:        response.Topics,
+		License:       response.License,
+		EnrichedAt:    timestamp(response.EnrichedAt),
+	}
+}
+
+// timestamp converts an optional time, nil staying unset
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,304 @@
+// Package grpc serves the typed API of proto/crawler.proto, RepoService, ReleaseService and
+// CommitService, from the same repositories and crawl code as the HTTP API. The code in pb is
+// generated from the proto file.
+package grpc
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/grpc/pb"
+	"crawler/baseline/internal/http/controller"
+	"crawler/baseline/internal/queue"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultListLimit is the page size of a List without a limit
+	defaultListLimit = 100
+	// maxListLimit bounds the page size of a List
+	maxListLimit = 1000
+	// streamBatchSize is how many rows a Stream loads at a time
+	streamBatchSize = 500
+)
+
+// Server serves the gRPC services
+type Server struct {
+	log  *logrus.Logger
+	db   *gorm.DB
+	keys *auth.KeyStore
+	// crawlRuns records every Crawl in crawl_runs; nil records nothing
+	crawlRuns *controller.CrawlRunRecorder
+	// rateLimit applies to every call and crawlRateLimit also to Crawl, sharing the buckets of
+	// the HTTP API; nil disables them
+	rateLimit      *controller.RateLimiter
+	crawlRateLimit *controller.RateLimiter
+	// backpressure refuses Crawl while a queue is saturated; nil never refuses
+	backpressure *queue.Backpressure
+
+	repoController    *controller.RepoController
+	releaseController *controller.ReleaseController
+	commitController  *controller.CommitController
+
+	server *grpcgo.Server
+}
+
+// NewServer registers the services. Keys, when enabled, guard every call as the routes of the
+// HTTP API: reading needs a reader key and Crawl an operator key. Crawl is also refused by the
+// crawl rate limit and while backpressure reports a saturated queue, as the crawl routes are.
+func NewServer(log *logrus.Logger, db *gorm.DB, keys *auth.KeyStore, crawlRuns *controller.CrawlRunRecorder,
+	rateLimit *controller.RateLimiter, crawlRateLimit *controller.RateLimiter, backpressure *queue.Backpressure,
+	repoController *controller.RepoController, releaseController *controller.ReleaseController,
+	commitController *controller.CommitController) *Server {
+	s := &Server{
+		log:               log,
+		db:                db,
+		keys:              keys,
+		crawlRuns:         crawlRuns,
+		rateLimit:         rateLimit,
+		crawlRateLimit:    crawlRateLimit,
+		backpressure:      backpressure,
+		repoController:    repoController,
+		releaseController: releaseController,
+		commitController:  commitController,
+	}
+	s.server = grpcgo.NewServer(grpcgo.UnaryInterceptor(s.guardUnary), grpcgo.StreamInterceptor(s.guardStream))
+	pb.RegisterRepoServiceServer(s.server, &repoService{server: s})
+	pb.RegisterReleaseServiceServer(s.server, &releaseService{server: s})
+	pb.RegisterCommitServiceServer(s.server, &commitService{server: s})
+	return s
+}
+
+// Start listens on port and serves in the background until stop is closed, then waits for the
+// calls in progress
+func (s *Server) Start(port int, stop <-chan struct{}) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			s.log.WithError(err).Error("gRPC server stopped")
+		}
+	}()
+	if stop != nil {
+		go func() {
+			<-stop
+			s.server.GracefulStop()
+		}()
+	}
+	s.log.WithField("port", port).Info("Serving gRPC")
+	return nil
+}
+
+// guardUnary lets a call through once it has a reader key and is within the rate limit
+func (s *Server) guardUnary(ctx context.Context, request interface{}, info *grpcgo.UnaryServerInfo,
+	handler grpcgo.UnaryHandler) (interface{}, error) {
+	if err := s.guard(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+// guardStream is guardUnary for the Stream calls
+func (s *Server) guardStream(srv interface{}, stream grpcgo.ServerStream, info *grpcgo.StreamServerInfo,
+	handler grpcgo.StreamHandler) error {
+	if err := s.guard(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+func (s *Server) guard(ctx context.Context, method string) error {
+	principal, err := s.authorize(ctx, method, auth.RoleReader)
+	if err != nil {
+		return err
+	}
+	return s.limit(ctx, method, s.rateLimit, principal)
+}
+
+// crawl runs a Crawl call as a crawl run of the API, after checking its API key, the crawl rate
+// limit and the queues
+func (s *Server) crawl(ctx context.Context, method string, crawl func(ctx context.Context) error) (*pb.CrawlResponse, error) {
+	principal, err := s.authorize(ctx, method, auth.RoleOperator)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limit(ctx, method, s.crawlRateLimit, principal); err != nil {
+		return nil, err
+	}
+	if saturated, states := s.backpressure.Saturated(); saturated {
+		s.log.WithFields(logrus.Fields{
+			"method": method,
+			"queues": states,
+		}).Warn("Refusing crawl trigger, queues are saturated")
+		setRetryAfter(ctx, s.backpressure.RetryAfter())
+		return nil, status.Error(codes.Unavailable, "Crawl queues are saturated, retry later")
+	}
+	startTime := time.Now()
+	counts, err := s.crawlRuns.RunCounted(ctx, entity.CrawlSourceAPI, "grpc "+method, principal.Name, crawl)
+	if err != nil {
+		return nil, s.statusError(err, "Crawl failed")
+	}
+	return &pb.CrawlResponse{
+		Items:      int64(counts.Saved),
+		Errors:     int64(counts.Errored),
+		DurationMs: time.Since(startTime).Milliseconds(),
+	}, nil
+}
+
+// authorize returns the principal of the API key of ctx when it has at least role. Without
+// keys every call passes, as a principal without name.
+func (s *Server) authorize(ctx context.Context, method string, role string) (auth.Principal, error) {
+	if !s.keys.Enabled() {
+		return auth.Principal{}, nil
+	}
+	principal, ok := s.keys.Lookup(apiKey(ctx))
+	if !ok {
+		s.log.WithField("method", method).Warn("Rejected gRPC call without a valid API key")
+		return principal, status.Error(codes.Unauthenticated, "Missing or invalid API key")
+	}
+	if !auth.Allows(principal.Role, role) {
+		s.log.WithFields(logrus.Fields{
+			"method":   method,
+			"key":      principal.Name,
+			"role":     principal.Role,
+			"required": role,
+		}).Warn("Rejected gRPC call with insufficient role")
+		return principal, status.Error(codes.PermissionDenied, "API key does not allow this operation")
+	}
+	return principal, nil
+}
+
+// limit takes a token of limiter for the client of the call, or answers ResourceExhausted with
+// the wait in the retry-after header, as the 429 of the HTTP API
+func (s *Server) limit(ctx context.Context, method string, limiter *controller.RateLimiter, principal auth.Principal) error {
+	client := rateLimitClient(ctx, principal)
+	wait := limiter.Take(client)
+	if wait <= 0 {
+		return nil
+	}
+	s.log.WithFields(logrus.Fields{
+		"client":      client,
+		"method":      method,
+		"retry_after": int(math.Ceil(wait.Seconds())),
+	}).Warn("Rate limit exceeded")
+	setRetryAfter(ctx, wait)
+	return status.Error(codes.ResourceExhausted, "Too many requests")
+}
+
+// rateLimitClient names the bucket of the caller as the HTTP API does, by API key name when
+// authentication is enabled and by IP address otherwise
+func rateLimitClient(ctx context.Context, principal auth.Principal) string {
+	if principal.Name != "" {
+		return "key:" + principal.Name
+	}
+	caller, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(caller.Addr.String())
+	if err != nil {
+		host = caller.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// setRetryAfter sends wait, in whole seconds, in the retry-after header of the call
+func setRetryAfter(ctx context.Context, wait time.Duration) {
+	if wait > 0 {
+		grpcgo.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(wait.Seconds())))))
+	}
+}
+
+// apiKey is the key of the call metadata, as the X-API-Key or Authorization header of the HTTP API
+func apiKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(strings.ToLower(controller.APIKeyHeader)); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		if token, ok := strings.CutPrefix(values[0], "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// statusError reports err with the code matching its HTTP status in apperrors, and message;
+// internal errors are logged, as by the HTTP API
+func (s *Server) statusError(err error, message string) error {
+	httpStatus, _ := apperrors.Classify(err)
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		if ctxErr := status.FromContextError(err); ctxErr.Code() == codes.Canceled {
+			code = codes.Canceled
+		} else {
+			s.log.WithError(err).Error(message)
+		}
+	}
+	return status.Error(code, message)
+}
+
+// pageLimit is the page size of request, or an InvalidArgument error
+func pageLimit(request *pb.ListRequest) (int, error) {
+	switch {
+	case request.Limit < 0 || request.AfterId < 0 || request.ParentId < 0:
+		return 0, status.Error(codes.InvalidArgument, "limit, after_id and parent_id must not be negative")
+	case request.Limit == 0:
+		return defaultListLimit, nil
+	default:
+		return min(int(request.Limit), maxListLimit), nil
+	}
+}
+
+// streamRows sends the rows selected by db with an ID above afterID, in ID order, loading them
+// streamBatchSize at a time until ctx is done
+func streamRows[T any](ctx context.Context, db *gorm.DB, afterID int64, find func(ctx context.Context, db *gorm.DB,
+	rows *[]T, afterID int64, limit int) error, id func(*T) int64, send func(*T) error) error {
+	for {
+		var rows []T
+		if err := find(ctx, db.Session(&gorm.Session{}), &rows, afterID, streamBatchSize); err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := send(&rows[i]); err != nil {
+				return err
+			}
+		}
+		if len(rows) < streamBatchSize {
+			return nil
+		}
+		afterID = id(&rows[len(rows)-1])
+	}
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type AdminController struct {
+	log *logrus.Logger
+	// config is the effective configuration with its secrets already redacted
+	config interface{}
+}
+
+func NewAdminController(log *logrus.Logger, config interface{}) *AdminController {
+	return &AdminController{
+		log:    log,
+		config: config,
+	}
+}
+
+// GetConfig returns the configuration this instance runs with, after defaults and overrides
+func (c *AdminController) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[interface{}]{
+		Data: c.config,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type AlertController struct {
+	log    *logrus.Logger
+	alerts *service.AlertEngine
+}
+
+func NewAlertController(log *logrus.Logger, alerts *service.AlertEngine) *AlertController {
+	return &AlertController{
+		log:    log,
+		alerts: alerts,
+	}
+}
+
+// ListAlerts reports the current value and firing state of every alert rule
+func (c *AlertController) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.AlertStatus]{
+		Data: c.alerts.Statuses(),
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
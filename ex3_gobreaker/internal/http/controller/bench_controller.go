@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type BenchController struct {
+	log   *logrus.Logger
+	bench *service.BenchRunner
+}
+
+func NewBenchController(log *logrus.Logger, bench *service.BenchRunner) *BenchController {
+	return &BenchController{
+		log:   log,
+		bench: bench,
+	}
+}
+
+// RunBench replays one synthetic workload against each configured variant deployment and
+// returns the comparative report. The request blocks until every target has been benchmarked.
+func (c *BenchController) RunBench(w http.ResponseWriter, r *http.Request) {
+	var request model.BenchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startTime := time.Now()
+	report, err := c.bench.Run(r.Context(), request)
+	switch {
+	case errors.Is(err, service.ErrBenchRunning):
+		writeAppError(w, r, err, "A benchmark is already running")
+		return
+	case errors.Is(err, service.ErrInvalidBench):
+		writeAppError(w, r, err, err.Error())
+		return
+	case err != nil:
+		c.log.WithError(err).Error("Benchmark failed")
+		writeAppError(w, r, err, "Benchmark failed")
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"targets":       len(report.Targets),
+		"requests":      report.Requests,
+		"fastest":       report.Fastest,
+		"total_time_ms": time.Since(startTime).Milliseconds(),
+	}).Info("Benchmark completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.BenchReport]{
+		Data: report,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type BreakerController struct {
+	log *logrus.Logger
+	// repoBreakers is nil when crawl.repo_breaker is disabled
+	repoBreakers *utils.BreakerGroup
+}
+
+func NewBreakerController(log *logrus.Logger, repoBreakers *utils.BreakerGroup) *BreakerController {
+	return &BreakerController{
+		log:          log,
+		repoBreakers: repoBreakers,
+	}
+}
+
+// RepoBreakers counts the per-repository circuit breakers by state and lists the repositories
+// whose breaker is open
+func (c *BreakerController) RepoBreakers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[utils.BreakerGroupStats]{
+		Data: c.repoBreakers.Stats(),
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
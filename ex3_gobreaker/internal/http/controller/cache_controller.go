@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"crawler/baseline/internal/cache"
+	"crawler/baseline/internal/model"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type CacheController struct {
+	log    *logrus.Logger
+	caches map[string]cache.StatsSource
+}
+
+func NewCacheController(log *logrus.Logger, caches map[string]cache.StatsSource) *CacheController {
+	return &CacheController{
+		log:    log,
+		caches: caches,
+	}
+}
+
+// CacheStats reports the hits, misses, evictions and invalidations of each enabled response
+// cache, by entity type
+func (c *CacheController) CacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := make(map[string]cache.Stats, len(c.caches))
+	for name, source := range c.caches {
+		stats[name] = source.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[map[string]cache.Stats]{
+		Data: stats,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
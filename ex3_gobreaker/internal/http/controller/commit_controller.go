@@ -1,19 +1,25 @@
 package controller
 
 import (
+	"context"
+	"crawler/baseline/internal/budget"
+	"crawler/baseline/internal/cache"
 	"crawler/baseline/internal/entity"
 	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/queue"
 	"crawler/baseline/internal/repository"
 	"crawler/baseline/internal/scrape"
 	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -22,60 +28,194 @@ type CommitController struct {
 	log           *logrus.Logger
 	db            *gorm.DB
 	commitUsecase *usecase.CommitUsecase
-	commitScrape  *scrape.CommitScrape
+	commitScrape  scrape.CommitSource
+	branchScrape  scrape.BranchSource
+	// repoPolicies bound or skip the commits crawled for large repositories
+	repoPolicies *usecase.RepoPolicyUsecase
+	// releaseConcurrency is how many releases CrawlCommits scrapes at once
+	releaseConcurrency atomic.Int64
+	// budget bounds the goroutines of the commits stage, across crawls
+	budget *budget.Budget
+	// responses caches GetCommit by commit ID; nil caches nothing
+	responses *cache.LRU[int64, *model.CommitResponse]
+	// repoBreakers reject the scrapes of repositories failing on every call; nil rejects nothing
+	repoBreakers *utils.BreakerGroup
+	// commitQueue stores the commits of bulk crawls in the background; nil stores them as they are scraped
+	commitQueue *queue.CommitQueueProcessor
 }
 
-func NewCommitController(log *logrus.Logger, db *gorm.DB,
-	commitUsecase *usecase.CommitUsecase, commitScrape *scrape.CommitScrape) *CommitController {
-	return &CommitController{
+func NewCommitController(log *logrus.Logger, db *gorm.DB, commitUsecase *usecase.CommitUsecase,
+	commitScrape scrape.CommitSource, branchScrape scrape.BranchSource, repoPolicies *usecase.RepoPolicyUsecase,
+	releaseConcurrency int, budget *budget.Budget, responses *cache.LRU[int64, *model.CommitResponse],
+	repoBreakers *utils.BreakerGroup, commitQueue *queue.CommitQueueProcessor) *CommitController {
+	c := &CommitController{
 		log:           log,
 		db:            db,
 		commitUsecase: commitUsecase,
 		commitScrape:  commitScrape,
+		branchScrape:  branchScrape,
+		repoPolicies:  repoPolicies,
+		budget:        budget,
+		responses:     responses,
+		repoBreakers:  repoBreakers,
+		commitQueue:   commitQueue,
 	}
+	c.releaseConcurrency.Store(int64(releaseConcurrency))
+	return c
 }
 
-func (c *CommitController) GetCommit(w http.ResponseWriter, r *http.Request) {
-	commitID, _ := strconv.Atoi(chi.URLParam(r, "commitID"))
+// SetReleaseConcurrency changes how many releases CrawlCommits scrapes at once, from the next crawl on
+func (c *CommitController) SetReleaseConcurrency(concurrency int) {
+	c.releaseConcurrency.Store(int64(max(1, concurrency)))
+}
 
-	c.log.Infof("Fetching commit with ID: %d", commitID)
+// saveCommits stores a page of crawled commits, or enqueues it when the commit queue is on, and
+// returns how many were saved or enqueued
+func (c *CommitController) saveCommits(ctx context.Context, requests []*model.CreateCommitRequest) (int, error) {
+	if c.commitQueue == nil {
+		if _, err := c.commitUsecase.BatchCreate(ctx, requests); err != nil {
+			return 0, err
+		}
+		return len(requests), nil
+	}
+	enqueued, err := c.commitQueue.EnqueueAll(ctx, requests)
+	if err != nil {
+		return enqueued, fmt.Errorf("enqueuing commits: %w", err)
+	}
+	return enqueued, nil
+}
+
+// defaultBranch returns the stored default branch of a repository, detecting and storing it when unknown.
+// An empty result makes the commit scraper fall back to master/main.
+func (c *CommitController) defaultBranch(ctx context.Context, repoEntity *entity.Repository) string {
+	if repoEntity.DefaultBranch != "" {
+		return repoEntity.DefaultBranch
+	}
+
+	branch, err := c.branchScrape.DetectDefaultBranch(repoEntity.UserName, repoEntity.RepoName)
+	if err != nil {
+		c.log.WithError(err).WithField("repo_id", repoEntity.ID).Warn("Error detecting default branch")
+		return ""
+	}
 
-	commitRepository := repository.NewCommitRepository(c.log)
+	repoRepository := repository.NewRepoRepository(c.log)
+	if err := repoRepository.UpdateDefaultBranch(ctx, c.db, repoEntity.ID, branch); err != nil {
+		c.log.WithError(err).WithField("repo_id", repoEntity.ID).Warn("Error saving default branch")
+	}
+	repoEntity.DefaultBranch = branch
 
-	commitEntity := &entity.Commit{}
-	err := commitRepository.FindById(c.db, commitEntity, commitID)
+	return branch
+}
 
+// newCommitRequests parses the "Hash: <hash> - Message: <message>" strings returned by the commit scraper
+func newCommitRequests(log *logrus.Logger, commitStrings []string, releaseID int64) []*model.CreateCommitRequest {
+	commitRequests := make([]*model.CreateCommitRequest, 0, len(commitStrings))
+	for _, commitStr := range commitStrings {
+		parts := strings.SplitN(commitStr, " - Message: ", 2)
+		if len(parts) != 2 {
+			log.WithField("commit_str", commitStr).Warn("Invalid commit string format")
+			continue
+		}
+
+		commitRequests = append(commitRequests, &model.CreateCommitRequest{
+			Hash:      strings.TrimPrefix(parts[0], "Hash: "),
+			Message:   parts[1],
+			ReleaseID: releaseID,
+		})
+	}
+	return commitRequests
+}
+
+// streamCommits scrapes the commits of a release, within limits, usually those of the
+// repository's policy, and passes them to save a compare page at a time, parsed and with their
+// stats attached, so a huge release is never held in memory whole. It returns the number of
+// commits found, and the scraper's error or the one save returned, which stops the crawl.
+func streamCommits(ctx context.Context, log *logrus.Logger, commitScrape scrape.CommitSource, repoEntity *entity.Repository,
+	limits scrape.RepoLimits, releaseID int64, releaseTag string, defaultBranch string,
+	save func(requests []*model.CreateCommitRequest) error) (int, error) {
+	return commitScrape.StreamCommits(ctx, repoEntity.UserName, repoEntity.RepoName, releaseTag, defaultBranch,
+		limits, func(commitStrings []string) error {
+			requests := newCommitRequests(log, commitStrings, releaseID)
+			attachCommitStats(ctx, log, commitScrape, repoEntity, requests)
+			return save(requests)
+		})
+}
+
+// attachCommitStats fills in the diff stats of each commit when stats scraping is enabled.
+// A commit whose stats can't be fetched is still saved, without stats, as are the remaining
+// commits once ctx is cancelled.
+func attachCommitStats(ctx context.Context, log *logrus.Logger, commitScrape scrape.CommitSource, repoEntity *entity.Repository,
+	requests []*model.CreateCommitRequest) {
+	if !commitScrape.StatsEnabled() {
+		return
+	}
+
+	for _, request := range requests {
+		if ctx.Err() != nil {
+			return
+		}
+		stats, err := commitScrape.CrawlCommitStats(ctx, repoEntity.UserName, repoEntity.RepoName, request.Hash)
+		if err != nil {
+			log.WithError(err).WithField("hash", request.Hash).Warn("Error fetching commit stats")
+			continue
+		}
+
+		request.FilesChanged = &stats.FilesChanged
+		request.Additions = &stats.Additions
+		request.Deletions = &stats.Deletions
+	}
+}
+
+func (c *CommitController) GetCommit(w http.ResponseWriter, r *http.Request) {
+	commitID, err := idParam(r, "commitID")
 	if err != nil {
-		c.log.WithError(err).Errorf("Error finding commit with ID %d", commitID)
-		http.Error(w, "Commit not found", http.StatusNotFound)
+		c.log.WithError(err).Error("Invalid commit ID format")
+		writeAppError(w, r, err, "Invalid commit ID")
 		return
 	}
 
-	commitResponse := &model.CommitResponse{
-		ID:        commitEntity.ID,
-		Hash:      commitEntity.Hash,
-		Message:   commitEntity.Message,
-		ReleaseID: commitEntity.ReleaseID,
+	c.log.Infof("Fetching commit with ID: %d", commitID)
+
+	commitResponse, cached := c.responses.Get(commitID)
+	if !cached {
+		commitRepository := repository.NewCommitRepository(c.log)
+
+		commitEntity := &entity.Commit{}
+		err = commitRepository.FindById(r.Context(), c.db, commitEntity, commitID)
+
+		if err != nil {
+			c.log.WithError(err).Errorf("Error finding commit with ID %d", commitID)
+			writeLookupError(w, r, err, "Commit not found")
+			return
+		}
+
+		commitResponse = usecase.CommitToResponse(commitEntity)
+		c.responses.Add(commitID, commitResponse)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(commitResponse); err != nil {
 		c.log.WithError(err).Error("Error encoding commit response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
 		return
 	}
 }
 
 func (c *CommitController) GetCommitsByRelease(w http.ResponseWriter, r *http.Request) {
-	releaseID, _ := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	releaseID, err := idParam(r, "releaseID")
+	if err != nil {
+		c.log.WithError(err).Error("Invalid release ID format")
+		writeAppError(w, r, err, "Invalid release ID")
+		return
+	}
 
 	c.log.Infof("Fetching commits for release ID: %d", releaseID)
 
 	// Get commits for this release
-	commits, err := c.commitUsecase.GetCommitsByReleaseID(r.Context(), int64(releaseID))
+	commits, err := c.commitUsecase.GetCommitsByReleaseID(r.Context(), releaseID)
 	if err != nil {
 		c.log.WithError(err).Errorf("Error fetching commits for release ID %d", releaseID)
-		http.Error(w, "Failed to retrieve commits", http.StatusInternalServerError)
+		writeError(w, r, "Failed to retrieve commits", http.StatusInternalServerError)
 		return
 	}
 
@@ -84,13 +224,79 @@ func (c *CommitController) GetCommitsByRelease(w http.ResponseWriter, r *http.Re
 		Data: commits,
 	}); err != nil {
 		c.log.WithError(err).Error("Error encoding commits response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
 		return
 	}
 }
 
+const (
+	defaultRepoCommitPageSize = 50
+	maxRepoCommitPageSize     = 500
+)
+
+// ListRepoCommits returns a page of the commits of a repository across its releases, newest
+// release first. Query parameters: page and size, tag for one release, from/to bounding the
+// release publish dates, and include_deleted for the releases tombstoned on GitHub.
+func (c *CommitController) ListRepoCommits(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	query := r.URL.Query()
+	page, size := 1, defaultRepoCommitPageSize
+	for name, value := range map[string]*int{"page": &page, "size": &size} {
+		if query.Get(name) == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(query.Get(name))
+		if err != nil || parsed <= 0 {
+			writeError(w, r, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*value = parsed
+	}
+	size = min(size, maxRepoCommitPageSize)
+
+	filter := repository.RepoCommitFilter{Tag: query.Get("tag")}
+	for name, bound := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		parsed, err := parseExportDate(query.Get(name))
+		if err != nil {
+			writeError(w, r, "Invalid "+name+", expected RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*bound = parsed
+	}
+	if filter.IncludeDeleted, err = includeDeleted(r); err != nil {
+		writeError(w, r, "Invalid include_deleted, expected true or false", http.StatusBadRequest)
+		return
+	}
+
+	commits, paging, err := c.commitUsecase.ListByRepo(r.Context(), repoID, filter, page, size)
+	if err != nil {
+		c.log.WithError(err).WithField("repo_id", repoID).Error("Error listing repository commits")
+		writeLookupError(w, r, err, "Repository not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.RepoCommitResponse]{
+		Data:   commits,
+		Paging: paging,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding commits response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
 func (c *CommitController) CrawlCommitsByRelease(w http.ResponseWriter, r *http.Request) {
-	releaseID, _ := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	releaseID, err := idParam(r, "releaseID")
+	if err != nil {
+		c.log.WithError(err).Error("Invalid release ID format")
+		writeAppError(w, r, err, "Invalid release ID")
+		return
+	}
 
 	c.log.WithFields(logrus.Fields{
 		"release_id": releaseID,
@@ -100,18 +306,24 @@ func (c *CommitController) CrawlCommitsByRelease(w http.ResponseWriter, r *http.
 	// Get the release information first
 	releaseRepository := repository.NewReleaseRepository(c.log)
 	releaseEntity := &entity.Release{}
-	if err := releaseRepository.FindById(c.db, releaseEntity, releaseID); err != nil {
+	if err := releaseRepository.FindById(r.Context(), c.db, releaseEntity, releaseID); err != nil {
 		c.log.WithError(err).Errorf("Error finding release with ID %d", releaseID)
-		http.Error(w, "Release not found", http.StatusNotFound)
+		writeLookupError(w, r, err, "Release not found")
 		return
 	}
 
 	// Get the repo information associated with this release
 	repoRepository := repository.NewRepoRepository(c.log)
 	repoEntity := &entity.Repository{}
-	if err := repoRepository.FindById(c.db, repoEntity, releaseEntity.RepoID); err != nil {
+	if err := repoRepository.FindById(r.Context(), c.db, repoEntity, releaseEntity.RepoID); err != nil {
 		c.log.WithError(err).Errorf("Error finding repository with ID %d", releaseEntity.RepoID)
-		http.Error(w, "Repository not found", http.StatusNotFound)
+		writeLookupError(w, r, err, "Repository not found")
+		return
+	}
+
+	policy := c.repoPolicies.For(r.Context(), repoEntity)
+	if policy.SkipCommits {
+		writeError(w, r, "The policy of this repository skips its commits", http.StatusConflict)
 		return
 	}
 
@@ -124,49 +336,38 @@ func (c *CommitController) CrawlCommitsByRelease(w http.ResponseWriter, r *http.
 		"phase":       "scraping",
 	}).Info("Crawling commits")
 
-	// Crawl commits
-	commitStrings := c.commitScrape.CrawlCommit(repoEntity.UserName, repoEntity.RepoName, releaseEntity.TagName)
-	scrapeTime := time.Since(startTime)
-
-	c.log.WithFields(logrus.Fields{
-		"commit_count": len(commitStrings),
-		"duration_ms":  scrapeTime.Milliseconds(),
-		"phase":        "scraping_complete",
-	}).Info("Commit crawling completed")
-
-	// Process and save the commits
-	dbStartTime := time.Now()
-	commitRequests := make([]*model.CreateCommitRequest, 0, len(commitStrings))
-
-	// Parse the commit strings and create requests
-	for _, commitStr := range commitStrings {
-		// Extract hash and message from the string (format: "Hash: <hash> - Message: <message>")
-		parts := strings.SplitN(commitStr, " - Message: ", 2)
-		if len(parts) != 2 {
-			c.log.WithField("commit_str", commitStr).Warn("Invalid commit string format")
-			continue
-		}
-
-		hash := strings.TrimPrefix(parts[0], "Hash: ")
-		message := parts[1]
-
-		commitRequests = append(commitRequests, &model.CreateCommitRequest{
-			Hash:      hash,
-			Message:   message,
-			ReleaseID: releaseEntity.ID,
+	// Crawl the commits, saving each page as it comes
+	responses := make([]*model.CommitResponse, 0)
+	var dbTime time.Duration
+	var saveErr error
+	found, err := streamCommits(r.Context(), c.log, c.commitScrape, repoEntity, repoLimits(policy), releaseEntity.ID, releaseEntity.TagName,
+		c.defaultBranch(r.Context(), repoEntity), func(requests []*model.CreateCommitRequest) error {
+			dbStartTime := time.Now()
+			defer func() { dbTime += time.Since(dbStartTime) }()
+			saved, err := c.commitUsecase.BatchCreate(r.Context(), requests)
+			if err != nil {
+				saveErr = err
+				return err
+			}
+			responses = append(responses, saved...)
+			return nil
 		})
+	if saveErr != nil {
+		// The release stays in crawl_errors, so its commits are retried
+		recordReleaseCrawl(r.Context(), c.db, c.log, repoEntity, releaseEntity, fmt.Errorf("saving commits: %w", saveErr))
+		c.log.WithError(saveErr).Error("Error saving commits")
+		writeError(w, r, "Failed to save commits", http.StatusInternalServerError)
+		return
 	}
-
-	// Batch create the commits
-	responses, err := c.commitUsecase.BatchCreate(r.Context(), commitRequests)
+	recordReleaseCrawl(r.Context(), c.db, c.log, repoEntity, releaseEntity, err)
 	if err != nil {
-		c.log.WithError(err).Error("Error saving commits")
-		http.Error(w, "Failed to save commits", http.StatusInternalServerError)
+		c.log.WithError(err).WithField("release_id", releaseID).Error("Error crawling commits")
+		writeScrapeError(w, r, err, "Error crawling commits")
 		return
 	}
 
-	dbTime := time.Since(dbStartTime)
 	totalTime := time.Since(startTime)
+	scrapeTime := totalTime - dbTime
 
 	c.log.WithFields(logrus.Fields{
 		"scrape_time_ms": scrapeTime.Milliseconds(),
@@ -175,159 +376,387 @@ func (c *CommitController) CrawlCommitsByRelease(w http.ResponseWriter, r *http.
 		"commit_count":   len(responses),
 		"phase":          "complete",
 	}).Info("Commit crawling and saving completed")
+	countCrawl(r.Context(), found, len(responses), 0)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.CommitResponse]{
 		Data: responses,
 	}); err != nil {
 		c.log.WithError(err).Error("Error encoding response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
 	}
 }
 
+// CrawlRelease crawls and saves the commits of one release, such as a retry of a failed crawl.
+// Releases of repositories that are not crawlable any more are left alone.
+func (c *CommitController) CrawlRelease(ctx context.Context, releaseID int64) error {
+	db := c.db.WithContext(ctx)
+	releaseEntity := &entity.Release{}
+	if err := repository.NewReleaseRepository(c.log).FindById(ctx, db, releaseEntity, releaseID); err != nil {
+		return fmt.Errorf("finding release: %w", err)
+	}
+	repoEntity := &entity.Repository{}
+	if err := repository.NewRepoRepository(c.log).FindById(ctx, db, repoEntity, releaseEntity.RepoID); err != nil {
+		return fmt.Errorf("finding repository: %w", err)
+	}
+	policy := c.repoPolicies.For(ctx, repoEntity)
+	if !repoEntity.Crawlable(time.Now()) || policy.SkipCommits {
+		return nil
+	}
+
+	saved := 0
+	var saveErr error
+	found, err := streamCommits(ctx, c.log, c.commitScrape, repoEntity, repoLimits(policy), releaseEntity.ID, releaseEntity.TagName,
+		c.defaultBranch(ctx, repoEntity), func(requests []*model.CreateCommitRequest) error {
+			count, err := c.saveCommits(ctx, requests)
+			saved += count
+			if err != nil {
+				saveErr = err
+				return err
+			}
+			return nil
+		})
+	if saveErr != nil {
+		saveErr = fmt.Errorf("saving commits: %w", saveErr)
+		recordReleaseCrawl(ctx, c.db, c.log, repoEntity, releaseEntity, saveErr)
+		countCrawl(ctx, found, saved, found-saved)
+		return saveErr
+	}
+	recordReleaseCrawl(ctx, c.db, c.log, repoEntity, releaseEntity, err)
+	countCrawl(ctx, found, saved, 0)
+	if err != nil {
+		return fmt.Errorf("crawling commits: %w", err)
+	}
+	return nil
+}
+
+// CrawlAllCommits crawls the commits of every release, at most ?max_commits of each. With
+// ?stream=true the response is NDJSON instead: a model.CommitCrawlProgress line after each release,
+// flushed as it comes, then the totals.
 func (c *CommitController) CrawlAllCommits(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
-	c.log.WithField("phase", "start").Info("Starting crawling commits for all releases")
+	maxCommits, err := countQuery(r, "max_commits", 0)
+	if err != nil {
+		writeAppError(w, r, err, "Invalid max_commits")
+		return
+	}
+	if stream, _ := strconv.ParseBool(r.URL.Query().Get("stream")); stream {
+		c.streamAllCommits(w, r, maxCommits)
+		return
+	}
 
-	// Metrics tracking
-	successCount := 0
-	errorCount := 0
-	releaseCount := 0
-	commitCount := 0
-
-	// Get all releases
-	var releases []entity.Release
-	if err := c.db.Find(&releases).Error; err != nil {
-		c.log.WithError(err).Error("Error fetching all releases")
-		http.Error(w, "Error fetching releases", http.StatusInternalServerError)
+	result, err := c.CrawlCommits(r.Context(), maxCommits)
+	if result == nil {
+		writeError(w, r, "Error fetching releases", http.StatusInternalServerError)
 		return
 	}
+	if err != nil {
+		c.log.WithError(err).Warn("Commit crawl stopped early")
+	}
 
-	releaseCount = len(releases)
-	c.log.WithFields(logrus.Fields{
-		"release_count": releaseCount,
-		"duration_ms":   time.Since(startTime).Milliseconds(),
-		"phase":         "releases_loaded",
-	}).Info("Releases loaded from database")
-
-	// Process each release
-	for i, release := range releases {
-		releaseStartTime := time.Now()
-
-		// Get the repository for this release
-		repoEntity := &entity.Repository{}
-		if err := c.db.First(repoEntity, release.RepoID).Error; err != nil {
-			c.log.WithFields(logrus.Fields{
-				"release_id": release.ID,
-				"repo_id":    release.RepoID,
-				"error":      err.Error(),
-			}).Error("Failed to find repository for release")
-			errorCount++
-			continue
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(commitCrawlResponse(result)); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// streamAllCommits is CrawlAllCommits with ?stream=true
+func (c *CommitController) streamAllCommits(w http.ResponseWriter, r *http.Request, maxCommits int) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	// The status is already sent once progress is streamed, so the outcome goes in the last line
+	result, err := c.crawlCommits(r.Context(), maxCommits, func(progress *model.CommitCrawlProgress) {
+		if err := encoder.Encode(progress); err != nil {
+			return
 		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if result == nil {
+		failCrawl(r.Context(), "Error fetching releases")
+		encoder.Encode(model.ErrorResponse{
+			Code:    errorCode(http.StatusInternalServerError),
+			Message: "Error fetching releases",
+		})
+		return
+	}
+	if err != nil {
+		c.log.WithError(err).Warn("Commit crawl stopped early")
+	}
+	if err := encoder.Encode(commitCrawlResponse(result)); err != nil {
+		c.log.WithError(err).Warn("Commit crawl client went away")
+	}
+}
 
-		// Log processing start
-		c.log.WithFields(logrus.Fields{
-			"progress":   fmt.Sprintf("%d/%d", i+1, releaseCount),
-			"release_id": release.ID,
-			"tag":        release.TagName,
-			"repo":       fmt.Sprintf("%s/%s", repoEntity.UserName, repoEntity.RepoName),
-		}).Info("Processing release")
+func commitCrawlResponse(result *CommitCrawlResult) model.WebResponse[map[string]interface{}] {
+	return model.WebResponse[map[string]interface{}]{
+		Data: map[string]interface{}{
+			"releases_processed": result.Releases,
+			"commits_found":      result.Found,
+			"commits_saved":      result.Saved,
+			"errors":             result.Errors,
+		},
+	}
+}
 
-		// Crawl commits for this release
-		scrapeStartTime := time.Now()
-		commitStrings := c.commitScrape.CrawlCommit(repoEntity.UserName, repoEntity.RepoName, release.TagName)
-		scrapeTime := time.Since(scrapeStartTime)
+// CommitCrawlResult counts what CrawlCommits processed
+type CommitCrawlResult struct {
+	Releases int
+	Found    int
+	Saved    int
+	Errors   int
+}
 
-		releaseCommitCount := len(commitStrings)
-		commitCount += releaseCommitCount
+func (r *CommitCrawlResult) add(other releaseCommits) {
+	r.Releases++
+	r.Found += other.found
+	r.Saved += other.saved
+	r.Errors += other.errors
+}
 
-		c.log.WithFields(logrus.Fields{
-			"release_id":     release.ID,
-			"tag":            release.TagName,
-			"commits_found":  releaseCommitCount,
-			"scrape_time_ms": scrapeTime.Milliseconds(),
-		}).Info("Commits scraped")
-
-		// Process and save commits
-		dbStartTime := time.Now()
-		releaseSuccessCount := 0
-		releaseErrorCount := 0
-
-		commitRequests := make([]*model.CreateCommitRequest, 0, releaseCommitCount)
-
-		// Parse commit strings
-		for _, commitStr := range commitStrings {
-			parts := strings.SplitN(commitStr, " - Message: ", 2)
-			if len(parts) != 2 {
-				c.log.WithField("commit_str", commitStr).Warn("Invalid commit string format")
-				continue
-			}
+// crawlReleaseBatchSize is how many releases CrawlCommits loads at a time
+const crawlReleaseBatchSize = 200
 
-			hash := strings.TrimPrefix(parts[0], "Hash: ")
-			message := parts[1]
+// CrawlCommits scrapes and saves the commits of every release, releaseConcurrency releases at
+// a time, counting the commits that fail to save instead of stopping. Releases are loaded in
+// batches rather than all at once. Once ctx is cancelled no more releases are started and the
+// counts so far are returned with the error. A positive maxCommits narrows the max commits of
+// each repository's policy. It also runs the in-process commits stage.
+func (c *CommitController) CrawlCommits(ctx context.Context, maxCommits int) (*CommitCrawlResult, error) {
+	return c.crawlCommits(ctx, maxCommits, nil)
+}
 
-			commitRequests = append(commitRequests, &model.CreateCommitRequest{
-				Hash:      hash,
-				Message:   message,
-				ReleaseID: release.ID,
-			})
-		}
+// crawlCommits is CrawlCommits calling progress, when set, after each release; calls don't overlap
+func (c *CommitController) crawlCommits(ctx context.Context, maxCommits int,
+	progress func(*model.CommitCrawlProgress)) (*CommitCrawlResult, error) {
+	startTime := time.Now()
+	c.log.WithField("phase", "start").Info("Starting crawling commits for all releases")
+
+	// All releases, except those gone from GitHub or of archived repositories
+	releaseQuery := c.db.WithContext(ctx).Model(&entity.Release{}).
+		Where("status <> ?", entity.StatusMissing).
+		Where("repoid IN (?)", c.db.Model(&entity.Repository{}).Select("id")).
+		Session(&gorm.Session{})
+	var total int64
+	if err := releaseQuery.Count(&total).Error; err != nil {
+		c.log.WithError(err).Error("Error counting releases")
+		return nil, fmt.Errorf("counting releases: %w", err)
+	}
 
-		// Batch create if we have commits
-		if len(commitRequests) > 0 {
-			_, err := c.commitUsecase.BatchCreate(r.Context(), commitRequests)
+	releaseCount := int(total)
+	workers := max(1, min(int(c.releaseConcurrency.Load()), releaseCount))
+	c.log.WithFields(logrus.Fields{
+		"release_count": releaseCount,
+		"workers":       workers,
+		"duration_ms":   time.Since(startTime).Milliseconds(),
+		"phase":         "releases_counted",
+	}).Info("Releases counted in database")
+
+	// Totals of the workers, guarded by mutex
+	var mutex sync.Mutex
+	result := &CommitCrawlResult{}
+	recorder := utils.NewPhaseRecorder()
+
+	// A release refused by the stage budget stops the crawl, once the started ones are done
+	var budgetErr error
+	releaseRepository := repository.NewReleaseRepository(c.log)
+	err := releaseRepository.FindInBatches(ctx, releaseQuery, crawlReleaseBatchSize, func(releases []entity.Release) error {
+		// The next batch is loaded into the same slice, so this one is finished before returning
+		semaphore := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		for i := range releases {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			done, err := c.budget.Spawn()
 			if err != nil {
+				<-semaphore
+				budgetErr = err
+				return err
+			}
+
+			wg.Add(1)
+			go func(release *entity.Release) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				defer done()
+				outcome := c.crawlReleaseCommits(ctx, release, maxCommits, recorder)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				result.add(outcome)
+				done := fmt.Sprintf("%d/%d", result.Releases, releaseCount)
 				c.log.WithFields(logrus.Fields{
+					"progress":   done,
 					"release_id": release.ID,
 					"tag":        release.TagName,
-					"error":      err.Error(),
-				}).Error("Failed to save commits")
-				releaseErrorCount += len(commitRequests)
-				errorCount += len(commitRequests)
-			} else {
-				releaseSuccessCount = len(commitRequests)
-				successCount += len(commitRequests)
-			}
+					"phase":      "release_done",
+				}).Info("Release done")
+				if progress != nil {
+					progress(&model.CommitCrawlProgress{
+						Progress:     done,
+						ReleaseID:    release.ID,
+						Tag:          release.TagName,
+						Repo:         outcome.repo,
+						Skipped:      outcome.skipped,
+						CommitsFound: outcome.found,
+						CommitsSaved: outcome.saved,
+						Error:        outcome.err,
+					})
+				}
+			}(&releases[i])
 		}
-
-		dbTime := time.Since(dbStartTime)
-		releaseTotalTime := time.Since(releaseStartTime)
-
-		c.log.WithFields(logrus.Fields{
-			"release_id":     release.ID,
-			"tag":            release.TagName,
-			"scrape_time_ms": scrapeTime.Milliseconds(),
-			"db_time_ms":     dbTime.Milliseconds(),
-			"total_time_ms":  releaseTotalTime.Milliseconds(),
-			"success_count":  releaseSuccessCount,
-			"error_count":    releaseErrorCount,
-		}).Info("Release processing completed")
-	}
+		return nil
+	})
 
 	// Log completion
 	totalTime := time.Since(startTime)
 	c.log.WithFields(logrus.Fields{
 		"total_time_ms":      totalTime.Milliseconds(),
-		"releases_processed": releaseCount,
-		"commits_total":      commitCount,
-		"success_count":      successCount,
-		"error_count":        errorCount,
-	}).Info("Commit crawling operation completed")
+		"releases_processed": result.Releases,
+		"releases_total":     releaseCount,
+		"workers":            workers,
+		"commits_total":      result.Found,
+		"success_count":      result.Saved,
+		"error_count":        result.Errors,
+	}).WithFields(recorder.Fields()).Info("Commit crawling operation completed")
+	countCrawl(ctx, result.Found, result.Saved, result.Errors)
+
+	if budgetErr != nil {
+		c.log.WithError(budgetErr).Error("Commit crawl refused by its budget")
+		return result, fmt.Errorf("commit crawl stopped after %d of %d releases: %w", result.Releases, releaseCount, budgetErr)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, fmt.Errorf("commit crawl stopped after %d of %d releases: %w", result.Releases, releaseCount, ctxErr)
+	}
+	if err != nil {
+		c.log.WithError(err).Error("Error fetching releases")
+		return nil, fmt.Errorf("fetching releases: %w", err)
+	}
+	return result, nil
+}
 
-	// Send response
-	w.Header().Set("Content-Type", "application/json")
-	response := model.WebResponse[map[string]interface{}]{
-		Data: map[string]interface{}{
-			"releases_processed": releaseCount,
-			"commits_found":      commitCount,
-			"commits_saved":      successCount,
-			"errors":             errorCount,
-		},
+// releaseCommits is the outcome of crawling the commits of one release
+type releaseCommits struct {
+	repo    string
+	skipped bool
+	found   int
+	saved   int
+	errors  int
+	err     string
+}
+
+// crawlReleaseCommits scrapes the commits of a release, at most maxCommits when positive, and
+// saves them. Releases of repositories that are not crawlable, or whose policy skips commits, are skipped.
+func (c *CommitController) crawlReleaseCommits(ctx context.Context, release *entity.Release, maxCommits int,
+	recorder *utils.PhaseRecorder) releaseCommits {
+	var result releaseCommits
+	releaseStartTime := time.Now()
+
+	// Get the repository for this release
+	repoEntity := &entity.Repository{}
+	if err := c.db.WithContext(ctx).First(repoEntity, release.RepoID).Error; err != nil {
+		c.log.WithFields(logrus.Fields{
+			"release_id": release.ID,
+			"repo_id":    release.RepoID,
+			"error":      err.Error(),
+		}).Error("Failed to find repository for release")
+		result.errors++
+		result.err = err.Error()
+		return result
+	}
+	result.repo = fmt.Sprintf("%s/%s", repoEntity.UserName, repoEntity.RepoName)
+	policy := c.repoPolicies.For(ctx, repoEntity)
+	if !repoEntity.Crawlable(time.Now()) || policy.SkipCommits {
+		result.skipped = true
+		return result
 	}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		c.log.WithError(err).Error("Error encoding response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+	// Log processing start
+	c.log.WithFields(logrus.Fields{
+		"release_id": release.ID,
+		"tag":        release.TagName,
+		"repo":       result.repo,
+	}).Info("Processing release")
+
+	// Crawl the commits of this release, saving each page as it comes
+	var dbTime time.Duration
+	limits := repoLimits(policy).Narrow(scrape.RepoLimits{MaxCommits: maxCommits})
+	var found int
+	// saveErr is the last failed save; the crawl goes on with the next pages
+	var saveErr error
+	_, err := c.repoBreakers.ExecuteHedged(ctx, result.repo, func(ctx context.Context) (interface{}, error) {
+		// A retried attempt saves its pages again
+		result.saved, result.errors, result.err = 0, 0, ""
+		saveErr = nil
+		var err error
+		found, err = streamCommits(ctx, c.log, c.commitScrape, repoEntity, limits, release.ID, release.TagName,
+			c.defaultBranch(ctx, repoEntity), func(requests []*model.CreateCommitRequest) error {
+				dbStartTime := time.Now()
+				defer func() { dbTime += time.Since(dbStartTime) }()
+				saved, err := c.saveCommits(ctx, requests)
+				result.saved += saved
+				if err != nil {
+					c.log.WithFields(logrus.Fields{
+						"release_id": release.ID,
+						"tag":        release.TagName,
+						"error":      err.Error(),
+					}).Error("Failed to save commits")
+					result.errors += len(requests) - saved
+					result.err = err.Error()
+					saveErr = err
+				}
+				return nil
+			})
+		return nil, err
+	})
+	if utils.BreakerRejected(err) {
+		c.log.WithFields(logrus.Fields{
+			"release_id": release.ID,
+			"repo":       result.repo,
+		}).Warn("Skipping release, the circuit breaker of its repository is open")
+		result.skipped = true
+		return result
+	}
+	result.found = found
+	releaseTotalTime := time.Since(releaseStartTime)
+	scrapeTime := releaseTotalTime - dbTime
+	recorder.Record(utils.PhaseScrape, scrapeTime)
+	recorder.Record(utils.PhaseDatabase, dbTime)
+	recorder.Record(utils.PhaseTotal, releaseTotalTime)
+	// A crawl cut short by the caller says nothing about the release. One with commits left
+	// unsaved stays in crawl_errors, so they are retried.
+	if ctx.Err() == nil {
+		crawlErr := err
+		if crawlErr == nil && saveErr != nil {
+			crawlErr = fmt.Errorf("saving commits: %w", saveErr)
+		}
+		recordReleaseCrawl(ctx, c.db, c.log, repoEntity, release, crawlErr)
 	}
+	if err != nil {
+		c.log.WithError(err).WithField("release_id", release.ID).Error("Error crawling commits")
+		result.errors++
+		result.err = err.Error()
+		return result
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"release_id":     release.ID,
+		"tag":            release.TagName,
+		"commits_found":  result.found,
+		"scrape_time_ms": scrapeTime.Milliseconds(),
+		"db_time_ms":     dbTime.Milliseconds(),
+		"total_time_ms":  releaseTotalTime.Milliseconds(),
+		"success_count":  result.saved,
+		"error_count":    result.errors,
+	}).Info("Release processing completed")
+	return result
 }
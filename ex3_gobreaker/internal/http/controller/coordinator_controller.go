@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+type CoordinatorController struct {
+	log         *logrus.Logger
+	coordinator *service.CrawlingCoordinator
+}
+
+func NewCoordinatorController(log *logrus.Logger, coordinator *service.CrawlingCoordinator) *CoordinatorController {
+	return &CoordinatorController{
+		log:         log,
+		coordinator: coordinator,
+	}
+}
+
+// DryRun reports which stages the next coordinator cycle would trigger and why
+func (c *CoordinatorController) DryRun(w http.ResponseWriter, r *http.Request) {
+	plans := c.coordinator.DryRun()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.StagePlan]{
+		Data: plans,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// RunStage triggers a single coordinator stage in the background, bypassing caches and pauses
+func (c *CoordinatorController) RunStage(w http.ResponseWriter, r *http.Request) {
+	stage := chi.URLParam(r, "stage")
+	if !c.coordinator.HasStage(stage) {
+		c.log.WithField("stage", stage).Error("Unknown coordinator stage")
+		writeErrorDetails(w, r, "Unknown stage", http.StatusNotFound, map[string]string{"stage": stage})
+		return
+	}
+
+	c.log.WithField("stage", stage).Info("Manually triggering coordinator stage")
+
+	// Crawl stages can take hours, so run detached from the request
+	go func() {
+		if err := c.coordinator.RunStage(stage); err != nil {
+			c.log.WithError(err).WithField("stage", stage).Error("Manual coordinator stage failed")
+			return
+		}
+		c.log.WithField("stage", stage).Info("Manual coordinator stage completed")
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[map[string]string]{
+		Data: map[string]string{
+			"stage":  stage,
+			"status": "started",
+		},
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// StageData responds with the cached summary of a stage, marked stale while its breaker is open
+func (c *CoordinatorController) StageData(w http.ResponseWriter, r *http.Request) {
+	stage := chi.URLParam(r, "stage")
+	data, err := c.coordinator.StageData(stage)
+	if err != nil {
+		writeErrorDetails(w, r, "Unknown stage", http.StatusNotFound, map[string]string{"stage": stage})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*service.StageData]{
+		Data: data,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// Runs lists recent coordinator runs with the outcome of each stage
+func (c *CoordinatorController) Runs(w http.ResponseWriter, r *http.Request) {
+	runs := c.coordinator.Runs()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.CoordinatorRun]{
+		Data: runs,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// Metrics reports per-stage run, failure and item counts
+func (c *CoordinatorController) Metrics(w http.ResponseWriter, r *http.Request) {
+	metrics := c.coordinator.Metrics()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.StageMetrics]{
+		Data: metrics,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+type stabilityThresholdRequest struct {
+	Threshold *int `json:"threshold"`
+}
+
+// SetStabilityThreshold changes the default stability threshold of every stage without its own
+func (c *CoordinatorController) SetStabilityThreshold(w http.ResponseWriter, r *http.Request) {
+	var request stabilityThresholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Threshold == nil || *request.Threshold < 1 {
+		writeError(w, r, "Invalid request body, expected a positive threshold", http.StatusBadRequest)
+		return
+	}
+
+	c.coordinator.SetStabilityThreshold(*request.Threshold)
+	c.writePlans(w)
+}
+
+// SetStageStabilityThreshold changes one stage's stability threshold; 0 reverts to the default
+func (c *CoordinatorController) SetStageStabilityThreshold(w http.ResponseWriter, r *http.Request) {
+	stage := chi.URLParam(r, "stage")
+	if !c.coordinator.HasStage(stage) {
+		writeErrorDetails(w, r, "Unknown stage", http.StatusNotFound, map[string]string{"stage": stage})
+		return
+	}
+
+	var request stabilityThresholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Threshold == nil {
+		writeError(w, r, "Invalid request body, expected a threshold", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.coordinator.SetStageStabilityThreshold(stage, *request.Threshold); err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"stage":     stage,
+		"threshold": *request.Threshold,
+	}).Info("Coordinator stage stability threshold changed")
+	c.writePlans(w)
+}
+
+// writePlans responds with the dry-run plans, which include each stage's effective threshold and pause
+func (c *CoordinatorController) writePlans(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.StagePlan]{
+		Data: c.coordinator.DryRun(),
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// PrometheusMetrics exports the coordinator's cycle, stage and breaker metrics for scraping
+func (c *CoordinatorController) PrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := c.coordinator.WritePrometheus(w); err != nil {
+		c.log.WithError(err).Error("Error writing coordinator metrics")
+	}
+}
+
+// Healthz reports the coordinator's own health, failing with 503 when periodic cycles stalled
+func (c *CoordinatorController) Healthz(w http.ResponseWriter, r *http.Request) {
+	health := c.coordinator.Health(time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	if health.Status != service.HealthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(model.WebResponse[service.CoordinatorHealth]{
+		Data: health,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
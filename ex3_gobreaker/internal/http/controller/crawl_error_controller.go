@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// JobRetryCrawlErrors crawls again the releases of failed commit crawls
+const JobRetryCrawlErrors = "retry_crawl_errors"
+
+const (
+	defaultCrawlErrorLimit = 100
+	maxCrawlErrorLimit     = 1000
+)
+
+// retryCrawlErrorsJob is the payload of retry jobs: the releases to crawl again in order, or
+// every release whose crawl error has an ID up to UpToID, read a page at a time as the job goes
+type retryCrawlErrorsJob struct {
+	ReleaseIDs []int64 `json:"releaseIDs,omitempty"`
+	UpToID     int64   `json:"upToID,omitempty"`
+	// Total is how many releases the job retries, for its progress
+	Total int `json:"total"`
+}
+
+type CrawlErrorController struct {
+	log              *logrus.Logger
+	db               *gorm.DB
+	commitController *CommitController
+	jobs             *service.JobManager
+}
+
+func NewCrawlErrorController(log *logrus.Logger, db *gorm.DB, commitController *CommitController,
+	jobs *service.JobManager, runs *CrawlRunRecorder) *CrawlErrorController {
+	c := &CrawlErrorController{
+		log:              log,
+		db:               db,
+		commitController: commitController,
+		jobs:             jobs,
+	}
+	// Registered on every instance, the retries may run on a separate worker
+	jobs.Handle(JobRetryCrawlErrors, runs.Job(JobRetryCrawlErrors, c.runRetryJob))
+	return c
+}
+
+// crawlErrorEntity reads the entity query parameter; only release crawls are recorded for now
+func crawlErrorEntity(r *http.Request) (string, bool) {
+	entityType := r.URL.Query().Get("entity")
+	if entityType == "" {
+		return entity.CrawlErrorRelease, true
+	}
+	return entityType, entityType == entity.CrawlErrorRelease
+}
+
+// ListCrawlErrors returns the entities whose last crawl failed, most recent failure first
+func (c *CrawlErrorController) ListCrawlErrors(w http.ResponseWriter, r *http.Request) {
+	entityType, ok := crawlErrorEntity(r)
+	if !ok {
+		writeError(w, r, "Invalid entity, expected release", http.StatusBadRequest)
+		return
+	}
+	limit := defaultCrawlErrorLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, r, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxCrawlErrorLimit)
+	}
+
+	crawlErrorRepository := repository.NewCrawlErrorRepository(c.log)
+	crawlErrs := []entity.CrawlError{}
+	if err := crawlErrorRepository.FindRecent(r.Context(), c.db, &crawlErrs, entityType, nil, limit); err != nil {
+		c.log.WithError(err).Error("Error fetching crawl errors")
+		writeError(w, r, "Error fetching crawl errors", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]model.CrawlErrorResponse, 0, len(crawlErrs))
+	for _, crawlErr := range crawlErrs {
+		responses = append(responses, model.CrawlErrorResponse{
+			ID:            crawlErr.ID,
+			EntityType:    crawlErr.EntityType,
+			EntityID:      crawlErr.EntityID,
+			Message:       crawlErr.Message,
+			HTTPStatus:    crawlErr.HTTPStatus,
+			RetryCount:    crawlErr.RetryCount,
+			FirstFailedAt: crawlErr.FirstFailedAt,
+			LastFailedAt:  crawlErr.LastFailedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]model.CrawlErrorResponse]{
+		Data: responses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// RetryCrawlErrors starts a background job crawling again the entities of the given crawl
+// errors, or of every recorded error of the entity type when the body has no IDs
+func (c *CrawlErrorController) RetryCrawlErrors(w http.ResponseWriter, r *http.Request) {
+	entityType, ok := crawlErrorEntity(r)
+	if !ok {
+		writeError(w, r, "Invalid entity, expected release", http.StatusBadRequest)
+		return
+	}
+	var request model.RetryCrawlErrorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(request.IDs) > maxCrawlErrorLimit {
+		writeError(w, r, fmt.Sprintf("At most %d crawl error IDs can be retried at once, send none to retry all",
+			maxCrawlErrorLimit), http.StatusBadRequest)
+		return
+	}
+	if err := c.jobs.Admit(time.Now()); err != nil {
+		writeBlackout(w, r, err)
+		return
+	}
+
+	job, err := c.retryJob(r.Context(), entityType, request.IDs)
+	if err != nil {
+		c.log.WithError(err).Error("Error fetching crawl errors")
+		writeError(w, r, "Error fetching crawl errors", http.StatusInternalServerError)
+		return
+	}
+	if job.Total == 0 {
+		writeError(w, r, "No crawl errors to retry", http.StatusNotFound)
+		return
+	}
+
+	submitted, err := c.jobs.Submit(r.Context(), JobRetryCrawlErrors, job)
+	if err != nil {
+		c.log.WithError(err).Error("Error starting crawl error retry")
+		writeError(w, r, "Error starting retry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.RetryCrawlErrorsResponse]{
+		Data: &model.RetryCrawlErrorsResponse{JobID: submitted.ID, Retried: job.Total},
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// retryJob selects the releases of the crawl errors with ids, or of every crawl error without
+func (c *CrawlErrorController) retryJob(ctx context.Context, entityType string, ids []int64) (retryCrawlErrorsJob, error) {
+	crawlErrorRepository := repository.NewCrawlErrorRepository(c.log)
+	if len(ids) == 0 {
+		total, maxID, err := crawlErrorRepository.CountAll(ctx, c.db, entityType)
+		return retryCrawlErrorsJob{UpToID: maxID, Total: int(total)}, err
+	}
+
+	crawlErrs := []entity.CrawlError{}
+	if err := crawlErrorRepository.FindRecent(ctx, c.db, &crawlErrs, entityType, ids, len(ids)); err != nil {
+		return retryCrawlErrorsJob{}, err
+	}
+	job := retryCrawlErrorsJob{ReleaseIDs: make([]int64, len(crawlErrs)), Total: len(crawlErrs)}
+	for i, crawlErr := range crawlErrs {
+		job.ReleaseIDs[i] = crawlErr.EntityID
+	}
+	return job, nil
+}
+
+// runRetryJob crawls the commits of each release again. A release failing again only counts a
+// retry on its crawl error; the job fails when any release did.
+func (c *CrawlErrorController) runRetryJob(ctx context.Context, payload json.RawMessage, progress func(string, ...interface{})) error {
+	var job retryCrawlErrorsJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("decoding retry job: %w", err)
+	}
+
+	retried := 0
+	var errs []error
+	retry := func(releaseID int64) {
+		retried++
+		progress("release %d/%d (ID %d)", retried, job.Total, releaseID)
+		if err := c.commitController.CrawlRelease(ctx, releaseID); err != nil {
+			errs = append(errs, fmt.Errorf("release %d: %w", releaseID, err))
+		}
+	}
+	for _, releaseID := range job.ReleaseIDs {
+		retry(releaseID)
+	}
+	if job.UpToID > 0 {
+		// Crawl errors keep their ID when they fail again, so the pages do not shift, and those
+		// recorded during the job are left for the next one
+		crawlErrorRepository := repository.NewCrawlErrorRepository(c.log)
+		selected := c.db.Where("entitytype = ? AND id <= ?", entity.CrawlErrorRelease, job.UpToID)
+		for afterID := int64(0); ctx.Err() == nil; {
+			crawlErrs := []entity.CrawlError{}
+			err := crawlErrorRepository.FindAfter(ctx, selected.Session(&gorm.Session{}), &crawlErrs, afterID, maxCrawlErrorLimit)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("fetching crawl errors: %w", err))
+				break
+			}
+			for _, crawlErr := range crawlErrs {
+				retry(crawlErr.EntityID)
+			}
+			if len(crawlErrs) < maxCrawlErrorLimit {
+				break
+			}
+			afterID = crawlErrs[len(crawlErrs)-1].ID
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	progress("done")
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCrawlRunLimit = 100
+	maxCrawlRunLimit     = 1000
+)
+
+type CrawlRunController struct {
+	log *logrus.Logger
+	db  *gorm.DB
+}
+
+func NewCrawlRunController(log *logrus.Logger, db *gorm.DB) *CrawlRunController {
+	return &CrawlRunController{
+		log: log,
+		db:  db,
+	}
+}
+
+// ListCrawlRuns returns the latest crawl runs, filtered by source, endpoint substring, API key,
+// failure and a start time range given as RFC 3339 "from" and "to"
+func (c *CrawlRunController) ListCrawlRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultCrawlRunLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, r, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxCrawlRunLimit)
+	}
+
+	filter := repository.CrawlRunFilter{
+		Source:   query.Get("source"),
+		Endpoint: query.Get("endpoint"),
+		APIKey:   query.Get("apiKey"),
+	}
+	switch filter.Source {
+	case "", entity.CrawlSourceAPI, entity.CrawlSourceCoordinator, entity.CrawlSourceJob:
+	default:
+		writeError(w, r, "Invalid source", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("failed"); value != "" {
+		failed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, r, "Invalid failed", http.StatusBadRequest)
+			return
+		}
+		filter.Failed = &failed
+	}
+	for name, bound := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, r, "Invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		*bound = &parsed
+	}
+
+	crawlRunRepository := repository.NewCrawlRunRepository(c.log)
+	runs := []entity.CrawlRun{}
+	if err := crawlRunRepository.FindRecent(r.Context(), c.db, &runs, filter, limit); err != nil {
+		c.log.WithError(err).Error("Error fetching crawl runs")
+		writeError(w, r, "Error fetching crawl runs", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]model.CrawlRunResponse, 0, len(runs))
+	for _, run := range runs {
+		responses = append(responses, model.CrawlRunResponse{
+			ID:         run.ID,
+			Source:     run.Source,
+			Endpoint:   run.Endpoint,
+			APIKey:     run.APIKey,
+			StartedAt:  run.StartedAt,
+			DurationMs: run.DurationMs,
+			StatusCode: run.StatusCode,
+			Found:      run.Found,
+			Saved:      run.Saved,
+			Errored:    run.Errored,
+			Error:      run.Error,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]model.CrawlRunResponse]{
+		Data: responses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// crawlTally counts the items of the crawl run in progress; the crawl code adds to the tally of
+// its context with countCrawl
+type crawlTally struct {
+	mutex   sync.Mutex
+	found   int
+	saved   int
+	errored int
+	err     string
+}
+
+type crawlTallyKey struct{}
+
+func crawlTallyFrom(ctx context.Context) *crawlTally {
+	tally, _ := ctx.Value(crawlTallyKey{}).(*crawlTally)
+	return tally
+}
+
+// countCrawl adds found, saved and errored items to the crawl run of ctx, if any
+func countCrawl(ctx context.Context, found, saved, errored int) {
+	tally := crawlTallyFrom(ctx)
+	if tally == nil {
+		return
+	}
+	tally.mutex.Lock()
+	defer tally.mutex.Unlock()
+	tally.found += found
+	tally.saved += saved
+	tally.errored += errored
+}
+
+// failCrawl keeps the error message of the crawl run of ctx, if any; the first one wins
+func failCrawl(ctx context.Context, message string) {
+	tally := crawlTallyFrom(ctx)
+	if tally == nil {
+		return
+	}
+	tally.mutex.Lock()
+	defer tally.mutex.Unlock()
+	if tally.err == "" {
+		tally.err = message
+	}
+}
+
+// CrawlRunRecorder stores a crawl_runs row for every crawl operation: crawl endpoints called
+// through Record, in-process coordinator stages through Run and crawl jobs through Job.
+// A nil recorder records nothing.
+type CrawlRunRecorder struct {
+	log        *logrus.Logger
+	db         *gorm.DB
+	repository *repository.CrawlRunRepository
+}
+
+func NewCrawlRunRecorder(log *logrus.Logger, db *gorm.DB) *CrawlRunRecorder {
+	return &CrawlRunRecorder{
+		log:        log,
+		db:         db,
+		repository: repository.NewCrawlRunRepository(log),
+	}
+}
+
+// Record is the middleware of crawl endpoints. It goes after authentication, so the run is
+// attributed to the API key or, for a signed request, to the coordinator.
+func (c *CrawlRunRecorder) Record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		run := &entity.CrawlRun{
+			Source:    entity.CrawlSourceAPI,
+			Endpoint:  r.Method + " " + r.URL.Path,
+			StartedAt: time.Now(),
+		}
+		if auth.IsSignedRequest(r.Context()) {
+			run.Source = entity.CrawlSourceCoordinator
+		} else if principal, ok := auth.FromContext(r.Context()); ok {
+			run.APIKey = principal.Name
+		}
+
+		tally := &crawlTally{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), crawlTallyKey{}, tally)))
+
+		run.StatusCode = ww.Status()
+		if run.StatusCode == 0 {
+			run.StatusCode = http.StatusOK
+		}
+		c.finish(run, tally)
+	})
+}
+
+// Run records a crawl called in process by source, such as a coordinator stage, under endpoint
+func (c *CrawlRunRecorder) Run(ctx context.Context, source, endpoint string, crawl func(ctx context.Context) error) error {
+	if c == nil {
+		return crawl(ctx)
+	}
+	_, err := c.RunCounted(ctx, source, endpoint, "", crawl)
+	return err
+}
+
+// CrawlCounts are the items a crawl run found, saved and failed to crawl or save
+type CrawlCounts struct {
+	Found   int
+	Saved   int
+	Errored int
+}
+
+// RunCounted is Run returning what the crawl counted, for callers reporting it, such as the gRPC
+// API; apiKey names the key that called it, if any. A nil recorder still counts.
+func (c *CrawlRunRecorder) RunCounted(ctx context.Context, source, endpoint, apiKey string,
+	crawl func(ctx context.Context) error) (CrawlCounts, error) {
+	run := &entity.CrawlRun{
+		Source:    source,
+		Endpoint:  endpoint,
+		APIKey:    apiKey,
+		StartedAt: time.Now(),
+	}
+	tally := &crawlTally{}
+	err := crawl(context.WithValue(ctx, crawlTallyKey{}, tally))
+
+	run.StatusCode = http.StatusOK
+	if err != nil {
+		run.StatusCode = http.StatusInternalServerError
+		failCrawl(context.WithValue(ctx, crawlTallyKey{}, tally), err.Error())
+	}
+	tally.mutex.Lock()
+	counts := CrawlCounts{Found: tally.found, Saved: tally.saved, Errored: tally.errored}
+	tally.mutex.Unlock()
+	if c != nil {
+		c.finish(run, tally)
+	}
+	return counts, err
+}
+
+// Job records the runs of a crawl job handler, under the endpoint "job <kind>"
+func (c *CrawlRunRecorder) Job(kind string, handler service.JobHandler) service.JobHandler {
+	return func(ctx context.Context, payload json.RawMessage, progress func(string, ...interface{})) error {
+		return c.Run(ctx, entity.CrawlSourceJob, "job "+kind, func(ctx context.Context) error {
+			return handler(ctx, payload, progress)
+		})
+	}
+}
+
+// finish stores a run with its tally. The crawl already happened, so a failure to store it
+// is only logged.
+func (c *CrawlRunRecorder) finish(run *entity.CrawlRun, tally *crawlTally) {
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+	tally.mutex.Lock()
+	run.Found, run.Saved, run.Errored, run.Error = tally.found, tally.saved, tally.errored, tally.err
+	tally.mutex.Unlock()
+
+	if err := c.repository.Create(context.Background(), c.db, run); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"source":   run.Source,
+			"endpoint": run.Endpoint,
+		}).Error("Error recording crawl run")
+	}
+}
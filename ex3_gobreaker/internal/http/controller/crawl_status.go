@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/scrape"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// recordRepoCrawl updates the crawl status of a repository from the outcome of crawling it.
+// A 404 is counted, and entity.NotFoundThreshold() of them in a row archive the repository so it
+// is no longer crawled. A 403 or 429 marks it blocked until the delay GitHub asked for, and a
+// successful crawl makes it active again. Other errors keep the status, so the next crawl
+// simply tries again.
+func recordRepoCrawl(ctx context.Context, db *gorm.DB, log *logrus.Logger, repo *entity.Repository, crawlErr error) {
+	if errors.Is(crawlErr, scrape.ErrNotFound) {
+		recordRepoNotFound(ctx, db, log, repo)
+		return
+	}
+
+	status := entity.StatusActive
+	var retryAt *time.Time
+	switch {
+	case errors.Is(crawlErr, scrape.ErrBlocked):
+		status = entity.StatusBlocked
+		retry := time.Now().Add(scrape.RetryAfter(crawlErr))
+		retryAt = &retry
+	case crawlErr != nil:
+		return
+	}
+	if status == entity.StatusActive && (repo.Status == entity.StatusActive || repo.Status == "") && repo.NotFoundCount == 0 {
+		return
+	}
+
+	repoRepository := repository.NewRepoRepository(log)
+	if err := repoRepository.UpdateStatus(ctx, db, repo.ID, status, retryAt); err != nil {
+		log.WithError(err).WithField("repo_id", repo.ID).Error("Error updating repository status")
+		return
+	}
+	if status == entity.StatusActive {
+		repo.NotFoundCount = 0
+	}
+	repo.Status = status
+	repo.RetryAt = retryAt
+	log.WithFields(logrus.Fields{
+		"repo":     repo.UserName + "/" + repo.RepoName,
+		"status":   status,
+		"retry_at": retryAt,
+	}).Warn("Repository crawl status changed")
+}
+
+// recordRepoNotFound counts a 404 of a repository and archives it at entity.NotFoundThreshold()
+func recordRepoNotFound(ctx context.Context, db *gorm.DB, log *logrus.Logger, repo *entity.Repository) {
+	repoRepository := repository.NewRepoRepository(log)
+	fields := logrus.Fields{
+		"repo":            repo.UserName + "/" + repo.RepoName,
+		"not_found_count": repo.NotFoundCount + 1,
+	}
+	if err := repoRepository.CountNotFound(ctx, db, repo.ID); err != nil {
+		log.WithError(err).WithField("repo_id", repo.ID).Error("Error counting repository 404")
+		return
+	}
+	repo.NotFoundCount++
+	if repo.NotFoundCount < entity.NotFoundThreshold() {
+		log.WithFields(fields).Warn("Repository not found on GitHub")
+		return
+	}
+
+	now := time.Now()
+	if err := repoRepository.Archive(ctx, db, repo.ID, now); err != nil {
+		log.WithError(err).WithField("repo_id", repo.ID).Error("Error archiving repository")
+		return
+	}
+	repo.Status = entity.StatusMissing
+	repo.RetryAt = nil
+	repo.Archived = true
+	repo.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	log.WithFields(fields).Warn("Repository not found on GitHub too many times in a row, archived")
+}
+
+// recordReleaseCrawl keeps the failure of a release in crawl_errors, or clears it after a
+// successful crawl, and tombstones the release when its page is gone. Blocked crawls are also
+// recorded on the repository, since GitHub blocks the client rather than a single release.
+func recordReleaseCrawl(ctx context.Context, db *gorm.DB, log *logrus.Logger,
+	repo *entity.Repository, release *entity.Release, crawlErr error) {
+	recordCrawlError(ctx, db, log, entity.CrawlErrorRelease, release.ID, crawlErr)
+	if errors.Is(crawlErr, scrape.ErrBlocked) {
+		recordRepoCrawl(ctx, db, log, repo, crawlErr)
+		return
+	}
+	if !errors.Is(crawlErr, scrape.ErrNotFound) {
+		return
+	}
+
+	releaseRepository := repository.NewReleaseRepository(log)
+	now := time.Now()
+	if err := releaseRepository.Tombstone(ctx, db, []int64{release.ID}, now); err != nil {
+		log.WithError(err).WithField("release_id", release.ID).Error("Error tombstoning release")
+		return
+	}
+	release.Status = entity.StatusMissing
+	release.TombstonedAt = &now
+	log.WithFields(logrus.Fields{
+		"repo": repo.UserName + "/" + repo.RepoName,
+		"tag":  release.TagName,
+	}).Warn("Release is gone from GitHub, tombstoned")
+}
+
+// recordCrawlError stores crawlErr as the last failure of an entity, with the status GitHub
+// answered, or clears the entity's failure when crawlErr is nil
+func recordCrawlError(ctx context.Context, db *gorm.DB, log *logrus.Logger, entityType string, entityID int64, crawlErr error) {
+	crawlErrorRepository := repository.NewCrawlErrorRepository(log)
+	fields := logrus.Fields{"entity_type": entityType, "entity_id": entityID}
+	if crawlErr == nil {
+		if err := crawlErrorRepository.Clear(ctx, db, entityType, entityID); err != nil {
+			log.WithError(err).WithFields(fields).Error("Error clearing crawl error")
+		}
+		return
+	}
+
+	now := time.Now()
+	record := &entity.CrawlError{
+		EntityType:    entityType,
+		EntityID:      entityID,
+		Message:       crawlErr.Error(),
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	}
+	var statusErr *scrape.StatusError
+	if errors.As(crawlErr, &statusErr) {
+		record.HTTPStatus = statusErr.Status
+	}
+	if err := crawlErrorRepository.Record(ctx, db, record); err != nil {
+		log.WithError(err).WithFields(fields).Error("Error recording crawl error")
+	}
+}
+
+// writeScrapeError answers 404 when the page is gone from GitHub, 503 with Retry-After when
+// GitHub blocks the crawler, and 502 with message for other scrape failures
+func writeScrapeError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, scrape.ErrNotFound):
+		writeAppError(w, r, err, "Not found on GitHub")
+	case errors.Is(err, scrape.ErrBlocked):
+		writeAppError(w, r, err, "GitHub is blocking the crawler, retry later")
+	case apperrors.Mapped(err):
+		writeAppError(w, r, err, message)
+	default:
+		writeErrorResponse(w, r, message, http.StatusBadGateway, apperrors.CodeBadGateway, nil)
+	}
+}
+
+// writeBlackout refuses a crawl during a blackout window, with Retry-After set to its end
+func writeBlackout(w http.ResponseWriter, r *http.Request, err error) {
+	writeAppError(w, r, err, "Crawling is paused during a blackout window, retry later")
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PoolStats is the state of the database connection pool
+type PoolStats struct {
+	MaxOpen           int   `json:"maxOpen"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"inUse"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"waitCount"`
+	WaitMs            int64 `json:"waitMs"`
+	MaxIdleClosed     int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64 `json:"maxLifetimeClosed"`
+}
+
+// DatabaseStats is the connection pool with the statements that ran longer than
+// database.slow_query.threshold
+type DatabaseStats struct {
+	Pool        PoolStats                 `json:"pool"`
+	SlowQueries repository.SlowQueryStats `json:"slowQueries"`
+}
+
+type DatabaseController struct {
+	log         *logrus.Logger
+	connection  *sql.DB
+	slowQueries *repository.SlowQueryLog
+}
+
+func NewDatabaseController(log *logrus.Logger, connection *sql.DB, slowQueries *repository.SlowQueryLog) *DatabaseController {
+	return &DatabaseController{
+		log:         log,
+		connection:  connection,
+		slowQueries: slowQueries,
+	}
+}
+
+// DatabaseStats reports the connection pool and the latest slow statements, with their
+// parameters described by type only
+func (c *DatabaseController) DatabaseStats(w http.ResponseWriter, r *http.Request) {
+	pool := c.connection.Stats()
+	stats := DatabaseStats{
+		Pool: PoolStats{
+			MaxOpen:           pool.MaxOpenConnections,
+			Open:              pool.OpenConnections,
+			InUse:             pool.InUse,
+			Idle:              pool.Idle,
+			WaitCount:         pool.WaitCount,
+			WaitMs:            pool.WaitDuration.Milliseconds(),
+			MaxIdleClosed:     pool.MaxIdleClosed,
+			MaxIdleTimeClosed: pool.MaxIdleTimeClosed,
+			MaxLifetimeClosed: pool.MaxLifetimeClosed,
+		},
+	}
+	if c.slowQueries != nil {
+		stats.SlowQueries = c.slowQueries.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[DatabaseStats]{
+		Data: stats,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// DebugMode decides when JSON responses are pretty-printed and carry a "meta" section with
+// the request ID and timing, to read them by hand during development
+type DebugMode struct {
+	// Query lets a client ask for a debug response with ?debug=true
+	Query bool `mapstructure:"query" json:"query"`
+	// Always answers every request in debug mode
+	Always bool `mapstructure:"always" json:"always"`
+}
+
+// DebugMeta is the "meta" section added to debug responses
+type DebugMeta struct {
+	RequestID  string    `json:"requestID,omitempty"`
+	TraceID    string    `json:"traceID,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	DurationMs float64   `json:"durationMs"`
+}
+
+// DebugResponses rewrites the JSON responses of debug requests; other content, such as
+// CSV exports and feeds, is passed through. A nil mode leaves every response alone.
+func (m *DebugMode) DebugResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m == nil || !m.enabled(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		dw := &debugWriter{ResponseWriter: w, received: time.Now()}
+		next.ServeHTTP(dw, r)
+		dw.finish(r)
+	})
+}
+
+func (m *DebugMode) enabled(r *http.Request) bool {
+	if m.Always {
+		return true
+	}
+	if !m.Query {
+		return false
+	}
+	debug, _ := strconv.ParseBool(r.URL.Query().Get("debug"))
+	return debug
+}
+
+// debugWriter holds back JSON responses until the handler has finished
+type debugWriter struct {
+	http.ResponseWriter
+	received time.Time
+
+	wroteHeader bool
+	buffering   bool
+	status      int
+	body        bytes.Buffer
+}
+
+func (w *debugWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.buffering = true
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *debugWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes through for streaming responses, which are never buffered
+func (w *debugWriter) Flush() {
+	if w.buffering {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *debugWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish writes the held back response indented, with the meta section added to objects.
+// A body that is not valid JSON is written as it came.
+func (w *debugWriter) finish(r *http.Request) {
+	if !w.buffering {
+		return
+	}
+
+	body := w.body.Bytes()
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		fields["meta"], _ = json.Marshal(DebugMeta{
+			RequestID:  middleware.GetReqID(r.Context()),
+			TraceID:    r.Header.Get("traceparent"),
+			ReceivedAt: w.received,
+			DurationMs: float64(time.Since(w.received).Microseconds()) / 1000,
+		})
+		if encoded, err := json.Marshal(fields); err == nil {
+			body = encoded
+		}
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err == nil {
+		indented.WriteByte('\n')
+		body = indented.Bytes()
+	}
+
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
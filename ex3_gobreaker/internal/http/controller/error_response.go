@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"bytes"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/model"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// writeError responds with a model.ErrorResponse; it takes the same message and status as http.Error
+func writeError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	writeErrorDetails(w, r, message, status, nil)
+}
+
+// writeErrorDetails responds with a model.ErrorResponse carrying extra details, such as the invalid fields
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, message string, status int, details interface{}) {
+	writeErrorResponse(w, r, message, status, errorCode(status), details)
+}
+
+// writeAppError responds with the status and code apperrors gives err, and message. Errors that
+// go away with time, such as a blackout window, set Retry-After.
+func writeAppError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	status, code := apperrors.Classify(err)
+	if retryAfter := apperrors.RetryAfter(err, time.Now()); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	writeErrorResponse(w, r, message, status, code, nil)
+}
+
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, message string, status int, code string, details interface{}) {
+	failCrawl(r.Context(), message)
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(model.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+}
+
+// writeLookupError answers 404 when a record does not exist, and reports other lookup failures,
+// 500 for a database error
+func writeLookupError(w http.ResponseWriter, r *http.Request, err error, notFoundMessage string) {
+	if apperrors.IsNotFound(err) {
+		writeAppError(w, r, err, notFoundMessage)
+		return
+	}
+	writeAppError(w, r, err, "Error querying database")
+}
+
+// errorCode turns a status into its machine-readable code, e.g. 404 -> "not_found"
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return strings.ToLower(text)
+}
+
+// ErrorResponses rewrites plain-text error responses, from http.Error in handlers and middleware,
+// router 404/405s or recovered panics, into a model.ErrorResponse so clients get one error format
+func ErrorResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := &errorMappingWriter{ResponseWriter: w, request: r}
+		next.ServeHTTP(mw, r)
+		mw.finish()
+	})
+}
+
+// errorMappingWriter holds back error responses that are not JSON until the handler has finished
+type errorMappingWriter struct {
+	http.ResponseWriter
+	request *http.Request
+
+	wroteHeader bool
+	mapping     bool
+	status      int
+	body        bytes.Buffer
+}
+
+func (w *errorMappingWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	contentType := w.Header().Get("Content-Type")
+	if status >= http.StatusBadRequest && (contentType == "" || strings.HasPrefix(contentType, "text/plain")) {
+		w.mapping = true
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorMappingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.mapping {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes through for streaming responses such as exports
+func (w *errorMappingWriter) Flush() {
+	if w.mapping {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *errorMappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *errorMappingWriter) finish() {
+	if !w.mapping {
+		return
+	}
+
+	message := strings.TrimSpace(w.body.String())
+	if message == "" {
+		message = http.StatusText(w.status)
+	}
+	writeError(w.ResponseWriter, w.request, message, w.status)
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"crawler/baseline/internal/experiment"
+	"crawler/baseline/internal/model"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ExperimentController struct {
+	log             *logrus.Logger
+	batchExperiment *experiment.BatchExperiment
+}
+
+func NewExperimentController(log *logrus.Logger, batchExperiment *experiment.BatchExperiment) *ExperimentController {
+	return &ExperimentController{
+		log:             log,
+		batchExperiment: batchExperiment,
+	}
+}
+
+// RunBatchExperiment compares worker counts and batch sizes on a synthetic commit workload
+// and stores the recommended combination in the experiment config overlay. The request blocks
+// until all trials have finished.
+func (c *ExperimentController) RunBatchExperiment(w http.ResponseWriter, r *http.Request) {
+	request := &model.BatchExperimentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startTime := time.Now()
+	response, err := c.batchExperiment.Run(r.Context(), request)
+	switch {
+	case errors.Is(err, experiment.ErrExperimentRunning):
+		writeError(w, r, "An experiment is already running", http.StatusConflict)
+		return
+	case errors.Is(err, experiment.ErrInvalidExperiment):
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		c.log.WithError(err).Error("Batch experiment failed")
+		writeError(w, r, "Batch experiment failed", http.StatusInternalServerError)
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"trials":        len(response.Trials),
+		"total_time_ms": time.Since(startTime).Milliseconds(),
+	}).Info("Batch experiment request completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.BatchExperimentResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
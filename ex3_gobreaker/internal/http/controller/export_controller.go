@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// exportFlushEvery is how many rows are written between flushes of the chunked response
+const exportFlushEvery = 500
+
+type ExportController struct {
+	log *logrus.Logger
+	db  *gorm.DB
+}
+
+func NewExportController(log *logrus.Logger, db *gorm.DB) *ExportController {
+	return &ExportController{
+		log: log,
+		db:  db,
+	}
+}
+
+// exportRowWriter writes one row in the requested format
+type exportRowWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(row map[string]interface{}) error
+	Flush() error
+}
+
+type csvRowWriter struct {
+	writer  *csv.Writer
+	columns []string
+}
+
+func (w *csvRowWriter) WriteHeader(columns []string) error {
+	w.columns = columns
+	return w.writer.Write(columns)
+}
+
+func (w *csvRowWriter) WriteRow(row map[string]interface{}) error {
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		record[i] = formatExportValue(row[column])
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvRowWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonRowWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonRowWriter) WriteHeader(columns []string) error {
+	return nil
+}
+
+func (w *ndjsonRowWriter) WriteRow(row map[string]interface{}) error {
+	return w.encoder.Encode(row)
+}
+
+func (w *ndjsonRowWriter) Flush() error {
+	return nil
+}
+
+func formatExportValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Export streams a whole dataset as CSV or NDJSON.
+// Query parameters: format (csv or ndjson), repo (ID or owner/name), and from/to
+// (RFC 3339 or YYYY-MM-DD) filtering releases and commits by release publish date.
+// Releases tombstoned after they disappeared from GitHub, and their commits, are only
+// exported with include_deleted=true.
+func (c *ExportController) Export(w http.ResponseWriter, r *http.Request) {
+	dataset := chi.URLParam(r, "dataset")
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		writeError(w, r, "Invalid format, expected csv or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseExportDate(query.Get("from"))
+	if err != nil {
+		writeError(w, r, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	to, err := parseExportDate(query.Get("to"))
+	if err != nil {
+		writeError(w, r, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		writeError(w, r, "Invalid include_deleted, expected true or false", http.StatusBadRequest)
+		return
+	}
+
+	var tx *gorm.DB
+	switch dataset {
+	case "repos":
+		if from != nil || to != nil {
+			writeError(w, r, "Date filters apply to releases and commits only", http.StatusBadRequest)
+			return
+		}
+		tx = c.db.Model(&entity.Repository{}).Order("repositories.id")
+	case "releases":
+		tx = c.db.Model(&entity.Release{}).Order("releases.id")
+	case "commits":
+		tx = c.db.Model(&entity.Commit{}).
+			Select("commits.*").
+			Joins("JOIN releases ON releases.id = commits.releaseid").
+			Order("commits.id")
+	default:
+		writeError(w, r, "Unknown dataset, expected repos, releases or commits", http.StatusNotFound)
+		return
+	}
+
+	if repo := query.Get("repo"); repo != "" {
+		repoColumn := "releases.repoid"
+		if dataset == "repos" {
+			repoColumn = "repositories.id"
+		}
+
+		if id, err := strconv.ParseInt(repo, 10, 64); err == nil {
+			tx = tx.Where(repoColumn+" = ?", id)
+		} else if owner, name, ok := strings.Cut(repo, "/"); ok {
+			tx = tx.Where(repoColumn+" IN (?)", c.db.Model(&entity.Repository{}).Select("id").
+				Where("LOWER(username) = LOWER(?) AND LOWER(reponame) = LOWER(?)", owner, name))
+		} else {
+			writeError(w, r, "Invalid repo, expected an ID or owner/name", http.StatusBadRequest)
+			return
+		}
+	}
+	if dataset != "repos" && !withDeleted {
+		tx = tx.Scopes(repository.NotTombstoned)
+	}
+	if from != nil {
+		tx = tx.Where("releases.publishedat >= ?", *from)
+	}
+	if to != nil {
+		tx = tx.Where("releases.publishedat < ?", *to)
+	}
+
+	rows, err := tx.Rows()
+	if err != nil {
+		c.log.WithError(err).WithField("dataset", dataset).Error("Error querying export")
+		writeError(w, r, "Error exporting data", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		c.log.WithError(err).Error("Error reading export columns")
+		writeError(w, r, "Error exporting data", http.StatusInternalServerError)
+		return
+	}
+
+	var writer exportRowWriter
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		writer = &csvRowWriter{writer: csv.NewWriter(w)}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		writer = &ndjsonRowWriter{encoder: json.NewEncoder(w)}
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", dataset, format))
+	flusher, _ := w.(http.Flusher)
+
+	startTime := time.Now()
+	count := 0
+	if err := writer.WriteHeader(columns); err != nil {
+		return
+	}
+
+	// The status is already sent once rows are streamed, so errors can only be logged
+	for rows.Next() {
+		row := make(map[string]interface{}, len(columns))
+		if err := c.db.ScanRows(rows, &row); err != nil {
+			c.log.WithError(err).Error("Error scanning export row")
+			return
+		}
+		// Rows are scanned as stored, release contents may be compressed
+		if stored, ok := row["content"].(string); ok && dataset == "releases" {
+			content, err := entity.DecodeContent(stored)
+			if err != nil {
+				c.log.WithError(err).WithField("release_id", row["id"]).Error("Error reading release content")
+				return
+			}
+			row["content"] = content
+		}
+		if err := writer.WriteRow(row); err != nil {
+			c.log.WithError(err).Warn("Export client went away")
+			return
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			writer.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	writer.Flush()
+
+	if err := rows.Err(); err != nil {
+		c.log.WithError(err).Error("Error iterating export rows")
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"dataset":     dataset,
+		"format":      format,
+		"rows":        count,
+		"duration_ms": time.Since(startTime).Milliseconds(),
+	}).Info("Export completed")
+}
+
+func parseExportDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
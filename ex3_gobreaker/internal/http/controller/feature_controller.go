@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/queue"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Features switch the write and protection strategies compared by the experiments, so one build
+// runs the baseline, batch, queue and breaker setups
+type Features struct {
+	// Batching stores each scraped page of repositories, releases, commits or tags with one
+	// insert; off, every row is stored in its own transaction, as the baseline did. On by default
+	Batching bool `mapstructure:"batching" json:"batching"`
+	// Queueing stores the commits of the bulk crawls through the commit queue, in the
+	// background, as Exp 2 did. Off by default
+	Queueing bool `mapstructure:"queueing" json:"queueing"`
+	// CircuitBreaking protects the coordinator stages, and the repositories when
+	// crawl.repo_breaker is enabled, with circuit breakers. On by default
+	CircuitBreaking bool `mapstructure:"circuit_breaking" json:"circuit_breaking"`
+}
+
+// FeaturesResponse is the features of the running server, with its commit queue when queueing is on
+type FeaturesResponse struct {
+	Features
+	CommitQueue *queue.QueueSnapshot `json:"commitQueue,omitempty"`
+}
+
+type FeatureController struct {
+	log      *logrus.Logger
+	features Features
+	// commitQueue is nil when queueing is off
+	commitQueue *queue.CommitQueueProcessor
+}
+
+func NewFeatureController(log *logrus.Logger, features Features, commitQueue *queue.CommitQueueProcessor) *FeatureController {
+	return &FeatureController{
+		log:         log,
+		features:    features,
+		commitQueue: commitQueue,
+	}
+}
+
+// GetFeatures returns the features the server runs with and the state of its commit queue
+func (c *FeatureController) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	response := FeaturesResponse{Features: c.features}
+	if c.commitQueue != nil {
+		snapshot := c.commitQueue.Snapshot()
+		response.CommitQueue = &snapshot
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[FeaturesResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/feed"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultFeedLimit = 50
+	maxFeedLimit     = 500
+)
+
+type FeedController struct {
+	log         *logrus.Logger
+	feedUsecase *usecase.FeedUsecase
+}
+
+func NewFeedController(log *logrus.Logger, feedUsecase *usecase.FeedUsecase) *FeedController {
+	return &FeedController{
+		log:         log,
+		feedUsecase: feedUsecase,
+	}
+}
+
+// RepoFeed serves the latest releases of a repository as an Atom (default), RSS or iCal feed
+func (c *FeedController) RepoFeed(w http.ResponseWriter, r *http.Request) {
+	c.serveFeed(w, r, "repoID", c.feedUsecase.RepoFeed)
+}
+
+// WatchlistFeed serves the latest releases of a watchlist's repositories as an Atom (default), RSS or iCal feed
+func (c *FeedController) WatchlistFeed(w http.ResponseWriter, r *http.Request) {
+	c.serveFeed(w, r, "watchlistID", c.feedUsecase.WatchlistFeed)
+}
+
+func (c *FeedController) serveFeed(w http.ResponseWriter, r *http.Request, param string,
+	load func(ctx context.Context, id int64, limit int, includeDeleted bool) (*model.ReleaseFeed, error)) {
+	id, err := idParam(r, param)
+	if err != nil {
+		writeAppError(w, r, err, "Invalid ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = feed.FormatAtom
+	}
+	contentType := feed.ContentType(format)
+	if contentType == "" {
+		writeError(w, r, "Invalid format, expected atom, rss or ical", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultFeedLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxFeedLimit {
+			writeError(w, r, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	withDeleted, err := includeDeleted(r)
+	if err != nil {
+		writeError(w, r, "Invalid include_deleted, expected true or false", http.StatusBadRequest)
+		return
+	}
+
+	releaseFeed, err := load(r.Context(), id, limit, withDeleted)
+	if apperrors.IsNotFound(err) {
+		writeAppError(w, r, err, "Not found")
+		return
+	}
+	if err != nil {
+		c.log.WithError(err).WithField(param, id).Error("Error building feed")
+		writeAppError(w, r, err, "Error building feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if err := feed.Write(w, format, releaseFeed, selfURL(r)); err != nil {
+		c.log.WithError(err).Error("Error writing feed")
+	}
+}
+
+// includeDeleted reads the include_deleted parameter of list endpoints, which adds the
+// releases tombstoned after they disappeared from GitHub
+func includeDeleted(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("include_deleted")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// selfURL reconstructs the absolute URL a request was made to, honouring proxy headers
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
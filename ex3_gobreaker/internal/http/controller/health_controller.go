@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/queue"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReadyResponse is the readiness of the server and the watermark state of its queues
+type ReadyResponse struct {
+	Ready  bool               `json:"ready"`
+	Queues []queue.QueueState `json:"queues"`
+}
+
+type HealthController struct {
+	log *logrus.Logger
+	// backpressure is nil when queueing is off, and the server is then always ready
+	backpressure *queue.Backpressure
+}
+
+func NewHealthController(log *logrus.Logger, backpressure *queue.Backpressure) *HealthController {
+	return &HealthController{
+		log:          log,
+		backpressure: backpressure,
+	}
+}
+
+// Readyz reports the queue watermark state; it answers 503 while any queue is saturated
+func (c *HealthController) Readyz(w http.ResponseWriter, r *http.Request) {
+	saturated, states := c.backpressure.Saturated()
+
+	w.Header().Set("Content-Type", "application/json")
+	if saturated {
+		c.setRetryAfter(w)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(model.WebResponse[ReadyResponse]{
+		Data: ReadyResponse{Ready: !saturated, Queues: states},
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// Backpressure refuses crawl triggers with 503 and Retry-After while any queue is saturated,
+// so schedulers back off instead of piling more work onto full queues
+func (c *HealthController) Backpressure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		saturated, states := c.backpressure.Saturated()
+		if !saturated {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.log.WithFields(logrus.Fields{
+			"path":   r.URL.Path,
+			"queues": states,
+		}).Warn("Refusing crawl trigger, queues are saturated")
+
+		c.setRetryAfter(w)
+		writeError(w, r, "Crawl queues are saturated, retry later", http.StatusServiceUnavailable)
+	})
+}
+
+func (c *HealthController) setRetryAfter(w http.ResponseWriter) {
+	if retryAfter := c.backpressure.RetryAfter(); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"crawler/baseline/internal/budget"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type JobController struct {
+	log     *logrus.Logger
+	jobs    *service.JobManager
+	budgets *budget.Set
+}
+
+func NewJobController(log *logrus.Logger, jobs *service.JobManager, budgets *budget.Set) *JobController {
+	return &JobController{
+		log:     log,
+		jobs:    jobs,
+		budgets: budgets,
+	}
+}
+
+// ListJobs returns the tracked background jobs, most recent first
+func (c *JobController) ListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := c.jobs.List(r.Context())
+	if err != nil {
+		writeError(w, r, "Error listing jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.Job]{
+		Data: jobs,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// GetJob returns the status and progress of a background job
+func (c *JobController) GetJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := idParam(r, "jobID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid job ID")
+		return
+	}
+
+	job, err := c.jobs.Get(r.Context(), jobID)
+	if errors.Is(err, service.ErrJobNotFound) {
+		writeAppError(w, r, err, "Job not found")
+		return
+	}
+	if err != nil {
+		writeAppError(w, r, err, "Error fetching job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[service.Job]{
+		Data: job,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// ListWorkers returns the worker instances registered with the shared job queue
+// and whether each is still sending heartbeats
+func (c *JobController) ListWorkers(w http.ResponseWriter, r *http.Request) {
+	workers, err := c.jobs.Workers(r.Context())
+	if err != nil {
+		writeError(w, r, "Error listing workers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]service.WorkerStatus]{
+		Data: workers,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// ListBudgets returns the goroutines each crawl stage runs against its budget, with the heap
+// of the process
+func (c *JobController) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]budget.Usage]{
+		Data: c.budgets.Usages(),
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/queue"
+	"crawler/baseline/internal/scrape"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+type MetricsController struct {
+	log *logrus.Logger
+	// commitQueue is nil when queueing is off, and the stream then has no queues
+	commitQueue *queue.CommitQueueProcessor
+	// scrapeStats is nil when the requests are not counted
+	scrapeStats *scrape.RequestStats
+	interval    time.Duration
+}
+
+func NewMetricsController(log *logrus.Logger, commitQueue *queue.CommitQueueProcessor,
+	scrapeStats *scrape.RequestStats, interval time.Duration) *MetricsController {
+	return &MetricsController{
+		log:         log,
+		commitQueue: commitQueue,
+		scrapeStats: scrapeStats,
+		interval:    interval,
+	}
+}
+
+// StreamMetrics upgrades to a WebSocket and pushes queue sizes, processing counts and
+// enqueue, dequeue and scrape rates as a JSON message every interval until the client leaves
+func (c *MetricsController) StreamMetrics(w http.ResponseWriter, r *http.Request) {
+	// websocket.Server skips the Origin check of websocket.Handler, so non-browser clients can connect
+	server := websocket.Server{Handler: c.stream}
+	server.ServeHTTP(w, r)
+}
+
+func (c *MetricsController) stream(ws *websocket.Conn) {
+	defer ws.Close()
+
+	// Clients only listen; reading detects when they disconnect
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws)
+		close(closed)
+	}()
+
+	c.log.WithField("remote", ws.Request().RemoteAddr).Info("Live metrics client connected")
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	previous := c.collect(nil)
+	for {
+		if err := websocket.JSON.Send(ws, previous); err != nil {
+			c.log.WithError(err).Debug("Live metrics client gone")
+			return
+		}
+
+		select {
+		case <-closed:
+			c.log.WithField("remote", ws.Request().RemoteAddr).Info("Live metrics client disconnected")
+			return
+		case <-ticker.C:
+			previous = c.collect(previous)
+		}
+	}
+}
+
+// collect takes a metrics sample; rates are per second since the previous sample
+func (c *MetricsController) collect(previous *model.LiveMetrics) *model.LiveMetrics {
+	snapshots := make([]queue.QueueSnapshot, 0, 1)
+	if c.commitQueue != nil {
+		snapshots = append(snapshots, c.commitQueue.Snapshot())
+	}
+
+	metrics := &model.LiveMetrics{
+		Timestamp: time.Now(),
+		Queues:    make([]model.LiveQueueMetrics, len(snapshots)),
+	}
+	elapsed := 0.0
+	if previous != nil {
+		elapsed = metrics.Timestamp.Sub(previous.Timestamp).Seconds()
+	}
+
+	for i, snapshot := range snapshots {
+		queueMetrics := model.LiveQueueMetrics{
+			Name:           snapshot.Name,
+			Size:           snapshot.Size,
+			Processing:     snapshot.Processing,
+			Enqueued:       snapshot.Enqueued,
+			Dequeued:       snapshot.Dequeued,
+			MaxQueueLength: snapshot.MaxQueueLength,
+			Created:        snapshot.Created,
+			Skipped:        snapshot.Skipped,
+			Failed:         snapshot.Failed,
+			Dropped:        snapshot.Dropped,
+			Rejected:       snapshot.Rejected,
+			Deduped:        snapshot.Deduped,
+		}
+		if elapsed > 0 {
+			queueMetrics.EnqueueRate = float64(snapshot.Enqueued-previous.Queues[i].Enqueued) / elapsed
+			queueMetrics.DequeueRate = float64(snapshot.Dequeued-previous.Queues[i].Dequeued) / elapsed
+		}
+		metrics.Queues[i] = queueMetrics
+	}
+
+	if c.scrapeStats != nil {
+		requests, errors := c.scrapeStats.Requests()
+		metrics.Scrape = model.LiveScrapeMetrics{
+			Requests: requests,
+			Errors:   errors,
+		}
+	}
+	if elapsed > 0 {
+		metrics.Scrape.RequestRate = float64(metrics.Scrape.Requests-previous.Scrape.Requests) / elapsed
+		metrics.Scrape.ErrorRate = float64(metrics.Scrape.Errors-previous.Scrape.Errors) / elapsed
+	}
+
+	return metrics
+}
@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/utils"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxBulkOnboardRows  = 1000
+	maxBulkOnboardBytes = 10 << 20
+	// bulkOnboardJobRows is how many rows of an upload one onboarding job looks up and crawls
+	bulkOnboardJobRows = 50
+)
+
+// bulkOnboardJob is the payload of bulk onboarding jobs, the accepted rows of an upload
+type bulkOnboardJob struct {
+	Rows []bulkOnboardRow `json:"rows"`
+}
+
+type bulkOnboardRow struct {
+	Row    int    `json:"row"`
+	URL    string `json:"url"`
+	Policy string `json:"policy"`
+}
+
+// BulkOnboard onboards a list of repositories uploaded as CSV or JSON, either as a multipart
+// "file" field or as the request body. Rows are validated and deduplicated against the file
+// and the database without calling GitHub; the accepted ones are queued, bulkOnboardJobRows per
+// job, and the jobs check them on GitHub, create and crawl them.
+func (c *OnboardController) BulkOnboard(w http.ResponseWriter, r *http.Request) {
+	if err := c.jobs.Admit(time.Now()); err != nil {
+		writeBlackout(w, r, err)
+		return
+	}
+
+	data, format, err := readBulkUpload(r)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requests, err := parseBulkOnboard(data, format)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(requests) > maxBulkOnboardRows {
+		writeError(w, r, fmt.Sprintf("Too many rows, at most %d are accepted", maxBulkOnboardRows), http.StatusBadRequest)
+		return
+	}
+
+	response := &model.BulkOnboardResponse{
+		JobIDs: make([]int64, 0),
+		Rows:   make([]model.OnboardRowResult, len(requests)),
+	}
+	seen := make(map[string]int)
+	accepted := make([]int, 0, len(requests))
+	names := make([]entity.Repository, 0, len(requests))
+
+	for i, request := range requests {
+		row := &response.Rows[i]
+		*row = model.OnboardRowResult{Row: i + 1, URL: request.URL}
+		if request.Policy == "" {
+			requests[i].Policy = service.DefaultPolicyName
+		}
+
+		owner, name, err := utils.ParseRepoURL(request.URL)
+		switch {
+		case err != nil:
+			row.Result = model.OnboardResultInvalid
+			row.Message = err.Error()
+		case !c.knownPolicy(requests[i].Policy):
+			row.Result = model.OnboardResultInvalid
+			row.Message = "Unknown policy"
+		default:
+			key := strings.ToLower(owner + "/" + name)
+			if first, ok := seen[key]; ok {
+				row.Result = model.OnboardResultDuplicate
+				row.Message = fmt.Sprintf("Same repository as row %d", first)
+				break
+			}
+			seen[key] = row.Row
+			accepted = append(accepted, i)
+			names = append(names, entity.Repository{UserName: owner, RepoName: name})
+		}
+	}
+
+	// Rows of repositories already tracked are rejected here, those tracked meanwhile by the jobs
+	var stored []entity.Repository
+	if err := c.repoUsecase.RepoRepository.FindStored(r.Context(), c.repoUsecase.DB, names, &stored); err != nil {
+		c.log.WithError(err).Error("Error looking up uploaded repositories")
+		writeError(w, r, "Error looking up repositories", http.StatusInternalServerError)
+		return
+	}
+	tracked := make(map[string]int64, len(stored))
+	for _, repo := range stored {
+		tracked[strings.ToLower(repo.UserName+"/"+repo.RepoName)] = repo.ID
+	}
+	queued := make([]int, 0, len(accepted))
+	for j, i := range accepted {
+		if id, ok := tracked[strings.ToLower(names[j].UserName+"/"+names[j].RepoName)]; ok {
+			response.Rows[i].Result = model.OnboardResultDuplicate
+			response.Rows[i].Message = fmt.Sprintf("Repository already tracked with ID %d", id)
+			response.Rows[i].RepoID = id
+			continue
+		}
+		queued = append(queued, i)
+	}
+
+	for start := 0; start < len(queued); start += bulkOnboardJobRows {
+		chunk := queued[start:min(start+bulkOnboardJobRows, len(queued))]
+		job := bulkOnboardJob{Rows: make([]bulkOnboardRow, len(chunk))}
+		for j, i := range chunk {
+			job.Rows[j] = bulkOnboardRow{Row: i + 1, URL: requests[i].URL, Policy: requests[i].Policy}
+		}
+		submitted, err := c.jobs.Submit(r.Context(), JobBulkOnboard, job)
+		if err != nil {
+			c.log.WithError(err).Error("Error queueing bulk onboarding")
+			writeError(w, r, "Error queueing onboarding", http.StatusInternalServerError)
+			return
+		}
+		response.JobIDs = append(response.JobIDs, submitted.ID)
+		for _, i := range chunk {
+			response.Rows[i].Result = model.OnboardResultQueued
+			response.Rows[i].JobID = submitted.ID
+		}
+		response.Queued += len(chunk)
+	}
+	response.Rejected = len(requests) - response.Queued
+
+	c.log.WithFields(logrus.Fields{
+		"rows":     len(requests),
+		"queued":   response.Queued,
+		"rejected": response.Rejected,
+		"jobs":     len(response.JobIDs),
+	}).Info("Bulk onboarding queued")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.BulkOnboardResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// runBulkOnboardJob looks up, creates and crawls the rows of a bulk onboarding job one after
+// another. The rows rejected by GitHub or already tracked fail the job, with the other errors.
+func (c *OnboardController) runBulkOnboardJob(ctx context.Context, payload json.RawMessage, progress func(string, ...interface{})) error {
+	var job bulkOnboardJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("decoding bulk onboarding job: %w", err)
+	}
+	if len(job.Rows) == 0 {
+		// Queued before the rows were looked up in jobs, with the IDs of the created repositories
+		return c.runOnboardJob(ctx, payload, progress)
+	}
+
+	var errs []error
+	for i, row := range job.Rows {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		rowProgress := func(format string, args ...interface{}) {
+			progress("row %d (%d/%d, %s): %s", row.Row, i+1, len(job.Rows), row.URL, fmt.Sprintf(format, args...))
+		}
+		rowProgress("looking up")
+		repoEntity, err := c.createRepo(ctx, model.OnboardRequest{URL: row.URL, Policy: row.Policy})
+		if err != nil {
+			var onboardErr *apperrors.Error
+			if errors.As(err, &onboardErr) {
+				err = fmt.Errorf("%s: %s", onboardResult(onboardErr.Code), onboardErr.Message)
+			}
+			errs = append(errs, fmt.Errorf("row %d (%s): %w", row.Row, row.URL, err))
+			continue
+		}
+		if err := c.crawlRepo(ctx, repoEntity, c.policies[repoEntity.Policy], rowProgress); err != nil {
+			errs = append(errs, fmt.Errorf("row %d (%s/%s): %w", row.Row, repoEntity.UserName, repoEntity.RepoName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readBulkUpload returns the uploaded list and its format, "csv" or "json"
+func readBulkUpload(r *http.Request) ([]byte, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxBulkOnboardBytes); err != nil {
+			return nil, "", fmt.Errorf("invalid multipart upload: %w", err)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("missing \"file\" field: %w", err)
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxBulkOnboardBytes))
+		if err != nil {
+			return nil, "", err
+		}
+
+		format := "csv"
+		if strings.EqualFold(filepath.Ext(header.Filename), ".json") {
+			format = "json"
+		}
+		return data, format, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBulkOnboardBytes))
+	if err != nil {
+		return nil, "", err
+	}
+
+	format := "csv"
+	if mediaType == "application/json" {
+		format = "json"
+	}
+	return data, format, nil
+}
+
+// parseBulkOnboard reads onboarding requests from a JSON array of URLs or {"url", "policy"}
+// objects, or from CSV rows of "url[,policy]" with an optional header
+func parseBulkOnboard(data []byte, format string) ([]model.OnboardRequest, error) {
+	if format == "json" {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON list: %w", err)
+		}
+
+		requests := make([]model.OnboardRequest, 0, len(raw))
+		for i, item := range raw {
+			var request model.OnboardRequest
+			if err := json.Unmarshal(item, &request.URL); err != nil {
+				if err := json.Unmarshal(item, &request); err != nil {
+					return nil, fmt.Errorf("invalid entry %d: %w", i+1, err)
+				}
+			}
+			requests = append(requests, request)
+		}
+		return requests, nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	requests := make([]model.OnboardRequest, 0, len(records))
+	for i, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "url") {
+			continue
+		}
+
+		request := model.OnboardRequest{URL: strings.TrimSpace(record[0])}
+		if len(record) > 1 {
+			request.Policy = strings.TrimSpace(record[1])
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of the jobs that crawl onboarded repositories
+const (
+	JobOnboard     = "onboard"
+	JobBulkOnboard = "bulk_onboard"
+	// JobRecrawl re-crawls repositories picked by the decay scheduler
+	JobRecrawl = "recrawl"
+)
+
+// onboardJob is the payload of onboarding jobs, the repositories to crawl in order
+type onboardJob struct {
+	RepoIDs []int64 `json:"repoIDs"`
+}
+
+type OnboardController struct {
+	log            *logrus.Logger
+	repoUsecase    *usecase.RepoUsecase
+	releaseUsecase *usecase.ReleaseUsecase
+	commitUsecase  *usecase.CommitUsecase
+	tagUsecase     *usecase.TagUsecase
+	repoScrape     scrape.RepoSource
+	releaseScrape  scrape.ReleaseSource
+	commitScrape   scrape.CommitSource
+	tagScrape      scrape.TagSource
+	policies       map[string]service.CrawlPolicy
+	// repoPolicies bound what is crawled of large repositories, whatever their named policy
+	repoPolicies *usecase.RepoPolicyUsecase
+	jobs         *service.JobManager
+}
+
+func NewOnboardController(log *logrus.Logger,
+	repoUsecase *usecase.RepoUsecase, releaseUsecase *usecase.ReleaseUsecase,
+	commitUsecase *usecase.CommitUsecase, tagUsecase *usecase.TagUsecase,
+	repoScrape scrape.RepoSource, releaseScrape scrape.ReleaseSource,
+	commitScrape scrape.CommitSource, tagScrape scrape.TagSource,
+	policies map[string]service.CrawlPolicy, repoPolicies *usecase.RepoPolicyUsecase, jobs *service.JobManager,
+	runs *CrawlRunRecorder) *OnboardController {
+	c := &OnboardController{
+		log:            log,
+		repoUsecase:    repoUsecase,
+		releaseUsecase: releaseUsecase,
+		commitUsecase:  commitUsecase,
+		tagUsecase:     tagUsecase,
+		repoScrape:     repoScrape,
+		releaseScrape:  releaseScrape,
+		commitScrape:   commitScrape,
+		tagScrape:      tagScrape,
+		policies:       policies,
+		repoPolicies:   repoPolicies,
+		jobs:           jobs,
+	}
+	// Registered on every instance, the crawls may run on a separate worker
+	jobs.Handle(JobOnboard, runs.Job(JobOnboard, c.runOnboardJob))
+	jobs.Handle(JobBulkOnboard, runs.Job(JobBulkOnboard, c.runBulkOnboardJob))
+	jobs.Handle(JobRecrawl, runs.Job(JobRecrawl, c.runOnboardJob))
+	return c
+}
+
+// Recrawl starts a job crawling tracked repositories again, as selected by their policies
+func (c *OnboardController) Recrawl(ctx context.Context, repoIDs []int64) error {
+	_, err := c.jobs.Submit(ctx, JobRecrawl, onboardJob{RepoIDs: repoIDs})
+	return err
+}
+
+// Onboard validates a GitHub repository URL, tracks the repository with a crawl policy
+// and starts its initial crawl as a background job
+func (c *OnboardController) Onboard(w http.ResponseWriter, r *http.Request) {
+	var request model.OnboardRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Policy == "" {
+		request.Policy = service.DefaultPolicyName
+	}
+	// Refused up front so a repository is not tracked without its initial crawl
+	if err := c.jobs.Admit(time.Now()); err != nil {
+		writeBlackout(w, r, err)
+		return
+	}
+
+	repoEntity, err := c.createRepo(r.Context(), request)
+	if err != nil {
+		var onboardErr *apperrors.Error
+		if errors.As(err, &onboardErr) {
+			writeAppError(w, r, err, onboardErr.Message)
+			return
+		}
+		c.log.WithError(err).WithField("url", request.URL).Error("Error onboarding repository")
+		writeError(w, r, "Error onboarding repository", http.StatusInternalServerError)
+		return
+	}
+	c.log.WithFields(logrus.Fields{
+		"repo":   fmt.Sprintf("%s/%s", repoEntity.UserName, repoEntity.RepoName),
+		"policy": request.Policy,
+	}).Info("Repository onboarded, starting initial crawl")
+
+	job, err := c.jobs.Submit(r.Context(), JobOnboard, onboardJob{RepoIDs: []int64{repoEntity.ID}})
+	if err != nil {
+		c.log.WithError(err).WithField("repo_id", repoEntity.ID).Error("Error starting initial crawl")
+		writeError(w, r, "Error starting initial crawl", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.OnboardResponse]{
+		Data: &model.OnboardResponse{
+			JobID:  job.ID,
+			Policy: request.Policy,
+			Repo:   usecase.RepoToResponse(repoEntity),
+		},
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// onboardResults are the results of the onboarding rejections, by error code
+var onboardResults = map[string]string{
+	apperrors.CodeBadRequest:     model.OnboardResultInvalid,
+	apperrors.CodeGitHubNotFound: model.OnboardResultNotFound,
+	apperrors.CodeGitHubPrivate:  model.OnboardResultPrivate,
+	apperrors.CodeConflict:       model.OnboardResultDuplicate,
+}
+
+// onboardResult is the result of an onboarding rejected with code
+func onboardResult(code string) string {
+	if result, ok := onboardResults[code]; ok {
+		return result
+	}
+	return model.OnboardResultFailed
+}
+
+func (c *OnboardController) knownPolicy(name string) bool {
+	_, ok := c.policies[name]
+	return ok
+}
+
+// createRepo validates an onboarding request against GitHub and stores the repository. A rejected
+// request fails with an *apperrors.Error carrying the message for the user.
+func (c *OnboardController) createRepo(ctx context.Context, request model.OnboardRequest) (*entity.Repository, error) {
+	owner, name, err := utils.ParseRepoURL(request.URL)
+	if err != nil {
+		return nil, apperrors.New(fmt.Errorf("%w: %w", apperrors.ErrInvalid, err), err.Error())
+	}
+
+	if !c.knownPolicy(request.Policy) {
+		return nil, apperrors.New(apperrors.ErrInvalid, "Unknown policy")
+	}
+
+	githubRepo, err := c.repoScrape.LookupRepo(owner, name)
+	switch {
+	case errors.Is(err, scrape.ErrRepoNotFound):
+		return nil, apperrors.New(err, "Repository not found or not accessible")
+	case errors.Is(err, scrape.ErrRepoPrivate):
+		return nil, apperrors.New(err, "Repository is private")
+	case err != nil:
+		c.log.WithError(err).WithField("url", request.URL).Error("Error validating repository")
+		return nil, &apperrors.Error{Status: http.StatusBadGateway, Code: apperrors.CodeBadGateway,
+			Message: "Error validating repository", Err: err}
+	}
+
+	repoEntity, err := c.repoUsecase.Onboard(ctx, githubRepo, request.Policy)
+	if errors.Is(err, apperrors.ErrConflict) {
+		return repoEntity, apperrors.New(err, fmt.Sprintf("Repository already tracked with ID %d", repoEntity.ID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return repoEntity, nil
+}
+
+// runOnboardJob crawls the repositories of an onboarding job one after another
+func (c *OnboardController) runOnboardJob(ctx context.Context, payload json.RawMessage, progress func(string, ...interface{})) error {
+	var job onboardJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("decoding onboarding job: %w", err)
+	}
+
+	var errs []error
+	for i, repoID := range job.RepoIDs {
+		// The job is cancelled when the server stops
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		repoEntity := new(entity.Repository)
+		if err := c.repoUsecase.RepoRepository.FindById(ctx, c.repoUsecase.DB, repoEntity, repoID); err != nil {
+			errs = append(errs, fmt.Errorf("repository %d: %w", repoID, err))
+			continue
+		}
+
+		if len(job.RepoIDs) == 1 {
+			return c.crawlRepo(ctx, repoEntity, c.policies[repoEntity.Policy], progress)
+		}
+		repoProgress := func(format string, args ...interface{}) {
+			progress("repository %d/%d (%s/%s): %s", i+1, len(job.RepoIDs),
+				repoEntity.UserName, repoEntity.RepoName, fmt.Sprintf(format, args...))
+		}
+		if err := c.crawlRepo(ctx, repoEntity, c.policies[repoEntity.Policy], repoProgress); err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", repoEntity.UserName, repoEntity.RepoName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// crawlRepo runs the initial crawl of a single repository as selected by its policy. The scrapes
+// call the GitHub API with the token of the lookup, github.token; once ctx is cancelled no page
+// of commits is fetched and no other release is crawled.
+func (c *OnboardController) crawlRepo(ctx context.Context, repoEntity *entity.Repository,
+	policy service.CrawlPolicy, progress func(string, ...interface{})) error {
+	owner, name := repoEntity.UserName, repoEntity.RepoName
+
+	if policy.Tags {
+		progress("crawling tags")
+		tags, err := c.tagScrape.CrawlTags(owner, name)
+		if err != nil {
+			c.log.WithError(err).Warn("Error scraping tags")
+		}
+		for _, tag := range tags {
+			tag.RepoID = repoEntity.ID
+		}
+		if _, err := c.tagUsecase.BatchCreate(ctx, tags); err != nil {
+			countCrawl(ctx, len(tags), 0, len(tags))
+			return fmt.Errorf("saving tags: %w", err)
+		}
+		countCrawl(ctx, len(tags), len(tags), 0)
+	}
+
+	if !policy.Releases {
+		progress("done")
+		return nil
+	}
+
+	repoPolicy := c.repoPolicies.For(ctx, repoEntity)
+	progress("crawling releases")
+	releases, err := c.releaseScrape.CrawlReleases(ctx, owner, name, repoLimits(repoPolicy))
+	// The releases listed before a failed page of the release list are still saved, but the
+	// job fails once they are, so it can be retried for the rest
+	var listErr error
+	if errors.Is(err, scrape.ErrIncompleteListing) {
+		listErr, err = err, nil
+	}
+	recordRepoCrawl(ctx, c.repoUsecase.DB, c.log, repoEntity, err)
+	if err != nil {
+		return fmt.Errorf("crawling releases: %w", err)
+	}
+	liveTags := make([]string, 0, len(releases))
+	releaseRequests := make([]*model.CreateReleaseRequest, 0, len(releases))
+	for tag, data := range releases {
+		liveTags = append(liveTags, tag)
+		releaseRequests = append(releaseRequests, &model.CreateReleaseRequest{
+			TagName:     tag,
+			Content:     data.Content,
+			Title:       data.Title,
+			PublishedAt: data.PublishedAt,
+			Author:      data.Author,
+			Prerelease:  data.Prerelease,
+			Assets:      data.Assets,
+			RepoID:      repoEntity.ID,
+		})
+	}
+
+	// A listing cut at the policy's max releases, or by a failed page, does not show which tags
+	// were deleted
+	if len(liveTags) > 0 && listErr == nil && repoPolicy.MaxReleases == 0 {
+		if _, _, err := c.releaseUsecase.SyncTombstones(ctx, repoEntity.ID, liveTags); err != nil {
+			return fmt.Errorf("tombstoning releases: %w", err)
+		}
+	}
+
+	releaseResponses, err := c.releaseUsecase.BatchCreate(ctx, releaseRequests)
+	if err != nil {
+		countCrawl(ctx, len(releaseRequests), 0, len(releaseRequests))
+		return fmt.Errorf("saving releases: %w", err)
+	}
+	countCrawl(ctx, len(releaseRequests), len(releaseResponses), 0)
+
+	if policy.Commits && !repoPolicy.SkipCommits {
+		for i, release := range releaseResponses {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("crawling commits: %w", err)
+			}
+			progress("crawling commits of release %d/%d", i+1, len(releaseResponses))
+
+			saved := 0
+			var saveErr error
+			found, err := streamCommits(ctx, c.log, c.commitScrape, repoEntity, repoLimits(repoPolicy), release.ID, release.TagName,
+				repoEntity.DefaultBranch, func(requests []*model.CreateCommitRequest) error {
+					if _, err := c.commitUsecase.BatchCreate(ctx, requests); err != nil {
+						saveErr = err
+						return err
+					}
+					saved += len(requests)
+					return nil
+				})
+			if saveErr != nil {
+				saveErr = fmt.Errorf("saving commits of release %s: %w", release.TagName, saveErr)
+				// The release stays in crawl_errors, so its commits are retried
+				recordReleaseCrawl(ctx, c.repoUsecase.DB, c.log, repoEntity,
+					&entity.Release{ID: release.ID, TagName: release.TagName}, saveErr)
+				countCrawl(ctx, found, saved, found-saved)
+				return saveErr
+			}
+			recordReleaseCrawl(ctx, c.repoUsecase.DB, c.log, repoEntity,
+				&entity.Release{ID: release.ID, TagName: release.TagName}, err)
+			countCrawl(ctx, found, saved, 0)
+			if err != nil {
+				if errors.Is(err, scrape.ErrNotFound) {
+					continue
+				}
+				return fmt.Errorf("crawling commits of release %s: %w", release.TagName, err)
+			}
+		}
+	}
+
+	if listErr != nil {
+		return fmt.Errorf("crawling releases: %w", listErr)
+	}
+	progress("done")
+	return nil
+}
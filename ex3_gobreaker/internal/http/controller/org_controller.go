@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// orgPattern matches GitHub organization names: alphanumeric with single inner hyphens, at
+// most 39 characters
+var orgPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+
+type OrgController struct {
+	log         *logrus.Logger
+	repoUsecase *usecase.RepoUsecase
+	orgScrape   *scrape.OrgScrape
+}
+
+func NewOrgController(log *logrus.Logger, repoUsecase *usecase.RepoUsecase, orgScrape *scrape.OrgScrape) *OrgController {
+	return &OrgController{
+		log:         log,
+		repoUsecase: repoUsecase,
+		orgScrape:   orgScrape,
+	}
+}
+
+// CrawlOrgRepos lists every repository of a GitHub organization and saves the ones not
+// tracked yet; the response marks the others existing
+func (c *OrgController) CrawlOrgRepos(w http.ResponseWriter, r *http.Request) {
+	org := chi.URLParam(r, "org")
+	if !orgPattern.MatchString(org) {
+		writeError(w, r, "Invalid organization", http.StatusBadRequest)
+		return
+	}
+
+	startTime := time.Now()
+	c.log.WithFields(logrus.Fields{
+		"org":   org,
+		"phase": "start",
+	}).Info("Starting organization crawling operation")
+
+	repos, err := c.orgScrape.CrawlOrgRepos(org)
+	if err != nil {
+		c.log.WithError(err).WithField("org", org).Error("Error crawling organization repositories")
+		writeScrapeError(w, r, err, "Failed to crawl organization repositories")
+		return
+	}
+	scrapeTime := time.Since(startTime)
+
+	responseData, err := c.repoUsecase.BatchCreate(r.Context(), repos)
+	if err != nil {
+		c.log.WithError(err).WithField("org", org).Error("Failed to create repositories")
+		writeError(w, r, "Failed to save repositories", http.StatusInternalServerError)
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"org":            org,
+		"scrape_time_ms": scrapeTime.Milliseconds(),
+		"total_time_ms":  time.Since(startTime).Milliseconds(),
+		"repos_found":    len(repos),
+		"success_count":  len(responseData),
+		"phase":          "operation_complete",
+	}).Info("Organization crawling operation completed")
+	countCrawl(r.Context(), len(repos), len(responseData), 0)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.RepoResponse]{
+		Data: responseData,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"crawler/baseline/internal/apperrors"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var errNotPositive = errors.New("must be positive")
+
+// paramError is a URL or query parameter that is not a valid ID or count
+type paramError struct {
+	name  string
+	value string
+	err   error
+}
+
+func (e *paramError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %v", e.name, e.value, e.err)
+}
+
+// Unwrap matches apperrors.ErrInvalid, so a bad ID is reported as 400, as well as the parse error
+func (e *paramError) Unwrap() []error {
+	return []error{apperrors.ErrInvalid, e.err}
+}
+
+// idParam reads the URL parameter name as an ID, which is a positive int64 like every ID column
+func idParam(r *http.Request, name string) (int64, error) {
+	value := chi.URLParam(r, name)
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err == nil && id <= 0 {
+		err = errNotPositive
+	}
+	if err != nil {
+		return 0, &paramError{name: name, value: value, err: err}
+	}
+	return id, nil
+}
+
+// countQuery reads the query parameter name as a positive count, or returns fallback when the
+// request does not set it
+func countQuery(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err == nil && count <= 0 {
+		err = errNotPositive
+	}
+	if err != nil {
+		return 0, &paramError{name: name, value: value, err: err}
+	}
+	return count, nil
+}
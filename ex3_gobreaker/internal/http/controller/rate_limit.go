@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"crawler/baseline/internal/auth"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimit is a token bucket: a client may send Burst requests at once,
+// then RequestsPerMinute spread over each minute
+type RateLimit struct {
+	RequestsPerMinute float64 `mapstructure:"requests_per_minute" json:"requests_per_minute"`
+	Burst             int     `mapstructure:"burst" json:"burst"`
+}
+
+// RateLimiter keeps one token bucket per client, identified by its API key name when
+// authentication is enabled and by its IP address otherwise
+type RateLimiter struct {
+	log   *logrus.Logger
+	name  string
+	limit RateLimit
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewRateLimiter(log *logrus.Logger, name string, limit RateLimit) (*RateLimiter, error) {
+	if limit.RequestsPerMinute <= 0 {
+		return nil, fmt.Errorf("rate limit %s needs a positive requests_per_minute", name)
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+
+	return &RateLimiter{
+		log:     log,
+		name:    name,
+		limit:   limit,
+		buckets: make(map[string]*tokenBucket),
+		swept:   time.Now(),
+	}, nil
+}
+
+// Limit answers 429 with Retry-After once a client has used up its bucket.
+// A nil limiter lets every request through.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := rateLimitClient(r)
+		wait, limit := l.take(client, time.Now())
+		if wait > 0 {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			l.log.WithFields(logrus.Fields{
+				"limit":       l.name,
+				"client":      client,
+				"path":        r.URL.Path,
+				"retry_after": retryAfter,
+			}).Warn("Rate limit exceeded")
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeErrorDetails(w, r, "Too many requests", http.StatusTooManyRequests, map[string]interface{}{
+				"limit":             l.name,
+				"requestsPerMinute": limit.RequestsPerMinute,
+				"burst":             limit.Burst,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Take removes a token from the bucket of client, as Limit does for a request, and returns
+// zero, or how long until one is available. A nil limiter lets every call through.
+func (l *RateLimiter) Take(client string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	wait, _ := l.take(client, time.Now())
+	return wait
+}
+
+// SetLimit changes the rate and burst of every client, keeping the tokens left in their buckets
+// up to the new burst. A limit without a positive rate is refused.
+func (l *RateLimiter) SetLimit(limit RateLimit) error {
+	if limit.RequestsPerMinute <= 0 {
+		return fmt.Errorf("rate limit %s needs a positive requests_per_minute", l.name)
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if limit == l.limit {
+		return nil
+	}
+	l.limit = limit
+	l.log.WithFields(logrus.Fields{
+		"limit":             l.name,
+		"requestsPerMinute": limit.RequestsPerMinute,
+		"burst":             limit.Burst,
+	}).Info("Rate limit changed")
+	return nil
+}
+
+// take removes a token from the client's bucket, or returns how long until one is available,
+// along with the limit it applied
+func (l *RateLimiter) take(client string, now time.Time) (time.Duration, RateLimit) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	perSecond := l.limit.RequestsPerMinute / 60
+	burst := float64(l.limit.Burst)
+	l.sweep(now, perSecond, burst)
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, updated: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, l.limit
+	}
+	return time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second)), l.limit
+}
+
+// sweep drops, once a minute, the buckets that have refilled, as a new bucket would be the same;
+// the caller must hold the mutex
+func (l *RateLimiter) sweep(now time.Time, perSecond float64, burst float64) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond >= burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+func rateLimitClient(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return "key:" + principal.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
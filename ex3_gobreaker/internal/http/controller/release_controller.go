@@ -1,18 +1,23 @@
 package controller
 
 import (
+	"context"
+	"crawler/baseline/internal/budget"
+	"crawler/baseline/internal/cache"
 	"crawler/baseline/internal/entity"
 	"crawler/baseline/internal/model"
 	"crawler/baseline/internal/repository"
 	"crawler/baseline/internal/scrape"
 	"crawler/baseline/internal/usecase"
+	"crawler/baseline/internal/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -21,180 +26,209 @@ type ReleaseController struct {
 	log            *logrus.Logger
 	db             *gorm.DB
 	releaseUsecase *usecase.ReleaseUsecase
-	releaseScrape  *scrape.ReleaseScrape
+	releaseScrape  scrape.ReleaseSource
+	// repoPolicies bound the releases crawled for large repositories
+	repoPolicies *usecase.RepoPolicyUsecase
+	// repoConcurrency is how many repositories CrawlReleases crawls at once
+	repoConcurrency atomic.Int64
+	// budget bounds the goroutines of the releases stage, across crawls
+	budget *budget.Budget
+	// responses caches GetRelease by release ID; nil caches nothing
+	responses *cache.LRU[int64, *model.ReleaseResponse]
+	// repoBreakers reject the scrapes of repositories failing on every call; nil rejects nothing
+	repoBreakers *utils.BreakerGroup
 }
 
-func NewReleaseController(log *logrus.Logger, db *gorm.DB,
-	releaseUsecase *usecase.ReleaseUsecase, releaseScrape *scrape.ReleaseScrape) *ReleaseController {
-	return &ReleaseController{
+func NewReleaseController(log *logrus.Logger, db *gorm.DB, releaseUsecase *usecase.ReleaseUsecase,
+	releaseScrape scrape.ReleaseSource, repoPolicies *usecase.RepoPolicyUsecase, repoConcurrency int,
+	budget *budget.Budget, responses *cache.LRU[int64, *model.ReleaseResponse],
+	repoBreakers *utils.BreakerGroup) *ReleaseController {
+	c := &ReleaseController{
 		log:            log,
 		db:             db,
 		releaseUsecase: releaseUsecase,
 		releaseScrape:  releaseScrape,
+		repoPolicies:   repoPolicies,
+		budget:         budget,
+		responses:      responses,
+		repoBreakers:   repoBreakers,
 	}
+	c.repoConcurrency.Store(int64(repoConcurrency))
+	return c
+}
+
+// SetRepoConcurrency changes how many repositories CrawlReleases crawls at once, from the next crawl on
+func (c *ReleaseController) SetRepoConcurrency(concurrency int) {
+	c.repoConcurrency.Store(int64(max(1, concurrency)))
 }
 
 func (c *ReleaseController) GetRelease(w http.ResponseWriter, r *http.Request) {
 	// Extract releaseID from URL parameters
-	releaseID, err := strconv.Atoi(chi.URLParam(r, "releaseID"))
+	releaseID, err := idParam(r, "releaseID")
 	if err != nil {
 		c.log.WithError(err).Error("Invalid release ID format")
-		http.Error(w, "Invalid release ID", http.StatusBadRequest)
+		writeAppError(w, r, err, "Invalid release ID")
 		return
 	}
 
 	c.log.WithField("release_id", releaseID).Info("Fetching release")
 
-	// Create release repository instance
-	releaseRepository := repository.NewReleaseRepository(c.log)
+	releaseResponse, cached := c.responses.Get(releaseID)
+	if !cached {
+		// Create release repository instance
+		releaseRepository := repository.NewReleaseRepository(c.log)
 
-	// Find release by ID
-	releaseEntity := &entity.Release{}
-	err = releaseRepository.FindById(c.db, releaseEntity, releaseID)
+		// Find release by ID
+		releaseEntity := &entity.Release{}
+		err = releaseRepository.FindById(r.Context(), c.db.Preload("Assets"), releaseEntity, releaseID)
 
-	if err != nil {
-		c.log.WithError(err).WithField("release_id", releaseID).Error("Release not found")
-		http.Error(w, "Release not found", http.StatusNotFound)
-		return
-	}
+		if err != nil {
+			c.log.WithError(err).WithField("release_id", releaseID).Error("Release not found")
+			writeLookupError(w, r, err, "Release not found")
+			return
+		}
 
-	// Convert entity to response model
-	releaseResponse := &model.ReleaseResponse{
-		ID:      releaseEntity.ID,
-		TagName: releaseEntity.TagName,
-		Content: releaseEntity.Content,
-		RepoID:  releaseEntity.RepoID,
+		// Convert entity to response model
+		releaseResponse = usecase.ReleaseToResponse(releaseEntity)
+		c.responses.Add(releaseID, releaseResponse)
 	}
 
 	// Send JSON response
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(releaseResponse); err != nil {
 		c.log.WithError(err).Error("Error encoding response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
 		return
 	}
 }
 
+// CrawlAllReleases saves the releases of the stored repositories; repo_limit crawls the first
+// repositories only and max_releases_per_repo keeps the newest releases of each
 func (c *ReleaseController) CrawlAllReleases(w http.ResponseWriter, r *http.Request) {
+	repoLimit, err := countQuery(r, "repo_limit", 0)
+	if err != nil {
+		writeAppError(w, r, err, "Invalid repo_limit")
+		return
+	}
+	maxReleases, err := countQuery(r, "max_releases_per_repo", 0)
+	if err != nil {
+		writeAppError(w, r, err, "Invalid max_releases_per_repo")
+		return
+	}
+
+	releaseResponses, err := c.CrawlReleases(r.Context(), repoLimit, maxReleases)
+	if err != nil {
+		writeError(w, r, "Error fetching repositories", http.StatusInternalServerError)
+		return
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.ReleaseResponse]{
+		Data: releaseResponses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// CrawlReleases scrapes and saves the releases of every repository, repoConcurrency repositories
+// at a time; a repository whose releases fail to save is logged and skipped. Once ctx is cancelled
+// no more repositories are started. A positive repoLimit crawls that many repositories, by ID, and
+// a positive maxReleasesPerRepo narrows the max releases of each repository's policy. It also
+// runs the in-process releases stage.
+func (c *ReleaseController) CrawlReleases(ctx context.Context, repoLimit int, maxReleasesPerRepo int) ([]*model.ReleaseResponse, error) {
 	// Create operation timer
 	startTime := time.Now()
 	c.log.WithField("phase", "start").Info("Starting release crawling operation")
 
-	// Metrics tracking
-	successCount := 0
-	errorCount := 0
-	repoCount := 0
-	releaseCount := 0
-
 	// Get all repositories
 	repoFetchStartTime := time.Now()
 	c.log.WithField("phase", "fetching_repositories").Info("Fetching repositories from database")
 
+	// Missing repositories and blocked ones still waiting to be retried are left out
 	repoEntities := []entity.Repository{}
 	repoRepository := repository.NewRepoRepository(c.log)
-	err := repoRepository.FindAll(c.db, &repoEntities)
+	db := c.db.WithContext(ctx)
+	if repoLimit > 0 {
+		db = db.Order("id").Limit(repoLimit)
+	}
+	err := repoRepository.FindCrawlable(ctx, db, &repoEntities, time.Now())
 	if err != nil {
 		c.log.WithError(err).Error("Error fetching repositories")
-		http.Error(w, "Error fetching repositories", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("fetching repositories: %w", err)
 	}
 
 	// Track repository fetch time
 	repoFetchTime := time.Since(repoFetchStartTime)
-	repoCount = len(repoEntities)
+	repoCount := len(repoEntities)
+	workers := max(1, min(int(c.repoConcurrency.Load()), repoCount))
 	c.log.WithFields(logrus.Fields{
 		"repo_count":  repoCount,
+		"workers":     workers,
 		"duration_ms": repoFetchTime.Milliseconds(),
 		"phase":       "repositories_loaded",
 	}).Info("Repositories loaded from database")
 
-	// Create response slice
+	// Totals of the workers, guarded by mutex
+	var mutex sync.Mutex
 	releaseResponses := make([]*model.ReleaseResponse, 0)
-	totalScrapeTime := time.Duration(0)
-	totalDbTime := time.Duration(0)
-
-	// Process each repository
-	for i, repo := range repoEntities {
-		repoStartTime := time.Now()
-		repoOwner := repo.UserName
-		repoName := repo.RepoName
-		repoID := repo.ID
-
-		// Log repository processing start
-		c.log.WithFields(logrus.Fields{
-			"progress": fmt.Sprintf("%d/%d", i+1, repoCount),
-			"owner":    repoOwner,
-			"name":     repoName,
-			"id":       repoID,
-			"phase":    "repo_processing_start",
-		}).Info("Processing repository")
-
-		// Scrape releases (measure scraping time)
-		scrapeStartTime := time.Now()
-		releases := c.releaseScrape.CrawlReleases(repoOwner, repoName)
-		scrapeTime := time.Since(scrapeStartTime)
-		totalScrapeTime += scrapeTime
-
-		// Log scraping results
-		releaseFoundCount := len(releases)
-		releaseCount += releaseFoundCount
-		c.log.WithFields(logrus.Fields{
-			"owner":          repoOwner,
-			"name":           repoName,
-			"releases_found": releaseFoundCount,
-			"scrape_time_ms": scrapeTime.Milliseconds(),
-			"phase":          "repo_scraping_complete",
-		}).Info("Repository releases scraped")
-
-		// Skip if no releases were found
-		if len(releases) == 0 {
-			continue
-		}
-
-		// Save releases to database using batch insert
-		dbStartTime := time.Now()
-
-		// Prepare the release requests as a batch
-		releaseRequests := make([]*model.CreateReleaseRequest, 0, len(releases))
-		for tag, content := range releases {
-			releaseRequests = append(releaseRequests, &model.CreateReleaseRequest{
-				TagName: tag,
-				Content: content,
-				RepoID:  repoID,
-			})
-		}
+	var total repoReleases
+	completed := 0
+	recorder := utils.NewPhaseRecorder()
 
-		// Batch create all releases for this repository
-		batchResponses, err := c.releaseUsecase.BatchCreate(r.Context(), releaseRequests)
+	// The collector's parallelism still bounds the requests sent to GitHub across workers. A
+	// worker refused by the stage budget fails the crawl before any repository is started.
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var budgetErr error
+	for w := 0; w < workers; w++ {
+		done, err := c.budget.Spawn()
 		if err != nil {
-			c.log.WithFields(logrus.Fields{
-				"repo":  repoName,
-				"error": err.Error(),
-			}).Error("Failed to batch save releases")
-			errorCount += len(releaseRequests)
-			continue
+			budgetErr = err
+			break
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer done()
+			for i := range indexes {
+				result := c.crawlRepoReleases(ctx, &repoEntities[i], maxReleasesPerRepo, recorder)
 
-		// Add successful responses to the main response list
-		releaseResponses = append(releaseResponses, batchResponses...)
-		successCount += len(batchResponses)
+				mutex.Lock()
+				releaseResponses = append(releaseResponses, result.responses...)
+				total.add(result)
+				completed++
+				progress := completed
+				mutex.Unlock()
 
-		// Calculate database time
-		dbTime := time.Since(dbStartTime)
-		totalDbTime += dbTime
-		repoTotalTime := time.Since(repoStartTime)
+				c.log.WithFields(logrus.Fields{
+					"progress": fmt.Sprintf("%d/%d", progress, repoCount),
+					"owner":    repoEntities[i].UserName,
+					"name":     repoEntities[i].RepoName,
+					"phase":    "repo_done",
+				}).Info("Repository done")
+			}
+		}()
+	}
 
-		// Log repository complete
-		c.log.WithFields(logrus.Fields{
-			"owner":          repoOwner,
-			"name":           repoName,
-			"scrape_time_ms": scrapeTime.Milliseconds(),
-			"db_time_ms":     dbTime.Milliseconds(),
-			"total_time_ms":  repoTotalTime.Milliseconds(),
-			"success_count":  len(batchResponses),
-			"phase":          "repo_processing_complete",
-		}).Info("Repository processing completed")
+dispatch:
+	for i := range repoEntities {
+		if budgetErr != nil {
+			break
+		}
+		// Heap grown past the budget stops the crawl between repositories
+		if budgetErr = c.budget.Check(); budgetErr != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
 	}
+	close(indexes)
+	wg.Wait()
 
 	// Calculate total times
 	totalTime := time.Since(startTime)
@@ -202,21 +236,187 @@ func (c *ReleaseController) CrawlAllReleases(w http.ResponseWriter, r *http.Requ
 	// Log completion
 	c.log.WithFields(logrus.Fields{
 		"total_time_ms":        totalTime.Milliseconds(),
-		"total_scrape_time_ms": totalScrapeTime.Milliseconds(),
-		"total_db_time_ms":     totalDbTime.Milliseconds(),
-		"repos_processed":      repoCount,
-		"releases_total":       releaseCount,
-		"success_count":        successCount,
-		"error_count":          errorCount,
+		"total_scrape_time_ms": total.scrapeTime.Milliseconds(),
+		"total_db_time_ms":     total.dbTime.Milliseconds(),
+		"repos_processed":      completed,
+		"repos_total":          repoCount,
+		"workers":              workers,
+		"releases_total":       total.found,
+		"success_count":        total.saved,
+		"error_count":          total.errors,
 		"phase":                "operation_complete",
-	}).Info("Release crawling operation completed")
+	}).WithFields(recorder.Fields()).Info("Release crawling operation completed")
+	countCrawl(ctx, total.found, total.saved, total.errors)
 
-	// Send response
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.ReleaseResponse]{
-		Data: releaseResponses,
-	}); err != nil {
-		c.log.WithError(err).Error("Error encoding response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+	if budgetErr != nil {
+		c.log.WithError(budgetErr).Error("Release crawl refused by its budget")
+		return releaseResponses, fmt.Errorf("release crawl stopped after %d of %d repositories: %w", completed, repoCount, budgetErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return releaseResponses, fmt.Errorf("release crawl stopped after %d of %d repositories: %w", completed, repoCount, err)
+	}
+	return releaseResponses, nil
+}
+
+// CrawlRepo scrapes and saves the releases of one stored repository, as CrawlReleases does for
+// each, counting the releases that fail in the crawl run of ctx
+func (c *ReleaseController) CrawlRepo(ctx context.Context, repoID int64) ([]*model.ReleaseResponse, error) {
+	repo := &entity.Repository{}
+	if err := repository.NewRepoRepository(c.log).FindById(ctx, c.db, repo, repoID); err != nil {
+		return nil, fmt.Errorf("finding repository: %w", err)
+	}
+	result := c.crawlRepoReleases(ctx, repo, 0, utils.NewPhaseRecorder())
+	countCrawl(ctx, result.found, result.saved, result.errors)
+	return result.responses, nil
+}
+
+// repoReleases is the outcome of crawling the releases of one repository, or the sum of several
+type repoReleases struct {
+	responses  []*model.ReleaseResponse
+	found      int
+	saved      int
+	errors     int
+	scrapeTime time.Duration
+	dbTime     time.Duration
+}
+
+func (r *repoReleases) add(other repoReleases) {
+	r.found += other.found
+	r.saved += other.saved
+	r.errors += other.errors
+	r.scrapeTime += other.scrapeTime
+	r.dbTime += other.dbTime
+}
+
+// crawlRepoReleases scrapes the releases of a repository, at most maxReleases when positive, and
+// saves them
+func (c *ReleaseController) crawlRepoReleases(ctx context.Context, repo *entity.Repository, maxReleases int,
+	recorder *utils.PhaseRecorder) repoReleases {
+	var result repoReleases
+	repoStartTime := time.Now()
+	repoOwner := repo.UserName
+	repoName := repo.RepoName
+	repoID := repo.ID
+
+	// Log repository processing start
+	c.log.WithFields(logrus.Fields{
+		"owner": repoOwner,
+		"name":  repoName,
+		"id":    repoID,
+		"phase": "repo_processing_start",
+	}).Info("Processing repository")
+
+	// Scrape releases (measure scraping time)
+	limits := repoLimits(c.repoPolicies.For(ctx, repo)).Narrow(scrape.RepoLimits{MaxReleases: maxReleases})
+	scrapeStartTime := time.Now()
+	scraped, err := c.repoBreakers.ExecuteHedged(ctx, repoOwner+"/"+repoName, func(ctx context.Context) (interface{}, error) {
+		return c.releaseScrape.CrawlReleases(ctx, repoOwner, repoName, limits)
+	})
+	releases, _ := scraped.(map[string]*model.ReleaseData)
+	result.scrapeTime = time.Since(scrapeStartTime)
+	recorder.Record(utils.PhaseScrape, result.scrapeTime)
+	if utils.BreakerRejected(err) {
+		c.log.WithField("repo", repoOwner+"/"+repoName).Warn("Skipping repository, its circuit breaker is open")
+		result.errors++
+		return result
+	}
+	// The releases listed before a failed page of the release list are still saved
+	complete := true
+	if errors.Is(err, scrape.ErrIncompleteListing) && releases != nil {
+		c.log.WithError(err).WithField("repo", repoName).Warn("Release list is incomplete, saving the releases found")
+		result.errors++
+		complete = false
+		err = nil
+	}
+	recordRepoCrawl(ctx, c.db, c.log, repo, err)
+	if err != nil {
+		c.log.WithError(err).WithField("repo", repoName).Error("Error scraping releases")
+		result.errors++
+		return result
 	}
+
+	// Log scraping results
+	result.found = len(releases)
+	c.log.WithFields(logrus.Fields{
+		"owner":          repoOwner,
+		"name":           repoName,
+		"releases_found": result.found,
+		"scrape_time_ms": result.scrapeTime.Milliseconds(),
+		"phase":          "repo_scraping_complete",
+	}).Info("Repository releases scraped")
+
+	// Skip if no releases were found
+	if len(releases) == 0 {
+		return result
+	}
+
+	// Tags missing from a complete listing were deleted on GitHub; a listing cut at the
+	// policy's or the request's max releases, or by a failed page, is not complete
+	if complete && limits.MaxReleases == 0 {
+		liveTags := make([]string, 0, len(releases))
+		for tag := range releases {
+			liveTags = append(liveTags, tag)
+		}
+		tombstoned, restored, err := c.releaseUsecase.SyncTombstones(ctx, repoID, liveTags)
+		if err != nil {
+			result.errors++
+		} else if tombstoned > 0 || restored > 0 {
+			c.log.WithFields(logrus.Fields{
+				"repo":       repoOwner + "/" + repoName,
+				"tombstoned": tombstoned,
+				"restored":   restored,
+			}).Warn("Release tags changed on GitHub")
+		}
+	}
+
+	// Save releases to database using batch insert
+	dbStartTime := time.Now()
+
+	// Prepare the release requests as a batch
+	releaseRequests := make([]*model.CreateReleaseRequest, 0, len(releases))
+	for tag, data := range releases {
+		releaseRequests = append(releaseRequests, &model.CreateReleaseRequest{
+			TagName:     tag,
+			Content:     data.Content,
+			Title:       data.Title,
+			PublishedAt: data.PublishedAt,
+			Author:      data.Author,
+			Prerelease:  data.Prerelease,
+			Assets:      data.Assets,
+			RepoID:      repoID,
+		})
+	}
+
+	// Batch create all releases for this repository
+	batchResponses, err := c.releaseUsecase.BatchCreate(ctx, releaseRequests)
+	if err != nil {
+		c.log.WithFields(logrus.Fields{
+			"repo":  repoName,
+			"error": err.Error(),
+		}).Error("Failed to batch save releases")
+		result.errors += len(releaseRequests)
+		return result
+	}
+
+	// Add successful responses to the main response list
+	result.responses = batchResponses
+	result.saved = len(batchResponses)
+
+	// Calculate database time
+	result.dbTime = time.Since(dbStartTime)
+	repoTotalTime := time.Since(repoStartTime)
+	recorder.Record(utils.PhaseDatabase, result.dbTime)
+	recorder.Record(utils.PhaseTotal, repoTotalTime)
+
+	// Log repository complete
+	c.log.WithFields(logrus.Fields{
+		"owner":          repoOwner,
+		"name":           repoName,
+		"scrape_time_ms": result.scrapeTime.Milliseconds(),
+		"db_time_ms":     result.dbTime.Milliseconds(),
+		"total_time_ms":  repoTotalTime.Milliseconds(),
+		"success_count":  len(batchResponses),
+		"phase":          "repo_processing_complete",
+	}).Info("Repository processing completed")
+	return result
 }
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ReleaseNoteController struct {
+	log                *logrus.Logger
+	releaseNoteUsecase *usecase.ReleaseNoteUsecase
+}
+
+func NewReleaseNoteController(log *logrus.Logger, releaseNoteUsecase *usecase.ReleaseNoteUsecase) *ReleaseNoteController {
+	return &ReleaseNoteController{
+		log:                log,
+		releaseNoteUsecase: releaseNoteUsecase,
+	}
+}
+
+// GetStructured returns the notes of a release split into features, bug fixes, breaking changes
+// and other items, with the issues and pull requests they link and the users they mention
+func (c *ReleaseNoteController) GetStructured(w http.ResponseWriter, r *http.Request) {
+	releaseID, err := idParam(r, "releaseID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid release ID")
+		return
+	}
+
+	structured, err := c.releaseNoteUsecase.Structured(r.Context(), releaseID)
+	if err != nil {
+		writeLookupError(w, r, err, "Release not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.StructuredReleaseResponse]{
+		Data: structured,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
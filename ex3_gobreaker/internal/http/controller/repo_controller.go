@@ -2,54 +2,61 @@ package controller
 
 import (
 	"context"
+	"crawler/baseline/internal/cache"
 	"crawler/baseline/internal/entity"
 	"crawler/baseline/internal/model"
 	"crawler/baseline/internal/repository"
 	"crawler/baseline/internal/scrape"
 	"crawler/baseline/internal/usecase"
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type RepoController struct {
-	log         *logrus.Logger
-	db          *gorm.DB
-	repoUsecase *usecase.RepoUsecase
-	repoScrape  *scrape.RepoScrape
+	log          *logrus.Logger
+	db           *gorm.DB
+	repoUsecase  *usecase.RepoUsecase
+	repoScrape   scrape.RepoSource
+	branchScrape scrape.BranchSource
+	// responses caches GetRepo by repository ID; nil caches nothing
+	responses *cache.LRU[int64, *model.RepoResponse]
 }
 
-func NewRepoController(log *logrus.Logger, db *gorm.DB,
-	repoUsecase *usecase.RepoUsecase, repoScrape *scrape.RepoScrape) *RepoController {
+func NewRepoController(log *logrus.Logger, db *gorm.DB, repoUsecase *usecase.RepoUsecase,
+	repoScrape scrape.RepoSource, branchScrape scrape.BranchSource,
+	responses *cache.LRU[int64, *model.RepoResponse]) *RepoController {
 	return &RepoController{
-		log:         log,
-		db:          db,
-		repoUsecase: repoUsecase,
-		repoScrape:  repoScrape,
+		log:          log,
+		db:           db,
+		repoUsecase:  repoUsecase,
+		repoScrape:   repoScrape,
+		branchScrape: branchScrape,
+		responses:    responses,
 	}
 }
 
 func (c *RepoController) RepoCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		repoID := chi.URLParam(r, "repoID")
+		repoID, err := idParam(r, "repoID")
+		if err != nil {
+			c.log.WithError(err).Error("Invalid repository ID format")
+			writeAppError(w, r, err, "Invalid repository ID")
+			return
+		}
 		repoEntity := &entity.Repository{}
 		repoRepository := repository.NewRepoRepository(c.log)
-		err := repoRepository.FindById(c.db, repoEntity, repoID)
+		err = repoRepository.FindById(r.Context(), c.db, repoEntity, repoID)
 		if err != nil {
-			c.log.WithError(err).Errorf("Error finding repo with ID %s", repoID)
-			http.Error(w, "Repo not found", http.StatusNotFound)
+			c.log.WithError(err).Errorf("Error finding repo with ID %d", repoID)
+			writeLookupError(w, r, err, "Repo not found")
 			return
 		}
-		repoResponse := model.RepoResponse{
-			ID:       repoEntity.ID,
-			RepoName: repoEntity.RepoName,
-			UserName: repoEntity.UserName,
-		}
+		repoResponse := *usecase.RepoToResponse(repoEntity)
 		ctx := context.WithValue(r.Context(), "repo", repoResponse)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -57,58 +64,92 @@ func (c *RepoController) RepoCtx(next http.Handler) http.Handler {
 
 func (c *RepoController) GetRepo(w http.ResponseWriter, r *http.Request) {
 	// Extract repoID from URL parameters
-	repoID, err := strconv.Atoi(chi.URLParam(r, "repoID"))
+	repoID, err := idParam(r, "repoID")
 	if err != nil {
 		c.log.WithError(err).Error("Invalid repository ID format")
-		http.Error(w, "Invalid repository ID", http.StatusBadRequest)
+		writeAppError(w, r, err, "Invalid repository ID")
 		return
 	}
 
 	c.log.WithField("repo_id", repoID).Info("Fetching repository")
 
-	// Create repository instance
-	repoRepository := repository.NewRepoRepository(c.log)
+	repoResponse, cached := c.responses.Get(repoID)
+	if !cached {
+		// Create repository instance
+		repoRepository := repository.NewRepoRepository(c.log)
 
-	// Find repository by ID
-	repoEntity := &entity.Repository{}
-	err = repoRepository.FindById(c.db, repoEntity, repoID)
+		// Find repository by ID, archived ones included so they can be restored
+		repoEntity := &entity.Repository{}
+		err = repoRepository.FindByIdWithArchived(r.Context(), c.db, repoEntity, repoID)
 
-	if err != nil {
-		c.log.WithError(err).WithField("repo_id", repoID).Error("Repository not found")
-		http.Error(w, "Repository not found", http.StatusNotFound)
-		return
-	}
+		if err != nil {
+			c.log.WithError(err).WithField("repo_id", repoID).Error("Repository not found")
+			writeLookupError(w, r, err, "Repository not found")
+			return
+		}
 
-	// Convert entity to response model
-	repoResponse := &model.RepoResponse{
-		ID:       repoEntity.ID,
-		RepoName: repoEntity.RepoName,
-		UserName: repoEntity.UserName,
+		// Convert entity to response model
+		repoResponse = usecase.RepoToResponse(repoEntity)
+		c.responses.Add(repoID, repoResponse)
 	}
 
 	// Send JSON response
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(repoResponse); err != nil {
 		c.log.WithError(err).Error("Error encoding response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
 		return
 	}
 }
 
+// CrawlAllRepos saves the top repositories of gitstar-ranking.com; the limit and pages query
+// parameters bound how many repositories and ranking pages are scraped
 func (c *RepoController) CrawlAllRepos(w http.ResponseWriter, r *http.Request) {
+	limit, err := countQuery(r, "limit", scrape.DefaultRepoLimit)
+	if err != nil {
+		writeAppError(w, r, err, "Invalid limit")
+		return
+	}
+	maxPages, err := countQuery(r, "pages", scrape.DefaultRepoPages)
+	if err != nil {
+		writeAppError(w, r, err, "Invalid pages")
+		return
+	}
+
+	responseData, err := c.CrawlRepos(r.Context(), limit, maxPages)
+	if err != nil {
+		writeError(w, r, "Failed to crawl repositories", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.RepoResponse]{
+		Data: responseData,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// CrawlRepos scrapes at most limit tracked repositories, from the first maxPages ranking pages,
+// and saves them. It serves CrawlAllRepos and the coordinator's repos stage when that runs in process.
+func (c *RepoController) CrawlRepos(ctx context.Context, limit int, maxPages int) ([]*model.RepoResponse, error) {
 	// Start timing
 	startTime := time.Now()
 	c.log.WithField("phase", "start").Info("Starting repository crawling operation")
 
 	// Scraping phase
 	scrapeStartTime := time.Now()
-	c.log.WithField("phase", "scraping_start").Info("Starting repository scraping")
+	c.log.WithFields(logrus.Fields{
+		"limit": limit,
+		"pages": maxPages,
+		"phase": "scraping_start",
+	}).Info("Starting repository scraping")
 
-	repos, err := c.repoScrape.CrawlAllRepos()
+	repos, err := c.repoScrape.CrawlAllRepos(limit, maxPages)
 	if err != nil {
 		c.log.WithError(err).Error("Error crawling repositories")
-		http.Error(w, "Failed to crawl repositories", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("crawling repositories: %w", err)
 	}
 
 	scrapeTime := time.Since(scrapeStartTime)
@@ -121,11 +162,10 @@ func (c *RepoController) CrawlAllRepos(w http.ResponseWriter, r *http.Request) {
 	dbStartTime := time.Now()
 	c.log.WithField("phase", "database_start").Info("Starting database operations")
 
-	responseData, err := c.repoUsecase.BatchCreate(r.Context(), repos)
+	responseData, err := c.repoUsecase.BatchCreate(ctx, repos)
 	if err != nil {
 		c.log.WithError(err).Error("Failed to create repositories")
-		http.Error(w, "Failed to save repositories", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("saving repositories: %w", err)
 	}
 
 	dbTime := time.Since(dbStartTime)
@@ -139,12 +179,175 @@ func (c *RepoController) CrawlAllRepos(w http.ResponseWriter, r *http.Request) {
 		"success_count":  len(responseData),
 		"phase":          "operation_complete",
 	}).Info("Repository crawling operation completed")
+	countCrawl(ctx, len(repos), len(responseData), 0)
+
+	return responseData, nil
+}
+
+// GetBranches detects the default branch of a repository, stores it, and lists all of its branches
+func (c *RepoController) GetBranches(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		c.log.WithError(err).Error("Invalid repository ID format")
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	repoRepository := repository.NewRepoRepository(c.log)
+	repoEntity := &entity.Repository{}
+	if err := repoRepository.FindById(r.Context(), c.db, repoEntity, repoID); err != nil {
+		c.log.WithError(err).WithField("repo_id", repoID).Error("Repository not found")
+		writeLookupError(w, r, err, "Repository not found")
+		return
+	}
+
+	defaultBranch, err := c.branchScrape.DetectDefaultBranch(repoEntity.UserName, repoEntity.RepoName)
+	recordRepoCrawl(r.Context(), c.db, c.log, repoEntity, err)
+	if err != nil {
+		c.log.WithError(err).WithField("repo_id", repoID).Error("Error detecting default branch")
+		writeScrapeError(w, r, err, "Error fetching branches")
+		return
+	}
+
+	if defaultBranch != repoEntity.DefaultBranch {
+		if err := repoRepository.UpdateDefaultBranch(r.Context(), c.db, repoEntity.ID, defaultBranch); err != nil {
+			c.log.WithError(err).WithField("repo_id", repoID).Error("Error saving default branch")
+			writeError(w, r, "Error saving default branch", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	branches, err := c.branchScrape.ListBranches(repoEntity.UserName, repoEntity.RepoName)
+	if err != nil {
+		c.log.WithError(err).WithField("repo_id", repoID).Error("Error listing branches")
+		writeScrapeError(w, r, err, "Error fetching branches")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.BranchesResponse]{
+		Data: &model.BranchesResponse{
+			DefaultBranch: defaultBranch,
+			Branches:      branches,
+		},
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// EnrichRepo scrapes and stores the front page metadata of a single repository
+func (c *RepoController) EnrichRepo(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		c.log.WithError(err).Error("Invalid repository ID format")
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	repoRepository := repository.NewRepoRepository(c.log)
+	repoEntity := &entity.Repository{}
+	if err := repoRepository.FindById(r.Context(), c.db, repoEntity, repoID); err != nil {
+		c.log.WithError(err).WithField("repo_id", repoID).Error("Repository not found")
+		writeLookupError(w, r, err, "Repository not found")
+		return
+	}
+
+	metadata, err := c.repoScrape.CrawlRepoMetadata(repoEntity.UserName, repoEntity.RepoName)
+	recordRepoCrawl(r.Context(), c.db, c.log, repoEntity, err)
+	if err != nil {
+		c.log.WithError(err).WithField("repo_id", repoID).Error("Error scraping repository metadata")
+		writeScrapeError(w, r, err, "Error scraping repository metadata")
+		return
+	}
+
+	repoResponse, err := c.repoUsecase.Enrich(r.Context(), repoEntity, metadata)
+	if err != nil {
+		writeError(w, r, "Error saving repository metadata", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.RepoResponse]{
+		Data: repoResponse,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// RestoreRepo brings back a repository archived after too many 404s, to be crawled again
+func (c *RepoController) RestoreRepo(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		c.log.WithError(err).Error("Invalid repository ID format")
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	repoResponse, err := c.repoUsecase.Restore(r.Context(), repoID)
+	if err != nil {
+		writeLookupError(w, r, err, "Repository not found")
+		return
+	}
+	c.log.WithField("repo_id", repoID).Info("Repository restored")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.RepoResponse]{
+		Data: repoResponse,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// EnrichAllRepos scrapes and stores the front page metadata of every repository
+func (c *RepoController) EnrichAllRepos(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	c.log.WithField("phase", "start").Info("Starting repository enrichment operation")
+
+	repoEntities := []entity.Repository{}
+	repoRepository := repository.NewRepoRepository(c.log)
+	if err := repoRepository.FindCrawlable(r.Context(), c.db, &repoEntities, time.Now()); err != nil {
+		c.log.WithError(err).Error("Error fetching repositories")
+		writeError(w, r, "Error fetching repositories", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]*model.RepoResponse, 0, len(repoEntities))
+	errorCount := 0
+	for i := range repoEntities {
+		repoEntity := &repoEntities[i]
+
+		metadata, err := c.repoScrape.CrawlRepoMetadata(repoEntity.UserName, repoEntity.RepoName)
+		recordRepoCrawl(r.Context(), c.db, c.log, repoEntity, err)
+		if err != nil {
+			c.log.WithError(err).WithField("repo_id", repoEntity.ID).Error("Error scraping repository metadata")
+			errorCount++
+			continue
+		}
+
+		repoResponse, err := c.repoUsecase.Enrich(r.Context(), repoEntity, metadata)
+		if err != nil {
+			errorCount++
+			continue
+		}
+		responses = append(responses, repoResponse)
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"total_time_ms":   time.Since(startTime).Milliseconds(),
+		"repos_processed": len(repoEntities),
+		"success_count":   len(responses),
+		"error_count":     errorCount,
+		"phase":           "operation_complete",
+	}).Info("Repository enrichment operation completed")
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.RepoResponse]{
-		Data: responseData,
+		Data: responses,
 	}); err != nil {
 		c.log.WithError(err).Error("Error encoding response")
-		http.Error(w, "Error processing response", http.StatusInternalServerError)
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
 	}
 }
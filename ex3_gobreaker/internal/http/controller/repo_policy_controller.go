@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RepoPolicyController shows and edits the crawl policy of a repository, which bounds the
+// releases and commits crawled and paces its requests
+type RepoPolicyController struct {
+	log               *logrus.Logger
+	repoPolicyUsecase *usecase.RepoPolicyUsecase
+}
+
+func NewRepoPolicyController(log *logrus.Logger, repoPolicyUsecase *usecase.RepoPolicyUsecase) *RepoPolicyController {
+	return &RepoPolicyController{
+		log:               log,
+		repoPolicyUsecase: repoPolicyUsecase,
+	}
+}
+
+// GetPolicy returns the policy applied to a repository and whether it is stored or configured
+func (c *RepoPolicyController) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	policy, err := c.repoPolicyUsecase.Get(r.Context(), repoID)
+	if err != nil {
+		writeLookupError(w, r, err, "Repository not found")
+		return
+	}
+	c.writePolicy(w, r, policy)
+}
+
+// UpdatePolicy stores the policy of a repository, replacing its configured one
+func (c *RepoPolicyController) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	var request model.UpdateRepoPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := c.repoPolicyUsecase.Update(r.Context(), repoID, &request, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalid):
+			writeAppError(w, r, err, err.Error())
+		default:
+			writeLookupError(w, r, err, "Repository not found")
+		}
+		return
+	}
+	c.writePolicy(w, r, policy)
+}
+
+// DeletePolicy removes the stored policy of a repository, returning the one it falls back to
+func (c *RepoPolicyController) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	repoID, err := idParam(r, "repoID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid repository ID")
+		return
+	}
+
+	policy, err := c.repoPolicyUsecase.Delete(r.Context(), repoID)
+	if err != nil {
+		writeLookupError(w, r, err, "Repository or stored policy not found")
+		return
+	}
+	c.writePolicy(w, r, policy)
+}
+
+func (c *RepoPolicyController) writePolicy(w http.ResponseWriter, r *http.Request, policy *model.RepoPolicyResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.RepoPolicyResponse]{
+		Data: policy,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// repoLimits are the scraper limits of a repository policy
+func repoLimits(policy service.RepoPolicy) scrape.RepoLimits {
+	return scrape.RepoLimits{
+		MaxReleases:       policy.MaxReleases,
+		MaxCommits:        policy.MaxCommitsPerRelease,
+		RequestsPerMinute: policy.RequestsPerMinute,
+	}
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCalendarHours = 24
+	maxCalendarHours     = 7 * 24
+	calendarRecrawlLimit = 500
+)
+
+type ScheduleController struct {
+	log       *logrus.Logger
+	blackouts *service.Blackouts
+	// coordinator is nil on instances without a scheduler, recrawlUsecase without decay re-crawls
+	coordinator     *service.CrawlingCoordinator
+	recrawlUsecase  *usecase.RecrawlUsecase
+	scheduleUsecase *usecase.ScheduleUsecase
+}
+
+func NewScheduleController(log *logrus.Logger, blackouts *service.Blackouts, coordinator *service.CrawlingCoordinator,
+	recrawlUsecase *usecase.RecrawlUsecase, scheduleUsecase *usecase.ScheduleUsecase) *ScheduleController {
+	return &ScheduleController{
+		log:             log,
+		blackouts:       blackouts,
+		coordinator:     coordinator,
+		recrawlUsecase:  recrawlUsecase,
+		scheduleUsecase: scheduleUsecase,
+	}
+}
+
+// ListSchedules returns the cron schedules of the coordinator stages with their next runs
+func (c *ScheduleController) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := c.scheduleUsecase.List(r.Context())
+	if err != nil {
+		writeError(w, r, "Error fetching schedules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]model.CrawlScheduleResponse]{
+		Data: schedules,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// UpdateSchedule replaces the cron expression of a schedule; the scheduler picks up the new
+// next run on its following check
+func (c *ScheduleController) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var request model.UpdateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Expression == "" {
+		writeError(w, r, "Invalid request body, expected an expression", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := c.scheduleUsecase.Update(r.Context(), name, request.Expression, time.Now())
+	if err != nil {
+		switch {
+		case apperrors.IsNotFound(err):
+			writeErrorDetails(w, r, "Unknown schedule", http.StatusNotFound, map[string]string{"name": name})
+		case errors.Is(err, apperrors.ErrInvalid):
+			writeAppError(w, r, err, err.Error())
+		default:
+			writeAppError(w, r, err, "Error updating schedule")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[model.CrawlScheduleResponse]{
+		Data: schedule,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// Calendar lists the blackout windows and the scheduled runs of the next hours (24 by
+// default): the coordinator cycles, the cron schedules and the decay re-crawls of repositories
+func (c *ScheduleController) Calendar(w http.ResponseWriter, r *http.Request) {
+	hours := defaultCalendarHours
+	if value := r.URL.Query().Get("hours"); value != "" {
+		var err error
+		hours, err = strconv.Atoi(value)
+		if err != nil || hours <= 0 || hours > maxCalendarHours {
+			writeError(w, r, "Invalid hours", http.StatusBadRequest)
+			return
+		}
+	}
+
+	location := c.blackouts.Location()
+	now := time.Now().In(location)
+	to := now.Add(time.Duration(hours) * time.Hour)
+	response := &model.ScheduleResponse{
+		From:      now,
+		To:        to,
+		Timezone:  location.String(),
+		Blackouts: make([]model.BlackoutPeriod, 0),
+		Runs:      make([]model.ScheduledRun, 0),
+	}
+
+	for _, period := range c.blackouts.Periods(now, to) {
+		response.Blackouts = append(response.Blackouts, model.BlackoutPeriod{Start: period.Start, End: period.End})
+	}
+	if c.coordinator != nil {
+		for _, cycle := range c.coordinator.UpcomingCycles(now, to) {
+			response.Runs = append(response.Runs, model.ScheduledRun{
+				At:      cycle.At,
+				Kind:    "coordinator",
+				Skipped: cycle.Skipped,
+			})
+		}
+	}
+	scheduled, err := c.scheduleUsecase.Upcoming(r.Context(), now, to, calendarRecrawlLimit)
+	if err != nil {
+		writeError(w, r, "Error fetching scheduled runs", http.StatusInternalServerError)
+		return
+	}
+	response.Runs = append(response.Runs, scheduled...)
+	if c.recrawlUsecase != nil {
+		recrawls, err := c.recrawlUsecase.Upcoming(r.Context(), now, to, calendarRecrawlLimit)
+		if err != nil {
+			writeError(w, r, "Error fetching scheduled re-crawls", http.StatusInternalServerError)
+			return
+		}
+		response.Runs = append(response.Runs, recrawls...)
+	}
+	sort.SliceStable(response.Runs, func(i, j int) bool { return response.Runs[i].At.Before(response.Runs[j].At) })
+	for i := range response.Runs {
+		response.Runs[i].At = response.Runs[i].At.In(location)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.ScheduleResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+type SelfCheckController struct {
+	log       *logrus.Logger
+	selfCheck *service.ScrapeSelfCheck
+}
+
+func NewSelfCheckController(log *logrus.Logger, selfCheck *service.ScrapeSelfCheck) *SelfCheckController {
+	return &SelfCheckController{
+		log:       log,
+		selfCheck: selfCheck,
+	}
+}
+
+// SelfCheck scrapes the configured repository and release with every scraper and reports how many
+// values each extracted. It answers 503 when a check fails, so a probe can alert on it directly.
+func (c *SelfCheckController) SelfCheck(w http.ResponseWriter, r *http.Request) {
+	report, err := c.selfCheck.Run(r.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrSelfCheckRunning) {
+			writeAppError(w, r, err, "A scraper self-check is already running")
+			return
+		}
+		c.log.WithError(err).Error("Scraper self-check failed")
+		writeAppError(w, r, err, "Scraper self-check failed")
+		return
+	}
+
+	status := http.StatusOK
+	if !report.Passed {
+		status = http.StatusServiceUnavailable
+		for _, check := range report.Checks {
+			if !check.Passed {
+				c.log.WithFields(logrus.Fields{
+					"check": check.Name,
+					"repo":  report.Repo,
+					"tag":   report.Tag,
+				}).Warn("Scraper self-check failed: " + check.Error)
+			}
+		}
+	}
+	c.log.WithFields(logrus.Fields{
+		"failed":        report.Failed,
+		"total_time_ms": report.DurationMs,
+	}).Info("Scraper self-check completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.SelfCheckReport]{
+		Data: report,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
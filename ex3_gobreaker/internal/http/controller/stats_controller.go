@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultStatsTop = 20
+	maxStatsTop     = 500
+)
+
+type StatsController struct {
+	log          *logrus.Logger
+	statsUsecase *usecase.StatsUsecase
+}
+
+func NewStatsController(log *logrus.Logger, statsUsecase *usecase.StatsUsecase) *StatsController {
+	return &StatsController{
+		log:          log,
+		statsUsecase: statsUsecase,
+	}
+}
+
+// GetStats returns aggregate counts for the admin dashboard; "top" limits the per-repo release counts
+func (c *StatsController) GetStats(w http.ResponseWriter, r *http.Request) {
+	top := defaultStatsTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		var err error
+		top, err = strconv.Atoi(value)
+		if err != nil || top < 0 || top > maxStatsTop {
+			writeError(w, r, "Invalid top", http.StatusBadRequest)
+			return
+		}
+	}
+
+	startTime := time.Now()
+	stats, err := c.statsUsecase.Stats(r.Context(), top)
+	if err != nil {
+		writeError(w, r, "Error computing stats", http.StatusInternalServerError)
+		return
+	}
+	c.log.WithField("duration_ms", time.Since(startTime).Milliseconds()).Info("Stats computed")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.StatsResponse]{
+		Data: stats,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// RepoSummary returns the repository count and checksum
+func (c *StatsController) RepoSummary(w http.ResponseWriter, r *http.Request) {
+	c.serveSummary(w, r, usecase.DatasetRepos)
+}
+
+// ReleaseSummary returns release counts and the latest tag per repository, with a checksum
+func (c *StatsController) ReleaseSummary(w http.ResponseWriter, r *http.Request) {
+	c.serveSummary(w, r, usecase.DatasetReleases)
+}
+
+// CommitSummary returns commit counts per repository, with a checksum
+func (c *StatsController) CommitSummary(w http.ResponseWriter, r *http.Request) {
+	c.serveSummary(w, r, usecase.DatasetCommits)
+}
+
+func (c *StatsController) serveSummary(w http.ResponseWriter, r *http.Request, dataset string) {
+	startTime := time.Now()
+	summary, err := c.statsUsecase.Summary(r.Context(), dataset)
+	if err != nil {
+		writeError(w, r, "Error computing summary", http.StatusInternalServerError)
+		return
+	}
+	c.log.WithFields(logrus.Fields{
+		"dataset":     dataset,
+		"count":       summary.Count,
+		"duration_ms": time.Since(startTime).Milliseconds(),
+	}).Info("Summary computed")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.DatasetSummary]{
+		Data: summary,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TagController struct {
+	log        *logrus.Logger
+	db         *gorm.DB
+	tagUsecase *usecase.TagUsecase
+	tagScrape  scrape.TagSource
+}
+
+func NewTagController(log *logrus.Logger, db *gorm.DB,
+	tagUsecase *usecase.TagUsecase, tagScrape scrape.TagSource) *TagController {
+	return &TagController{
+		log:        log,
+		db:         db,
+		tagUsecase: tagUsecase,
+		tagScrape:  tagScrape,
+	}
+}
+
+// CrawlAllTags crawls the git tags of every stored repository, whether or not they have releases
+func (c *TagController) CrawlAllTags(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	c.log.WithField("phase", "start").Info("Starting tag crawling operation")
+
+	successCount := 0
+	errorCount := 0
+	tagCount := 0
+
+	repoEntities := []entity.Repository{}
+	repoRepository := repository.NewRepoRepository(c.log)
+	if err := repoRepository.FindCrawlable(r.Context(), c.db, &repoEntities, time.Now()); err != nil {
+		c.log.WithError(err).Error("Error fetching repositories")
+		writeError(w, r, "Error fetching repositories", http.StatusInternalServerError)
+		return
+	}
+
+	repoCount := len(repoEntities)
+	tagResponses := make([]*model.TagResponse, 0)
+	totalScrapeTime := time.Duration(0)
+	totalDbTime := time.Duration(0)
+
+	for i := range repoEntities {
+		repo := &repoEntities[i]
+		c.log.WithFields(logrus.Fields{
+			"progress": fmt.Sprintf("%d/%d", i+1, repoCount),
+			"owner":    repo.UserName,
+			"name":     repo.RepoName,
+			"id":       repo.ID,
+			"phase":    "repo_processing_start",
+		}).Info("Processing repository")
+
+		scrapeStartTime := time.Now()
+		tags, err := c.tagScrape.CrawlTags(repo.UserName, repo.RepoName)
+		scrapeTime := time.Since(scrapeStartTime)
+		totalScrapeTime += scrapeTime
+		recordRepoCrawl(r.Context(), c.db, c.log, repo, err)
+		if err != nil {
+			// Keep the tags of the pages fetched before the failure
+			c.log.WithError(err).WithField("repo", repo.RepoName).Error("Error scraping tags")
+		}
+
+		tagCount += len(tags)
+		if len(tags) == 0 {
+			continue
+		}
+
+		for _, tag := range tags {
+			tag.RepoID = repo.ID
+		}
+
+		dbStartTime := time.Now()
+		batchResponses, err := c.tagUsecase.BatchCreate(r.Context(), tags)
+		dbTime := time.Since(dbStartTime)
+		totalDbTime += dbTime
+		if err != nil {
+			c.log.WithFields(logrus.Fields{
+				"repo":  repo.RepoName,
+				"error": err.Error(),
+			}).Error("Failed to batch save tags")
+			errorCount += len(tags)
+			continue
+		}
+
+		tagResponses = append(tagResponses, batchResponses...)
+		successCount += len(batchResponses)
+
+		c.log.WithFields(logrus.Fields{
+			"owner":          repo.UserName,
+			"name":           repo.RepoName,
+			"tags_found":     len(tags),
+			"scrape_time_ms": scrapeTime.Milliseconds(),
+			"db_time_ms":     dbTime.Milliseconds(),
+			"phase":          "repo_processing_complete",
+		}).Info("Repository tags processed")
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"total_time_ms":        time.Since(startTime).Milliseconds(),
+		"total_scrape_time_ms": totalScrapeTime.Milliseconds(),
+		"total_db_time_ms":     totalDbTime.Milliseconds(),
+		"repos_processed":      repoCount,
+		"tags_total":           tagCount,
+		"success_count":        successCount,
+		"error_count":          errorCount,
+		"phase":                "operation_complete",
+	}).Info("Tag crawling operation completed")
+	countCrawl(r.Context(), tagCount, successCount, errorCount)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.TagResponse]{
+		Data: tagResponses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
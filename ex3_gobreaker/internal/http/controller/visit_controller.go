@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultVisitLimit = 100
+	maxVisitLimit     = 1000
+)
+
+type VisitController struct {
+	log *logrus.Logger
+	db  *gorm.DB
+}
+
+func NewVisitController(log *logrus.Logger, db *gorm.DB) *VisitController {
+	return &VisitController{
+		log: log,
+		db:  db,
+	}
+}
+
+// ListVisits returns the most recent recorded visits, optionally filtered by a URL substring
+// such as "owner/repo", to see exactly what was fetched for a repository
+func (c *VisitController) ListVisits(w http.ResponseWriter, r *http.Request) {
+	limit := defaultVisitLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, r, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxVisitLimit)
+	}
+	urlContains := r.URL.Query().Get("url")
+
+	visitRepository := repository.NewVisitRepository(c.log)
+	visits := []entity.Visit{}
+	if err := visitRepository.FindRecent(r.Context(), c.db, &visits, urlContains, limit); err != nil {
+		c.log.WithError(err).Error("Error fetching visits")
+		writeError(w, r, "Error fetching visits", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]model.VisitResponse, 0, len(visits))
+	for _, visit := range visits {
+		responses = append(responses, model.VisitResponse{
+			ID:         visit.ID,
+			URL:        visit.URL,
+			Method:     visit.Method,
+			StatusCode: visit.StatusCode,
+			Bytes:      visit.Bytes,
+			DurationMs: visit.DurationMs,
+			Error:      visit.Error,
+			VisitedAt:  visit.VisitedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]model.VisitResponse]{
+		Data: responses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
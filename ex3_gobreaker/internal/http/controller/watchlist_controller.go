@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type WatchlistController struct {
+	log              *logrus.Logger
+	watchlistUsecase *usecase.WatchlistUsecase
+	digestUsecase    *usecase.DigestUsecase
+	keywordUsecase   *usecase.KeywordUsecase
+}
+
+func NewWatchlistController(log *logrus.Logger, watchlistUsecase *usecase.WatchlistUsecase,
+	digestUsecase *usecase.DigestUsecase, keywordUsecase *usecase.KeywordUsecase) *WatchlistController {
+	return &WatchlistController{
+		log:              log,
+		watchlistUsecase: watchlistUsecase,
+		digestUsecase:    digestUsecase,
+		keywordUsecase:   keywordUsecase,
+	}
+}
+
+func (c *WatchlistController) CreateWatchlist(w http.ResponseWriter, r *http.Request) {
+	var request model.CreateWatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Name == "" {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := c.watchlistUsecase.Create(r.Context(), &request)
+	if errors.Is(err, apperrors.ErrInvalid) {
+		writeAppError(w, r, err, err.Error())
+		return
+	}
+	if err != nil {
+		writeAppError(w, r, err, "Error creating watchlist")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.WatchlistResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+func (c *WatchlistController) ListWatchlists(w http.ResponseWriter, r *http.Request) {
+	responses, err := c.watchlistUsecase.List(r.Context())
+	if err != nil {
+		writeError(w, r, "Error fetching watchlists", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.WatchlistResponse]{
+		Data: responses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+func (c *WatchlistController) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	watchlistID, err := idParam(r, "watchlistID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid watchlist ID")
+		return
+	}
+
+	response, err := c.watchlistUsecase.Get(r.Context(), watchlistID)
+	if err != nil {
+		c.log.WithError(err).WithField("watchlist_id", watchlistID).Error("Watchlist not found")
+		writeLookupError(w, r, err, "Watchlist not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.WatchlistResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// GetDigest previews the release digest of a watchlist over the last "hours" (default 24).
+// With format=markdown only the rendered markdown is returned.
+func (c *WatchlistController) GetDigest(w http.ResponseWriter, r *http.Request) {
+	c.digest(w, r, false)
+}
+
+// SendDigest builds the release digest of a watchlist and delivers it through the notifiers now
+func (c *WatchlistController) SendDigest(w http.ResponseWriter, r *http.Request) {
+	c.digest(w, r, true)
+}
+
+func (c *WatchlistController) digest(w http.ResponseWriter, r *http.Request, send bool) {
+	watchlistID, err := idParam(r, "watchlistID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid watchlist ID")
+		return
+	}
+
+	hours := 24
+	if value := r.URL.Query().Get("hours"); value != "" {
+		hours, err = strconv.Atoi(value)
+		if err != nil || hours <= 0 {
+			writeError(w, r, "Invalid hours", http.StatusBadRequest)
+			return
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var digest *model.ReleaseDigest
+	if send {
+		digest, err = c.digestUsecase.Send(r.Context(), watchlistID, since)
+	} else {
+		digest, err = c.digestUsecase.Build(r.Context(), watchlistID, since)
+	}
+	if apperrors.IsNotFound(err) {
+		writeAppError(w, r, err, "Watchlist not found")
+		return
+	}
+	if err != nil && digest == nil {
+		writeAppError(w, r, err, "Error building digest")
+		return
+	}
+	if err != nil {
+		writeError(w, r, "Error sending digest", http.StatusBadGateway)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(digest.Markdown))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.ReleaseDigest]{
+		Data: digest,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// ListKeywords returns the keywords a watchlist is subscribed to
+func (c *WatchlistController) ListKeywords(w http.ResponseWriter, r *http.Request) {
+	watchlistID, err := idParam(r, "watchlistID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid watchlist ID")
+		return
+	}
+
+	responses, err := c.keywordUsecase.List(r.Context(), watchlistID)
+	if err != nil {
+		writeLookupError(w, r, err, "Watchlist not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.WebResponse[[]*model.KeywordResponse]{
+		Data: responses,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+		writeError(w, r, "Error processing response", http.StatusInternalServerError)
+	}
+}
+
+// AddKeyword subscribes a watchlist to a keyword: new release notes and commit messages of its
+// repositories containing it are sent as keyword.matched notifications
+func (c *WatchlistController) AddKeyword(w http.ResponseWriter, r *http.Request) {
+	watchlistID, err := idParam(r, "watchlistID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid watchlist ID")
+		return
+	}
+
+	var request model.CreateKeywordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Keyword == "" {
+		writeError(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := c.keywordUsecase.Add(r.Context(), watchlistID, request.Keyword)
+	if errors.Is(err, apperrors.ErrInvalid) || errors.Is(err, apperrors.ErrConflict) {
+		writeAppError(w, r, err, err.Error())
+		return
+	}
+	if err != nil {
+		writeLookupError(w, r, err, "Watchlist not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(model.WebResponse[*model.KeywordResponse]{
+		Data: response,
+	}); err != nil {
+		c.log.WithError(err).Error("Error encoding response")
+	}
+}
+
+// DeleteKeyword unsubscribes a watchlist from a keyword
+func (c *WatchlistController) DeleteKeyword(w http.ResponseWriter, r *http.Request) {
+	watchlistID, err := idParam(r, "watchlistID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid watchlist ID")
+		return
+	}
+	keywordID, err := idParam(r, "keywordID")
+	if err != nil {
+		writeAppError(w, r, err, "Invalid keyword ID")
+		return
+	}
+
+	if err := c.keywordUsecase.Delete(r.Context(), watchlistID, keywordID); err != nil {
+		writeLookupError(w, r, err, "Keyword not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,6 +1,8 @@
 package route
 
 import (
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/graph"
 	http "crawler/baseline/internal/http/controller"
 	"time"
 
@@ -9,43 +11,210 @@ import (
 )
 
 type RouteConfig struct {
-	App               *chi.Mux
-	RepoController    *http.RepoController
-	ReleaseController *http.ReleaseController
-	CommitController  *http.CommitController
+	App                  *chi.Mux
+	RepoController       *http.RepoController
+	OrgController        *http.OrgController
+	RepoPolicyController *http.RepoPolicyController
+	ReleaseController    *http.ReleaseController
+	CommitController     *http.CommitController
+	TagController        *http.TagController
+	VisitController      *http.VisitController
+	ExportController     *http.ExportController
+	FeedController       *http.FeedController
+	StatsController      *http.StatsController
+	CrawlRunController   *http.CrawlRunController
+	CrawlErrorController *http.CrawlErrorController
+
+	WatchlistController *http.WatchlistController
+	OnboardController   *http.OnboardController
+	JobController       *http.JobController
+
+	CoordinatorController *http.CoordinatorController
+	AlertController       *http.AlertController
+	BenchController       *http.BenchController
+	// ExperimentController is nil where crawled data is published instead of stored
+	ExperimentController  *http.ExperimentController
+	HealthController      *http.HealthController
+	MetricsController     *http.MetricsController
+	SelfCheckController   *http.SelfCheckController
+	CacheController       *http.CacheController
+	BreakerController     *http.BreakerController
+	FeatureController     *http.FeatureController
+	AdminController       *http.AdminController
+	DatabaseController    *http.DatabaseController
+	ScheduleController    *http.ScheduleController
+	ReleaseNoteController *http.ReleaseNoteController
+	// GraphQL answers the nested queries of /graphql
+	GraphQL *graph.Handler
+
+	// Auth checks API keys and roles; nil leaves every route open
+	Auth *http.AuthMiddleware
+	// Signature checks requests signed by the coordinator; nil ignores signatures
+	Signature *http.SignatureMiddleware
+	// RateLimit applies to every request and CrawlRateLimit also to operator routes; nil disables them
+	RateLimit      *http.RateLimiter
+	CrawlRateLimit *http.RateLimiter
+	// Debug pretty-prints JSON responses of debug requests; nil never does
+	Debug *http.DebugMode
+	// CrawlRuns records every crawl endpoint call in crawl_runs; nil records nothing
+	CrawlRuns *http.CrawlRunRecorder
 }
 
 func (c *RouteConfig) Setup() *chi.Mux {
 	// c.SetupGuestRoute()
 
+	// The root router serves every path, the readiness probe included, so all responses go
+	// through the same request ID, error format, logging and recovery
+	root := chi.NewRouter()
+	// RequestID goes first so error responses and request logs carry the ID
+	root.Use(middleware.RequestID)
+	// Debug responses wrap error responses too, so they also get the meta section
+	root.Use(c.Debug.DebugResponses)
+	root.Use(http.ErrorResponses)
+	root.Use(middleware.Logger)
+	root.Use(middleware.Recoverer)
+	root.Use(middleware.Timeout(10000000 * time.Second))
+
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.Timeout(10000000 * time.Second))
+
+	// Every route needs at least a reader key; crawl triggers and calls that reach GitHub
+	// need an operator key, coordinator tuning, the worker list and the crawl history need an
+	// admin key
+	// A signed request is authenticated as an operator before API keys are checked
+	r.Use(c.Signature.Verify)
+	r.Use(c.Auth.Require(auth.RoleReader))
+	// Limits run after authentication so clients are counted by API key
+	r.Use(c.RateLimit.Limit)
+	operator := chi.Chain(c.Signature.Require, c.Auth.Require(auth.RoleOperator), c.CrawlRateLimit.Limit).Handler
+	// crawl is an operator route that also goes in the crawl history, refused while the commit
+	// queue is saturated
+	crawl := chi.Chain(c.Signature.Require, c.Auth.Require(auth.RoleOperator), c.CrawlRateLimit.Limit,
+		c.HealthController.Backpressure, c.CrawlRuns.Record).Handler
+	admin := c.Auth.Require(auth.RoleAdmin)
 
 	r.Route("/api/repos", func(r chi.Router) {
-		r.Get("/crawl", c.RepoController.CrawlAllRepos)
+		r.With(crawl).Get("/crawl", c.RepoController.CrawlAllRepos)
+		r.Get("/summary", c.StatsController.RepoSummary)
+		r.With(operator).Post("/enrich", c.RepoController.EnrichAllRepos)
 		r.Route("/{repoID}", func(r chi.Router) {
 			// r.Use(c.RepoController.RepoCtx)
 			r.Get("/", c.RepoController.GetRepo)
+			r.With(operator).Get("/branches", c.RepoController.GetBranches)
+			r.With(operator).Post("/enrich", c.RepoController.EnrichRepo)
+			r.With(operator).Post("/restore", c.RepoController.RestoreRepo)
+			r.Get("/policy", c.RepoPolicyController.GetPolicy)
+			r.With(admin).Put("/policy", c.RepoPolicyController.UpdatePolicy)
+			r.With(admin).Delete("/policy", c.RepoPolicyController.DeletePolicy)
+			r.Get("/feed", c.FeedController.RepoFeed)
+			r.Get("/commits", c.CommitController.ListRepoCommits)
 
 		})
 
 	})
 	r.Route("/api/releases", func(r chi.Router) {
-		r.Get("/crawl", c.ReleaseController.CrawlAllReleases)
+		r.With(crawl).Get("/crawl", c.ReleaseController.CrawlAllReleases)
+		r.Get("/summary", c.StatsController.ReleaseSummary)
 		r.Route("/{releaseID}", func(r chi.Router) {
 			r.Get("/", c.ReleaseController.GetRelease)
-			r.Get("/commits", c.CommitController.CrawlCommitsByRelease)
+			r.Get("/structured", c.ReleaseNoteController.GetStructured)
+			r.With(crawl).Get("/commits", c.CommitController.CrawlCommitsByRelease)
 		})
 	})
 
 	r.Route("/api/commits", func(r chi.Router) {
-		r.Get("/crawl", c.CommitController.CrawlAllCommits)
+		r.With(crawl).Get("/crawl", c.CommitController.CrawlAllCommits)
+		r.Get("/summary", c.StatsController.CommitSummary)
 		r.Route("/{commitID}", func(r chi.Router) {
 			r.Get("/", c.CommitController.GetCommit)
 		})
 	})
+
+	r.Route("/api/tags", func(r chi.Router) {
+		r.With(crawl).Get("/crawl", c.TagController.CrawlAllTags)
+	})
+
+	r.With(crawl).Post("/api/orgs/{org}/crawl", c.OrgController.CrawlOrgRepos)
+
+	r.Get("/ws/metrics", c.MetricsController.StreamMetrics)
+	r.Get("/api/stats", c.StatsController.GetStats)
+	r.Get("/api/visits", c.VisitController.ListVisits)
+	// Crawl runs name the API keys that triggered them
+	r.With(admin).Get("/api/crawl-runs", c.CrawlRunController.ListCrawlRuns)
+	r.Get("/api/errors", c.CrawlErrorController.ListCrawlErrors)
+	r.With(operator).Post("/api/errors/retry", c.CrawlErrorController.RetryCrawlErrors)
+	r.Get("/api/export/{dataset}", c.ExportController.Export)
+	r.With(operator).Post("/api/onboard", c.OnboardController.Onboard)
+	r.With(operator).Post("/api/onboard/bulk", c.OnboardController.BulkOnboard)
+
+	r.Route("/api/watchlists", func(r chi.Router) {
+		r.Get("/", c.WatchlistController.ListWatchlists)
+		r.With(operator).Post("/", c.WatchlistController.CreateWatchlist)
+		r.Route("/{watchlistID}", func(r chi.Router) {
+			r.Get("/", c.WatchlistController.GetWatchlist)
+			r.Get("/digest", c.WatchlistController.GetDigest)
+			r.With(operator).Post("/digest", c.WatchlistController.SendDigest)
+			r.Get("/feed", c.FeedController.WatchlistFeed)
+			r.Get("/keywords", c.WatchlistController.ListKeywords)
+			r.With(operator).Post("/keywords", c.WatchlistController.AddKeyword)
+			r.With(operator).Delete("/keywords/{keywordID}", c.WatchlistController.DeleteKeyword)
+		})
+	})
+
+	r.Get("/api/schedule", c.ScheduleController.ListSchedules)
+	r.With(admin).Put("/api/schedule/{name}", c.ScheduleController.UpdateSchedule)
+	r.Get("/api/schedule/calendar", c.ScheduleController.Calendar)
+
+	r.Route("/api/jobs", func(r chi.Router) {
+		r.Get("/", c.JobController.ListJobs)
+		r.Get("/{jobID}", c.JobController.GetJob)
+	})
+	r.With(admin).Get("/api/workers", c.JobController.ListWorkers)
+	r.With(admin).Get("/api/budgets", c.JobController.ListBudgets)
+	r.With(admin).Get("/api/admin/config", c.AdminController.GetConfig)
+	r.With(admin).Get("/api/admin/database", c.DatabaseController.DatabaseStats)
+	r.Get("/api/cache", c.CacheController.CacheStats)
+	r.Get("/api/breakers/repos", c.BreakerController.RepoBreakers)
+	r.Get("/api/features", c.FeatureController.GetFeatures)
+	r.Post("/graphql", c.GraphQL.ServeHTTP)
+
+	if c.CoordinatorController != nil {
+		r.Route("/api/coordinator", func(r chi.Router) {
+			r.Get("/dry-run", c.CoordinatorController.DryRun)
+			r.With(operator).Post("/stages/{stage}/run", c.CoordinatorController.RunStage)
+			r.Get("/stages/{stage}/data", c.CoordinatorController.StageData)
+			r.With(admin).Put("/stability-threshold", c.CoordinatorController.SetStabilityThreshold)
+			r.With(admin).Put("/stages/{stage}/stability-threshold", c.CoordinatorController.SetStageStabilityThreshold)
+			r.Get("/runs", c.CoordinatorController.Runs)
+			r.Get("/metrics", c.CoordinatorController.Metrics)
+		})
+	}
+
+	if c.AlertController != nil {
+		r.Get("/api/alerts", c.AlertController.ListAlerts)
+	}
+
+	if c.BenchController != nil {
+		r.With(operator).Post("/api/bench", c.BenchController.RunBench)
+	}
+	if c.ExperimentController != nil {
+		r.With(admin).Post("/api/experiments/batch", c.ExperimentController.RunBatchExperiment)
+	}
+	r.With(operator).Get("/api/scrape/selfcheck", c.SelfCheckController.SelfCheck)
+
+	// The readiness probe answers without an API key, so it stays out of the auth middlewares
+	root.Get("/readyz", c.HealthController.Readyz)
+	root.Mount("/", r)
+	return root
+}
+
+// SetupCoordinatorMetrics serves the coordinator's /metrics and /healthz on their own listener,
+// so the coordinator can be scraped and probed without going through the crawler API
+func SetupCoordinatorMetrics(controller *http.CoordinatorController) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(http.ErrorResponses)
+	r.Use(middleware.Recoverer)
+	r.Get("/metrics", controller.PrometheusMetrics)
+	r.Get("/healthz", controller.Healthz)
 	return r
 }
@@ -0,0 +1,101 @@
+// Package idgen generates the primary keys of crawled data in the application instead of the
+// database sequences, so several writers can insert into one database, or into databases that
+// are merged later, and an exported row keeps its ID when imported elsewhere.
+package idgen
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// StrategyDatabase leaves IDs to the database sequences
+	StrategyDatabase = "database"
+	// StrategySnowflake generates time-ordered 64-bit IDs unique per node
+	StrategySnowflake = "snowflake"
+)
+
+// Generator hands out new primary keys. A nil Generator leaves them to the database.
+type Generator interface {
+	NextID() int64
+}
+
+// New returns the generator of strategy, or nil for StrategyDatabase
+func New(strategy string, node int64) (Generator, error) {
+	switch strategy {
+	case "", StrategyDatabase:
+		return nil, nil
+	case StrategySnowflake:
+		return NewSnowflake(node)
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q, expected %s or %s", strategy, StrategyDatabase, StrategySnowflake)
+	}
+}
+
+// Next returns a new ID from g, or 0 when g is nil so the database assigns one on insert
+func Next(g Generator) int64 {
+	if g == nil {
+		return 0
+	}
+	return g.NextID()
+}
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	// MaxNode is the highest node number of a snowflake generator
+	MaxNode     = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// snowflakeEpoch is the start of the timestamps, which last about 69 years from it
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates IDs made of the milliseconds since snowflakeEpoch, the node number and a
+// sequence within the millisecond, so up to 4096 IDs per millisecond and node. Every writer of
+// a database needs its own node number.
+type Snowflake struct {
+	node int64
+
+	mutex    sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake creates the generator of node, from 0 to MaxNode
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("snowflake node %d out of range 0-%d", node, MaxNode)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// NextID returns an ID greater than every one this generator returned before. It waits for
+// the next millisecond when the sequence runs out, and for the clock to catch up when it
+// moved backwards.
+func (s *Snowflake) NextID() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Since(snowflakeEpoch).Milliseconds()
+	for now < s.last {
+		time.Sleep(time.Duration(s.last-now) * time.Millisecond)
+		now = time.Since(snowflakeEpoch).Milliseconds()
+	}
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			for now <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = now
+
+	return now<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"crawler/baseline/internal/entity"
+	"strconv"
+)
+
+// keySchema is the Avro schema of the record keys, the ID that orders the records of an entity
+const keySchema = `"string"`
+
+// Avro schemas of the published values, matching the JSON of RepoMessage, ReleaseMessage and
+// CommitMessage. Times are in milliseconds since the epoch and unknown times and numbers are -1, so no
+// field is a union.
+const (
+	repoSchema = `{"type":"record","name":"Repo","namespace":"crawler","fields":[` +
+		`{"name":"id","type":"long"},{"name":"owner","type":"string"},{"name":"name","type":"string"},` +
+		`{"name":"defaultBranch","type":"string"}]}`
+	releaseSchema = `{"type":"record","name":"Release","namespace":"crawler","fields":[` +
+		`{"name":"id","type":"long"},{"name":"repoID","type":"long"},{"name":"tag","type":"string"},` +
+		`{"name":"title","type":"string"},{"name":"author","type":"string"},{"name":"prerelease","type":"boolean"},` +
+		`{"name":"publishedAtMs","type":"long"},{"name":"content","type":"string"}]}`
+	commitSchema = `{"type":"record","name":"Commit","namespace":"crawler","fields":[` +
+		`{"name":"id","type":"long"},{"name":"releaseID","type":"long"},{"name":"hash","type":"string"},` +
+		`{"name":"message","type":"string"},{"name":"filesChanged","type":"int"},` +
+		`{"name":"additions","type":"int"},{"name":"deletions","type":"int"}]}`
+)
+
+// RepoMessage is a created repository, keyed by its ID
+type RepoMessage struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	Name          string `json:"name"`
+	DefaultBranch string `json:"defaultBranch"`
+}
+
+// ReleaseMessage is a created release, keyed by its repository so the releases of a repository
+// stay in order
+type ReleaseMessage struct {
+	ID            int64  `json:"id"`
+	RepoID        int64  `json:"repoID"`
+	Tag           string `json:"tag"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	Prerelease    bool   `json:"prerelease"`
+	PublishedAtMs int64  `json:"publishedAtMs"`
+	Content       string `json:"content"`
+}
+
+// CommitMessage is a created commit, keyed by its release; the stats are -1 when not scraped
+type CommitMessage struct {
+	ID           int64  `json:"id"`
+	ReleaseID    int64  `json:"releaseID"`
+	Hash         string `json:"hash"`
+	Message      string `json:"message"`
+	FilesChanged int    `json:"filesChanged"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+}
+
+// PublishRepos publishes created repositories to the repos topic; a nil producer publishes nothing
+func (p *Producer) PublishRepos(repos []entity.Repository) {
+	if p == nil {
+		return
+	}
+	for _, repo := range repos {
+		p.publish(p.settings.Topics.Repos, repoSchema, strconv.FormatInt(repo.ID, 10), RepoMessage{
+			ID:            repo.ID,
+			Owner:         repo.UserName,
+			Name:          repo.RepoName,
+			DefaultBranch: repo.DefaultBranch,
+		})
+	}
+}
+
+// PublishReleases publishes created releases to the releases topic; a nil producer publishes nothing
+func (p *Producer) PublishReleases(releases []entity.Release) {
+	if p == nil {
+		return
+	}
+	for _, release := range releases {
+		message := ReleaseMessage{
+			ID:            release.ID,
+			RepoID:        release.RepoID,
+			Tag:           release.TagName,
+			Title:         release.Title,
+			Author:        release.Author,
+			Prerelease:    release.Prerelease,
+			PublishedAtMs: -1,
+			Content:       release.Content,
+		}
+		if release.PublishedAt != nil {
+			message.PublishedAtMs = release.PublishedAt.UnixMilli()
+		}
+		p.publish(p.settings.Topics.Releases, releaseSchema, strconv.FormatInt(release.RepoID, 10), message)
+	}
+}
+
+// PublishCommits publishes created commits to the commits topic; a nil producer publishes nothing
+func (p *Producer) PublishCommits(commits []entity.Commit) {
+	if p == nil {
+		return
+	}
+	for _, commit := range commits {
+		p.publish(p.settings.Topics.Commits, commitSchema, strconv.FormatInt(commit.ReleaseID, 10), CommitMessage{
+			ID:           commit.ID,
+			ReleaseID:    commit.ReleaseID,
+			Hash:         commit.Hash,
+			Message:      commit.Message,
+			FilesChanged: orUnknown(commit.FilesChanged),
+			Additions:    orUnknown(commit.Additions),
+			Deletions:    orUnknown(commit.Deletions),
+		})
+	}
+}
+
+func orUnknown(value *int) int {
+	if value == nil {
+		return -1
+	}
+	return *value
+}
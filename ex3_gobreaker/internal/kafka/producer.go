@@ -0,0 +1,317 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formats of the published values
+const (
+	FormatJSON = "json"
+	// FormatAvro has the REST proxy encode the values with the schemas of schemas.go and register
+	// them in the schema registry
+	FormatAvro = "avro"
+)
+
+// Settings is the "kafka" config section; a zero value keeps the default of its field
+type Settings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// RESTProxy is the URL of the Kafka REST proxy (v2 API) the records are produced through
+	RESTProxy string `mapstructure:"rest_proxy" json:"rest_proxy"`
+	// Format is FormatJSON (default) or FormatAvro
+	Format string `mapstructure:"format" json:"format"`
+	Topics Topics `mapstructure:"topics" json:"topics"`
+	// BatchSize is how many records of a topic are produced in one request, 100 by default
+	BatchSize int `mapstructure:"batch_size" json:"batch_size"`
+	// FlushInterval is how long a record waits for its batch to fill, 1s by default
+	FlushInterval time.Duration `mapstructure:"flush_interval" json:"flush_interval"`
+	// BufferSize is how many records wait to be produced; records published while it is full
+	// are dropped. 10000 by default
+	BufferSize int `mapstructure:"buffer_size" json:"buffer_size"`
+	// MaxAttempts bounds the requests producing a batch, 3 by default
+	MaxAttempts int `mapstructure:"max_attempts" json:"max_attempts"`
+	// Timeout bounds each request to the REST proxy, 10s by default
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+}
+
+// Topics are the topics each kind of entity is published to; an empty topic publishes nothing
+type Topics struct {
+	Repos    string `mapstructure:"repos" json:"repos"`
+	Releases string `mapstructure:"releases" json:"releases"`
+	Commits  string `mapstructure:"commits" json:"commits"`
+}
+
+// Validate rejects an unknown format, negative sizes and durations, and an enabled producer
+// without a REST proxy
+func (s Settings) Validate() error {
+	switch s.Format {
+	case "", FormatJSON, FormatAvro:
+	default:
+		return fmt.Errorf("unknown format %q, expected %s or %s", s.Format, FormatJSON, FormatAvro)
+	}
+	if s.BatchSize < 0 || s.BufferSize < 0 || s.MaxAttempts < 0 {
+		return errors.New("sizes must not be negative")
+	}
+	if s.FlushInterval < 0 || s.Timeout < 0 {
+		return errors.New("durations must not be negative")
+	}
+	if s.Enabled {
+		if s.RESTProxy == "" {
+			return errors.New("rest_proxy is required when enabled")
+		}
+		if _, err := url.ParseRequestURI(s.RESTProxy); err != nil {
+			return fmt.Errorf("rest_proxy: %w", err)
+		}
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left zero
+func (s Settings) WithDefaults() Settings {
+	if s.Format == "" {
+		s.Format = FormatJSON
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = 100
+	}
+	if s.FlushInterval == 0 {
+		s.FlushInterval = time.Second
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 10000
+	}
+	if s.MaxAttempts == 0 {
+		s.MaxAttempts = 3
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 10 * time.Second
+	}
+	return s
+}
+
+// Stats counts the records of the producer since it started
+type Stats struct {
+	Published int64 `json:"published"`
+	// Dropped counts the records published while the buffer was full or once stopped
+	Dropped int64 `json:"dropped"`
+	// Failed counts the records of the batches the REST proxy did not accept
+	Failed int64 `json:"failed"`
+}
+
+// record is a value waiting to be produced to topic
+type record struct {
+	topic  string
+	schema string
+	key    string
+	value  interface{}
+}
+
+// Producer publishes the crawled entities to Kafka in the background, through a Kafka REST
+// proxy. Publishing never blocks the crawl: records wait in a bounded buffer and are produced in
+// batches by topic. Delivery is best effort; records still buffered when the process dies are lost.
+type Producer struct {
+	settings Settings
+	log      *logrus.Logger
+	client   *http.Client
+
+	// mutex keeps records from being published while the channel is closed
+	mutex   sync.RWMutex
+	stopped bool
+	records chan record
+	done    chan struct{}
+
+	published atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+func NewProducer(log *logrus.Logger, settings Settings) *Producer {
+	settings = settings.WithDefaults()
+	return &Producer{
+		settings: settings,
+		log:      log,
+		client:   &http.Client{Timeout: settings.Timeout},
+		records:  make(chan record, settings.BufferSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start produces the buffered records until Stop is called
+func (p *Producer) Start() {
+	go p.run()
+}
+
+// Stop produces the records still buffered and stops the producer
+func (p *Producer) Stop() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.records)
+	}
+	p.mutex.Unlock()
+	<-p.done
+}
+
+// Stats returns the record counters of the producer
+func (p *Producer) Stats() Stats {
+	return Stats{
+		Published: p.published.Load(),
+		Dropped:   p.dropped.Load(),
+		Failed:    p.failed.Load(),
+	}
+}
+
+// publish buffers a record, dropping it when the buffer is full or the producer is stopped
+func (p *Producer) publish(topic string, schema string, key string, value interface{}) {
+	if topic == "" {
+		return
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.stopped {
+		p.dropped.Add(1)
+		return
+	}
+	select {
+	case p.records <- record{topic: topic, schema: schema, key: key, value: value}:
+	default:
+		if p.dropped.Add(1) == 1 {
+			p.log.WithField("topic", topic).Warn("Kafka buffer full, dropping records")
+		}
+	}
+}
+
+func (p *Producer) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.settings.FlushInterval)
+	defer ticker.Stop()
+
+	batches := make(map[string][]record)
+	for {
+		select {
+		case r, ok := <-p.records:
+			if !ok {
+				for topic, batch := range batches {
+					p.produce(topic, batch)
+				}
+				return
+			}
+			batches[r.topic] = append(batches[r.topic], r)
+			if len(batches[r.topic]) >= p.settings.BatchSize {
+				p.produce(r.topic, batches[r.topic])
+				delete(batches, r.topic)
+			}
+		case <-ticker.C:
+			for topic, batch := range batches {
+				p.produce(topic, batch)
+				delete(batches, topic)
+			}
+		}
+	}
+}
+
+// produce sends a batch of one topic to the REST proxy, retrying failed requests
+func (p *Producer) produce(topic string, batch []record) {
+	body, contentType, err := p.encode(batch)
+	if err == nil {
+		backoff := 500 * time.Millisecond
+		for attempt := 1; ; attempt++ {
+			err = p.send(topic, body, contentType)
+			if err == nil || attempt >= p.settings.MaxAttempts {
+				break
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	if err != nil {
+		p.failed.Add(int64(len(batch)))
+		p.log.WithError(err).WithFields(logrus.Fields{
+			"topic":   topic,
+			"records": len(batch),
+		}).Error("Error producing records to Kafka")
+		return
+	}
+	p.published.Add(int64(len(batch)))
+}
+
+// encode builds the body of a v2 produce request; Avro requests carry the schemas, which the
+// proxy registers and caches
+func (p *Producer) encode(batch []record) ([]byte, string, error) {
+	type proxyRecord struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	request := struct {
+		KeySchema   string        `json:"key_schema,omitempty"`
+		ValueSchema string        `json:"value_schema,omitempty"`
+		Records     []proxyRecord `json:"records"`
+	}{
+		Records: make([]proxyRecord, len(batch)),
+	}
+	for i, r := range batch {
+		request.Records[i] = proxyRecord{Key: r.key, Value: r.value}
+	}
+
+	contentType := "application/vnd.kafka.json.v2+json"
+	if p.settings.Format == FormatAvro {
+		contentType = "application/vnd.kafka.avro.v2+json"
+		request.KeySchema = keySchema
+		request.ValueSchema = batch[0].schema
+	}
+	body, err := json.Marshal(request)
+	return body, contentType, err
+}
+
+func (p *Producer) send(topic string, body []byte, contentType string) error {
+	endpoint := strings.TrimRight(p.settings.RESTProxy, "/") + "/topics/" + url.PathEscape(topic)
+	ctx, cancel := context.WithTimeout(context.Background(), p.settings.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("rest proxy answered %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	// The proxy answers 200 with a per-record error when a partition rejected a record
+	var response struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil
+	}
+	for _, offset := range response.Offsets {
+		if offset.Error != "" {
+			return fmt.Errorf("rest proxy rejected a record: %s", offset.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package model
+
+// BenchRequest selects the deployments to compare and the synthetic workload replayed against each.
+// Empty fields use the "bench" section of the config.
+type BenchRequest struct {
+	Targets     []string `json:"targets"`
+	Requests    int      `json:"requests"`
+	Concurrency int      `json:"concurrency"`
+	Seed        int64    `json:"seed"`
+	// MaxID bounds the repository, release and commit IDs requested
+	MaxID int `json:"maxID"`
+	// IncludeCrawl adds crawl triggers to the workload; they call GitHub and are off by default
+	IncludeCrawl bool `json:"includeCrawl"`
+}
+
+type BenchReport struct {
+	Requests    int                 `json:"requests"`
+	Concurrency int                 `json:"concurrency"`
+	Seed        int64               `json:"seed"`
+	Targets     []BenchTargetResult `json:"targets"`
+	// Fastest is the reachable target with the highest throughput
+	Fastest string `json:"fastest,omitempty"`
+}
+
+type BenchTargetResult struct {
+	Name       string                 `json:"name"`
+	BaseURL    string                 `json:"baseURL"`
+	Reachable  bool                   `json:"reachable"`
+	Requests   int                    `json:"requests"`
+	Errors     int                    `json:"errors"`
+	ErrorRate  float64                `json:"errorRate"`
+	DurationMs int64                  `json:"durationMs"`
+	Throughput float64                `json:"throughput"`
+	Latency    BenchLatency           `json:"latency"`
+	Operations []BenchOperationResult `json:"operations"`
+}
+
+type BenchOperationResult struct {
+	Operation string       `json:"operation"`
+	Requests  int          `json:"requests"`
+	Errors    int          `json:"errors"`
+	Latency   BenchLatency `json:"latency"`
+}
+
+type BenchLatency struct {
+	P50Ms int64 `json:"p50Ms"`
+	P95Ms int64 `json:"p95Ms"`
+	P99Ms int64 `json:"p99Ms"`
+	MaxMs int64 `json:"maxMs"`
+}
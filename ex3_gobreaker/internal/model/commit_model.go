@@ -1,19 +1,58 @@
 package model
 
+import "time"
+
 type CommitResponse struct {
-	ID        int64  `json:"id"`
-	Hash      string `json:"hash"`
-	Message   string `json:"message"`
-	ReleaseID int64  `json:"releaseID"`
+	ID           int64  `json:"id"`
+	Hash         string `json:"hash"`
+	Message      string `json:"message"`
+	FilesChanged *int   `json:"filesChanged,omitempty"`
+	Additions    *int   `json:"additions,omitempty"`
+	Deletions    *int   `json:"deletions,omitempty"`
+	ReleaseID    int64  `json:"releaseID"`
+	// Existing is set by batch creates for commits that were already stored
+	Existing bool `json:"existing,omitempty"`
+}
+
+// RepoCommitResponse is a commit listed for a repository, with the release it was crawled from
+type RepoCommitResponse struct {
+	CommitResponse
+	Tag         string     `json:"tag"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
 }
 
 type CreateCommitRequest struct {
-	Hash      string `json:"hash"`
-	Message   string `json:"message"`
-	ReleaseID int64  `json:"releaseID"`
+	Hash         string `json:"hash"`
+	Message      string `json:"message"`
+	FilesChanged *int   `json:"filesChanged"`
+	Additions    *int   `json:"additions"`
+	Deletions    *int   `json:"deletions"`
+	ReleaseID    int64  `json:"releaseID"`
+	// ID is set by the scrape process publishing the commit, as CreateRepoRequest.ID
+	ID int64 `json:"id,omitempty"`
 }
 
 type CommitData struct {
 	Hash    string `json:"hash"`
 	Message string `json:"message"`
 }
+
+// CommitStats is the diff summary shown on a commit page
+type CommitStats struct {
+	FilesChanged int
+	Additions    int
+	Deletions    int
+}
+
+// CommitCrawlProgress is a line of a streamed commit crawl, sent once a release is done
+type CommitCrawlProgress struct {
+	// Progress is "<releases done>/<releases in total>"
+	Progress     string `json:"progress"`
+	ReleaseID    int64  `json:"releaseID"`
+	Tag          string `json:"tag"`
+	Repo         string `json:"repo,omitempty"`
+	Skipped      bool   `json:"skipped,omitempty"`
+	CommitsFound int    `json:"commitsFound"`
+	CommitsSaved int    `json:"commitsSaved"`
+	Error        string `json:"error,omitempty"`
+}
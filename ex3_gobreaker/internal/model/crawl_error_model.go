@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type CrawlErrorResponse struct {
+	ID            int64     `json:"id"`
+	EntityType    string    `json:"entityType"`
+	EntityID      int64     `json:"entityID"`
+	Message       string    `json:"message"`
+	HTTPStatus    int       `json:"httpStatus,omitempty"`
+	RetryCount    int       `json:"retryCount"`
+	FirstFailedAt time.Time `json:"firstFailedAt"`
+	LastFailedAt  time.Time `json:"lastFailedAt"`
+}
+
+// RetryCrawlErrorsRequest selects the crawl errors to retry; no IDs retries every error of the entity type
+type RetryCrawlErrorsRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type RetryCrawlErrorsResponse struct {
+	JobID   int64 `json:"jobID"`
+	Retried int   `json:"retried"`
+}
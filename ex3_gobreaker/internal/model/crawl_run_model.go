@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type CrawlRunResponse struct {
+	ID         int64     `json:"id"`
+	Source     string    `json:"source"`
+	Endpoint   string    `json:"endpoint"`
+	APIKey     string    `json:"apiKey,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	StatusCode int       `json:"statusCode"`
+	Found      int       `json:"found"`
+	Saved      int       `json:"saved"`
+	Errored    int       `json:"errored"`
+	Error      string    `json:"error,omitempty"`
+}
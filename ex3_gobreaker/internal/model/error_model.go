@@ -0,0 +1,10 @@
+package model
+
+// ErrorResponse is the body of every API error. Code is a machine-readable snake_case
+// version of the HTTP status, such as "bad_request" or "not_found".
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
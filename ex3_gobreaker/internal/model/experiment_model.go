@@ -0,0 +1,38 @@
+package model
+
+// BatchExperimentRequest selects the workload size and the worker counts and batch sizes to compare.
+// Empty fields use the "experiment" section of the config.
+type BatchExperimentRequest struct {
+	Items      int   `json:"items"`
+	Workers    []int `json:"workers"`
+	BatchSizes []int `json:"batchSizes"`
+	// Apply also writes the recommendation to the commit queue settings used on the next start
+	Apply bool `json:"apply"`
+}
+
+// BatchTrial is the outcome of one worker count and batch size combination
+type BatchTrial struct {
+	Workers    int     `json:"workers"`
+	BatchSize  int     `json:"batchSize"`
+	Items      int     `json:"items"`
+	Batches    int     `json:"batches"`
+	Errors     int     `json:"errors"`
+	DurationMs int64   `json:"durationMs"`
+	Throughput float64 `json:"throughput"`
+	BatchP50Ms int64   `json:"batchP50Ms"`
+	BatchP95Ms int64   `json:"batchP95Ms"`
+	BatchP99Ms int64   `json:"batchP99Ms"`
+}
+
+type BatchRecommendation struct {
+	Workers    int     `json:"workers"`
+	BatchSize  int     `json:"batchSize"`
+	Throughput float64 `json:"throughput"`
+}
+
+type BatchExperimentResponse struct {
+	Trials         []BatchTrial        `json:"trials"`
+	Recommendation BatchRecommendation `json:"recommendation"`
+	ConfigFile     string              `json:"configFile"`
+	Applied        bool                `json:"applied"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// ReleaseFeed is a subscribable list of releases of one repository or watchlist, newest first
+type ReleaseFeed struct {
+	ID      string
+	Title   string
+	Link    string
+	Updated time.Time
+	Items   []FeedItem
+}
+
+type FeedItem struct {
+	ID         string
+	Repo       string
+	TagName    string
+	Title      string
+	Link       string
+	Author     string
+	Content    string
+	Prerelease bool
+	// Published is the release's publish date, or when it was first stored if unknown
+	Published time.Time
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// LiveMetrics is one message of the live metrics stream
+type LiveMetrics struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Queues    []LiveQueueMetrics `json:"queues"`
+	Scrape    LiveScrapeMetrics  `json:"scrape"`
+}
+
+type LiveQueueMetrics struct {
+	Name           string  `json:"name"`
+	Size           int     `json:"size"`
+	Processing     int     `json:"processing"`
+	Enqueued       int64   `json:"enqueued"`
+	Dequeued       int64   `json:"dequeued"`
+	MaxQueueLength int     `json:"maxQueueLength"`
+	Created        int64   `json:"created"`
+	Skipped        int64   `json:"skipped"`
+	Failed         int64   `json:"failed"`
+	Dropped        int64   `json:"dropped"`
+	Rejected       int64   `json:"rejected"`
+	Deduped        int64   `json:"deduped"`
+	EnqueueRate    float64 `json:"enqueueRate"`
+	DequeueRate    float64 `json:"dequeueRate"`
+}
+
+type LiveScrapeMetrics struct {
+	Requests    int64   `json:"requests"`
+	Errors      int64   `json:"errors"`
+	RequestRate float64 `json:"requestRate"`
+	ErrorRate   float64 `json:"errorRate"`
+}
@@ -1,15 +1,81 @@
 package model
 
+import "time"
+
 type ReleaseResponse struct {
-	ID      int64            `json:"id,omitempty"`
-	TagName string           `json:"tagName,omitempty"`
-	Content string           `json:"content,omitempty"`
-	RepoID  int64            `json:"repoID,omitempty"`
-	Commits []CommitResponse `json:"commits,omitempty"`
+	ID           int64                  `json:"id,omitempty"`
+	TagName      string                 `json:"tagName,omitempty"`
+	Content      string                 `json:"content,omitempty"`
+	Title        string                 `json:"title,omitempty"`
+	PublishedAt  *time.Time             `json:"publishedAt,omitempty"`
+	Author       string                 `json:"author,omitempty"`
+	Prerelease   bool                   `json:"prerelease"`
+	Assets       []ReleaseAssetResponse `json:"assets,omitempty"`
+	RepoID       int64                  `json:"repoID,omitempty"`
+	Status       string                 `json:"status,omitempty"`
+	TombstonedAt *time.Time             `json:"tombstonedAt,omitempty"`
+	Commits      []CommitResponse       `json:"commits,omitempty"`
+	// Existing is set by batch creates for releases that were already stored
+	Existing bool `json:"existing,omitempty"`
+}
+
+type ReleaseAssetResponse struct {
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	DownloadCount int64  `json:"downloadCount"`
 }
 
 type CreateReleaseRequest struct {
-	Content string `json:"content" validate:"required"`
-	RepoID  int64  `json:"repoID" validate:"required"`
-	TagName string `json:"tagName" validate:"required"`
+	Content     string                      `json:"content" validate:"required"`
+	RepoID      int64                       `json:"repoID" validate:"required"`
+	TagName     string                      `json:"tagName" validate:"required"`
+	Title       string                      `json:"title"`
+	PublishedAt *time.Time                  `json:"publishedAt"`
+	Author      string                      `json:"author"`
+	Prerelease  bool                        `json:"prerelease"`
+	Assets      []CreateReleaseAssetRequest `json:"assets"`
+	// ID is set by the scrape process publishing the release, as CreateRepoRequest.ID
+	ID int64 `json:"id,omitempty"`
+}
+
+type CreateReleaseAssetRequest struct {
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	DownloadCount int64  `json:"downloadCount"`
+}
+
+// ReleaseData is the scraped content and metadata of a single release
+type ReleaseData struct {
+	Content     string
+	Title       string
+	PublishedAt *time.Time
+	Author      string
+	Prerelease  bool
+	Assets      []CreateReleaseAssetRequest
+}
+
+// StructuredReleaseResponse is the notes of a release split into sections, with the issues and
+// pull requests they link and the users they mention
+type StructuredReleaseResponse struct {
+	ReleaseID       int64                      `json:"releaseID"`
+	TagName         string                     `json:"tagName"`
+	Features        []ReleaseNoteItemResponse  `json:"features"`
+	Fixes           []ReleaseNoteItemResponse  `json:"fixes"`
+	BreakingChanges []ReleaseNoteItemResponse  `json:"breakingChanges"`
+	Other           []ReleaseNoteItemResponse  `json:"other"`
+	References      []ReleaseReferenceResponse `json:"references"`
+	Mentions        []string                   `json:"mentions"`
+	// Stored is false for notes parsed for the request, of a release stored before parsing was added
+	Stored bool `json:"stored"`
+}
+
+type ReleaseNoteItemResponse struct {
+	Section string `json:"section,omitempty"`
+	Text    string `json:"text"`
+}
+
+type ReleaseReferenceResponse struct {
+	Repo   string `json:"repo,omitempty"`
+	Number int64  `json:"number"`
+	Type   string `json:"type,omitempty"`
 }
@@ -1,17 +1,101 @@
 package model
 
+import "time"
+
 type RepoResponse struct {
-	ID       int64  `json:"id,omitempty"`
-	UserName string `json:"userName,omitempty"`
-	RepoName string `json:"repoName,omitempty"`
+	ID            int64      `json:"id,omitempty"`
+	UserName      string     `json:"userName,omitempty"`
+	RepoName      string     `json:"repoName,omitempty"`
+	DefaultBranch string     `json:"defaultBranch,omitempty"`
+	Policy        string     `json:"policy,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	Stars         int64      `json:"stars,omitempty"`
+	Forks         int64      `json:"forks,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	Topics        []string   `json:"topics,omitempty"`
+	License       string     `json:"license,omitempty"`
+	EnrichedAt    *time.Time `json:"enrichedAt,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	RetryAt       *time.Time `json:"retryAt,omitempty"`
+	NextCrawlAt   *time.Time `json:"nextCrawlAt,omitempty"`
+	NotFoundCount int        `json:"notFoundCount,omitempty"`
+	Archived      bool       `json:"archived,omitempty"`
+	DeletedAt     *time.Time `json:"deletedAt,omitempty"`
+	// Existing is set by batch creates for repositories that were already tracked
+	Existing bool `json:"existing,omitempty"`
+}
+
+// RepoMetadata is the repository information shown on its GitHub front page
+type RepoMetadata struct {
+	Description string
+	Stars       int64
+	Forks       int64
+	Language    string
+	Topics      []string
+	License     string
+}
+
+type BranchesResponse struct {
+	DefaultBranch string   `json:"defaultBranch"`
+	Branches      []string `json:"branches"`
 }
 
 type CreateRepoRequest struct {
 	RepoName string `json:"repoName" validate:"required"`
 	UserName string `json:"userName" validate:"required"`
+	// ID is given by a scrape process to a row it publishes, for the persist process to store it
+	// with; zero lets the ID strategy assign one
+	ID int64 `json:"id,omitempty"`
 }
 
 type SearchRepoRequest struct {
 	RepoName string `json:"repoName" validate:"required"`
 	UserName string `json:"userName" validate:"required"`
 }
+
+// GitHubRepo is a repository as reported by the GitHub API
+type GitHubRepo struct {
+	UserName      string
+	RepoName      string
+	Private       bool
+	DefaultBranch string
+}
+
+type OnboardRequest struct {
+	URL    string `json:"url" validate:"required"`
+	Policy string `json:"policy"`
+}
+
+type OnboardResponse struct {
+	JobID  int64         `json:"jobID"`
+	Policy string        `json:"policy"`
+	Repo   *RepoResponse `json:"repo"`
+}
+
+// Results of onboarding a single repository
+const (
+	// OnboardResultQueued is a row of a bulk upload left to a job to look up, create and crawl
+	OnboardResultQueued    = "queued"
+	OnboardResultDuplicate = "duplicate"
+	OnboardResultInvalid   = "invalid"
+	OnboardResultNotFound  = "not_found"
+	OnboardResultPrivate   = "private"
+	OnboardResultFailed    = "failed"
+)
+
+type OnboardRowResult struct {
+	Row    int    `json:"row"`
+	URL    string `json:"url"`
+	Result string `json:"result"`
+	RepoID int64  `json:"repoID,omitempty"`
+	// JobID is the job onboarding a queued row
+	JobID   int64  `json:"jobID,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type BulkOnboardResponse struct {
+	JobIDs   []int64            `json:"jobIDs"`
+	Queued   int                `json:"queued"`
+	Rejected int                `json:"rejected"`
+	Rows     []OnboardRowResult `json:"rows"`
+}
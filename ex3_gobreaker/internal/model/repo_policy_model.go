@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Sources of the crawl policy of a repository
+const (
+	// RepoPolicySourceStored is a policy set through the API
+	RepoPolicySourceStored = "stored"
+	// RepoPolicySourceConfig is an entry of the repo_policies config section
+	RepoPolicySourceConfig = "config"
+	// RepoPolicySourceNone means the repository is crawled whole
+	RepoPolicySourceNone = "none"
+)
+
+// UpdateRepoPolicyRequest replaces the crawl policy of a repository; zero values leave the crawl unbounded
+type UpdateRepoPolicyRequest struct {
+	MaxReleases          int     `json:"maxReleases"`
+	MaxCommitsPerRelease int     `json:"maxCommitsPerRelease"`
+	SkipCommits          bool    `json:"skipCommits"`
+	RequestsPerMinute    float64 `json:"requestsPerMinute"`
+}
+
+// RepoPolicyResponse is the crawl policy applied to a repository and where it comes from
+type RepoPolicyResponse struct {
+	RepoID               int64      `json:"repoID"`
+	Repo                 string     `json:"repo"`
+	Source               string     `json:"source"`
+	MaxReleases          int        `json:"maxReleases"`
+	MaxCommitsPerRelease int        `json:"maxCommitsPerRelease"`
+	SkipCommits          bool       `json:"skipCommits"`
+	RequestsPerMinute    float64    `json:"requestsPerMinute"`
+	UpdatedAt            *time.Time `json:"updatedAt,omitempty"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// ScheduleResponse is the crawl calendar between From and To
+type ScheduleResponse struct {
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	Timezone  string           `json:"timezone"`
+	Blackouts []BlackoutPeriod `json:"blackouts"`
+	Runs      []ScheduledRun   `json:"runs"`
+}
+
+// BlackoutPeriod is a time range during which nothing is crawled
+type BlackoutPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ScheduledRun is an upcoming crawl: a coordinator cycle, a cron schedule run (kind
+// "schedule:<name>") or the decay re-crawl of a repository
+type ScheduledRun struct {
+	At   time.Time     `json:"at"`
+	Kind string        `json:"kind"`
+	Repo *RepoResponse `json:"repo,omitempty"`
+	// DueAt is set when a blackout window delays the run past when it was due
+	DueAt *time.Time `json:"dueAt,omitempty"`
+	// Skipped coordinator cycles and schedule runs fall in a blackout window and do not run
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// CrawlScheduleResponse is the cron schedule of a coordinator stage, or "all" for full cycles
+type CrawlScheduleResponse struct {
+	Name       string     `json:"name"`
+	Expression string     `json:"expression"`
+	NextRunAt  time.Time  `json:"nextRunAt"`
+	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
+	LastStatus string     `json:"lastStatus,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+}
+
+// UpdateScheduleRequest replaces the cron expression of a schedule
+type UpdateScheduleRequest struct {
+	Expression string `json:"expression"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// SelfCheckReport is the outcome of scraping the self-check target with every scraper
+type SelfCheckReport struct {
+	Repo       string            `json:"repo"`
+	Tag        string            `json:"tag"`
+	Passed     bool              `json:"passed"`
+	Failed     int               `json:"failed"`
+	CheckedAt  time.Time         `json:"checkedAt"`
+	DurationMs int64             `json:"durationMs"`
+	Checks     []SelfCheckResult `json:"checks"`
+}
+
+// SelfCheckResult is one scraper's part of the self-check. Values counts what its selectors
+// extracted; a check passes when it extracted something without error.
+type SelfCheckResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Passed      bool   `json:"passed"`
+	Values      int    `json:"values"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"durationMs"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+type StatsResponse struct {
+	Repos                  int64              `json:"repos"`
+	Releases               int64              `json:"releases"`
+	Commits                int64              `json:"commits"`
+	Tags                   int64              `json:"tags"`
+	ReposWithoutReleases   int64              `json:"reposWithoutReleases"`
+	ReleasesWithoutCommits int64              `json:"releasesWithoutCommits"`
+	TopReposByReleases     []RepoReleaseCount `json:"topReposByReleases"`
+	LastCrawl              CrawlTimes         `json:"lastCrawl"`
+	Database               DatabaseSize       `json:"database"`
+}
+
+type RepoReleaseCount struct {
+	RepoID   int64  `json:"repoID"`
+	Repo     string `json:"repo"`
+	Releases int64  `json:"releases"`
+}
+
+// CrawlTimes are the latest times data of each kind was stored
+type CrawlTimes struct {
+	Release  *time.Time `json:"release,omitempty"`
+	Enriched *time.Time `json:"enriched,omitempty"`
+	Visit    *time.Time `json:"visit,omitempty"`
+}
+
+// DatabaseSize is Postgres' own estimate of the database and table sizes
+type DatabaseSize struct {
+	TotalBytes int64       `json:"totalBytes"`
+	Tables     []TableSize `json:"tables"`
+}
+
+type TableSize struct {
+	Name          string `json:"name"`
+	Bytes         int64  `json:"bytes"`
+	EstimatedRows int64  `json:"estimatedRows"`
+}
+
+// DatasetSummary is a compact fingerprint of one dataset. The coordinator compares
+// summaries between crawls instead of whole crawl responses.
+type DatasetSummary struct {
+	Dataset  string                `json:"dataset"`
+	Count    int64                 `json:"count"`
+	Checksum string                `json:"checksum"`
+	Repos    map[int64]RepoSummary `json:"repos,omitempty"`
+}
+
+// RepoSummary is the per-repository part of a release or commit summary
+type RepoSummary struct {
+	Count     int64  `json:"count"`
+	LatestTag string `json:"latestTag,omitempty"`
+}
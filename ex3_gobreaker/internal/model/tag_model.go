@@ -0,0 +1,18 @@
+package model
+
+type TagResponse struct {
+	ID        int64  `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	CommitSHA string `json:"commitSHA,omitempty"`
+	RepoID    int64  `json:"repoID,omitempty"`
+	// Existing is set by batch creates for tags that were already stored
+	Existing bool `json:"existing,omitempty"`
+}
+
+type CreateTagRequest struct {
+	Name      string `json:"name" validate:"required"`
+	CommitSHA string `json:"commitSHA" validate:"required"`
+	RepoID    int64  `json:"repoID" validate:"required"`
+	// ID is set by the scrape process publishing the tag, as CreateRepoRequest.ID
+	ID int64 `json:"id,omitempty"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type VisitResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	Method     string    `json:"method"`
+	StatusCode int       `json:"statusCode"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+	VisitedAt  time.Time `json:"visitedAt"`
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+type WatchlistResponse struct {
+	ID    int64           `json:"id"`
+	Name  string          `json:"name"`
+	Repos []*RepoResponse `json:"repos"`
+}
+
+type CreateWatchlistRequest struct {
+	Name string `json:"name" validate:"required"`
+	// Repos are repository IDs or "owner/name" of tracked repositories
+	Repos []string `json:"repos"`
+}
+
+type KeywordResponse struct {
+	ID          int64     `json:"id"`
+	WatchlistID int64     `json:"watchlistID"`
+	Keyword     string    `json:"keyword"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type CreateKeywordRequest struct {
+	Keyword string `json:"keyword" validate:"required"`
+}
+
+// KeywordMatch is a new release or commit whose text contains a subscribed keyword
+type KeywordMatch struct {
+	Repo string `json:"repo"`
+	// Kind is "release" or "commit"
+	Kind string `json:"kind"`
+	// Ref is the tag of a release or the hash of a commit
+	Ref      string   `json:"ref"`
+	URL      string   `json:"url"`
+	Snippets []string `json:"snippets"`
+}
+
+// ReleaseDigest summarizes the releases of a watchlist detected in a time window
+type ReleaseDigest struct {
+	WatchlistID int64           `json:"watchlistID"`
+	Watchlist   string          `json:"watchlist"`
+	Since       time.Time       `json:"since"`
+	Until       time.Time       `json:"until"`
+	Releases    []DigestRelease `json:"releases"`
+	Markdown    string          `json:"markdown,omitempty"`
+}
+
+type DigestRelease struct {
+	Repo        string     `json:"repo"`
+	TagName     string     `json:"tagName"`
+	Title       string     `json:"title,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Prerelease  bool       `json:"prerelease"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	DetectedAt  time.Time  `json:"detectedAt"`
+	URL         string     `json:"url"`
+}
@@ -0,0 +1,330 @@
+// Package nats connects the scrape processes, which publish crawled batches, to the persist
+// processes, which store them, through a JetStream stream: a published batch is kept by the
+// server until a persist process acknowledges it, and delivered again when storing it fails.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrMaxPayload is returned for a message larger than the server accepts
+	ErrMaxPayload = natsgo.ErrMaxPayload
+	// ErrUnprocessable, wrapped by a handler of Consume, drops a message that no redelivery can
+	// store, such as one that does not decode
+	ErrUnprocessable = errors.New("nats: message cannot be processed")
+)
+
+// Settings is the "nats" config section; a zero value keeps the default of its field
+type Settings struct {
+	// URL is the server, "nats://host:port" or "tls://host:port"; nats://127.0.0.1:4222 by default
+	URL      string `mapstructure:"url" json:"url"`
+	User     string `mapstructure:"user" json:"user"`
+	Password string `mapstructure:"password" json:"password"`
+	Token    string `mapstructure:"token" json:"token"`
+	// Subject prefixes the subjects of the crawled batches, "<subject>.<kind>"; crawler.items by default
+	Subject string `mapstructure:"subject" json:"subject"`
+	// Stream is the JetStream stream keeping the batches until they are stored; CRAWLER_ITEMS by default
+	Stream string `mapstructure:"stream" json:"stream"`
+	// Queue is the durable consumer the persist processes share, each batch goes to one of them;
+	// persist by default
+	Queue string `mapstructure:"queue" json:"queue"`
+	// Workers is how many batches a persist process stores at once, 1 by default
+	Workers int `mapstructure:"workers" json:"workers"`
+	// Timeout bounds connecting and waiting for the server to acknowledge a batch, 5s by default
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+	// ReconnectWait is the delay between attempts to reconnect after losing the server, 2s by default
+	ReconnectWait time.Duration `mapstructure:"reconnect_wait" json:"reconnect_wait"`
+	// AckWait is how long the server waits for a persist process to store a batch before giving it
+	// to another one; a batch still being stored is extended. 1m by default.
+	AckWait time.Duration `mapstructure:"ack_wait" json:"ack_wait"`
+	// MaxDeliver is how many times a batch is delivered before it is dropped, 5 by default
+	MaxDeliver int `mapstructure:"max_deliver" json:"max_deliver"`
+	// RedeliverDelay is the wait before a batch that failed to be stored is delivered again, 10s by default
+	RedeliverDelay time.Duration `mapstructure:"redeliver_delay" json:"redeliver_delay"`
+}
+
+// Validate rejects a URL that is not nats:// or tls://, a subject, stream or queue that the
+// server does not accept, and negative sizes and durations
+func (s Settings) Validate() error {
+	if s.URL != "" {
+		parsed, err := url.Parse(s.URL)
+		if err != nil {
+			return fmt.Errorf("url: %w", err)
+		}
+		if (parsed.Scheme != "nats" && parsed.Scheme != "tls") || parsed.Host == "" {
+			return fmt.Errorf("url %q: expected nats://host:port or tls://host:port", s.URL)
+		}
+	}
+	if strings.ContainsAny(s.Subject, " \t*>") || strings.HasPrefix(s.Subject, ".") || strings.HasSuffix(s.Subject, ".") {
+		return fmt.Errorf("invalid subject %q", s.Subject)
+	}
+	if strings.ContainsAny(s.Stream, " \t.*>/\\") {
+		return fmt.Errorf("invalid stream %q", s.Stream)
+	}
+	if strings.ContainsAny(s.Queue, " \t.*>/\\") {
+		return fmt.Errorf("invalid queue %q", s.Queue)
+	}
+	if s.Workers < 0 || s.MaxDeliver < 0 {
+		return errors.New("workers and max_deliver must not be negative")
+	}
+	if s.Timeout < 0 || s.ReconnectWait < 0 || s.AckWait < 0 || s.RedeliverDelay < 0 {
+		return errors.New("durations must not be negative")
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left zero
+func (s Settings) WithDefaults() Settings {
+	if s.URL == "" {
+		s.URL = "nats://127.0.0.1:4222"
+	}
+	if s.Subject == "" {
+		s.Subject = "crawler.items"
+	}
+	if s.Stream == "" {
+		s.Stream = "CRAWLER_ITEMS"
+	}
+	if s.Queue == "" {
+		s.Queue = "persist"
+	}
+	if s.Workers == 0 {
+		s.Workers = 1
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 5 * time.Second
+	}
+	if s.ReconnectWait == 0 {
+		s.ReconnectWait = 2 * time.Second
+	}
+	if s.AckWait == 0 {
+		s.AckWait = time.Minute
+	}
+	if s.MaxDeliver == 0 {
+		s.MaxDeliver = 5
+	}
+	if s.RedeliverDelay == 0 {
+		s.RedeliverDelay = 10 * time.Second
+	}
+	return s
+}
+
+// SubjectOf is the subject the batches of kind are published on
+func (s Settings) SubjectOf(kind string) string {
+	return s.Subject + "." + kind
+}
+
+// Stats counts the messages and reconnections of a connection since it was opened
+type Stats struct {
+	Published  int64 `json:"published"`
+	Received   int64 `json:"received"`
+	Reconnects int64 `json:"reconnects"`
+	Connected  bool  `json:"connected"`
+	// Redelivered counts the received messages given back to the server to be delivered again
+	Redelivered int64 `json:"redelivered"`
+	// Dropped counts the received messages that will not be delivered again
+	Dropped int64 `json:"dropped"`
+}
+
+// Msg is a message received by Consume. Delivered is 1 the first time it is delivered.
+type Msg struct {
+	Subject   string
+	Data      []byte
+	Delivered int
+}
+
+// Conn is a connection to a NATS server with JetStream, reconnecting in the background when it
+// is lost. A message is acknowledged by the server when Publish returns, and stays in the stream
+// until a consumer stores it: delivery is at least once.
+type Conn struct {
+	settings Settings
+	log      *logrus.Logger
+	conn     *natsgo.Conn
+	js       jetstream.JetStream
+
+	mutex     sync.Mutex
+	consumers []jetstream.ConsumeContext
+
+	published   atomic.Int64
+	received    atomic.Int64
+	redelivered atomic.Int64
+	dropped     atomic.Int64
+}
+
+// Connect opens a connection to the server of settings, announcing itself as name, and creates
+// or updates the stream of the batches
+func Connect(log *logrus.Logger, settings Settings, name string) (*Conn, error) {
+	settings = settings.WithDefaults()
+	options := []natsgo.Option{
+		natsgo.Name(name),
+		natsgo.Timeout(settings.Timeout),
+		natsgo.ReconnectWait(settings.ReconnectWait),
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			log.WithError(err).WithField("url", settings.URL).Warn("Lost the NATS connection, reconnecting")
+		}),
+		natsgo.ReconnectHandler(func(*natsgo.Conn) {
+			log.WithField("url", settings.URL).Info("Reconnected to NATS")
+		}),
+	}
+	if settings.User != "" {
+		options = append(options, natsgo.UserInfo(settings.User, settings.Password))
+	}
+	if settings.Token != "" {
+		options = append(options, natsgo.Token(settings.Token))
+	}
+	conn, err := natsgo.Connect(settings.URL, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), settings.Timeout)
+		_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     settings.Stream,
+			Subjects: []string{settings.SubjectOf("*")},
+			// A batch is removed once stored
+			Retention: jetstream.WorkQueuePolicy,
+			Storage:   jetstream.FileStorage,
+		})
+		cancel()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: creating stream %s: %w", settings.Stream, err)
+	}
+	return &Conn{settings: settings, log: log, conn: conn, js: js}, nil
+}
+
+// Publish sends data on subject and waits for the server to store it in the stream
+func (c *Conn) Publish(ctx context.Context, subject string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.settings.Timeout)
+	defer cancel()
+	if _, err := c.js.Publish(ctx, subject, data); err != nil {
+		return err
+	}
+	c.published.Add(1)
+	return nil
+}
+
+// Consume hands the messages of the stream to handle, Workers at a time, through the durable
+// consumer Queue shared by every process consuming them, so each message goes to one of them.
+// A message is acknowledged when handle returns nil, and delivered again after RedeliverDelay
+// when it fails, up to MaxDeliver times; an error wrapping ErrUnprocessable drops it at once.
+func (c *Conn) Consume(handle func(Msg) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.settings.Timeout)
+	defer cancel()
+	consumer, err := c.js.CreateOrUpdateConsumer(ctx, c.settings.Stream, jetstream.ConsumerConfig{
+		Durable:       c.settings.Queue,
+		FilterSubject: c.settings.SubjectOf("*"),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       c.settings.AckWait,
+		MaxDeliver:    c.settings.MaxDeliver,
+		MaxAckPending: c.settings.Workers,
+	})
+	if err != nil {
+		return fmt.Errorf("nats: creating consumer %s: %w", c.settings.Queue, err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i := 0; i < c.settings.Workers; i++ {
+		// One message at a time per worker, so none waits in a buffer while its AckWait runs
+		consuming, err := consumer.Consume(func(msg jetstream.Msg) {
+			c.handle(msg, handle)
+		}, jetstream.PullMaxMessages(1))
+		if err != nil {
+			return fmt.Errorf("nats: consuming %s: %w", c.settings.Queue, err)
+		}
+		c.consumers = append(c.consumers, consuming)
+	}
+	return nil
+}
+
+// handle runs handle on msg, telling the server it is still being handled every half AckWait,
+// then acknowledges it or gives it back
+func (c *Conn) handle(msg jetstream.Msg, handle func(Msg) error) {
+	c.received.Add(1)
+	delivered := 1
+	if metadata, err := msg.Metadata(); err == nil {
+		delivered = int(metadata.NumDelivered)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.settings.AckWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				msg.InProgress()
+			case <-done:
+				return
+			}
+		}
+	}()
+	err := handle(Msg{Subject: msg.Subject(), Data: msg.Data(), Delivered: delivered})
+	close(done)
+
+	logger := c.log.WithFields(logrus.Fields{
+		"subject":   msg.Subject(),
+		"delivered": delivered,
+	})
+	var replyErr error
+	switch {
+	case err == nil:
+		replyErr = msg.Ack()
+	case errors.Is(err, ErrUnprocessable) || delivered >= c.settings.MaxDeliver:
+		c.dropped.Add(1)
+		logger.WithError(err).Error("Error handling NATS message, dropping it")
+		replyErr = msg.Term()
+	default:
+		c.redelivered.Add(1)
+		logger.WithError(err).Warn("Error handling NATS message, it will be delivered again")
+		replyErr = msg.NakWithDelay(c.settings.RedeliverDelay)
+	}
+	if replyErr != nil {
+		// The server delivers the message again once AckWait is over
+		logger.WithError(replyErr).Warn("Error replying to NATS message")
+	}
+}
+
+// Close stops consuming, waiting for the messages being handled, and closes the connection.
+// Messages received but not handled yet are delivered again.
+func (c *Conn) Close() {
+	c.mutex.Lock()
+	consumers := c.consumers
+	c.consumers = nil
+	c.mutex.Unlock()
+	for _, consuming := range consumers {
+		consuming.Stop()
+	}
+	for _, consuming := range consumers {
+		<-consuming.Closed()
+	}
+	c.conn.Close()
+}
+
+// Stats returns the counters of the connection
+func (c *Conn) Stats() Stats {
+	return Stats{
+		Published:   c.published.Load(),
+		Received:    c.received.Load(),
+		Reconnects:  int64(c.conn.Stats().Reconnects),
+		Connected:   c.conn.IsConnected(),
+		Redelivered: c.redelivered.Load(),
+		Dropped:     c.dropped.Load(),
+	}
+}
@@ -0,0 +1,281 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notification is a message delivered to every configured integration
+type Notification struct {
+	Event   string                 `json:"event"`
+	Title   string                 `json:"title"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	SentAt  time.Time              `json:"sentAt"`
+}
+
+// Notifier delivers notifications to an external integration
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the log, so they are visible even without integrations
+type LogNotifier struct {
+	Log *logrus.Logger
+}
+
+func NewLogNotifier(log *logrus.Logger) *LogNotifier {
+	return &LogNotifier{
+		Log: log,
+	}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.Log.WithFields(logrus.Fields(notification.Fields)).
+		WithField("event", notification.Event).
+		Warnf("%s: %s", notification.Title, notification.Message)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a URL.
+// With a Secret, each body is signed with HMAC-SHA256 in the X-Webhook-Signature header
+// as "sha256=<hex>"; with Events, only those events (or all with "*") are delivered.
+// Failed deliveries are retried with exponential backoff up to MaxAttempts.
+type WebhookNotifier struct {
+	URL         string
+	Events      []string
+	Secret      string
+	MaxAttempts int
+	Backoff     time.Duration
+	Client      *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:         url,
+		MaxAttempts: 1,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Subscribed reports whether the webhook wants notifications of the given event
+func (n *WebhookNotifier) Subscribed(event string) bool {
+	if len(n.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range n.Events {
+		if subscribed == "*" || subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	if !n.Subscribed(notification.Event) {
+		return nil
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.deliver(ctx, notification.Event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.MaxAttempts {
+			return fmt.Errorf("webhook %s failed after %d attempts: %w", n.URL, attempt, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s: %w", n.URL, ctx.Err())
+		}
+		backoff *= 2
+		if backoff > maxWebhookBackoff {
+			backoff = maxWebhookBackoff
+		}
+	}
+}
+
+// maxWebhookBackoff caps the delay between delivery attempts
+const maxWebhookBackoff = time.Minute
+
+// deliver makes one delivery attempt and reports whether a failure is worth retrying
+func (n *WebhookNotifier) deliver(ctx context.Context, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	if n.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", Sign(n.Secret, body))
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// Client errors other than rate limiting will not succeed on retry
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Sign returns the X-Webhook-Signature value of a body, for receivers to compare against
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Settings is the "notifiers" config section
+type Settings struct {
+	// Webhooks receive every notification, unsigned and without retries
+	Webhooks []string      `mapstructure:"webhooks" json:"webhooks"`
+	Email    EmailSettings `mapstructure:"email" json:"email"`
+}
+
+// EmailSettings configures the SMTP server notifications are mailed through; mail is off without a host
+type EmailSettings struct {
+	Host     string   `mapstructure:"host" json:"host"`
+	Port     int      `mapstructure:"port" json:"port"`
+	Username string   `mapstructure:"username" json:"username"`
+	Password string   `mapstructure:"password" json:"password"`
+	From     string   `mapstructure:"from" json:"from"`
+	To       []string `mapstructure:"to" json:"to"`
+}
+
+// WebhookSubscription is one entry of the "webhooks" config section
+type WebhookSubscription struct {
+	URL         string        `mapstructure:"url" json:"url"`
+	Events      []string      `mapstructure:"events" json:"events"`
+	Secret      string        `mapstructure:"secret" json:"secret"`
+	MaxAttempts int           `mapstructure:"max_attempts" json:"max_attempts"`
+	Backoff     time.Duration `mapstructure:"backoff" json:"backoff"`
+}
+
+// EmailNotifier sends notifications as plain text mail through an SMTP server
+type EmailNotifier struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+func NewEmailNotifier(host string, port int, username string, password string, from string, to []string) *EmailNotifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &EmailNotifier{
+		Addr: fmt.Sprintf("%s:%d", host, port),
+		Auth: auth,
+		From: from,
+		To:   to,
+	}
+}
+
+// headerLineBreaks turns the line breaks of a header value into spaces
+var headerLineBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification Notification) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	// A line break in the title would end the header and let it add headers of its own
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerLineBreaks.Replace(notification.Title))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(notification.Message)
+
+	return smtp.SendMail(n.Addr, n.Auth, n.From, n.To, []byte(msg.String()))
+}
+
+// MultiNotifier fans a notification out to several notifiers, returning all delivery errors
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, notification Notification) error {
+	if notification.SentAt.IsZero() {
+		notification.SentAt = time.Now()
+	}
+
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewWebhooks builds a signed webhook notifier, with retries, for each "webhooks" subscription
+func NewWebhooks(subscriptions []WebhookSubscription, log *logrus.Logger) MultiNotifier {
+	notifiers := MultiNotifier{}
+	for _, subscription := range subscriptions {
+		if subscription.URL == "" {
+			log.Warn("Ignoring webhook subscription without a url")
+			continue
+		}
+
+		webhook := NewWebhookNotifier(subscription.URL)
+		webhook.Events = subscription.Events
+		webhook.Secret = subscription.Secret
+		webhook.MaxAttempts = subscription.MaxAttempts
+		if webhook.MaxAttempts <= 0 {
+			webhook.MaxAttempts = 5
+		}
+		webhook.Backoff = subscription.Backoff
+		if webhook.Backoff <= 0 {
+			webhook.Backoff = time.Second
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	return notifiers
+}
+
+// NewNotifier builds the notifier integrations from the "notifiers" and "webhooks" config sections.
+// Notifications are always logged; each URL in "notifiers.webhooks" also receives them,
+// each "webhooks" subscription receives its events signed and with retries,
+// and they are mailed when "notifiers.email.host" is set.
+func NewNotifier(settings Settings, subscriptions []WebhookSubscription, log *logrus.Logger) Notifier {
+	notifiers := MultiNotifier{NewLogNotifier(log)}
+	for _, url := range settings.Webhooks {
+		notifiers = append(notifiers, NewWebhookNotifier(url))
+	}
+	notifiers = append(notifiers, NewWebhooks(subscriptions, log)...)
+
+	if email := settings.Email; email.Host != "" {
+		notifiers = append(notifiers, NewEmailNotifier(
+			email.Host,
+			email.Port,
+			email.Username,
+			email.Password,
+			email.From,
+			email.To,
+		))
+	}
+	return notifiers
+}
@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BackpressureSettings set when crawl triggers are refused because a queue is saturated.
+// Watermarks are fractions of the queue max size.
+type BackpressureSettings struct {
+	// HighWatermark saturates the queue, 0.8 by default
+	HighWatermark float64 `mapstructure:"high_watermark" json:"high_watermark"`
+	// LowWatermark is where a saturated queue accepts crawls again, 3/4 of the high one by default
+	LowWatermark float64 `mapstructure:"low_watermark" json:"low_watermark"`
+	// RetryAfter is how long refused callers are asked to wait, 30s by default
+	RetryAfter time.Duration `mapstructure:"retry_after" json:"retry_after"`
+}
+
+// Validate rejects watermarks outside 0 to 1 and a low watermark above the high one
+func (s BackpressureSettings) Validate() error {
+	if s.HighWatermark < 0 || s.HighWatermark > 1 || s.LowWatermark < 0 || s.LowWatermark > 1 {
+		return errors.New("watermarks must be between 0 and 1")
+	}
+	if s.HighWatermark > 0 && s.LowWatermark > s.HighWatermark {
+		return errors.New("low_watermark must not be above high_watermark")
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left zero
+func (s BackpressureSettings) WithDefaults() BackpressureSettings {
+	if s.HighWatermark == 0 {
+		s.HighWatermark = 0.8
+	}
+	if s.LowWatermark == 0 {
+		s.LowWatermark = s.HighWatermark * 0.75
+	}
+	if s.RetryAfter == 0 {
+		s.RetryAfter = 30 * time.Second
+	}
+	return s
+}
+
+// QueueState is the watermark state of one tracked queue
+type QueueState struct {
+	Name          string `json:"name"`
+	Size          int    `json:"size"`
+	MaxSize       int    `json:"maxSize"`
+	HighWatermark int    `json:"highWatermark"`
+	LowWatermark  int    `json:"lowWatermark"`
+	Saturated     bool   `json:"saturated"`
+}
+
+type trackedQueue struct {
+	name      string
+	size      func() int
+	maxSize   int
+	high      int
+	low       int
+	saturated bool
+}
+
+// Backpressure tracks queue depths against high and low watermarks. A queue becomes saturated
+// when it reaches the high watermark and stays saturated until it drains below the low
+// watermark, so callers are not flipped between accepted and refused on every enqueue.
+// A nil Backpressure tracks nothing and is never saturated.
+type Backpressure struct {
+	mutex      sync.Mutex
+	log        *logrus.Logger
+	retryAfter time.Duration
+	queues     []*trackedQueue
+}
+
+func NewBackpressure(log *logrus.Logger, retryAfter time.Duration) *Backpressure {
+	return &Backpressure{
+		log:        log,
+		retryAfter: retryAfter,
+	}
+}
+
+// Track adds a queue whose depth is read from size, with the watermarks of settings
+func (b *Backpressure) Track(name string, size func() int, settings Settings) {
+	settings = settings.WithDefaults()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.queues = append(b.queues, &trackedQueue{
+		name:    name,
+		size:    size,
+		maxSize: settings.MaxSize,
+		high:    int(float64(settings.MaxSize) * settings.Backpressure.HighWatermark),
+		low:     int(float64(settings.MaxSize) * settings.Backpressure.LowWatermark),
+	})
+}
+
+// RetryAfter is how long refused callers are asked to wait
+func (b *Backpressure) RetryAfter() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.retryAfter
+}
+
+// States refreshes and returns the watermark state of every tracked queue
+func (b *Backpressure) States() []QueueState {
+	if b == nil {
+		return []QueueState{}
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	states := make([]QueueState, 0, len(b.queues))
+	for _, q := range b.queues {
+		size := q.size()
+		switch {
+		case !q.saturated && size >= q.high:
+			q.saturated = true
+			b.log.WithFields(logrus.Fields{
+				"queue":          q.name,
+				"queue_size":     size,
+				"high_watermark": q.high,
+			}).Warn("Queue reached high watermark, refusing new crawls")
+		case q.saturated && size < q.low:
+			q.saturated = false
+			b.log.WithFields(logrus.Fields{
+				"queue":         q.name,
+				"queue_size":    size,
+				"low_watermark": q.low,
+			}).Info("Queue drained below low watermark, accepting crawls again")
+		}
+
+		states = append(states, QueueState{
+			Name:          q.name,
+			Size:          size,
+			MaxSize:       q.maxSize,
+			HighWatermark: q.high,
+			LowWatermark:  q.low,
+			Saturated:     q.saturated,
+		})
+	}
+
+	return states
+}
+
+// Saturated reports whether any tracked queue is saturated, along with all queue states
+func (b *Backpressure) Saturated() (bool, []QueueState) {
+	states := b.States()
+	for _, state := range states {
+		if state.Saturated {
+			return true, states
+		}
+	}
+	return false, states
+}
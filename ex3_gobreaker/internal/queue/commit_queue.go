@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"context"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/usecase"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// commitFallbackBatchSizes are the sizes of the smaller batches a failed batch of commits is
+// retried in, one after the other, ending with the commits one by one
+var commitFallbackBatchSizes = []int{10, 1}
+
+// commitFallbackWorkers bounds the smaller batches of one failed batch stored at once
+const commitFallbackWorkers = 4
+
+// CommitQueueProcessor stores the crawled commits in the background
+type CommitQueueProcessor = Processor[*model.CreateCommitRequest]
+
+// commitCreator stores a batch of commits, like CommitUsecase.BatchCreate: commits already
+// stored are skipped and returned Existing, and a batch held in the spool returns no responses
+type commitCreator func(ctx context.Context, commits []*model.CreateCommitRequest) ([]*model.CommitResponse, error)
+
+// commitOutcome is the result of storing one commit of a batch. Response is nil when the commit
+// failed, with Err, or when its batch was held in the spool.
+type commitOutcome struct {
+	Response *model.CommitResponse
+	Err      error
+}
+
+// NewCommitQueueProcessor creates the commit queue, storing its batches with commitUsecase
+func NewCommitQueueProcessor(log *logrus.Logger, commitUsecase *usecase.CommitUsecase,
+	settings Settings) *CommitQueueProcessor {
+	kind := Kind{Name: "commits", Label: "Commit", Plural: "commits"}
+	return NewProcessor(kind, log, func(ctx context.Context, commits []*model.CreateCommitRequest) (Summary, error) {
+		outcomes, err := saveCommits(ctx, log, commitUsecase.BatchCreate, commits)
+		return summarizeCommits(outcomes), err
+	}, settings)
+}
+
+// saveCommits stores a batch of commits and returns the outcome of each, in order. When the
+// batch fails as a whole, it is retried in smaller batches by a bounded pool of workers, and a
+// smaller batch that fails is retried commit by commit, so only the commits that cannot be
+// stored fail; the error of the whole batch is still returned, with the outcomes of the retries.
+func saveCommits(ctx context.Context, log *logrus.Logger, create commitCreator,
+	commits []*model.CreateCommitRequest) ([]commitOutcome, error) {
+	responses, err := create(ctx, commits)
+	if err == nil {
+		return commitOutcomes(commits, responses), nil
+	}
+
+	log.WithError(err).Info("Batch of commits failed, trying smaller batches as fallback")
+	outcomes := make([]commitOutcome, len(commits))
+	saveCommitsIn(ctx, log, create, commits, outcomes, commitFallbackBatchSizes, commitFallbackWorkers)
+	return outcomes, err
+}
+
+// saveCommitsIn stores commits in batches of sizes[0] with up to workers batches at once,
+// writing the outcome of commits[i] to outcomes[i]. A failed batch is retried with the
+// following sizes by the same worker; a batch failing at the last size fails all its commits.
+// Batches insert only the commits not stored yet, so two batches holding the same commit store
+// it once and the other one gets it back Existing.
+func saveCommitsIn(ctx context.Context, log *logrus.Logger, create commitCreator,
+	commits []*model.CreateCommitRequest, outcomes []commitOutcome, sizes []int, workers int) {
+	starts := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, (len(commits)+sizes[0]-1)/sizes[0]); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				end := min(start+sizes[0], len(commits))
+				saveCommitBatch(ctx, log, create, commits[start:end], outcomes[start:end], sizes)
+			}
+		}()
+	}
+	for start := 0; start < len(commits); start += sizes[0] {
+		starts <- start
+	}
+	close(starts)
+	wg.Wait()
+}
+
+// saveCommitBatch stores one batch of saveCommitsIn, retrying it with the smaller sizes
+func saveCommitBatch(ctx context.Context, log *logrus.Logger, create commitCreator,
+	commits []*model.CreateCommitRequest, outcomes []commitOutcome, sizes []int) {
+	responses, err := create(ctx, commits)
+	if err == nil {
+		copy(outcomes, commitOutcomes(commits, responses))
+		return
+	}
+	if len(sizes) > 1 && len(commits) > 1 {
+		saveCommitsIn(ctx, log, create, commits, outcomes, sizes[1:], 1)
+		return
+	}
+
+	for i := range outcomes {
+		outcomes[i] = commitOutcome{Err: err}
+	}
+	entry := log.WithError(err).WithField("batch_size", len(commits))
+	if len(commits) == 1 {
+		entry = entry.WithField("hash", commits[0].Hash).WithField("release_id", commits[0].ReleaseID)
+	}
+	entry.Error("Smaller batch of commits failed")
+}
+
+// commitOutcomes pairs the commits of a stored batch with their responses, which come in the
+// same order; a batch held in the spool has no responses and leaves every outcome empty
+func commitOutcomes(commits []*model.CreateCommitRequest, responses []*model.CommitResponse) []commitOutcome {
+	outcomes := make([]commitOutcome, len(commits))
+	for i := range outcomes {
+		if i < len(responses) {
+			outcomes[i].Response = responses[i]
+		}
+	}
+	return outcomes
+}
+
+// summarizeCommits counts the outcomes of a batch; commits held in the spool count nothing
+func summarizeCommits(outcomes []commitOutcome) Summary {
+	var summary Summary
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.Err != nil:
+			summary.Failed++
+		case outcome.Response == nil:
+		case outcome.Response.Existing:
+			summary.Skipped++
+		default:
+			summary.Created++
+		}
+	}
+	return summary
+}
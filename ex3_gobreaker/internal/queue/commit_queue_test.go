@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"crawler/baseline/internal/model"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+var errPoison = errors.New("commit cannot be stored")
+
+// fakeCommitStore stores commits by release and hash like CommitUsecase.BatchCreate: stored
+// commits and repeats within a batch come back Existing, and a batch holding a poison hash
+// fails as a whole
+type fakeCommitStore struct {
+	mutex  sync.Mutex
+	poison map[string]bool
+	stored map[string]int64
+	nextID int64
+	calls  int
+}
+
+func newFakeCommitStore(poison ...string) *fakeCommitStore {
+	s := &fakeCommitStore{poison: make(map[string]bool), stored: make(map[string]int64)}
+	for _, hash := range poison {
+		s.poison[hash] = true
+	}
+	return s
+}
+
+func (s *fakeCommitStore) create(ctx context.Context, commits []*model.CreateCommitRequest) ([]*model.CommitResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls++
+
+	for _, commit := range commits {
+		if s.poison[commit.Hash] {
+			return nil, fmt.Errorf("%w: %s", errPoison, commit.Hash)
+		}
+	}
+	responses := make([]*model.CommitResponse, len(commits))
+	for i, commit := range commits {
+		key := fmt.Sprintf("%d/%s", commit.ReleaseID, commit.Hash)
+		id, existing := s.stored[key]
+		if !existing {
+			s.nextID++
+			id = s.nextID
+			s.stored[key] = id
+		}
+		responses[i] = &model.CommitResponse{ID: id, Hash: commit.Hash, ReleaseID: commit.ReleaseID, Existing: existing}
+	}
+	return responses, nil
+}
+
+func commitRequests(hashes ...string) []*model.CreateCommitRequest {
+	commits := make([]*model.CreateCommitRequest, len(hashes))
+	for i, hash := range hashes {
+		commits[i] = &model.CreateCommitRequest{Hash: hash, ReleaseID: 1}
+	}
+	return commits
+}
+
+func TestSaveCommitsDuplicateHashes(t *testing.T) {
+	store := newFakeCommitStore()
+	outcomes, err := saveCommits(context.Background(), testLogger(), store.create, commitRequests("a", "b", "a", "c", "b"))
+	if err != nil {
+		t.Fatalf("saveCommits = %v", err)
+	}
+
+	if got, want := summarizeCommits(outcomes), (Summary{Created: 3, Skipped: 2}); got != want {
+		t.Fatalf("summary = %+v, want %+v", got, want)
+	}
+	// A repeated hash gets the row stored for its first occurrence
+	if outcomes[2].Response.ID != outcomes[0].Response.ID || outcomes[4].Response.ID != outcomes[1].Response.ID {
+		t.Fatalf("repeated hashes got IDs %d and %d, want %d and %d", outcomes[2].Response.ID,
+			outcomes[4].Response.ID, outcomes[0].Response.ID, outcomes[1].Response.ID)
+	}
+}
+
+func TestSaveCommitsPartialFailure(t *testing.T) {
+	hashes := make([]string, 35)
+	for i := range hashes {
+		hashes[i] = fmt.Sprintf("h%02d", i)
+	}
+	// Duplicates across the smaller batches are stored once by the concurrent workers
+	hashes[30] = hashes[0]
+	hashes[31] = hashes[12]
+	store := newFakeCommitStore("h07", "h23")
+
+	outcomes, err := saveCommits(context.Background(), testLogger(), store.create, commitRequests(hashes...))
+	if !errors.Is(err, errPoison) {
+		t.Fatalf("saveCommits = %v, want the error of the whole batch", err)
+	}
+
+	if got, want := summarizeCommits(outcomes), (Summary{Created: 31, Skipped: 2, Failed: 2}); got != want {
+		t.Fatalf("summary = %+v, want %+v", got, want)
+	}
+	for i, outcome := range outcomes {
+		poison := hashes[i] == "h07" || hashes[i] == "h23"
+		if poison != (outcome.Err != nil) {
+			t.Errorf("outcome of %s: err = %v, want failed %t", hashes[i], outcome.Err, poison)
+		}
+		if !poison && (outcome.Response == nil || outcome.Response.Hash != hashes[i]) {
+			t.Errorf("outcome of %s: response = %+v, want the stored commit", hashes[i], outcome.Response)
+		}
+	}
+	if len(store.stored) != 31 {
+		t.Fatalf("stored %d commits, want 31", len(store.stored))
+	}
+}
+
+func TestSaveCommitsSpooled(t *testing.T) {
+	spool := func(ctx context.Context, commits []*model.CreateCommitRequest) ([]*model.CommitResponse, error) {
+		return []*model.CommitResponse{}, nil
+	}
+	outcomes, err := saveCommits(context.Background(), testLogger(), spool, commitRequests("a", "b"))
+	if err != nil {
+		t.Fatalf("saveCommits = %v", err)
+	}
+	if got := summarizeCommits(outcomes); got != (Summary{}) {
+		t.Fatalf("summary = %+v, want nothing counted for a spooled batch", got)
+	}
+}
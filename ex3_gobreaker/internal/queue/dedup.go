@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"time"
+)
+
+// payloadKey identifies an item by the hash of its JSON encoding
+type payloadKey [sha256.Size]byte
+
+func payloadHash(item any) (payloadKey, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return payloadKey{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+type dedupEntry struct {
+	key     payloadKey
+	expires time.Time
+}
+
+// dedupWindow remembers the items stored during the last window, so an identical item
+// enqueued again within it is coalesced with the first. Items still queued or being stored are
+// pending: identical items are coalesced with them too, but they are only remembered once
+// their batch is stored, so an item that fails or is dropped can be enqueued again. The caller
+// must hold the queue's mutex.
+type dedupWindow struct {
+	window     time.Duration
+	maxEntries int
+	seen       map[payloadKey]time.Time
+	// order holds the entries of seen by expiry, which is also the order they were added in
+	order   []dedupEntry
+	pending map[payloadKey]struct{}
+}
+
+func newDedupWindow(settings DedupSettings) *dedupWindow {
+	return &dedupWindow{
+		window:     settings.Window,
+		maxEntries: settings.MaxEntries,
+		seen:       make(map[payloadKey]time.Time),
+		pending:    make(map[payloadKey]struct{}),
+	}
+}
+
+// contains reports whether key is pending or was added less than a window ago
+func (d *dedupWindow) contains(key payloadKey, now time.Time) bool {
+	if _, ok := d.pending[key]; ok {
+		return true
+	}
+	d.prune(now)
+	_, ok := d.seen[key]
+	return ok
+}
+
+// hold marks key pending while its item is queued or being stored
+func (d *dedupWindow) hold(key payloadKey) {
+	d.pending[key] = struct{}{}
+}
+
+// release ends the pending state of key, remembering it for a window from now when its item was
+// stored and forgetting it otherwise
+func (d *dedupWindow) release(key payloadKey, stored bool, now time.Time) {
+	delete(d.pending, key)
+	if stored {
+		d.add(key, now)
+	}
+}
+
+// add remembers key for a window from now, forgetting the oldest entry beyond maxEntries
+func (d *dedupWindow) add(key payloadKey, now time.Time) {
+	expires := now.Add(d.window)
+	d.seen[key] = expires
+	d.order = append(d.order, dedupEntry{key: key, expires: expires})
+	if d.maxEntries > 0 && len(d.order) > d.maxEntries {
+		d.forget(d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+// prune forgets the entries whose window ended
+func (d *dedupWindow) prune(now time.Time) {
+	expired := 0
+	for expired < len(d.order) && !d.order[expired].expires.After(now) {
+		d.forget(d.order[expired])
+		expired++
+	}
+	d.order = d.order[expired:]
+}
+
+func (d *dedupWindow) forget(entry dedupEntry) {
+	// The key may have been added again since, with a later expiry
+	if d.seen[entry.key].Equal(entry.expires) {
+		delete(d.seen, entry.key)
+	}
+}
@@ -0,0 +1,557 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Overflow policies of a queue, applied when an item is enqueued while it is full
+const (
+	// OverflowReject refuses the new item
+	OverflowReject = "reject"
+	// OverflowBlock waits for the workers to free space, up to the block timeout
+	OverflowBlock = "block"
+	// OverflowDropOldest evicts the oldest queued item to make room for the new one
+	OverflowDropOldest = "drop_oldest"
+)
+
+var (
+	// ErrQueueFull is returned when an item is refused because the queue stayed full
+	ErrQueueFull = errors.New("queue is full")
+	// ErrQueueStopped is returned for an item enqueued once the processor is stopped, or while
+	// it waited for space
+	ErrQueueStopped = errors.New("queue processor stopped")
+)
+
+// Settings tune a queue; a zero value keeps the default of its field
+type Settings struct {
+	// MaxSize is how many items may wait in the queue, 10000 by default
+	MaxSize int `mapstructure:"max_size" json:"max_size"`
+	// Workers is how many batches are stored at once, the number of CPUs (at least 2) by default
+	Workers int `mapstructure:"workers" json:"workers"`
+	// BatchSize is how many items a worker takes at once, 100 by default
+	BatchSize int              `mapstructure:"batch_size" json:"batch_size"`
+	Overflow  OverflowSettings `mapstructure:"overflow" json:"overflow"`
+	Dedup     DedupSettings    `mapstructure:"dedup" json:"dedup"`
+	// Backpressure sets when crawl triggers are refused because the queue is saturated
+	Backpressure BackpressureSettings `mapstructure:"backpressure" json:"backpressure"`
+}
+
+// OverflowSettings set what happens to an item enqueued while the queue is full
+type OverflowSettings struct {
+	// Policy is OverflowReject (default), OverflowBlock or OverflowDropOldest
+	Policy string `mapstructure:"policy" json:"policy"`
+	// BlockTimeout bounds the wait of the block policy, 5s by default
+	BlockTimeout time.Duration `mapstructure:"block_timeout" json:"block_timeout"`
+}
+
+// DedupSettings set whether an item identical to one still queued, or stored shortly before, is
+// coalesced with it instead of being queued and stored again
+type DedupSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// Window is how long a stored item is remembered, 1m by default
+	Window time.Duration `mapstructure:"window" json:"window"`
+	// MaxEntries bounds the items remembered, forgetting the oldest first, 100000 by default
+	MaxEntries int `mapstructure:"max_entries" json:"max_entries"`
+}
+
+// Validate rejects negative sizes and durations and an unknown overflow policy
+func (s Settings) Validate() error {
+	if s.MaxSize < 0 || s.Workers < 0 || s.BatchSize < 0 || s.Dedup.MaxEntries < 0 {
+		return errors.New("sizes must not be negative")
+	}
+	if s.Overflow.BlockTimeout < 0 || s.Dedup.Window < 0 || s.Backpressure.RetryAfter < 0 {
+		return errors.New("durations must not be negative")
+	}
+	if err := s.Backpressure.Validate(); err != nil {
+		return fmt.Errorf("backpressure: %w", err)
+	}
+	switch s.Overflow.Policy {
+	case "", OverflowReject, OverflowBlock, OverflowDropOldest:
+	default:
+		return fmt.Errorf("unknown overflow.policy %q, expected %s, %s or %s",
+			s.Overflow.Policy, OverflowReject, OverflowBlock, OverflowDropOldest)
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left zero
+func (s Settings) WithDefaults() Settings {
+	if s.MaxSize == 0 {
+		s.MaxSize = 10000
+	}
+	if s.Workers == 0 {
+		s.Workers = max(2, runtime.NumCPU())
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = 100
+	}
+	if s.Overflow.Policy == "" {
+		s.Overflow.Policy = OverflowReject
+	}
+	if s.Overflow.BlockTimeout == 0 {
+		s.Overflow.BlockTimeout = 5 * time.Second
+	}
+	if s.Dedup.Window == 0 {
+		s.Dedup.Window = time.Minute
+	}
+	if s.Dedup.MaxEntries == 0 {
+		s.Dedup.MaxEntries = 100000
+	}
+	s.Backpressure = s.Backpressure.WithDefaults()
+	return s
+}
+
+// Summary counts the outcomes of the items of a batch
+type Summary struct {
+	Created int
+	// Skipped counts the items already stored
+	Skipped int
+	Failed  int
+}
+
+// BatchHandler stores a batch of items taken from a queue. The summary counts the outcome of
+// every item, including those of a batch that failed with an error.
+type BatchHandler[T any] func(ctx context.Context, items []T) (Summary, error)
+
+// Kind names the items of a queue in its snapshot and logs
+type Kind struct {
+	// Name is the queue name of the snapshot, such as "commits"
+	Name string
+	// Label starts the log messages, such as "Commit"
+	Label string
+	// Plural names a batch in the log messages, such as "commits"
+	Plural string
+}
+
+// metrics counts the items of a queue; the queue's mutex guards it
+type metrics struct {
+	enqueued       int64
+	dequeued       int64
+	maxQueueLength int
+
+	// Outcomes of the processed items: stored, already stored, or not saved
+	created int64
+	skipped int64
+	failed  int64
+
+	// Items evicted by the drop-oldest overflow policy, and items refused because the queue
+	// was full
+	dropped  int64
+	rejected int64
+
+	// Items coalesced with an identical one still queued or stored within the dedup window
+	deduped int64
+}
+
+// queuedItem is an item waiting in a queue, with its dedup key when it has one
+type queuedItem[T any] struct {
+	item  T
+	key   payloadKey
+	dedup bool
+}
+
+// Processor queues items in memory and stores them in batches with worker goroutines, as a
+// write strategy that returns to the crawl before its items are stored
+type Processor[T any] struct {
+	kind   Kind
+	handle BatchHandler[T]
+	log    *logrus.Logger
+
+	items      []queuedItem[T]
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	maxSize    int
+	metrics    metrics
+	processing int
+	// spaceFreed is closed and replaced whenever workers take items, waking the enqueues
+	// waiting for space
+	spaceFreed chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// workerCount is how many workers should run and running how many do; workers beyond
+	// workerCount stop once they finish their batch
+	workerCount  int
+	running      int
+	nextWorkerID int
+	workerWg     sync.WaitGroup
+	batchSize    int
+
+	// overflow is the policy of Enqueue and EnqueueAll when the queue is full
+	overflow     string
+	blockTimeout time.Duration
+
+	// dedup, when set, remembers the queued and recently stored items to coalesce identical ones
+	dedup *dedupWindow
+}
+
+// NewProcessor creates a processor storing the batches of its queue with handle
+func NewProcessor[T any](kind Kind, log *logrus.Logger, handle BatchHandler[T], settings Settings) *Processor[T] {
+	settings = settings.WithDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[T]{
+		kind:         kind,
+		handle:       handle,
+		log:          log,
+		items:        make([]queuedItem[T], 0),
+		maxSize:      settings.MaxSize,
+		spaceFreed:   make(chan struct{}),
+		ctx:          ctx,
+		cancel:       cancel,
+		workerCount:  settings.Workers,
+		batchSize:    settings.BatchSize,
+		overflow:     settings.Overflow.Policy,
+		blockTimeout: settings.Overflow.BlockTimeout,
+	}
+	if settings.Dedup.Enabled {
+		p.dedup = newDedupWindow(settings.Dedup)
+	}
+	p.cond = sync.NewCond(&p.mutex)
+
+	// Wake the workers waiting for items once the processor is stopped. The broadcast takes
+	// the mutex, so it cannot slip in between a worker's check of ctx and its wait.
+	context.AfterFunc(ctx, func() {
+		p.mutex.Lock()
+		p.cond.Broadcast()
+		p.mutex.Unlock()
+	})
+
+	return p
+}
+
+// Start begins processing with worker goroutines
+func (p *Processor[T]) Start() {
+	p.log.WithField("worker_count", p.workerCount).Infof("Starting %s queue processor", p.kind.Plural)
+
+	p.mutex.Lock()
+	p.startWorkers()
+	p.mutex.Unlock()
+
+	go p.reportMetrics()
+}
+
+// Resize changes the number of workers of a started processor. Extra workers start at once;
+// surplus workers stop once they finish the batch they hold.
+func (p *Processor[T]) Resize(workerCount int) {
+	if workerCount <= 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if workerCount == p.workerCount || p.ctx.Err() != nil {
+		return
+	}
+	p.log.WithFields(logrus.Fields{
+		"from": p.workerCount,
+		"to":   workerCount,
+	}).Infof("Resizing %s queue processor", p.kind.Plural)
+	p.workerCount = workerCount
+	p.startWorkers()
+	// Wake the idle workers so the surplus ones stop
+	p.cond.Broadcast()
+}
+
+// SetBatchSize changes how many items a worker takes at once, from its next batch on
+func (p *Processor[T]) SetBatchSize(batchSize int) {
+	if batchSize <= 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if batchSize == p.batchSize {
+		return
+	}
+	p.log.WithFields(logrus.Fields{
+		"from": p.batchSize,
+		"to":   batchSize,
+	}).Infof("Changing %s queue batch size", p.kind.Plural)
+	p.batchSize = batchSize
+}
+
+// startWorkers starts workers until workerCount run; the caller must hold the mutex
+func (p *Processor[T]) startWorkers() {
+	for p.running < p.workerCount {
+		p.running++
+		p.workerWg.Add(1)
+		workerID := p.nextWorkerID
+		p.nextWorkerID++
+
+		go func() {
+			defer p.workerWg.Done()
+			p.worker(workerID)
+		}()
+	}
+}
+
+// Stop terminates all processing. Batches being stored are finished; the items still queued
+// are left there and items enqueued afterwards are refused.
+func (p *Processor[T]) Stop() {
+	p.log.Infof("Stopping %s queue processor", p.kind.Plural)
+	p.cancel()
+	p.workerWg.Wait()
+	p.log.Infof("%s queue processor stopped", p.kind.Label)
+}
+
+// Enqueue adds an item to the queue, applying the overflow policy when it is full
+func (p *Processor[T]) Enqueue(ctx context.Context, item T) error {
+	if p.overflow == OverflowBlock {
+		return p.enqueue(ctx, item, true, p.blockTimeout)
+	}
+	return p.enqueue(ctx, item, false, 0)
+}
+
+// EnqueueAll adds items to the queue in order, applying the overflow policy, and stops at the
+// first one refused. It returns the number enqueued with the error that stopped it.
+func (p *Processor[T]) EnqueueAll(ctx context.Context, items []T) (int, error) {
+	for i, item := range items {
+		if err := p.Enqueue(ctx, item); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// enqueue adds an item to the queue. An item identical to one still queued, or stored within
+// the dedup window, is coalesced with it and accepted without being queued. When the queue is
+// full, the item waits for space if wait is set, and otherwise evicts the oldest item or is
+// refused depending on the overflow policy.
+func (p *Processor[T]) enqueue(ctx context.Context, item T, wait bool, timeout time.Duration) error {
+	var key payloadKey
+	dedup := p.dedup != nil
+	if dedup {
+		var err error
+		if key, err = payloadHash(item); err != nil {
+			p.log.WithError(err).Warnf("Cannot hash %s queue item, enqueuing it without dedup", p.kind.Plural)
+			dedup = false
+		}
+	}
+
+	var deadline <-chan time.Time
+	if wait && timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	p.mutex.Lock()
+	if p.ctx.Err() != nil {
+		p.mutex.Unlock()
+		return ErrQueueStopped
+	}
+	if dedup && p.coalesce(key) {
+		p.mutex.Unlock()
+		return nil
+	}
+	for p.maxSize > 0 && len(p.items) >= p.maxSize {
+		if !wait && p.overflow == OverflowDropOldest {
+			if oldest := p.items[0]; oldest.dedup {
+				p.dedup.release(oldest.key, false, time.Now())
+			}
+			p.items[0] = queuedItem[T]{}
+			p.items = p.items[1:]
+			p.metrics.dropped++
+			p.log.Warnf("%s queue is full, dropped the oldest item", p.kind.Label)
+			break
+		}
+		if !wait {
+			p.metrics.rejected++
+			p.mutex.Unlock()
+			p.log.Warnf("%s queue is full, refusing item", p.kind.Label)
+			return ErrQueueFull
+		}
+
+		spaceFreed := p.spaceFreed
+		p.mutex.Unlock()
+		select {
+		case <-spaceFreed:
+		case <-deadline:
+			p.mutex.Lock()
+			p.metrics.rejected++
+			p.mutex.Unlock()
+			p.log.WithField("timeout", timeout).Warnf("%s queue stayed full, giving up", p.kind.Label)
+			return fmt.Errorf("%w after waiting %s", ErrQueueFull, timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.ctx.Done():
+			return ErrQueueStopped
+		}
+		p.mutex.Lock()
+		if p.ctx.Err() != nil {
+			p.mutex.Unlock()
+			return ErrQueueStopped
+		}
+		// An identical item may have been enqueued while this one waited
+		if dedup && p.coalesce(key) {
+			p.mutex.Unlock()
+			return nil
+		}
+	}
+	defer p.mutex.Unlock()
+
+	p.items = append(p.items, queuedItem[T]{item: item, key: key, dedup: dedup})
+	p.metrics.enqueued++
+	if dedup {
+		p.dedup.hold(key)
+	}
+	p.metrics.maxQueueLength = max(p.metrics.maxQueueLength, len(p.items))
+	p.cond.Signal()
+	return nil
+}
+
+// coalesce reports whether an item of key is queued or was stored within the dedup window,
+// counting it as deduped; the caller must hold the mutex
+func (p *Processor[T]) coalesce(key payloadKey) bool {
+	if !p.dedup.contains(key, time.Now()) {
+		return false
+	}
+	p.metrics.deduped++
+	p.log.Debugf("Coalesced a %s queue item identical to one queued or stored recently", p.kind.Plural)
+	return true
+}
+
+// dequeue gets a batch of up to batchSize items from the queue, waiting for one while it is
+// empty. It returns nil once the processor is stopped, or once the worker is surplus after
+// Resize, and the worker then stops.
+func (p *Processor[T]) dequeue() []queuedItem[T] {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(p.items) == 0 && p.ctx.Err() == nil && p.running <= p.workerCount {
+		p.cond.Wait()
+	}
+	if p.ctx.Err() != nil {
+		return nil
+	}
+	if p.running > p.workerCount {
+		p.running--
+		return nil
+	}
+
+	count := min(p.batchSize, len(p.items))
+	items := make([]queuedItem[T], count)
+	copy(items, p.items[:count])
+	p.items = p.items[count:]
+	p.metrics.dequeued += int64(count)
+
+	// Wake the enqueues waiting for space
+	close(p.spaceFreed)
+	p.spaceFreed = make(chan struct{})
+
+	p.processing += count
+	return items
+}
+
+// worker processes items from the queue until the processor is stopped
+func (p *Processor[T]) worker(workerID int) {
+	p.log.WithField("worker_id", workerID).Infof("%s worker started", p.kind.Label)
+
+	for {
+		items := p.dequeue()
+		if items == nil {
+			p.log.WithField("worker_id", workerID).Infof("%s worker stopping", p.kind.Label)
+			return
+		}
+
+		p.process(workerID, items)
+
+		p.mutex.Lock()
+		p.processing -= len(items)
+		p.mutex.Unlock()
+	}
+}
+
+// process stores a batch with the handler and counts its outcomes. The dedup keys of the batch
+// are remembered only when every item was stored; otherwise they are forgotten, since the
+// handler does not tell which items failed, and all of them may be enqueued again.
+func (p *Processor[T]) process(workerID int, queued []queuedItem[T]) {
+	items := make([]T, len(queued))
+	for i, q := range queued {
+		items[i] = q.item
+	}
+
+	startTime := time.Now()
+	summary, err := p.handle(context.Background(), items)
+	duration := time.Since(startTime)
+
+	p.mutex.Lock()
+	p.metrics.created += int64(summary.Created)
+	p.metrics.skipped += int64(summary.Skipped)
+	p.metrics.failed += int64(summary.Failed)
+	stored := err == nil && summary.Failed == 0
+	now := time.Now()
+	for _, q := range queued {
+		if q.dedup {
+			p.dedup.release(q.key, stored, now)
+		}
+	}
+	p.mutex.Unlock()
+
+	fields := logrus.Fields{
+		"worker_id":     workerID,
+		"success_count": summary.Created,
+		"skipped_count": summary.Skipped,
+		"error_count":   summary.Failed,
+		"duration_ms":   duration.Milliseconds(),
+		"batch_size":    len(items),
+	}
+	if err != nil {
+		p.log.WithFields(fields).WithError(err).Errorf("Error processing batch of %s", p.kind.Plural)
+		return
+	}
+	p.log.WithFields(fields).Debugf("Batch processing of %s completed", p.kind.Plural)
+}
+
+// reportMetrics periodically logs queue metrics
+func (p *Processor[T]) reportMetrics() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot := p.Snapshot()
+			p.log.WithFields(logrus.Fields{
+				"queue_size":     snapshot.Size,
+				"processing":     snapshot.Processing,
+				"enqueued_total": snapshot.Enqueued,
+				"dequeued_total": snapshot.Dequeued,
+				"max_queue_size": snapshot.MaxQueueLength,
+				"dropped_total":  snapshot.Dropped,
+				"rejected_total": snapshot.Rejected,
+				"deduped_total":  snapshot.Deduped,
+			}).Infof("%s queue metrics", p.kind.Label)
+		}
+	}
+}
+
+// Snapshot returns the current size, processing count and counters of the queue
+func (p *Processor[T]) Snapshot() QueueSnapshot {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return QueueSnapshot{
+		Name:           p.kind.Name,
+		Size:           len(p.items),
+		Processing:     p.processing,
+		Enqueued:       p.metrics.enqueued,
+		Dequeued:       p.metrics.dequeued,
+		MaxQueueLength: p.metrics.maxQueueLength,
+		Created:        p.metrics.created,
+		Skipped:        p.metrics.skipped,
+		Failed:         p.metrics.failed,
+		Dropped:        p.metrics.dropped,
+		Rejected:       p.metrics.rejected,
+		Deduped:        p.metrics.deduped,
+	}
+}
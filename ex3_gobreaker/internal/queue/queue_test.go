@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var testKind = Kind{Name: "items", Label: "Item", Plural: "items"}
+
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// stopWithin fails the test when Stop does not return within timeout
+func stopWithin(t *testing.T, p *Processor[int], timeout time.Duration) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("Stop did not return within %s", timeout)
+	}
+}
+
+func TestStopWakesIdleWorkers(t *testing.T) {
+	p := NewProcessor(testKind, testLogger(), func(ctx context.Context, items []int) (Summary, error) {
+		return Summary{Created: len(items)}, nil
+	}, Settings{Workers: 4})
+	p.Start()
+
+	// Let every worker wait on the empty queue
+	time.Sleep(10 * time.Millisecond)
+	stopWithin(t, p, time.Second)
+
+	if err := p.Enqueue(context.Background(), 1); !errors.Is(err, ErrQueueStopped) {
+		t.Fatalf("Enqueue after Stop = %v, want %v", err, ErrQueueStopped)
+	}
+}
+
+func TestConcurrentEnqueueStopDequeue(t *testing.T) {
+	var stopped atomic.Bool
+	var handled atomic.Int64
+	p := NewProcessor(testKind, testLogger(), func(ctx context.Context, items []int) (Summary, error) {
+		if stopped.Load() {
+			t.Error("a batch was dequeued after Stop returned")
+		}
+		time.Sleep(time.Millisecond)
+		handled.Add(int64(len(items)))
+		return Summary{Created: len(items)}, nil
+	}, Settings{Workers: 4, BatchSize: 8, MaxSize: 100000})
+	p.Start()
+
+	const producers, perProducer = 8, 500
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func(producer int) {
+			defer wg.Done()
+			for j := 0; j < perProducer; j++ {
+				err := p.Enqueue(context.Background(), producer*perProducer+j)
+				switch {
+				case err == nil:
+					accepted.Add(1)
+				case errors.Is(err, ErrQueueStopped):
+					return
+				default:
+					t.Errorf("Enqueue = %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	stopWithin(t, p, 5*time.Second)
+	stopped.Store(true)
+	wg.Wait()
+
+	// Every accepted item was either stored before Stop returned or is still queued
+	snapshot := p.Snapshot()
+	if got := handled.Load() + int64(snapshot.Size); got != accepted.Load() {
+		t.Fatalf("handled %d + queued %d = %d items, want the %d accepted",
+			handled.Load(), snapshot.Size, got, accepted.Load())
+	}
+	if snapshot.Processing != 0 {
+		t.Fatalf("Processing = %d after Stop, want 0", snapshot.Processing)
+	}
+	if snapshot.Dequeued != handled.Load() {
+		t.Fatalf("Dequeued = %d, want the %d handled", snapshot.Dequeued, handled.Load())
+	}
+}
+
+func TestStopReleasesBlockedEnqueue(t *testing.T) {
+	release := make(chan struct{})
+	p := NewProcessor(testKind, testLogger(), func(ctx context.Context, items []int) (Summary, error) {
+		<-release
+		return Summary{Created: len(items)}, nil
+	}, Settings{Workers: 1, BatchSize: 1, MaxSize: 1,
+		Overflow: OverflowSettings{Policy: OverflowBlock, BlockTimeout: time.Minute}})
+	p.Start()
+
+	// The worker holds the first item and the second fills the queue
+	for i := 0; i < 2; i++ {
+		if err := p.Enqueue(context.Background(), i); err != nil {
+			t.Fatalf("Enqueue(%d) = %v", i, err)
+		}
+		for i == 0 && p.Snapshot().Processing == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- p.Enqueue(context.Background(), 2)
+	}()
+
+	stopDone := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(stopDone)
+	}()
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrQueueStopped) {
+			t.Fatalf("blocked Enqueue = %v, want %v", err, ErrQueueStopped)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked Enqueue was not released by Stop")
+	}
+
+	// Stop waits for the batch being stored
+	close(release)
+	select {
+	case <-stopDone:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return once the batch was stored")
+	}
+	if size := p.Snapshot().Size; size != 1 {
+		t.Fatalf("queue size after Stop = %d, want the 1 item left queued", size)
+	}
+}
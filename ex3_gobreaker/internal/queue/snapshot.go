@@ -0,0 +1,17 @@
+package queue
+
+// QueueSnapshot is a point-in-time view of a queue's depth and counters
+type QueueSnapshot struct {
+	Name           string `json:"name"`
+	Size           int    `json:"size"`
+	Processing     int    `json:"processing"`
+	Enqueued       int64  `json:"enqueued"`
+	Dequeued       int64  `json:"dequeued"`
+	MaxQueueLength int    `json:"maxQueueLength"`
+	Created        int64  `json:"created"`
+	Skipped        int64  `json:"skipped"`
+	Failed         int64  `json:"failed"`
+	Dropped        int64  `json:"dropped"`
+	Rejected       int64  `json:"rejected"`
+	Deduped        int64  `json:"deduped"`
+}
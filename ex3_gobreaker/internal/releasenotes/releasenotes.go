@@ -0,0 +1,348 @@
+// Package releasenotes splits the notes of a release into structured sections: the features, bug
+// fixes and breaking changes they list, the issues and pull requests they link and the users they
+// mention. The notes are the text of the rendered release body, so Markdown heading and list
+// markers are recognised when present but not required.
+package releasenotes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of the items of a release note
+const (
+	KindFeature  = "feature"
+	KindFix      = "fix"
+	KindBreaking = "breaking"
+	KindOther    = "other"
+)
+
+// Types of the references; a bare "#123" does not tell an issue from a pull request
+const (
+	ReferenceIssue = "issue"
+	ReferencePull  = "pull"
+)
+
+const (
+	// maxHeadingLength bounds a line taken for a heading without a Markdown heading marker
+	maxHeadingLength = 50
+	// maxHeadingWords bounds the words of such a line
+	maxHeadingWords = 5
+)
+
+// Item is an entry of the notes, usually a line of a list
+type Item struct {
+	Kind string
+	// Section is the heading the item is listed under, empty before the first heading
+	Section string
+	Text    string
+}
+
+// Reference is an issue or pull request linked from the notes
+type Reference struct {
+	// Repo is "owner/name" when the reference names its repository, empty for the release's own
+	Repo   string
+	Number int64
+	// Type is ReferenceIssue or ReferencePull for links, empty for "#123" references
+	Type string
+}
+
+// Notes is the structure extracted from the notes of a release
+type Notes struct {
+	Items      []Item
+	References []Reference
+	// Mentions are the mentioned users, without "@", in their first spelling
+	Mentions []string
+}
+
+var (
+	// markdownHeading is a "## Heading" line, or a line that is entirely bold
+	markdownHeading = regexp.MustCompile(`^(?:#{1,6}\s+(.+?)\s*#*|\*\*(.+?)\*\*:?|__(.+?)__:?)$`)
+	// listMarker starts a list entry: "-", "*", "+", "•", "1." or "1)", and a task checkbox
+	listMarker = regexp.MustCompile(`^(?:[-*+•]|\d{1,3}[.)])\s+(?:\[[ xX]\]\s+)?`)
+	// conventional is a Conventional Commits prefix, such as "feat(api)!: "
+	conventional = regexp.MustCompile(`(?i)^(feat|feature|fix|bugfix|hotfix)(?:\([^)]*\))?(!)?:\s*`)
+	// issueLink is the URL of an issue or pull request on GitHub
+	issueLink = regexp.MustCompile(`https?://github\.com/([A-Za-z0-9-]+/[A-Za-z0-9_.-]+)/(issues|pull)/(\d+)`)
+	// shortReference is "#123" or "owner/name#123", not inside a word, a URL path or an HTML entity
+	shortReference = regexp.MustCompile(`(?:^|[^\w/&#-])((?:[A-Za-z0-9-]+/[A-Za-z0-9_.-]+)?)#(\d+)\b`)
+	// mention is "@user", not inside a word, an e-mail address or a package name like "@types/node"
+	mention = regexp.MustCompile(`(?:^|[^\w.@/-])@([A-Za-z0-9](?:[A-Za-z0-9-]{0,38}))`)
+)
+
+// headingKinds classify a heading by the first group with a word it contains, so that "Breaking
+// fixes" is breaking and "New fixes" a fix
+var headingKinds = []struct {
+	kind  string
+	words []string
+}{
+	{KindBreaking, []string{"breaking", "incompatible", "incompatibilities", "backwards", "backward"}},
+	{KindFix, []string{"fix", "fixes", "fixed", "bug", "bugs", "bugfix", "bugfixes", "hotfix", "hotfixes", "security"}},
+	{KindFeature, []string{"feature", "features", "new", "added", "additions", "enhancement", "enhancements",
+		"improvement", "improvements", "highlights"}},
+}
+
+// neutralHeadings are headings of the usual generated notes that do not tell the kind of their
+// items, which are then classified one by one
+var neutralHeadings = map[string]bool{
+	"what's changed":    true,
+	"whats changed":     true,
+	"changes":           true,
+	"changelog":         true,
+	"change log":        true,
+	"other changes":     true,
+	"other":             true,
+	"misc":              true,
+	"miscellaneous":     true,
+	"maintenance":       true,
+	"dependencies":      true,
+	"documentation":     true,
+	"docs":              true,
+	"internal":          true,
+	"new contributors":  true,
+	"contributors":      true,
+	"notes":             true,
+	"release notes":     true,
+	"upgrade notes":     true,
+	"deprecations":      true,
+	"known issues":      true,
+	"full changelog":    true,
+	"acknowledgements":  true,
+	"acknowledgments":   true,
+	"performance":       true,
+	"refactoring":       true,
+	"under the hood":    true,
+	"behind the scenes": true,
+}
+
+// headingFillers are the words of headings that name no kind
+var headingFillers = map[string]bool{
+	"and":      true,
+	"changes":  true,
+	"change":   true,
+	"minor":    true,
+	"major":    true,
+	"notable":  true,
+	"other":    true,
+	"api":      true,
+	"the":      true,
+	"in":       true,
+	"this":     true,
+	"release":  true,
+	"version":  true,
+	"updates":  true,
+	"patches":  true,
+	"critical": true,
+}
+
+// leadingVerbs classify the items starting with them, such as the pull request titles of the
+// notes GitHub generates; other items are KindOther
+var leadingVerbs = map[string]string{
+	"fix":       KindFix,
+	"fixes":     KindFix,
+	"fixed":     KindFix,
+	"fixing":    KindFix,
+	"add":       KindFeature,
+	"adds":      KindFeature,
+	"added":     KindFeature,
+	"adding":    KindFeature,
+	"implement": KindFeature,
+	"introduce": KindFeature,
+}
+
+// Parse extracts the structure of release notes. Every non-blank line that is not a heading is an
+// item, of the kind of its section, or, in a section of no particular kind, of the kind given by a
+// Conventional Commits prefix or the verb it starts with. References and mentions are collected
+// across the whole text, once each.
+func Parse(content string) Notes {
+	notes := Notes{
+		Items:      make([]Item, 0),
+		References: make([]Reference, 0),
+		Mentions:   make([]string, 0),
+	}
+
+	section, sectionKind := "", KindOther
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if heading, kind, ok := parseHeading(line); ok {
+			section, sectionKind = heading, kind
+			continue
+		}
+
+		text := strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if text == "" {
+			continue
+		}
+		kind := sectionKind
+		if kind == KindOther {
+			kind = itemKind(text)
+		}
+		notes.Items = append(notes.Items, Item{Kind: kind, Section: section, Text: text})
+	}
+
+	notes.References = references(content)
+	notes.Mentions = mentions(content)
+	return notes
+}
+
+// parseHeading reports whether line is a heading, returning its text and the kind of its items.
+// A line without heading marker is only taken for one when it is short, is not a list entry or a
+// sentence, and is a usual neutral heading or names a kind with heading words only.
+func parseHeading(line string) (string, string, bool) {
+	if match := markdownHeading.FindStringSubmatch(line); match != nil {
+		heading := strings.TrimSuffix(strings.TrimSpace(match[1]+match[2]+match[3]), ":")
+		return heading, headingKind(heading), true
+	}
+
+	if utf8.RuneCountInString(line) > maxHeadingLength || listMarker.MatchString(line) ||
+		strings.ContainsAny(line, "#@.,;!?()[]`") || strings.Contains(line, "://") {
+		return "", "", false
+	}
+	heading := strings.TrimSpace(strings.TrimSuffix(line, ":"))
+	words := headingWords(heading)
+	if len(words) == 0 || len(words) > maxHeadingWords {
+		return "", "", false
+	}
+	if neutralHeadings[strings.Join(words, " ")] {
+		return heading, KindOther, true
+	}
+	// Every word must belong to a heading, or a short item such as "Fix typo" would be taken for one
+	for _, word := range words {
+		if !headingWord(word) {
+			return "", "", false
+		}
+	}
+	if kind := headingKind(heading); kind != KindOther {
+		return heading, kind, true
+	}
+	return "", "", false
+}
+
+// headingWord reports whether word names a kind of items or is one of the fillers of headings,
+// as in "Features and Improvements"
+func headingWord(word string) bool {
+	if headingFillers[word] {
+		return true
+	}
+	for _, group := range headingKinds {
+		for _, candidate := range group.words {
+			if word == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headingKind is the kind of the items listed under heading, KindOther when it names none or is
+// a neutral heading such as "New Contributors"
+func headingKind(heading string) string {
+	words := headingWords(heading)
+	if neutralHeadings[strings.Join(words, " ")] {
+		return KindOther
+	}
+	for _, group := range headingKinds {
+		for _, word := range words {
+			for _, candidate := range group.words {
+				if word == candidate {
+					return group.kind
+				}
+			}
+		}
+	}
+	return KindOther
+}
+
+// headingWords are the lowercase words of a heading, without the emojis and punctuation
+// decorating it
+func headingWords(heading string) []string {
+	return strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '\'' || r == '-')
+	})
+}
+
+// itemKind classifies an item of a section of no particular kind by its Conventional Commits
+// prefix, a "BREAKING CHANGE" note or the verb it starts with
+func itemKind(text string) string {
+	if strings.Contains(strings.ToUpper(text), "BREAKING CHANGE") {
+		return KindBreaking
+	}
+	match := conventional.FindStringSubmatch(text)
+	if match == nil {
+		if words := headingWords(text); len(words) > 0 && leadingVerbs[words[0]] != "" {
+			return leadingVerbs[words[0]]
+		}
+		return KindOther
+	}
+	if match[2] == "!" {
+		return KindBreaking
+	}
+	if strings.EqualFold(match[1], "feat") || strings.EqualFold(match[1], "feature") {
+		return KindFeature
+	}
+	return KindFix
+}
+
+// references collects the issue and pull request links of content, then its "#123" references,
+// each in order of first appearance. A link and an "owner/name#123" reference to the same number
+// of the same repository are one reference, typed by the link. A bare "#123" stays a reference of
+// its own, since the notes do not name the repository it points into.
+func references(content string) []Reference {
+	found := make([]Reference, 0)
+	index := make(map[string]int)
+	add := func(repo string, number string, kind string) {
+		n, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || n <= 0 {
+			return
+		}
+		key := strings.ToLower(repo) + "#" + number
+		if i, ok := index[key]; ok {
+			if found[i].Type == "" {
+				found[i].Type = kind
+			}
+			return
+		}
+		index[key] = len(found)
+		found = append(found, Reference{Repo: repo, Number: n, Type: kind})
+	}
+
+	for _, match := range issueLink.FindAllStringSubmatch(content, -1) {
+		kind := ReferenceIssue
+		if match[2] == "pull" {
+			kind = ReferencePull
+		}
+		add(match[1], match[3], kind)
+	}
+	// The links are taken out first, so that their fragments are not read as references
+	for _, match := range shortReference.FindAllStringSubmatch(issueLink.ReplaceAllString(content, " "), -1) {
+		add(match[1], match[2], "")
+	}
+	return found
+}
+
+// mentions collects the users mentioned in content, in order of first appearance, ignoring case
+func mentions(content string) []string {
+	found := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range mention.FindAllStringSubmatchIndex(content, -1) {
+		end := match[3]
+		// "@scope/package" names a package, "@user.name" an e-mail domain or a decorator
+		if end < len(content) && (content[end] == '/' || content[end] == '.' && end+1 < len(content) &&
+			content[end+1] != ' ' && content[end+1] != '\n') {
+			continue
+		}
+		user := strings.TrimRight(content[match[2]:end], "-")
+		key := strings.ToLower(user)
+		if user == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		found = append(found, user)
+	}
+	return found
+}
@@ -0,0 +1,114 @@
+package releasenotes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		items      []Item
+		references []Reference
+		mentions   []string
+	}{
+		{
+			name:       "empty",
+			content:    "",
+			items:      []Item{},
+			references: []Reference{},
+			mentions:   []string{},
+		},
+		{
+			name:    "markdown sections",
+			content: "## Features\n- Add dark mode\n\n## Bug Fixes\n* Crash on start\n\n### Breaking Changes\n1. Drop Go 1.20",
+			items: []Item{
+				{Kind: KindFeature, Section: "Features", Text: "Add dark mode"},
+				{Kind: KindFix, Section: "Bug Fixes", Text: "Crash on start"},
+				{Kind: KindBreaking, Section: "Breaking Changes", Text: "Drop Go 1.20"},
+			},
+			references: []Reference{},
+			mentions:   []string{},
+		},
+		{
+			name:    "plain headings of rendered notes",
+			content: "New Features\nStreaming uploads\nBugfixes:\nTimeouts are honoured",
+			items: []Item{
+				{Kind: KindFeature, Section: "New Features", Text: "Streaming uploads"},
+				{Kind: KindFix, Section: "Bugfixes", Text: "Timeouts are honoured"},
+			},
+			references: []Reference{},
+			mentions:   []string{},
+		},
+		{
+			name: "neutral section classified by item",
+			content: "## What's Changed\n- feat(api): paging by cursor\n- fix!: rename the flag\n" +
+				"- Fix typo\n- Bump deps\n- Note: BREAKING CHANGE in config",
+			items: []Item{
+				{Kind: KindFeature, Section: "What's Changed", Text: "feat(api): paging by cursor"},
+				{Kind: KindBreaking, Section: "What's Changed", Text: "fix!: rename the flag"},
+				{Kind: KindFix, Section: "What's Changed", Text: "Fix typo"},
+				{Kind: KindOther, Section: "What's Changed", Text: "Bump deps"},
+				{Kind: KindBreaking, Section: "What's Changed", Text: "Note: BREAKING CHANGE in config"},
+			},
+			references: []Reference{},
+			mentions:   []string{},
+		},
+		{
+			name:    "new contributors is neutral",
+			content: "## New Contributors\n- @alice made their first contribution",
+			items: []Item{
+				{Kind: KindOther, Section: "New Contributors", Text: "@alice made their first contribution"},
+			},
+			references: []Reference{},
+			mentions:   []string{"alice"},
+		},
+		{
+			name: "references",
+			content: "- Fix leak by @bob in https://github.com/acme/tool/pull/12\n" +
+				"- See acme/tool#12, #7 and other/lib#3\n- Closes https://github.com/acme/tool/issues/9 and #7",
+			items: []Item{
+				{Kind: KindFix, Text: "Fix leak by @bob in https://github.com/acme/tool/pull/12"},
+				{Kind: KindOther, Text: "See acme/tool#12, #7 and other/lib#3"},
+				{Kind: KindOther, Text: "Closes https://github.com/acme/tool/issues/9 and #7"},
+			},
+			references: []Reference{
+				{Repo: "acme/tool", Number: 12, Type: ReferencePull},
+				{Repo: "acme/tool", Number: 9, Type: ReferenceIssue},
+				{Number: 7},
+				{Repo: "other/lib", Number: 3},
+			},
+			mentions: []string{"bob"},
+		},
+		{
+			name:       "not references",
+			content:    "Use &#35; entities, anchors like page#2 and issue-#4",
+			items:      []Item{{Kind: KindOther, Text: "Use &#35; entities, anchors like page#2 and issue-#4"}},
+			references: []Reference{},
+			mentions:   []string{},
+		},
+		{
+			name:       "mentions",
+			content:    "Thanks @Carol, @carol and @dave-! Mail me@example.com, install @types/node, use @dave.",
+			items:      []Item{{Kind: KindOther, Text: "Thanks @Carol, @carol and @dave-! Mail me@example.com, install @types/node, use @dave."}},
+			references: []Reference{},
+			mentions:   []string{"Carol", "dave"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notes := Parse(test.content)
+			if !reflect.DeepEqual(notes.Items, test.items) {
+				t.Errorf("items = %+v, want %+v", notes.Items, test.items)
+			}
+			if !reflect.DeepEqual(notes.References, test.references) {
+				t.Errorf("references = %+v, want %+v", notes.References, test.references)
+			}
+			if !reflect.DeepEqual(notes.Mentions, test.mentions) {
+				t.Errorf("mentions = %+v, want %+v", notes.Mentions, test.mentions)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// aggregateTimeLayouts are the text forms of the times an embedded SQLite database stores
+var aggregateTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// AggregateTime is a time computed by SQL, such as MAX(createdat). PostgreSQL returns it as a
+// time, but SQLite returns the text it stored since the result has no column type.
+type AggregateTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *AggregateTime) Scan(value interface{}) error {
+	*t = AggregateTime{}
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		t.Time, t.Valid = value, true
+		return nil
+	case []byte:
+		return t.parse(string(value))
+	case string:
+		return t.parse(value)
+	}
+	return fmt.Errorf("cannot scan %T into a time", value)
+}
+
+func (t *AggregateTime) parse(text string) error {
+	for _, layout := range aggregateTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time, t.Valid = parsed, true
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a time", text)
+}
+
+func (t AggregateTime) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return t.Time, nil
+}
+
+// Ptr returns the time, nil when SQL gave NULL
+func (t AggregateTime) Ptr() *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"crawler/baseline/internal/entity"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type CommitRepository struct {
@@ -16,3 +19,89 @@ func NewCommitRepository(log *logrus.Logger) *CommitRepository {
 		Log: log,
 	}
 }
+
+// FindStored finds the stored commits with the release and hash of the given ones, looking up
+// the hashes of each release with FindByHashes
+func (r *CommitRepository) FindStored(ctx context.Context, db *gorm.DB, commits []entity.Commit, stored *[]entity.Commit) error {
+	hashes := make(map[int64][]string)
+	releaseIDs := make([]int64, 0)
+	for _, commit := range commits {
+		if _, ok := hashes[commit.ReleaseID]; !ok {
+			releaseIDs = append(releaseIDs, commit.ReleaseID)
+		}
+		hashes[commit.ReleaseID] = append(hashes[commit.ReleaseID], commit.Hash)
+	}
+
+	for _, releaseID := range releaseIDs {
+		if err := r.FindByHashes(ctx, db, stored, releaseID, hashes[releaseID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByHashes appends to commits the stored commits of a release with any of hashes
+func (r *CommitRepository) FindByHashes(ctx context.Context, db *gorm.DB, commits *[]entity.Commit, releaseID int64, hashes []string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return findIn(db.Where("releaseid = ?", releaseID), "hash IN ?", hashes, commits)
+}
+
+// RepoCommit is a commit with the tag and publish date of the release it was crawled from
+type RepoCommit struct {
+	ID           int64      `gorm:"column:id"`
+	Hash         string     `gorm:"column:hash"`
+	Message      string     `gorm:"column:message"`
+	FilesChanged *int       `gorm:"column:fileschanged"`
+	Additions    *int       `gorm:"column:additions"`
+	Deletions    *int       `gorm:"column:deletions"`
+	ReleaseID    int64      `gorm:"column:releaseid"`
+	TagName      string     `gorm:"column:tagname"`
+	PublishedAt  *time.Time `gorm:"column:publishedat"`
+}
+
+// RepoCommitFilter selects the commits of a repository; empty fields select everything
+type RepoCommitFilter struct {
+	// Tag selects the commits of one release
+	Tag string
+	// From and To bound the publish date of the releases, To excluded
+	From *time.Time
+	To   *time.Time
+	// IncludeDeleted adds the commits of tombstoned releases
+	IncludeDeleted bool
+}
+
+// FindByRepo finds up to limit commits of the releases of a repository matching filter, newest
+// release first, skipping offset, and counts all those matching
+func (r *CommitRepository) FindByRepo(ctx context.Context, db *gorm.DB, commits *[]RepoCommit, repoID int64, filter RepoCommitFilter,
+	offset int, limit int) (int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	query := db.Table("commits").
+		Joins("JOIN releases ON releases.id = commits.releaseid").
+		Where("releases.repoid = ?", repoID)
+	if !filter.IncludeDeleted {
+		query = query.Scopes(NotTombstoned)
+	}
+	if filter.Tag != "" {
+		query = query.Where("releases.tagname = ?", filter.Tag)
+	}
+	if filter.From != nil {
+		query = query.Where("releases.publishedat >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("releases.publishedat < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	err := query.
+		Select("commits.id, commits.hash, commits.message, commits.fileschanged, commits.additions, " +
+			"commits.deletions, commits.releaseid, releases.tagname, releases.publishedat").
+		Order("COALESCE(releases.publishedat, releases.createdat) DESC, commits.releaseid DESC, commits.id").
+		Offset(offset).Limit(limit).
+		Scan(commits).Error
+	return total, err
+}
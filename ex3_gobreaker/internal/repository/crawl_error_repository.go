@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CrawlErrorRepository struct {
+	Repository[entity.CrawlError]
+	Log *logrus.Logger
+}
+
+func NewCrawlErrorRepository(log *logrus.Logger) *CrawlErrorRepository {
+	return &CrawlErrorRepository{
+		Log: log,
+	}
+}
+
+// Record stores the failure of an entity, or counts a retry when it already failed before
+func (r *CrawlErrorRepository) Record(ctx context.Context, db *gorm.DB, crawlErr *entity.CrawlError) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "entitytype"}, {Name: "entityid"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"message":      crawlErr.Message,
+			"httpstatus":   crawlErr.HTTPStatus,
+			"lastfailedat": crawlErr.LastFailedAt,
+			"retrycount":   gorm.Expr("crawl_errors.retrycount + 1"),
+		}),
+	}).Create(crawlErr).Error
+}
+
+// Clear removes the error of an entity that crawled successfully
+func (r *CrawlErrorRepository) Clear(ctx context.Context, db *gorm.DB, entityType string, entityID int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("entitytype = ? AND entityid = ?", entityType, entityID).Delete(&entity.CrawlError{}).Error
+}
+
+// FindRecent returns the latest failures of entityType, most recent first; ids, when given,
+// limit them to those crawl errors
+func (r *CrawlErrorRepository) FindRecent(ctx context.Context, db *gorm.DB, crawlErrs *[]entity.CrawlError, entityType string, ids []int64, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	query := db.Where("entitytype = ?", entityType).Order("lastfailedat DESC, id DESC").Limit(limit)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	return query.Find(crawlErrs).Error
+}
+
+// CountAll returns how many failures of entityType are recorded and the highest ID among them
+func (r *CrawlErrorRepository) CountAll(ctx context.Context, db *gorm.DB, entityType string) (int64, int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	var row struct {
+		Total int64
+		MaxID int64
+	}
+	err := db.Model(&entity.CrawlError{}).Select("COUNT(*) AS total, COALESCE(MAX(id), 0) AS max_id").
+		Where("entitytype = ?", entityType).Scan(&row).Error
+	return row.Total, row.MaxID, err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type CrawlRunRepository struct {
+	Repository[entity.CrawlRun]
+	Log *logrus.Logger
+}
+
+func NewCrawlRunRepository(log *logrus.Logger) *CrawlRunRepository {
+	return &CrawlRunRepository{
+		Log: log,
+	}
+}
+
+// CrawlRunFilter selects crawl runs; empty fields select everything
+type CrawlRunFilter struct {
+	Source string
+	// Endpoint matches the endpoints containing it, such as "/releases"
+	Endpoint string
+	APIKey   string
+	// Failed selects the runs that failed, or those that did not
+	Failed *bool
+	// From and To bound the start of the runs, To excluded
+	From *time.Time
+	To   *time.Time
+}
+
+// FindRecent returns the latest crawl runs matching filter, newest first
+func (r *CrawlRunRepository) FindRecent(ctx context.Context, db *gorm.DB, runs *[]entity.CrawlRun, filter CrawlRunFilter, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	query := db.Order("startedat DESC, id DESC").Limit(limit)
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Endpoint != "" {
+		query = query.Where("endpoint LIKE ?", "%"+filter.Endpoint+"%")
+	}
+	if filter.APIKey != "" {
+		query = query.Where("apikey = ?", filter.APIKey)
+	}
+	if filter.Failed != nil {
+		if *filter.Failed {
+			query = query.Where("statuscode >= 400")
+		} else {
+			query = query.Where("statuscode < 400")
+		}
+	}
+	if filter.From != nil {
+		query = query.Where("startedat >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("startedat < ?", *filter.To)
+	}
+	return query.Find(runs).Error
+}
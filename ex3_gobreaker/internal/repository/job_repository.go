@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type JobRepository struct {
+	Repository[entity.CrawlJob]
+	Log *logrus.Logger
+}
+
+func NewJobRepository(log *logrus.Logger) *JobRepository {
+	return &JobRepository{
+		Log: log,
+	}
+}
+
+// Claim marks the oldest job with the given status as running for workerID. The update only
+// succeeds while the job still has that status, so two workers never claim the same job.
+// It returns gorm.ErrRecordNotFound when there is nothing to claim.
+func (r *JobRepository) Claim(ctx context.Context, db *gorm.DB, job *entity.CrawlJob, status string, running string, workerID string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	for {
+		// A job with an ID would limit the lookup to that ID
+		*job = entity.CrawlJob{}
+		if err := db.Where("status = ?", status).Order("id").Take(job).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := db.Model(&entity.CrawlJob{}).
+			Where("id = ? AND status = ?", job.ID, status).
+			Updates(map[string]interface{}{
+				"status":    running,
+				"workerid":  workerID,
+				"startedat": now,
+				"attempts":  gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = running
+			job.WorkerID = workerID
+			job.StartedAt = &now
+			job.Attempts++
+			return nil
+		}
+		// Another worker claimed it first, try the next one
+	}
+}
+
+// FindRecent returns the latest jobs, newest first
+func (r *JobRepository) FindRecent(ctx context.Context, db *gorm.DB, jobs *[]entity.CrawlJob, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Order("id DESC").Limit(limit).Find(jobs).Error
+}
+
+// FindByStatus returns the jobs with the given status, oldest first
+func (r *JobRepository) FindByStatus(ctx context.Context, db *gorm.DB, jobs *[]entity.CrawlJob, status string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("status = ?", status).Order("id").Find(jobs).Error
+}
+
+// Requeue gives a job back to the queue with the pending status, as long as it still has the
+// running status; its attempts are kept
+func (r *JobRepository) Requeue(ctx context.Context, db *gorm.DB, id int64, running string, pending string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.CrawlJob{}).
+		Where("id = ? AND status = ?", id, running).
+		Updates(map[string]interface{}{
+			"status":    pending,
+			"workerid":  "",
+			"startedat": nil,
+			"progress":  "",
+		}).Error
+}
+
+type WorkerRepository struct {
+	Repository[entity.CrawlWorker]
+	Log *logrus.Logger
+}
+
+func NewWorkerRepository(log *logrus.Logger) *WorkerRepository {
+	return &WorkerRepository{
+		Log: log,
+	}
+}
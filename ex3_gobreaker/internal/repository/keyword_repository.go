@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type KeywordRepository struct {
+	Repository[entity.WatchlistKeyword]
+	Log *logrus.Logger
+}
+
+func NewKeywordRepository(log *logrus.Logger) *KeywordRepository {
+	return &KeywordRepository{
+		Log: log,
+	}
+}
+
+// KeywordSubscription is a keyword of a watchlist applied to one of its repositories
+type KeywordSubscription struct {
+	KeywordID   int64  `gorm:"column:keywordid"`
+	Keyword     string `gorm:"column:keyword"`
+	WatchlistID int64  `gorm:"column:watchlistid"`
+	Watchlist   string `gorm:"column:watchlist"`
+	RepoID      int64  `gorm:"column:repoid"`
+}
+
+// FindByWatchlist finds the keywords of a watchlist, oldest first
+func (r *KeywordRepository) FindByWatchlist(ctx context.Context, db *gorm.DB, keywords *[]entity.WatchlistKeyword, watchlistID int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("watchlistid = ?", watchlistID).Order("id").Find(keywords).Error
+}
+
+// FindSubscriptions finds the keywords of the watchlists containing the given repositories
+func (r *KeywordRepository) FindSubscriptions(ctx context.Context, db *gorm.DB, subscriptions *[]KeywordSubscription, repoIDs []int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Table("watchlist_keywords").
+		Select("watchlist_keywords.id AS keywordid, watchlist_keywords.keyword, watchlist_keywords.watchlistid, "+
+			"watchlists.name AS watchlist, watchlist_repos.repoid").
+		Joins("JOIN watchlists ON watchlists.id = watchlist_keywords.watchlistid").
+		Joins("JOIN watchlist_repos ON watchlist_repos.watchlistid = watchlist_keywords.watchlistid").
+		Where("watchlist_repos.repoid IN ?", repoIDs).
+		Order("watchlist_keywords.id").
+		Scan(subscriptions).Error
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type OutboxRepository struct {
+	Repository[entity.OutboxEvent]
+	Log *logrus.Logger
+}
+
+func NewOutboxRepository(log *logrus.Logger) *OutboxRepository {
+	return &OutboxRepository{
+		Log: log,
+	}
+}
+
+// CreateAll stores events, usually in the transaction of the rows they are about
+func (r *OutboxRepository) CreateAll(ctx context.Context, db *gorm.DB, events []entity.OutboxEvent) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Create(&events).Error
+}
+
+// Claim leases up to limit undelivered events that are due, oldest first, until now+lease.
+// An event is only leased while no other lease holds it, so two dispatchers never deliver it
+// at the same time; an event whose dispatcher died is leased again once its lease ends.
+func (r *OutboxRepository) Claim(ctx context.Context, db *gorm.DB, events *[]entity.OutboxEvent, now time.Time,
+	lease time.Duration, limit int, maxAttempts int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+
+	var due []entity.OutboxEvent
+	if err := db.Where("deliveredat IS NULL AND nextattemptat <= ? AND attempts < ?", now, maxAttempts).
+		Where("lockeduntil IS NULL OR lockeduntil < ?", now).
+		Order("id").Limit(limit).Find(&due).Error; err != nil {
+		return err
+	}
+
+	lockedUntil := now.Add(lease)
+	*events = (*events)[:0]
+	for _, event := range due {
+		result := db.Model(&entity.OutboxEvent{}).
+			Where("id = ? AND (lockeduntil IS NULL OR lockeduntil < ?)", event.ID, now).
+			Update("lockeduntil", lockedUntil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			event.LockedUntil = &lockedUntil
+			*events = append(*events, event)
+		}
+		// Otherwise another dispatcher leased it first
+	}
+	return nil
+}
+
+// MarkDelivered records the delivery of an event and releases its lease
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, db *gorm.DB, id int64, deliveredAt time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deliveredat": deliveredAt,
+			"lockeduntil": nil,
+		}).Error
+}
+
+// MarkFailed counts a failed delivery of an event and releases its lease until nextAttemptAt
+func (r *OutboxRepository) MarkFailed(ctx context.Context, db *gorm.DB, id int64, message string, nextAttemptAt time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":      gorm.Expr("attempts + 1"),
+			"lasterror":     message,
+			"nextattemptat": nextAttemptAt,
+			"lockeduntil":   nil,
+		}).Error
+}
+
+// DeleteDelivered removes the events delivered before the given time and returns how many
+func (r *OutboxRepository) DeleteDelivered(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	result := db.Where("deliveredat < ?", before).Delete(&entity.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// CountPending counts the undelivered events that will still be attempted
+func (r *OutboxRepository) CountPending(ctx context.Context, db *gorm.DB, maxAttempts int) (int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	var total int64
+	err := db.Model(&entity.OutboxEvent{}).Where("deliveredat IS NULL AND attempts < ?", maxAttempts).
+		Count(&total).Error
+	return total, err
+}
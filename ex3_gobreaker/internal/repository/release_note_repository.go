@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// releaseNoteBatchSize bounds the rows of one insert of structured release notes
+const releaseNoteBatchSize = 500
+
+// ReleaseNoteRepository stores the structured notes of releases: their items, references and
+// mentions, each in a table of its own
+type ReleaseNoteRepository struct {
+	Repository[entity.ReleaseNoteItem]
+	Log *logrus.Logger
+}
+
+func NewReleaseNoteRepository(log *logrus.Logger) *ReleaseNoteRepository {
+	return &ReleaseNoteRepository{
+		Log: log,
+	}
+}
+
+// CreateAll stores the items, references and mentions of some releases, usually in the
+// transaction storing the releases
+func (r *ReleaseNoteRepository) CreateAll(ctx context.Context, db *gorm.DB, items []entity.ReleaseNoteItem,
+	references []entity.ReleaseNoteReference, mentions []entity.ReleaseNoteMention) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	if len(items) > 0 {
+		if err := db.CreateInBatches(items, releaseNoteBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(references) > 0 {
+		if err := db.CreateInBatches(references, releaseNoteBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(mentions) > 0 {
+		return db.CreateInBatches(mentions, releaseNoteBatchSize).Error
+	}
+	return nil
+}
+
+// DeleteByReleases deletes the items, references and mentions of some releases
+func (r *ReleaseNoteRepository) DeleteByReleases(ctx context.Context, db *gorm.DB, releaseIDs []int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	if err := db.Where("releaseid IN ?", releaseIDs).Delete(&entity.ReleaseNoteItem{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("releaseid IN ?", releaseIDs).Delete(&entity.ReleaseNoteReference{}).Error; err != nil {
+		return err
+	}
+	return db.Where("releaseid IN ?", releaseIDs).Delete(&entity.ReleaseNoteMention{}).Error
+}
+
+// FindByRelease finds the items of a release in the order of its notes, and its references and
+// mentions in the order they were stored
+func (r *ReleaseNoteRepository) FindByRelease(ctx context.Context, db *gorm.DB, releaseID int64,
+	items *[]entity.ReleaseNoteItem, references *[]entity.ReleaseNoteReference,
+	mentions *[]entity.ReleaseNoteMention) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	if err := db.Where("releaseid = ?", releaseID).Order("position").Find(items).Error; err != nil {
+		return err
+	}
+	if err := db.Where("releaseid = ?", releaseID).Order("id").Find(references).Error; err != nil {
+		return err
+	}
+	return db.Where("releaseid = ?", releaseID).Order("id").Find(mentions).Error
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"crawler/baseline/internal/entity"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type ReleaseRepository struct {
@@ -16,3 +19,87 @@ func NewReleaseRepository(log *logrus.Logger) *ReleaseRepository {
 		Log: log,
 	}
 }
+
+// StoredContent is the content of a release as it is stored, compressed or not
+type StoredContent struct {
+	ID      int64  `gorm:"column:id"`
+	Content string `gorm:"column:content"`
+}
+
+// FindStoredContents finds the stored contents of up to limit releases after afterID, by ID.
+// They are read from the table rather than through entity.Release, which would decompress them.
+func (r *ReleaseRepository) FindStoredContents(ctx context.Context, db *gorm.DB, contents *[]StoredContent, afterID int64, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Table("releases").Select("id", "content").
+		Where("id > ?", afterID).Order("id").Limit(limit).Find(contents).Error
+}
+
+// UpdateStoredContent replaces the stored content of a release as is
+func (r *ReleaseRepository) UpdateStoredContent(ctx context.Context, db *gorm.DB, id int64, stored string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Table("releases").Where("id = ?", id).Update("content", stored).Error
+}
+
+// NotTombstoned is a scope leaving out the releases that are gone from GitHub
+func NotTombstoned(db *gorm.DB) *gorm.DB {
+	return db.Where("releases.tombstonedat IS NULL")
+}
+
+// FindStored finds the stored releases with the repository and tag of the given ones
+func (r *ReleaseRepository) FindStored(ctx context.Context, db *gorm.DB, releases []entity.Release, stored *[]entity.Release) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	tags := make([][]interface{}, len(releases))
+	for i, release := range releases {
+		tags[i] = []interface{}{release.RepoID, release.TagName}
+	}
+	return findIn(db, "(repoid, tagname) IN ?", tags, stored)
+}
+
+// LatestActivity returns, for each repository with live releases, when its latest release was
+// published, or stored when GitHub gave no publish date
+func (r *ReleaseRepository) LatestActivity(ctx context.Context, db *gorm.DB, repoIDs []int64) (map[int64]time.Time, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	var rows []struct {
+		RepoID int64
+		Latest AggregateTime
+	}
+	if err := db.Model(&entity.Release{}).Scopes(NotTombstoned).
+		Select("repoid AS repo_id, MAX(COALESCE(publishedat, createdat)) AS latest").
+		Where("repoid IN ?", repoIDs).
+		Group("repoid").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		latest[row.RepoID] = row.Latest.Time
+	}
+	return latest, nil
+}
+
+// Tombstone marks releases missing, recording at as when they were found gone from GitHub
+func (r *ReleaseRepository) Tombstone(ctx context.Context, db *gorm.DB, ids []int64, at time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.Release{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":       entity.StatusMissing,
+		"statusat":     at,
+		"tombstonedat": at,
+	}).Error
+}
+
+// Restore makes tombstoned releases that showed up on GitHub again active
+func (r *ReleaseRepository) Restore(ctx context.Context, db *gorm.DB, ids []int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.Release{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":       entity.StatusActive,
+		"statusat":     time.Now(),
+		"tombstonedat": nil,
+	}).Error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RepoPolicyRepository struct {
+	Repository[entity.RepoPolicy]
+	Log *logrus.Logger
+}
+
+func NewRepoPolicyRepository(log *logrus.Logger) *RepoPolicyRepository {
+	return &RepoPolicyRepository{
+		Log: log,
+	}
+}
+
+// FindByRepoID loads the policy stored for a repository, failing with gorm.ErrRecordNotFound
+// when it has none
+func (r *RepoPolicyRepository) FindByRepoID(ctx context.Context, db *gorm.DB, policy *entity.RepoPolicy, repoID int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("repoid = ?", repoID).Take(policy).Error
+}
+
+// Upsert stores the policy of a repository, replacing the one stored before
+func (r *RepoPolicyRepository) Upsert(ctx context.Context, db *gorm.DB, policy *entity.RepoPolicy) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "repoid"}},
+		UpdateAll: true,
+	}).Create(policy).Error
+}
+
+// DeleteByRepoID removes the policy stored for a repository, returning whether there was one
+func (r *RepoPolicyRepository) DeleteByRepoID(ctx context.Context, db *gorm.DB, repoID int64) (bool, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	result := db.Where("repoid = ?", repoID).Delete(&entity.RepoPolicy{})
+	return result.RowsAffected > 0, result.Error
+}
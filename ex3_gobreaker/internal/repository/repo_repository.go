@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"context"
 	"crawler/baseline/internal/entity"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type RepoRepository struct {
@@ -16,3 +20,124 @@ func NewRepoRepository(log *logrus.Logger) *RepoRepository {
 		Log: log,
 	}
 }
+
+func (r *RepoRepository) UpdateDefaultBranch(ctx context.Context, db *gorm.DB, id int64, branch string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.Repository{}).Where("id = ?", id).Update("defaultbranch", branch).Error
+}
+
+// FindByName finds a repository by owner and name, ignoring case as GitHub does. Archived
+// repositories are found too, as they keep their name.
+func (r *RepoRepository) FindByName(ctx context.Context, db *gorm.DB, repo *entity.Repository, userName string, repoName string) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Unscoped().Where("LOWER(username) = LOWER(?) AND LOWER(reponame) = LOWER(?)", userName, repoName).Take(repo).Error
+}
+
+// FindStored finds the stored repositories with the owner and name of the given ones, ignoring
+// case, archived ones included
+func (r *RepoRepository) FindStored(ctx context.Context, db *gorm.DB, repos []entity.Repository, stored *[]entity.Repository) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	names := make([][]interface{}, len(repos))
+	for i, repo := range repos {
+		names[i] = []interface{}{strings.ToLower(repo.UserName), strings.ToLower(repo.RepoName)}
+	}
+	return findIn(db.Unscoped(), "(LOWER(username), LOWER(reponame)) IN ?", names, stored)
+}
+
+// FindCrawlable finds the repositories that are neither missing nor blocked at now
+func (r *RepoRepository) FindCrawlable(ctx context.Context, db *gorm.DB, repos *[]entity.Repository, now time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("status = ? OR (status = ? AND (retryat IS NULL OR retryat <= ?))",
+		entity.StatusActive, entity.StatusBlocked, now).Find(repos).Error
+}
+
+// FindDue finds up to limit crawlable repositories whose next decay re-crawl is due at now,
+// the never scheduled ones first, then the most overdue
+func (r *RepoRepository) FindDue(ctx context.Context, db *gorm.DB, repos *[]entity.Repository, now time.Time, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("status = ? OR (status = ? AND (retryat IS NULL OR retryat <= ?))",
+		entity.StatusActive, entity.StatusBlocked, now).
+		Where("nextcrawlat IS NULL OR nextcrawlat <= ?", now).
+		Order("nextcrawlat IS NOT NULL, nextcrawlat, id").
+		Limit(limit).
+		Find(repos).Error
+}
+
+// UpdateNextCrawl sets when the decay scheduler re-checks a repository
+func (r *RepoRepository) UpdateNextCrawl(ctx context.Context, db *gorm.DB, id int64, at time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.Repository{}).Where("id = ?", id).Update("nextcrawlat", at).Error
+}
+
+// UpdateStatus sets the crawl status of a repository; retryAt is only kept for blocked repositories.
+// An active repository was found on GitHub, which ends its run of 404s.
+func (r *RepoRepository) UpdateStatus(ctx context.Context, db *gorm.DB, id int64, status string, retryAt *time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	updates := map[string]interface{}{
+		"status":   status,
+		"statusat": time.Now(),
+		"retryat":  retryAt,
+	}
+	if status == entity.StatusActive {
+		updates["notfoundcount"] = 0
+	}
+	return db.Model(&entity.Repository{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// CountNotFound adds a 404 to the run of a repository
+func (r *RepoRepository) CountNotFound(ctx context.Context, db *gorm.DB, id int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.Repository{}).Where("id = ?", id).
+		Update("notfoundcount", gorm.Expr("notfoundcount + 1")).Error
+}
+
+// Archive marks a repository missing and archived, and soft deletes it, at now
+func (r *RepoRepository) Archive(ctx context.Context, db *gorm.DB, id int64, now time.Time) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Model(&entity.Repository{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    entity.StatusMissing,
+		"statusat":  now,
+		"retryat":   nil,
+		"archived":  true,
+		"deletedat": now,
+	}).Error
+}
+
+// FindByIdWithArchived finds a repository by ID, archived ones included
+func (r *RepoRepository) FindByIdWithArchived(ctx context.Context, db *gorm.DB, repo *entity.Repository, id int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Unscoped().Where("id = ?", id).Take(repo).Error
+}
+
+// Restore brings back an archived or missing repository as active, to be crawled again as soon
+// as possible. It fails with gorm.ErrRecordNotFound when there is no such repository.
+func (r *RepoRepository) Restore(ctx context.Context, db *gorm.DB, id int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	result := db.Unscoped().Model(&entity.Repository{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        entity.StatusActive,
+		"statusat":      time.Now(),
+		"retryat":       nil,
+		"notfoundcount": 0,
+		"archived":      false,
+		"deletedat":     nil,
+		"nextcrawlat":   nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -1,33 +1,154 @@
 package repository
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lookupBatchSize bounds the values of the IN list of one query of a batch lookup
+const lookupBatchSize = 500
+
+// queryTimeout bounds every repository call, in nanoseconds; zero leaves them to their context
+var queryTimeout atomic.Int64
+
+// SetQueryTimeout bounds each repository call by timeout, besides the deadline of its context,
+// so a slow query fails with context.DeadlineExceeded; zero or less removes the bound
+func SetQueryTimeout(timeout time.Duration) {
+	queryTimeout.Store(int64(max(0, timeout)))
+}
+
+// withContext binds db to ctx, bounded by the query timeout. cancel releases the timeout once
+// the call is done.
+func withContext(ctx context.Context, db *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if timeout := time.Duration(queryTimeout.Load()); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return db.WithContext(ctx), cancel
+}
 
 type Repository[T any] struct {
 	DB *gorm.DB
 }
 
-func (r *Repository[T]) Create(db *gorm.DB, entity *T) error {
+func (r *Repository[T]) Create(ctx context.Context, db *gorm.DB, entity *T) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
 	return db.Create(entity).Error
 }
 
-func (r *Repository[T]) Update(db *gorm.DB, entity *T) error {
+// CreateMissing inserts entities in batches, skipping those that conflict with a stored row on
+// a unique index, and returns how many were inserted. The IDs are only reliable when all were.
+func (r *Repository[T]) CreateMissing(ctx context.Context, db *gorm.DB, entities []T, batchSize int) (int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(entities, batchSize)
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository[T]) Update(ctx context.Context, db *gorm.DB, entity *T) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
 	return db.Save(entity).Error
 }
 
-func (r *Repository[T]) Delete(db *gorm.DB, entity *T) error {
+func (r *Repository[T]) Delete(ctx context.Context, db *gorm.DB, entity *T) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
 	return db.Delete(entity).Error
 }
 
-func (r *Repository[T]) CountById(db *gorm.DB, id any) (int64, error) {
+func (r *Repository[T]) CountById(ctx context.Context, db *gorm.DB, id int64) (int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
 	var total int64
 	err := db.Model(new(T)).Where("id = ?", id).Count(&total).Error
 	return total, err
 }
 
-func (r *Repository[T]) FindById(db *gorm.DB, entity *T, id any) error {
+func (r *Repository[T]) FindById(ctx context.Context, db *gorm.DB, entity *T, id int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
 	return db.Where("id = ?", id).Take(entity).Error
 }
 
-func (r *Repository[T]) FindAll(db *gorm.DB, entities *[]T) error {
+// FindByIds finds the stored rows with any of ids, looking them up in chunks; unknown IDs are
+// left out
+func (r *Repository[T]) FindByIds(ctx context.Context, db *gorm.DB, entities *[]T, ids []int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return findIn(db, "id IN ?", ids, entities)
+}
+
+// FindAfter finds at most limit of the rows selected by db with an ID above afterID, in ID
+// order, to page through them by keyset
+func (r *Repository[T]) FindAfter(ctx context.Context, db *gorm.DB, entities *[]T, afterID int64, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Where("id > ?", afterID).Order("id").Limit(limit).Find(entities).Error
+}
+
+// FindByParents finds the rows selected by db whose column holds any of parentIDs, looking them
+// up in chunks
+func (r *Repository[T]) FindByParents(ctx context.Context, db *gorm.DB, entities *[]T, column string, parentIDs []int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return findIn(db, column+" IN ?", parentIDs, entities)
+}
+
+// FindAfterByParents finds, for each of parentIDs, at most limit of the rows selected by db whose
+// column holds it and whose ID is above afterID, in ID order: a page of the children of many
+// parents in one query per chunk of parents
+func (r *Repository[T]) FindAfterByParents(ctx context.Context, db *gorm.DB, entities *[]T, column string, parentIDs []int64, afterID int64, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	for start := 0; start < len(parentIDs); start += lookupBatchSize {
+		ranked := db.Session(&gorm.Session{}).Model(new(T)).
+			Select("*, ROW_NUMBER() OVER (PARTITION BY "+column+" ORDER BY id) AS pagerow").
+			Where(column+" IN ?", parentIDs[start:min(start+lookupBatchSize, len(parentIDs))]).
+			Where("id > ?", afterID)
+		var found []T
+		err := db.Session(&gorm.Session{NewDB: true}).Unscoped().Table("(?) AS ranked", ranked).
+			Where("pagerow <= ?", limit).Order("id").Find(&found).Error
+		if err != nil {
+			return err
+		}
+		*entities = append(*entities, found...)
+	}
+	return nil
+}
+
+func (r *Repository[T]) FindAll(ctx context.Context, db *gorm.DB, entities *[]T) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
 	return db.Find(entities).Error
 }
+
+// FindInBatches calls process with the rows selected by db, batchSize at a time in primary key
+// order, so a whole table is never held in memory. The batch slice is reused between calls.
+func (r *Repository[T]) FindInBatches(ctx context.Context, db *gorm.DB, batchSize int, process func(batch []T) error) error {
+	db = db.WithContext(ctx)
+	var batch []T
+	return db.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return process(batch)
+	}).Error
+}
+
+// findIn appends to stored the rows matching query, an IN condition with a single placeholder,
+// for values taken lookupBatchSize at a time, so no query ships thousands of values. The
+// conditions already on db apply to every chunk.
+func findIn[T any, V any](db *gorm.DB, query string, values []V, stored *[]T) error {
+	db = db.Session(&gorm.Session{})
+	for start := 0; start < len(values); start += lookupBatchSize {
+		var found []T
+		if err := db.Where(query, values[start:min(start+lookupBatchSize, len(values))]).Find(&found).Error; err != nil {
+			return err
+		}
+		*stored = append(*stored, found...)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduleRepository struct {
+	Repository[entity.CrawlSchedule]
+	Log *logrus.Logger
+}
+
+func NewScheduleRepository(log *logrus.Logger) *ScheduleRepository {
+	return &ScheduleRepository{
+		Log: log,
+	}
+}
+
+// Seed stores a schedule unless one with the same name exists, so expressions edited at
+// runtime win over the config
+func (r *ScheduleRepository) Seed(ctx context.Context, db *gorm.DB, schedule *entity.CrawlSchedule) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(schedule).Error
+}
+
+// FindAll returns every schedule by name
+func (r *ScheduleRepository) FindAll(ctx context.Context, db *gorm.DB, schedules *[]entity.CrawlSchedule) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Order("name").Find(schedules).Error
+}
+
+// Claim moves a due schedule to its next run. The update only succeeds while the schedule is
+// still due at dueAt, so a run is started once even if its expression was edited meanwhile.
+func (r *ScheduleRepository) Claim(ctx context.Context, db *gorm.DB, name string, dueAt, next, now time.Time, running string) (bool, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	result := db.Model(&entity.CrawlSchedule{}).
+		Where("name = ? AND nextrunat = ?", name, dueAt).
+		Updates(map[string]interface{}{
+			"nextrunat":  next,
+			"lastrunat":  now,
+			"laststatus": running,
+			"lasterror":  "",
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// FinishRunning sets the last status of every schedule still marked running, returning how many
+func (r *ScheduleRepository) FinishRunning(ctx context.Context, db *gorm.DB, running string, status string, message string) (int64, error) {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	result := db.Model(&entity.CrawlSchedule{}).
+		Where("laststatus = ?", running).
+		Updates(map[string]interface{}{"laststatus": status, "lasterror": message})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SlowQueryPluginName is the name the slow query log is registered under in gorm.Config.Plugins
+const SlowQueryPluginName = "crawler:slow_query"
+
+const (
+	slowQueryStartKey = "crawler:slow_query_start"
+	// maxSlowQuerySQL bounds the statement kept for a slow query; a batch insert can run to
+	// megabytes of placeholders
+	maxSlowQuerySQL = 2000
+	// maxSlowQueryParams bounds the parameters described for a slow query
+	maxSlowQueryParams = 20
+)
+
+// SlowQuery is a statement that ran longer than the threshold. Its SQL keeps the placeholders
+// and its parameters are only described by type and size, so no crawled or secret value is kept.
+type SlowQuery struct {
+	At         time.Time `json:"at"`
+	DurationMs int64     `json:"durationMs"`
+	Table      string    `json:"table,omitempty"`
+	SQL        string    `json:"sql"`
+	Params     []string  `json:"params,omitempty"`
+	ParamCount int       `json:"paramCount"`
+	Rows       int64     `json:"rows"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SlowQueryStats counts the slow statements since the server started, with the latest ones
+type SlowQueryStats struct {
+	ThresholdMs int64            `json:"thresholdMs"`
+	Total       int64            `json:"total"`
+	MaxMs       int64            `json:"maxMs"`
+	ByTable     map[string]int64 `json:"byTable"`
+	Recent      []SlowQuery      `json:"recent"`
+}
+
+// SlowQueryLog is a gorm plugin timing every statement; those running longer than the
+// threshold are logged, counted by table and kept in a ring of the latest ones
+type SlowQueryLog struct {
+	log *logrus.Logger
+	// threshold is in nanoseconds; zero or less records nothing
+	threshold atomic.Int64
+
+	mutex   sync.Mutex
+	total   int64
+	max     time.Duration
+	byTable map[string]int64
+	// recent holds up to keep queries, next is where the following one goes
+	recent []SlowQuery
+	next   int
+	keep   int
+}
+
+func NewSlowQueryLog(log *logrus.Logger, threshold time.Duration, keep int) *SlowQueryLog {
+	slowQueries := &SlowQueryLog{
+		log:     log,
+		byTable: make(map[string]int64),
+		keep:    max(1, keep),
+	}
+	slowQueries.SetThreshold(threshold)
+	return slowQueries
+}
+
+// SetThreshold changes the duration from which a statement is slow; zero or less turns the log off
+func (l *SlowQueryLog) SetThreshold(threshold time.Duration) {
+	l.threshold.Store(int64(threshold))
+}
+
+func (l *SlowQueryLog) Name() string {
+	return SlowQueryPluginName
+}
+
+// Initialize times the create, query, update, delete, row and raw statements of db
+func (l *SlowQueryLog) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	statements := []struct {
+		start, finish callbackRegisterer
+	}{
+		{callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+	for _, statement := range statements {
+		if err := statement.start.Register(SlowQueryPluginName+":start", l.start); err != nil {
+			return err
+		}
+		if err := statement.finish.Register(SlowQueryPluginName+":finish", l.finish); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats returns the slow statement counters, with the latest statements first
+func (l *SlowQueryLog) Stats() SlowQueryStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	stats := SlowQueryStats{
+		ThresholdMs: time.Duration(l.threshold.Load()).Milliseconds(),
+		Total:       l.total,
+		MaxMs:       l.max.Milliseconds(),
+		ByTable:     make(map[string]int64, len(l.byTable)),
+		Recent:      make([]SlowQuery, 0, len(l.recent)),
+	}
+	for table, count := range l.byTable {
+		stats.ByTable[table] = count
+	}
+	for i := 1; i <= len(l.recent); i++ {
+		stats.Recent = append(stats.Recent, l.recent[(l.next-i+len(l.recent))%len(l.recent)])
+	}
+	return stats
+}
+
+func (l *SlowQueryLog) start(db *gorm.DB) {
+	if l.threshold.Load() > 0 {
+		db.InstanceSet(slowQueryStartKey, time.Now())
+	}
+}
+
+func (l *SlowQueryLog) finish(db *gorm.DB) {
+	threshold := time.Duration(l.threshold.Load())
+	value, ok := db.InstanceGet(slowQueryStartKey)
+	if !ok || threshold <= 0 {
+		return
+	}
+	elapsed := time.Since(value.(time.Time))
+	if elapsed < threshold {
+		return
+	}
+
+	query := SlowQuery{
+		At:         time.Now(),
+		DurationMs: elapsed.Milliseconds(),
+		Table:      db.Statement.Table,
+		SQL:        db.Statement.SQL.String(),
+		ParamCount: len(db.Statement.Vars),
+		Rows:       db.Statement.RowsAffected,
+	}
+	if len(query.SQL) > maxSlowQuerySQL {
+		query.SQL = query.SQL[:maxSlowQuerySQL] + "..."
+	}
+	for _, param := range db.Statement.Vars[:min(len(db.Statement.Vars), maxSlowQueryParams)] {
+		query.Params = append(query.Params, describeParam(param))
+	}
+	if db.Error != nil {
+		query.Error = db.Error.Error()
+	}
+	l.record(query, elapsed)
+
+	l.log.WithFields(logrus.Fields{
+		"duration_ms": query.DurationMs,
+		"table":       query.Table,
+		"rows":        query.Rows,
+		"params":      query.ParamCount,
+		"sql":         query.SQL,
+	}).Warn("Slow query")
+}
+
+func (l *SlowQueryLog) record(query SlowQuery, elapsed time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.total++
+	l.max = max(l.max, elapsed)
+	table := query.Table
+	if table == "" {
+		// Raw statements name no table
+		table = "other"
+	}
+	l.byTable[table]++
+	if len(l.recent) < l.keep {
+		l.recent = append(l.recent, query)
+	} else {
+		l.recent[l.next] = query
+	}
+	l.next = (l.next + 1) % l.keep
+}
+
+// describeParam names the type of a bound parameter, with the length of strings and byte slices,
+// in place of its value
+func describeParam(param interface{}) string {
+	switch value := param.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string(%d)", len(value))
+	case []byte:
+		return fmt.Sprintf("bytes(%d)", len(value))
+	}
+	return reflect.TypeOf(param).String()
+}
+
+// callbackRegisterer is the part of a gorm callback that registers a function
+type callbackRegisterer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TagRepository struct {
+	Repository[entity.Tag]
+	Log *logrus.Logger
+}
+
+func NewTagRepository(log *logrus.Logger) *TagRepository {
+	return &TagRepository{
+		Log: log,
+	}
+}
+
+// FindStored finds the stored tags with the repository and name of the given ones
+func (r *TagRepository) FindStored(ctx context.Context, db *gorm.DB, tags []entity.Tag, stored *[]entity.Tag) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	keys := make([][]interface{}, len(tags))
+	for i, tag := range tags {
+		keys[i] = []interface{}{tag.RepoID, tag.Name}
+	}
+	return findIn(db, "(repoid, name) IN ?", keys, stored)
+}
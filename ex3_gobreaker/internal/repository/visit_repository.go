@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type VisitRepository struct {
+	Repository[entity.Visit]
+	Log *logrus.Logger
+}
+
+func NewVisitRepository(log *logrus.Logger) *VisitRepository {
+	return &VisitRepository{
+		Log: log,
+	}
+}
+
+// FindRecent returns the latest visits whose URL contains the given text, newest first
+func (r *VisitRepository) FindRecent(ctx context.Context, db *gorm.DB, visits *[]entity.Visit, urlContains string, limit int) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	query := db.Order("visitedat DESC").Limit(limit)
+	if urlContains != "" {
+		query = query.Where("url LIKE ?", "%"+urlContains+"%")
+	}
+	return query.Find(visits).Error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type WatchlistRepository struct {
+	Repository[entity.Watchlist]
+	Log *logrus.Logger
+}
+
+func NewWatchlistRepository(log *logrus.Logger) *WatchlistRepository {
+	return &WatchlistRepository{
+		Log: log,
+	}
+}
+
+// FindWithRepos finds a watchlist by ID together with its repositories
+func (r *WatchlistRepository) FindWithRepos(ctx context.Context, db *gorm.DB, watchlist *entity.Watchlist, id int64) error {
+	db, cancel := withContext(ctx, db)
+	defer cancel()
+	return db.Preload("Repositories").Where("id = ?", id).Take(watchlist).Error
+}
@@ -0,0 +1,77 @@
+package scrape
+
+import (
+	"bytes"
+	"crawler/baseline/internal/archive"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ArchiveTransport fetches pages through Next and archives every response, whatever its status,
+// before handing it to the scraper. A page that cannot be archived is still returned, the
+// failure is only logged.
+type ArchiveTransport struct {
+	Log     *logrus.Logger
+	Archive *archive.Archive
+	Next    http.RoundTripper
+}
+
+func NewArchiveTransport(log *logrus.Logger, pages *archive.Archive, next http.RoundTripper) *ArchiveTransport {
+	return &ArchiveTransport{Log: log, Archive: pages, Next: next}
+}
+
+func (t *ArchiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fetchedAt := time.Now()
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if _, err := t.Archive.Put(req.Context(), &archive.Page{
+		URL:       req.URL.String(),
+		FetchedAt: fetchedAt,
+		Status:    resp.StatusCode,
+		Header:    resp.Header,
+		Body:      string(body),
+	}); err != nil {
+		t.Log.WithError(err).WithField("url", req.URL.String()).Warn("Error archiving fetched page")
+	}
+	return resp, nil
+}
+
+// ArchiveReplayTransport serves the pages kept by ArchiveTransport, each in its latest version
+// archived at or before At (the latest one for a zero At), and never reaches GitHub
+type ArchiveReplayTransport struct {
+	Archive *archive.Archive
+	At      time.Time
+}
+
+func NewArchiveReplayTransport(pages *archive.Archive, at time.Time) *ArchiveReplayTransport {
+	return &ArchiveReplayTransport{Archive: pages, At: at}
+}
+
+func (t *ArchiveReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	page, err := t.Archive.Latest(req.Context(), req.URL.String(), t.At)
+	if errors.Is(err, archive.ErrNoObject) {
+		return nil, fmt.Errorf("replaying %s: %w", req.URL, ErrNoRecording)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replaying %s: %w", req.URL, err)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return pageResponse(req, page.Status, page.Header, []byte(page.Body)), nil
+}
@@ -0,0 +1,125 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// branchesPerPage is the maximum page size accepted by the GitHub REST API
+const branchesPerPage = 100
+
+type BranchScrape struct {
+	Log   *logrus.Logger
+	Colly *colly.Collector
+
+	// Token is an optional GitHub token authenticating the API requests, as those of RepoScrape
+	Token string
+}
+
+type githubBranch struct {
+	Name string `json:"name"`
+}
+
+func NewBranchScrape(log *logrus.Logger, colly *colly.Collector) *BranchScrape {
+	return &BranchScrape{
+		Log:   log,
+		Colly: colly,
+	}
+}
+
+// newAPICollector clones the shared collector for a JSON API call
+func (s *BranchScrape) newAPICollector() *colly.Collector {
+	c := s.Colly.Clone()
+	c.OnRequest(func(req *colly.Request) {
+		req.Headers.Set("Accept", "application/vnd.github+json")
+		if s.Token != "" {
+			req.Headers.Set("Authorization", "Bearer "+s.Token)
+		}
+	})
+	return c
+}
+
+// DetectDefaultBranch asks the GitHub API for the repository's default branch
+func (s *BranchScrape) DetectDefaultBranch(repoOwner string, repoName string) (string, error) {
+	c := s.newAPICollector()
+
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	var crawlErr error
+
+	c.OnResponse(func(r *colly.Response) {
+		if err := json.Unmarshal(r.Body, &repo); err != nil {
+			crawlErr = fmt.Errorf("decoding repository %s/%s: %w", repoOwner, repoName, err)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching repository %s/%s: %w", repoOwner, repoName, responseError(r, err))
+	})
+
+	if err := c.Visit(fmt.Sprintf("https://api.github.com/repos/%s/%s", repoOwner, repoName)); err != nil {
+		return "", err
+	}
+	c.Wait()
+
+	if crawlErr != nil {
+		return "", crawlErr
+	}
+	if repo.DefaultBranch == "" {
+		return "", fmt.Errorf("repository %s/%s has no default branch", repoOwner, repoName)
+	}
+
+	s.Log.Infof("Default branch of %s/%s is %s", repoOwner, repoName, repo.DefaultBranch)
+	return repo.DefaultBranch, nil
+}
+
+// ListBranches returns the names of all branches of a repository
+func (s *BranchScrape) ListBranches(repoOwner string, repoName string) ([]string, error) {
+	c := s.newAPICollector()
+
+	branches := make([]string, 0)
+	pageCount := 0
+	var crawlErr error
+
+	c.OnResponse(func(r *colly.Response) {
+		var page []githubBranch
+		if err := json.Unmarshal(r.Body, &page); err != nil {
+			crawlErr = fmt.Errorf("decoding branches of %s/%s: %w", repoOwner, repoName, err)
+			return
+		}
+
+		pageCount = len(page)
+		for _, branch := range page {
+			branches = append(branches, branch.Name)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching branches of %s/%s: %w", repoOwner, repoName, responseError(r, err))
+	})
+
+	for page := 1; ; page++ {
+		pageCount = 0
+		pageURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches?per_page=%d&page=%d",
+			repoOwner, repoName, branchesPerPage, page)
+		if err := c.Visit(pageURL); err != nil {
+			return branches, err
+		}
+		c.Wait()
+
+		if crawlErr != nil {
+			return branches, crawlErr
+		}
+
+		// A short page means there is nothing left to fetch
+		if pageCount < branchesPerPage {
+			break
+		}
+	}
+
+	return branches, nil
+}
@@ -1,8 +1,13 @@
 package scrape
 
 import (
+	"context"
+	"crawler/baseline/internal/model"
 	"crawler/baseline/internal/utils"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/gocolly/colly/v2"
@@ -12,8 +17,22 @@ import (
 type CommitScrape struct {
 	Log   *logrus.Logger
 	Colly *colly.Collector
+
+	// FetchStats enables CrawlCommitStats, which costs one extra request per commit
+	FetchStats bool
+	// Pacer applies the requests per minute of RepoLimits; nil leaves the requests unpaced
+	Pacer *RepoPacer
+
+	// stats are the stats parsed from commit pages, reused while the page cache finds them unchanged
+	stats parsedPages[model.CommitStats]
 }
 
+var (
+	filesChangedPattern = regexp.MustCompile(`([\d,]+)\s+changed\s+files?`)
+	additionsPattern    = regexp.MustCompile(`([\d,]+)\s+additions?`)
+	deletionsPattern    = regexp.MustCompile(`([\d,]+)\s+deletions?`)
+)
+
 func NewCommitScrape(log *logrus.Logger, colly *colly.Collector) *CommitScrape {
 	return &CommitScrape{
 		Log:   log,
@@ -21,24 +40,99 @@ func NewCommitScrape(log *logrus.Logger, colly *colly.Collector) *CommitScrape {
 	}
 }
 
-func (s *CommitScrape) CrawlCommit(repoOwner string, repoName string, releaseTag string) []string {
+// StatsEnabled reports whether CrawlCommitStats should be called for new commits
+func (s *CommitScrape) StatsEnabled() bool {
+	return s.FetchStats
+}
+
+// CommitBatch receives the new commits of one compare page, formatted as
+// "Hash: <hash> - Message: <message>". An error stops the crawl and is returned by StreamCommits.
+type CommitBatch func(commits []string) error
+
+// StreamCommits crawls the commits between a release tag and the repository's default branch and
+// hands them to emit a compare page at a time, so a release with thousands of commits is never
+// held in memory whole. When the default branch is unknown it falls back to trying "master" then
+// "main". It returns the number of commits emitted, at most limits.MaxCommits when set.
+// The error matches ErrNotFound when the release is gone and ErrBlocked when GitHub refuses the crawl.
+// Cancelling ctx aborts the pending requests and stops before the next page, returning ctx's error.
+func (s *CommitScrape) StreamCommits(ctx context.Context, repoOwner string, repoName string, releaseTag string,
+	defaultBranch string, limits RepoLimits, emit CommitBatch) (int, error) {
 	log := s.Log
+	s.Pacer.Pace(repoOwner, repoName, limits.RequestsPerMinute)
 
-	commits := s.tryBranch(repoOwner, repoName, releaseTag, "master", log)
+	commitCount, err := s.countCommits(ctx, repoOwner, repoName, releaseTag)
+	if err != nil {
+		return 0, err
+	}
 
-	if len(commits) == 0 {
+	if defaultBranch != "" {
+		emitted, err := s.tryBranch(ctx, repoOwner, repoName, releaseTag, defaultBranch, commitCount, limits.MaxCommits, emit, log)
+		if err != nil {
+			return emitted, err
+		}
+		log.Infof("Total unique commits found: %d", emitted)
+		return emitted, nil
+	}
+
+	emitted, err := s.tryBranch(ctx, repoOwner, repoName, releaseTag, "master", commitCount, limits.MaxCommits, emit, log)
+	if err != nil {
+		return emitted, err
+	}
+
+	if emitted == 0 {
 		log.Info("No commits found with master branch, trying main branch")
-		commits = s.tryBranch(repoOwner, repoName, releaseTag, "main", log)
+		emitted, err = s.tryBranch(ctx, repoOwner, repoName, releaseTag, "main", commitCount, limits.MaxCommits, emit, log)
+		if err != nil {
+			return emitted, err
+		}
 	}
 
-	log.Infof("Total unique commits found: %d", len(commits))
-	return commits
+	log.Infof("Total unique commits found: %d", emitted)
+	return emitted, nil
 }
 
-func (s *CommitScrape) tryBranch(repoOwner string, repoName string, releaseTag string, branchName string, log *logrus.Logger) []string {
-	c := s.Colly
+// CrawlCommit collects all the commits of a release at once, see StreamCommits
+func (s *CommitScrape) CrawlCommit(ctx context.Context, repoOwner string, repoName string, releaseTag string,
+	defaultBranch string) ([]string, error) {
+	commits := make([]string, 0)
+	_, err := s.StreamCommits(ctx, repoOwner, repoName, releaseTag, defaultBranch, RepoLimits{}, func(batch []string) error {
+		commits = append(commits, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// countCommits reads the commit count from the release page, failing if the page is gone or blocked
+func (s *CommitScrape) countCommits(ctx context.Context, repoOwner string, repoName string, releaseTag string) (int, error) {
 	releaseURL := "https://github.com/" + repoOwner + "/" + repoName + "/releases/tag/" + releaseTag
-	commitCount := utils.GetNumCommitRelease(releaseURL)
+
+	c := s.Colly.Clone()
+	c.Context = ctx
+	var crawlErr error
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching release %s: %w", releaseTag, responseError(r, err))
+	})
+
+	commitCount := utils.GetNumCommitRelease(c, releaseURL)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return commitCount, crawlErr
+}
+
+// tryBranch emits the commits of the compare pages between a tag and a branch, a page at a time,
+// and returns how many it emitted, stopping after maxCommits when it is set. Only the hashes
+// already emitted are kept between pages. A branch that does not exist yields no commits; only
+// blocked and unexpected responses are errors.
+func (s *CommitScrape) tryBranch(ctx context.Context, repoOwner string, repoName string, releaseTag string,
+	branchName string, commitCount int, maxCommits int, emit CommitBatch, log *logrus.Logger) (int, error) {
+	// Use a clone so the handlers of one crawl don't leak into the shared collector,
+	// with ctx on its requests
+	c := s.Colly.Clone()
+	c.Context = ctx
 
 	baseURL := fmt.Sprintf("https://github.com/%s/%s/compare/commit-list?range=%s...%s",
 		repoOwner, repoName, releaseTag, branchName)
@@ -49,17 +143,21 @@ func (s *CommitScrape) tryBranch(repoOwner string, repoName string, releaseTag s
 		log.Info("Received response with status: ", r.StatusCode)
 	})
 
-	c.OnRequest(func(req *colly.Request) {
-		// log.Info("Visiting: ", req.URL.String())
+	var crawlErr error
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching commits of %s...%s: %w", releaseTag, branchName, responseError(r, err))
 	})
 
+	// commitMap holds the commits of the current page, in the order of pageHashes
 	commitMap := make(map[string]string)
+	pageHashes := make([]string, 0)
+	emitted := make(map[string]bool)
 
-	c.OnHTML("div.TimelineItem-body", func(e *colly.HTMLElement) {
+	utils.OnHTML(c, "commit_item", func(e *colly.HTMLElement) {
 		commitHash := ""
 		commitMsg := ""
 
-		e.ForEach("p.mb-1 a.Link--primary", func(_ int, link *colly.HTMLElement) {
+		utils.ForEach(e, "commit_link", func(_ int, link *colly.HTMLElement) {
 			href := link.Attr("href")
 			if strings.Contains(href, "/commit/") {
 				parts := strings.Split(href, "/commit/")
@@ -73,6 +171,7 @@ func (s *CommitScrape) tryBranch(repoOwner string, repoName string, releaseTag s
 							log.Infof("Updated commit %s with additional message: %s", commitHash, commitMsg)
 						} else {
 							commitMap[commitHash] = commitMsg
+							pageHashes = append(pageHashes, commitHash)
 							log.Infof("Found new commit: %s - %s", commitHash, commitMsg)
 						}
 					}
@@ -82,55 +181,163 @@ func (s *CommitScrape) tryBranch(repoOwner string, repoName string, releaseTag s
 	})
 
 	hasCommits := true
-	c.OnHTML("div.blankslate", func(e *colly.HTMLElement) {
+	utils.OnHTML(c, "commit_empty", func(e *colly.HTMLElement) {
 		if strings.Contains(e.Text, "There aren't any commits") {
 			hasCommits = false
 			log.Infof("No commits found with branch: %s", branchName)
 		}
 	})
 
-	c.OnHTML("div.Box-header", func(e *colly.HTMLElement) {
-		countText := e.ChildText("span.text-emphasized")
-		log.Info("Commit count info: ", countText)
+	utils.OnHTML(c, "commit_count_header", func(e *colly.HTMLElement) {
+		log.Info("Commit count info: ", strings.TrimSpace(e.Text))
 	})
 
-	c.OnHTML("div.js-navigation-container", func(e *colly.HTMLElement) {
+	utils.OnHTML(c, "commit_container", func(e *colly.HTMLElement) {
 		log.Info("Found commit container with child count: ", len(e.DOM.Children().Nodes))
 	})
 
+	// emitPage hands the new commits of the page just visited to emit
+	emitPage := func() error {
+		commits := make([]string, 0, len(pageHashes))
+		for _, hash := range pageHashes {
+			if emitted[hash] {
+				continue
+			}
+			if maxCommits > 0 && len(emitted) >= maxCommits {
+				break
+			}
+			emitted[hash] = true
+			commits = append(commits, fmt.Sprintf("Hash: %s - Message: %s", hash, commitMap[hash]))
+		}
+		clear(commitMap)
+		pageHashes = pageHashes[:0]
+		if len(commits) == 0 {
+			return nil
+		}
+		return emit(commits)
+	}
+
 	page := 1
+	if maxCommits > 0 {
+		commitCount = min(commitCount, maxCommits)
+	}
 	maxPages := (commitCount + 49) / 50 // Each page has ~50 commits
 
 	err := c.Visit(baseURL)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return 0, ctxErr
+	}
 	if err != nil {
 		log.Errorf("Error visiting URL with branch %s: %v", branchName, err)
-		return []string{}
+		return 0, nil
+	}
+	c.Wait()
+
+	if errors.Is(crawlErr, ErrNotFound) {
+		log.Infof("Branch %s not found", branchName)
+		return 0, nil
+	}
+	if crawlErr != nil {
+		return 0, crawlErr
 	}
 
 	if !hasCommits {
-		return []string{}
+		return 0, nil
+	}
+	if err := emitPage(); err != nil {
+		return len(emitted), err
 	}
 
-	for page < maxPages {
+	for page < maxPages && (maxCommits <= 0 || len(emitted) < maxCommits) {
+		if err := ctx.Err(); err != nil {
+			return len(emitted), err
+		}
 		page++
 		commitURL := fmt.Sprintf("%s&page=%d", baseURL, page)
 
-		// log.Infof("Visiting page %d of %d", page, maxPages)
+		log.Debugf("Visiting page %d of %d", page, maxPages)
 		err := c.Visit(commitURL)
 		if err != nil {
 			log.Error("Error visiting commit URL: ", err)
 			break
 		}
+		c.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return len(emitted), err
+		}
+		if errors.Is(crawlErr, ErrBlocked) {
+			return len(emitted), crawlErr
+		}
+		if crawlErr != nil {
+			log.WithError(crawlErr).Error("Error fetching commit page")
+			break
+		}
+		if err := emitPage(); err != nil {
+			return len(emitted), err
+		}
 
 		log.Infof("Completed page %d", page)
 	}
 
-	commits := make([]string, 0, len(commitMap))
-	for hash, message := range commitMap {
-		commitInfo := fmt.Sprintf("Hash: %s - Message: %s", hash, message)
-		commits = append(commits, commitInfo)
+	log.Infof("Found %d commits with branch: %s", len(emitted), branchName)
+	return len(emitted), nil
+}
+
+// CrawlCommitStats scrapes the files changed, additions and deletions summary from a commit page
+func (s *CommitScrape) CrawlCommitStats(ctx context.Context, repoOwner string, repoName string, hash string) (*model.CommitStats, error) {
+	commitURL := fmt.Sprintf("https://github.com/%s/%s/commit/%s", repoOwner, repoName, hash)
+
+	// Use a clone so the stats handlers don't leak into the shared collector
+	c := s.Colly.Clone()
+	c.Context = ctx
+
+	summary := ""
+	var crawlErr error
+	parse := s.stats.watch(c, commitURL)
+
+	utils.OnHTML(c, "commit_stats", func(e *colly.HTMLElement) {
+		summary = strings.Join(strings.Fields(e.Text), " ")
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		if !skippedUnchanged(err) {
+			crawlErr = fmt.Errorf("fetching commit %s: %w", hash, responseError(r, err))
+		}
+	})
+
+	if err := c.Visit(commitURL); err != nil && !skippedUnchanged(err) {
+		return nil, err
+	}
+	c.Wait()
+
+	if crawlErr != nil {
+		return nil, crawlErr
+	}
+	if parse.Unchanged {
+		stats := parse.Value
+		return &stats, nil
+	}
+	if summary == "" {
+		return nil, fmt.Errorf("no diff summary found for commit %s", hash)
+	}
+
+	stats := model.CommitStats{
+		FilesChanged: matchCount(filesChangedPattern, summary),
+		Additions:    matchCount(additionsPattern, summary),
+		Deletions:    matchCount(deletionsPattern, summary),
+	}
+	parse.Remember(stats)
+	return &stats, nil
+}
+
+// matchCount returns the number captured by pattern, or 0 when it is absent
+func matchCount(pattern *regexp.Regexp, text string) int {
+	match := pattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0
 	}
 
-	log.Infof("Found %d commits with branch: %s", len(commits), branchName)
-	return commits
+	count, _ := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+	return count
 }
@@ -0,0 +1,154 @@
+package scrape
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// FixtureTransport answers requests from pages recorded under Dir instead of the network, so
+// the scrapers parse the same HTML and JSON on every run.
+//
+// The page of https://github.com/owner/repo/tags is read from Dir/github.com/owner/repo/tags/index.html
+// (or index.json), and a query string is appended after "@", as in index@page=2.html. A page
+// without a file is answered with 404; a sibling .status file, such as index.status containing
+// "429", sets another status code.
+type FixtureTransport struct {
+	Dir string
+}
+
+func NewFixtureTransport(dir string) *FixtureTransport {
+	return &FixtureTransport{Dir: dir}
+}
+
+// NewFixtureCollector returns a collector that only reads pages from dir, for building
+// scrapers that never reach GitHub. It is asynchronous like the shared collector, since the
+// scrapers report failed pages from their error callbacks.
+func NewFixtureCollector(dir string) *colly.Collector {
+	c := colly.NewCollector(colly.Async(true))
+	c.WithTransport(NewFixtureTransport(dir))
+	return c
+}
+
+// FixturePath is the file of the page at u in dir, without its .html, .json or .status extension
+func FixturePath(dir string, u *url.URL) string {
+	name := "index"
+	if u.RawQuery != "" {
+		name += "@" + u.RawQuery
+	}
+	return filepath.Join(dir, u.Host, filepath.FromSlash(strings.Trim(u.Path, "/")), name)
+}
+
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := FixturePath(t.Dir, req.URL)
+
+	status := http.StatusOK
+	if raw, err := os.ReadFile(base + ".status"); err == nil {
+		if status, err = strconv.Atoi(strings.TrimSpace(string(raw))); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, page := range []struct{ ext, contentType string }{
+		{".html", "text/html; charset=utf-8"},
+		{".json", "application/json"},
+	} {
+		body, err := os.ReadFile(base + page.ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return pageResponse(req, status, http.Header{"Content-Type": []string{page.contentType}}, body), nil
+	}
+
+	if status == http.StatusOK {
+		status = http.StatusNotFound
+	}
+	return pageResponse(req, status, http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}}, nil), nil
+}
+
+// pageResponse builds the response of a page served from disk
+func pageResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// FixtureRecordTransport fetches pages through Next and saves them to Dir in the layout read by
+// FixtureTransport, replacing the fixtures of the same pages, so fixtures can be refreshed from
+// GitHub. A response other than 200 is saved with its status file.
+type FixtureRecordTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+func NewFixtureRecordTransport(dir string, next http.RoundTripper) *FixtureRecordTransport {
+	return &FixtureRecordTransport{Dir: dir, Next: next}
+}
+
+func (t *FixtureRecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.save(req.URL, resp, body); err != nil {
+		return nil, fmt.Errorf("saving fixture of %s: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+func (t *FixtureRecordTransport) save(u *url.URL, resp *http.Response, body []byte) error {
+	base := FixturePath(t.Dir, u)
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return err
+	}
+
+	ext := ".html"
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		ext = ".json"
+	}
+	// Remove the previous fixture of the page, which may have had the other extension
+	for _, old := range []string{".html", ".json", ".status"} {
+		if err := os.Remove(base + old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if err := os.WriteFile(base+".status", []byte(strconv.Itoa(resp.StatusCode)+"\n"), 0o644); err != nil {
+			return err
+		}
+		if len(body) == 0 {
+			return nil
+		}
+	}
+	return os.WriteFile(base+ext, body, 0o644)
+}
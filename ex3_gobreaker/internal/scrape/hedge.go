@@ -0,0 +1,139 @@
+package scrape
+
+import (
+	"context"
+	"crawler/baseline/internal/utils"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HedgeTransport sends a GET request a second time when it has no response after the delay of
+// its context, set by the circuit breaker of the call sending it (see utils.WithHedgeAfter), and
+// keeps the first response that is not a server error, cancelling the other request. A slow
+// page then costs one extra request instead of holding up the crawl of its repository. Requests
+// without a delay are sent once.
+type HedgeTransport struct {
+	Next http.RoundTripper
+	// Limit, when set, gives the second request a slot of its own, so hedging never sends more
+	// requests at once than the limit. The first request took its slot above this transport.
+	Limit *ParallelismLimit
+}
+
+func NewHedgeTransport(next http.RoundTripper) *HedgeTransport {
+	return &HedgeTransport{Next: next}
+}
+
+// hedgedResponse is the outcome of one of the requests of a hedged request
+type hedgedResponse struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+func (t *HedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	after := utils.HedgeAfter(req.Context())
+	// Only reads may be sent twice
+	if after <= 0 || (req.Method != http.MethodGet && req.Method != http.MethodHead) ||
+		(req.Body != nil && req.Body != http.NoBody) {
+		return t.Next.RoundTrip(req)
+	}
+
+	// Buffered for both requests, so the one not waited for does not block
+	outcomes := make(chan hedgedResponse, 2)
+	cancels := make([]context.CancelFunc, 0, 2)
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := t.roundTrip(req.Clone(ctx), index > 0)
+			outcomes <- hedgedResponse{index: index, resp: resp, err: err}
+		}()
+	}
+	// abandon cancels the requests but keep, and closes the responses of the running ones
+	// once they return
+	abandon := func(keep int, running int) {
+		for i, cancel := range cancels {
+			if i != keep {
+				cancel()
+			}
+		}
+		go func() {
+			for ; running > 0; running-- {
+				closeResponse(<-outcomes)
+			}
+		}()
+	}
+	send()
+
+	timer := time.NewTimer(after)
+	defer timer.Stop()
+	hedgeAt := timer.C
+	running := 1
+	for {
+		select {
+		case <-hedgeAt:
+			hedgeAt = nil
+			running++
+			send()
+		case done := <-outcomes:
+			running--
+			failed := done.err != nil || done.resp.StatusCode >= http.StatusInternalServerError
+			if failed && hedgeAt == nil && running > 0 {
+				// The other request may still succeed
+				cancels[done.index]()
+				closeResponse(done)
+				continue
+			}
+			abandon(done.index, running)
+			if done.err != nil {
+				cancels[done.index]()
+				return nil, done.err
+			}
+			// The request stays alive until its body is read
+			done.resp.Body = &cancelingBody{ReadCloser: done.resp.Body, cancel: cancels[done.index]}
+			return done.resp, nil
+		case <-req.Context().Done():
+			abandon(-1, running)
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// roundTrip sends req, first waiting for a slot of Limit when it is the hedged request
+func (t *HedgeTransport) roundTrip(req *http.Request, hedged bool) (*http.Response, error) {
+	if !hedged || t.Limit == nil {
+		return t.Next.RoundTrip(req)
+	}
+	if err := t.Limit.acquire(req); err != nil {
+		return nil, err
+	}
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		t.Limit.release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: t.Limit.release}
+	return resp, nil
+}
+
+func closeResponse(done hedgedResponse) {
+	if done.resp != nil {
+		done.resp.Body.Close()
+	}
+}
+
+// cancelingBody ends the request of the response it is the body of once closed
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (b *cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+	return err
+}
@@ -0,0 +1,110 @@
+package scrape
+
+import (
+	"crawler/baseline/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// orgReposPerPage is the maximum page size accepted by the GitHub REST API
+const orgReposPerPage = 100
+
+// ErrOrgNotFound is returned when GitHub has no organization of the requested name
+var ErrOrgNotFound = fmt.Errorf("organization %w", ErrNotFound)
+
+type OrgScrape struct {
+	Log   *logrus.Logger
+	Colly *colly.Collector
+	// Token is an optional GitHub token, which raises the API rate limit and lists the private
+	// repositories it grants access to
+	Token string
+}
+
+type orgRepo struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func NewOrgScrape(log *logrus.Logger, colly *colly.Collector) *OrgScrape {
+	return &OrgScrape{
+		Log:   log,
+		Colly: colly,
+	}
+}
+
+// CrawlOrgRepos enumerates all repositories of a GitHub organization through the REST API
+func (s *OrgScrape) CrawlOrgRepos(org string) ([]*model.CreateRepoRequest, error) {
+	s.Log.WithField("org", org).Info("Starting to scrape organization repositories")
+
+	// Use a clone so the JSON handlers don't leak into the shared collector
+	c := s.Colly.Clone()
+	c.OnRequest(func(req *colly.Request) {
+		req.Headers.Set("Accept", "application/vnd.github+json")
+		if s.Token != "" {
+			req.Headers.Set("Authorization", "Bearer "+s.Token)
+		}
+	})
+
+	repos := make([]*model.CreateRepoRequest, 0)
+	pageCount := 0
+	var crawlErr error
+
+	c.OnResponse(func(r *colly.Response) {
+		var page []orgRepo
+		if err := json.Unmarshal(r.Body, &page); err != nil {
+			crawlErr = fmt.Errorf("decoding repositories of %s: %w", org, err)
+			return
+		}
+
+		pageCount = len(page)
+		for _, repo := range page {
+			repos = append(repos, &model.CreateRepoRequest{
+				RepoName: repo.Name,
+				UserName: repo.Owner.Login,
+			})
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		statusErr := responseError(r, err)
+		if errors.Is(statusErr, ErrNotFound) {
+			crawlErr = ErrOrgNotFound
+			return
+		}
+		crawlErr = fmt.Errorf("fetching repositories of %s: %w", org, statusErr)
+	})
+
+	for page := 1; ; page++ {
+		pageCount = 0
+		pageURL := fmt.Sprintf("https://api.github.com/orgs/%s/repos?per_page=%d&page=%d",
+			url.PathEscape(org), orgReposPerPage, page)
+		if err := c.Visit(pageURL); err != nil {
+			s.Log.WithError(err).Errorf("Error visiting page %d", page)
+			return repos, err
+		}
+		c.Wait()
+
+		if crawlErr != nil {
+			s.Log.WithError(crawlErr).Errorf("Error scraping page %d", page)
+			return repos, crawlErr
+		}
+
+		// A short page means there is nothing left to fetch
+		if pageCount < orgReposPerPage {
+			break
+		}
+	}
+
+	s.Log.WithFields(logrus.Fields{
+		"org":         org,
+		"repos_found": len(repos),
+	}).Info("Organization repositories scraped")
+	return repos, nil
+}
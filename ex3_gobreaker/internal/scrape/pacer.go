@@ -0,0 +1,131 @@
+package scrape
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepoLimits bound the crawl of one repository, for those too large to crawl whole; zero
+// values leave the crawl unbounded
+type RepoLimits struct {
+	// MaxReleases keeps the newest releases of the repository only
+	MaxReleases int
+	// MaxCommits stops the commits of a release after that many
+	MaxCommits int
+	// RequestsPerMinute paces the requests about the repository, when the scraper has a Pacer
+	RequestsPerMinute float64
+}
+
+// Narrow returns the tighter of l and other for each limit
+func (l RepoLimits) Narrow(other RepoLimits) RepoLimits {
+	return RepoLimits{
+		MaxReleases:       tighter(l.MaxReleases, other.MaxReleases),
+		MaxCommits:        tighter(l.MaxCommits, other.MaxCommits),
+		RequestsPerMinute: tighter(l.RequestsPerMinute, other.RequestsPerMinute),
+	}
+}
+
+// tighter returns the smaller of two limits, where zero is no limit
+func tighter[T int | float64](a T, b T) T {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	return min(a, b)
+}
+
+// RepoPacer is a transport spacing out the requests about the repositories given a rate with
+// Pace, on github.com and api.github.com; requests about other repositories go straight through.
+// It goes in front of the parallelism limit, so a paced request waits without holding a slot.
+type RepoPacer struct {
+	Next http.RoundTripper
+
+	mutex sync.Mutex
+	// intervals and next are keyed by "owner/name" in lower case
+	intervals map[string]time.Duration
+	next      map[string]time.Time
+}
+
+func NewRepoPacer(next http.RoundTripper) *RepoPacer {
+	return &RepoPacer{
+		Next:      next,
+		intervals: make(map[string]time.Duration),
+		next:      make(map[string]time.Time),
+	}
+}
+
+// Pace sends at most requestsPerMinute requests a minute about a repository, or stops pacing it
+// when requestsPerMinute is 0. It does nothing on a nil pacer.
+func (p *RepoPacer) Pace(repoOwner string, repoName string, requestsPerMinute float64) {
+	if p == nil {
+		return
+	}
+
+	key := strings.ToLower(repoOwner + "/" + repoName)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if requestsPerMinute <= 0 {
+		delete(p.intervals, key)
+		delete(p.next, key)
+		return
+	}
+	p.intervals[key] = time.Duration(float64(time.Minute) / requestsPerMinute)
+}
+
+func (p *RepoPacer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := p.reserve(pacedRepo(req.URL)); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return p.Next.RoundTrip(req)
+}
+
+// reserve books the next slot of a paced repository and returns how long to wait for it
+func (p *RepoPacer) reserve(key string) time.Duration {
+	if key == "" {
+		return 0
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	interval, ok := p.intervals[key]
+	if !ok {
+		return 0
+	}
+	now := time.Now()
+	at := p.next[key]
+	if at.Before(now) {
+		at = now
+	}
+	p.next[key] = at.Add(interval)
+	return at.Sub(now)
+}
+
+// pacedRepo returns the "owner/name" a GitHub URL is about, in lower case, or "" for other URLs
+func pacedRepo(u *url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch u.Hostname() {
+	case "github.com":
+	case "api.github.com":
+		if len(segments) == 0 || segments[0] != "repos" {
+			return ""
+		}
+		segments = segments[1:]
+	default:
+		return ""
+	}
+	if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+		return ""
+	}
+	return strings.ToLower(segments[0] + "/" + segments[1])
+}
@@ -0,0 +1,223 @@
+package scrape
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crawler/baseline/internal/entity"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pageCachePruneInterval is how often the pages past their retention are deleted
+const pageCachePruneInterval = time.Hour
+
+// PageCache is an http.RoundTripper that keeps the pages fetched with an ETag or Last-Modified
+// header in the cached_pages table and fetches them again with If-None-Match and
+// If-Modified-Since. When the server answers 304 Not Modified, the cached page is handed to the
+// collector as a 200 response marked with PageCacheHeader; scrapers that remember what they
+// parsed from the page skip it, the others parse it as if it had been downloaded again. A 304 is
+// a few hundred bytes, and GitHub does not count it against the API rate limit.
+type PageCache struct {
+	Log       *logrus.Logger
+	DB        *gorm.DB
+	Transport http.RoundTripper
+	// MaxPageSize is the size in bytes above which a page is not cached, 0 for no limit
+	MaxPageSize int64
+	// Retention is how long a page the server has not confirmed is kept, 0 to keep it forever
+	Retention time.Duration
+}
+
+func NewPageCache(log *logrus.Logger, db *gorm.DB, transport http.RoundTripper, maxPageSize int64,
+	retention time.Duration) *PageCache {
+	c := &PageCache{
+		Log:         log,
+		DB:          db,
+		Transport:   transport,
+		MaxPageSize: maxPageSize,
+		Retention:   retention,
+	}
+
+	if retention > 0 {
+		go c.run()
+	}
+	return c
+}
+
+func (c *PageCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return c.Transport.RoundTrip(req)
+	}
+
+	url := req.URL.String()
+	cached := &entity.CachedPage{}
+	if err := c.DB.WithContext(req.Context()).Where("url = ?", url).Take(cached).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Log.WithError(err).WithField("url", url).Warn("Error reading cached page")
+		}
+		cached = nil
+	} else if c.expired(cached) {
+		cached = nil
+	}
+
+	if cached != nil {
+		// The request belongs to the collector, so the validators go on a copy
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.Transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return c.serveCached(req, resp, cached)
+	case resp.StatusCode == http.StatusOK:
+		return c.store(url, resp)
+	default:
+		return resp, nil
+	}
+}
+
+// serveCached answers a 304 with the cached page, keeping the headers of the 304
+func (c *PageCache) serveCached(req *http.Request, resp *http.Response, cached *entity.CachedPage) (*http.Response, error) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	body, err := gunzip(cached.Body)
+	if err != nil {
+		// The page can still be fetched whole
+		c.Log.WithError(err).WithField("url", cached.URL).Warn("Unreadable cached page, fetching it again")
+		c.DB.Where("url = ?", cached.URL).Delete(&entity.CachedPage{})
+		req.Header.Del("If-None-Match")
+		req.Header.Del("If-Modified-Since")
+		return c.Transport.RoundTrip(req)
+	}
+
+	now := time.Now()
+	if err := c.DB.Model(&entity.CachedPage{}).Where("url = ?", cached.URL).Update("checkedat", now).Error; err != nil {
+		c.Log.WithError(err).WithField("url", cached.URL).Warn("Error updating cached page")
+	}
+
+	header := resp.Header.Clone()
+	header.Set("Content-Type", cached.ContentType)
+	header.Del("Content-Length")
+	// The scrapers recognise the page they parsed by its validators, which a 304 need not repeat
+	if header.Get("ETag") == "" && cached.ETag != "" {
+		header.Set("ETag", cached.ETag)
+	}
+	if header.Get("Last-Modified") == "" && cached.LastModified != "" {
+		header.Set("Last-Modified", cached.LastModified)
+	}
+	header.Set(PageCacheHeader, pageCacheNotModified)
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         resp.Proto,
+		ProtoMajor:    resp.ProtoMajor,
+		ProtoMinor:    resp.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       resp.Request,
+	}, nil
+}
+
+// store caches a page that came with validators, then hands it on to the collector
+func (c *PageCache) store(url string, resp *http.Response) (*http.Response, error) {
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return resp, nil
+	}
+	if c.MaxPageSize > 0 && resp.ContentLength > c.MaxPageSize {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxPageSize > 0 && int64(len(body)) > c.MaxPageSize {
+		return resp, nil
+	}
+
+	compressed, err := gzipBytes(body)
+	if err != nil {
+		c.Log.WithError(err).WithField("url", url).Warn("Error compressing page")
+		return resp, nil
+	}
+	now := time.Now()
+	page := &entity.CachedPage{
+		URL:          url,
+		ETag:         etag,
+		LastModified: lastModified,
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         compressed,
+		FetchedAt:    now,
+		CheckedAt:    now,
+	}
+	if err := c.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "url"}},
+		UpdateAll: true,
+	}).Create(page).Error; err != nil {
+		c.Log.WithError(err).WithField("url", url).Warn("Error caching page")
+	}
+	return resp, nil
+}
+
+func (c *PageCache) expired(page *entity.CachedPage) bool {
+	return c.Retention > 0 && time.Since(page.CheckedAt) > c.Retention
+}
+
+// run deletes the pages past their retention, as nothing else removes the pages of URLs that
+// are not crawled anymore
+func (c *PageCache) run() {
+	ticker := time.NewTicker(pageCachePruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		result := c.DB.Where("checkedat < ?", time.Now().Add(-c.Retention)).Delete(&entity.CachedPage{})
+		if result.Error != nil {
+			c.Log.WithError(result.Error).Error("Error deleting expired cached pages")
+		} else if result.RowsAffected > 0 {
+			c.Log.WithField("pages", result.RowsAffected).Info("Deleted expired cached pages")
+		}
+	}
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("compressing page: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("compressing page: %w", err)
+	}
+	return compressed.Bytes(), nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing page: %w", err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
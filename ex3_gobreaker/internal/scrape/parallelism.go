@@ -0,0 +1,105 @@
+package scrape
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ParallelismLimit is a transport sending at most its limit of requests at once. Unlike the
+// parallelism of a colly LimitRule, which is fixed once the rule is added to the collector, the
+// limit can change while the crawler runs. A request counts until its response body is closed.
+type ParallelismLimit struct {
+	Next http.RoundTripper
+
+	mutex  sync.Mutex
+	limit  int
+	active int
+	// freed is closed and replaced whenever a request ends or the limit changes, waking the
+	// requests waiting for a slot
+	freed chan struct{}
+}
+
+func NewParallelismLimit(next http.RoundTripper, limit int) *ParallelismLimit {
+	return &ParallelismLimit{
+		Next:  next,
+		limit: max(1, limit),
+		freed: make(chan struct{}),
+	}
+}
+
+// SetLimit changes how many requests are sent at once. Requests beyond a lowered limit finish,
+// and new ones wait until fewer than the limit are in flight.
+func (l *ParallelismLimit) SetLimit(limit int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limit = max(1, limit)
+	l.wake()
+}
+
+// Limit returns how many requests are sent at once
+func (l *ParallelismLimit) Limit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limit
+}
+
+func (l *ParallelismLimit) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := l.acquire(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := l.Next.RoundTrip(req)
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: l.release}
+	return resp, nil
+}
+
+// acquire waits for a slot, or for the request to be cancelled
+func (l *ParallelismLimit) acquire(req *http.Request) error {
+	for {
+		l.mutex.Lock()
+		if l.active < l.limit {
+			l.active++
+			l.mutex.Unlock()
+			return nil
+		}
+		freed := l.freed
+		l.mutex.Unlock()
+
+		select {
+		case <-freed:
+		case <-req.Context().Done():
+			return req.Context().Err()
+		}
+	}
+}
+
+func (l *ParallelismLimit) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.active--
+	l.wake()
+}
+
+// wake lets the waiting requests check for a slot again; the caller must hold the mutex
+func (l *ParallelismLimit) wake() {
+	close(l.freed)
+	l.freed = make(chan struct{})
+}
+
+// releasingBody frees the slot of its request once closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,100 @@
+package scrape
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	// PageCacheHeader is set by PageCache on the pages it answers from the cache after a 304
+	PageCacheHeader      = "X-Page-Cache"
+	pageCacheNotModified = "not-modified"
+
+	// maxParsedPages bounds the pages a scraper remembers the parse of; past it, the memory is
+	// cleared and filled again
+	maxParsedPages = 10000
+)
+
+// parsedPages remembers what a scraper parsed from pages, by URL and the validators the page
+// came with. When PageCache confirms with a 304 that a page is unchanged, the download is
+// aborted after the headers and the remembered value is used instead of parsing the page again.
+// The zero value is ready to use.
+type parsedPages[T any] struct {
+	mutex   sync.Mutex
+	entries map[string]parsedPage[T]
+}
+
+type parsedPage[T any] struct {
+	validators string
+	value      T
+}
+
+// pageParse tracks one visit of a collector for parsedPages
+type pageParse[T any] struct {
+	pages      *parsedPages[T]
+	url        string
+	validators string
+	// Value is the remembered value when Unchanged is set
+	Value     T
+	Unchanged bool
+}
+
+// watch registers the callbacks of c that skip an unchanged page visited at url
+func (p *parsedPages[T]) watch(c *colly.Collector, url string) *pageParse[T] {
+	parse := &pageParse[T]{pages: p, url: url}
+	c.OnResponseHeaders(func(r *colly.Response) {
+		parse.validators = pageValidators(r.Headers)
+		if parse.validators == "" || r.Headers.Get(PageCacheHeader) != pageCacheNotModified {
+			return
+		}
+		if value, ok := p.get(url, parse.validators); ok {
+			parse.Value, parse.Unchanged = value, true
+			r.Request.Abort()
+		}
+	})
+	return parse
+}
+
+// Remember keeps value as the parse of the page, when the page came with validators
+func (p *pageParse[T]) Remember(value T) {
+	if p.validators == "" || p.Unchanged {
+		return
+	}
+	p.pages.mutex.Lock()
+	defer p.pages.mutex.Unlock()
+	if p.pages.entries == nil || len(p.pages.entries) >= maxParsedPages {
+		p.pages.entries = make(map[string]parsedPage[T])
+	}
+	p.pages.entries[p.url] = parsedPage[T]{validators: p.validators, value: value}
+}
+
+func (p *parsedPages[T]) get(url string, validators string) (T, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	page, ok := p.entries[url]
+	if !ok || page.validators != validators {
+		var zero T
+		return zero, false
+	}
+	return page.value, true
+}
+
+// skippedUnchanged reports whether err only tells that the download of an unchanged page was
+// aborted on purpose
+func skippedUnchanged(err error) bool {
+	return errors.Is(err, colly.ErrAbortedAfterHeaders)
+}
+
+func pageValidators(headers *http.Header) string {
+	if headers == nil {
+		return ""
+	}
+	etag, lastModified := headers.Get("ETag"), headers.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return ""
+	}
+	return etag + "\n" + lastModified
+}
@@ -0,0 +1,120 @@
+package scrape
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// recording is a fetched page as saved by RecordTransport
+type recording struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// RecordingPath is the file of the recording of rawURL in dir, named after the SHA-256 of the URL
+func RecordingPath(dir string, rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// RecordTransport fetches pages through Next and saves every response, whatever its status,
+// to Dir so that ReplayTransport can serve the same crawl again without the network
+type RecordTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+func NewRecordTransport(dir string, next http.RoundTripper) *RecordTransport {
+	return &RecordTransport{Dir: dir, Next: next}
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.save(&recording{
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   string(body),
+	}); err != nil {
+		return nil, fmt.Errorf("recording %s: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+// save writes through a temporary file so a concurrent replay never reads half a recording
+func (t *RecordTransport) save(rec *recording) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(t.Dir, "recording-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), RecordingPath(t.Dir, rec.URL))
+}
+
+// ErrNoRecording is returned by ReplayTransport for a page that was never recorded
+var ErrNoRecording = errors.New("page was not recorded")
+
+// ReplayTransport serves the pages saved by RecordTransport in Dir, with their recorded status,
+// headers and body, and never reaches the network
+type ReplayTransport struct {
+	Dir string
+}
+
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{Dir: dir}
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(RecordingPath(t.Dir, req.URL.String()))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("replaying %s: %w", req.URL, ErrNoRecording)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("replaying %s: %w", req.URL, err)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	return pageResponse(req, rec.Status, rec.Header, []byte(rec.Body)), nil
+}
@@ -1,16 +1,55 @@
 package scrape
 
 import (
+	"context"
+	"crawler/baseline/internal/model"
 	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
 	"github.com/sirupsen/logrus"
 )
 
+var releaseCountPattern = regexp.MustCompile(`\d+`)
+
+// ErrIncompleteListing is returned with the releases listed before a page of the release list
+// failed; tags missing from them may still exist, so they must not be tombstoned
+var ErrIncompleteListing = errors.New("release list is incomplete")
+
 type ReleaseScrape struct {
 	Log   *logrus.Logger
 	Colly *colly.Collector
+
+	// Pacer applies the requests per minute of RepoLimits; nil leaves the requests unpaced
+	Pacer *RepoPacer
+	// Token is an optional GitHub token authenticating the release API requests, which
+	// otherwise share the low rate limit of anonymous clients
+	Token string
+
+	// notes are the notes parsed from release pages, reused while the page cache finds them unchanged
+	notes parsedPages[string]
+}
+
+// githubRelease is the subset of the GitHub release API payload we store
+type githubRelease struct {
+	Name        string     `json:"name"`
+	PublishedAt *time.Time `json:"published_at"`
+	Prerelease  bool       `json:"prerelease"`
+	Author      struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Assets []struct {
+		Name          string `json:"name"`
+		Size          int64  `json:"size"`
+		DownloadCount int64  `json:"download_count"`
+	} `json:"assets"`
 }
 
 func NewReleaseScrape(log *logrus.Logger, colly *colly.Collector) *ReleaseScrape {
@@ -20,40 +59,195 @@ func NewReleaseScrape(log *logrus.Logger, colly *colly.Collector) *ReleaseScrape
 	}
 }
 
-func (s *ReleaseScrape) CrawlRelease(repoOwner string, repoName string, releaseTag string) string {
+// CrawlRelease scrapes the notes of a release from its page
+func (s *ReleaseScrape) CrawlRelease(ctx context.Context, repoOwner string, repoName string, releaseTag string) (string, error) {
 	releaseURL := "https://github.com/" + repoOwner + "/" + repoName + "/releases/tag/" + releaseTag
-	// s.Log.Info("Starting to scrape release: ", releaseURL)
-	s.Colly.OnRequest(func(req *colly.Request) {
-		// s.Log.Info("visiting: ", releaseURL)
-	})
+
+	// Use a clone so the content handler doesn't leak into the shared collector, with ctx on
+	// its requests
+	c := s.Colly.Clone()
+	c.Context = ctx
+
 	contentData := ""
-	s.Colly.OnHTML("div.Box-body", func(e *colly.HTMLElement) {
-		e.DOM.Find("div.markdown-body.my-3").Each(func(i int, s *goquery.Selection) {
-			contentData += s.Text() + "\n"
+	var crawlErr error
+	parse := s.notes.watch(c, releaseURL)
+	utils.OnHTML(c, "release_notes_box", func(e *colly.HTMLElement) {
+		utils.ForEach(e, "release_notes", func(_ int, notes *colly.HTMLElement) {
+			contentData += notes.Text + "\n"
 		})
 	})
 
-	err := s.Colly.Visit(releaseURL)
-	if err != nil {
-		s.Log.Error("Error visiting release URL: ", err)
-		return ""
+	c.OnError(func(r *colly.Response, err error) {
+		if !skippedUnchanged(err) {
+			crawlErr = fmt.Errorf("fetching release %s: %w", releaseTag, responseError(r, err))
+		}
+	})
+
+	if err := c.Visit(releaseURL); err != nil && !skippedUnchanged(err) {
+		return "", err
 	}
+	c.Wait()
+
+	if crawlErr != nil {
+		return "", crawlErr
+	}
+	if parse.Unchanged {
+		s.Log.Debug("Release page unchanged, reusing its notes: ", releaseTag)
+		return parse.Value, nil
+	}
+	parse.Remember(contentData)
 	s.Log.Info("Scraping completed for release: ", releaseTag)
-	// s.Log.Info("Content: ", contentData)
-	return contentData
+	return contentData, nil
 }
 
-func (s *ReleaseScrape) CrawlReleases(repoOwner string, repoName string) map[string]string {
-	releaseCount := utils.GetNumRelease(repoOwner, repoName)
-	releaseTags := utils.GetReleaseTags(repoOwner, repoName, releaseCount)
+// CrawlReleaseMetadata fetches title, publish date, author, pre-release flag and assets of a release.
+// Asset download counts are only exposed by the API, so this uses the release API instead of the HTML page.
+// The error matches ErrBlocked when the API rate limit is exhausted.
+func (s *ReleaseScrape) CrawlReleaseMetadata(ctx context.Context, repoOwner string, repoName string, releaseTag string) (*model.ReleaseData, error) {
+	// Tags may hold slashes and other characters reserved in paths
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", repoOwner, repoName,
+		url.PathEscape(releaseTag))
+
+	// Use a clone so the JSON handlers don't leak into the shared collector, with ctx on its
+	// requests
+	c := s.Colly.Clone()
+	c.Context = ctx
+	c.OnRequest(func(req *colly.Request) {
+		req.Headers.Set("Accept", "application/vnd.github+json")
+		if s.Token != "" {
+			req.Headers.Set("Authorization", "Bearer "+s.Token)
+		}
+	})
+
+	var release githubRelease
+	var crawlErr error
+
+	c.OnResponse(func(r *colly.Response) {
+		if err := json.Unmarshal(r.Body, &release); err != nil {
+			crawlErr = fmt.Errorf("decoding release %s: %w", releaseTag, err)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching release %s: %w", releaseTag, responseError(r, err))
+	})
 
-	releases := make(map[string]string, 0)
+	if err := c.Visit(apiURL); err != nil {
+		return nil, err
+	}
+	c.Wait()
+
+	if crawlErr != nil {
+		return nil, crawlErr
+	}
+
+	data := &model.ReleaseData{
+		Title:       release.Name,
+		PublishedAt: release.PublishedAt,
+		Author:      release.Author.Login,
+		Prerelease:  release.Prerelease,
+		Assets:      make([]model.CreateReleaseAssetRequest, 0, len(release.Assets)),
+	}
+	for _, asset := range release.Assets {
+		data.Assets = append(data.Assets, model.CreateReleaseAssetRequest{
+			Name:          asset.Name,
+			Size:          asset.Size,
+			DownloadCount: asset.DownloadCount,
+		})
+	}
+
+	return data, nil
+}
+
+// CrawlReleases scrapes every release of a repository, or its limits.MaxReleases newest ones.
+// The error matches ErrNotFound when the repository is gone and ErrBlocked when GitHub refuses
+// the crawl or the release API rate limit is exhausted; releases whose page is gone are left
+// out. When a page of the release list cannot be fetched, or the page of a release, the other
+// releases are returned with an error matching ErrIncompleteListing, and must not be taken as
+// all the releases of the repository. Its requests are sent with ctx.
+func (s *ReleaseScrape) CrawlReleases(ctx context.Context, repoOwner string, repoName string, limits RepoLimits) (map[string]*model.ReleaseData, error) {
+	s.Pacer.Pace(repoOwner, repoName, limits.RequestsPerMinute)
+	releaseCount, err := s.CountReleases(ctx, repoOwner, repoName)
+	if err != nil {
+		return nil, err
+	}
+	// The release list is newest first, so only the pages of the newest releases are read
+	if limits.MaxReleases > 0 {
+		releaseCount = min(releaseCount, limits.MaxReleases)
+	}
+	listing := s.Colly.Clone()
+	listing.Context = ctx
+	releaseTags, listErr := utils.GetReleaseTags(listing, repoOwner, repoName, releaseCount)
+	if listErr != nil {
+		listErr = fmt.Errorf("%w for %s/%s: %w", ErrIncompleteListing, repoOwner, repoName, listErr)
+	}
+	if limits.MaxReleases > 0 && len(releaseTags) > limits.MaxReleases {
+		releaseTags = releaseTags[:limits.MaxReleases]
+	}
+
+	releases := make(map[string]*model.ReleaseData, 0)
+	var releaseErrs []error
 	for i := 0; i < len(releaseTags); i++ {
 		releaseTag := releaseTags[i]
 
-		content := s.CrawlRelease(repoOwner, repoName, releaseTag)
+		content, err := s.CrawlRelease(ctx, repoOwner, repoName, releaseTag)
+		switch {
+		case errors.Is(err, ErrBlocked):
+			return nil, err
+		case errors.Is(err, ErrNotFound):
+			s.Log.WithError(err).Warnf("Release %s is gone, skipping it", releaseTag)
+			continue
+		case err != nil:
+			// Stored without its notes the release would not be crawled again
+			s.Log.WithError(err).Errorf("Error scraping release %s, skipping it", releaseTag)
+			releaseErrs = append(releaseErrs, fmt.Errorf("release %s: %w", releaseTag, err))
+			continue
+		}
+
+		data, err := s.CrawlReleaseMetadata(ctx, repoOwner, repoName, releaseTag)
+		switch {
+		case errors.Is(err, ErrBlocked):
+			// The other releases would fail the same until the rate limit resets
+			return nil, err
+		case err != nil:
+			// Metadata is best effort, the release body is still worth storing
+			s.Log.WithError(err).Warnf("Error fetching metadata for release %s", releaseTag)
+			data = &model.ReleaseData{}
+		}
+		data.Content = content
+
+		releases[releaseTag] = data
+	}
+	// The tags of the skipped releases still exist, so the listing is not complete either
+	if len(releaseErrs) > 0 {
+		listErr = errors.Join(listErr, fmt.Errorf("%w for %s/%s: %d releases failed: %w", ErrIncompleteListing,
+			repoOwner, repoName, len(releaseErrs), errors.Join(releaseErrs...)))
+	}
+	return releases, listErr
+}
+
+// CountReleases reads the release count from the repository's front page, which is also
+// where a deleted or blocked repository shows up first
+func (s *ReleaseScrape) CountReleases(ctx context.Context, repoOwner string, repoName string) (int, error) {
+	c := s.Colly.Clone()
+	c.Context = ctx
+
+	count := 0
+	var crawlErr error
+	utils.OnHTML(c, "release_count_link", func(e *colly.HTMLElement) {
+		if strings.Contains(e.Text, "Releases") {
+			count, _ = strconv.Atoi(releaseCountPattern.FindString(e.Text))
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching %s/%s: %w", repoOwner, repoName, responseError(r, err))
+	})
 
-		releases[releaseTag] = content
+	if err := c.Visit(fmt.Sprintf("https://github.com/%s/%s", repoOwner, repoName)); err != nil {
+		return 0, err
 	}
-	return releases
+	c.Wait()
+
+	return count, crawlErr
 }
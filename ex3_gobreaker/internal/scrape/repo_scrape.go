@@ -2,7 +2,11 @@ package scrape
 
 import (
 	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -10,11 +14,29 @@ import (
 	"github.com/gocolly/colly/v2"
 )
 
+// Bounds of CrawlAllRepos when the request does not set them
+const (
+	DefaultRepoLimit = 5000
+	DefaultRepoPages = 50
+)
+
+// gitstar-ranking.com lists this many repositories a page
+const reposPerPage = 100
+
 type RepoScrape struct {
 	Log   *logrus.Logger
 	Colly *colly.Collector
+
+	// Token is an optional GitHub token used by LookupRepo to access private repositories
+	Token string
 }
 
+var (
+	// ErrRepoNotFound also matches ErrNotFound
+	ErrRepoNotFound = fmt.Errorf("repository %w", ErrNotFound)
+	ErrRepoPrivate  = errors.New("repository is private")
+)
+
 func NewRepoScrape(log *logrus.Logger, colly *colly.Collector) *RepoScrape {
 	return &RepoScrape{
 		Log:   log,
@@ -22,8 +44,8 @@ func NewRepoScrape(log *logrus.Logger, colly *colly.Collector) *RepoScrape {
 	}
 }
 
-func (s *RepoScrape) CrawlAllRepos() ([]*model.CreateRepoRequest, error) {
-	limit := 5000
+// CrawlAllRepos scrapes at most limit repositories from the first maxPages pages of the ranking
+func (s *RepoScrape) CrawlAllRepos(limit int, maxPages int) ([]*model.CreateRepoRequest, error) {
 	s.Log.Info("Starting to scrape top repositories from gitstar-ranking.com")
 
 	repos := make([]*model.CreateRepoRequest, 0, limit)
@@ -31,10 +53,10 @@ func (s *RepoScrape) CrawlAllRepos() ([]*model.CreateRepoRequest, error) {
 	count := 0
 
 	s.Colly.OnRequest(func(req *colly.Request) {
-		// log.Println("visiting", req.URL.String())
+		s.Log.Debug("Visiting: ", req.URL.String())
 	})
 
-	s.Colly.OnHTML("a.list-group-item.paginated_item", func(e *colly.HTMLElement) {
+	utils.OnHTML(s.Colly, "repo_ranking_item", func(e *colly.HTMLElement) {
 		if count >= limit {
 			return
 		}
@@ -60,7 +82,8 @@ func (s *RepoScrape) CrawlAllRepos() ([]*model.CreateRepoRequest, error) {
 
 	// Start scraping
 	startPage := 1
-	maxPages := 50
+	// Pages past the limit would only be visited to be ignored
+	maxPages = min(maxPages, (limit+reposPerPage-1)/reposPerPage)
 
 	for page := startPage; page <= maxPages; page++ {
 		pageURL := fmt.Sprintf("https://gitstar-ranking.com/repositories?page=%d", page)
@@ -73,3 +96,130 @@ func (s *RepoScrape) CrawlAllRepos() ([]*model.CreateRepoRequest, error) {
 	// log.Infof("Found %d repositories", len(repos))
 	return repos, nil
 }
+
+// CrawlRepoMetadata scrapes description, stars, forks, primary language, topics and license
+// from a repository's GitHub front page
+func (s *RepoScrape) CrawlRepoMetadata(repoOwner string, repoName string) (*model.RepoMetadata, error) {
+	repoURL := fmt.Sprintf("https://github.com/%s/%s", repoOwner, repoName)
+
+	// Use a clone so the metadata handlers don't leak into the shared collector
+	c := s.Colly.Clone()
+
+	metadata := &model.RepoMetadata{}
+	found := false
+	var crawlErr error
+
+	utils.OnHTML(c, "repo_stars", func(e *colly.HTMLElement) {
+		found = true
+		metadata.Stars = parseCount(e.Attr("title"))
+	})
+
+	utils.OnHTML(c, "repo_forks", func(e *colly.HTMLElement) {
+		metadata.Forks = parseCount(e.Attr("title"))
+	})
+
+	utils.OnHTML(c, "repo_sidebar_cell", func(e *colly.HTMLElement) {
+		heading := utils.ChildText(e, "repo_sidebar_heading")
+		switch heading {
+		case "About":
+			metadata.Description = utils.ChildText(e, "repo_description")
+			utils.ForEach(e, "repo_topic", func(_ int, topic *colly.HTMLElement) {
+				metadata.Topics = append(metadata.Topics, strings.TrimSpace(topic.Text))
+			})
+			utils.ForEach(e, "repo_license_link", func(_ int, link *colly.HTMLElement) {
+				text := strings.Join(strings.Fields(link.Text), " ")
+				if metadata.License == "" && strings.Contains(strings.ToLower(text), "license") {
+					metadata.License = text
+				}
+			})
+		case "Languages":
+			// Languages are listed by share, so the first one is the primary language
+			utils.ForEach(e, "repo_language", func(i int, lang *colly.HTMLElement) {
+				if i == 0 {
+					metadata.Language = strings.TrimSpace(lang.Text)
+				}
+			})
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching %s/%s: %w", repoOwner, repoName, responseError(r, err))
+	})
+
+	if err := c.Visit(repoURL); err != nil {
+		return nil, err
+	}
+	c.Wait()
+
+	if crawlErr != nil {
+		return nil, crawlErr
+	}
+	if !found {
+		return nil, fmt.Errorf("no repository metadata found on %s", repoURL)
+	}
+
+	return metadata, nil
+}
+
+// parseCount parses a counter such as "12,345"
+func parseCount(text string) int64 {
+	count, _ := strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(text), ",", ""), 10, 64)
+	return count
+}
+
+// LookupRepo checks through the GitHub API that a repository exists and is accessible.
+// Private repositories are only accepted when a token is configured, since the API
+// then only returns them if the token grants access.
+func (s *RepoScrape) LookupRepo(repoOwner string, repoName string) (*model.GitHubRepo, error) {
+	c := s.Colly.Clone()
+	c.OnRequest(func(req *colly.Request) {
+		req.Headers.Set("Accept", "application/vnd.github+json")
+		if s.Token != "" {
+			req.Headers.Set("Authorization", "Bearer "+s.Token)
+		}
+	})
+
+	var repo struct {
+		Name          string `json:"name"`
+		Private       bool   `json:"private"`
+		DefaultBranch string `json:"default_branch"`
+		Owner         struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	}
+	var crawlErr error
+
+	c.OnResponse(func(r *colly.Response) {
+		if err := json.Unmarshal(r.Body, &repo); err != nil {
+			crawlErr = fmt.Errorf("decoding repository %s/%s: %w", repoOwner, repoName, err)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		statusErr := responseError(r, err)
+		if errors.Is(statusErr, ErrNotFound) {
+			crawlErr = ErrRepoNotFound
+			return
+		}
+		crawlErr = fmt.Errorf("fetching repository %s/%s: %w", repoOwner, repoName, statusErr)
+	})
+
+	if err := c.Visit(fmt.Sprintf("https://api.github.com/repos/%s/%s", repoOwner, repoName)); err != nil {
+		return nil, err
+	}
+	c.Wait()
+
+	if crawlErr != nil {
+		return nil, crawlErr
+	}
+	if repo.Private && s.Token == "" {
+		return nil, ErrRepoPrivate
+	}
+
+	return &model.GitHubRepo{
+		UserName:      repo.Owner.Login,
+		RepoName:      repo.Name,
+		Private:       repo.Private,
+		DefaultBranch: repo.DefaultBranch,
+	}, nil
+}
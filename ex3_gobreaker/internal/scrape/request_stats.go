@@ -0,0 +1,37 @@
+package scrape
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// RequestStats is an http.RoundTripper counting the requests made by the scrapers.
+// Collector clones share their parent's transport, so installing it on the shared
+// collector counts the requests of every scraper.
+type RequestStats struct {
+	Transport http.RoundTripper
+
+	requests int64
+	errors   int64
+}
+
+func NewRequestStats(transport http.RoundTripper) *RequestStats {
+	return &RequestStats{
+		Transport: transport,
+	}
+}
+
+func (s *RequestStats) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&s.requests, 1)
+
+	resp, err := s.Transport.RoundTrip(req)
+	if err != nil || resp.StatusCode >= 400 {
+		atomic.AddInt64(&s.errors, 1)
+	}
+	return resp, err
+}
+
+// Requests returns how many requests were made, and how many failed or got an error status
+func (s *RequestStats) Requests() (int64, int64) {
+	return atomic.LoadInt64(&s.requests), atomic.LoadInt64(&s.errors)
+}
@@ -0,0 +1,47 @@
+package scrape
+
+import (
+	"context"
+	"crawler/baseline/internal/model"
+)
+
+// RepoSource lists repositories and reads their front page metadata
+type RepoSource interface {
+	CrawlAllRepos(limit int, maxPages int) ([]*model.CreateRepoRequest, error)
+	CrawlRepoMetadata(repoOwner string, repoName string) (*model.RepoMetadata, error)
+	LookupRepo(repoOwner string, repoName string) (*model.GitHubRepo, error)
+}
+
+// ReleaseSource reads the releases of a repository, within its limits, sending its requests
+// with ctx
+type ReleaseSource interface {
+	CrawlReleases(ctx context.Context, repoOwner string, repoName string, limits RepoLimits) (map[string]*model.ReleaseData, error)
+}
+
+// CommitSource reads the commits of a release, a page at a time and within the limits of its
+// repository, and, when StatsEnabled, their diff stats. Both stop fetching pages once ctx is cancelled.
+type CommitSource interface {
+	StreamCommits(ctx context.Context, repoOwner string, repoName string, releaseTag string, defaultBranch string,
+		limits RepoLimits, emit CommitBatch) (int, error)
+	CrawlCommitStats(ctx context.Context, repoOwner string, repoName string, hash string) (*model.CommitStats, error)
+	StatsEnabled() bool
+}
+
+// TagSource reads the git tags of a repository
+type TagSource interface {
+	CrawlTags(repoOwner string, repoName string) ([]*model.CreateTagRequest, error)
+}
+
+// BranchSource reads the branches of a repository
+type BranchSource interface {
+	DetectDefaultBranch(repoOwner string, repoName string) (string, error)
+	ListBranches(repoOwner string, repoName string) ([]string, error)
+}
+
+var (
+	_ RepoSource    = (*RepoScrape)(nil)
+	_ ReleaseSource = (*ReleaseScrape)(nil)
+	_ CommitSource  = (*CommitScrape)(nil)
+	_ TagSource     = (*TagScrape)(nil)
+	_ BranchSource  = (*BranchScrape)(nil)
+)
@@ -0,0 +1,78 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+var (
+	// ErrNotFound matches pages GitHub answers with 404 or 410: the repository or release was
+	// deleted, renamed or made private, and crawling it again will not help
+	ErrNotFound = errors.New("not found on GitHub")
+	// ErrBlocked matches pages GitHub refused with 403 or 429 because of rate limits or abuse
+	// detection; the crawl should be retried after RetryAfter
+	ErrBlocked = errors.New("blocked by GitHub")
+)
+
+// defaultRetryAfter is how long a blocked crawl waits when GitHub does not say
+const defaultRetryAfter = time.Hour
+
+// StatusError is a failed GitHub response. It matches ErrNotFound or ErrBlocked with errors.Is.
+type StatusError struct {
+	Status int
+	// RetryAfter is the delay GitHub asked for through Retry-After or X-RateLimit-Reset
+	RetryAfter time.Duration
+	Err        error
+}
+
+// responseError builds the StatusError of a colly error callback
+func responseError(r *colly.Response, err error) *StatusError {
+	statusErr := &StatusError{Status: r.StatusCode, Err: err}
+	if r.Headers == nil {
+		return statusErr
+	}
+
+	if seconds, parseErr := strconv.Atoi(r.Headers.Get("Retry-After")); parseErr == nil {
+		statusErr.RetryAfter = time.Duration(seconds) * time.Second
+	} else if reset, parseErr := strconv.ParseInt(r.Headers.Get("X-RateLimit-Reset"), 10, 64); parseErr == nil {
+		statusErr.RetryAfter = time.Until(time.Unix(reset, 0))
+	}
+	return statusErr
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d: %v", e.Status, e.Err)
+}
+
+// StatusCode returns the status of the response, for utils.IsTransient
+func (e *StatusError) StatusCode() int {
+	return e.Status
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound || e.Status == http.StatusGone
+	case ErrBlocked:
+		return e.Status == http.StatusForbidden || e.Status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// RetryAfter returns how long to wait before crawling again after a blocked error
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+	return defaultRetryAfter
+}
@@ -0,0 +1,95 @@
+package scrape
+
+import (
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/utils"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type TagScrape struct {
+	Log   *logrus.Logger
+	Colly *colly.Collector
+}
+
+func NewTagScrape(log *logrus.Logger, colly *colly.Collector) *TagScrape {
+	return &TagScrape{
+		Log:   log,
+		Colly: colly,
+	}
+}
+
+// CrawlTags walks the tags page of a repository, including tags that have no GitHub release.
+// The page is paginated with an "after" cursor, so it is followed until there is no next link.
+// The returned requests have no RepoID set.
+func (s *TagScrape) CrawlTags(repoOwner string, repoName string) ([]*model.CreateTagRequest, error) {
+	tagsURL := fmt.Sprintf("https://github.com/%s/%s/tags", repoOwner, repoName)
+	maxPages := 100
+
+	// Use a clone so the tag handlers don't leak into the shared collector
+	c := s.Colly.Clone()
+
+	tags := make([]*model.CreateTagRequest, 0)
+	seen := make(map[string]bool)
+	nextURL := ""
+	var crawlErr error
+
+	utils.OnHTML(c, "tag_row", func(e *colly.HTMLElement) {
+		name := ""
+		utils.ForEach(e, "tag_name", func(_ int, link *colly.HTMLElement) {
+			if name == "" {
+				name = strings.TrimSpace(link.Text)
+			}
+		})
+
+		sha := ""
+		utils.ForEach(e, "tag_commit_link", func(_ int, link *colly.HTMLElement) {
+			if sha == "" {
+				sha = path.Base(link.Attr("href"))
+			}
+		})
+
+		if name == "" || sha == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		tags = append(tags, &model.CreateTagRequest{Name: name, CommitSHA: sha})
+	})
+
+	utils.OnHTML(c, "tag_pagination_link", func(e *colly.HTMLElement) {
+		if strings.TrimSpace(e.Text) == "Next" {
+			nextURL = e.Request.AbsoluteURL(e.Attr("href"))
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		crawlErr = fmt.Errorf("fetching tags of %s/%s: %w", repoOwner, repoName, responseError(r, err))
+	})
+
+	pageURL := tagsURL
+	for page := 1; page <= maxPages && pageURL != ""; page++ {
+		nextURL = ""
+		if err := c.Visit(pageURL); err != nil {
+			return tags, err
+		}
+		c.Wait()
+
+		if crawlErr != nil {
+			return tags, crawlErr
+		}
+
+		// Only follow pagination links that stay on this repository's tags page
+		if next, err := url.Parse(nextURL); err != nil || next.Path != "/"+repoOwner+"/"+repoName+"/tags" {
+			nextURL = ""
+		}
+		pageURL = nextURL
+	}
+
+	s.Log.Infof("Found %d tags for %s/%s", len(tags), repoOwner, repoName)
+	return tags, nil
+}
@@ -0,0 +1,167 @@
+package scrape
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/notifier"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// EventQueueOverflow is notified when a full buffer starts dropping work
+const EventQueueOverflow = "queue.overflow"
+
+const (
+	visitBufferSize    = 1000
+	visitBatchSize     = 100
+	visitFlushInterval = time.Second
+)
+
+// VisitRecorder is an http.RoundTripper that records every fetched URL into the visits table.
+// Collector clones share their parent's transport, so installing it on the shared collector
+// covers every scraper. Visits are written in batches in the background; when the buffer is
+// full new visits are dropped rather than slowing down the crawl.
+type VisitRecorder struct {
+	Log        *logrus.Logger
+	DB         *gorm.DB
+	Transport  http.RoundTripper
+	SampleRate float64
+	// Notifier, if set, receives a queue.overflow notification when visits start being dropped
+	Notifier notifier.Notifier
+
+	visits  chan *entity.Visit
+	dropped int64
+}
+
+func NewVisitRecorder(log *logrus.Logger, db *gorm.DB, transport http.RoundTripper, sampleRate float64) *VisitRecorder {
+	r := &VisitRecorder{
+		Log:        log,
+		DB:         db,
+		Transport:  transport,
+		SampleRate: sampleRate,
+		visits:     make(chan *entity.Visit, visitBufferSize),
+	}
+
+	go r.run()
+	return r
+}
+
+func (r *VisitRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.SampleRate < 1 && rand.Float64() >= r.SampleRate {
+		return r.Transport.RoundTrip(req)
+	}
+
+	visit := &entity.Visit{
+		URL:       req.URL.String(),
+		Method:    req.Method,
+		VisitedAt: time.Now(),
+	}
+
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		visit.Error = err.Error()
+		visit.DurationMs = time.Since(visit.VisitedAt).Milliseconds()
+		r.record(visit)
+		return resp, err
+	}
+
+	visit.StatusCode = resp.StatusCode
+	// The size is only known once the collector has read the body
+	resp.Body = &countingBody{ReadCloser: resp.Body, onClose: func(n int64) {
+		visit.Bytes = n
+		visit.DurationMs = time.Since(visit.VisitedAt).Milliseconds()
+		r.record(visit)
+	}}
+
+	return resp, nil
+}
+
+func (r *VisitRecorder) record(visit *entity.Visit) {
+	select {
+	case r.visits <- visit:
+	default:
+		if dropped := atomic.AddInt64(&r.dropped, 1); dropped%visitBufferSize == 1 {
+			r.Log.Warnf("Visit buffer full, %d visits dropped so far", dropped)
+			r.notifyOverflow(dropped)
+		}
+	}
+}
+
+func (r *VisitRecorder) notifyOverflow(dropped int64) {
+	if r.Notifier == nil {
+		return
+	}
+
+	notification := notifier.Notification{
+		Event:   EventQueueOverflow,
+		Title:   "Visit buffer full",
+		Message: fmt.Sprintf("%d visits dropped so far", dropped),
+		Fields: map[string]interface{}{
+			"queue":    "visits",
+			"capacity": visitBufferSize,
+			"dropped":  dropped,
+		},
+		SentAt: time.Now(),
+	}
+
+	go func() {
+		if err := r.Notifier.Notify(context.Background(), notification); err != nil {
+			r.Log.WithError(err).Error("Error notifying visit buffer overflow")
+		}
+	}()
+}
+
+// run writes buffered visits in batches
+func (r *VisitRecorder) run() {
+	ticker := time.NewTicker(visitFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*entity.Visit, 0, visitBatchSize)
+	for {
+		select {
+		case visit := <-r.visits:
+			batch = append(batch, visit)
+			if len(batch) < visitBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		if err := r.DB.CreateInBatches(batch, visitBatchSize).Error; err != nil {
+			r.Log.WithError(err).Errorf("Error saving %d visits", len(batch))
+		}
+		batch = make([]*entity.Visit, 0, visitBatchSize)
+	}
+}
+
+// countingBody counts the bytes read from a response body and reports the total once on close
+type countingBody struct {
+	io.ReadCloser
+	read    int64
+	closed  bool
+	onClose func(int64)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.onClose(b.read)
+	}
+	return b.ReadCloser.Close()
+}
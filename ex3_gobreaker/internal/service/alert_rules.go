@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"crawler/baseline/internal/notifier"
+)
+
+// Notification events sent by the alert engine
+const (
+	EventAlertFiring   = "alert.firing"
+	EventAlertResolved = "alert.resolved"
+)
+
+// AlertRule fires when its expression holds for the configured duration.
+// Expressions compare a metric with a number, e.g. "commits.items < 10",
+// or the per-minute rate of a counter, e.g. "rate(commits.items) < 50".
+type AlertRule struct {
+	Name string        `mapstructure:"name" json:"name"`
+	Expr string        `mapstructure:"expr" json:"expr"`
+	For  time.Duration `mapstructure:"for" json:"for"`
+
+	metric    string
+	rate      bool
+	op        string
+	threshold float64
+}
+
+var alertExprPattern = regexp.MustCompile(`^\s*(?:(rate)\(\s*([\w.\-]+)\s*\)|([\w.\-]+))\s*(<=|>=|==|!=|<|>)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*$`)
+
+// parse validates the rule expression and extracts its parts
+func (r *AlertRule) parse() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rule without a name")
+	}
+
+	match := alertExprPattern.FindStringSubmatch(r.Expr)
+	if match == nil {
+		return fmt.Errorf("alert rule %s has invalid expression %q", r.Name, r.Expr)
+	}
+
+	r.rate = match[1] != ""
+	r.metric = match[2] + match[3]
+	r.op = match[4]
+	r.threshold, _ = strconv.ParseFloat(match[5], 64)
+	return nil
+}
+
+func (r *AlertRule) holds(value float64) bool {
+	switch r.op {
+	case "<":
+		return value < r.threshold
+	case "<=":
+		return value <= r.threshold
+	case ">":
+		return value > r.threshold
+	case ">=":
+		return value >= r.threshold
+	case "==":
+		return value == r.threshold
+	default:
+		return value != r.threshold
+	}
+}
+
+// NewAlertRules parses the expressions of the "alerts.rules" config section
+func NewAlertRules(configured []AlertRule) ([]AlertRule, error) {
+	rules := append([]AlertRule(nil), configured...)
+	for i := range rules {
+		if err := rules[i].parse(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// MetricSource returns the current value of each metric it knows, keyed by metric name
+type MetricSource func() map[string]float64
+
+// AlertStatus is the evaluation state of one rule
+type AlertStatus struct {
+	Rule         string     `json:"rule"`
+	Expr         string     `json:"expr"`
+	Value        *float64   `json:"value,omitempty"`
+	Firing       bool       `json:"firing"`
+	PendingSince *time.Time `json:"pendingSince,omitempty"`
+	FiringSince  *time.Time `json:"firingSince,omitempty"`
+}
+
+type metricSample struct {
+	value float64
+	at    time.Time
+}
+
+// AlertEngine periodically evaluates alert rules over metric sources and notifies on state changes
+type AlertEngine struct {
+	rules    []AlertRule
+	notifier notifier.Notifier
+
+	mutex    sync.Mutex
+	sources  []MetricSource
+	previous map[string]metricSample
+	statuses []AlertStatus
+}
+
+func NewAlertEngine(rules []AlertRule, notifier notifier.Notifier) *AlertEngine {
+	statuses := make([]AlertStatus, len(rules))
+	for i, rule := range rules {
+		statuses[i] = AlertStatus{Rule: rule.Name, Expr: rule.Expr}
+	}
+
+	return &AlertEngine{
+		rules:    rules,
+		notifier: notifier,
+		previous: make(map[string]metricSample),
+		statuses: statuses,
+	}
+}
+
+// AddSource registers a source of metrics the rules can refer to
+func (e *AlertEngine) AddSource(source MetricSource) {
+	e.mutex.Lock()
+	e.sources = append(e.sources, source)
+	e.mutex.Unlock()
+}
+
+// Evaluate checks every rule against the current metrics
+func (e *AlertEngine) Evaluate() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := time.Now()
+	values := make(map[string]float64)
+	for _, source := range e.sources {
+		for name, value := range source() {
+			values[name] = value
+		}
+	}
+
+	// Rates need two samples, so a rate metric has no value on the first evaluation
+	rates := make(map[string]float64, len(values))
+	for name, value := range values {
+		if prev, ok := e.previous[name]; ok && now.After(prev.at) {
+			rates[name] = (value - prev.value) / now.Sub(prev.at).Minutes()
+		}
+		e.previous[name] = metricSample{value: value, at: now}
+	}
+
+	for i, rule := range e.rules {
+		status := &e.statuses[i]
+
+		value, ok := values[rule.metric]
+		if rule.rate {
+			value, ok = rates[rule.metric]
+		}
+		if !ok {
+			status.Value = nil
+			continue
+		}
+		status.Value = &value
+
+		if !rule.holds(value) {
+			status.PendingSince = nil
+			if status.Firing {
+				status.Firing = false
+				status.FiringSince = nil
+				e.notify(rule, EventAlertResolved, value, "resolved")
+			}
+			continue
+		}
+
+		if status.PendingSince == nil {
+			pendingSince := now
+			status.PendingSince = &pendingSince
+		}
+		if !status.Firing && now.Sub(*status.PendingSince) >= rule.For {
+			status.Firing = true
+			firingSince := now
+			status.FiringSince = &firingSince
+			e.notify(rule, EventAlertFiring, value, "firing")
+		}
+	}
+}
+
+func (e *AlertEngine) notify(rule AlertRule, event string, value float64, state string) {
+	notification := notifier.Notification{
+		Event:   event,
+		Title:   fmt.Sprintf("Alert %s %s", rule.Name, state),
+		Message: fmt.Sprintf("%s (current value %.2f)", rule.Expr, value),
+		Fields: map[string]interface{}{
+			"rule":  rule.Name,
+			"expr":  rule.Expr,
+			"value": value,
+		},
+		SentAt: time.Now(),
+	}
+
+	// Deliver outside the evaluation so a slow webhook doesn't block it
+	go func() {
+		if err := e.notifier.Notify(context.Background(), notification); err != nil {
+			log.Printf("Error sending %s notification for alert %s: %v", state, rule.Name, err)
+		}
+	}()
+}
+
+// Statuses returns the current state of every rule
+func (e *AlertEngine) Statuses() []AlertStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return append([]AlertStatus(nil), e.statuses...)
+}
+
+// StartEvaluating evaluates the rules on every interval until stopChan is closed
+func (e *AlertEngine) StartEvaluating(interval time.Duration, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.Evaluate()
+		case <-stopChan:
+			log.Println("Stopping alert evaluation")
+			return
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/utils"
+)
+
+// Bench operations use only endpoints that every mode (baseline, batch, queue, breaker) serves
+const (
+	BenchGetRepo       = "get_repo"
+	BenchGetRelease    = "get_release"
+	BenchGetCommit     = "get_commit"
+	BenchCrawlReleases = "crawl_release_commits"
+)
+
+// ErrBenchRunning is returned when a benchmark is started while another one runs
+var ErrBenchRunning = errors.New("a benchmark is already running")
+
+// ErrInvalidBench wraps errors about the requested targets or workload
+var ErrInvalidBench = errors.New("invalid benchmark")
+
+// BenchTarget is one deployment of a mode, addressed by its API base URL
+type BenchTarget struct {
+	Name    string `mapstructure:"name" json:"name"`
+	BaseURL string `mapstructure:"base_url" json:"base_url"`
+	// APIKey is sent to targets that require authentication; a reader key is enough
+	APIKey string `mapstructure:"api_key" json:"api_key"`
+}
+
+// NewBenchTargets validates the deployments to compare from the "bench.targets" config section
+func NewBenchTargets(configured []BenchTarget) ([]BenchTarget, error) {
+	targets := append([]BenchTarget(nil), configured...)
+	seen := make(map[string]bool, len(targets))
+	for i := range targets {
+		if targets[i].Name == "" || targets[i].BaseURL == "" {
+			return nil, fmt.Errorf("bench target %d needs a name and a base_url", i+1)
+		}
+		if seen[targets[i].Name] {
+			return nil, fmt.Errorf("duplicate bench target %s", targets[i].Name)
+		}
+		seen[targets[i].Name] = true
+		targets[i].BaseURL = strings.TrimSuffix(targets[i].BaseURL, "/")
+	}
+	return targets, nil
+}
+
+// benchOp is one request of the synthetic workload
+type benchOp struct {
+	name string
+	path string
+}
+
+// BenchRunner replays the same seeded request sequence against each target in turn
+// and reports throughput, error rate and latency percentiles per target and operation
+type BenchRunner struct {
+	targets  []BenchTarget
+	defaults model.BenchRequest
+	client   *http.Client
+
+	// running allows one benchmark at a time, concurrent runs would skew each other
+	running sync.Mutex
+}
+
+func NewBenchRunner(targets []BenchTarget, defaults model.BenchRequest) *BenchRunner {
+	if defaults.Requests <= 0 {
+		defaults.Requests = 500
+	}
+	if defaults.Concurrency <= 0 {
+		defaults.Concurrency = 10
+	}
+	if defaults.MaxID <= 0 {
+		defaults.MaxID = 100
+	}
+	if defaults.Seed == 0 {
+		defaults.Seed = 1
+	}
+
+	return &BenchRunner{
+		targets:  targets,
+		defaults: defaults,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run benchmarks the requested targets, or all configured targets, one after another
+func (b *BenchRunner) Run(ctx context.Context, request model.BenchRequest) (*model.BenchReport, error) {
+	if !b.running.TryLock() {
+		return nil, ErrBenchRunning
+	}
+	defer b.running.Unlock()
+
+	request = b.withDefaults(request)
+	targets, err := b.selectTargets(request.Targets)
+	if err != nil {
+		return nil, err
+	}
+	if request.Requests <= 0 || request.Concurrency <= 0 || request.MaxID <= 0 {
+		return nil, fmt.Errorf("%w: requests, concurrency and maxID must be positive", ErrInvalidBench)
+	}
+
+	workload := benchWorkload(request)
+	report := &model.BenchReport{
+		Requests:    request.Requests,
+		Concurrency: request.Concurrency,
+		Seed:        request.Seed,
+		Targets:     make([]model.BenchTargetResult, 0, len(targets)),
+	}
+
+	for _, target := range targets {
+		log.Printf("Benchmarking %s at %s with %d requests", target.Name, target.BaseURL, len(workload))
+		result := b.runTarget(ctx, target, workload, request.Concurrency)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("Benchmark of %s finished: %.1f req/s, %d errors", target.Name, result.Throughput, result.Errors)
+		report.Targets = append(report.Targets, result)
+	}
+
+	best := -1.0
+	for _, result := range report.Targets {
+		if result.Reachable && result.Throughput > best {
+			best = result.Throughput
+			report.Fastest = result.Name
+		}
+	}
+	return report, nil
+}
+
+func (b *BenchRunner) withDefaults(request model.BenchRequest) model.BenchRequest {
+	if request.Requests == 0 {
+		request.Requests = b.defaults.Requests
+	}
+	if request.Concurrency == 0 {
+		request.Concurrency = b.defaults.Concurrency
+	}
+	if request.MaxID == 0 {
+		request.MaxID = b.defaults.MaxID
+	}
+	if request.Seed == 0 {
+		request.Seed = b.defaults.Seed
+	}
+	return request
+}
+
+func (b *BenchRunner) selectTargets(names []string) ([]BenchTarget, error) {
+	if len(b.targets) == 0 {
+		return nil, fmt.Errorf("%w: no bench targets configured", ErrInvalidBench)
+	}
+	if len(names) == 0 {
+		return b.targets, nil
+	}
+
+	selected := make([]BenchTarget, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, target := range b.targets {
+			if target.Name == name {
+				selected = append(selected, target)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown target %s", ErrInvalidBench, name)
+		}
+	}
+	return selected, nil
+}
+
+// benchWorkload builds the request sequence from the seed, so every target gets identical requests
+func benchWorkload(request model.BenchRequest) []benchOp {
+	random := rand.New(rand.NewSource(request.Seed))
+	workload := make([]benchOp, request.Requests)
+	for i := range workload {
+		id := random.Intn(request.MaxID) + 1
+		roll := random.Intn(100)
+		switch {
+		case request.IncludeCrawl && roll < 5:
+			workload[i] = benchOp{BenchCrawlReleases, fmt.Sprintf("/releases/%d/commits", id)}
+		case roll < 35:
+			workload[i] = benchOp{BenchGetRepo, fmt.Sprintf("/repos/%d", id)}
+		case roll < 70:
+			workload[i] = benchOp{BenchGetRelease, fmt.Sprintf("/releases/%d", id)}
+		default:
+			workload[i] = benchOp{BenchGetCommit, fmt.Sprintf("/commits/%d", id)}
+		}
+	}
+	return workload
+}
+
+// runTarget replays the workload with the given concurrency. Transport errors and 5xx answers
+// count as errors; 404s for IDs missing from a target's database are normal responses.
+func (b *BenchRunner) runTarget(ctx context.Context, target BenchTarget, workload []benchOp, concurrency int) model.BenchTargetResult {
+	result := model.BenchTargetResult{Name: target.Name, BaseURL: target.BaseURL}
+	// Probe with a read that every mode serves before starting the clock
+	if _, err := b.call(ctx, target, "/repos/1"); err != nil {
+		log.Printf("Bench target %s is unreachable: %v", target.Name, err)
+		return result
+	}
+	result.Reachable = true
+
+	recorder := utils.NewPhaseRecorder()
+	var errorsMutex sync.Mutex
+	opErrors := make(map[string]int)
+
+	jobs := make(chan benchOp)
+	var wg sync.WaitGroup
+	startTime := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for op := range jobs {
+				callStart := time.Now()
+				status, err := b.call(ctx, target, op.path)
+				duration := time.Since(callStart)
+
+				recorder.Record(op.name, duration)
+				recorder.Record(utils.PhaseTotal, duration)
+				if err != nil || status >= http.StatusInternalServerError {
+					errorsMutex.Lock()
+					opErrors[op.name]++
+					errorsMutex.Unlock()
+				}
+			}
+		}()
+	}
+
+sendLoop:
+	for _, op := range workload {
+		select {
+		case <-ctx.Done():
+			break sendLoop
+		case jobs <- op:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	duration := time.Since(startTime)
+
+	total := recorder.Stats(utils.PhaseTotal)
+	result.Requests = total.Count
+	result.DurationMs = duration.Milliseconds()
+	if duration > 0 {
+		result.Throughput = float64(total.Count) / duration.Seconds()
+	}
+	result.Latency = benchLatency(total)
+
+	for _, name := range []string{BenchGetRepo, BenchGetRelease, BenchGetCommit, BenchCrawlReleases} {
+		stats := recorder.Stats(name)
+		if stats.Count == 0 {
+			continue
+		}
+		result.Errors += opErrors[name]
+		result.Operations = append(result.Operations, model.BenchOperationResult{
+			Operation: name,
+			Requests:  stats.Count,
+			Errors:    opErrors[name],
+			Latency:   benchLatency(stats),
+		})
+	}
+	if result.Requests > 0 {
+		result.ErrorRate = float64(result.Errors) / float64(result.Requests)
+	}
+	return result
+}
+
+func (b *BenchRunner) call(ctx context.Context, target BenchTarget, path string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.BaseURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if target.APIKey != "" {
+		req.Header.Set("X-API-Key", target.APIKey)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Read the whole body so the timing includes the transfer
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+func benchLatency(stats utils.PhaseStats) model.BenchLatency {
+	return model.BenchLatency{
+		P50Ms: stats.P50.Milliseconds(),
+		P95Ms: stats.P95.Milliseconds(),
+		P99Ms: stats.P99.Milliseconds(),
+		MaxMs: stats.Max.Milliseconds(),
+	}
+}
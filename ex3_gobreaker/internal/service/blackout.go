@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrBlackout matches the errors of crawls refused during a blackout window
+var ErrBlackout = errors.New("crawling is paused during a blackout window")
+
+// BlackoutError is a crawl refused during a blackout window that ends at Until
+type BlackoutError struct {
+	Until time.Time
+}
+
+func (e *BlackoutError) Error() string {
+	return fmt.Sprintf("%v until %s", ErrBlackout, e.Until.Format(time.RFC3339))
+}
+
+func (e *BlackoutError) Is(target error) bool {
+	return target == ErrBlackout
+}
+
+// BlackoutWindow is a daily time range, such as 09:00 to 17:00, during which nothing is crawled.
+// A window ending before it starts runs past midnight. Days limits it to some weekdays
+// ("mon" to "sun"), counted on the day the window starts; empty means every day.
+type BlackoutWindow struct {
+	Start string   `mapstructure:"start" json:"start"`
+	End   string   `mapstructure:"end" json:"end"`
+	Days  []string `mapstructure:"days" json:"days,omitempty"`
+}
+
+// BlackoutPeriod is one occurrence of a blackout window
+type BlackoutPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// blackoutWindow is a parsed BlackoutWindow, with times as offsets from midnight
+type blackoutWindow struct {
+	start time.Duration
+	end   time.Duration
+	days  map[time.Weekday]bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Blackouts tells the scheduler and job admission when crawling is not allowed.
+// A nil *Blackouts never blacks anything out.
+type Blackouts struct {
+	windows  []blackoutWindow
+	location *time.Location
+}
+
+// NewBlackouts parses the "scheduler.blackouts" windows, read in the named time zone
+// ("Asia/Ho_Chi_Minh", ...) or in the server's local time when timezone is empty
+func NewBlackouts(windows []BlackoutWindow, timezone string) (*Blackouts, error) {
+	location := time.Local
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid blackout timezone: %w", err)
+		}
+	}
+
+	b := &Blackouts{location: location}
+	for i, window := range windows {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return nil, fmt.Errorf("blackout %d: invalid start: %w", i, err)
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return nil, fmt.Errorf("blackout %d: invalid end: %w", i, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("blackout %d: start and end are the same", i)
+		}
+		if end < start {
+			end += 24 * time.Hour
+		}
+
+		parsed := blackoutWindow{start: start, end: end}
+		if len(window.Days) > 0 {
+			parsed.days = make(map[time.Weekday]bool, len(window.Days))
+			for _, day := range window.Days {
+				weekday, ok := weekdays[strings.ToLower(day)]
+				if !ok {
+					return nil, fmt.Errorf("blackout %d: unknown day %q", i, day)
+				}
+				parsed.days[weekday] = true
+			}
+		}
+		b.windows = append(b.windows, parsed)
+	}
+	return b, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Periods lists the blackout periods overlapping [from, to), in order
+func (b *Blackouts) Periods(from, to time.Time) []BlackoutPeriod {
+	if b == nil || len(b.windows) == 0 {
+		return nil
+	}
+
+	periods := make([]BlackoutPeriod, 0)
+	// Start a day early for windows running past midnight into from
+	local := from.In(b.location)
+	day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, b.location)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, window := range b.windows {
+			if window.days != nil && !window.days[day.Weekday()] {
+				continue
+			}
+			start, end := day.Add(window.start), day.Add(window.end)
+			if end.After(from) && start.Before(to) {
+				periods = append(periods, BlackoutPeriod{Start: start, End: end})
+			}
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+	return periods
+}
+
+// Until returns when the blackout in effect at now ends, following windows that overlap or
+// follow each other, and whether a blackout is in effect at all
+func (b *Blackouts) Until(now time.Time) (time.Time, bool) {
+	until := now
+	for _, period := range b.Periods(now, now.Add(8*24*time.Hour)) {
+		if period.Start.After(until) {
+			break
+		}
+		if period.End.After(until) {
+			until = period.End
+		}
+	}
+	return until, until.After(now)
+}
+
+// Check returns a BlackoutError when crawling is not allowed at now
+func (b *Blackouts) Check(now time.Time) error {
+	if until, active := b.Until(now); active {
+		return &BlackoutError{Until: until}
+	}
+	return nil
+}
+
+// Location is the time zone the windows are read in
+func (b *Blackouts) Location() *time.Location {
+	if b == nil {
+		return time.Local
+	}
+	return b.location
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+)
+
+// CrawlOutput is what the crawl call of a stage returned, for change detection
+type CrawlOutput struct {
+	Items int
+	// Checksum is the SHA-256 of the crawl response, empty when the crawl ran in process
+	Checksum string
+}
+
+// ChangeDetector fingerprints the data of a stage after its crawl; the coordinator compares the
+// fingerprint with the one of the previous crawl
+type ChangeDetector interface {
+	Summarize(ctx context.Context, output CrawlOutput) (*StageSummary, error)
+}
+
+// ResponseHashDetector fingerprints a stage by the hash of its crawl response. A crawl run in
+// process has no response, so it is compared by item count alone.
+type ResponseHashDetector struct{}
+
+func (ResponseHashDetector) Summarize(ctx context.Context, output CrawlOutput) (*StageSummary, error) {
+	return &StageSummary{Count: int64(output.Items), Checksum: output.Checksum}, nil
+}
+
+// SummaryDetector fingerprints a stage by its summary, fetched once the crawl is done
+type SummaryDetector struct {
+	Fetch func(ctx context.Context) (*StageSummary, error)
+}
+
+func (d SummaryDetector) Summarize(ctx context.Context, output CrawlOutput) (*StageSummary, error) {
+	return d.Fetch(ctx)
+}
+
+// CountDetector fingerprints a stage by the number of items crawled
+type CountDetector struct{}
+
+func (CountDetector) Summarize(ctx context.Context, output CrawlOutput) (*StageSummary, error) {
+	return &StageSummary{Count: int64(output.Items)}, nil
+}
+
+// HashResponse reads a JSON response token by token, without building it in memory, and returns
+// the SHA-256 of its tokens, which does not depend on whitespace, and the number of items of its
+// "data" list, 0 when it has none
+func HashResponse(r io.Reader) (string, int, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	sum := sha256.New()
+
+	// containers holds the objects and arrays being read; keyNext tells an object's keys from its values
+	type container struct {
+		object  bool
+		keyNext bool
+	}
+	var containers []container
+	topKey := ""
+	inData := false
+	items := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			if len(containers) > 0 {
+				return "", 0, io.ErrUnexpectedEOF
+			}
+			break
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		writeToken(sum, token)
+
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			containers = containers[:len(containers)-1]
+			if inData && len(containers) == 1 {
+				inData = false
+			}
+			continue
+		}
+
+		if n := len(containers); n > 0 && containers[n-1].object {
+			if containers[n-1].keyNext {
+				containers[n-1].keyNext = false
+				if n == 1 {
+					topKey, _ = token.(string)
+				}
+				continue
+			}
+			containers[n-1].keyNext = true
+		}
+
+		// The token starts a value
+		if inData && len(containers) == 2 {
+			items++
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '[' && len(containers) == 1 && containers[0].object && topKey == "data" {
+				inData = true
+			}
+			containers = append(containers, container{object: delim == '{', keyNext: delim == '{'})
+		}
+	}
+	return hex.EncodeToString(sum.Sum(nil)), items, nil
+}
+
+// writeToken hashes a token followed by a separator, so adjacent numbers stay apart
+func writeToken(sum hash.Hash, token json.Token) {
+	switch value := token.(type) {
+	case json.Delim:
+		io.WriteString(sum, value.String())
+	case string:
+		io.WriteString(sum, strconv.Quote(value))
+	case json.Number:
+		io.WriteString(sum, value.String())
+	case bool:
+		io.WriteString(sum, strconv.FormatBool(value))
+	case nil:
+		io.WriteString(sum, "null")
+	}
+	io.WriteString(sum, ",")
+}
@@ -1,345 +1,1100 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
+	"crawler/baseline/internal/auth"
+	"crawler/baseline/internal/notifier"
 	"crawler/baseline/internal/utils"
 )
 
-// CrawlingCoordinator orchestrates the crawling operations with circuit breaker protection
-type CrawlingCoordinator struct {
-	baseURL   string
-	repoCB    *utils.CircuitBreakerWrapper
-	releaseCB *utils.CircuitBreakerWrapper
-	commitCB  *utils.CircuitBreakerWrapper
+// EventBreakerOpened is notified when a stage's circuit breaker opens
+const EventBreakerOpened = "breaker.opened"
+
+// EventStageStale is notified when a stage whose breaker is open starts serving its cached data
+const EventStageStale = "stage.stale"
+
+// stageState holds the runtime state of a single crawl stage
+type stageState struct {
+	config StageConfig
+	stage  CrawlStage
+	cb     *utils.CircuitBreakerWrapper
+
+	// Summary of the last successful crawl, compared with the next one, and when it was fetched
+	cache     *StageSummary
+	fetchedAt time.Time
 
-	// Cache for comparing responses
-	repoCache    interface{}
-	releaseCache interface{}
-	commitCache  interface{}
+	// While the breaker rejects calls, cache is served as stale data since staleSince, and the
+	// stage is retried at retryAt
+	staleSince time.Time
+	retryAt    time.Time
 
 	// Track consecutive no-change responses to stop calling stable endpoints
-	repoNoChangeCount    int
-	releaseNoChangeCount int
-	commitNoChangeCount  int
+	noChangeCount int
+
+	// A stable endpoint is paused until pausedUntil, then re-checked once. Each pause that
+	// ends without changes doubles pauseLength, up to the coordinator's maxPause.
+	pausedUntil time.Time
+	pauseLength time.Duration
+
+	// Stages that depend directly on this one
+	dependents []string
+
+	// Semaphore bounding concurrent calls of this stage
+	slots chan struct{}
+}
+
+// CrawlingCoordinator orchestrates the crawling operations with circuit breaker protection
+type CrawlingCoordinator struct {
+	baseURL string
 
-	// Flags to track if endpoints are paused due to stability
-	repoPaused    bool
-	releasePaused bool
-	commitPaused  bool
+	// Stages keyed by name, and their names in dependency order. Both are fixed once
+	// NewCrawlingCoordinator returns, so they are read without cacheMutex; the state of each
+	// stage is guarded by it.
+	stages map[string]*stageState
+	order  []string
 
-	// Threshold for number of no-changes before pausing
+	// Default number of no-changes before pausing, for stages without their own threshold
 	stabilityThreshold int
 
+	// breakerSettings tune the circuit breakers of every stage; with breakersOff the stages are
+	// called without them
+	breakerSettings utils.BreakerSettings
+	breakersOff     bool
+
+	// First pause length, one crawl interval once periodic crawling starts, and the longest pause
+	pauseBase time.Duration
+	maxPause  time.Duration
+
 	cacheMutex sync.RWMutex
 	client     *http.Client
+
+	history *runHistory
+
+	// Periodic cycles are skipped during blackouts; cycleInterval and nextCycle are set
+	// while periodic crawling runs, for the schedule calendar
+	blackouts     *Blackouts
+	cycleInterval time.Duration
+	nextCycle     time.Time
+
+	// notifier has its own mutex: breaker callbacks can fire while cacheMutex is held
+	notifierMutex sync.Mutex
+	notifier      notifier.Notifier
 }
 
-// NewCrawlingCoordinator creates a new crawling coordinator
-func NewCrawlingCoordinator(baseURL string) *CrawlingCoordinator {
-	return &CrawlingCoordinator{
+// StagePlan describes what the coordinator would do for a stage on the next cycle
+type StagePlan struct {
+	Stage         string     `json:"stage"`
+	DependsOn     []string   `json:"dependsOn,omitempty"`
+	Condition     string     `json:"condition"`
+	Concurrency   int        `json:"concurrency"`
+	Running       int        `json:"running"`
+	WouldRun      bool       `json:"wouldRun"`
+	Reason        string     `json:"reason"`
+	Paused        bool       `json:"paused"`
+	PausedUntil   *time.Time `json:"pausedUntil,omitempty"`
+	NoChangeCount int        `json:"noChangeCount"`
+	HasCache      bool       `json:"hasCache"`
+	BreakerState  string     `json:"breakerState"`
+	Stale         bool       `json:"stale"`
+	RetryAt       *time.Time `json:"retryAt,omitempty"`
+
+	// Count and checksum of the cached summary
+	CachedCount int64  `json:"cachedCount"`
+	Checksum    string `json:"checksum,omitempty"`
+
+	StabilityThreshold int `json:"stabilityThreshold"`
+}
+
+// StageData is the cached summary of a stage, as the coordinator last fetched it
+type StageData struct {
+	Stage     string        `json:"stage"`
+	Summary   *StageSummary `json:"summary"`
+	FetchedAt *time.Time    `json:"fetchedAt,omitempty"`
+	// Stale is set while the stage's breaker rejects calls, Summary being the last one fetched before
+	Stale      bool       `json:"stale"`
+	StaleSince *time.Time `json:"staleSince,omitempty"`
+	RetryAt    *time.Time `json:"retryAt,omitempty"`
+}
+
+// NewCrawlingCoordinator creates a new crawling coordinator for the given stage graph.
+// Every configured stage calls its crawler API path.
+func NewCrawlingCoordinator(baseURL string, stages []StageConfig) (*CrawlingCoordinator, error) {
+	c := &CrawlingCoordinator{
 		baseURL:            baseURL,
-		repoCB:             utils.NewCircuitBreaker("repo-crawler"),
-		releaseCB:          utils.NewCircuitBreaker("release-crawler"),
-		commitCB:           utils.NewCircuitBreaker("commit-crawler"),
+		stages:             make(map[string]*stageState, len(stages)),
 		client:             &http.Client{Timeout: 30 * time.Second},
 		stabilityThreshold: 3, // Stop calling after 3 consecutive no-change responses
+		pauseBase:          time.Minute,
+		maxPause:           24 * time.Hour,
+		history:            newRunHistory(),
 	}
-}
 
-// CrawlRepos crawls repositories with circuit breaker protection
-func (c *CrawlingCoordinator) CrawlRepos() (interface{}, error) {
-	result, err := c.repoCB.Execute(func() (interface{}, error) {
-		resp, err := c.client.Get(fmt.Sprintf("%s/repos/crawl", c.baseURL))
-		if err != nil {
-			return nil, err
+	for _, config := range stages {
+		if config.Path == "" {
+			return nil, fmt.Errorf("stage %s has no path", config.Name)
 		}
-		defer resp.Body.Close()
+		detector := newDetector(config.changeDetection(), baseURL+config.SummaryPath, c.client)
+		c.stages[config.Name] = c.newStageState(config, newHTTPStage(config.Name, baseURL+config.Path, detector, c.client))
+		c.order = append(c.order, config.Name)
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to crawl repos: status %d", resp.StatusCode)
-		}
+	if err := c.buildGraph(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
 
-		var data interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-			return nil, err
+func (c *CrawlingCoordinator) newStageState(config StageConfig, stage CrawlStage) *stageState {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.Condition == "" {
+		config.Condition = ConditionUpstreamChanged
+	}
+
+	cb := utils.NewCircuitBreaker(config.Name+"-crawler", c.breakerStateChanged(config.Name))
+	if c.breakerSettings != (utils.BreakerSettings{}) {
+		cb.Configure(c.breakerSettings)
+	}
+	return &stageState{
+		config: config,
+		stage:  stage,
+		cb:     cb,
+		slots:  make(chan struct{}, config.Concurrency),
+	}
+}
+
+// breakerStateChanged returns the state change callback of a stage's circuit breaker
+func (c *CrawlingCoordinator) breakerStateChanged(stage string) func(name string, from string, to string) {
+	return func(name string, from string, to string) {
+		log.Printf("Circuit breaker %s changed from %s to %s", name, from, to)
+		if to != "open" {
+			return
 		}
 
-		return data, nil
-	})
+		c.notify(notifier.Notification{
+			Event:   EventBreakerOpened,
+			Title:   fmt.Sprintf("Circuit breaker of the %s stage opened", stage),
+			Message: fmt.Sprintf("Calls to the %s stage are rejected until the breaker half-opens", stage),
+			Fields: map[string]interface{}{
+				"stage":   stage,
+				"breaker": name,
+				"from":    from,
+			},
+		})
+	}
+}
 
-	if err != nil {
-		return nil, err
+// SetNotifier sets where coordinator events such as opened breakers and finished runs are sent
+func (c *CrawlingCoordinator) SetNotifier(n notifier.Notifier) {
+	c.notifierMutex.Lock()
+	c.notifier = n
+	c.notifierMutex.Unlock()
+}
+
+// notify sends a notification in the background, if a notifier is set
+func (c *CrawlingCoordinator) notify(notification notifier.Notification) {
+	c.notifierMutex.Lock()
+	n := c.notifier
+	c.notifierMutex.Unlock()
+	if n == nil {
+		return
 	}
 
-	return result, nil
+	notification.SentAt = time.Now()
+	go func() {
+		if err := n.Notify(context.Background(), notification); err != nil {
+			log.Printf("Failed to send %s notification: %v", notification.Event, err)
+		}
+	}()
 }
 
-// CrawlReleases crawls releases with circuit breaker protection
-func (c *CrawlingCoordinator) CrawlReleases() (interface{}, error) {
-	result, err := c.releaseCB.Execute(func() (interface{}, error) {
-		resp, err := c.client.Get(fmt.Sprintf("%s/releases/crawl", c.baseURL))
-		if err != nil {
-			return nil, err
+// finishRun records the end of a run and notifies its outcome
+func (c *CrawlingCoordinator) finishRun(run *CoordinatorRun) {
+	c.history.finish(run)
+
+	finished := c.history.snapshot(run)
+
+	// Idle periodic cycles where every stage was skipped are not worth a notification
+	idle := true
+	for _, stageRun := range finished.Stages {
+		if stageRun.Status != StatusSkipped {
+			idle = false
 		}
-		defer resp.Body.Close()
+	}
+	if idle {
+		return
+	}
+
+	c.notify(notifier.Notification{
+		Event:   EventCrawlFinished,
+		Title:   fmt.Sprintf("Coordinator run %d (%s) %s", finished.ID, finished.Trigger, finished.Status),
+		Message: fmt.Sprintf("%d stages", len(finished.Stages)),
+		Fields: map[string]interface{}{
+			"run": finished,
+		},
+	})
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to crawl releases: status %d", resp.StatusCode)
+// UseInProcess switches the configured stages whose crawl and summary paths the api serves
+// from HTTP calls to direct calls; their breakers, caches and history are kept. Other stages
+// keep calling the crawler API. It returns the names of the switched stages.
+func (c *CrawlingCoordinator) UseInProcess(api InProcessAPI) []string {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	var switched []string
+	for _, name := range c.order {
+		stage := c.stages[name]
+		crawl, ok := api.Crawls[stage.config.Path]
+		if stage.config.Path == "" || !ok {
+			continue
 		}
+		local := &inProcessStage{name: name, crawl: crawl}
+		switch stage.config.changeDetection() {
+		case ChangeDetectionSummary:
+			// Change detection must keep comparing the same summary, so it has to be served too
+			summarize, ok := api.Summaries[stage.config.SummaryPath]
+			if !ok {
+				log.Printf("%s stage keeps calling the API, %s is not served in process", name, stage.config.SummaryPath)
+				continue
+			}
+			local.detector = SummaryDetector{Fetch: summarize}
+		default:
+			// There is no crawl response to hash, so the stage is compared by item count
+			local.detector = CountDetector{}
+		}
+
+		stage.stage = local
+		switched = append(switched, name)
+	}
+	return switched
+}
+
+// buildGraph computes the stage order and dependents, once for NewCrawlingCoordinator
+func (c *CrawlingCoordinator) buildGraph() error {
+	configs := make([]StageConfig, 0, len(c.stages))
+	for _, name := range c.order {
+		configs = append(configs, c.stages[name].config)
+	}
 
-		var data interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-			return nil, err
+	order, err := sortStages(configs)
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs {
+		for _, dep := range config.DependsOn {
+			c.stages[dep].dependents = append(c.stages[dep].dependents, config.Name)
 		}
+	}
 
-		return data, nil
-	})
+	c.order = order
+	return nil
+}
+
+// callStage runs a stage with circuit breaker protection, unless the breakers are disabled
+func (c *CrawlingCoordinator) callStage(ctx context.Context, name string, scope StageScope) (StageResult, error) {
+	stage, ok := c.stages[name]
+	if !ok {
+		return StageResult{}, fmt.Errorf("unknown stage: %s", name)
+	}
+
+	// UseInProcess may swap the stage implementation while the first cycle runs
+	c.cacheMutex.RLock()
+	implementation := stage.stage
+	c.cacheMutex.RUnlock()
+
+	var result StageResult
+	run := func(ctx context.Context) (interface{}, error) {
+		result = implementation.Run(ctx, scope)
+		return result.Summary, result.Err
+	}
+	var err error
+	if c.breakersOff {
+		_, err = run(ctx)
+	} else {
+		// Only in-process stages send requests to GitHub to hedge; the crawl endpoints of an
+		// HTTP stage are not sent twice
+		_, err = stage.cb.ExecuteHedged(ctx, run)
+	}
 
 	if err != nil {
-		return nil, err
+		return StageResult{Err: err}, err
 	}
 
 	return result, nil
 }
 
-// CrawlCommits crawls commits with circuit breaker protection
-func (c *CrawlingCoordinator) CrawlCommits() (interface{}, error) {
-	result, err := c.commitCB.Execute(func() (interface{}, error) {
-		resp, err := c.client.Get(fmt.Sprintf("%s/commits/crawl", c.baseURL))
-		if err != nil {
-			return nil, err
+// hasDataChanged compares the previous and current summaries of a stage
+func (c *CrawlingCoordinator) hasDataChanged(previous, current *StageSummary) bool {
+	if previous == nil || current == nil {
+		return true
+	}
+	return previous.Count != current.Count || previous.Checksum != current.Checksum
+}
+
+// logSummaryChange logs how a stage's data changed, including latest tag changes per repository
+func logSummaryChange(name string, previous, current *StageSummary, repos []int64) {
+	if previous == nil || current == nil {
+		count := int64(0)
+		if current != nil {
+			count = current.Count
 		}
-		defer resp.Body.Close()
+		log.Printf("%s data fetched: %d items", name, count)
+		return
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to crawl commits: status %d", resp.StatusCode)
+	log.Printf("%s data has changed: %d -> %d items, %d repositories changed",
+		name, previous.Count, current.Count, len(repos))
+
+	const maxLoggedTags = 10
+	logged := 0
+	for _, id := range repos {
+		before, after := previous.Repos[id].LatestTag, current.Repos[id].LatestTag
+		if before == after || after == "" {
+			continue
+		}
+		if logged == maxLoggedTags {
+			log.Printf("%s: more repositories have a new latest tag", name)
+			break
 		}
+		log.Printf("%s: repository %d latest tag %q -> %q", name, id, before, after)
+		logged++
+	}
+}
+
+// isPaused reports whether the stage is inside a stability pause; the caller must hold cacheMutex
+func (s *stageState) isPaused(now time.Time) bool {
+	return now.Before(s.pausedUntil)
+}
+
+// recheckDue reports whether a stability pause has expired and the stage should be checked again
+func (s *stageState) recheckDue(now time.Time) bool {
+	return s.pauseLength > 0 && !s.isPaused(now)
+}
+
+// resume clears the stage's stability pause and backoff; the caller must hold cacheMutex
+func (s *stageState) resume() {
+	s.noChangeCount = 0
+	s.pausedUntil = time.Time{}
+	s.pauseLength = 0
+}
 
-		var data interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-			return nil, err
+// shouldCrawl evaluates a stage's condition; the caller must hold cacheMutex
+func (s *stageState) shouldCrawl(upstreamChanged bool, now time.Time) bool {
+	switch s.config.Condition {
+	case ConditionOnce:
+		return s.cache == nil
+	case ConditionAlways:
+		return !s.isPaused(now)
+	default:
+		return (upstreamChanged || s.cache == nil || s.recheckDue(now)) && !s.isPaused(now)
+	}
+}
+
+// crawlStage runs a stage if its condition holds; force bypasses the condition and stability pause.
+// It records the outcome in run and reports whether the stage produced changed data,
+// with the repositories that changed when its summary identifies them.
+func (c *CrawlingCoordinator) crawlStage(ctx context.Context, run *CoordinatorRun, name string, changedUpstream []string, upstreamRepos []int64, force bool) (bool, []int64, error) {
+	stage := c.stages[name]
+
+	now := time.Now()
+	c.cacheMutex.RLock()
+	shouldCrawl := stage.shouldCrawl(len(changedUpstream) > 0, now)
+	paused := stage.isPaused(now)
+	pausedUntil := stage.pausedUntil
+	hasCache := stage.cache != nil
+	c.cacheMutex.RUnlock()
+
+	if !shouldCrawl && !force {
+		switch {
+		case paused:
+			log.Printf("%s API is stable, skipping call until %s", name, pausedUntil.Format(time.RFC3339))
+		case stage.config.Condition == ConditionOnce && hasCache:
+			log.Printf("%s data already fetched, skipping API call", name)
+		default:
+			log.Printf("Skipping %s crawling, no upstream changes", name)
 		}
+		c.history.stageSkipped(run, name, paused)
+		return false, nil, nil
+	}
 
-		return data, nil
-	})
+	scope := StageScope{
+		Trigger:         run.Trigger,
+		Force:           force,
+		ChangedUpstream: changedUpstream,
+		ChangedRepos:    upstreamRepos,
+	}
 
+	// Wait for a free slot if the stage is already running at its concurrency limit
+	stage.slots <- struct{}{}
+	c.history.stageStarted(run, name)
+	if len(upstreamRepos) > 0 {
+		log.Printf("Starting %s crawling, %d repositories changed upstream...", name, len(upstreamRepos))
+	} else {
+		log.Printf("Starting %s crawling...", name)
+	}
+	result, err := c.callStage(ctx, name, scope)
+	<-stage.slots
 	if err != nil {
-		return nil, err
+		if utils.BreakerRejected(err) && c.serveStale(run, name, err) {
+			return false, nil, nil
+		}
+		log.Printf("Error crawling %s: %v", name, err)
+		c.history.stageFinished(run, name, StatusFailed, false, 0, err)
+		return false, nil, err
 	}
 
-	return result, nil
+	if result.Summary == nil {
+		// Stages without a summary are compared by item count alone
+		result.Summary = &StageSummary{Count: int64(result.Items)}
+	}
+	changed, repos := c.updateStageCache(name, result.Summary)
+	c.history.stageFinished(run, name, StatusSucceeded, changed, result.Items, nil)
+	return changed, repos, nil
 }
 
-// hasDataChanged compares previous and current data to detect changes
-func (c *CrawlingCoordinator) hasDataChanged(previous, current interface{}) bool {
-	if previous == nil {
-		return true
+// serveStale falls back to the cached summary of a stage whose breaker rejected the call: the
+// stage is marked stale, which its dependents see as unchanged data, and retried once the
+// breaker's timeout has passed. It reports false when nothing is cached to fall back to.
+func (c *CrawlingCoordinator) serveStale(run *CoordinatorRun, name string, err error) bool {
+	stage := c.stages[name]
+
+	now := time.Now()
+	c.cacheMutex.Lock()
+	if stage.cache == nil {
+		c.cacheMutex.Unlock()
+		return false
+	}
+	engaged := stage.staleSince.IsZero()
+	if engaged {
+		stage.staleSince = now
+	}
+	scheduleRetry := stage.retryAt.IsZero()
+	if scheduleRetry {
+		stage.retryAt = now.Add(stage.cb.Settings().Timeout)
 	}
+	retryAt := stage.retryAt
+	count := stage.cache.Count
+	fetchedAt := stage.fetchedAt
+	c.cacheMutex.Unlock()
+
+	log.Printf("%s circuit breaker is open, serving the data fetched at %s (%d items) as stale, retrying at %s",
+		name, fetchedAt.Format(time.RFC3339), count, retryAt.Format(time.RFC3339))
+	c.history.stageFinished(run, name, StatusStale, false, 0, err)
+	if scheduleRetry {
+		time.AfterFunc(time.Until(retryAt), func() { c.retryStale(name) })
+	}
+	if engaged {
+		message := fmt.Sprintf("Its circuit breaker is open; the data fetched at %s is served until the retry at %s",
+			fetchedAt.Format(time.RFC3339), retryAt.Format(time.RFC3339))
+		c.notify(notifier.Notification{
+			Event:   EventStageStale,
+			Title:   fmt.Sprintf("The %s stage is serving stale data", name),
+			Message: message,
+			Fields: map[string]interface{}{
+				"stage":     name,
+				"count":     count,
+				"fetchedAt": fetchedAt,
+				"retryAt":   retryAt,
+			},
+		})
+	}
+	return true
+}
 
-	// Convert both to JSON for deep comparison
-	prevJSON, _ := json.Marshal(previous)
-	currJSON, _ := json.Marshal(current)
+// retryStale runs a stage serving stale data again, once its breaker had time to half-open
+func (c *CrawlingCoordinator) retryStale(name string) {
+	c.cacheMutex.Lock()
+	stage, ok := c.stages[name]
+	if ok {
+		stage.retryAt = time.Time{}
+	}
+	c.cacheMutex.Unlock()
+	if !ok {
+		return
+	}
 
-	// Compare JSON strings
-	return string(prevJSON) != string(currJSON)
+	if err := c.runStage(TriggerRetry, name); err != nil {
+		log.Printf("Retry of the stale %s stage failed: %v", name, err)
+	}
 }
 
-// CrawlAll orchestrates the crawling of all data with interdependencies
+// updateStageCache stores a fresh stage summary and updates the stability tracking,
+// reporting whether the data changed and which repositories changed
+func (c *CrawlingCoordinator) updateStageCache(name string, summary *StageSummary) (bool, []int64) {
+	stage := c.stages[name]
+
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	if !stage.staleSince.IsZero() {
+		log.Printf("%s data is fresh again after being stale since %s", name, stage.staleSince.Format(time.RFC3339))
+		stage.staleSince = time.Time{}
+	}
+	stage.fetchedAt = time.Now()
+
+	if c.hasDataChanged(stage.cache, summary) {
+		repos := changedRepos(stage.cache, summary)
+		logSummaryChange(name, stage.cache, summary, repos)
+		stage.cache = summary
+		stage.resume()
+
+		// When a stage changes, unpause the stages that depend on it
+		for _, dependent := range stage.dependents {
+			c.stages[dependent].resume()
+		}
+		return true, repos
+	}
+
+	log.Printf("No changes in %s data (%d items)", name, summary.Count)
+	stage.noChangeCount++
+
+	// Check if we should pause this endpoint
+	if stage.noChangeCount >= c.thresholdOf(stage) {
+		c.pause(stage)
+		log.Printf("%s API has been stable for multiple checks, pausing calls for %s", name, stage.pauseLength)
+	}
+
+	return false, nil
+}
+
+// CrawlAll runs every stage as soon as its dependencies have finished, so stages
+// that don't depend on each other run concurrently. Stages whose condition does not hold are skipped.
 func (c *CrawlingCoordinator) CrawlAll() {
-	var wg sync.WaitGroup
-	repoChanged := false
-	releaseChanged := false
+	ctx := context.Background()
 
-	// Step 1: Crawl repositories only if we haven't successfully done so yet or previous attempt failed
 	c.cacheMutex.RLock()
-	repoNeedsCall := c.repoCache == nil // Only call if we don't have repo data yet
+	order := append([]string(nil), c.order...)
 	c.cacheMutex.RUnlock()
 
-	if repoNeedsCall {
+	run := c.history.start(TriggerPeriodic, order)
+	defer c.finishRun(run)
+
+	var wg sync.WaitGroup
+	var changedMutex sync.Mutex
+	changed := make(map[string]bool, len(order))
+	changedReposOf := make(map[string][]int64, len(order))
+
+	// Each stage closes its channel when finished to release its dependents
+	finished := make(map[string]chan struct{}, len(order))
+	for _, name := range order {
+		finished[name] = make(chan struct{})
+	}
+
+	for _, name := range order {
 		wg.Add(1)
-		go func() {
+		go func(name string) {
 			defer wg.Done()
+			defer close(finished[name])
 
-			log.Println("Starting repository crawling (one-time)...")
-			repoData, err := c.CrawlRepos()
-			if err != nil {
-				log.Printf("Error crawling repositories: %v", err)
-				return
+			var changedUpstream []string
+			var upstreamRepos []int64
+			targeted := true
+			for _, dep := range c.stages[name].config.DependsOn {
+				<-finished[dep]
+
+				changedMutex.Lock()
+				if changed[dep] {
+					changedUpstream = append(changedUpstream, dep)
+					// A changed dependency without per-repository changes touches every repository
+					if len(changedReposOf[dep]) == 0 {
+						targeted = false
+					}
+					upstreamRepos = mergeRepos(upstreamRepos, changedReposOf[dep])
+				}
+				changedMutex.Unlock()
+			}
+			if !targeted {
+				upstreamRepos = nil
 			}
 
-			log.Println("Repository data successfully fetched")
-			c.cacheMutex.Lock()
-			c.repoCache = repoData
-			repoChanged = true
+			stageChanged, repos, _ := c.crawlStage(ctx, run, name, changedUpstream, upstreamRepos, false)
 
-			// Initial repo data fetch should trigger release check
-			c.releasePaused = false
-			c.releaseNoChangeCount = 0
-			c.cacheMutex.Unlock()
-		}()
+			changedMutex.Lock()
+			changed[name] = stageChanged
+			changedReposOf[name] = repos
+			changedMutex.Unlock()
+		}(name)
+	}
 
-		wg.Wait()
+	wg.Wait()
+
+	// Check status of APIs - for logging purposes
+	now := time.Now()
+	c.cacheMutex.RLock()
+	dependentsPaused := true
+	for _, stage := range c.stages {
+		if len(stage.config.DependsOn) > 0 && !stage.isPaused(now) {
+			dependentsPaused = false
+		}
+	}
+	c.cacheMutex.RUnlock()
+
+	if dependentsPaused {
+		log.Println("All dependent APIs are stable, monitoring for changes")
 	} else {
-		log.Println("Repository data already fetched, skipping repo API call")
+		log.Println("Crawling cycle completed")
 	}
+}
 
-	// Step 2: If repos changed or no release data yet, crawl releases
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+// mergeRepos adds the repositories of b missing from a, keeping the result sorted
+func mergeRepos(a, b []int64) []int64 {
+	seen := make(map[int64]bool, len(a))
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			seen[id] = true
+			a = append(a, id)
+		}
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	return a
+}
 
-		c.cacheMutex.RLock()
-		shouldCrawl := (repoChanged || c.releaseCache == nil) && !c.releasePaused
-		c.cacheMutex.RUnlock()
+// HasStage reports whether a stage with the given name is configured
+func (c *CrawlingCoordinator) HasStage(name string) bool {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
 
-		if !shouldCrawl {
-			if c.releasePaused {
-				log.Println("Release API is stable, skipping call")
-			} else {
-				log.Println("Skipping release crawling, no repo changes")
-			}
-			return
-		}
+	_, ok := c.stages[name]
+	return ok
+}
 
-		log.Println("Starting release crawling...")
-		releaseData, err := c.CrawlReleases()
-		if err != nil {
-			log.Printf("Error crawling releases: %v", err)
-			return
-		}
+// RunStage runs a single stage on demand, ignoring cached data and stability pauses.
+// Dependent stages are not triggered, but they are unpaused if the stage reports changes.
+func (c *CrawlingCoordinator) RunStage(name string) error {
+	return c.runStage(TriggerManual, name)
+}
 
-		c.cacheMutex.RLock()
-		prevData := c.releaseCache
-		c.cacheMutex.RUnlock()
+// RunScheduledStage runs a single stage on its cron schedule, like RunStage
+func (c *CrawlingCoordinator) RunScheduledStage(name string) error {
+	return c.runStage(TriggerScheduled, name)
+}
 
-		if c.hasDataChanged(prevData, releaseData) {
-			log.Println("Release data has changed")
-			c.cacheMutex.Lock()
-			c.releaseCache = releaseData
-			c.releaseNoChangeCount = 0
-			releaseChanged = true
+func (c *CrawlingCoordinator) runStage(trigger string, name string) error {
+	if !c.HasStage(name) {
+		return fmt.Errorf("unknown stage: %s", name)
+	}
 
-			// When releases change, unpause commit API
-			c.commitPaused = false
-			c.commitNoChangeCount = 0
-			c.cacheMutex.Unlock()
-		} else {
-			log.Println("No changes in release data")
-			c.cacheMutex.Lock()
-			c.releaseNoChangeCount++
+	log.Printf("Running %s stage (%s)", name, trigger)
+	run := c.history.start(trigger, []string{name})
+	defer c.finishRun(run)
 
-			// Check if we should pause this endpoint
-			if c.releaseNoChangeCount >= c.stabilityThreshold {
-				c.releasePaused = true
-				log.Println("Release API has been stable for multiple checks, pausing calls")
-			}
-			c.cacheMutex.Unlock()
-		}
-	}()
+	_, _, err := c.crawlStage(context.Background(), run, name, nil, nil, true)
+	return err
+}
 
-	wg.Wait()
+// StageData returns the cached summary of a stage, marked stale while its breaker is open
+func (c *CrawlingCoordinator) StageData(name string) (*StageData, error) {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
 
-	// Step 3: If releases changed or no commit data yet, crawl commits
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	stage, ok := c.stages[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown stage: %s", name)
+	}
+
+	data := &StageData{
+		Stage:   name,
+		Summary: stage.cache,
+		Stale:   !stage.staleSince.IsZero(),
+	}
+	if !stage.fetchedAt.IsZero() {
+		fetchedAt := stage.fetchedAt
+		data.FetchedAt = &fetchedAt
+	}
+	if data.Stale {
+		staleSince := stage.staleSince
+		data.StaleSince = &staleSince
+	}
+	if !stage.retryAt.IsZero() {
+		retryAt := stage.retryAt
+		data.RetryAt = &retryAt
+	}
+	return data, nil
+}
 
-		c.cacheMutex.RLock()
-		shouldCrawl := (releaseChanged || c.commitCache == nil) && !c.commitPaused
-		c.cacheMutex.RUnlock()
+// Runs returns the most recent coordinator runs, newest first, including any run in progress
+func (c *CrawlingCoordinator) Runs() []CoordinatorRun {
+	return c.history.list()
+}
 
-		if !shouldCrawl {
-			if c.commitPaused {
-				log.Println("Commit API is stable, skipping call")
-			} else {
-				log.Println("Skipping commit crawling, no release changes")
-			}
-			return
-		}
+// Metrics returns per-stage run metrics in dependency order
+func (c *CrawlingCoordinator) Metrics() []StageMetrics {
+	c.cacheMutex.RLock()
+	order := append([]string(nil), c.order...)
+	c.cacheMutex.RUnlock()
 
-		log.Println("Starting commit crawling...")
-		commitData, err := c.CrawlCommits()
-		if err != nil {
-			log.Printf("Error crawling commits: %v", err)
-			return
+	return c.history.stageMetrics(order)
+}
+
+// MetricValues exposes the stage metrics to the alert engine as "<stage>.<metric>" values:
+// runs, failures, skips, changes, items and stale_serves are counters, last_duration_ms,
+// breaker_open and stale are gauges
+func (c *CrawlingCoordinator) MetricValues() map[string]float64 {
+	values := make(map[string]float64)
+	for _, m := range c.Metrics() {
+		values[m.Stage+".runs"] = float64(m.Runs)
+		values[m.Stage+".failures"] = float64(m.Failures)
+		values[m.Stage+".skips"] = float64(m.Skips)
+		values[m.Stage+".changes"] = float64(m.Changes)
+		values[m.Stage+".items"] = float64(m.ItemsTotal)
+		values[m.Stage+".last_duration_ms"] = float64(m.LastDurationMs)
+		values[m.Stage+".stale_serves"] = float64(m.StaleServes)
+	}
+
+	c.cacheMutex.RLock()
+	for name, stage := range c.stages {
+		breakerOpen := 0.0
+		if stage.cb.State() == "open" {
+			breakerOpen = 1
 		}
+		values[name+".breaker_open"] = breakerOpen
+		stale := 0.0
+		if !stage.staleSince.IsZero() {
+			stale = 1
+		}
+		values[name+".stale"] = stale
+	}
+	c.cacheMutex.RUnlock()
 
-		c.cacheMutex.RLock()
-		prevData := c.commitCache
-		c.cacheMutex.RUnlock()
+	return values
+}
 
-		if c.hasDataChanged(prevData, commitData) {
-			log.Println("Commit data has changed")
-			c.cacheMutex.Lock()
-			c.commitCache = commitData
-			c.commitNoChangeCount = 0
-			c.cacheMutex.Unlock()
-		} else {
-			log.Println("No changes in commit data")
-			c.cacheMutex.Lock()
-			c.commitNoChangeCount++
+// DryRun reports which stages the next CrawlAll cycle would trigger without calling any API
+func (c *CrawlingCoordinator) DryRun() []StagePlan {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	now := time.Now()
+	wouldRun := make(map[string]bool, len(c.order))
+	plans := make([]StagePlan, 0, len(c.order))
+
+	for _, name := range c.order {
+		stage := c.stages[name]
+		plan := StagePlan{
+			Stage:         name,
+			DependsOn:     stage.config.DependsOn,
+			Condition:     stage.config.Condition,
+			Concurrency:   stage.config.Concurrency,
+			Running:       len(stage.slots),
+			Paused:        stage.isPaused(now),
+			NoChangeCount: stage.noChangeCount,
+			HasCache:      stage.cache != nil,
+			BreakerState:  stage.cb.State(),
+			Stale:         !stage.staleSince.IsZero(),
+
+			StabilityThreshold: c.thresholdOf(stage),
+		}
+		if stage.cache != nil {
+			plan.CachedCount = stage.cache.Count
+			plan.Checksum = stage.cache.Checksum
+		}
+		if !stage.retryAt.IsZero() {
+			retryAt := stage.retryAt
+			plan.RetryAt = &retryAt
+		}
 
-			// Check if we should pause this endpoint
-			if c.commitNoChangeCount >= c.stabilityThreshold {
-				c.commitPaused = true
-				log.Println("Commit API has been stable for multiple checks, pausing calls")
+		upstream := ""
+		for _, dep := range stage.config.DependsOn {
+			if wouldRun[dep] {
+				upstream = dep
+				break
 			}
-			c.cacheMutex.Unlock()
 		}
-	}()
 
-	wg.Wait()
+		switch {
+		case stage.config.Condition == ConditionOnce && stage.cache != nil:
+			plan.Reason = "data already fetched"
+		case stage.config.Condition == ConditionOnce:
+			plan.WouldRun = true
+			plan.Reason = "no data fetched yet"
+		case stage.isPaused(now):
+			until := stage.pausedUntil
+			plan.PausedUntil = &until
+			plan.Reason = fmt.Sprintf("paused until %s after %d consecutive unchanged responses",
+				until.Format(time.RFC3339), stage.noChangeCount)
+		case stage.config.Condition == ConditionAlways:
+			plan.WouldRun = true
+			plan.Reason = "runs on every cycle"
+		case stage.cache == nil:
+			plan.WouldRun = true
+			plan.Reason = "no data fetched yet"
+		case stage.recheckDue(now):
+			plan.WouldRun = true
+			plan.Reason = "stability pause expired, re-checking"
+		case upstream != "":
+			plan.WouldRun = true
+			plan.Reason = fmt.Sprintf("runs if the %s stage reports changes", upstream)
+		default:
+			plan.Reason = "no upstream changes expected"
+		}
 
-	// Check status of APIs - for logging purposes
-	c.cacheMutex.RLock()
-	releaseAndCommitPaused := c.releasePaused && c.commitPaused
-	c.cacheMutex.RUnlock()
+		if plan.WouldRun && plan.BreakerState == "open" {
+			plan.Reason += "; circuit breaker is open, call would be rejected"
+		}
 
-	if releaseAndCommitPaused {
-		log.Println("All dependent APIs are stable, monitoring for changes")
-	} else {
-		log.Println("Crawling cycle completed")
+		wouldRun[name] = plan.WouldRun
+		plans = append(plans, plan)
 	}
+
+	return plans
 }
 
 // ForceReactivateAll forcibly reactivates all API endpoints
 func (c *CrawlingCoordinator) ForceReactivateAll() {
 	c.cacheMutex.Lock()
-	c.repoCache = nil // Force repo to be fetched again
-	c.releasePaused = false
-	c.commitPaused = false
-	c.repoNoChangeCount = 0
-	c.releaseNoChangeCount = 0
-	c.commitNoChangeCount = 0
+	for _, stage := range c.stages {
+		// Force one-time stages to be fetched again
+		if stage.config.Condition == ConditionOnce {
+			stage.cache = nil
+			stage.staleSince = time.Time{}
+		}
+		stage.resume()
+	}
 	c.cacheMutex.Unlock()
 	log.Println("Forcibly reactivated all API endpoints")
 }
 
-// SetStabilityThreshold sets the number of consecutive no-change responses before pausing an endpoint
+// SetStabilityThreshold sets the default number of consecutive no-change responses before pausing
+// an endpoint. Stages with their own threshold keep it.
 func (c *CrawlingCoordinator) SetStabilityThreshold(threshold int) {
 	if threshold < 1 {
 		threshold = 1
 	}
 	c.cacheMutex.Lock()
 	c.stabilityThreshold = threshold
+	for _, stage := range c.stages {
+		c.reevaluatePause(stage)
+	}
 	c.cacheMutex.Unlock()
 	log.Printf("Stability threshold set to %d consecutive no-change responses", threshold)
 }
 
+// SetStageStabilityThreshold overrides the stability threshold of one stage at runtime;
+// 0 reverts the stage to the coordinator's default. A paused stage whose unchanged count
+// is now below its threshold is resumed, and one at or above it is paused.
+func (c *CrawlingCoordinator) SetStageStabilityThreshold(name string, threshold int) error {
+	if threshold < 0 {
+		return fmt.Errorf("stability threshold must not be negative")
+	}
+
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	stage, ok := c.stages[name]
+	if !ok {
+		return fmt.Errorf("unknown stage: %s", name)
+	}
+
+	stage.config.StabilityThreshold = threshold
+	c.reevaluatePause(stage)
+	log.Printf("Stability threshold of %s set to %d consecutive no-change responses", name, c.thresholdOf(stage))
+	return nil
+}
+
+// thresholdOf returns the effective stability threshold of a stage; the caller must hold cacheMutex
+func (c *CrawlingCoordinator) thresholdOf(stage *stageState) int {
+	if stage.config.StabilityThreshold > 0 {
+		return stage.config.StabilityThreshold
+	}
+	return c.stabilityThreshold
+}
+
+// reevaluatePause applies a changed threshold to a stage's pause; the caller must hold cacheMutex
+func (c *CrawlingCoordinator) reevaluatePause(stage *stageState) {
+	if stage.noChangeCount == 0 {
+		return
+	}
+
+	stable := stage.noChangeCount >= c.thresholdOf(stage)
+	switch {
+	case stable && stage.pauseLength == 0:
+		c.pause(stage)
+	case !stable && stage.pauseLength > 0:
+		stage.pausedUntil = time.Time{}
+		stage.pauseLength = 0
+	}
+}
+
+// pause starts the next stability pause of a stage, twice as long as the previous one
+// and at most maxPause; the caller must hold cacheMutex
+func (c *CrawlingCoordinator) pause(stage *stageState) {
+	if stage.pauseLength == 0 {
+		stage.pauseLength = c.pauseBase
+	} else {
+		stage.pauseLength *= 2
+	}
+	if stage.pauseLength > c.maxPause {
+		stage.pauseLength = c.maxPause
+	}
+	stage.pausedUntil = time.Now().Add(stage.pauseLength)
+	c.history.stagePaused(stage.config.Name)
+}
+
+// SetBreakerSettings replaces the circuit breakers of every stage with ones using settings.
+// The new breakers start closed, so an open breaker lets calls through again at once.
+func (c *CrawlingCoordinator) SetBreakerSettings(settings utils.BreakerSettings) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	if settings == c.breakerSettings {
+		return
+	}
+	c.breakerSettings = settings
+	for _, stage := range c.stages {
+		stage.cb.Configure(settings)
+	}
+	log.Printf("Circuit breakers of %d stages reconfigured", len(c.stages))
+}
+
+// DisableBreakers makes the stages call their crawlers without circuit breakers, as the
+// experiments before Exp 3 did; call it before the coordinator starts
+func (c *CrawlingCoordinator) DisableBreakers() {
+	c.breakersOff = true
+	log.Printf("Circuit breakers of the coordinator stages disabled")
+}
+
+// SetMaxPause caps how long a stable stage goes without being re-checked
+func (c *CrawlingCoordinator) SetMaxPause(maxPause time.Duration) {
+	if maxPause <= 0 {
+		return
+	}
+	c.cacheMutex.Lock()
+	c.maxPause = maxPause
+	c.cacheMutex.Unlock()
+	log.Printf("Stable stages are re-checked at least every %s", maxPause)
+}
+
+// SetAPIKey makes the coordinator send an API key with every call to the crawler API,
+// needed when the API requires authentication; stage runs need an operator key
+func (c *CrawlingCoordinator) SetAPIKey(key string) {
+	c.client.Transport = &apiKeyTransport{key: key, next: c.transport()}
+}
+
+// SetSigningSecret makes the coordinator sign every call to the crawler API with the secret
+// it shares with the API, which then knows the call comes from the coordinator
+func (c *CrawlingCoordinator) SetSigningSecret(secret string) {
+	c.client.Transport = &signingTransport{signer: auth.NewSigner(secret), next: c.transport()}
+}
+
+// transport is the client transport that SetAPIKey and SetSigningSecret wrap
+func (c *CrawlingCoordinator) transport() http.RoundTripper {
+	if c.client.Transport != nil {
+		return c.client.Transport
+	}
+	return http.DefaultTransport
+}
+
+// apiKeyTransport adds the X-API-Key header to outgoing requests
+type apiKeyTransport struct {
+	key  string
+	next http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-API-Key", t.key)
+	return t.next.RoundTrip(req)
+}
+
+// signingTransport adds the signature headers to outgoing requests
+type signingTransport struct {
+	signer *auth.Signer
+	next   http.RoundTripper
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := t.signer.Sign(req, time.Now()); err != nil {
+		return nil, fmt.Errorf("signing request: %w", err)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// SetBlackouts makes periodic crawling skip the cycles falling in a blackout window.
+// Stages run on demand with RunStage are not affected.
+func (c *CrawlingCoordinator) SetBlackouts(blackouts *Blackouts) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+	c.blackouts = blackouts
+}
+
+// InBlackout returns when the blackout in effect at now ends, and whether there is one
+func (c *CrawlingCoordinator) InBlackout(now time.Time) (time.Time, bool) {
+	c.cacheMutex.RLock()
+	blackouts := c.blackouts
+	c.cacheMutex.RUnlock()
+	return blackouts.Until(now)
+}
+
+// ScheduledCycle is an upcoming periodic cycle; Skipped cycles fall in a blackout window
+type ScheduledCycle struct {
+	At      time.Time
+	Skipped bool
+}
+
+// UpcomingCycles lists the periodic cycles due in [from, to). It is empty when periodic
+// crawling is not running.
+func (c *CrawlingCoordinator) UpcomingCycles(from, to time.Time) []ScheduledCycle {
+	c.cacheMutex.RLock()
+	interval, next, blackouts := c.cycleInterval, c.nextCycle, c.blackouts
+	c.cacheMutex.RUnlock()
+
+	if interval <= 0 {
+		return nil
+	}
+	for next.Before(from) {
+		next = next.Add(interval)
+	}
+	var cycles []ScheduledCycle
+	for ; next.Before(to); next = next.Add(interval) {
+		_, blackedOut := blackouts.Until(next)
+		cycles = append(cycles, ScheduledCycle{At: next, Skipped: blackedOut})
+	}
+	return cycles
+}
+
 // StartPeriodicCrawling continuously monitors for changes and crawls data
 func (c *CrawlingCoordinator) StartPeriodicCrawling(interval time.Duration, stopChan <-chan struct{}) {
+	// Stability pauses start at one crawl interval and double after each unchanged re-check
+	c.cacheMutex.Lock()
+	c.pauseBase = interval
+	c.cycleInterval = interval
+	c.nextCycle = time.Now().Add(interval)
+	c.cacheMutex.Unlock()
+
 	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	defer func() {
+		ticker.Stop()
+		c.cacheMutex.Lock()
+		c.cycleInterval = 0
+		c.cacheMutex.Unlock()
+	}()
 
 	for {
 		select {
-		case <-ticker.C:
+		case now := <-ticker.C:
+			c.cacheMutex.Lock()
+			c.nextCycle = now.Add(interval)
+			c.cacheMutex.Unlock()
+
+			if until, blackedOut := c.InBlackout(now); blackedOut {
+				log.Printf("Blackout window until %s, skipping crawling cycle", until.Format(time.Kitchen))
+				c.history.cycleSkipped()
+				continue
+			}
 			c.CrawlAll()
 		case <-stopChan:
 			log.Println("Stopping periodic crawling")
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Coordinator health statuses
+const (
+	HealthOK = "ok"
+	// HealthStalled means no periodic cycle started for over two crawl intervals past the due one
+	HealthStalled = "stalled"
+)
+
+// CoordinatorHealth is the coordinator's own state, independent of the crawler API it drives
+type CoordinatorHealth struct {
+	Status       string     `json:"status"`
+	Periodic     bool       `json:"periodic"`
+	LastCycleAt  *time.Time `json:"lastCycleAt,omitempty"`
+	NextCycleAt  *time.Time `json:"nextCycleAt,omitempty"`
+	OpenBreakers []string   `json:"openBreakers,omitempty"`
+	PausedStages []string   `json:"pausedStages,omitempty"`
+}
+
+// breakerStateValues are the values of the breaker state gauge
+var breakerStateValues = map[string]float64{
+	"closed":    0,
+	"half-open": 1,
+	"open":      2,
+}
+
+// stageGauges is the current state of a stage, for metrics and health
+type stageGauges struct {
+	name    string
+	breaker string
+	paused  bool
+	stale   bool
+	running int
+}
+
+func (c *CrawlingCoordinator) stageGauges(now time.Time) []stageGauges {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	gauges := make([]stageGauges, 0, len(c.order))
+	for _, name := range c.order {
+		stage := c.stages[name]
+		gauges = append(gauges, stageGauges{
+			name:    name,
+			breaker: stage.cb.State(),
+			paused:  stage.isPaused(now),
+			stale:   !stage.staleSince.IsZero(),
+			running: len(stage.slots),
+		})
+	}
+	return gauges
+}
+
+// CycleMetrics returns the counts of periodic crawl cycles
+func (c *CrawlingCoordinator) CycleMetrics() CycleMetrics {
+	return c.history.cycleMetrics()
+}
+
+// Health reports whether periodic crawling keeps up, and lists the stages whose breaker is open
+// or that are paused. Open breakers are the crawler API's trouble and leave the status ok.
+func (c *CrawlingCoordinator) Health(now time.Time) CoordinatorHealth {
+	health := CoordinatorHealth{
+		Status:      HealthOK,
+		LastCycleAt: c.history.cycleMetrics().LastRunAt,
+	}
+
+	c.cacheMutex.RLock()
+	interval, next := c.cycleInterval, c.nextCycle
+	c.cacheMutex.RUnlock()
+	if interval > 0 {
+		health.Periodic = true
+		health.NextCycleAt = &next
+		if now.After(next.Add(2 * interval)) {
+			health.Status = HealthStalled
+		}
+	}
+
+	for _, stage := range c.stageGauges(now) {
+		if stage.breaker == "open" {
+			health.OpenBreakers = append(health.OpenBreakers, stage.name)
+		}
+		if stage.paused {
+			health.PausedStages = append(health.PausedStages, stage.name)
+		}
+	}
+	return health
+}
+
+// WritePrometheus writes the cycle and stage metrics in the Prometheus text format
+func (c *CrawlingCoordinator) WritePrometheus(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	p := &promWriter{w: out}
+
+	cycles := c.CycleMetrics()
+	p.family("crawler_coordinator_cycles_total", "counter", "Periodic crawl cycles run")
+	p.sample("crawler_coordinator_cycles_total", nil, float64(cycles.Runs))
+	p.family("crawler_coordinator_cycle_failures_total", "counter", "Periodic crawl cycles with a failed stage")
+	p.sample("crawler_coordinator_cycle_failures_total", nil, float64(cycles.Failures))
+	p.family("crawler_coordinator_cycles_skipped_total", "counter", "Periodic crawl cycles skipped during a blackout window")
+	p.sample("crawler_coordinator_cycles_skipped_total", nil, float64(cycles.BlackoutSkips))
+	p.family("crawler_coordinator_cycle_last_duration_seconds", "gauge", "Duration of the last periodic crawl cycle")
+	p.sample("crawler_coordinator_cycle_last_duration_seconds", nil, float64(cycles.LastDurationMs)/1000)
+	if cycles.LastRunAt != nil {
+		p.family("crawler_coordinator_cycle_last_run_timestamp_seconds", "gauge", "Start of the last periodic crawl cycle")
+		p.sample("crawler_coordinator_cycle_last_run_timestamp_seconds", nil, float64(cycles.LastRunAt.Unix()))
+	}
+
+	stageCounters := []struct {
+		name, help string
+		value      func(m StageMetrics) float64
+	}{
+		{"crawler_coordinator_stage_runs_total", "Stage calls, failed or not",
+			func(m StageMetrics) float64 { return float64(m.Runs) }},
+		{"crawler_coordinator_stage_failures_total", "Failed stage calls",
+			func(m StageMetrics) float64 { return float64(m.Failures) }},
+		{"crawler_coordinator_stage_changes_total", "Stage calls whose data changed",
+			func(m StageMetrics) float64 { return float64(m.Changes) }},
+		{"crawler_coordinator_stage_items_total", "Items reported by stage calls",
+			func(m StageMetrics) float64 { return float64(m.ItemsTotal) }},
+		{"crawler_coordinator_stage_duration_seconds_total", "Time spent in stage calls",
+			func(m StageMetrics) float64 { return float64(m.TotalDurationMs) / 1000 }},
+		{"crawler_coordinator_stage_cache_hits_total", "Stage calls skipped as the cached data was still valid",
+			func(m StageMetrics) float64 { return float64(m.CacheHits) }},
+		{"crawler_coordinator_stage_paused_skips_total", "Stage calls skipped during a stability pause",
+			func(m StageMetrics) float64 { return float64(m.PausedSkips) }},
+		{"crawler_coordinator_stage_pauses_total", "Stability pauses started",
+			func(m StageMetrics) float64 { return float64(m.Pauses) }},
+		{"crawler_coordinator_stage_stale_serves_total", "Stage calls rejected by an open breaker that served the cached data",
+			func(m StageMetrics) float64 { return float64(m.StaleServes) }},
+	}
+	metrics := c.Metrics()
+	for _, counter := range stageCounters {
+		p.family(counter.name, "counter", counter.help)
+		for _, m := range metrics {
+			p.sample(counter.name, []string{"stage", m.Stage}, counter.value(m))
+		}
+	}
+	p.family("crawler_coordinator_stage_last_duration_seconds", "gauge", "Duration of the last stage call")
+	for _, m := range metrics {
+		p.sample("crawler_coordinator_stage_last_duration_seconds", []string{"stage", m.Stage}, float64(m.LastDurationMs)/1000)
+	}
+
+	gauges := c.stageGauges(time.Now())
+	p.family("crawler_coordinator_stage_breaker_state", "gauge", "Circuit breaker state: 0 closed, 1 half-open, 2 open")
+	for _, stage := range gauges {
+		p.sample("crawler_coordinator_stage_breaker_state", []string{"stage", stage.name}, breakerStateValues[stage.breaker])
+	}
+	p.family("crawler_coordinator_stage_paused", "gauge", "Whether the stage is in a stability pause")
+	for _, stage := range gauges {
+		paused := 0.0
+		if stage.paused {
+			paused = 1
+		}
+		p.sample("crawler_coordinator_stage_paused", []string{"stage", stage.name}, paused)
+	}
+	p.family("crawler_coordinator_stage_stale", "gauge", "Whether the stage serves its cached data as its breaker is open")
+	for _, stage := range gauges {
+		stale := 0.0
+		if stage.stale {
+			stale = 1
+		}
+		p.sample("crawler_coordinator_stage_stale", []string{"stage", stage.name}, stale)
+	}
+	p.family("crawler_coordinator_stage_running", "gauge", "Stage calls in progress")
+	for _, stage := range gauges {
+		p.sample("crawler_coordinator_stage_running", []string{"stage", stage.name}, float64(stage.running))
+	}
+
+	if p.err != nil {
+		return p.err
+	}
+	return out.Flush()
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promWriter writes metric families, keeping the first write error
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) family(name, kind, help string) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+}
+
+// sample writes one value; labels alternate names and values
+func (p *promWriter) sample(name string, labels []string, value float64) {
+	if p.err != nil {
+		return
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	if len(pairs) > 0 {
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	_, p.err = fmt.Fprintf(p.w, "%s %s\n", name, strconv.FormatFloat(value, 'g', -1, 64))
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears bounds the search for the next run, so expressions that never match, such
+// as "0 0 30 2 *", are rejected
+const cronSearchYears = 5
+
+// cronDescriptors are the shorthands accepted in place of the five fields
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is the range of values of one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// CronSchedule is a parsed cron expression: five fields "minute hour day-of-month month
+// day-of-week" with lists, ranges and steps ("0,30 9-17/2 * * 1-5"), one of the descriptors
+// such as "@hourly", or "@every <duration>" for a fixed interval
+type CronSchedule struct {
+	expression string
+	// every is set for "@every" schedules, which ignore the fields
+	every time.Duration
+
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record an unrestricted day field: as in cron, a day matches
+	// either day field when both are restricted
+	domStar, dowStar bool
+}
+
+// ParseCron parses a cron expression
+func ParseCron(expression string) (*CronSchedule, error) {
+	expr := strings.TrimSpace(expression)
+	if interval, ok := strings.CutPrefix(expr, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+		if every < time.Minute {
+			return nil, fmt.Errorf("invalid cron expression %q: interval must be at least a minute", expression)
+		}
+		return &CronSchedule{expression: expr, every: every}, nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		fields, ok := cronDescriptors[expr]
+		if !ok {
+			return nil, fmt.Errorf("invalid cron expression %q: unknown descriptor", expression)
+		}
+		schedule, err := ParseCron(fields)
+		if err != nil {
+			return nil, err
+		}
+		schedule.expression = expr
+		return schedule, nil
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields", expression, len(cronFields))
+	}
+	schedule := &CronSchedule{
+		expression: expr,
+		domStar:    fields[2] == "*",
+		dowStar:    fields[4] == "*",
+	}
+	sets := []*uint64{&schedule.minute, &schedule.hour, &schedule.dom, &schedule.month, &schedule.dow}
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+		*sets[i] = set
+	}
+	// Sunday may be written 7
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid cron expression %q: never matches", expression)
+	}
+	return schedule, nil
+}
+
+// parseCronField returns the set of values of a field as a bit mask
+func parseCronField(value string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepPart, field.name)
+			}
+			step = parsed
+		}
+
+		// Sunday may be written 7 in the day of week field
+		max := field.max
+		if field.name == "day of week" {
+			max = 7
+		}
+		low, high := field.min, max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s", lowPart, field.name)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s", highPart, field.name)
+				}
+			} else if hasStep {
+				// "5/15" steps from 5 to the end of the range
+				high = max
+			}
+		} else if field.name == "day of week" {
+			high = field.max
+		}
+		if low < field.min || high > max || low > high {
+			return 0, fmt.Errorf("%s out of range %d-%d: %q", field.name, field.min, field.max, part)
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// String returns the expression as it was parsed
+func (s *CronSchedule) String() string {
+	return s.expression
+}
+
+// Next returns the first run strictly after after, in after's location
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every).Truncate(time.Minute)
+	}
+
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// DecayCurve spaces out the re-crawls of a repository by how long it has been dormant.
+// A repository with a release within the last HalfLife is re-checked every MinInterval, and
+// the interval doubles with every further HalfLife without a release, up to MaxInterval.
+type DecayCurve struct {
+	MinInterval time.Duration `mapstructure:"min_interval" json:"min_interval"`
+	MaxInterval time.Duration `mapstructure:"max_interval" json:"max_interval"`
+	HalfLife    time.Duration `mapstructure:"half_life" json:"half_life"`
+}
+
+// DefaultDecayCurve re-checks active repositories hourly and dormant ones weekly
+func DefaultDecayCurve() DecayCurve {
+	return DecayCurve{
+		MinInterval: time.Hour,
+		MaxInterval: 7 * 24 * time.Hour,
+		HalfLife:    7 * 24 * time.Hour,
+	}
+}
+
+// Validate rejects curves whose interval would not grow from MinInterval to MaxInterval
+func (d DecayCurve) Validate() error {
+	if d.MinInterval <= 0 || d.HalfLife <= 0 {
+		return errors.New("min_interval and half_life must be positive")
+	}
+	if d.MaxInterval < d.MinInterval {
+		return errors.New("max_interval must not be shorter than min_interval")
+	}
+	return nil
+}
+
+// Interval is how long to wait before re-checking a repository whose last activity was idle ago
+func (d DecayCurve) Interval(idle time.Duration) time.Duration {
+	if idle < d.HalfLife {
+		return d.MinInterval
+	}
+
+	interval := float64(d.MinInterval) * math.Pow(2, float64(idle/d.HalfLife))
+	if interval >= float64(d.MaxInterval) {
+		return d.MaxInterval
+	}
+	return time.Duration(interval)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"crawler/baseline/internal/notifier"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// EventJobsRecovered is notified when a starting worker instance finds jobs orphaned by a crash
+const EventJobsRecovered = "jobs.recovered"
+
+// ErrJobOrphaned fails a job whose worker stopped while running it, once it used up its attempts
+var ErrJobOrphaned = errors.New("worker stopped while running the job")
+
+// JobRecovery counts the orphaned jobs found by RecoverOrphans
+type JobRecovery struct {
+	Requeued []int64 `json:"requeued"`
+	Failed   []int64 `json:"failed"`
+}
+
+// RecoverOrphans looks for jobs left running by worker instances that are gone: a previous run
+// of workerID, which is starting, or an instance without a live heartbeat. Jobs claimed fewer
+// than maxAttempts times are put back in the queue and run again from the start, which skips
+// what was already stored; the others are failed. Call it before StartWorkers.
+func (m *JobManager) RecoverOrphans(ctx context.Context, workerID string, maxAttempts int) (JobRecovery, error) {
+	recovery := JobRecovery{Requeued: []int64{}, Failed: []int64{}}
+	if m.store == nil {
+		return recovery, nil
+	}
+
+	running, err := m.store.Running(ctx)
+	if err != nil || len(running) == 0 {
+		return recovery, err
+	}
+	workers, err := m.Workers(ctx)
+	if err != nil {
+		return recovery, err
+	}
+	alive := make(map[string]bool, len(workers))
+	for _, worker := range workers {
+		if worker.Alive && worker.ID != workerID {
+			alive[worker.ID] = true
+		}
+	}
+
+	for _, job := range running {
+		// Workers are named "<instance>-<n>" by StartWorkers
+		instance := job.WorkerID
+		if i := strings.LastIndex(instance, "-"); i >= 0 {
+			instance = instance[:i]
+		}
+		if alive[instance] {
+			continue
+		}
+
+		if job.Attempts < maxAttempts {
+			if err := m.store.Requeue(ctx, job.ID); err != nil {
+				return recovery, fmt.Errorf("requeueing job %d: %w", job.ID, err)
+			}
+			log.Printf("Job %d (%s) orphaned by %s, requeued after %d attempts", job.ID, job.Kind, job.WorkerID, job.Attempts)
+			recovery.Requeued = append(recovery.Requeued, job.ID)
+			continue
+		}
+
+		finished, err := m.store.Finish(ctx, job.ID, fmt.Errorf("%w: %s", ErrJobOrphaned, job.WorkerID))
+		if err != nil {
+			return recovery, fmt.Errorf("failing job %d: %w", job.ID, err)
+		}
+		log.Printf("Job %d (%s) orphaned by %s, failed after %d attempts", job.ID, job.Kind, job.WorkerID, job.Attempts)
+		recovery.Failed = append(recovery.Failed, job.ID)
+		m.notifyFinished(finished)
+	}
+
+	m.notifyRecovered(workerID, recovery)
+	return recovery, nil
+}
+
+func (m *JobManager) notifyRecovered(workerID string, recovery JobRecovery) {
+	if m.notifier == nil || len(recovery.Requeued)+len(recovery.Failed) == 0 {
+		return
+	}
+
+	err := m.notifier.Notify(context.Background(), notifier.Notification{
+		Event: EventJobsRecovered,
+		Title: fmt.Sprintf("%d orphaned jobs recovered by %s", len(recovery.Requeued)+len(recovery.Failed), workerID),
+		Message: fmt.Sprintf("%d requeued, %d failed after using up their attempts",
+			len(recovery.Requeued), len(recovery.Failed)),
+		Fields: map[string]interface{}{
+			"requeued": recovery.Requeued,
+			"failed":   recovery.Failed,
+		},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to notify recovered jobs: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	// WorkerHeartbeatInterval is how often a worker instance refreshes its registration
+	WorkerHeartbeatInterval = 10 * time.Second
+	// workerStaleAfter is how long after its last heartbeat an instance is reported as down
+	workerStaleAfter = 3 * WorkerHeartbeatInterval
+)
+
+// WorkerStatus is a worker instance as registered in the job store
+type WorkerStatus struct {
+	ID          string    `json:"id"`
+	Host        string    `json:"host"`
+	Workers     int       `json:"workers"`
+	Running     int64     `json:"running"`
+	Processed   int64     `json:"processed"`
+	Failed      int64     `json:"failed"`
+	StartedAt   time.Time `json:"startedAt"`
+	HeartbeatAt time.Time `json:"heartbeatAt"`
+	Alive       bool      `json:"alive"`
+}
+
+// StartWorkers runs count workers that take jobs from the store until stop is closed,
+// polling every pollInterval while the queue is empty. The instance registers as workerID
+// and sends a heartbeat with its counters until it stops.
+func (m *JobManager) StartWorkers(workerID string, count int, pollInterval time.Duration, stop <-chan struct{}) {
+	if m.store == nil {
+		log.Println("Job workers need a shared job store, workers not started")
+		return
+	}
+
+	host, _ := os.Hostname()
+	status := WorkerStatus{
+		ID:        workerID,
+		Host:      host,
+		Workers:   count,
+		StartedAt: time.Now(),
+	}
+
+	log.Printf("Starting %d job workers as %s", count, workerID)
+	m.workers.Add(count + 1)
+	go m.heartbeat(status, stop)
+	for i := 1; i <= count; i++ {
+		go m.work(fmt.Sprintf("%s-%d", workerID, i), pollInterval, stop)
+	}
+}
+
+// Wait blocks until the workers have finished their current jobs and deregistered after stop
+func (m *JobManager) Wait() {
+	m.workers.Wait()
+}
+
+// Workers lists the registered worker instances
+func (m *JobManager) Workers(ctx context.Context) ([]WorkerStatus, error) {
+	if m.store == nil {
+		return []WorkerStatus{}, nil
+	}
+
+	workers, err := m.store.Workers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range workers {
+		workers[i].Alive = time.Since(workers[i].HeartbeatAt) < workerStaleAfter
+	}
+	return workers, nil
+}
+
+func (m *JobManager) heartbeat(status WorkerStatus, stop <-chan struct{}) {
+	defer m.workers.Done()
+
+	ticker := time.NewTicker(WorkerHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		status.Running = m.running.Load()
+		status.Processed = m.processed.Load()
+		status.Failed = m.failed.Load()
+		status.HeartbeatAt = time.Now()
+		if err := m.store.Heartbeat(context.Background(), status); err != nil {
+			log.Printf("Failed to send heartbeat of worker %s: %v", status.ID, err)
+		}
+
+		select {
+		case <-stop:
+			if err := m.store.Deregister(context.Background(), status.ID); err != nil {
+				log.Printf("Failed to deregister worker %s: %v", status.ID, err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *JobManager) work(workerID string, pollInterval time.Duration, stop <-chan struct{}) {
+	defer m.workers.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before waiting for the next poll
+		for m.runNext(workerID) {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and runs one job, reporting whether there was one
+func (m *JobManager) runNext(workerID string) bool {
+	ctx := context.Background()
+	queued, err := m.store.Claim(ctx, workerID)
+	if err != nil {
+		log.Printf("Worker %s failed to claim a job: %v", workerID, err)
+		return false
+	}
+	if queued == nil {
+		return false
+	}
+
+	m.running.Add(1)
+	var jobErr error
+	if handler, ok := m.handler(queued.Kind); ok {
+		progress := func(format string, args ...interface{}) {
+			if err := m.store.SetProgress(ctx, queued.ID, fmt.Sprintf(format, args...)); err != nil {
+				log.Printf("Failed to store progress of job %d: %v", queued.ID, err)
+			}
+		}
+		jobErr = handler(ctx, queued.Payload, progress)
+	} else {
+		jobErr = fmt.Errorf("%w: %s", ErrUnknownJobKind, queued.Kind)
+	}
+	m.running.Add(-1)
+	m.processed.Add(1)
+	if jobErr != nil {
+		m.failed.Add(1)
+	}
+
+	finished, err := m.store.Finish(ctx, queued.ID, jobErr)
+	if err != nil {
+		log.Printf("Failed to store the result of job %d: %v", queued.ID, err)
+		return true
+	}
+	if jobErr != nil {
+		log.Printf("Job %d (%s) failed on %s: %v", queued.ID, queued.Kind, workerID, jobErr)
+	}
+	m.notifyFinished(finished)
+	return true
+}
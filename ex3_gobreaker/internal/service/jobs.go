@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crawler/baseline/internal/notifier"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxJobHistory is how many finished jobs are kept in memory, and listed from a job store
+const maxJobHistory = 200
+
+// EventCrawlFinished is notified when a crawl job or coordinator run finishes
+const EventCrawlFinished = "crawl.finished"
+
+var (
+	// ErrJobNotFound is returned for an unknown job ID
+	ErrJobNotFound = errors.New("job not found")
+	// ErrUnknownJobKind is returned for a job kind without a registered handler
+	ErrUnknownJobKind = errors.New("unknown job kind")
+)
+
+// Job is a background crawl started through the API
+type Job struct {
+	ID       int64  `json:"id"`
+	Kind     string `json:"kind"`
+	Status   string `json:"status"`
+	Progress string `json:"progress,omitempty"`
+	Error    string `json:"error,omitempty"`
+	WorkerID string `json:"workerID,omitempty"`
+	// Attempts counts the times a worker claimed the job from a job store
+	Attempts   int        `json:"attempts,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// JobFunc does the work of a job, reporting progress messages as it goes
+type JobFunc func(ctx context.Context, progress func(format string, args ...interface{})) error
+
+// JobHandler does the work of a submitted job of one kind, decoding its JSON payload
+type JobHandler func(ctx context.Context, payload json.RawMessage, progress func(format string, args ...interface{})) error
+
+// QueuedJob is a job claimed from a JobStore with its payload
+type QueuedJob struct {
+	Job
+	Payload json.RawMessage
+}
+
+// JobStore is a job queue shared by every instance, so jobs submitted to an API
+// instance can be run by separately deployed workers
+type JobStore interface {
+	Enqueue(ctx context.Context, kind string, payload []byte) (Job, error)
+	// Claim takes the oldest pending job for workerID, or returns nil when there is none
+	Claim(ctx context.Context, workerID string) (*QueuedJob, error)
+	SetProgress(ctx context.Context, id int64, progress string) error
+	// Finish marks a job succeeded, or failed with jobErr, and returns its final state
+	Finish(ctx context.Context, id int64, jobErr error) (Job, error)
+	Get(ctx context.Context, id int64) (Job, error)
+	List(ctx context.Context, limit int) ([]Job, error)
+	// Running lists the jobs claimed by a worker and not finished yet
+	Running(ctx context.Context) ([]Job, error)
+	// Requeue puts a running job back in the queue, for any worker to claim it again
+	Requeue(ctx context.Context, id int64) error
+
+	// Heartbeat registers or refreshes a worker instance, Deregister removes it on shutdown
+	Heartbeat(ctx context.Context, worker WorkerStatus) error
+	Deregister(ctx context.Context, id string) error
+	Workers(ctx context.Context) ([]WorkerStatus, error)
+}
+
+// JobManager runs jobs in the background and keeps their status for polling. With a store,
+// submitted jobs go to the shared queue instead and are run by workers.
+type JobManager struct {
+	mutex    sync.Mutex
+	nextID   int64
+	jobs     map[int64]*Job
+	order    []int64
+	handlers map[string]JobHandler
+	store    JobStore
+	notifier notifier.Notifier
+	// blackouts refuses new jobs while crawling is paused
+	blackouts *Blackouts
+	// ctx is the parent of the jobs started in this process, cancelled when the server stops
+	ctx context.Context
+
+	// Worker side, see job_workers.go
+	workers   sync.WaitGroup
+	running   atomic.Int64
+	processed atomic.Int64
+	failed    atomic.Int64
+}
+
+func NewJobManager(notifier notifier.Notifier) *JobManager {
+	return &JobManager{
+		jobs:     make(map[int64]*Job),
+		handlers: make(map[string]JobHandler),
+		notifier: notifier,
+		ctx:      context.Background(),
+	}
+}
+
+// SetStop cancels the jobs started in this process when stop is closed, so their crawls end
+// with the server; call it before submitting. Jobs run by workers from a store finish first.
+func (m *JobManager) SetStop(stop <-chan struct{}) {
+	if stop == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	go func() {
+		<-stop
+		cancel()
+	}()
+}
+
+// SetStore makes Submit enqueue jobs in store; call it before submitting or starting workers
+func (m *JobManager) SetStore(store JobStore) {
+	m.store = store
+}
+
+// SetBlackouts makes Submit refuse jobs during the configured blackout windows
+func (m *JobManager) SetBlackouts(blackouts *Blackouts) {
+	m.blackouts = blackouts
+}
+
+// Admit returns a BlackoutError when no job would be accepted at now, so callers can
+// refuse a request before doing any work for it
+func (m *JobManager) Admit(now time.Time) error {
+	return m.blackouts.Check(now)
+}
+
+// HasStore reports whether jobs go through a shared store
+func (m *JobManager) HasStore() bool {
+	return m.store != nil
+}
+
+// Handle registers the handler that runs submitted jobs of the given kind
+func (m *JobManager) Handle(kind string, handler JobHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handlers[kind] = handler
+}
+
+func (m *JobManager) handler(kind string) (JobHandler, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	handler, ok := m.handlers[kind]
+	return handler, ok
+}
+
+// Submit queues a job of a registered kind with a payload that is passed to its handler
+// as JSON. Without a store the job starts right away in this process. During a blackout
+// window the job is refused with a BlackoutError.
+func (m *JobManager) Submit(ctx context.Context, kind string, payload interface{}) (Job, error) {
+	handler, ok := m.handler(kind)
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrUnknownJobKind, kind)
+	}
+	if err := m.Admit(time.Now()); err != nil {
+		return Job{}, err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("encoding %s job: %w", kind, err)
+	}
+
+	if m.store != nil {
+		return m.store.Enqueue(ctx, kind, data)
+	}
+	return m.Start(kind, func(ctx context.Context, progress func(string, ...interface{})) error {
+		return handler(ctx, data, progress)
+	}), nil
+}
+
+// Start runs fn in the background and returns the job tracking it
+func (m *JobManager) Start(kind string, fn JobFunc) Job {
+	m.mutex.Lock()
+	m.nextID++
+	job := &Job{
+		ID:        m.nextID,
+		Kind:      kind,
+		Status:    StatusRunning,
+		CreatedAt: time.Now(),
+	}
+	m.jobs[job.ID] = job
+	m.order = append(m.order, job.ID)
+	m.evict()
+	started := *job
+	m.mutex.Unlock()
+
+	go func() {
+		progress := func(format string, args ...interface{}) {
+			m.mutex.Lock()
+			job.Progress = fmt.Sprintf(format, args...)
+			m.mutex.Unlock()
+		}
+
+		err := fn(m.ctx, progress)
+
+		m.mutex.Lock()
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Status = StatusSucceeded
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			log.Printf("Job %d (%s) failed: %v", job.ID, kind, err)
+		}
+		finished := *job
+		m.mutex.Unlock()
+
+		m.notifyFinished(finished)
+	}()
+
+	return started
+}
+
+func (m *JobManager) notifyFinished(job Job) {
+	if m.notifier == nil {
+		return
+	}
+
+	message := job.Progress
+	if job.Error != "" {
+		message = job.Error
+	}
+
+	err := m.notifier.Notify(context.Background(), notifier.Notification{
+		Event:   EventCrawlFinished,
+		Title:   fmt.Sprintf("Job %d (%s) %s", job.ID, job.Kind, job.Status),
+		Message: message,
+		Fields: map[string]interface{}{
+			"job": job,
+		},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to notify completion of job %d: %v", job.ID, err)
+	}
+}
+
+// evict drops the oldest finished jobs beyond maxJobHistory; the caller must hold the mutex
+func (m *JobManager) evict() {
+	for len(m.order) > maxJobHistory {
+		oldest := m.jobs[m.order[0]]
+		if oldest.FinishedAt == nil {
+			return
+		}
+		delete(m.jobs, oldest.ID)
+		m.order = m.order[1:]
+	}
+}
+
+// Get returns a copy of the job with the given ID
+func (m *JobManager) Get(ctx context.Context, id int64) (Job, error) {
+	if m.store != nil {
+		return m.store.Get(ctx, id)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// List returns copies of the tracked jobs, most recent first
+func (m *JobManager) List(ctx context.Context) ([]Job, error) {
+	if m.store != nil {
+		return m.store.List(ctx, maxJobHistory)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	jobs := make([]Job, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *m.jobs[m.order[i]])
+	}
+	return jobs, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LeaderElector makes exactly one instance of a fleet run the scheduled work, using a
+// PostgreSQL session advisory lock: the instance whose connection holds the lock is the
+// leader, and the lock is released by the database as soon as that connection drops.
+type LeaderElector struct {
+	db    *sql.DB
+	name  string
+	key   int64
+	retry time.Duration
+
+	mutex  sync.Mutex
+	tasks  []func(stop <-chan struct{})
+	leader atomic.Bool
+}
+
+// NewLeaderElector elects a leader among the instances using the same name. Without a
+// database, for a single instance, it is always the leader.
+func NewLeaderElector(db *sql.DB, name string, retry time.Duration) *LeaderElector {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return &LeaderElector{
+		db:    db,
+		name:  name,
+		key:   int64(hash.Sum64()),
+		retry: retry,
+	}
+}
+
+// OnElected registers work that runs while this instance leads; stop is closed when it
+// loses the lead. Tasks must be registered before Run.
+func (e *LeaderElector) OnElected(task func(stop <-chan struct{})) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.tasks = append(e.tasks, task)
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for the lead every retry interval until stop is closed
+func (e *LeaderElector) Run(stop <-chan struct{}) {
+	if e.db == nil {
+		e.lead(stop, func() {
+			<-stop
+		})
+		return
+	}
+
+	for {
+		if conn := e.acquire(); conn != nil {
+			e.lead(stop, func() {
+				e.hold(conn, stop)
+			})
+			e.release(conn)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// lead starts the tasks and stops them once hold returns
+func (e *LeaderElector) lead(stop <-chan struct{}, hold func()) {
+	e.mutex.Lock()
+	tasks := append([]func(stop <-chan struct{}){}, e.tasks...)
+	e.mutex.Unlock()
+
+	log.Printf("Elected leader for %s, starting %d scheduled tasks", e.name, len(tasks))
+	e.leader.Store(true)
+	leading := make(chan struct{})
+	for _, task := range tasks {
+		go task(leading)
+	}
+
+	hold()
+
+	close(leading)
+	e.leader.Store(false)
+	select {
+	case <-stop:
+	default:
+		log.Printf("Lost leadership for %s, scheduled tasks stopped", e.name)
+	}
+}
+
+// acquire returns the connection holding the lock, or nil when another instance leads
+func (e *LeaderElector) acquire() *sql.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), e.retry)
+	defer cancel()
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		log.Printf("Leader election for %s failed to connect: %v", e.name, err)
+		return nil
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&locked); err != nil {
+		log.Printf("Leader election for %s failed: %v", e.name, err)
+		conn.Close()
+		return nil
+	}
+	if !locked {
+		conn.Close()
+		return nil
+	}
+	return conn
+}
+
+// hold keeps the lock's connection alive until stop is closed or the connection breaks
+func (e *LeaderElector) hold(conn *sql.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), e.retry)
+			err := conn.PingContext(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Leader connection for %s lost: %v", e.name, err)
+				return
+			}
+		}
+	}
+}
+
+func (e *LeaderElector) release(conn *sql.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.retry)
+	defer cancel()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", e.key); err != nil {
+		log.Printf("Failed to release leader lock for %s: %v", e.name, err)
+		// Discard the session instead of returning it to the pool, which releases the lock
+		conn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+	conn.Close()
+}
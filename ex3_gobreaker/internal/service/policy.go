@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultPolicyName is the policy applied to repositories that don't name one
+const DefaultPolicyName = "default"
+
+// CrawlPolicy selects what is crawled for a repository
+type CrawlPolicy struct {
+	Releases bool `mapstructure:"releases" json:"releases"`
+	Commits  bool `mapstructure:"commits" json:"commits"`
+	Tags     bool `mapstructure:"tags" json:"tags"`
+}
+
+// NewCrawlPolicies copies the named policies of the "policies" config section.
+// A "default" policy crawling releases and commits is added when none is configured.
+func NewCrawlPolicies(configured map[string]CrawlPolicy) map[string]CrawlPolicy {
+	policies := make(map[string]CrawlPolicy, len(configured)+1)
+	for name, policy := range configured {
+		policies[name] = policy
+	}
+
+	if _, ok := policies[DefaultPolicyName]; !ok {
+		policies[DefaultPolicyName] = CrawlPolicy{Releases: true, Commits: true}
+	}
+	return policies
+}
+
+// RepoPolicy overrides how one repository is crawled, for those with too many releases or
+// commits to crawl whole; zero values leave the crawl unbounded
+type RepoPolicy struct {
+	// MaxReleases keeps the newest releases of the repository only
+	MaxReleases int `mapstructure:"max_releases" json:"max_releases"`
+	// MaxCommitsPerRelease stops the commits of each release after that many
+	MaxCommitsPerRelease int `mapstructure:"max_commits_per_release" json:"max_commits_per_release"`
+	// SkipCommits leaves the commits of the repository out of every crawl
+	SkipCommits bool `mapstructure:"skip_commits" json:"skip_commits"`
+	// RequestsPerMinute paces the requests to GitHub about the repository
+	RequestsPerMinute float64 `mapstructure:"requests_per_minute" json:"requests_per_minute"`
+}
+
+// Validate checks that no limit is negative
+func (p RepoPolicy) Validate() error {
+	if p.MaxReleases < 0 || p.MaxCommitsPerRelease < 0 || p.RequestsPerMinute < 0 {
+		return fmt.Errorf("max_releases, max_commits_per_release and requests_per_minute must not be negative")
+	}
+	return nil
+}
+
+// ConfiguredRepoPolicy is an entry of the "repo_policies" config section. The section is a list
+// rather than a map keyed by repository, since viper splits keys such as "vercel/next.js" on dots.
+type ConfiguredRepoPolicy struct {
+	// Repo is "owner/name"
+	Repo       string `mapstructure:"repo" json:"repo"`
+	RepoPolicy `mapstructure:",squash"`
+}
+
+// RepoPolicies are the configured policies by repository
+type RepoPolicies map[string]RepoPolicy
+
+// NewRepoPolicies indexes the "repo_policies" config section by repository, ignoring case
+func NewRepoPolicies(configured []ConfiguredRepoPolicy) RepoPolicies {
+	policies := make(RepoPolicies, len(configured))
+	for _, policy := range configured {
+		policies[strings.ToLower(policy.Repo)] = policy.RepoPolicy
+	}
+	return policies
+}
+
+// For returns the configured policy of a repository, if any
+func (p RepoPolicies) For(repoOwner string, repoName string) (RepoPolicy, bool) {
+	policy, ok := p[strings.ToLower(repoOwner+"/"+repoName)]
+	return policy, ok
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Statuses of coordinator runs and of the stages within them
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	// StatusStale is a stage whose breaker rejected the call, its cached data served instead
+	StatusStale = "stale"
+)
+
+// maxRunHistory is how many coordinator runs are kept in memory
+const maxRunHistory = 50
+
+// StageRun records the outcome of one stage within a coordinator run
+type StageRun struct {
+	Stage      string     `json:"stage"`
+	Status     string     `json:"status"`
+	Changed    bool       `json:"changed"`
+	Items      int        `json:"items"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	DurationMs int64      `json:"durationMs"`
+}
+
+// CoordinatorRun records one CrawlAll cycle or manual stage run
+type CoordinatorRun struct {
+	ID         int64       `json:"id"`
+	Trigger    string      `json:"trigger"`
+	Status     string      `json:"status"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+	Stages     []*StageRun `json:"stages"`
+}
+
+// StageMetrics aggregates a stage's outcomes across runs
+type StageMetrics struct {
+	Stage           string     `json:"stage"`
+	Runs            int64      `json:"runs"`
+	Failures        int64      `json:"failures"`
+	Skips           int64      `json:"skips"`
+	Changes         int64      `json:"changes"`
+	ItemsTotal      int64      `json:"itemsTotal"`
+	LastDurationMs  int64      `json:"lastDurationMs"`
+	TotalDurationMs int64      `json:"totalDurationMs"`
+	LastRunAt       *time.Time `json:"lastRunAt,omitempty"`
+
+	// Skips split into CacheHits, when the cached data was still valid, and PausedSkips,
+	// during a stability pause; Pauses counts the stability pauses started
+	CacheHits   int64 `json:"cacheHits"`
+	PausedSkips int64 `json:"pausedSkips"`
+	Pauses      int64 `json:"pauses"`
+	// StaleServes counts the calls rejected by an open breaker that fell back to the cached data
+	StaleServes int64 `json:"staleServes"`
+}
+
+// CycleMetrics aggregates the periodic crawl cycles
+type CycleMetrics struct {
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	BlackoutSkips  int64      `json:"blackoutSkips"`
+	LastDurationMs int64      `json:"lastDurationMs"`
+	LastRunAt      *time.Time `json:"lastRunAt,omitempty"`
+}
+
+// runHistory keeps recent runs and per-stage metrics
+type runHistory struct {
+	mutex   sync.Mutex
+	nextID  int64
+	runs    []*CoordinatorRun
+	metrics map[string]*StageMetrics
+	cycles  CycleMetrics
+}
+
+func newRunHistory() *runHistory {
+	return &runHistory{
+		metrics: make(map[string]*StageMetrics),
+	}
+}
+
+// start records a new run with every given stage pending
+func (h *runHistory) start(trigger string, stages []string) *CoordinatorRun {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.nextID++
+	run := &CoordinatorRun{
+		ID:        h.nextID,
+		Trigger:   trigger,
+		Status:    StatusRunning,
+		StartedAt: time.Now(),
+		Stages:    make([]*StageRun, 0, len(stages)),
+	}
+	for _, stage := range stages {
+		run.Stages = append(run.Stages, &StageRun{Stage: stage, Status: StatusPending})
+	}
+
+	h.runs = append(h.runs, run)
+	if len(h.runs) > maxRunHistory {
+		h.runs = h.runs[len(h.runs)-maxRunHistory:]
+	}
+
+	return run
+}
+
+// stageStarted marks a stage of a run as running
+func (h *runHistory) stageStarted(run *CoordinatorRun, stage string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, stageRun := range run.Stages {
+		if stageRun.Stage == stage {
+			now := time.Now()
+			stageRun.Status = StatusRunning
+			stageRun.StartedAt = &now
+		}
+	}
+}
+
+// stageMetricsOf returns the metrics of a stage, creating them; the caller must hold the mutex
+func (h *runHistory) stageMetricsOf(stage string) *StageMetrics {
+	metrics, ok := h.metrics[stage]
+	if !ok {
+		metrics = &StageMetrics{Stage: stage}
+		h.metrics[stage] = metrics
+	}
+	return metrics
+}
+
+// stageSkipped records a skipped stage, because of a stability pause or else its cached data
+func (h *runHistory) stageSkipped(run *CoordinatorRun, stage string, paused bool) {
+	h.stageFinished(run, stage, StatusSkipped, false, 0, nil)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if paused {
+		h.stageMetricsOf(stage).PausedSkips++
+	} else {
+		h.stageMetricsOf(stage).CacheHits++
+	}
+}
+
+// stagePaused counts a stability pause of a stage
+func (h *runHistory) stagePaused(stage string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.stageMetricsOf(stage).Pauses++
+}
+
+// cycleSkipped counts a periodic cycle skipped during a blackout window
+func (h *runHistory) cycleSkipped() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.cycles.BlackoutSkips++
+}
+
+// stageFinished records a stage's outcome in the run and in the stage metrics
+func (h *runHistory) stageFinished(run *CoordinatorRun, stage string, status string, changed bool, items int, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	metrics := h.stageMetricsOf(stage)
+
+	for _, stageRun := range run.Stages {
+		if stageRun.Stage != stage {
+			continue
+		}
+
+		stageRun.Status = status
+		stageRun.Changed = changed
+		stageRun.Items = items
+		if err != nil {
+			stageRun.Error = err.Error()
+		}
+		if stageRun.StartedAt != nil {
+			stageRun.DurationMs = time.Since(*stageRun.StartedAt).Milliseconds()
+		}
+
+		switch status {
+		case StatusSkipped:
+			metrics.Skips++
+			continue
+		case StatusStale:
+			metrics.StaleServes++
+			continue
+		case StatusFailed:
+			metrics.Failures++
+		}
+
+		metrics.Runs++
+		metrics.ItemsTotal += int64(items)
+		metrics.LastDurationMs = stageRun.DurationMs
+		metrics.TotalDurationMs += stageRun.DurationMs
+		metrics.LastRunAt = stageRun.StartedAt
+		if changed {
+			metrics.Changes++
+		}
+	}
+}
+
+// finish marks a run as completed, failed if any stage failed
+func (h *runHistory) finish(run *CoordinatorRun) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = StatusSucceeded
+	for _, stageRun := range run.Stages {
+		if stageRun.Status == StatusFailed {
+			run.Status = StatusFailed
+		}
+	}
+
+	if run.Trigger == TriggerPeriodic {
+		h.cycles.Runs++
+		if run.Status == StatusFailed {
+			h.cycles.Failures++
+		}
+		h.cycles.LastDurationMs = now.Sub(run.StartedAt).Milliseconds()
+		startedAt := run.StartedAt
+		h.cycles.LastRunAt = &startedAt
+	}
+}
+
+// list returns copies of the recorded runs, most recent first
+func (h *runHistory) list() []CoordinatorRun {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	runs := make([]CoordinatorRun, 0, len(h.runs))
+	for i := len(h.runs) - 1; i >= 0; i-- {
+		runs = append(runs, copyRun(h.runs[i]))
+	}
+
+	return runs
+}
+
+// snapshot returns a copy of a single run
+func (h *runHistory) snapshot(run *CoordinatorRun) CoordinatorRun {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return copyRun(run)
+}
+
+// copyRun deep-copies a run; the caller must hold the mutex
+func copyRun(run *CoordinatorRun) CoordinatorRun {
+	copied := *run
+	copied.Stages = make([]*StageRun, len(run.Stages))
+	for i, stageRun := range run.Stages {
+		stageCopy := *stageRun
+		copied.Stages[i] = &stageCopy
+	}
+	return copied
+}
+
+// stageMetrics returns copies of the metrics of the given stages, in order
+func (h *runHistory) stageMetrics(stages []string) []StageMetrics {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	metrics := make([]StageMetrics, 0, len(stages))
+	for _, stage := range stages {
+		if m, ok := h.metrics[stage]; ok {
+			metrics = append(metrics, *m)
+		} else {
+			metrics = append(metrics, StageMetrics{Stage: stage})
+		}
+	}
+
+	return metrics
+}
+
+// cycleMetrics returns a copy of the periodic cycle metrics
+func (h *runHistory) cycleMetrics() CycleMetrics {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.cycles
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/scrape"
+	"crawler/baseline/internal/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSelfCheckRunning is returned when a self-check is started while another one runs
+var ErrSelfCheckRunning = errors.New("a scraper self-check is already running")
+
+// errNothingExtracted fails a check whose selectors matched nothing
+var errNothingExtracted = errors.New("selectors extracted nothing")
+
+// SelfCheckTarget is the repository and release the self-check scrapes. It should have a
+// description, releases with notes, a few tags and commits since the release.
+type SelfCheckTarget struct {
+	Repo string `mapstructure:"repo" json:"repo"`
+	Tag  string `mapstructure:"tag" json:"tag"`
+}
+
+// selfCheck is one scraper run by the self-check, returning how many values its selectors extracted
+type selfCheck struct {
+	name        string
+	description string
+	run         func(ctx context.Context, collector *colly.Collector) (int, error)
+}
+
+// ScrapeSelfCheck scrapes a known repository and release from GitHub with every scraper and
+// reports which of them still extract data. A change of GitHub's markup otherwise goes unnoticed:
+// the scrapers find no commits or empty release notes and the crawls save nothing without failing.
+type ScrapeSelfCheck struct {
+	// Token authenticates the GitHub API requests, as it does those of the crawls
+	Token string
+
+	log       *logrus.Logger
+	target    SelfCheckTarget
+	transport http.RoundTripper
+
+	running sync.Mutex
+	// last is the report of the latest run, guarded by mutex
+	mutex sync.Mutex
+	last  *model.SelfCheckReport
+}
+
+// NewScrapeSelfCheck checks the scrapers against target, sending the requests through transport,
+// usually the one of the shared collector so that they are paced, limited and recorded the same
+func NewScrapeSelfCheck(log *logrus.Logger, target SelfCheckTarget, transport http.RoundTripper) *ScrapeSelfCheck {
+	return &ScrapeSelfCheck{
+		log:       log,
+		target:    target,
+		transport: transport,
+	}
+}
+
+// Run runs every check, one after the other, and keeps the report for MetricValues. Checks not
+// started when ctx is cancelled fail with its error.
+func (s *ScrapeSelfCheck) Run(ctx context.Context) (*model.SelfCheckReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrSelfCheckRunning
+	}
+	defer s.running.Unlock()
+
+	startTime := time.Now()
+	owner, name, _ := strings.Cut(s.target.Repo, "/")
+	report := &model.SelfCheckReport{
+		Repo:      s.target.Repo,
+		Tag:       s.target.Tag,
+		CheckedAt: startTime,
+	}
+	for _, check := range s.checks(owner, name, s.target.Tag) {
+		result := model.SelfCheckResult{Name: check.name, Description: check.description}
+		checkStartTime := time.Now()
+		err := ctx.Err()
+		if err == nil {
+			// A collector does not visit a page twice, and several checks read the same pages
+			collector := colly.NewCollector(colly.Async(true))
+			collector.WithTransport(s.transport)
+			collector.Context = ctx
+			result.Values, err = check.run(ctx, collector)
+		}
+		if err == nil && result.Values == 0 {
+			err = errNothingExtracted
+		}
+		result.DurationMs = time.Since(checkStartTime).Milliseconds()
+		result.Passed = err == nil
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	report.Passed = report.Failed == 0
+	report.DurationMs = time.Since(startTime).Milliseconds()
+
+	s.mutex.Lock()
+	s.last = report
+	s.mutex.Unlock()
+	return report, nil
+}
+
+// checks are the scrapers run against a repository and one of its releases
+func (s *ScrapeSelfCheck) checks(owner string, name string, tag string) []selfCheck {
+	releaseURL := fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", owner, name, tag)
+	return []selfCheck{
+		{"ranking", "repositories listed on the first page of gitstar-ranking.com",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				repos, err := scrape.NewRepoScrape(s.log, c).CrawlAllRepos(10, 1)
+				return len(repos), err
+			}},
+		{"repo_metadata", "description, stars and language on the repository page",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				metadata, err := scrape.NewRepoScrape(s.log, c).CrawlRepoMetadata(owner, name)
+				if err != nil {
+					return 0, err
+				}
+				return extracted(map[string]bool{
+					"description": metadata.Description != "",
+					"stars":       metadata.Stars > 0,
+					"language":    metadata.Language != "",
+				})
+			}},
+		{"release_count", "release count on the repository page",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				return scrape.NewReleaseScrape(s.log, c).CountReleases(ctx, owner, name)
+			}},
+		{"release_tags", "release tags on the releases page",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				tags, err := utils.GetReleaseTags(c, owner, name, 1)
+				return len(tags), err
+			}},
+		{"release_notes", "notes on the release page",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				content, err := scrape.NewReleaseScrape(s.log, c).CrawlRelease(ctx, owner, name, tag)
+				if strings.TrimSpace(content) == "" {
+					return 0, err
+				}
+				return 1, err
+			}},
+		{"release_metadata", "title and publish date from the release API",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				releaseScrape := scrape.NewReleaseScrape(s.log, c)
+				releaseScrape.Token = s.Token
+				data, err := releaseScrape.CrawlReleaseMetadata(ctx, owner, name, tag)
+				if err != nil {
+					return 0, err
+				}
+				return extracted(map[string]bool{
+					"title":        data.Title != "",
+					"published at": data.PublishedAt != nil,
+				})
+			}},
+		{"commit_count", "commits since the release on the release page",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				var crawlErr error
+				c.OnError(func(r *colly.Response, err error) {
+					crawlErr = err
+				})
+				return utils.GetNumCommitRelease(c, releaseURL), crawlErr
+			}},
+		{"commits", "commits on the compare page of the release and the default branch",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				// Without the default branch the scraper tries master and main
+				branchScrape := scrape.NewBranchScrape(s.log, c.Clone())
+				branchScrape.Token = s.Token
+				branch, _ := branchScrape.DetectDefaultBranch(owner, name)
+				return scrape.NewCommitScrape(s.log, c).StreamCommits(ctx, owner, name, tag, branch,
+					scrape.RepoLimits{MaxCommits: 5}, func([]string) error { return nil })
+			}},
+		{"tags", "tags and their commits on the tags page",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				tags, err := scrape.NewTagScrape(s.log, c).CrawlTags(owner, name)
+				return len(tags), err
+			}},
+		{"default_branch", "default branch from the repository API",
+			func(ctx context.Context, c *colly.Collector) (int, error) {
+				branchScrape := scrape.NewBranchScrape(s.log, c)
+				branchScrape.Token = s.Token
+				branch, err := branchScrape.DetectDefaultBranch(owner, name)
+				if branch == "" {
+					return 0, err
+				}
+				return 1, err
+			}},
+	}
+}
+
+// extracted counts the fields found and fails naming the missing ones
+func extracted(fields map[string]bool) (int, error) {
+	found := 0
+	var missing []string
+	for field, ok := range fields {
+		if ok {
+			found++
+		} else {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return found, fmt.Errorf("no %s extracted", strings.Join(missing, ", "))
+	}
+	return found, nil
+}
+
+// Last returns the report of the latest run, nil before the first one
+func (s *ScrapeSelfCheck) Last() *model.SelfCheckReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.last
+}
+
+// MetricValues exposes the latest run to the alert rules: "selfcheck.failed" counts the failed
+// checks, "selfcheck.<check>.values" what each extracted and "selfcheck.age_s" how old the run is.
+// There are none before the first run.
+func (s *ScrapeSelfCheck) MetricValues() map[string]float64 {
+	report := s.Last()
+	if report == nil {
+		return nil
+	}
+
+	values := map[string]float64{
+		"selfcheck.failed":      float64(report.Failed),
+		"selfcheck.age_s":       time.Since(report.CheckedAt).Seconds(),
+		"selfcheck.duration_ms": float64(report.DurationMs),
+	}
+	for _, check := range report.Checks {
+		values["selfcheck."+check.Name+".values"] = float64(check.Values)
+	}
+	return values
+}
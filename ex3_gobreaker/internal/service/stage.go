@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"crawler/baseline/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// Triggers recorded on coordinator runs
+const (
+	TriggerPeriodic  = "periodic"
+	TriggerManual    = "manual"
+	TriggerScheduled = "scheduled"
+	// TriggerRetry is the retry of a stage serving stale data, once its breaker half-opens
+	TriggerRetry = "retry"
+)
+
+// CrawlStage is a unit of crawl work scheduled by the coordinator: a call to the crawler API,
+// or to the controllers in process. Every stage gets circuit breaker protection, change
+// detection, run history and metrics without extra wiring.
+type CrawlStage interface {
+	Name() string
+	Run(ctx context.Context, scope StageScope) StageResult
+}
+
+// StageScope describes why and how a stage is being run
+type StageScope struct {
+	// Trigger is TriggerPeriodic, TriggerManual, TriggerScheduled or TriggerRetry
+	Trigger string
+	// Force is set when the stage's condition and stability pause were bypassed
+	Force bool
+	// ChangedUpstream lists the dependencies that reported changes in this cycle
+	ChangedUpstream []string
+	// ChangedRepos lists the repositories whose upstream data changed, for targeted re-crawls.
+	// It is empty when the whole upstream dataset is new or its summary has no per-repository data.
+	ChangedRepos []int64
+}
+
+// StageResult is what a stage reports back to the coordinator
+type StageResult struct {
+	// Summary is compared with the previous run's summary to detect changes
+	Summary *StageSummary
+	// Items is the number of items the stage processed, when known
+	Items int
+	Err   error
+}
+
+// StageSummary is the fingerprint of a stage's data cached by the coordinator,
+// as returned by the crawler API's summary endpoints
+type StageSummary struct {
+	Count    int64                 `json:"count"`
+	Checksum string                `json:"checksum"`
+	Repos    map[int64]RepoSummary `json:"repos,omitempty"`
+}
+
+// RepoSummary is the per-repository part of a release or commit summary
+type RepoSummary struct {
+	Count     int64  `json:"count"`
+	LatestTag string `json:"latestTag,omitempty"`
+}
+
+// changedRepos lists the repositories whose count or latest tag differ between two summaries.
+// It returns nil when the summaries carry no per-repository data or there is no previous summary.
+func changedRepos(previous, current *StageSummary) []int64 {
+	if previous == nil || current == nil || (previous.Repos == nil && current.Repos == nil) {
+		return nil
+	}
+
+	var repos []int64
+	for id, repo := range current.Repos {
+		if previous.Repos[id] != repo {
+			repos = append(repos, id)
+		}
+	}
+	for id := range previous.Repos {
+		if _, ok := current.Repos[id]; !ok {
+			repos = append(repos, id)
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i] < repos[j] })
+	return repos
+}
+
+// httpStage triggers a crawl endpoint of the crawler API, then fingerprints the stage's data
+// with its change detector
+type httpStage struct {
+	name     string
+	url      string
+	detector ChangeDetector
+	client   *http.Client
+}
+
+func newHTTPStage(name string, url string, detector ChangeDetector, client *http.Client) *httpStage {
+	return &httpStage{
+		name:     name,
+		url:      url,
+		detector: detector,
+		client:   client,
+	}
+}
+
+func (s *httpStage) Name() string {
+	return s.name
+}
+
+func (s *httpStage) Run(ctx context.Context, scope StageScope) StageResult {
+	var output CrawlOutput
+	err := get(ctx, s.client, s.url, func(body io.Reader) (err error) {
+		output.Checksum, output.Items, err = HashResponse(body)
+		return err
+	})
+	if err != nil {
+		return StageResult{Err: fmt.Errorf("failed to crawl %s: %w", s.name, err)}
+	}
+
+	summary, err := s.detector.Summarize(ctx, output)
+	if err != nil {
+		return StageResult{Err: fmt.Errorf("failed to summarize %s: %w", s.name, err)}
+	}
+	return StageResult{Summary: summary, Items: output.Items}
+}
+
+// newDetector returns the change detector of a stage calling the crawler API
+func newDetector(method string, summaryURL string, client *http.Client) ChangeDetector {
+	switch method {
+	case ChangeDetectionSummary:
+		return SummaryDetector{Fetch: func(ctx context.Context) (*StageSummary, error) {
+			var summary struct {
+				Data StageSummary `json:"data"`
+			}
+			err := get(ctx, client, summaryURL, func(body io.Reader) error {
+				return json.NewDecoder(body).Decode(&summary)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch summary: %w", err)
+			}
+			return &summary.Data, nil
+		}}
+	case ChangeDetectionCount:
+		return CountDetector{}
+	default:
+		return ResponseHashDetector{}
+	}
+}
+
+// get calls an endpoint of the crawler API and hands its response body to read
+func get(ctx context.Context, client *http.Client, url string, read func(body io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &utils.HTTPStatusError{Status: resp.StatusCode}
+	}
+
+	return read(resp.Body)
+}
+
+// InProcessAPI serves crawler API paths to the coordinator as function calls, for a coordinator
+// running in the same process as the API. Keys are paths relative to the API base URL.
+type InProcessAPI struct {
+	// Crawls run a crawl endpoint's work and return the number of items processed
+	Crawls map[string]func(ctx context.Context) (int, error)
+	// Summaries return what a summary endpoint would respond with
+	Summaries map[string]func(ctx context.Context) (*StageSummary, error)
+}
+
+// inProcessStage runs a stage through InProcessAPI functions. There is no crawl response to
+// hash, so its detector compares a summary function or the item count.
+type inProcessStage struct {
+	name     string
+	crawl    func(ctx context.Context) (int, error)
+	detector ChangeDetector
+}
+
+func (s *inProcessStage) Name() string {
+	return s.name
+}
+
+func (s *inProcessStage) Run(ctx context.Context, scope StageScope) StageResult {
+	items, err := s.crawl(ctx)
+	if err != nil {
+		return StageResult{Err: fmt.Errorf("failed to crawl %s: %w", s.name, err)}
+	}
+
+	summary, err := s.detector.Summarize(ctx, CrawlOutput{Items: items})
+	if err != nil {
+		return StageResult{Err: fmt.Errorf("failed to summarize %s: %w", s.name, err)}
+	}
+	return StageResult{Summary: summary, Items: items}
+}
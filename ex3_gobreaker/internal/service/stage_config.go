@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+)
+
+// Stage names of the default crawl pipeline
+const (
+	StageRepos    = "repos"
+	StageReleases = "releases"
+	StageCommits  = "commits"
+)
+
+// Stage conditions controlling when a stage runs during a cycle
+const (
+	// ConditionOnce runs the stage until its first successful fetch
+	ConditionOnce = "once"
+	// ConditionUpstreamChanged runs the stage when a dependency changed or nothing is cached yet
+	ConditionUpstreamChanged = "upstream_changed"
+	// ConditionAlways runs the stage on every cycle unless it is paused
+	ConditionAlways = "always"
+)
+
+// Change detection methods of a stage, set by "change_detection"
+const (
+	// ChangeDetectionSummary compares the counts and checksums of the stage's summary endpoint,
+	// which the crawler API computes in the database
+	ChangeDetectionSummary = "summary"
+	// ChangeDetectionHash compares the SHA-256 of the crawl response
+	ChangeDetectionHash = "hash"
+	// ChangeDetectionCount compares the number of items crawled only
+	ChangeDetectionCount = "count"
+)
+
+// StageConfig declares one crawl stage of the coordinator's dependency graph.
+// Stages with a Path call that crawler API endpoint; stages registered in code leave it empty.
+// SummaryPath is the endpoint whose summary is cached for change detection; ChangeDetection
+// picks what is compared between crawls, defaulting to the summary when SummaryPath is set and
+// to the hash of the crawl response otherwise.
+type StageConfig struct {
+	Name        string   `mapstructure:"name" json:"name"`
+	Path        string   `mapstructure:"path" json:"path"`
+	SummaryPath string   `mapstructure:"summary_path" json:"summary_path"`
+	DependsOn   []string `mapstructure:"depends_on" json:"depends_on"`
+	Condition   string   `mapstructure:"condition" json:"condition"`
+
+	// ChangeDetection is ChangeDetectionSummary, ChangeDetectionHash or ChangeDetectionCount
+	ChangeDetection string `mapstructure:"change_detection" json:"change_detection"`
+
+	// Concurrency caps how many calls of this stage may be in flight at once
+	// (periodic cycles and manual runs combined); defaults to 1
+	Concurrency int `mapstructure:"concurrency" json:"concurrency"`
+
+	// StabilityThreshold is how many consecutive unchanged responses pause the stage;
+	// 0 uses the coordinator's default
+	StabilityThreshold int `mapstructure:"stability_threshold" json:"stability_threshold"`
+}
+
+// DefaultStageConfigs returns the repos -> releases -> commits pipeline
+func DefaultStageConfigs() []StageConfig {
+	return []StageConfig{
+		{Name: StageRepos, Path: "/repos/crawl", SummaryPath: "/repos/summary", ChangeDetection: ChangeDetectionSummary, Condition: ConditionOnce, Concurrency: 1},
+		{Name: StageReleases, Path: "/releases/crawl", SummaryPath: "/releases/summary", ChangeDetection: ChangeDetectionSummary, DependsOn: []string{StageRepos}, Condition: ConditionUpstreamChanged, Concurrency: 1},
+		{Name: StageCommits, Path: "/commits/crawl", SummaryPath: "/commits/summary", ChangeDetection: ChangeDetectionSummary, DependsOn: []string{StageReleases}, Condition: ConditionUpstreamChanged, Concurrency: 1},
+	}
+}
+
+// changeDetection returns the stage's change detection method, defaulted from its summary path
+func (c StageConfig) changeDetection() string {
+	switch {
+	case c.ChangeDetection != "":
+		return c.ChangeDetection
+	case c.SummaryPath != "":
+		return ChangeDetectionSummary
+	default:
+		return ChangeDetectionHash
+	}
+}
+
+// NewStageConfigs validates the stage graph of the "coordinator.stages" section,
+// falling back to the default pipeline when the section is missing
+func NewStageConfigs(configured []StageConfig) ([]StageConfig, error) {
+	if configured == nil {
+		return DefaultStageConfigs(), nil
+	}
+
+	stages := append([]StageConfig(nil), configured...)
+	for i := range stages {
+		if stages[i].Condition == "" {
+			stages[i].Condition = ConditionUpstreamChanged
+		}
+		if stages[i].Concurrency <= 0 {
+			stages[i].Concurrency = 1
+		}
+		if stages[i].StabilityThreshold < 0 {
+			return nil, fmt.Errorf("stage %s has a negative stability threshold", stages[i].Name)
+		}
+		stages[i].ChangeDetection = stages[i].changeDetection()
+		switch stages[i].ChangeDetection {
+		case ChangeDetectionSummary:
+			if stages[i].SummaryPath == "" {
+				return nil, fmt.Errorf("stage %s detects changes by summary but has no summary_path", stages[i].Name)
+			}
+		case ChangeDetectionHash, ChangeDetectionCount:
+		default:
+			return nil, fmt.Errorf("stage %s has unknown change detection %q", stages[i].Name, stages[i].ChangeDetection)
+		}
+	}
+
+	if _, err := sortStages(stages); err != nil {
+		return nil, err
+	}
+
+	return stages, nil
+}
+
+// sortStages validates the stage graph and returns stage names in dependency order.
+// Stages without ordering constraints keep their configured order.
+func sortStages(stages []StageConfig) ([]string, error) {
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("coordinator has no stages configured")
+	}
+
+	byName := make(map[string]StageConfig, len(stages))
+	for _, stage := range stages {
+		if stage.Name == "" {
+			return nil, fmt.Errorf("coordinator stage without a name")
+		}
+		if _, exists := byName[stage.Name]; exists {
+			return nil, fmt.Errorf("duplicate stage %s", stage.Name)
+		}
+		switch stage.Condition {
+		case ConditionOnce, ConditionUpstreamChanged, ConditionAlways:
+		default:
+			return nil, fmt.Errorf("stage %s has unknown condition %q", stage.Name, stage.Condition)
+		}
+		byName[stage.Name] = stage
+	}
+
+	for _, stage := range stages {
+		for _, dep := range stage.DependsOn {
+			if _, exists := byName[dep]; !exists {
+				return nil, fmt.Errorf("stage %s depends on unknown stage %s", stage.Name, dep)
+			}
+		}
+	}
+
+	order := make([]string, 0, len(stages))
+	done := make(map[string]bool, len(stages))
+	for len(order) < len(stages) {
+		progressed := false
+		for _, stage := range stages {
+			if done[stage.Name] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range stage.DependsOn {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				done[stage.Name] = true
+				order = append(order, stage.Name)
+				progressed = true
+			}
+		}
+
+		if !progressed {
+			return nil, fmt.Errorf("coordinator stages contain a dependency cycle")
+		}
+	}
+
+	return order, nil
+}
@@ -0,0 +1,204 @@
+// Package spool buffers crawl results on local disk while the database is unreachable, so
+// a long crawl keeps its results through a short outage and stores them once it is back.
+package spool
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrFull is returned when a batch would grow the spool past its size limit
+var ErrFull = errors.New("spool is full")
+
+// quarantineDir is the subdirectory of the spool the batches the database rejects are moved to
+const quarantineDir = "quarantine"
+
+// Unavailable reports whether err means the database could not be reached, as opposed to a
+// query it rejected or that ran out of time. Only those failures are worth buffering and
+// replaying.
+func Unavailable(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch {
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET):
+		return true
+	}
+	// A connection that could not be opened, or broke other than by timing out; a slow query
+	// on a working connection is not an outage
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || !opErr.Timeout()) {
+		return true
+	}
+	// pgx reports a failed connection attempt as "failed to connect to ..."
+	return strings.Contains(err.Error(), "failed to connect")
+}
+
+// Spool keeps batches as one JSON file each in a directory, named so they list in the
+// order they were added. A nil Spool buffers nothing.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mutex sync.Mutex
+	size  int64
+	last  int64
+}
+
+// New opens the spool in dir, creating it if needed. Batches left by a previous run are kept
+// and count towards maxBytes.
+func New(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating spool directory: %w", err)
+	}
+	s := &Spool{dir: dir, maxBytes: maxBytes}
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			s.size += info.Size()
+		}
+	}
+	return s, nil
+}
+
+// Hold buffers payload as a batch of kind when err means the database is unreachable, and
+// reports whether it did; the caller then treats the batch as handled
+func (s *Spool) Hold(err error, kind string, payload interface{}) bool {
+	if s == nil || !Unavailable(err) {
+		return false
+	}
+	return s.Append(kind, payload) == nil
+}
+
+// Append writes a batch of kind to the spool
+func (s *Spool) Append(kind string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding %s batch: %w", kind, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.size+int64(len(data)) > s.maxBytes {
+		return ErrFull
+	}
+
+	// Nanosecond names keep the order across restarts, last keeps them unique
+	seq := time.Now().UnixNano()
+	if seq <= s.last {
+		seq = s.last + 1
+	}
+	s.last = seq
+
+	name := filepath.Join(s.dir, fmt.Sprintf("%020d-%s.json", seq, kind))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s batch: %w", kind, err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing %s batch: %w", kind, err)
+	}
+	s.size += int64(len(data))
+	return nil
+}
+
+// Replay hands the buffered batches to store in the order they were added, removing each one
+// stored, and returns the number of batches stored. It stops at the first batch store fails
+// to store because the database is unreachable, which stays for the next replay. A batch the
+// database rejects is moved to the quarantine subdirectory for inspection, so it does not
+// block the ones after it, and its error is returned with the others once all are replayed.
+func (s *Spool) Replay(store func(kind string, payload json.RawMessage) error) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	var rejected []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return replayed, err
+		}
+		kind := strings.TrimSuffix(filepath.Base(file), ".json")
+		if _, after, ok := strings.Cut(kind, "-"); ok {
+			kind = after
+		}
+		if err := store(kind, data); err != nil {
+			err = fmt.Errorf("replaying %s: %w", filepath.Base(file), err)
+			if Unavailable(err) {
+				return replayed, errors.Join(append(rejected, err)...)
+			}
+			if qErr := s.quarantine(file); qErr != nil {
+				return replayed, errors.Join(append(rejected, err, qErr)...)
+			}
+			rejected = append(rejected, err)
+		} else {
+			if err := os.Remove(file); err != nil {
+				return replayed, errors.Join(append(rejected, err)...)
+			}
+			replayed++
+		}
+
+		s.mutex.Lock()
+		s.size -= int64(len(data))
+		s.mutex.Unlock()
+	}
+	return replayed, errors.Join(rejected...)
+}
+
+// quarantine moves a batch out of the spool, where it no longer counts towards its size limit
+func (s *Spool) quarantine(file string) error {
+	dir := filepath.Join(s.dir, quarantineDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating quarantine directory: %w", err)
+	}
+	if err := os.Rename(file, filepath.Join(dir, filepath.Base(file))); err != nil {
+		return fmt.Errorf("quarantining %s: %w", filepath.Base(file), err)
+	}
+	return nil
+}
+
+// Pending returns the number of buffered batches and their total size in bytes
+func (s *Spool) Pending() (int, int64) {
+	if s == nil {
+		return 0, 0
+	}
+	files, _ := s.files()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(files), s.size
+}
+
+// files lists the batches, oldest first
+func (s *Spool) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -3,8 +3,12 @@ package usecase
 import (
 	"context"
 	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/kafka"
 	"crawler/baseline/internal/model"
 	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/spool"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -14,6 +18,19 @@ type CommitUsecase struct {
 	DB               *gorm.DB
 	Log              *logrus.Logger
 	CommitRepository *repository.CommitRepository
+	Spool            *spool.Spool
+	// IDs generates the keys of new commits; nil leaves them to the database
+	IDs idgen.Generator
+	// RowByRow stores the commits of a batch create one per transaction, when features.batching is off
+	RowByRow bool
+	// Keywords matches the messages of new commits against the watchlist keywords
+	Keywords *KeywordUsecase
+	// Outbox receives the commits.stored events in the transaction of each batch create; nil sends none
+	Outbox *OutboxUsecase
+	// Kafka publishes the created commits; nil publishes nothing
+	Kafka *kafka.Producer
+	// Items sends the batch creates to the persist processes in scrape mode; nil stores them
+	Items *ItemBus
 }
 
 func NewCommitUsecase(db *gorm.DB, log *logrus.Logger,
@@ -30,13 +47,9 @@ func (c *CommitUsecase) Create(ctx context.Context, request *model.CreateCommitR
 	defer tx.Rollback()
 
 	// Create commit entity
-	commit := &entity.Commit{
-		Hash:      request.Hash,
-		Message:   request.Message,
-		ReleaseID: request.ReleaseID,
-	}
+	commit := newCommitEntity(request, c.IDs)
 
-	if err := c.CommitRepository.Create(tx, commit); err != nil {
+	if err := c.CommitRepository.Create(ctx, tx, commit); err != nil {
 		c.Log.WithError(err).Error("error creating commit")
 		return nil, err
 	}
@@ -45,13 +58,9 @@ func (c *CommitUsecase) Create(ctx context.Context, request *model.CreateCommitR
 		c.Log.WithError(err).Error("error committing transaction")
 		return nil, err
 	}
+	c.Kafka.PublishCommits([]entity.Commit{*commit})
 
-	return &model.CommitResponse{
-		ID:        commit.ID,
-		Hash:      commit.Hash,
-		Message:   commit.Message,
-		ReleaseID: commit.ReleaseID,
-	}, nil
+	return CommitToResponse(commit), nil
 }
 
 // GetCommitsByReleaseID retrieves all commits for a specific release
@@ -65,59 +74,154 @@ func (c *CommitUsecase) GetCommitsByReleaseID(ctx context.Context, releaseID int
 
 	// Convert entities to response models
 	commits := make([]*model.CommitResponse, len(commitEntities))
-	for i, entity := range commitEntities {
-		commits[i] = &model.CommitResponse{
-			ID:        entity.ID,
-			Hash:      entity.Hash,
-			Message:   entity.Message,
-			ReleaseID: entity.ReleaseID,
-		}
+	for i := range commitEntities {
+		commits[i] = CommitToResponse(&commitEntities[i])
 	}
 
 	return commits, nil
 }
 
-// BatchCreate inserts multiple commits in a single transaction
+// ListByRepo returns a page, counted from 1, of the commits of a repository's releases matching
+// filter, newest release first. A commit listed by several releases appears once for each.
+func (c *CommitUsecase) ListByRepo(ctx context.Context, repoID int64, filter repository.RepoCommitFilter,
+	page int, size int) ([]*model.RepoCommitResponse, *model.PageMetadata, error) {
+	db := c.DB.WithContext(ctx)
+	if err := db.Select("id").First(&entity.Repository{}, repoID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var rows []repository.RepoCommit
+	total, err := c.CommitRepository.FindByRepo(ctx, db, &rows, repoID, filter, (page-1)*size, size)
+	if err != nil {
+		c.Log.WithError(err).Error("error fetching commits for repository")
+		return nil, nil, err
+	}
+
+	commits := make([]*model.RepoCommitResponse, len(rows))
+	for i, row := range rows {
+		commits[i] = &model.RepoCommitResponse{
+			CommitResponse: model.CommitResponse{
+				ID:           row.ID,
+				Hash:         row.Hash,
+				Message:      row.Message,
+				FilesChanged: row.FilesChanged,
+				Additions:    row.Additions,
+				Deletions:    row.Deletions,
+				ReleaseID:    row.ReleaseID,
+			},
+			Tag:         row.TagName,
+			PublishedAt: row.PublishedAt,
+		}
+	}
+
+	paging := &model.PageMetadata{
+		Page:      page,
+		Size:      size,
+		TotalItem: total,
+		TotalPage: (total + int64(size) - 1) / int64(size),
+	}
+	return commits, paging, nil
+}
+
+// BatchCreate inserts multiple commits in a single transaction. Commits already stored for
+// their release are returned as stored and marked Existing.
 func (c *CommitUsecase) BatchCreate(ctx context.Context, requests []*model.CreateCommitRequest) ([]*model.CommitResponse, error) {
-	if len(requests) == 0 {
+	if c.Items != nil {
+		return c.publish(ctx, requests)
+	}
+	responses, err := c.batchCreate(ctx, requests)
+	if c.Spool.Hold(err, spoolCommits, requests) {
+		c.Log.WithField("count", len(requests)).Warn("Database unreachable, commits buffered in the spool")
 		return []*model.CommitResponse{}, nil
 	}
+	return responses, err
+}
 
-	tx := c.DB.WithContext(ctx).Begin()
-	defer tx.Rollback()
+func (c *CommitUsecase) batchCreate(ctx context.Context, requests []*model.CreateCommitRequest) ([]*model.CommitResponse, error) {
+	if len(requests) == 0 {
+		return []*model.CommitResponse{}, nil
+	}
 
 	// Create slice of entities for batch insertion
 	commits := make([]entity.Commit, len(requests))
 	for i, req := range requests {
-		commits[i] = entity.Commit{
-			Hash:      req.Hash,
-			Message:   req.Message,
-			ReleaseID: req.ReleaseID,
-		}
+		commits[i] = *newCommitEntity(req, c.IDs)
 	}
 
-	// Use CreateInBatches to handle large datasets efficiently
-	// The second parameter (100) is the batch size
-	if err := tx.CreateInBatches(commits, 100).Error; err != nil {
+	existing, err := createMissing(ctx, c.DB, c.RowByRow, commits, commitKey,
+		c.CommitRepository.FindStored, c.CommitRepository.CreateMissing,
+		outboxEvent(c.Outbox, storedCommitsEvent))
+	if err != nil {
 		c.Log.WithError(err).Error("error batch creating commits")
 		return nil, err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		c.Log.WithError(err).Error("error committing batch transaction")
+	// Create responses with the stored IDs
+	responses := make([]*model.CommitResponse, len(commits))
+	created := make([]*model.CommitResponse, 0, len(commits))
+	createdCommits := make([]entity.Commit, 0, len(commits))
+	for i := range commits {
+		responses[i] = CommitToResponse(&commits[i])
+		responses[i].Existing = existing[i]
+		if !existing[i] {
+			created = append(created, responses[i])
+			createdCommits = append(createdCommits, commits[i])
+		}
+	}
+	c.Keywords.MatchCommits(created)
+	c.Kafka.PublishCommits(createdCommits)
+
+	return responses, nil
+}
+
+// publish sends the commits not stored yet to a persist process, which stores them, and returns
+// them all as batchCreate does
+func (c *CommitUsecase) publish(ctx context.Context, requests []*model.CreateCommitRequest) ([]*model.CommitResponse, error) {
+	commits := make([]entity.Commit, len(requests))
+	for i, req := range requests {
+		commits[i] = *newCommitEntity(req, c.IDs)
+	}
+	existing, err := publishMissing(ctx, c.DB, c.Items, spoolCommits, requests, commits, commitKey,
+		func(request *model.CreateCommitRequest, commit *entity.Commit) { request.ID = commit.ID },
+		c.CommitRepository.FindStored)
+	if err != nil {
 		return nil, err
 	}
 
-	// Create responses with IDs assigned by database
 	responses := make([]*model.CommitResponse, len(commits))
-	for i, commit := range commits {
-		responses[i] = &model.CommitResponse{
-			ID:        commit.ID,
-			Hash:      commit.Hash,
-			Message:   commit.Message,
-			ReleaseID: commit.ReleaseID,
-		}
+	for i := range commits {
+		responses[i] = CommitToResponse(&commits[i])
+		responses[i].Existing = existing[i]
 	}
-
 	return responses, nil
 }
+
+// commitKey identifies a commit like the unique index on its release and hash
+func commitKey(commit *entity.Commit) string {
+	return fmt.Sprintf("%d/%s", commit.ReleaseID, commit.Hash)
+}
+
+func newCommitEntity(request *model.CreateCommitRequest, ids idgen.Generator) *entity.Commit {
+	return &entity.Commit{
+		ID:           nextID(request.ID, ids),
+		Hash:         request.Hash,
+		Message:      request.Message,
+		FilesChanged: request.FilesChanged,
+		Additions:    request.Additions,
+		Deletions:    request.Deletions,
+		ReleaseID:    request.ReleaseID,
+	}
+}
+
+// CommitToResponse converts a commit entity to a response model; stats are omitted when not scraped
+func CommitToResponse(commit *entity.Commit) *model.CommitResponse {
+	return &model.CommitResponse{
+		ID:           commit.ID,
+		Hash:         commit.Hash,
+		Message:      commit.Message,
+		FilesChanged: commit.FilesChanged,
+		Additions:    commit.Additions,
+		Deletions:    commit.Deletions,
+		ReleaseID:    commit.ReleaseID,
+	}
+}
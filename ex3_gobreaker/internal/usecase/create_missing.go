@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const (
+	createBatchSize = 100
+	// maxCreateAttempts bounds the retries of createMissing after losing a race with another writer
+	maxCreateAttempts = 3
+)
+
+// errConcurrentInsert fails an attempt of createMissing when another writer stored some of the
+// same rows between the lookup and the insert
+var errConcurrentInsert = errors.New("rows were inserted concurrently")
+
+// createMissing stores, in one transaction, the entities whose key is not stored yet and replaces
+// the others with their stored rows, so re-running a crawl does not duplicate rows. existing[i]
+// reports whether entities[i] was already stored or repeats an earlier entity of the batch.
+// findStored looks up the stored rows of some entities and insert skips rows conflicting on a
+// unique index, like the FindStored and CreateMissing repository methods. With rowByRow, every
+// entity is looked up and stored in a transaction of its own instead, as when batching is off.
+// onInserted, unless nil, is called with the inserted entities in the transaction that inserts
+// them, such as to write outbox events; its error rolls the insert back.
+func createMissing[T any](ctx context.Context, db *gorm.DB, rowByRow bool, entities []T, key func(*T) string,
+	findStored func(ctx context.Context, db *gorm.DB, entities []T, stored *[]T) error,
+	insert func(ctx context.Context, db *gorm.DB, entities []T, batchSize int) (int64, error),
+	onInserted func(ctx context.Context, tx *gorm.DB, inserted []T) error) ([]bool, error) {
+	if !rowByRow {
+		return createMissingBatch(ctx, db, entities, key, findStored, insert, onInserted)
+	}
+
+	existing := make([]bool, len(entities))
+	for i := range entities {
+		stored, err := createMissingBatch(ctx, db, entities[i:i+1], key, findStored, insert, onInserted)
+		if err != nil {
+			return nil, err
+		}
+		existing[i] = stored[0]
+	}
+	return existing, nil
+}
+
+// createMissingBatch is createMissing storing all the entities in one transaction
+func createMissingBatch[T any](ctx context.Context, db *gorm.DB, entities []T, key func(*T) string,
+	findStored func(ctx context.Context, db *gorm.DB, entities []T, stored *[]T) error,
+	insert func(ctx context.Context, db *gorm.DB, entities []T, batchSize int) (int64, error),
+	onInserted func(ctx context.Context, tx *gorm.DB, inserted []T) error) ([]bool, error) {
+	var existing []bool
+	var err error
+	for attempt := 0; attempt < maxCreateAttempts; attempt++ {
+		existing, err = tryCreateMissing(ctx, db, entities, key, findStored, insert, onInserted)
+		if !errors.Is(err, errConcurrentInsert) {
+			break
+		}
+	}
+	return existing, err
+}
+
+func tryCreateMissing[T any](ctx context.Context, db *gorm.DB, entities []T, key func(*T) string,
+	findStored func(ctx context.Context, db *gorm.DB, entities []T, stored *[]T) error,
+	insert func(ctx context.Context, db *gorm.DB, entities []T, batchSize int) (int64, error),
+	onInserted func(ctx context.Context, tx *gorm.DB, inserted []T) error) ([]bool, error) {
+	tx := db.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// The repositories look the rows up in chunks, so a large batch does not make one huge query
+	var found []T
+	if err := findStored(ctx, tx, entities, &found); err != nil {
+		return nil, err
+	}
+	stored := make(map[string]T, len(found))
+	for i := range found {
+		stored[key(&found[i])] = found[i]
+	}
+
+	existing := make([]bool, len(entities))
+	missing := make([]T, 0)
+	// position is the index in missing of each key to insert
+	position := make(map[string]int)
+	for i := range entities {
+		k := key(&entities[i])
+		if _, ok := stored[k]; ok {
+			existing[i] = true
+		} else if _, ok := position[k]; ok {
+			existing[i] = true
+		} else {
+			position[k] = len(missing)
+			missing = append(missing, entities[i])
+		}
+	}
+
+	if len(missing) > 0 {
+		inserted, err := insert(ctx, tx, missing, createBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		// Rows skipped on conflict would also shift the IDs given to the inserted ones
+		if inserted != int64(len(missing)) {
+			return nil, errConcurrentInsert
+		}
+		if onInserted != nil {
+			if err := onInserted(ctx, tx, missing); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	for i := range entities {
+		k := key(&entities[i])
+		if row, ok := stored[k]; ok {
+			entities[i] = row
+		} else {
+			entities[i] = missing[position[k]]
+		}
+	}
+	return existing, nil
+}
+
+// chainInserted combines onInserted hooks of createMissing, called in order until one fails;
+// nil hooks are left out, and nil is returned when all are
+func chainInserted[T any](hooks ...func(ctx context.Context, tx *gorm.DB, inserted []T) error) func(
+	ctx context.Context, tx *gorm.DB, inserted []T) error {
+	chained := make([]func(ctx context.Context, tx *gorm.DB, inserted []T) error, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook != nil {
+			chained = append(chained, hook)
+		}
+	}
+	if len(chained) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, tx *gorm.DB, inserted []T) error {
+		for _, hook := range chained {
+			if err := hook(ctx, tx, inserted); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/repository"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// EventReleaseDigest is the notification event carrying a release digest
+const EventReleaseDigest = "release.digest"
+
+type DigestUsecase struct {
+	DB                  *gorm.DB
+	Log                 *logrus.Logger
+	WatchlistRepository *repository.WatchlistRepository
+	Notifier            notifier.Notifier
+}
+
+func NewDigestUsecase(db *gorm.DB, log *logrus.Logger,
+	watchlistRepo *repository.WatchlistRepository, notifier notifier.Notifier) *DigestUsecase {
+	return &DigestUsecase{
+		DB:                  db,
+		Log:                 log,
+		WatchlistRepository: watchlistRepo,
+		Notifier:            notifier,
+	}
+}
+
+// Build collects the releases of a watchlist's repositories first stored since the given time
+func (u *DigestUsecase) Build(ctx context.Context, watchlistID int64, since time.Time) (*model.ReleaseDigest, error) {
+	watchlist := &entity.Watchlist{}
+	if err := u.WatchlistRepository.FindWithRepos(ctx, u.DB, watchlist, watchlistID); err != nil {
+		return nil, err
+	}
+
+	digest := &model.ReleaseDigest{
+		WatchlistID: watchlist.ID,
+		Watchlist:   watchlist.Name,
+		Since:       since,
+		Until:       time.Now(),
+		Releases:    make([]model.DigestRelease, 0),
+	}
+	if len(watchlist.Repositories) == 0 {
+		return digest, nil
+	}
+
+	repos := make(map[int64]entity.Repository, len(watchlist.Repositories))
+	repoIDs := make([]int64, 0, len(watchlist.Repositories))
+	for _, repo := range watchlist.Repositories {
+		repos[repo.ID] = repo
+		repoIDs = append(repoIDs, repo.ID)
+	}
+
+	var releases []entity.Release
+	if err := u.DB.WithContext(ctx).Scopes(repository.NotTombstoned).
+		Where("repoid IN ? AND createdat >= ? AND createdat < ?", repoIDs, since, digest.Until).
+		Order("repoid, createdat").
+		Find(&releases).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching digest releases")
+		return nil, err
+	}
+
+	for _, release := range releases {
+		repo := repos[release.RepoID]
+		fullName := repo.UserName + "/" + repo.RepoName
+		digest.Releases = append(digest.Releases, model.DigestRelease{
+			Repo:        fullName,
+			TagName:     release.TagName,
+			Title:       release.Title,
+			Author:      release.Author,
+			Prerelease:  release.Prerelease,
+			PublishedAt: release.PublishedAt,
+			DetectedAt:  release.CreatedAt,
+			URL:         "https://github.com/" + fullName + "/releases/tag/" + release.TagName,
+		})
+	}
+
+	digest.Markdown = RenderDigestMarkdown(digest)
+	return digest, nil
+}
+
+// RenderDigestMarkdown renders a digest as a markdown summary grouped by repository
+func RenderDigestMarkdown(digest *model.ReleaseDigest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s: %d new releases\n\n", digest.Watchlist, len(digest.Releases))
+	fmt.Fprintf(&sb, "_%s – %s_\n", digest.Since.Format(time.RFC822), digest.Until.Format(time.RFC822))
+
+	currentRepo := ""
+	for _, release := range digest.Releases {
+		if release.Repo != currentRepo {
+			currentRepo = release.Repo
+			fmt.Fprintf(&sb, "\n## %s\n\n", currentRepo)
+		}
+
+		title := release.TagName
+		if release.Title != "" && release.Title != release.TagName {
+			title += " – " + release.Title
+		}
+		if release.Prerelease {
+			title += " (pre-release)"
+		}
+		fmt.Fprintf(&sb, "- [%s](%s)\n", title, release.URL)
+	}
+
+	return sb.String()
+}
+
+// Send builds the digest of a watchlist and delivers it through the notifiers.
+// Empty digests are not sent.
+func (u *DigestUsecase) Send(ctx context.Context, watchlistID int64, since time.Time) (*model.ReleaseDigest, error) {
+	digest, err := u.Build(ctx, watchlistID, since)
+	if err != nil {
+		return nil, err
+	}
+	if len(digest.Releases) == 0 {
+		return digest, nil
+	}
+
+	err = u.Notifier.Notify(ctx, notifier.Notification{
+		Event:   EventReleaseDigest,
+		Title:   fmt.Sprintf("%s: %d new releases", digest.Watchlist, len(digest.Releases)),
+		Message: digest.Markdown,
+		Fields: map[string]interface{}{
+			"digest": digest,
+		},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		u.Log.WithError(err).WithField("watchlist_id", watchlistID).Error("error sending release digest")
+		return digest, err
+	}
+	return digest, nil
+}
+
+// SendAll sends the digest of every watchlist covering the last period
+func (u *DigestUsecase) SendAll(ctx context.Context, period time.Duration) {
+	var watchlists []entity.Watchlist
+	if err := u.DB.WithContext(ctx).Find(&watchlists).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching watchlists")
+		return
+	}
+
+	since := time.Now().Add(-period)
+	for _, watchlist := range watchlists {
+		digest, err := u.Send(ctx, watchlist.ID, since)
+		if err != nil {
+			continue
+		}
+		u.Log.WithFields(logrus.Fields{
+			"watchlist": watchlist.Name,
+			"releases":  len(digest.Releases),
+		}).Info("Release digest processed")
+	}
+}
+
+// StartDigests sends the digests of all watchlists once per period until stopChan is closed.
+// The digests go out on the wall-clock boundaries of the period, at midnight UTC for 24h, so a
+// restart neither shifts them nor sends one early.
+func (u *DigestUsecase) StartDigests(period time.Duration, stopChan <-chan struct{}) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(period).Add(period).Sub(now))
+		select {
+		case <-timer.C:
+			u.SendAll(context.Background(), period)
+		case <-stopChan:
+			timer.Stop()
+			u.Log.Info("Stopping release digests")
+			return
+		}
+	}
+}
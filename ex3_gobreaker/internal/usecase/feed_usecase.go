@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type FeedUsecase struct {
+	DB                  *gorm.DB
+	Log                 *logrus.Logger
+	RepoRepository      *repository.RepoRepository
+	WatchlistRepository *repository.WatchlistRepository
+}
+
+func NewFeedUsecase(db *gorm.DB, log *logrus.Logger,
+	repoRepo *repository.RepoRepository, watchlistRepo *repository.WatchlistRepository) *FeedUsecase {
+	return &FeedUsecase{
+		DB:                  db,
+		Log:                 log,
+		RepoRepository:      repoRepo,
+		WatchlistRepository: watchlistRepo,
+	}
+}
+
+// RepoFeed returns the latest releases of a repository, leaving out tombstoned ones unless includeDeleted
+func (u *FeedUsecase) RepoFeed(ctx context.Context, repoID int64, limit int, includeDeleted bool) (*model.ReleaseFeed, error) {
+	repo := &entity.Repository{}
+	if err := u.RepoRepository.FindById(ctx, u.DB, repo, repoID); err != nil {
+		return nil, err
+	}
+
+	fullName := repo.UserName + "/" + repo.RepoName
+	feed := &model.ReleaseFeed{
+		ID:    fmt.Sprintf("repo-%d", repo.ID),
+		Title: fullName + " releases",
+		Link:  "https://github.com/" + fullName + "/releases",
+	}
+	return u.fillFeed(ctx, feed, []entity.Repository{*repo}, limit, includeDeleted)
+}
+
+// WatchlistFeed returns the latest releases across the repositories of a watchlist, leaving out
+// tombstoned ones unless includeDeleted
+func (u *FeedUsecase) WatchlistFeed(ctx context.Context, watchlistID int64, limit int, includeDeleted bool) (*model.ReleaseFeed, error) {
+	watchlist := &entity.Watchlist{}
+	if err := u.WatchlistRepository.FindWithRepos(ctx, u.DB, watchlist, watchlistID); err != nil {
+		return nil, err
+	}
+
+	feed := &model.ReleaseFeed{
+		ID:    fmt.Sprintf("watchlist-%d", watchlist.ID),
+		Title: watchlist.Name + " releases",
+	}
+	return u.fillFeed(ctx, feed, watchlist.Repositories, limit, includeDeleted)
+}
+
+func (u *FeedUsecase) fillFeed(ctx context.Context, feed *model.ReleaseFeed,
+	repos []entity.Repository, limit int, includeDeleted bool) (*model.ReleaseFeed, error) {
+	feed.Items = make([]model.FeedItem, 0)
+	if len(repos) == 0 {
+		feed.Updated = time.Now()
+		return feed, nil
+	}
+
+	names := make(map[int64]string, len(repos))
+	repoIDs := make([]int64, 0, len(repos))
+	for _, repo := range repos {
+		names[repo.ID] = repo.UserName + "/" + repo.RepoName
+		repoIDs = append(repoIDs, repo.ID)
+	}
+
+	tx := u.DB.WithContext(ctx)
+	if !includeDeleted {
+		tx = tx.Scopes(repository.NotTombstoned)
+	}
+
+	var releases []entity.Release
+	if err := tx.
+		Where("repoid IN ?", repoIDs).
+		Order("COALESCE(publishedat, createdat) DESC, id DESC").
+		Limit(limit).
+		Find(&releases).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching feed releases")
+		return nil, err
+	}
+
+	for _, release := range releases {
+		fullName := names[release.RepoID]
+		published := release.CreatedAt
+		if release.PublishedAt != nil {
+			published = *release.PublishedAt
+		}
+
+		title := fullName + " " + release.TagName
+		if release.Title != "" && release.Title != release.TagName {
+			title += ": " + release.Title
+		}
+
+		feed.Items = append(feed.Items, model.FeedItem{
+			ID:         fmt.Sprintf("release-%d", release.ID),
+			Repo:       fullName,
+			TagName:    release.TagName,
+			Title:      title,
+			Link:       "https://github.com/" + fullName + "/releases/tag/" + release.TagName,
+			Author:     release.Author,
+			Content:    release.Content,
+			Prerelease: release.Prerelease,
+			Published:  published,
+		})
+		if published.After(feed.Updated) {
+			feed.Updated = published
+		}
+	}
+
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Now()
+	}
+	return feed, nil
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/service"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// JobUsecase is the service.JobStore kept in the crawl_jobs table, shared by every instance
+type JobUsecase struct {
+	DB               *gorm.DB
+	Log              *logrus.Logger
+	JobRepository    *repository.JobRepository
+	WorkerRepository *repository.WorkerRepository
+}
+
+func NewJobUsecase(db *gorm.DB, log *logrus.Logger,
+	jobRepo *repository.JobRepository, workerRepo *repository.WorkerRepository) *JobUsecase {
+	return &JobUsecase{
+		DB:               db,
+		Log:              log,
+		JobRepository:    jobRepo,
+		WorkerRepository: workerRepo,
+	}
+}
+
+func (u *JobUsecase) Enqueue(ctx context.Context, kind string, payload []byte) (service.Job, error) {
+	job := &entity.CrawlJob{
+		Kind:      kind,
+		Payload:   string(payload),
+		Status:    service.StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := u.JobRepository.Create(ctx, u.DB, job); err != nil {
+		u.Log.WithError(err).WithField("kind", kind).Error("error enqueueing job")
+		return service.Job{}, err
+	}
+	return JobToResponse(job), nil
+}
+
+func (u *JobUsecase) Claim(ctx context.Context, workerID string) (*service.QueuedJob, error) {
+	job := new(entity.CrawlJob)
+	err := u.JobRepository.Claim(ctx, u.DB, job, service.StatusPending, service.StatusRunning, workerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &service.QueuedJob{Job: JobToResponse(job), Payload: []byte(job.Payload)}, nil
+}
+
+func (u *JobUsecase) SetProgress(ctx context.Context, id int64, progress string) error {
+	return u.DB.WithContext(ctx).Model(&entity.CrawlJob{}).Where("id = ?", id).
+		Update("progress", progress).Error
+}
+
+func (u *JobUsecase) Finish(ctx context.Context, id int64, jobErr error) (service.Job, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     service.StatusSucceeded,
+		"finishedat": now,
+	}
+	if jobErr != nil {
+		updates["status"] = service.StatusFailed
+		updates["error"] = jobErr.Error()
+	}
+
+	db := u.DB.WithContext(ctx)
+	if err := db.Model(&entity.CrawlJob{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return service.Job{}, err
+	}
+	return u.Get(ctx, id)
+}
+
+func (u *JobUsecase) Get(ctx context.Context, id int64) (service.Job, error) {
+	job := new(entity.CrawlJob)
+	err := u.JobRepository.FindById(ctx, u.DB, job, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return service.Job{}, service.ErrJobNotFound
+	}
+	if err != nil {
+		u.Log.WithError(err).WithField("job", id).Error("error fetching job")
+		return service.Job{}, err
+	}
+	return JobToResponse(job), nil
+}
+
+func (u *JobUsecase) List(ctx context.Context, limit int) ([]service.Job, error) {
+	var jobs []entity.CrawlJob
+	if err := u.JobRepository.FindRecent(ctx, u.DB, &jobs, limit); err != nil {
+		u.Log.WithError(err).Error("error listing jobs")
+		return nil, err
+	}
+
+	responses := make([]service.Job, len(jobs))
+	for i := range jobs {
+		responses[i] = JobToResponse(&jobs[i])
+	}
+	return responses, nil
+}
+
+func (u *JobUsecase) Running(ctx context.Context) ([]service.Job, error) {
+	var jobs []entity.CrawlJob
+	if err := u.JobRepository.FindByStatus(ctx, u.DB, &jobs, service.StatusRunning); err != nil {
+		u.Log.WithError(err).Error("error listing running jobs")
+		return nil, err
+	}
+
+	responses := make([]service.Job, len(jobs))
+	for i := range jobs {
+		responses[i] = JobToResponse(&jobs[i])
+	}
+	return responses, nil
+}
+
+func (u *JobUsecase) Requeue(ctx context.Context, id int64) error {
+	return u.JobRepository.Requeue(ctx, u.DB, id, service.StatusRunning, service.StatusPending)
+}
+
+// Heartbeat inserts the worker's registration or replaces it with the latest counters
+func (u *JobUsecase) Heartbeat(ctx context.Context, worker service.WorkerStatus) error {
+	return u.WorkerRepository.Update(ctx, u.DB, &entity.CrawlWorker{
+		ID:          worker.ID,
+		Host:        worker.Host,
+		Workers:     worker.Workers,
+		Running:     worker.Running,
+		Processed:   worker.Processed,
+		Failed:      worker.Failed,
+		StartedAt:   worker.StartedAt,
+		HeartbeatAt: worker.HeartbeatAt,
+	})
+}
+
+func (u *JobUsecase) Deregister(ctx context.Context, id string) error {
+	return u.WorkerRepository.Delete(ctx, u.DB, &entity.CrawlWorker{ID: id})
+}
+
+func (u *JobUsecase) Workers(ctx context.Context) ([]service.WorkerStatus, error) {
+	var workers []entity.CrawlWorker
+	if err := u.DB.WithContext(ctx).Order("id").Find(&workers).Error; err != nil {
+		u.Log.WithError(err).Error("error listing workers")
+		return nil, err
+	}
+
+	responses := make([]service.WorkerStatus, len(workers))
+	for i, worker := range workers {
+		responses[i] = service.WorkerStatus{
+			ID:          worker.ID,
+			Host:        worker.Host,
+			Workers:     worker.Workers,
+			Running:     worker.Running,
+			Processed:   worker.Processed,
+			Failed:      worker.Failed,
+			StartedAt:   worker.StartedAt,
+			HeartbeatAt: worker.HeartbeatAt,
+		}
+	}
+	return responses, nil
+}
+
+func JobToResponse(job *entity.CrawlJob) service.Job {
+	return service.Job{
+		ID:         job.ID,
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Progress:   job.Progress,
+		Error:      job.Error,
+		WorkerID:   job.WorkerID,
+		Attempts:   job.Attempts,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
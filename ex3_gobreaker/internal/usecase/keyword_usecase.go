@@ -0,0 +1,418 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/repository"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// EventKeywordMatched is notified when new release notes or commit messages of a watchlist's
+// repositories contain one of its keywords
+const EventKeywordMatched = "keyword.matched"
+
+const (
+	minKeywordLength = 2
+	maxKeywordLength = 100
+	// keywordSnippetContext is how many bytes of text surround a match in its snippet
+	keywordSnippetContext = 60
+	// maxKeywordSnippets bounds the snippets of one release or commit
+	maxKeywordSnippets = 3
+	// maxKeywordMatches bounds the matches listed in one notification
+	maxKeywordMatches = 20
+	// keywordBufferSize is how many stored batches wait to be matched; batches stored while it
+	// is full are not matched
+	keywordBufferSize = 1000
+	// keywordQuietPeriod ends a crawl run when no batch has been stored for that long
+	keywordQuietPeriod = 30 * time.Second
+	// keywordMaxWait bounds how long a match waits for its run to end
+	keywordMaxWait = 10 * time.Minute
+	// keywordStopTimeout bounds sending the pending matches when stopping
+	keywordStopTimeout = 10 * time.Second
+)
+
+// KeywordUsecase manages the keyword subscriptions of watchlists and matches them against newly
+// stored releases and commits. A nil KeywordUsecase matches nothing.
+type KeywordUsecase struct {
+	DB                *gorm.DB
+	Log               *logrus.Logger
+	KeywordRepository *repository.KeywordRepository
+	Notifier          notifier.Notifier
+
+	// batches are the stored releases and commits waiting for StartMatching
+	batches chan []keywordItem
+}
+
+func NewKeywordUsecase(db *gorm.DB, log *logrus.Logger,
+	keywordRepo *repository.KeywordRepository, notifier notifier.Notifier) *KeywordUsecase {
+	return &KeywordUsecase{
+		DB:                db,
+		Log:               log,
+		KeywordRepository: keywordRepo,
+		Notifier:          notifier,
+		batches:           make(chan []keywordItem, keywordBufferSize),
+	}
+}
+
+// Add subscribes a watchlist to a keyword, matched ignoring case and spacing. An invalid keyword
+// fails with an error wrapping apperrors.ErrInvalid, one already subscribed with apperrors.ErrConflict.
+func (u *KeywordUsecase) Add(ctx context.Context, watchlistID int64, keyword string) (*model.KeywordResponse, error) {
+	keyword = strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+	if length := utf8.RuneCountInString(keyword); length < minKeywordLength || length > maxKeywordLength {
+		return nil, fmt.Errorf("%w: keyword must have %d to %d characters", apperrors.ErrInvalid,
+			minKeywordLength, maxKeywordLength)
+	}
+
+	db := u.DB.WithContext(ctx)
+	if err := db.Select("id").First(&entity.Watchlist{}, watchlistID).Error; err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := db.Model(&entity.WatchlistKeyword{}).
+		Where("watchlistid = ? AND keyword = ?", watchlistID, keyword).Count(&existing).Error; err != nil {
+		u.Log.WithError(err).Error("error looking up keyword")
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: keyword %q", apperrors.ErrConflict, keyword)
+	}
+
+	subscription := &entity.WatchlistKeyword{WatchlistID: watchlistID, Keyword: keyword}
+	if err := u.KeywordRepository.Create(ctx, db, subscription); err != nil {
+		u.Log.WithError(err).Error("error creating keyword")
+		return nil, err
+	}
+	return KeywordToResponse(subscription), nil
+}
+
+// List returns the keywords of a watchlist
+func (u *KeywordUsecase) List(ctx context.Context, watchlistID int64) ([]*model.KeywordResponse, error) {
+	db := u.DB.WithContext(ctx)
+	if err := db.Select("id").First(&entity.Watchlist{}, watchlistID).Error; err != nil {
+		return nil, err
+	}
+
+	var keywords []entity.WatchlistKeyword
+	if err := u.KeywordRepository.FindByWatchlist(ctx, db, &keywords, watchlistID); err != nil {
+		u.Log.WithError(err).Error("error fetching keywords")
+		return nil, err
+	}
+	responses := make([]*model.KeywordResponse, len(keywords))
+	for i := range keywords {
+		responses[i] = KeywordToResponse(&keywords[i])
+	}
+	return responses, nil
+}
+
+// Delete unsubscribes a watchlist from a keyword
+func (u *KeywordUsecase) Delete(ctx context.Context, watchlistID int64, keywordID int64) error {
+	result := u.DB.WithContext(ctx).Where("watchlistid = ?", watchlistID).Delete(&entity.WatchlistKeyword{ID: keywordID})
+	if result.Error != nil {
+		u.Log.WithError(result.Error).Error("error deleting keyword")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// keywordItem is the text of a new release or commit matched against the keywords
+type keywordItem struct {
+	repoID int64
+	// releaseID is the release of a commit, whose repository is looked up when matching
+	releaseID int64
+	kind      string
+	ref       string
+	text      string
+}
+
+// MatchReleases queues the notes of newly stored releases to be looked for the keywords
+func (u *KeywordUsecase) MatchReleases(releases []*model.ReleaseResponse) {
+	if u == nil || len(releases) == 0 {
+		return
+	}
+
+	items := make([]keywordItem, len(releases))
+	for i, release := range releases {
+		items[i] = keywordItem{
+			repoID: release.RepoID,
+			kind:   "release",
+			ref:    release.TagName,
+			text:   release.Title + "\n" + release.Content,
+		}
+	}
+	u.queue(items)
+}
+
+// MatchCommits queues the messages of newly stored commits to be looked for the keywords
+func (u *KeywordUsecase) MatchCommits(commits []*model.CommitResponse) {
+	if u == nil || len(commits) == 0 {
+		return
+	}
+
+	items := make([]keywordItem, len(commits))
+	for i, commit := range commits {
+		items[i] = keywordItem{
+			releaseID: commit.ReleaseID,
+			kind:      "commit",
+			ref:       commit.Hash,
+			text:      commit.Message,
+		}
+	}
+	u.queue(items)
+}
+
+// queue hands a batch to StartMatching without blocking the crawl, dropping it when the buffer
+// is full
+func (u *KeywordUsecase) queue(items []keywordItem) {
+	select {
+	case u.batches <- items:
+	default:
+		u.Log.WithField("count", len(items)).Warn("Keyword matching buffer full, dropping new releases or commits")
+	}
+}
+
+// StartMatching looks for the keywords in the queued batches until stopChan is closed. The
+// matches of a crawl run, the batches stored until none has come for keywordQuietPeriod, are
+// sent as one keyword.matched notification per keyword subscription; a long run is notified at
+// least every keywordMaxWait. The matches pending when stopped are sent before returning.
+func (u *KeywordUsecase) StartMatching(stopChan <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	run := &keywordRun{byKeyword: make(map[int64]*keywordMatches)}
+	var quiet, deadline <-chan time.Time
+	notify := func(ctx context.Context) {
+		for _, keywordID := range run.order {
+			u.notifyMatched(ctx, run.byKeyword[keywordID])
+		}
+		run = &keywordRun{byKeyword: make(map[int64]*keywordMatches)}
+		quiet, deadline = nil, nil
+	}
+	for {
+		select {
+		case items := <-u.batches:
+			u.match(ctx, items, run)
+			if len(run.order) == 0 {
+				continue
+			}
+			quiet = time.After(keywordQuietPeriod)
+			if deadline == nil {
+				deadline = time.After(keywordMaxWait)
+			}
+		case <-quiet:
+			notify(ctx)
+		case <-deadline:
+			notify(ctx)
+		case <-stopChan:
+			if len(run.order) > 0 {
+				stopCtx, stopCancel := context.WithTimeout(context.Background(), keywordStopTimeout)
+				notify(stopCtx)
+				stopCancel()
+			}
+			u.Log.Info("Stopping keyword matching")
+			return
+		}
+	}
+}
+
+// keywordMatches are the matches of one keyword subscription
+type keywordMatches struct {
+	subscription repository.KeywordSubscription
+	matches      []model.KeywordMatch
+	count        int
+}
+
+// keywordRun collects the matches of the batches of a crawl run, by keyword subscription
+type keywordRun struct {
+	byKeyword map[int64]*keywordMatches
+	order     []int64
+}
+
+// match adds to run the matches of the keyword subscriptions in items
+func (u *KeywordUsecase) match(ctx context.Context, items []keywordItem, run *keywordRun) {
+	releaseIDs := make([]int64, 0)
+	seenReleases := make(map[int64]bool)
+	for _, item := range items {
+		if item.repoID == 0 && item.releaseID != 0 && !seenReleases[item.releaseID] {
+			seenReleases[item.releaseID] = true
+			releaseIDs = append(releaseIDs, item.releaseID)
+		}
+	}
+	if len(releaseIDs) > 0 {
+		var releases []entity.Release
+		if err := u.DB.WithContext(ctx).Select("id", "repoid").Where("id IN ?", releaseIDs).Find(&releases).Error; err != nil {
+			u.Log.WithError(err).Error("error fetching releases of new commits")
+			return
+		}
+		releaseRepos := make(map[int64]int64, len(releases))
+		for _, release := range releases {
+			releaseRepos[release.ID] = release.RepoID
+		}
+		for i := range items {
+			if items[i].repoID == 0 {
+				items[i].repoID = releaseRepos[items[i].releaseID]
+			}
+		}
+	}
+
+	repoIDs := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, item := range items {
+		if item.repoID != 0 && !seen[item.repoID] {
+			seen[item.repoID] = true
+			repoIDs = append(repoIDs, item.repoID)
+		}
+	}
+	if len(repoIDs) == 0 {
+		return
+	}
+
+	var subscriptions []repository.KeywordSubscription
+	if err := u.KeywordRepository.FindSubscriptions(ctx, u.DB, &subscriptions, repoIDs); err != nil {
+		u.Log.WithError(err).Error("error fetching keyword subscriptions")
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	var repos []entity.Repository
+	if err := u.DB.WithContext(ctx).Select("id", "username", "reponame").Where("id IN ?", repoIDs).Find(&repos).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching repositories of keyword matches")
+		return
+	}
+	names := make(map[int64]string, len(repos))
+	for _, repo := range repos {
+		names[repo.ID] = repo.UserName + "/" + repo.RepoName
+	}
+
+	// Each subscription row applies a keyword to one repository
+	patterns := make(map[string]*regexp.Regexp)
+	for _, subscription := range subscriptions {
+		pattern, ok := patterns[subscription.Keyword]
+		if !ok {
+			pattern = keywordPattern(subscription.Keyword)
+			patterns[subscription.Keyword] = pattern
+		}
+
+		for _, item := range items {
+			if item.repoID != subscription.RepoID {
+				continue
+			}
+			snippets := keywordSnippets(pattern, item.text, maxKeywordSnippets)
+			if len(snippets) == 0 {
+				continue
+			}
+
+			found, ok := run.byKeyword[subscription.KeywordID]
+			if !ok {
+				found = &keywordMatches{subscription: subscription}
+				run.byKeyword[subscription.KeywordID] = found
+				run.order = append(run.order, subscription.KeywordID)
+			}
+			found.count++
+			if len(found.matches) < maxKeywordMatches {
+				found.matches = append(found.matches, keywordMatch(names[item.repoID], item, snippets))
+			}
+		}
+	}
+}
+
+func (u *KeywordUsecase) notifyMatched(ctx context.Context, found *keywordMatches) {
+	if u.Notifier == nil {
+		return
+	}
+
+	lines := make([]string, 0, len(found.matches))
+	for _, match := range found.matches {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", match.Repo, match.Ref, match.Snippets[0]))
+	}
+	err := u.Notifier.Notify(ctx, notifier.Notification{
+		Event: EventKeywordMatched,
+		Title: fmt.Sprintf("%q found in %d new releases or commits of %s", found.subscription.Keyword,
+			found.count, found.subscription.Watchlist),
+		Message: strings.Join(lines, "\n"),
+		Fields: map[string]interface{}{
+			"watchlistID": found.subscription.WatchlistID,
+			"watchlist":   found.subscription.Watchlist,
+			"keyword":     found.subscription.Keyword,
+			"count":       found.count,
+			"matches":     found.matches,
+		},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		u.Log.WithError(err).WithField("keyword", found.subscription.Keyword).Error("error notifying keyword match")
+	}
+}
+
+// keywordPattern matches a keyword ignoring case, with any whitespace between its words
+func keywordPattern(keyword string) *regexp.Regexp {
+	words := strings.Fields(keyword)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(words, `\s+`))
+}
+
+// keywordSnippets returns up to limit extracts of text around the matches of pattern, on one line
+func keywordSnippets(pattern *regexp.Regexp, text string, limit int) []string {
+	locations := pattern.FindAllStringIndex(text, limit)
+	snippets := make([]string, 0, len(locations))
+	for _, location := range locations {
+		start := max(0, location[0]-keywordSnippetContext)
+		end := min(len(text), location[1]+keywordSnippetContext)
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+
+		snippet := strings.Join(strings.Fields(text[start:end]), " ")
+		if start > 0 {
+			snippet = "…" + snippet
+		}
+		if end < len(text) {
+			snippet += "…"
+		}
+		snippets = append(snippets, snippet)
+	}
+	return snippets
+}
+
+func keywordMatch(repo string, item keywordItem, snippets []string) model.KeywordMatch {
+	url := "https://github.com/" + repo + "/releases/tag/" + item.ref
+	if item.kind == "commit" {
+		url = "https://github.com/" + repo + "/commit/" + item.ref
+	}
+	return model.KeywordMatch{
+		Repo:     repo,
+		Kind:     item.kind,
+		Ref:      item.ref,
+		URL:      url,
+		Snippets: snippets,
+	}
+}
+
+func KeywordToResponse(keyword *entity.WatchlistKeyword) *model.KeywordResponse {
+	return &model.KeywordResponse{
+		ID:          keyword.ID,
+		WatchlistID: keyword.WatchlistID,
+		Keyword:     keyword.Keyword,
+		CreatedAt:   keyword.CreatedAt,
+	}
+}
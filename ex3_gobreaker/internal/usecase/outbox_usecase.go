@@ -0,0 +1,292 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/notifier"
+	"crawler/baseline/internal/repository"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Events written to the outbox in the transaction of the rows they are about
+const (
+	// EventReleasesStored is published for each batch of releases that stored new releases
+	EventReleasesStored = "releases.stored"
+	// EventCommitsStored is published for each batch of commits that stored new commits
+	EventCommitsStored = "commits.stored"
+)
+
+// maxOutboxBackoff caps the delay between delivery attempts of an event
+const maxOutboxBackoff = time.Hour
+
+// OutboxSettings is the "outbox" config section; a zero value keeps the default of its field
+type OutboxSettings struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// PollInterval is how often the dispatcher looks for due events, 1s by default
+	PollInterval time.Duration `mapstructure:"poll_interval" json:"poll_interval"`
+	// BatchSize is how many events the dispatcher leases at once, 100 by default
+	BatchSize int `mapstructure:"batch_size" json:"batch_size"`
+	// MaxAttempts is how many failed deliveries an event is given up after, 10 by default
+	MaxAttempts int `mapstructure:"max_attempts" json:"max_attempts"`
+	// Backoff is the delay after the first failed delivery, doubled after each, 1s by default
+	Backoff time.Duration `mapstructure:"backoff" json:"backoff"`
+	// Lease is how long a dispatcher holds the events it delivers, 1m by default; events of a
+	// dispatcher that died are delivered by another once it ends
+	Lease time.Duration `mapstructure:"lease" json:"lease"`
+	// Retention is how long delivered events are kept, 24h by default
+	Retention time.Duration `mapstructure:"retention" json:"retention"`
+}
+
+// Validate rejects negative sizes and durations
+func (s OutboxSettings) Validate() error {
+	if s.BatchSize < 0 || s.MaxAttempts < 0 {
+		return errors.New("sizes must not be negative")
+	}
+	if s.PollInterval < 0 || s.Backoff < 0 || s.Lease < 0 || s.Retention < 0 {
+		return errors.New("durations must not be negative")
+	}
+	return nil
+}
+
+// WithDefaults fills in the fields left zero
+func (s OutboxSettings) WithDefaults() OutboxSettings {
+	if s.PollInterval == 0 {
+		s.PollInterval = time.Second
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = 100
+	}
+	if s.MaxAttempts == 0 {
+		s.MaxAttempts = 10
+	}
+	if s.Backoff == 0 {
+		s.Backoff = time.Second
+	}
+	if s.Lease == 0 {
+		s.Lease = time.Minute
+	}
+	if s.Retention == 0 {
+		s.Retention = 24 * time.Hour
+	}
+	return s
+}
+
+// OutboxStats counts the deliveries of this instance's dispatcher since it started
+type OutboxStats struct {
+	Delivered int64 `json:"delivered"`
+	Failed    int64 `json:"failed"`
+	// Abandoned counts the events given up after their last attempt
+	Abandoned int64 `json:"abandoned"`
+}
+
+// OutboxUsecase writes events in the transaction of the rows they are about and delivers them
+// once committed, so an event is neither lost when the process dies after the commit nor sent
+// for rows that were rolled back. Delivery is at least once: an event whose delivery succeeded
+// right before a crash is delivered again.
+type OutboxUsecase struct {
+	DB               *gorm.DB
+	Log              *logrus.Logger
+	OutboxRepository *repository.OutboxRepository
+	// Notifier delivers the events
+	Notifier notifier.Notifier
+	Settings OutboxSettings
+
+	delivered atomic.Int64
+	failed    atomic.Int64
+	abandoned atomic.Int64
+}
+
+func NewOutboxUsecase(db *gorm.DB, log *logrus.Logger, outboxRepository *repository.OutboxRepository,
+	notifier notifier.Notifier, settings OutboxSettings) *OutboxUsecase {
+	return &OutboxUsecase{
+		DB:               db,
+		Log:              log,
+		OutboxRepository: outboxRepository,
+		Notifier:         notifier,
+		Settings:         settings.WithDefaults(),
+	}
+}
+
+// Add writes notifications to the outbox in tx, to be delivered once tx is committed
+func (u *OutboxUsecase) Add(ctx context.Context, tx *gorm.DB, notifications ...notifier.Notification) error {
+	now := time.Now()
+	events := make([]entity.OutboxEvent, 0, len(notifications))
+	for _, notification := range notifications {
+		if notification.SentAt.IsZero() {
+			notification.SentAt = now
+		}
+		payload, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+		events = append(events, entity.OutboxEvent{
+			Event:         notification.Event,
+			Payload:       string(payload),
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		})
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return u.OutboxRepository.CreateAll(ctx, tx, events)
+}
+
+// Dispatch delivers the due events, one lease of BatchSize at a time, and returns how many
+// were delivered. A failed delivery is retried after a backoff doubling with each attempt.
+func (u *OutboxUsecase) Dispatch(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		var events []entity.OutboxEvent
+		if err := u.OutboxRepository.Claim(ctx, u.DB, &events, time.Now(), u.Settings.Lease,
+			u.Settings.BatchSize, u.Settings.MaxAttempts); err != nil {
+			return delivered, err
+		}
+		if len(events) == 0 {
+			return delivered, nil
+		}
+
+		for i := range events {
+			if err := u.deliver(ctx, &events[i]); err != nil {
+				return delivered, err
+			}
+			if events[i].DeliveredAt != nil {
+				delivered++
+			}
+		}
+		if len(events) < u.Settings.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// deliver sends one leased event and records the outcome; the error is only about recording it
+func (u *OutboxUsecase) deliver(ctx context.Context, event *entity.OutboxEvent) error {
+	var notification notifier.Notification
+	err := json.Unmarshal([]byte(event.Payload), &notification)
+	if err == nil {
+		// Past its lease, the event may already be delivered by another dispatcher
+		notifyCtx, cancel := context.WithTimeout(ctx, u.Settings.Lease)
+		err = u.Notifier.Notify(notifyCtx, notification)
+		cancel()
+	}
+	if err == nil {
+		now := time.Now()
+		event.DeliveredAt = &now
+		u.delivered.Add(1)
+		return u.OutboxRepository.MarkDelivered(ctx, u.DB, event.ID, now)
+	}
+
+	u.failed.Add(1)
+	logger := u.Log.WithError(err).WithFields(logrus.Fields{
+		"event_id": event.ID,
+		"event":    event.Event,
+		"attempt":  event.Attempts + 1,
+	})
+	if event.Attempts+1 >= u.Settings.MaxAttempts {
+		u.abandoned.Add(1)
+		logger.Error("Giving up delivering outbox event")
+	} else {
+		logger.Warn("Error delivering outbox event, retrying later")
+	}
+	backoff := min(u.Settings.Backoff<<min(event.Attempts, 20), maxOutboxBackoff)
+	return u.OutboxRepository.MarkFailed(ctx, u.DB, event.ID, err.Error(), time.Now().Add(backoff))
+}
+
+// Stats returns the delivery counters of this instance
+func (u *OutboxUsecase) Stats() OutboxStats {
+	return OutboxStats{
+		Delivered: u.delivered.Load(),
+		Failed:    u.failed.Load(),
+		Abandoned: u.abandoned.Load(),
+	}
+}
+
+// StartDispatching delivers the due events every PollInterval and removes the events delivered
+// longer than Retention ago every hour, until stopChan is closed
+func (u *OutboxUsecase) StartDispatching(stopChan <-chan struct{}) {
+	ticker := time.NewTicker(u.Settings.PollInterval)
+	defer ticker.Stop()
+	var lastCleanup time.Time
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := context.Background()
+			if delivered, err := u.Dispatch(ctx); err != nil {
+				u.Log.WithError(err).WithField("delivered", delivered).Error("Error dispatching outbox events")
+			} else if delivered > 0 {
+				u.Log.WithField("delivered", delivered).Debug("Delivered outbox events")
+			}
+
+			if time.Since(lastCleanup) >= time.Hour {
+				lastCleanup = time.Now()
+				removed, err := u.OutboxRepository.DeleteDelivered(ctx, u.DB, lastCleanup.Add(-u.Settings.Retention))
+				if err != nil {
+					u.Log.WithError(err).Error("Error removing delivered outbox events")
+				} else if removed > 0 {
+					u.Log.WithField("removed", removed).Info("Removed delivered outbox events")
+				}
+			}
+		case <-stopChan:
+			u.Log.Info("Stopping outbox dispatcher")
+			return
+		}
+	}
+}
+
+// storedReleasesEvent describes a batch of newly stored releases
+func storedReleasesEvent(releases []entity.Release) notifier.Notification {
+	stored := make([]map[string]interface{}, len(releases))
+	for i, release := range releases {
+		stored[i] = map[string]interface{}{
+			"id":     release.ID,
+			"repoID": release.RepoID,
+			"tag":    release.TagName,
+		}
+	}
+	return notifier.Notification{
+		Event:   EventReleasesStored,
+		Title:   "Releases stored",
+		Message: "New releases were crawled and stored",
+		Fields: map[string]interface{}{
+			"count":    len(releases),
+			"releases": stored,
+		},
+	}
+}
+
+// storedCommitsEvent describes a batch of newly stored commits, counted by release
+func storedCommitsEvent(commits []entity.Commit) notifier.Notification {
+	byRelease := make(map[int64]int)
+	for _, commit := range commits {
+		byRelease[commit.ReleaseID]++
+	}
+	return notifier.Notification{
+		Event:   EventCommitsStored,
+		Title:   "Commits stored",
+		Message: "New commits were crawled and stored",
+		Fields: map[string]interface{}{
+			"count":     len(commits),
+			"byRelease": byRelease,
+		},
+	}
+}
+
+// outboxEvent is the onInserted hook of createMissing writing event(inserted) to outbox, or nil
+// without an outbox
+func outboxEvent[T any](outbox *OutboxUsecase,
+	event func(inserted []T) notifier.Notification) func(ctx context.Context, tx *gorm.DB, inserted []T) error {
+	if outbox == nil {
+		return nil
+	}
+	return func(ctx context.Context, tx *gorm.DB, inserted []T) error {
+		return outbox.Add(ctx, tx, event(inserted))
+	}
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/nats"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ItemBus is where the BatchCreate usecases of a scrape process send the crawled batches, on
+// the subject of their kind, instead of storing them; the persist processes store them
+type ItemBus struct {
+	Conn     *nats.Conn
+	Settings nats.Settings
+}
+
+// publishItems sends items on the subject of kind and waits for the server to have them. A
+// batch larger than the server accepts is sent in halves.
+func publishItems[T any](ctx context.Context, bus *ItemBus, kind string, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if err := publishBatch(ctx, bus, kind, items); err != nil {
+		return fmt.Errorf("publishing %s: %w", kind, err)
+	}
+	return nil
+}
+
+// publishMissing publishes the entities not stored yet, through their requests, and replaces
+// the others with their stored rows, as createMissing does when storing them: existing[i]
+// reports whether entities[i] was already stored or repeats an earlier entity of the batch.
+// The published entities keep the ID they were built with, which setID copies to their request
+// so the persist process stores them under it and the caller can use it at once, such as to
+// crawl the commits of a new release. Without an ID generator the new entities have ID 0.
+func publishMissing[T any, R any](ctx context.Context, db *gorm.DB, bus *ItemBus, kind string, requests []R,
+	entities []T, key func(*T) string, setID func(request R, entity *T),
+	findStored func(ctx context.Context, db *gorm.DB, entities []T, stored *[]T) error) ([]bool, error) {
+	var found []T
+	if err := findStored(ctx, db, entities, &found); err != nil {
+		return nil, err
+	}
+	stored := make(map[string]T, len(found))
+	for i := range found {
+		stored[key(&found[i])] = found[i]
+	}
+
+	existing := make([]bool, len(entities))
+	missing := make([]R, 0, len(entities))
+	// first is the index of the first entity of each key to publish
+	first := make(map[string]int)
+	for i := range entities {
+		k := key(&entities[i])
+		if row, ok := stored[k]; ok {
+			entities[i] = row
+			existing[i] = true
+		} else if j, ok := first[k]; ok {
+			entities[i] = entities[j]
+			existing[i] = true
+		} else {
+			first[k] = i
+			setID(requests[i], &entities[i])
+			missing = append(missing, requests[i])
+		}
+	}
+
+	if err := publishItems(ctx, bus, kind, missing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// nextID is the ID a request carries, or a new one from ids
+func nextID(id int64, ids idgen.Generator) int64 {
+	if id != 0 {
+		return id
+	}
+	return idgen.Next(ids)
+}
+
+func publishBatch[T any](ctx context.Context, bus *ItemBus, kind string, items []T) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	err = bus.Conn.Publish(ctx, bus.Settings.SubjectOf(kind), data)
+	if errors.Is(err, nats.ErrMaxPayload) && len(items) > 1 {
+		half := len(items) / 2
+		if err := publishBatch(ctx, bus, kind, items[:half]); err != nil {
+			return err
+		}
+		return publishBatch(ctx, bus, kind, items[half:])
+	}
+	return err
+}
+
+// PersistUsecase stores the batches published by the scrape processes. Every persist process
+// consumes through the same durable consumer, so each batch is stored once whatever their
+// number, and a batch that fails to be stored is delivered again.
+type PersistUsecase struct {
+	Log            *logrus.Logger
+	Bus            *ItemBus
+	RepoUsecase    *RepoUsecase
+	ReleaseUsecase *ReleaseUsecase
+	CommitUsecase  *CommitUsecase
+	TagUsecase     *TagUsecase
+}
+
+func NewPersistUsecase(log *logrus.Logger, bus *ItemBus, repoUsecase *RepoUsecase, releaseUsecase *ReleaseUsecase,
+	commitUsecase *CommitUsecase, tagUsecase *TagUsecase) *PersistUsecase {
+	return &PersistUsecase{
+		Log:            log,
+		Bus:            bus,
+		RepoUsecase:    repoUsecase,
+		ReleaseUsecase: releaseUsecase,
+		CommitUsecase:  commitUsecase,
+		TagUsecase:     tagUsecase,
+	}
+}
+
+// Start consumes the batches of every kind; they are stored until the connection is closed.
+// A batch that fails, such as commits arriving before their release is stored, is given back
+// to the server to be delivered again.
+func (u *PersistUsecase) Start() error {
+	settings := u.Bus.Settings
+	return u.Bus.Conn.Consume(func(msg nats.Msg) error {
+		kind := strings.TrimPrefix(msg.Subject, settings.Subject+".")
+		if err := u.store(context.Background(), kind, msg.Data); err != nil {
+			return err
+		}
+		u.Log.WithFields(logrus.Fields{
+			"kind":      kind,
+			"bytes":     len(msg.Data),
+			"delivered": msg.Delivered,
+		}).Debug("Stored published batch")
+		return nil
+	})
+}
+
+// store saves a published batch through BatchCreate, so a batch arriving while the database is
+// unreachable goes to the spool when there is one. A batch that cannot be decoded returns an
+// error wrapping nats.ErrUnprocessable.
+func (u *PersistUsecase) store(ctx context.Context, kind string, payload []byte) error {
+	var err error
+	var decodeErr error
+	switch kind {
+	case spoolRepos:
+		var requests []*model.CreateRepoRequest
+		if decodeErr = json.Unmarshal(payload, &requests); decodeErr == nil {
+			_, err = u.RepoUsecase.BatchCreate(ctx, requests)
+		}
+	case spoolReleases:
+		var requests []*model.CreateReleaseRequest
+		if decodeErr = json.Unmarshal(payload, &requests); decodeErr == nil {
+			_, err = u.ReleaseUsecase.BatchCreate(ctx, requests)
+		}
+	case spoolCommits:
+		var requests []*model.CreateCommitRequest
+		if decodeErr = json.Unmarshal(payload, &requests); decodeErr == nil {
+			_, err = u.CommitUsecase.BatchCreate(ctx, requests)
+		}
+	case spoolTags:
+		var requests []*model.CreateTagRequest
+		if decodeErr = json.Unmarshal(payload, &requests); decodeErr == nil {
+			_, err = u.TagUsecase.BatchCreate(ctx, requests)
+		}
+	default:
+		decodeErr = fmt.Errorf("unknown batch kind %q", kind)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("%w: %w", nats.ErrUnprocessable, decodeErr)
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/service"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RecrawlUsecase schedules the re-crawls of tracked repositories on a decay curve, so repositories
+// with recent releases are re-checked often and dormant ones rarely
+type RecrawlUsecase struct {
+	DB                *gorm.DB
+	Log               *logrus.Logger
+	RepoRepository    *repository.RepoRepository
+	ReleaseRepository *repository.ReleaseRepository
+	Curve             service.DecayCurve
+	// Blackouts holds back due re-crawls until the window ends
+	Blackouts *service.Blackouts
+}
+
+func NewRecrawlUsecase(db *gorm.DB, log *logrus.Logger, repoRepo *repository.RepoRepository,
+	releaseRepo *repository.ReleaseRepository, curve service.DecayCurve, blackouts *service.Blackouts) *RecrawlUsecase {
+	return &RecrawlUsecase{
+		DB:                db,
+		Log:               log,
+		RepoRepository:    repoRepo,
+		ReleaseRepository: releaseRepo,
+		Curve:             curve,
+		Blackouts:         blackouts,
+	}
+}
+
+// ScheduleDue picks up to limit repositories due for a re-crawl at now and moves their next
+// re-crawl along the decay curve, so they are not picked again while being crawled. Repositories
+// without live releases count as dormant.
+func (u *RecrawlUsecase) ScheduleDue(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	db := u.DB.WithContext(ctx)
+
+	var repos []entity.Repository
+	if err := u.RepoRepository.FindDue(ctx, db, &repos, now, limit); err != nil {
+		u.Log.WithError(err).Error("error fetching repositories due for a re-crawl")
+		return nil, err
+	}
+	if len(repos) == 0 {
+		return nil, nil
+	}
+
+	repoIDs := make([]int64, len(repos))
+	for i, repo := range repos {
+		repoIDs[i] = repo.ID
+	}
+	latest, err := u.ReleaseRepository.LatestActivity(ctx, db, repoIDs)
+	if err != nil {
+		u.Log.WithError(err).Error("error fetching latest release activity")
+		return nil, err
+	}
+
+	for _, repo := range repos {
+		interval := u.Curve.MaxInterval
+		if activity, ok := latest[repo.ID]; ok {
+			interval = u.Curve.Interval(now.Sub(activity))
+		}
+		if err := u.RepoRepository.UpdateNextCrawl(ctx, db, repo.ID, now.Add(interval)); err != nil {
+			u.Log.WithError(err).WithField("repo_id", repo.ID).Error("error scheduling re-crawl")
+			return nil, err
+		}
+		u.Log.WithFields(logrus.Fields{
+			"repo":     repo.UserName + "/" + repo.RepoName,
+			"interval": interval.String(),
+		}).Debug("Repository re-crawl scheduled")
+	}
+	return repoIDs, nil
+}
+
+// StartRecrawling checks every tick for repositories due for a re-crawl, up to batch at a time,
+// and hands them to crawl until stopChan is closed
+func (u *RecrawlUsecase) StartRecrawling(tick time.Duration, batch int,
+	crawl func(ctx context.Context, repoIDs []int64) error, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			// Due repositories stay due and are picked up on the first tick after the blackout
+			if until, blackedOut := u.Blackouts.Until(now); blackedOut {
+				u.Log.WithField("until", until).Debug("Blackout window, holding back re-crawls")
+				continue
+			}
+			ctx := context.Background()
+			repoIDs, err := u.ScheduleDue(ctx, now, batch)
+			if err != nil || len(repoIDs) == 0 {
+				continue
+			}
+			if err := crawl(ctx, repoIDs); err != nil {
+				u.Log.WithError(err).WithField("repos", repoIDs).Error("Error starting re-crawl")
+				continue
+			}
+			u.Log.WithField("repos", len(repoIDs)).Info("Started re-crawl of due repositories")
+		case <-stopChan:
+			u.Log.Info("Stopping decay re-crawls")
+			return
+		}
+	}
+}
+
+// Upcoming lists up to limit re-crawls due by to, in the order they will start. A re-crawl
+// due during a blackout window starts when the window ends.
+func (u *RecrawlUsecase) Upcoming(ctx context.Context, now, to time.Time, limit int) ([]model.ScheduledRun, error) {
+	var repos []entity.Repository
+	if err := u.RepoRepository.FindDue(ctx, u.DB, &repos, to, limit); err != nil {
+		u.Log.WithError(err).Error("error fetching upcoming re-crawls")
+		return nil, err
+	}
+
+	runs := make([]model.ScheduledRun, 0, len(repos))
+	for i := range repos {
+		due := now
+		if next := repos[i].NextCrawlAt; next != nil && next.After(due) {
+			due = *next
+		}
+		if retry := repos[i].RetryAt; repos[i].Status == entity.StatusBlocked && retry != nil && retry.After(due) {
+			due = *retry
+		}
+
+		run := model.ScheduledRun{At: due, Kind: "recrawl", Repo: RepoToResponse(&repos[i])}
+		if until, blackedOut := u.Blackouts.Until(due); blackedOut {
+			run.At = until
+			run.DueAt = &due
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].At.Before(runs[j].At) })
+	return runs, nil
+}
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/releasenotes"
+	"crawler/baseline/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ReleaseNoteUsecase parses the notes of releases with releasenotes and stores their items,
+// references and mentions in tables of their own
+type ReleaseNoteUsecase struct {
+	DB  *gorm.DB
+	Log *logrus.Logger
+	// ReleaseNoteRepository is nil while release_notes.enabled is off; notes are then parsed
+	// for each call and never stored
+	ReleaseNoteRepository *repository.ReleaseNoteRepository
+	ReleaseRepository     *repository.ReleaseRepository
+}
+
+func NewReleaseNoteUsecase(db *gorm.DB, log *logrus.Logger, releaseNoteRepo *repository.ReleaseNoteRepository,
+	releaseRepo *repository.ReleaseRepository) *ReleaseNoteUsecase {
+	return &ReleaseNoteUsecase{
+		DB:                    db,
+		Log:                   log,
+		ReleaseNoteRepository: releaseNoteRepo,
+		ReleaseRepository:     releaseRepo,
+	}
+}
+
+// Store parses the notes of new releases and stores them in tx, the transaction inserting the
+// releases, so a release is never stored without its structured notes
+func (u *ReleaseNoteUsecase) Store(ctx context.Context, tx *gorm.DB, releases []entity.Release) error {
+	var items []entity.ReleaseNoteItem
+	var references []entity.ReleaseNoteReference
+	var mentions []entity.ReleaseNoteMention
+	for i := range releases {
+		items, references, mentions = appendNotes(items, references, mentions, releases[i].ID, releases[i].Content)
+	}
+	return u.ReleaseNoteRepository.CreateAll(ctx, tx, items, references, mentions)
+}
+
+// storeHook is Store as the onInserted hook of createMissing, or nil without u
+func (u *ReleaseNoteUsecase) storeHook() func(ctx context.Context, tx *gorm.DB, inserted []entity.Release) error {
+	if u == nil {
+		return nil
+	}
+	return u.Store
+}
+
+// Structured returns the structured notes of a release. The notes of a release with none stored,
+// stored before parsing was added or while it was off, are parsed for the call.
+func (u *ReleaseNoteUsecase) Structured(ctx context.Context, releaseID int64) (*model.StructuredReleaseResponse, error) {
+	release := &entity.Release{}
+	if err := u.ReleaseRepository.FindById(ctx, u.DB, release, releaseID); err != nil {
+		return nil, err
+	}
+
+	var items []entity.ReleaseNoteItem
+	var references []entity.ReleaseNoteReference
+	var mentions []entity.ReleaseNoteMention
+	if u.ReleaseNoteRepository != nil {
+		err := u.ReleaseNoteRepository.FindByRelease(ctx, u.DB, releaseID, &items, &references, &mentions)
+		if err != nil {
+			u.Log.WithError(err).WithField("release_id", releaseID).Error("error fetching structured release notes")
+			return nil, err
+		}
+	}
+	stored := len(items)+len(references)+len(mentions) > 0
+	if !stored {
+		items, references, mentions = appendNotes(nil, nil, nil, release.ID, release.Content)
+	}
+	return StructuredToResponse(release, items, references, mentions, stored), nil
+}
+
+// ParseAll parses the notes of every release again and replaces their stored structure,
+// batchSize releases per transaction, and returns how many it parsed. It fills the tables for
+// the releases stored before parsing was added, and refreshes them after a parser change.
+func (u *ReleaseNoteUsecase) ParseAll(ctx context.Context, batchSize int) (int, error) {
+	db := u.DB.WithContext(ctx)
+	parsed := 0
+	var afterID int64
+	for {
+		var contents []repository.StoredContent
+		if err := u.ReleaseRepository.FindStoredContents(ctx, db, &contents, afterID, batchSize); err != nil {
+			u.Log.WithError(err).Error("error fetching release contents")
+			return parsed, err
+		}
+		if len(contents) == 0 {
+			return parsed, nil
+		}
+
+		releaseIDs := make([]int64, len(contents))
+		var items []entity.ReleaseNoteItem
+		var references []entity.ReleaseNoteReference
+		var mentions []entity.ReleaseNoteMention
+		for i, stored := range contents {
+			content, err := entity.DecodeContent(stored.Content)
+			if err != nil {
+				u.Log.WithError(err).WithField("release_id", stored.ID).Error("error reading release content")
+				return parsed, err
+			}
+			releaseIDs[i] = stored.ID
+			items, references, mentions = appendNotes(items, references, mentions, stored.ID, content)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := u.ReleaseNoteRepository.DeleteByReleases(ctx, tx, releaseIDs); err != nil {
+				return err
+			}
+			return u.ReleaseNoteRepository.CreateAll(ctx, tx, items, references, mentions)
+		})
+		if err != nil {
+			u.Log.WithError(err).WithField("after_id", afterID).Error("error storing structured release notes")
+			return parsed, err
+		}
+		parsed += len(contents)
+		afterID = contents[len(contents)-1].ID
+	}
+}
+
+// appendNotes parses the notes of a release and appends their rows
+func appendNotes(items []entity.ReleaseNoteItem, references []entity.ReleaseNoteReference,
+	mentions []entity.ReleaseNoteMention, releaseID int64, content string) ([]entity.ReleaseNoteItem,
+	[]entity.ReleaseNoteReference, []entity.ReleaseNoteMention) {
+	notes := releasenotes.Parse(content)
+	for i, item := range notes.Items {
+		items = append(items, entity.ReleaseNoteItem{
+			ReleaseID: releaseID,
+			Position:  i,
+			Kind:      item.Kind,
+			Section:   item.Section,
+			Text:      item.Text,
+		})
+	}
+	for _, reference := range notes.References {
+		references = append(references, entity.ReleaseNoteReference{
+			ReleaseID: releaseID,
+			Repo:      reference.Repo,
+			Number:    reference.Number,
+			Type:      reference.Type,
+		})
+	}
+	for _, username := range notes.Mentions {
+		mentions = append(mentions, entity.ReleaseNoteMention{ReleaseID: releaseID, Username: username})
+	}
+	return items, references, mentions
+}
+
+// StructuredToResponse converts the structured notes of a release to a response model, with the
+// items grouped by kind
+func StructuredToResponse(release *entity.Release, items []entity.ReleaseNoteItem,
+	references []entity.ReleaseNoteReference, mentions []entity.ReleaseNoteMention,
+	stored bool) *model.StructuredReleaseResponse {
+	response := &model.StructuredReleaseResponse{
+		ReleaseID:       release.ID,
+		TagName:         release.TagName,
+		Features:        make([]model.ReleaseNoteItemResponse, 0),
+		Fixes:           make([]model.ReleaseNoteItemResponse, 0),
+		BreakingChanges: make([]model.ReleaseNoteItemResponse, 0),
+		Other:           make([]model.ReleaseNoteItemResponse, 0),
+		References:      make([]model.ReleaseReferenceResponse, len(references)),
+		Mentions:        make([]string, len(mentions)),
+		Stored:          stored,
+	}
+	for _, item := range items {
+		itemResponse := model.ReleaseNoteItemResponse{Section: item.Section, Text: item.Text}
+		switch item.Kind {
+		case releasenotes.KindFeature:
+			response.Features = append(response.Features, itemResponse)
+		case releasenotes.KindFix:
+			response.Fixes = append(response.Fixes, itemResponse)
+		case releasenotes.KindBreaking:
+			response.BreakingChanges = append(response.BreakingChanges, itemResponse)
+		default:
+			response.Other = append(response.Other, itemResponse)
+		}
+	}
+	for i, reference := range references {
+		response.References[i] = model.ReleaseReferenceResponse{
+			Repo:   reference.Repo,
+			Number: reference.Number,
+			Type:   reference.Type,
+		}
+	}
+	for i, mention := range mentions {
+		response.Mentions[i] = mention.Username
+	}
+	return response
+}
@@ -3,25 +3,52 @@ package usecase
 import (
 	"context"
 	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/kafka"
 	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/notifier"
 	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/spool"
+	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// EventReleaseDiscovered is notified for each release stored for a repository that already had releases
+const EventReleaseDiscovered = "release.discovered"
+
 type ReleaseUsecase struct {
 	DB                *gorm.DB
 	Log               *logrus.Logger
 	ReleaseRepository *repository.ReleaseRepository
+	Notifier          notifier.Notifier
+	// Spool buffers the batches that fail while the database is unreachable; nil fails them
+	Spool *spool.Spool
+	// IDs generates the keys of new releases and assets; nil leaves them to the database
+	IDs idgen.Generator
+	// RowByRow stores the releases of a batch create one per transaction, when features.batching is off
+	RowByRow bool
+	// Keywords matches the notes of new releases against the watchlist keywords
+	Keywords *KeywordUsecase
+	// Outbox receives the releases.stored events in the transaction of each batch create; nil sends none
+	Outbox *OutboxUsecase
+	// Kafka publishes the created releases; nil publishes nothing
+	Kafka *kafka.Producer
+	// Items sends the batch creates to the persist processes in scrape mode; nil stores them
+	Items *ItemBus
+	// Notes stores the structured notes of new releases in the transaction inserting them; nil stores none
+	Notes *ReleaseNoteUsecase
 }
 
 func NewReleaseUsecase(db *gorm.DB, log *logrus.Logger,
-	releaseRepo *repository.ReleaseRepository) *ReleaseUsecase {
+	releaseRepo *repository.ReleaseRepository, notifier notifier.Notifier) *ReleaseUsecase {
 	return &ReleaseUsecase{
 		DB:                db,
 		Log:               log,
 		ReleaseRepository: releaseRepo,
+		Notifier:          notifier,
 	}
 }
 
@@ -29,65 +56,301 @@ func (r *ReleaseUsecase) Create(ctx context.Context, request *model.CreateReleas
 	tx := r.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	release := &entity.Release{
-		TagName: request.TagName,
-		Content: request.Content,
-		RepoID:  request.RepoID,
-	}
-	if err := r.ReleaseRepository.Create(tx, release); err != nil {
+	release := newReleaseEntity(request, r.IDs)
+	if err := r.ReleaseRepository.Create(ctx, tx, release); err != nil {
 		r.Log.WithError(err).Error("error creating release")
 		return nil, err
 	}
+	if r.Notes != nil {
+		if err := r.Notes.Store(ctx, tx, []entity.Release{*release}); err != nil {
+			r.Log.WithError(err).Error("error storing structured release notes")
+			return nil, err
+		}
+	}
 	if err := tx.Commit().Error; err != nil {
 		r.Log.WithError(err).Error("error committing transaction")
 		return nil, err
 	}
-	return &model.ReleaseResponse{
-		ID:      release.ID,
-		Content: release.Content,
-		RepoID:  release.RepoID,
-	}, nil
+	r.Kafka.PublishReleases([]entity.Release{*release})
+	return ReleaseToResponse(release), nil
 }
 
+// BatchCreate stores the releases whose tag is not stored yet for their repository. Stored
+// ones are returned as they are, without their assets, and marked Existing.
 func (r *ReleaseUsecase) BatchCreate(ctx context.Context, requests []*model.CreateReleaseRequest) ([]*model.ReleaseResponse, error) {
+	if r.Items != nil {
+		return r.publish(ctx, requests)
+	}
+	responses, err := r.batchCreate(ctx, requests)
+	if r.Spool.Hold(err, spoolReleases, requests) {
+		r.Log.WithField("count", len(requests)).Warn("Database unreachable, releases buffered in the spool")
+		return []*model.ReleaseResponse{}, nil
+	}
+	return responses, err
+}
+
+func (r *ReleaseUsecase) batchCreate(ctx context.Context, requests []*model.CreateReleaseRequest) ([]*model.ReleaseResponse, error) {
 	if len(requests) == 0 {
 		return []*model.ReleaseResponse{}, nil
 	}
 
-	tx := r.DB.WithContext(ctx).Begin()
-	defer tx.Rollback()
+	discovered, err := r.findDiscovered(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create slice of entities for batch insertion
 	releases := make([]entity.Release, len(requests))
 	for i, req := range requests {
-		releases[i] = entity.Release{
-			TagName: req.TagName,
-			Content: req.Content,
-			RepoID:  req.RepoID,
-		}
+		releases[i] = *newReleaseEntity(req, r.IDs)
 	}
 
-	// Perform batch insert with chunks of 100
-	if err := tx.CreateInBatches(releases, 100).Error; err != nil {
+	existing, err := createMissing(ctx, r.DB, r.RowByRow, releases, releaseKey,
+		r.ReleaseRepository.FindStored, r.ReleaseRepository.CreateMissing,
+		chainInserted(outboxEvent(r.Outbox, storedReleasesEvent), r.Notes.storeHook()))
+	if err != nil {
 		r.Log.WithError(err).Error("error batch creating releases")
 		return nil, err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		r.Log.WithError(err).Error("error committing batch transaction")
+	// Create responses with the stored IDs
+	responses := make([]*model.ReleaseResponse, len(releases))
+	created := make([]*model.ReleaseResponse, 0, len(releases))
+	createdReleases := make([]entity.Release, 0, len(releases))
+	for i := range releases {
+		responses[i] = ReleaseToResponse(&releases[i])
+		responses[i].Existing = existing[i]
+		if !existing[i] {
+			created = append(created, responses[i])
+			createdReleases = append(createdReleases, releases[i])
+		}
+		if discovered[i] && !existing[i] {
+			r.notifyDiscovered(responses[i])
+		}
+	}
+	r.Keywords.MatchReleases(created)
+	r.Kafka.PublishReleases(createdReleases)
+
+	return responses, nil
+}
+
+// publish sends the releases not stored yet to a persist process, which stores them, and returns
+// them all as batchCreate does; the persist process notifies the discovered ones
+func (r *ReleaseUsecase) publish(ctx context.Context, requests []*model.CreateReleaseRequest) ([]*model.ReleaseResponse, error) {
+	releases := make([]entity.Release, len(requests))
+	for i, req := range requests {
+		releases[i] = *newReleaseEntity(req, r.IDs)
+	}
+	existing, err := publishMissing(ctx, r.DB, r.Items, spoolReleases, requests, releases, releaseKey,
+		func(request *model.CreateReleaseRequest, release *entity.Release) { request.ID = release.ID },
+		r.ReleaseRepository.FindStored)
+	if err != nil {
 		return nil, err
 	}
 
-	// Create responses with IDs assigned by database
 	responses := make([]*model.ReleaseResponse, len(releases))
-	for i, release := range releases {
-		responses[i] = &model.ReleaseResponse{
-			ID:      release.ID,
-			TagName: release.TagName,
-			Content: release.Content,
-			RepoID:  release.RepoID,
+	for i := range releases {
+		responses[i] = ReleaseToResponse(&releases[i])
+		responses[i].Existing = existing[i]
+	}
+	return responses, nil
+}
+
+// SyncTombstones compares the stored releases of a repository with the tags it currently lists on
+// GitHub: releases whose tag disappeared are tombstoned, and tombstoned releases whose tag is listed
+// again are restored. liveTags must be the complete listing, or live releases get tombstoned.
+func (r *ReleaseUsecase) SyncTombstones(ctx context.Context, repoID int64, liveTags []string) (int, int, error) {
+	var stored []entity.Release
+	if err := r.DB.WithContext(ctx).Select("id", "tagname", "tombstonedat").
+		Where("repoid = ?", repoID).Find(&stored).Error; err != nil {
+		r.Log.WithError(err).Error("error fetching stored release tags")
+		return 0, 0, err
+	}
+
+	live := make(map[string]bool, len(liveTags))
+	for _, tag := range liveTags {
+		live[tag] = true
+	}
+
+	gone := make([]int64, 0)
+	back := make([]int64, 0)
+	for _, release := range stored {
+		switch {
+		case !live[release.TagName] && release.TombstonedAt == nil:
+			gone = append(gone, release.ID)
+		case live[release.TagName] && release.TombstonedAt != nil:
+			back = append(back, release.ID)
 		}
 	}
 
-	return responses, nil
+	db := r.DB.WithContext(ctx)
+	if len(gone) > 0 {
+		if err := r.ReleaseRepository.Tombstone(ctx, db, gone, time.Now()); err != nil {
+			r.Log.WithError(err).Error("error tombstoning releases")
+			return 0, 0, err
+		}
+	}
+	if len(back) > 0 {
+		if err := r.ReleaseRepository.Restore(ctx, db, back); err != nil {
+			r.Log.WithError(err).Error("error restoring releases")
+			return len(gone), 0, err
+		}
+	}
+	return len(gone), len(back), nil
+}
+
+// findDiscovered marks the requests whose tag is not stored yet for a repository that already has
+// releases. A repository's first crawl stores its whole history, which is not reported as discovered.
+func (r *ReleaseUsecase) findDiscovered(ctx context.Context, requests []*model.CreateReleaseRequest) ([]bool, error) {
+	discovered := make([]bool, len(requests))
+	if r.Notifier == nil {
+		return discovered, nil
+	}
+
+	repoIDs := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, req := range requests {
+		if !seen[req.RepoID] {
+			seen[req.RepoID] = true
+			repoIDs = append(repoIDs, req.RepoID)
+		}
+	}
+
+	var stored []entity.Release
+	if err := r.DB.WithContext(ctx).Select("repoid", "tagname").
+		Where("repoid IN ?", repoIDs).Find(&stored).Error; err != nil {
+		r.Log.WithError(err).Error("error fetching stored release tags")
+		return nil, err
+	}
+
+	hasReleases := make(map[int64]bool)
+	known := make(map[string]bool, len(stored))
+	for _, release := range stored {
+		hasReleases[release.RepoID] = true
+		known[fmt.Sprintf("%d/%s", release.RepoID, release.TagName)] = true
+	}
+
+	for i, req := range requests {
+		key := fmt.Sprintf("%d/%s", req.RepoID, req.TagName)
+		discovered[i] = hasReleases[req.RepoID] && !known[key]
+		// A tag repeated within the batch is only discovered once
+		known[key] = true
+	}
+	return discovered, nil
+}
+
+// notifyDiscovered sends a release.discovered notification in the background
+func (r *ReleaseUsecase) notifyDiscovered(release *model.ReleaseResponse) {
+	notification := notifier.Notification{
+		Event:   EventReleaseDiscovered,
+		Title:   fmt.Sprintf("New release %s", release.TagName),
+		Message: release.Title,
+		Fields: map[string]interface{}{
+			"release": release,
+		},
+		SentAt: time.Now(),
+	}
+
+	go func() {
+		if err := r.Notifier.Notify(context.Background(), notification); err != nil {
+			r.Log.WithError(err).WithField("release_id", release.ID).Error("error notifying discovered release")
+		}
+	}()
+}
+
+// RecompressContents stores the content of every release again under the current compression
+// setting, batchSize releases at a time, and returns how many it rewrote. Turning compression on
+// and running it compresses the contents stored before; turning it off decompresses them.
+func (r *ReleaseUsecase) RecompressContents(ctx context.Context, batchSize int) (int, error) {
+	db := r.DB.WithContext(ctx)
+	rewritten := 0
+	var afterID int64
+	for {
+		var contents []repository.StoredContent
+		if err := r.ReleaseRepository.FindStoredContents(ctx, db, &contents, afterID, batchSize); err != nil {
+			r.Log.WithError(err).Error("error fetching release contents")
+			return rewritten, err
+		}
+		if len(contents) == 0 {
+			return rewritten, nil
+		}
+
+		for _, stored := range contents {
+			content, err := entity.DecodeContent(stored.Content)
+			if err != nil {
+				r.Log.WithError(err).WithField("release_id", stored.ID).Error("error reading release content")
+				return rewritten, err
+			}
+			encoded, err := entity.EncodeContent(content)
+			if err != nil {
+				r.Log.WithError(err).WithField("release_id", stored.ID).Error("error compressing release content")
+				return rewritten, err
+			}
+			if encoded == stored.Content {
+				continue
+			}
+			if err := r.ReleaseRepository.UpdateStoredContent(ctx, db, stored.ID, encoded); err != nil {
+				r.Log.WithError(err).WithField("release_id", stored.ID).Error("error storing release content")
+				return rewritten, err
+			}
+			rewritten++
+		}
+		afterID = contents[len(contents)-1].ID
+	}
+}
+
+// releaseKey identifies a release like the unique index on its repository and tag
+func releaseKey(release *entity.Release) string {
+	return fmt.Sprintf("%d/%s", release.RepoID, release.TagName)
+}
+
+// newReleaseEntity builds a release entity, including its assets, from a create request
+func newReleaseEntity(request *model.CreateReleaseRequest, ids idgen.Generator) *entity.Release {
+	release := &entity.Release{
+		ID:          nextID(request.ID, ids),
+		TagName:     request.TagName,
+		Content:     request.Content,
+		Title:       request.Title,
+		PublishedAt: request.PublishedAt,
+		Author:      request.Author,
+		Prerelease:  request.Prerelease,
+		RepoID:      request.RepoID,
+	}
+
+	for _, asset := range request.Assets {
+		release.Assets = append(release.Assets, entity.ReleaseAsset{
+			ID:            idgen.Next(ids),
+			Name:          asset.Name,
+			Size:          asset.Size,
+			DownloadCount: asset.DownloadCount,
+		})
+	}
+
+	return release
+}
+
+// ReleaseToResponse converts a release entity, with its loaded assets, to a response model
+func ReleaseToResponse(release *entity.Release) *model.ReleaseResponse {
+	response := &model.ReleaseResponse{
+		ID:           release.ID,
+		TagName:      release.TagName,
+		Content:      release.Content,
+		Title:        release.Title,
+		PublishedAt:  release.PublishedAt,
+		Author:       release.Author,
+		Prerelease:   release.Prerelease,
+		RepoID:       release.RepoID,
+		Status:       release.Status,
+		TombstonedAt: release.TombstonedAt,
+	}
+
+	for _, asset := range release.Assets {
+		response.Assets = append(response.Assets, model.ReleaseAssetResponse{
+			Name:          asset.Name,
+			Size:          asset.Size,
+			DownloadCount: asset.DownloadCount,
+		})
+	}
+
+	return response
 }
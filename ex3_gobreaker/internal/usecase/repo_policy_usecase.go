@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/service"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RepoPolicyUsecase resolves the crawl policy of repositories: the one stored through the API,
+// else the repo_policies entry of the config, else none
+type RepoPolicyUsecase struct {
+	DB                   *gorm.DB
+	Log                  *logrus.Logger
+	RepoPolicyRepository *repository.RepoPolicyRepository
+	RepoRepository       *repository.RepoRepository
+	// Configured are the policies of the repo_policies config section
+	Configured service.RepoPolicies
+}
+
+func NewRepoPolicyUsecase(db *gorm.DB, log *logrus.Logger, repoPolicyRepo *repository.RepoPolicyRepository,
+	repoRepo *repository.RepoRepository, configured service.RepoPolicies) *RepoPolicyUsecase {
+	return &RepoPolicyUsecase{
+		DB:                   db,
+		Log:                  log,
+		RepoPolicyRepository: repoPolicyRepo,
+		RepoRepository:       repoRepo,
+		Configured:           configured,
+	}
+}
+
+// For returns the policy a crawl of the repository applies. A policy that fails to load is
+// logged and the configured one applied instead. A nil usecase applies none.
+func (u *RepoPolicyUsecase) For(ctx context.Context, repo *entity.Repository) service.RepoPolicy {
+	if u == nil {
+		return service.RepoPolicy{}
+	}
+
+	policy, _, _, err := u.resolve(ctx, repo)
+	if err != nil {
+		u.Log.WithError(err).WithField("repo_id", repo.ID).Warn("error loading repository policy, using the configured one")
+		policy, _ = u.Configured.For(repo.UserName, repo.RepoName)
+	}
+	return policy
+}
+
+// Get returns the policy of a repository with its source
+func (u *RepoPolicyUsecase) Get(ctx context.Context, repoID int64) (*model.RepoPolicyResponse, error) {
+	repo, err := u.findRepo(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, source, updatedAt, err := u.resolve(ctx, repo)
+	if err != nil {
+		u.Log.WithError(err).WithField("repo_id", repoID).Error("error loading repository policy")
+		return nil, err
+	}
+	return repoPolicyToResponse(repo, policy, source, updatedAt), nil
+}
+
+// Update stores the policy of a repository, which replaces its configured one
+func (u *RepoPolicyUsecase) Update(ctx context.Context, repoID int64, request *model.UpdateRepoPolicyRequest,
+	now time.Time) (*model.RepoPolicyResponse, error) {
+	policy := service.RepoPolicy{
+		MaxReleases:          request.MaxReleases,
+		MaxCommitsPerRelease: request.MaxCommitsPerRelease,
+		SkipCommits:          request.SkipCommits,
+		RequestsPerMinute:    request.RequestsPerMinute,
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalid, err)
+	}
+
+	repo, err := u.findRepo(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &entity.RepoPolicy{
+		RepoID:               repoID,
+		MaxReleases:          policy.MaxReleases,
+		MaxCommitsPerRelease: policy.MaxCommitsPerRelease,
+		SkipCommits:          policy.SkipCommits,
+		RequestsPerMinute:    policy.RequestsPerMinute,
+		UpdatedAt:            now,
+	}
+	if err := u.RepoPolicyRepository.Upsert(ctx, u.DB, stored); err != nil {
+		u.Log.WithError(err).WithField("repo_id", repoID).Error("error saving repository policy")
+		return nil, err
+	}
+
+	u.Log.WithFields(logrus.Fields{
+		"repo":                    repo.UserName + "/" + repo.RepoName,
+		"max_releases":            policy.MaxReleases,
+		"max_commits_per_release": policy.MaxCommitsPerRelease,
+		"skip_commits":            policy.SkipCommits,
+		"requests_per_minute":     policy.RequestsPerMinute,
+	}).Info("Repository policy updated")
+	return repoPolicyToResponse(repo, policy, model.RepoPolicySourceStored, &stored.UpdatedAt), nil
+}
+
+// Delete removes the stored policy of a repository, which falls back to its configured one
+func (u *RepoPolicyUsecase) Delete(ctx context.Context, repoID int64) (*model.RepoPolicyResponse, error) {
+	repo, err := u.findRepo(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := u.RepoPolicyRepository.DeleteByRepoID(ctx, u.DB, repoID)
+	if err != nil {
+		u.Log.WithError(err).WithField("repo_id", repoID).Error("error deleting repository policy")
+		return nil, err
+	}
+	if !deleted {
+		return nil, fmt.Errorf("%w: repository %d has no stored policy", apperrors.ErrNotFound, repoID)
+	}
+
+	policy, source := service.RepoPolicy{}, model.RepoPolicySourceNone
+	if configured, ok := u.Configured.For(repo.UserName, repo.RepoName); ok {
+		policy, source = configured, model.RepoPolicySourceConfig
+	}
+	return repoPolicyToResponse(repo, policy, source, nil), nil
+}
+
+func (u *RepoPolicyUsecase) findRepo(ctx context.Context, repoID int64) (*entity.Repository, error) {
+	repo := &entity.Repository{}
+	if err := u.RepoRepository.FindById(ctx, u.DB, repo, repoID); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// resolve returns the policy of a repository, where it comes from and, for a stored one, when
+// it was last updated
+func (u *RepoPolicyUsecase) resolve(ctx context.Context, repo *entity.Repository) (service.RepoPolicy, string, *time.Time, error) {
+	stored := &entity.RepoPolicy{}
+	err := u.RepoPolicyRepository.FindByRepoID(ctx, u.DB, stored, repo.ID)
+	if err == nil {
+		return service.RepoPolicy{
+			MaxReleases:          stored.MaxReleases,
+			MaxCommitsPerRelease: stored.MaxCommitsPerRelease,
+			SkipCommits:          stored.SkipCommits,
+			RequestsPerMinute:    stored.RequestsPerMinute,
+		}, model.RepoPolicySourceStored, &stored.UpdatedAt, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return service.RepoPolicy{}, "", nil, err
+	}
+
+	if policy, ok := u.Configured.For(repo.UserName, repo.RepoName); ok {
+		return policy, model.RepoPolicySourceConfig, nil, nil
+	}
+	return service.RepoPolicy{}, model.RepoPolicySourceNone, nil, nil
+}
+
+func repoPolicyToResponse(repo *entity.Repository, policy service.RepoPolicy, source string,
+	updatedAt *time.Time) *model.RepoPolicyResponse {
+	return &model.RepoPolicyResponse{
+		RepoID:               repo.ID,
+		Repo:                 repo.UserName + "/" + repo.RepoName,
+		Source:               source,
+		MaxReleases:          policy.MaxReleases,
+		MaxCommitsPerRelease: policy.MaxCommitsPerRelease,
+		SkipCommits:          policy.SkipCommits,
+		RequestsPerMinute:    policy.RequestsPerMinute,
+		UpdatedAt:            updatedAt,
+	}
+}
@@ -2,9 +2,17 @@ package usecase
 
 import (
 	"context"
+	"crawler/baseline/internal/apperrors"
 	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/kafka"
 	"crawler/baseline/internal/model"
 	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/spool"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -14,6 +22,15 @@ type RepoUsecase struct {
 	DB             *gorm.DB
 	Log            *logrus.Logger
 	RepoRepository *repository.RepoRepository
+	Spool          *spool.Spool
+	// IDs generates the keys of new repositories; nil leaves them to the database
+	IDs idgen.Generator
+	// RowByRow stores the repositories of a batch create one per transaction, when features.batching is off
+	RowByRow bool
+	// Kafka publishes the created repositories; nil publishes nothing
+	Kafka *kafka.Producer
+	// Items sends the batch creates to the persist processes in scrape mode; nil stores them
+	Items *ItemBus
 }
 
 func NewRepoUsecase(db *gorm.DB, log *logrus.Logger,
@@ -31,11 +48,12 @@ func (r *RepoUsecase) Create(ctx context.Context, request *model.CreateRepoReque
 
 	// Create repository entity that matches your schema
 	repo := &entity.Repository{
+		ID:       idgen.Next(r.IDs),
 		RepoName: request.RepoName,
 		UserName: request.UserName,
 	}
 
-	if err := r.RepoRepository.Create(tx, repo); err != nil {
+	if err := r.RepoRepository.Create(ctx, tx, repo); err != nil {
 		r.Log.WithError(err).Error("error creating repository")
 		return nil, nil
 	}
@@ -44,51 +62,189 @@ func (r *RepoUsecase) Create(ctx context.Context, request *model.CreateRepoReque
 		r.Log.WithError(err).Error("error committing transaction")
 		return nil, nil
 	}
+	r.Kafka.PublishRepos([]entity.Repository{*repo})
 
-	return &model.RepoResponse{
-		ID:       repo.ID,
-		RepoName: repo.RepoName,
-		UserName: repo.UserName,
-	}, nil
+	return RepoToResponse(repo), nil
 }
 
+// BatchCreate stores the repositories that are not tracked yet. Already tracked ones, matched
+// by owner and name ignoring case, are returned as stored and marked Existing.
 func (r *RepoUsecase) BatchCreate(ctx context.Context, requests []*model.CreateRepoRequest) ([]*model.RepoResponse, error) {
+	if r.Items != nil {
+		return r.publish(ctx, requests)
+	}
+	responses, err := r.batchCreate(ctx, requests)
+	if r.Spool.Hold(err, spoolRepos, requests) {
+		r.Log.WithField("count", len(requests)).Warn("Database unreachable, repositories buffered in the spool")
+		return []*model.RepoResponse{}, nil
+	}
+	return responses, err
+}
+
+func (r *RepoUsecase) batchCreate(ctx context.Context, requests []*model.CreateRepoRequest) ([]*model.RepoResponse, error) {
 	if len(requests) == 0 {
 		return []*model.RepoResponse{}, nil
 	}
 
-	tx := r.DB.WithContext(ctx).Begin()
-	defer tx.Rollback()
+	repos := r.newEntities(requests)
+	existing, err := createMissing(ctx, r.DB, r.RowByRow, repos, repoKey,
+		r.RepoRepository.FindStored, r.RepoRepository.CreateMissing, nil)
+	if err != nil {
+		r.Log.WithError(err).Error("error batch creating repositories")
+		return nil, err
+	}
 
-	// Create slice of entities for batch insertion
+	// Create responses with the stored IDs
+	responses := make([]*model.RepoResponse, len(repos))
+	created := make([]entity.Repository, 0, len(repos))
+	for i := range repos {
+		responses[i] = RepoToResponse(&repos[i])
+		responses[i].Existing = existing[i]
+		if !existing[i] {
+			created = append(created, repos[i])
+		}
+	}
+	r.Kafka.PublishRepos(created)
+
+	return responses, nil
+}
+
+// publish sends the repositories not tracked yet to a persist process, which stores them, and
+// returns them all as batchCreate does
+func (r *RepoUsecase) publish(ctx context.Context, requests []*model.CreateRepoRequest) ([]*model.RepoResponse, error) {
+	repos := r.newEntities(requests)
+	existing, err := publishMissing(ctx, r.DB, r.Items, spoolRepos, requests, repos, repoKey,
+		func(request *model.CreateRepoRequest, repo *entity.Repository) { request.ID = repo.ID },
+		r.RepoRepository.FindStored)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*model.RepoResponse, len(repos))
+	for i := range repos {
+		responses[i] = RepoToResponse(&repos[i])
+		responses[i].Existing = existing[i]
+	}
+	return responses, nil
+}
+
+// newEntities builds the repository entities of a batch
+func (r *RepoUsecase) newEntities(requests []*model.CreateRepoRequest) []entity.Repository {
 	repos := make([]entity.Repository, len(requests))
 	for i, req := range requests {
 		repos[i] = entity.Repository{
+			ID:       nextID(req.ID, r.IDs),
 			RepoName: req.RepoName,
 			UserName: req.UserName,
 		}
 	}
+	return repos
+}
 
-	// Perform batch insert
-	if err := tx.CreateInBatches(repos, 100).Error; err != nil {
-		r.Log.WithError(err).Error("error batch creating repositories")
+// repoKey identifies a repository like the unique index on its owner and name
+func repoKey(repo *entity.Repository) string {
+	return strings.ToLower(repo.UserName) + "/" + strings.ToLower(repo.RepoName)
+}
+
+// Onboard creates a repository with a known default branch and crawl policy.
+// It fails with an error wrapping apperrors.ErrConflict, and returns the stored repository, if the
+// repository is already tracked.
+func (r *RepoUsecase) Onboard(ctx context.Context, repo *model.GitHubRepo, policy string) (*entity.Repository, error) {
+	tx := r.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	existing := &entity.Repository{}
+	err := r.RepoRepository.FindByName(ctx, tx, existing, repo.UserName, repo.RepoName)
+	if err == nil {
+		return existing, fmt.Errorf("%w: repository %s/%s", apperrors.ErrConflict, existing.UserName, existing.RepoName)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		r.Log.WithError(err).Error("error looking up repository")
+		return nil, err
+	}
+
+	created := &entity.Repository{
+		ID:            idgen.Next(r.IDs),
+		UserName:      repo.UserName,
+		RepoName:      repo.RepoName,
+		DefaultBranch: repo.DefaultBranch,
+		Policy:        policy,
+	}
+	if err := r.RepoRepository.Create(ctx, tx, created); err != nil {
+		r.Log.WithError(err).Error("error creating repository")
 		return nil, err
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		r.Log.WithError(err).Error("error committing batch transaction")
+		r.Log.WithError(err).Error("error committing transaction")
 		return nil, err
 	}
+	r.Kafka.PublishRepos([]entity.Repository{*created})
+	return created, nil
+}
 
-	// Create responses with IDs assigned by database
-	responses := make([]*model.RepoResponse, len(repos))
-	for i, repo := range repos {
-		responses[i] = &model.RepoResponse{
-			ID:       repo.ID,
-			RepoName: repo.RepoName,
-			UserName: repo.UserName,
-		}
+// Enrich stores scraped front page metadata on a repository
+func (r *RepoUsecase) Enrich(ctx context.Context, repo *entity.Repository, metadata *model.RepoMetadata) (*model.RepoResponse, error) {
+	now := time.Now()
+	repo.Description = metadata.Description
+	repo.Stars = metadata.Stars
+	repo.Forks = metadata.Forks
+	repo.Language = metadata.Language
+	repo.Topics = strings.Join(metadata.Topics, ",")
+	repo.License = metadata.License
+	repo.EnrichedAt = &now
+
+	if err := r.RepoRepository.Update(ctx, r.DB, repo); err != nil {
+		r.Log.WithError(err).Error("error enriching repository")
+		return nil, err
 	}
 
-	return responses, nil
+	return RepoToResponse(repo), nil
+}
+
+// Restore brings back an archived repository so it is crawled again
+func (r *RepoUsecase) Restore(ctx context.Context, id int64) (*model.RepoResponse, error) {
+	db := r.DB.WithContext(ctx)
+	if err := r.RepoRepository.Restore(ctx, db, id); err != nil {
+		r.Log.WithError(err).WithField("repo_id", id).Error("error restoring repository")
+		return nil, err
+	}
+
+	repo := &entity.Repository{}
+	if err := r.RepoRepository.FindById(ctx, db, repo, id); err != nil {
+		r.Log.WithError(err).WithField("repo_id", id).Error("error loading restored repository")
+		return nil, err
+	}
+	return RepoToResponse(repo), nil
+}
+
+// RepoToResponse converts a repository entity to a response model
+func RepoToResponse(repo *entity.Repository) *model.RepoResponse {
+	response := &model.RepoResponse{
+		ID:            repo.ID,
+		RepoName:      repo.RepoName,
+		UserName:      repo.UserName,
+		DefaultBranch: repo.DefaultBranch,
+		Policy:        repo.Policy,
+		Description:   repo.Description,
+		Stars:         repo.Stars,
+		Forks:         repo.Forks,
+		Language:      repo.Language,
+		License:       repo.License,
+		EnrichedAt:    repo.EnrichedAt,
+		Status:        repo.Status,
+		RetryAt:       repo.RetryAt,
+		NextCrawlAt:   repo.NextCrawlAt,
+		NotFoundCount: repo.NotFoundCount,
+		Archived:      repo.Archived,
+	}
+	if repo.DeletedAt.Valid {
+		deletedAt := repo.DeletedAt.Time
+		response.DeletedAt = &deletedAt
+	}
+	if repo.Topics != "" {
+		response.Topics = strings.Split(repo.Topics, ",")
+	}
+
+	return response
 }
@@ -0,0 +1,262 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/service"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ScheduleAll names the schedule of full coordinator cycles, the other schedules run one stage
+const ScheduleAll = "all"
+
+// ScheduleUsecase runs coordinator stages on the cron schedules kept in the crawl_schedules
+// table, which every instance can list and edit
+type ScheduleUsecase struct {
+	DB                 *gorm.DB
+	Log                *logrus.Logger
+	ScheduleRepository *repository.ScheduleRepository
+	// Blackouts gives the timezone of the expressions and skips runs due during a window
+	Blackouts *service.Blackouts
+
+	// running holds the schedules whose previous run has not finished yet
+	mutex   sync.Mutex
+	running map[string]bool
+}
+
+func NewScheduleUsecase(db *gorm.DB, log *logrus.Logger, scheduleRepo *repository.ScheduleRepository,
+	blackouts *service.Blackouts) *ScheduleUsecase {
+	return &ScheduleUsecase{
+		DB:                 db,
+		Log:                log,
+		ScheduleRepository: scheduleRepo,
+		Blackouts:          blackouts,
+		running:            make(map[string]bool),
+	}
+}
+
+// Seed stores the configured expressions of schedules that are not stored yet
+func (u *ScheduleUsecase) Seed(ctx context.Context, expressions map[string]string, now time.Time) error {
+	db := u.DB.WithContext(ctx)
+	for name, expression := range expressions {
+		cron, err := service.ParseCron(expression)
+		if err != nil {
+			return fmt.Errorf("schedule %s: %w", name, err)
+		}
+		schedule := &entity.CrawlSchedule{
+			Name:       name,
+			Expression: cron.String(),
+			NextRunAt:  cron.Next(now.In(u.Blackouts.Location())),
+			UpdatedAt:  now,
+		}
+		if err := u.ScheduleRepository.Seed(ctx, db, schedule); err != nil {
+			u.Log.WithError(err).WithField("schedule", name).Error("error seeding schedule")
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns every schedule by name
+func (u *ScheduleUsecase) List(ctx context.Context) ([]model.CrawlScheduleResponse, error) {
+	var schedules []entity.CrawlSchedule
+	if err := u.ScheduleRepository.FindAll(ctx, u.DB, &schedules); err != nil {
+		u.Log.WithError(err).Error("error fetching schedules")
+		return nil, err
+	}
+
+	responses := make([]model.CrawlScheduleResponse, 0, len(schedules))
+	for i := range schedules {
+		responses = append(responses, u.toResponse(&schedules[i]))
+	}
+	return responses, nil
+}
+
+// Update replaces the expression of a schedule and moves its next run accordingly
+func (u *ScheduleUsecase) Update(ctx context.Context, name string, expression string, now time.Time) (model.CrawlScheduleResponse, error) {
+	cron, err := service.ParseCron(expression)
+	if err != nil {
+		return model.CrawlScheduleResponse{}, fmt.Errorf("%w: %v", apperrors.ErrInvalid, err)
+	}
+
+	db := u.DB.WithContext(ctx)
+	schedule := &entity.CrawlSchedule{}
+	if err := db.Where("name = ?", name).Take(schedule).Error; err != nil {
+		return model.CrawlScheduleResponse{}, err
+	}
+	schedule.Expression = cron.String()
+	schedule.NextRunAt = cron.Next(now.In(u.Blackouts.Location()))
+	schedule.UpdatedAt = now
+	if err := u.ScheduleRepository.Update(ctx, db, schedule); err != nil {
+		u.Log.WithError(err).WithField("schedule", name).Error("error updating schedule")
+		return model.CrawlScheduleResponse{}, err
+	}
+
+	u.Log.WithFields(logrus.Fields{
+		"schedule":   name,
+		"expression": schedule.Expression,
+		"next_run":   schedule.NextRunAt,
+	}).Info("Schedule updated")
+	return u.toResponse(schedule), nil
+}
+
+// Upcoming lists the runs of every schedule between from and to, flagging those that fall in
+// a blackout window
+func (u *ScheduleUsecase) Upcoming(ctx context.Context, from, to time.Time, limit int) ([]model.ScheduledRun, error) {
+	var schedules []entity.CrawlSchedule
+	if err := u.ScheduleRepository.FindAll(ctx, u.DB, &schedules); err != nil {
+		u.Log.WithError(err).Error("error fetching schedules")
+		return nil, err
+	}
+
+	runs := make([]model.ScheduledRun, 0)
+	for _, schedule := range schedules {
+		cron, err := service.ParseCron(schedule.Expression)
+		if err != nil {
+			continue
+		}
+		at := schedule.NextRunAt.In(u.Blackouts.Location())
+		for ; !at.IsZero() && at.Before(to) && len(runs) < limit; at = cron.Next(at) {
+			if at.Before(from) {
+				continue
+			}
+			_, blackedOut := u.Blackouts.Until(at)
+			runs = append(runs, model.ScheduledRun{At: at, Kind: "schedule:" + schedule.Name, Skipped: blackedOut})
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].At.Before(runs[j].At) })
+	return runs, nil
+}
+
+// StartScheduling checks every tick for due schedules and runs them in the background until
+// stopChan is closed. A schedule whose previous run is still going, or that is due during a
+// blackout window, skips the run and waits for its next one. Runs still marked running when
+// scheduling starts were cut short by a previous scheduler and are marked failed.
+func (u *ScheduleUsecase) StartScheduling(tick time.Duration, run func(name string) error, stopChan <-chan struct{}) {
+	interrupted, err := u.ScheduleRepository.FinishRunning(context.Background(), u.DB, service.StatusRunning, service.StatusFailed,
+		"interrupted by a scheduler restart")
+	if err != nil {
+		u.Log.WithError(err).Error("error recovering interrupted scheduled runs")
+	} else if interrupted > 0 {
+		u.Log.WithField("schedules", interrupted).Warn("Marked scheduled runs interrupted by a restart as failed")
+	}
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			u.runDue(now, run)
+		case <-stopChan:
+			u.Log.Info("Stopping scheduled crawls")
+			return
+		}
+	}
+}
+
+func (u *ScheduleUsecase) runDue(now time.Time, run func(name string) error) {
+	ctx := context.Background()
+	db := u.DB.WithContext(ctx)
+
+	var schedules []entity.CrawlSchedule
+	if err := u.ScheduleRepository.FindAll(ctx, db, &schedules); err != nil {
+		u.Log.WithError(err).Error("error fetching schedules")
+		return
+	}
+
+	for _, schedule := range schedules {
+		if schedule.NextRunAt.After(now) {
+			continue
+		}
+		cron, err := service.ParseCron(schedule.Expression)
+		if err != nil {
+			u.Log.WithError(err).WithField("schedule", schedule.Name).Error("Invalid schedule, not running it")
+			continue
+		}
+
+		// The next run is stored before starting, so a restart does not run it again
+		next := cron.Next(now.In(u.Blackouts.Location()))
+		claimed, err := u.ScheduleRepository.Claim(ctx, db, schedule.Name, schedule.NextRunAt, next, now, service.StatusRunning)
+		if err != nil {
+			u.Log.WithError(err).WithField("schedule", schedule.Name).Error("error claiming schedule")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		logger := u.Log.WithFields(logrus.Fields{"schedule": schedule.Name, "next_run": next})
+		if until, blackedOut := u.Blackouts.Until(now); blackedOut {
+			logger.WithField("until", until).Info("Blackout window, skipping scheduled run")
+			u.finish(db, schedule.Name, service.StatusSkipped, "blackout window")
+			continue
+		}
+		if !u.start(schedule.Name) {
+			logger.Warn("Previous run still in progress, skipping scheduled run")
+			u.finish(db, schedule.Name, service.StatusSkipped, "previous run still in progress")
+			continue
+		}
+
+		logger.Info("Starting scheduled run")
+		go func(name string) {
+			defer u.done(name)
+			if err := run(name); err != nil {
+				logger.WithError(err).Error("Scheduled run failed")
+				u.finish(db, name, service.StatusFailed, err.Error())
+				return
+			}
+			u.finish(db, name, service.StatusSucceeded, "")
+		}(schedule.Name)
+	}
+}
+
+// start marks a schedule as running, unless it already is
+func (u *ScheduleUsecase) start(name string) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.running[name] {
+		return false
+	}
+	u.running[name] = true
+	return true
+}
+
+func (u *ScheduleUsecase) done(name string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	delete(u.running, name)
+}
+
+func (u *ScheduleUsecase) finish(db *gorm.DB, name string, status string, message string) {
+	err := db.Model(&entity.CrawlSchedule{}).Where("name = ?", name).
+		Updates(map[string]interface{}{"laststatus": status, "lasterror": message}).Error
+	if err != nil {
+		u.Log.WithError(err).WithField("schedule", name).Error("error recording scheduled run")
+	}
+}
+
+func (u *ScheduleUsecase) toResponse(schedule *entity.CrawlSchedule) model.CrawlScheduleResponse {
+	location := u.Blackouts.Location()
+	response := model.CrawlScheduleResponse{
+		Name:       schedule.Name,
+		Expression: schedule.Expression,
+		NextRunAt:  schedule.NextRunAt.In(location),
+		LastStatus: schedule.LastStatus,
+		LastError:  schedule.LastError,
+	}
+	if schedule.LastRunAt != nil {
+		lastRunAt := schedule.LastRunAt.In(location)
+		response.LastRunAt = &lastRunAt
+	}
+	return response
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/spool"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Kinds of the batches buffered in the spool by BatchCreate, also the last token of the subjects
+// they are published on in scrape mode
+const (
+	spoolRepos    = "repos"
+	spoolReleases = "releases"
+	spoolCommits  = "commits"
+	spoolTags     = "tags"
+)
+
+// spoolCommitsMessage is the crawl error of the releases stored from the spool, whose commits
+// are still to crawl
+const spoolCommitsMessage = "commits not crawled: release was buffered while the database was unreachable"
+
+// SpoolUsecase replays the batches that the BatchCreate usecases buffered in the spool while
+// the database was unreachable, once it can be reached again. Buffered batches are stored as if
+// they had just been crawled, so rows crawled again meanwhile are not duplicated.
+type SpoolUsecase struct {
+	DB             *gorm.DB
+	Log            *logrus.Logger
+	Spool          *spool.Spool
+	RepoUsecase    *RepoUsecase
+	ReleaseUsecase *ReleaseUsecase
+	CommitUsecase  *CommitUsecase
+	TagUsecase     *TagUsecase
+}
+
+func NewSpoolUsecase(db *gorm.DB, log *logrus.Logger, spool *spool.Spool, repoUsecase *RepoUsecase,
+	releaseUsecase *ReleaseUsecase, commitUsecase *CommitUsecase, tagUsecase *TagUsecase) *SpoolUsecase {
+	return &SpoolUsecase{
+		DB:             db,
+		Log:            log,
+		Spool:          spool,
+		RepoUsecase:    repoUsecase,
+		ReleaseUsecase: releaseUsecase,
+		CommitUsecase:  commitUsecase,
+		TagUsecase:     tagUsecase,
+	}
+}
+
+// Replay stores the buffered batches if the database answers, and returns how many were stored
+func (u *SpoolUsecase) Replay(ctx context.Context) (int, error) {
+	if batches, _ := u.Spool.Pending(); batches == 0 {
+		return 0, nil
+	}
+	sqlDB, err := u.DB.DB()
+	if err != nil {
+		return 0, err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return 0, err
+	}
+
+	return u.Spool.Replay(func(kind string, payload json.RawMessage) error {
+		return u.store(ctx, kind, payload)
+	})
+}
+
+// store saves a buffered batch without buffering it again on failure
+func (u *SpoolUsecase) store(ctx context.Context, kind string, payload json.RawMessage) error {
+	var err error
+	switch kind {
+	case spoolRepos:
+		var requests []*model.CreateRepoRequest
+		if err = json.Unmarshal(payload, &requests); err == nil {
+			_, err = u.RepoUsecase.batchCreate(ctx, requests)
+		}
+	case spoolReleases:
+		var requests []*model.CreateReleaseRequest
+		if err = json.Unmarshal(payload, &requests); err == nil {
+			var responses []*model.ReleaseResponse
+			if responses, err = u.ReleaseUsecase.batchCreate(ctx, requests); err == nil {
+				u.markForCommits(ctx, responses)
+			}
+		}
+	case spoolCommits:
+		var requests []*model.CreateCommitRequest
+		if err = json.Unmarshal(payload, &requests); err == nil {
+			_, err = u.CommitUsecase.batchCreate(ctx, requests)
+		}
+	case spoolTags:
+		var requests []*model.CreateTagRequest
+		if err = json.Unmarshal(payload, &requests); err == nil {
+			_, err = u.TagUsecase.batchCreate(ctx, requests)
+		}
+	default:
+		err = fmt.Errorf("unknown batch kind %q", kind)
+	}
+	return err
+}
+
+// markForCommits records the replayed releases as crawl errors: the crawl that found them went
+// on without their IDs, so their commits were never crawled. Retrying the crawl errors crawls
+// them, which clears the records.
+func (u *SpoolUsecase) markForCommits(ctx context.Context, releases []*model.ReleaseResponse) {
+	crawlErrorRepository := repository.NewCrawlErrorRepository(u.Log)
+	now := time.Now()
+	for _, release := range releases {
+		record := &entity.CrawlError{
+			EntityType:    entity.CrawlErrorRelease,
+			EntityID:      release.ID,
+			Message:       spoolCommitsMessage,
+			FirstFailedAt: now,
+			LastFailedAt:  now,
+		}
+		if err := crawlErrorRepository.Record(ctx, u.DB, record); err != nil {
+			u.Log.WithError(err).WithField("release_id", release.ID).Error("Error marking replayed release for commit crawl")
+		}
+	}
+}
+
+// StartReplaying tries to replay the spool every interval until stopChan is closed. A batch
+// the database rejects for another reason than being unreachable is moved to the quarantine
+// subdirectory of the spool and logged, the ones after it are still replayed.
+func (u *SpoolUsecase) StartReplaying(interval time.Duration, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			replayed, err := u.Replay(context.Background())
+			batches, size := u.Spool.Pending()
+			logger := u.Log.WithFields(logrus.Fields{
+				"replayed":      replayed,
+				"pending":       batches,
+				"pending_bytes": size,
+			})
+			switch {
+			case err != nil && spool.Unavailable(err):
+				logger.WithError(err).Warn("Database still unreachable, keeping buffered crawl results")
+			case err != nil:
+				logger.WithError(err).Error("Error replaying buffered crawl results, rejected batches quarantined")
+			case replayed > 0:
+				logger.Info("Replayed buffered crawl results")
+			}
+		case <-stopChan:
+			u.Log.Info("Stopping spool replay")
+			return
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// summaryBatchSize is how many rows Summary reads at a time when it hashes whole rows
+const summaryBatchSize = 1000
+
+type StatsUsecase struct {
+	DB  *gorm.DB
+	Log *logrus.Logger
+}
+
+func NewStatsUsecase(db *gorm.DB, log *logrus.Logger) *StatsUsecase {
+	return &StatsUsecase{
+		DB:  db,
+		Log: log,
+	}
+}
+
+// Stats aggregates row counts, coverage gaps, the top repositories by release count,
+// the latest crawl times and the database size. Each section is one aggregate query.
+func (u *StatsUsecase) Stats(ctx context.Context, top int) (*model.StatsResponse, error) {
+	db := u.DB.WithContext(ctx)
+	stats := &model.StatsResponse{}
+
+	var counts struct {
+		Repos                  int64
+		Releases               int64
+		Commits                int64
+		Tags                   int64
+		ReposWithoutReleases   int64
+		ReleasesWithoutCommits int64
+	}
+	if err := db.Raw(`SELECT
+		(SELECT COUNT(*) FROM repositories) AS repos,
+		(SELECT COUNT(*) FROM releases) AS releases,
+		(SELECT COUNT(*) FROM commits) AS commits,
+		(SELECT COUNT(*) FROM tags) AS tags,
+		(SELECT COUNT(*) FROM repositories r
+			WHERE NOT EXISTS (SELECT 1 FROM releases rl WHERE rl.repoid = r.id)) AS repos_without_releases,
+		(SELECT COUNT(*) FROM releases rl
+			WHERE NOT EXISTS (SELECT 1 FROM commits c WHERE c.releaseid = rl.id)) AS releases_without_commits`).
+		Scan(&counts).Error; err != nil {
+		u.Log.WithError(err).Error("error counting rows")
+		return nil, err
+	}
+	stats.Repos = counts.Repos
+	stats.Releases = counts.Releases
+	stats.Commits = counts.Commits
+	stats.Tags = counts.Tags
+	stats.ReposWithoutReleases = counts.ReposWithoutReleases
+	stats.ReleasesWithoutCommits = counts.ReleasesWithoutCommits
+
+	stats.TopReposByReleases = make([]model.RepoReleaseCount, 0, top)
+	if err := db.Raw(`SELECT r.id AS repo_id, r.username || '/' || r.reponame AS repo, COUNT(rl.id) AS releases
+		FROM repositories r
+		JOIN releases rl ON rl.repoid = r.id
+		GROUP BY r.id, r.username, r.reponame
+		ORDER BY releases DESC, r.id
+		LIMIT ?`, top).
+		Scan(&stats.TopReposByReleases).Error; err != nil {
+		u.Log.WithError(err).Error("error counting releases per repository")
+		return nil, err
+	}
+
+	var crawlTimes struct {
+		Release  repository.AggregateTime
+		Enriched repository.AggregateTime
+		Visit    repository.AggregateTime
+	}
+	if err := db.Raw(`SELECT
+		(SELECT MAX(createdat) FROM releases) AS release,
+		(SELECT MAX(enrichedat) FROM repositories) AS enriched,
+		(SELECT MAX(visitedat) FROM visits) AS visit`).
+		Scan(&crawlTimes).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching last crawl times")
+		return nil, err
+	}
+	stats.LastCrawl = model.CrawlTimes{
+		Release:  crawlTimes.Release.Ptr(),
+		Enriched: crawlTimes.Enriched.Ptr(),
+		Visit:    crawlTimes.Visit.Ptr(),
+	}
+
+	// Sizes come from the PostgreSQL catalog and are left empty on SQLite
+	stats.Database.Tables = make([]model.TableSize, 0)
+	if u.DB.Dialector.Name() != "postgres" {
+		return stats, nil
+	}
+
+	if err := db.Raw("SELECT pg_database_size(current_database())").
+		Scan(&stats.Database.TotalBytes).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching database size")
+		return nil, err
+	}
+
+	if err := db.Raw(`SELECT c.relname AS name, pg_total_relation_size(c.oid) AS bytes,
+			GREATEST(c.reltuples, 0)::bigint AS estimated_rows
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relkind = 'r'
+		ORDER BY bytes DESC`).
+		Scan(&stats.Database.Tables).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching table sizes")
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Datasets with a summary
+const (
+	DatasetRepos    = "repos"
+	DatasetReleases = "releases"
+	DatasetCommits  = "commits"
+)
+
+// Summary fingerprints a dataset: its row count, a checksum that changes whenever rows are
+// added or replaced, and for releases and commits the per-repository counts and latest tag
+func (u *StatsUsecase) Summary(ctx context.Context, dataset string) (*model.DatasetSummary, error) {
+	db := u.DB.WithContext(ctx)
+	summary := &model.DatasetSummary{Dataset: dataset}
+
+	if dataset == DatasetRepos {
+		// Repositories are hashed in ID order a batch at a time, archived ones included
+		hash := sha256.New()
+		repoRepository := repository.NewRepoRepository(u.Log)
+		err := repoRepository.FindInBatches(ctx, db.Unscoped().Select("id", "username", "reponame"), summaryBatchSize,
+			func(repos []entity.Repository) error {
+				for _, repo := range repos {
+					fmt.Fprintf(hash, "%d:%s/%s\n", repo.ID, repo.UserName, repo.RepoName)
+				}
+				summary.Count += int64(len(repos))
+				return nil
+			})
+		if err != nil {
+			u.Log.WithError(err).Error("error summarizing repositories")
+			return nil, err
+		}
+		summary.Checksum = hex.EncodeToString(hash.Sum(nil))
+		return summary, nil
+	}
+
+	var query string
+	switch dataset {
+	case DatasetReleases:
+		// Plain SQL so the summary also works on the embedded SQLite database
+		query = `SELECT rl.repoid AS repo_id, COUNT(*) AS count, MAX(rl.id) AS max_id,
+			(SELECT l.tagname FROM releases l WHERE l.repoid = rl.repoid
+				ORDER BY COALESCE(l.publishedat, l.createdat) DESC, l.id DESC LIMIT 1) AS latest_tag
+			FROM releases rl
+			GROUP BY rl.repoid
+			ORDER BY rl.repoid`
+	case DatasetCommits:
+		query = `SELECT rl.repoid AS repo_id, COUNT(c.id) AS count, MAX(c.id) AS max_id, '' AS latest_tag
+			FROM commits c
+			JOIN releases rl ON rl.id = c.releaseid
+			GROUP BY rl.repoid
+			ORDER BY rl.repoid`
+	default:
+		return nil, fmt.Errorf("unknown dataset %s", dataset)
+	}
+
+	var rows []struct {
+		RepoID    int64
+		Count     int64
+		MaxID     int64
+		LatestTag string
+	}
+	if err := db.Raw(query).Scan(&rows).Error; err != nil {
+		u.Log.WithError(err).WithField("dataset", dataset).Error("error summarizing dataset")
+		return nil, err
+	}
+
+	// Rows are ordered by repository, so the checksum is stable between calls
+	hash := sha256.New()
+	summary.Repos = make(map[int64]model.RepoSummary, len(rows))
+	for _, row := range rows {
+		fmt.Fprintf(hash, "%d:%d:%d:%s\n", row.RepoID, row.Count, row.MaxID, row.LatestTag)
+		summary.Count += row.Count
+		summary.Repos[row.RepoID] = model.RepoSummary{Count: row.Count, LatestTag: row.LatestTag}
+	}
+	summary.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	return summary, nil
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/idgen"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"crawler/baseline/internal/spool"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TagUsecase struct {
+	DB            *gorm.DB
+	Log           *logrus.Logger
+	TagRepository *repository.TagRepository
+	Spool         *spool.Spool
+	// IDs generates the keys of new tags; nil leaves them to the database
+	IDs idgen.Generator
+	// RowByRow stores the tags of a batch create one per transaction, when features.batching is off
+	RowByRow bool
+	// Items sends the batch creates to the persist processes in scrape mode; nil stores them
+	Items *ItemBus
+}
+
+func NewTagUsecase(db *gorm.DB, log *logrus.Logger,
+	tagRepo *repository.TagRepository) *TagUsecase {
+	return &TagUsecase{
+		DB:            db,
+		Log:           log,
+		TagRepository: tagRepo,
+	}
+}
+
+// BatchCreate stores the tags not stored yet for their repository; stored ones are returned
+// as they are and marked Existing
+func (r *TagUsecase) BatchCreate(ctx context.Context, requests []*model.CreateTagRequest) ([]*model.TagResponse, error) {
+	if r.Items != nil {
+		return r.publish(ctx, requests)
+	}
+	responses, err := r.batchCreate(ctx, requests)
+	if r.Spool.Hold(err, spoolTags, requests) {
+		r.Log.WithField("count", len(requests)).Warn("Database unreachable, tags buffered in the spool")
+		return []*model.TagResponse{}, nil
+	}
+	return responses, err
+}
+
+func (r *TagUsecase) batchCreate(ctx context.Context, requests []*model.CreateTagRequest) ([]*model.TagResponse, error) {
+	if len(requests) == 0 {
+		return []*model.TagResponse{}, nil
+	}
+
+	tags := r.newEntities(requests)
+	existing, err := createMissing(ctx, r.DB, r.RowByRow, tags, tagKey,
+		r.TagRepository.FindStored, r.TagRepository.CreateMissing, nil)
+	if err != nil {
+		r.Log.WithError(err).Error("error batch creating tags")
+		return nil, err
+	}
+
+	return tagResponses(tags, existing), nil
+}
+
+// publish sends the tags not stored yet to a persist process, which stores them, and returns
+// them all as batchCreate does
+func (r *TagUsecase) publish(ctx context.Context, requests []*model.CreateTagRequest) ([]*model.TagResponse, error) {
+	tags := r.newEntities(requests)
+	existing, err := publishMissing(ctx, r.DB, r.Items, spoolTags, requests, tags, tagKey,
+		func(request *model.CreateTagRequest, tag *entity.Tag) { request.ID = tag.ID },
+		r.TagRepository.FindStored)
+	if err != nil {
+		return nil, err
+	}
+	return tagResponses(tags, existing), nil
+}
+
+// newEntities builds the tag entities of a batch
+func (r *TagUsecase) newEntities(requests []*model.CreateTagRequest) []entity.Tag {
+	tags := make([]entity.Tag, len(requests))
+	for i, req := range requests {
+		tags[i] = entity.Tag{
+			ID:        nextID(req.ID, r.IDs),
+			Name:      req.Name,
+			CommitSHA: req.CommitSHA,
+			RepoID:    req.RepoID,
+		}
+	}
+	return tags
+}
+
+// tagResponses converts the tags of a batch, with the stored IDs, to response models
+func tagResponses(tags []entity.Tag, existing []bool) []*model.TagResponse {
+	responses := make([]*model.TagResponse, len(tags))
+	for i, tag := range tags {
+		responses[i] = &model.TagResponse{
+			ID:        tag.ID,
+			Name:      tag.Name,
+			CommitSHA: tag.CommitSHA,
+			RepoID:    tag.RepoID,
+			Existing:  existing[i],
+		}
+	}
+	return responses
+}
+
+// tagKey identifies a tag like the unique index on its repository and name
+func tagKey(tag *entity.Tag) string {
+	return fmt.Sprintf("%d/%s", tag.RepoID, tag.Name)
+}
@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"crawler/baseline/internal/apperrors"
+	"crawler/baseline/internal/entity"
+	"crawler/baseline/internal/model"
+	"crawler/baseline/internal/repository"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type WatchlistUsecase struct {
+	DB                  *gorm.DB
+	Log                 *logrus.Logger
+	WatchlistRepository *repository.WatchlistRepository
+	RepoRepository      *repository.RepoRepository
+}
+
+func NewWatchlistUsecase(db *gorm.DB, log *logrus.Logger,
+	watchlistRepo *repository.WatchlistRepository, repoRepo *repository.RepoRepository) *WatchlistUsecase {
+	return &WatchlistUsecase{
+		DB:                  db,
+		Log:                 log,
+		WatchlistRepository: watchlistRepo,
+		RepoRepository:      repoRepo,
+	}
+}
+
+// Create stores a watchlist of tracked repositories.
+// An unknown repository fails with an error wrapping apperrors.ErrInvalid.
+func (u *WatchlistUsecase) Create(ctx context.Context, request *model.CreateWatchlistRequest) (*model.WatchlistResponse, error) {
+	tx := u.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	watchlist := &entity.Watchlist{Name: request.Name}
+	for _, ref := range request.Repos {
+		repo := entity.Repository{}
+		var err error
+		if id, parseErr := strconv.ParseInt(ref, 10, 64); parseErr == nil {
+			err = u.RepoRepository.FindById(ctx, tx, &repo, id)
+		} else if owner, name, ok := strings.Cut(ref, "/"); ok {
+			err = u.RepoRepository.FindByName(ctx, tx, &repo, owner, name)
+		} else {
+			err = gorm.ErrRecordNotFound
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: unknown repository %s", apperrors.ErrInvalid, ref)
+		}
+		if err != nil {
+			u.Log.WithError(err).WithField("repo", ref).Error("error looking up watchlist repository")
+			return nil, err
+		}
+		watchlist.Repositories = append(watchlist.Repositories, repo)
+	}
+
+	if err := u.WatchlistRepository.Create(ctx, tx, watchlist); err != nil {
+		u.Log.WithError(err).Error("error creating watchlist")
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		u.Log.WithError(err).Error("error committing transaction")
+		return nil, err
+	}
+	return WatchlistToResponse(watchlist), nil
+}
+
+func (u *WatchlistUsecase) Get(ctx context.Context, id int64) (*model.WatchlistResponse, error) {
+	watchlist := &entity.Watchlist{}
+	if err := u.WatchlistRepository.FindWithRepos(ctx, u.DB, watchlist, id); err != nil {
+		return nil, err
+	}
+	return WatchlistToResponse(watchlist), nil
+}
+
+func (u *WatchlistUsecase) List(ctx context.Context) ([]*model.WatchlistResponse, error) {
+	var watchlists []entity.Watchlist
+	if err := u.DB.WithContext(ctx).Preload("Repositories").Order("id").Find(&watchlists).Error; err != nil {
+		u.Log.WithError(err).Error("error fetching watchlists")
+		return nil, err
+	}
+
+	responses := make([]*model.WatchlistResponse, len(watchlists))
+	for i := range watchlists {
+		responses[i] = WatchlistToResponse(&watchlists[i])
+	}
+	return responses, nil
+}
+
+func WatchlistToResponse(watchlist *entity.Watchlist) *model.WatchlistResponse {
+	response := &model.WatchlistResponse{
+		ID:    watchlist.ID,
+		Name:  watchlist.Name,
+		Repos: make([]*model.RepoResponse, 0, len(watchlist.Repositories)),
+	}
+	for i := range watchlist.Repositories {
+		response.Repos = append(response.Repos, RepoToResponse(&watchlist.Repositories[i]))
+	}
+	return response
+}
@@ -6,16 +6,38 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
 	"github.com/sirupsen/logrus"
 )
 
 var baseURL = "https://github.com"
 
+var commitCountPattern = regexp.MustCompile(`([\d,]+)\s+commits?`)
+
 func GetRepoURL(repo string) string {
 	return baseURL + "repos/" + repo
 }
 
+// ParseRepoURL extracts the owner and name from a GitHub repository URL such as
+// "https://github.com/opencv/opencv" or from a plain "opencv/opencv"
+func ParseRepoURL(rawURL string) (string, string, error) {
+	path := strings.TrimSpace(rawURL)
+	for _, prefix := range []string{"https://", "http://", "www.", "github.com/"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	if strings.Contains(path, "://") {
+		return "", "", fmt.Errorf("not a GitHub repository URL: %s", rawURL)
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("not a GitHub repository URL: %s", rawURL)
+	}
+
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), nil
+}
+
 func GetNumRelease(repoOwner string, repoName string) int {
 	repoURL := baseURL + "/" + repoOwner + "/" + repoName
 
@@ -24,10 +46,10 @@ func GetNumRelease(repoOwner string, repoName string) int {
 	numRelease := 0
 
 	c.OnRequest(func(r *colly.Request) {
-		// fmt.Println("Visiting", r.URL)
+		logrus.Debug("Visiting: ", r.URL)
 	})
 
-	c.OnHTML("a.Link--primary.no-underline.Link", func(e *colly.HTMLElement) {
+	OnHTML(c, "release_count_link", func(e *colly.HTMLElement) {
 		text := e.Text
 		if strings.Contains(text, "Releases") {
 			// fmt.Println("Text:", text)
@@ -46,17 +68,20 @@ func GetNumRelease(repoOwner string, repoName string) int {
 	return numRelease
 }
 
-func GetReleaseTags(owner string, repo string, numRelease int) []string {
-	log := logrus.New()
+// GetReleaseTags walks the releases pages of a repository with c until numRelease tags are found
+// or a page adds none. A page that cannot be fetched ends the walk with its error, returned with
+// the tags found before it, which are then not the complete listing.
+func GetReleaseTags(c *colly.Collector, owner string, repo string, numRelease int) ([]string, error) {
+	log := logrus.StandardLogger()
 	releaseURL := baseURL + "/" + owner + "/" + repo + "/releases"
 
-	c := colly.NewCollector()
-
-	c.OnRequest(func(r *colly.Request) {
-	})
 	tags := make([]string, 0, numRelease)
+	var pageErr error
+	c.OnError(func(r *colly.Response, err error) {
+		pageErr = fmt.Errorf("fetching %s: status %d: %w", r.Request.URL, r.StatusCode, err)
+	})
 
-	c.OnHTML("a.Link--primary.Link", func(e *colly.HTMLElement) {
+	OnHTML(c, "release_tag_link", func(e *colly.HTMLElement) {
 		tagHref := strings.Split(e.Attr("href"), "/")
 		tag := tagHref[len(tagHref)-1]
 		tags = append(tags, tag)
@@ -69,15 +94,23 @@ func GetReleaseTags(owner string, repo string, numRelease int) []string {
 			break
 		}
 		visitURL := releaseURL + "?page=" + strconv.Itoa(currentPage)
+		found := len(tags)
 		if err := c.Visit(visitURL); err != nil {
-			log.WithError(err).Errorf("Error visiting %s: %v", visitURL, err)
+			log.WithError(err).Errorf("Error visiting %s", visitURL)
+			return tags, fmt.Errorf("visiting %s: %w", visitURL, err)
+		}
+		c.Wait()
+		if pageErr != nil {
+			log.WithError(pageErr).Errorf("Error fetching release tags of %s/%s", owner, repo)
+			return tags, pageErr
+		}
+		if len(tags) == found {
 			break
-
 		}
 		currentPage++
 	}
 
-	return tags
+	return tags, nil
 }
 
 func GetReleaseURLs(repo string, tags []string) []string {
@@ -92,9 +125,10 @@ func GetCommitURLs(repo string, sha string) string {
 	return baseURL + "repos/" + repo + "/commits/" + sha
 }
 
-func GetNumCommitRelease(releaseURL string) int {
-	log := logrus.New()
-	c := colly.NewCollector()
+// GetNumCommitRelease reads the commit count of a release page using c, which callers
+// can give their own error callbacks to find out why the page could not be fetched
+func GetNumCommitRelease(c *colly.Collector, releaseURL string) int {
+	log := logrus.StandardLogger()
 
 	c.OnRequest(func(r *colly.Request) {
 		log.Debug("Visiting release URL: ", r.URL)
@@ -102,63 +136,27 @@ func GetNumCommitRelease(releaseURL string) int {
 
 	numCommits := 0
 
-	// Try multiple selectors since GitHub's HTML structure may vary
-	c.OnHTML("div.d-flex.flex-row.flex-wrap.color-fg-muted.flex-items-end", func(e *colly.HTMLElement) {
-		text := e.Text
-		log.Debug("Found commit info text: ", text)
-
-		// Look for patterns like "123 commits" or "1,234 commits"
-		re := regexp.MustCompile(`([\d,]+)\s+commits?`)
-		match := re.FindStringSubmatch(text)
-
-		// Only proceed if we found a match
-		if len(match) > 1 {
-			// Remove commas from numbers like "1,234"
-			commitStr := strings.ReplaceAll(match[1], ",", "")
-			count, err := strconv.Atoi(commitStr)
-			if err == nil {
-				numCommits = count
-				log.Infof("Found %d commits in release", numCommits)
-			}
-		}
-	})
-
-	// Alternative selector for commit count
-	c.OnHTML("span.d-none.d-sm-inline", func(e *colly.HTMLElement) {
-		text := e.Text
-		if strings.Contains(text, "commit") {
-			log.Debug("Found alternate commit info: ", text)
-
-			re := regexp.MustCompile(`([\d,]+)\s+commits?`)
-			match := re.FindStringSubmatch(text)
-
-			if len(match) > 1 {
-				commitStr := strings.ReplaceAll(match[1], ",", "")
-				count, err := strconv.Atoi(commitStr)
-				if err == nil && count > numCommits {
-					numCommits = count
-					log.Infof("Found %d commits using alternative selector", numCommits)
+	// The chain lists the places GitHub has shown the count in; the first one holding it is used
+	c.OnHTML("html", func(page *colly.HTMLElement) {
+		for _, selector := range SelectorChain("release_commit_count") {
+			page.DOM.Find(selector).Each(func(_ int, s *goquery.Selection) {
+				text := s.Text()
+				log.Debugf("Found commit info text with %s: %s", selector, text)
+
+				// Look for patterns like "123 commits" or "1,234 commits"
+				match := commitCountPattern.FindStringSubmatch(text)
+				if len(match) < 2 {
+					return
 				}
-			}
-		}
-	})
-
-	// Try one more selector for commit comparison page
-	c.OnHTML("div.Box-header span.text-emphasized", func(e *colly.HTMLElement) {
-		text := e.Text
-		if strings.Contains(text, "Showing") || strings.Contains(text, "commit") {
-			log.Debug("Found header commit info: ", text)
-
-			re := regexp.MustCompile(`([\d,]+)`)
-			match := re.FindString(text)
-
-			if match != "" {
-				commitStr := strings.ReplaceAll(match, ",", "")
-				count, err := strconv.Atoi(commitStr)
+				// Remove commas from numbers like "1,234"
+				count, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
 				if err == nil && count > numCommits {
 					numCommits = count
-					log.Infof("Found %d commits using Box-header selector", numCommits)
 				}
+			})
+			if numCommits > 0 {
+				log.Infof("Found %d commits in release with %s", numCommits, selector)
+				return
 			}
 		}
 	})
@@ -173,6 +171,7 @@ func GetNumCommitRelease(releaseURL string) int {
 	if err := c.Visit(releaseURL); err != nil {
 		log.WithError(err).Errorf("Error visiting %s", releaseURL)
 	}
+	c.Wait()
 
 	return numCommits
 }
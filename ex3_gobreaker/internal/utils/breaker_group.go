@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// BreakerGroupStats counts the breakers of a group by state
+type BreakerGroupStats struct {
+	Breakers int `json:"breakers"`
+	Closed   int `json:"closed"`
+	HalfOpen int `json:"halfOpen"`
+	Open     int `json:"open"`
+	// OpenKeys lists the keys whose breaker is open, sorted
+	OpenKeys []string `json:"openKeys"`
+}
+
+// BreakerGroup keeps a circuit breaker per key, such as an owner/repo, created on first use, so
+// a key failing on every call is rejected without tripping the others. Breakers not used for
+// idleTTL are dropped and start closed when their key comes back. A nil BreakerGroup protects nothing.
+type BreakerGroup struct {
+	name          string
+	idleTTL       time.Duration
+	onStateChange func(name string, from string, to string)
+
+	mutex     sync.Mutex
+	settings  BreakerSettings
+	breakers  map[string]*groupBreaker
+	lastSweep time.Time
+}
+
+type groupBreaker struct {
+	cb       *CircuitBreakerWrapper
+	lastUsed time.Time
+}
+
+// NewBreakerGroup creates an empty group whose breakers are named "<name>:<key>".
+// onStateChange, if not nil, is called on every transition of any breaker of the group.
+func NewBreakerGroup(name string, settings BreakerSettings, idleTTL time.Duration,
+	onStateChange func(name string, from string, to string)) *BreakerGroup {
+	return &BreakerGroup{
+		name:          name,
+		idleTTL:       idleTTL,
+		onStateChange: onStateChange,
+		settings:      settings,
+		breakers:      make(map[string]*groupBreaker),
+		lastSweep:     time.Now(),
+	}
+}
+
+// Get returns the breaker of key, creating it when the key has none
+func (g *BreakerGroup) Get(key string) *CircuitBreakerWrapper {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := time.Now()
+	g.sweep(now)
+	breaker, ok := g.breakers[key]
+	if !ok {
+		cb := NewCircuitBreaker(g.name+":"+key, g.onStateChange)
+		cb.Configure(g.settings)
+		breaker = &groupBreaker{cb: cb}
+		g.breakers[key] = breaker
+	}
+	breaker.lastUsed = now
+	return breaker.cb
+}
+
+// Execute runs fn with the protection of key's breaker, as CircuitBreakerWrapper.ExecuteContext does
+func (g *BreakerGroup) Execute(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	if g == nil {
+		return fn()
+	}
+	return g.Get(key).ExecuteContext(ctx, fn)
+}
+
+// ExecuteHedged runs fn with the protection of key's breaker, as CircuitBreakerWrapper.ExecuteHedged does
+func (g *BreakerGroup) ExecuteHedged(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if g == nil {
+		return fn(ctx)
+	}
+	return g.Get(key).ExecuteHedged(ctx, fn)
+}
+
+// Configure replaces every breaker of the group with one using settings, as
+// CircuitBreakerWrapper.Configure does; breakers created later use them too
+func (g *BreakerGroup) Configure(settings BreakerSettings) {
+	if g == nil {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.settings = settings
+	for _, breaker := range g.breakers {
+		breaker.cb.Configure(settings)
+	}
+}
+
+// Stats counts the breakers of the group by state
+func (g *BreakerGroup) Stats() BreakerGroupStats {
+	stats := BreakerGroupStats{OpenKeys: []string{}}
+	if g == nil {
+		return stats
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.sweep(time.Now())
+	stats.Breakers = len(g.breakers)
+	for key, breaker := range g.breakers {
+		switch breaker.cb.State() {
+		case gobreaker.StateOpen.String():
+			stats.Open++
+			stats.OpenKeys = append(stats.OpenKeys, key)
+		case gobreaker.StateHalfOpen.String():
+			stats.HalfOpen++
+		default:
+			stats.Closed++
+		}
+	}
+	sort.Strings(stats.OpenKeys)
+	return stats
+}
+
+// sweep drops the breakers idle for idleTTL, at most once per idleTTL; the caller must hold mutex
+func (g *BreakerGroup) sweep(now time.Time) {
+	if g.idleTTL <= 0 || now.Sub(g.lastSweep) < g.idleTTL {
+		return
+	}
+	g.lastSweep = now
+	for key, breaker := range g.breakers {
+		if now.Sub(breaker.lastUsed) >= g.idleTTL {
+			delete(g.breakers, key)
+		}
+	}
+}
+
+// BreakerRejected reports whether err is a call rejected by an open or half-open breaker,
+// rather than an error of the call itself
+func BreakerRejected(err error) bool {
+	return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)
+}
@@ -1,35 +1,244 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sony/gobreaker"
 )
 
+// BreakerSettings tune a circuit breaker; a zero value keeps the default of its field
+type BreakerSettings struct {
+	// MaxRequests is how many calls a half-open breaker lets through, 3 by default
+	MaxRequests uint32 `mapstructure:"max_requests" json:"max_requests"`
+	// Interval is how often a closed breaker clears its counts, 10s by default
+	Interval time.Duration `mapstructure:"interval" json:"interval"`
+	// Timeout is how long an open breaker rejects calls before half-opening, 30s by default
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout"`
+	// MinRequests is how many calls an interval needs before the breaker may trip, 3 by default
+	MinRequests uint32 `mapstructure:"min_requests" json:"min_requests"`
+	// FailureRatio is the share of failed calls that trips the breaker, 0.6 by default
+	FailureRatio float64 `mapstructure:"failure_ratio" json:"failure_ratio"`
+	// Retry retries transient failures within one call of the breaker, so they only count
+	// toward tripping once every attempt failed
+	Retry RetrySettings `mapstructure:"retry" json:"retry"`
+	// HedgeAfter sends the GET requests of an ExecuteHedged call a second time when they have no
+	// response after it, see scrape.HedgeTransport; 0 leaves them as the caller hedges them
+	HedgeAfter time.Duration `mapstructure:"hedge_after" json:"hedge_after"`
+}
+
+// RetrySettings tune the retries of a circuit breaker; a zero value makes one attempt
+type RetrySettings struct {
+	// Attempts is how many times a call is tried in total, 1 by default
+	Attempts int `mapstructure:"attempts" json:"attempts"`
+	// Backoff is the wait before the second attempt, doubled before each next one, 500ms by default
+	Backoff time.Duration `mapstructure:"backoff" json:"backoff"`
+	// MaxBackoff caps the wait between attempts, 10s by default
+	MaxBackoff time.Duration `mapstructure:"max_backoff" json:"max_backoff"`
+}
+
+// Validate rejects negative durations and counts and a failure ratio outside 0 to 1
+func (s BreakerSettings) Validate() error {
+	if s.Interval < 0 || s.Timeout < 0 || s.HedgeAfter < 0 || s.Retry.Backoff < 0 || s.Retry.MaxBackoff < 0 {
+		return errors.New("durations must not be negative")
+	}
+	if s.Retry.Attempts < 0 {
+		return errors.New("retry.attempts must not be negative")
+	}
+	if s.FailureRatio < 0 || s.FailureRatio > 1 {
+		return fmt.Errorf("failure_ratio %v is not from 0 to 1", s.FailureRatio)
+	}
+	return nil
+}
+
+// withDefaults fills in the fields left zero
+func (s BreakerSettings) withDefaults() BreakerSettings {
+	if s.MaxRequests == 0 {
+		s.MaxRequests = 3
+	}
+	if s.Interval == 0 {
+		s.Interval = 10 * time.Second
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 30 * time.Second
+	}
+	if s.MinRequests == 0 {
+		s.MinRequests = 3
+	}
+	if s.FailureRatio == 0 {
+		s.FailureRatio = 0.6
+	}
+	if s.Retry.Attempts <= 0 {
+		s.Retry.Attempts = 1
+	}
+	if s.Retry.Backoff == 0 {
+		s.Retry.Backoff = 500 * time.Millisecond
+	}
+	if s.Retry.MaxBackoff == 0 {
+		s.Retry.MaxBackoff = 10 * time.Second
+	}
+	return s
+}
+
 // CircuitBreakerWrapper wraps API calls with circuit breaker functionality
 type CircuitBreakerWrapper struct {
-	cb *gobreaker.CircuitBreaker
+	name          string
+	onStateChange func(name string, from string, to string)
+
+	mutex    sync.RWMutex
+	cb       *gobreaker.CircuitBreaker
+	settings BreakerSettings
+}
+
+// NewCircuitBreaker creates a new circuit breaker with specified settings.
+// onStateChange, if not nil, is called with the old and new state names on every transition.
+func NewCircuitBreaker(name string, onStateChange func(name string, from string, to string)) *CircuitBreakerWrapper {
+	cbw := &CircuitBreakerWrapper{name: name, onStateChange: onStateChange}
+	cbw.Configure(BreakerSettings{})
+	return cbw
 }
 
-// NewCircuitBreaker creates a new circuit breaker with specified settings
-func NewCircuitBreaker(name string) *CircuitBreakerWrapper {
-	settings := gobreaker.Settings{
-		Name:        name,
-		MaxRequests: 3,
-		Interval:    10 * time.Second,
-		Timeout:     30 * time.Second,
+// Configure replaces the breaker with one using settings. The new breaker starts closed with
+// no counts; calls already running finish on the previous one.
+func (cbw *CircuitBreakerWrapper) Configure(settings BreakerSettings) {
+	settings = settings.withDefaults()
+	breakerSettings := gobreaker.Settings{
+		Name:        cbw.name,
+		MaxRequests: settings.MaxRequests,
+		Interval:    settings.Interval,
+		Timeout:     settings.Timeout,
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-			return counts.Requests >= 3 && failureRatio >= 0.6
+			return counts.Requests >= settings.MinRequests && failureRatio >= settings.FailureRatio
 		},
 	}
-
-	return &CircuitBreakerWrapper{
-		cb: gobreaker.NewCircuitBreaker(settings),
+	if cbw.onStateChange != nil {
+		breakerSettings.OnStateChange = func(name string, from gobreaker.State, to gobreaker.State) {
+			cbw.onStateChange(name, from.String(), to.String())
+		}
 	}
+
+	cbw.mutex.Lock()
+	cbw.cb = gobreaker.NewCircuitBreaker(breakerSettings)
+	cbw.settings = settings
+	cbw.mutex.Unlock()
 }
 
 // Execute executes the given function with circuit breaker protection
 func (cbw *CircuitBreakerWrapper) Execute(fn func() (interface{}, error)) (interface{}, error) {
-	return cbw.cb.Execute(fn)
+	return cbw.ExecuteContext(context.Background(), fn)
+}
+
+// ExecuteContext executes fn with circuit breaker protection, retrying its transient failures
+// as the retry settings allow. Cancelling ctx stops the retries.
+func (cbw *CircuitBreakerWrapper) ExecuteContext(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
+	cb, settings := cbw.current()
+	return cb.Execute(func() (interface{}, error) {
+		return retry(ctx, settings.Retry, fn)
+	})
+}
+
+// ExecuteHedged is ExecuteContext handing fn a ctx that hedges its slow GET requests after
+// HedgeAfter. Only the requests are sent twice, not fn, so fn may write; it must send its
+// requests with the ctx it is given.
+func (cbw *CircuitBreakerWrapper) ExecuteHedged(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	_, settings := cbw.current()
+	hedged := WithHedgeAfter(ctx, settings.HedgeAfter)
+	return cbw.ExecuteContext(ctx, func() (interface{}, error) {
+		return fn(hedged)
+	})
+}
+
+// Settings returns the settings of the breaker, defaults filled in
+func (cbw *CircuitBreakerWrapper) Settings() BreakerSettings {
+	_, settings := cbw.current()
+	return settings
+}
+
+// State returns the current state of the circuit breaker ("closed", "half-open" or "open")
+func (cbw *CircuitBreakerWrapper) State() string {
+	cb, _ := cbw.current()
+	return cb.State().String()
+}
+
+func (cbw *CircuitBreakerWrapper) current() (*gobreaker.CircuitBreaker, BreakerSettings) {
+	cbw.mutex.RLock()
+	defer cbw.mutex.RUnlock()
+	return cbw.cb, cbw.settings
+}
+
+type hedgeAfterKey struct{}
+
+// WithHedgeAfter returns ctx hedging its GET requests after after; 0 keeps the delay of ctx
+func WithHedgeAfter(ctx context.Context, after time.Duration) context.Context {
+	if after <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, hedgeAfterKey{}, after)
+}
+
+// HedgeAfter returns how long the requests sent with ctx wait for their response before they
+// are sent again, 0 when they are sent once
+func HedgeAfter(ctx context.Context) time.Duration {
+	after, _ := ctx.Value(hedgeAfterKey{}).(time.Duration)
+	return after
+}
+
+// retry calls fn until it succeeds, fails with an error that is not transient, or made
+// settings.Attempts attempts, waiting an exponential backoff between attempts
+func retry(ctx context.Context, settings RetrySettings, fn func() (interface{}, error)) (interface{}, error) {
+	backoff := settings.Backoff
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt >= settings.Attempts || !IsTransient(err) {
+			return result, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, settings.MaxBackoff)
+	}
+}
+
+// IsTransient reports whether err is worth retrying: a 5xx response, a timeout or a dropped
+// connection. Errors carrying their HTTP status report it through a StatusCode method.
+func IsTransient(err error) bool {
+	var status interface{ StatusCode() int }
+	if errors.As(err, &status) {
+		return status.StatusCode() >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// HTTPStatusError is a response of an unexpected status
+type HTTPStatusError struct {
+	Status int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("status %d", e.Status)
+}
+
+func (e *HTTPStatusError) StatusCode() int {
+	return e.Status
 }
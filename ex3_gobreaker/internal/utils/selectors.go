@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/gocolly/colly/v2"
+)
+
+// Selectors are the CSS selectors the scrapers read the pages with, keyed by data point. The
+// selectors of a data point are a fallback chain: the first one matching the page is used, so a
+// selector for new markup can be tried before the one GitHub used until then.
+type Selectors map[string][]string
+
+// defaultSelectors are the chains used for the data points the config does not override
+var defaultSelectors = Selectors{
+	// gitstar-ranking.com
+	"repo_ranking_item": {"a.list-group-item.paginated_item"},
+
+	// Repository front page
+	"repo_stars":           {"#repo-stars-counter-star"},
+	"repo_forks":           {"#repo-network-counter"},
+	"repo_sidebar_cell":    {"div.BorderGrid-cell"},
+	"repo_sidebar_heading": {"h2"},
+	"repo_description":     {"p.f4"},
+	"repo_topic":           {"a.topic-tag"},
+	"repo_license_link":    {"a.Link--muted"},
+	"repo_language":        {"span.color-fg-default.text-bold"},
+	"release_count_link":   {"a.Link--primary.no-underline.Link"},
+
+	// Releases and release pages
+	"release_tag_link":  {"a.Link--primary.Link"},
+	"release_notes_box": {"div.Box-body"},
+	"release_notes":     {"div.markdown-body.my-3", "div.markdown-body"},
+	"release_commit_count": {
+		"div.d-flex.flex-row.flex-wrap.color-fg-muted.flex-items-end",
+		"span.d-none.d-sm-inline",
+		"div.Box-header span.text-emphasized",
+	},
+
+	// Compare and commit pages
+	"commit_item":         {"div.TimelineItem-body"},
+	"commit_link":         {"p.mb-1 a.Link--primary"},
+	"commit_empty":        {"div.blankslate"},
+	"commit_count_header": {"div.Box-header span.text-emphasized"},
+	"commit_container":    {"div.js-navigation-container"},
+	"commit_stats":        {"#toc .toc-diff-stats"},
+
+	// Tags pages
+	"tag_row":             {"div.Box-row"},
+	"tag_name":            {"h2 a.Link--primary"},
+	"tag_commit_link":     {"a[href*='/commit/']"},
+	"tag_pagination_link": {"div.paginate-container a"},
+}
+
+var (
+	selectorsMutex sync.RWMutex
+	selectors      = defaultSelectors
+)
+
+// ValidateSelectors checks that overrides only name known data points, with chains of valid selectors
+func ValidateSelectors(overrides Selectors) error {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if _, ok := defaultSelectors[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown data point %q", name))
+			continue
+		}
+		if len(overrides[name]) == 0 {
+			errs = append(errs, fmt.Errorf("%s has no selector", name))
+		}
+		for _, selector := range overrides[name] {
+			if _, err := cascadia.Compile(selector); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid selector %q: %w", name, selector, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetSelectors replaces the chains of the data points in overrides and restores the default
+// chains of the others. It applies to the pages scraped from then on, also by running crawls.
+func SetSelectors(overrides Selectors) error {
+	if err := ValidateSelectors(overrides); err != nil {
+		return err
+	}
+
+	merged := make(Selectors, len(defaultSelectors))
+	for name, chain := range defaultSelectors {
+		merged[name] = chain
+	}
+	for name, chain := range overrides {
+		merged[name] = chain
+	}
+
+	selectorsMutex.Lock()
+	selectors = merged
+	selectorsMutex.Unlock()
+	return nil
+}
+
+// SelectorChain returns the selectors of a data point, in the order they are tried
+func SelectorChain(dataPoint string) []string {
+	selectorsMutex.RLock()
+	defer selectorsMutex.RUnlock()
+	chain, ok := selectors[dataPoint]
+	if !ok {
+		panic("unknown selector data point " + dataPoint)
+	}
+	return chain
+}
+
+// OnHTML registers fn on the elements of each page matched by a data point, like c.OnHTML
+func OnHTML(c *colly.Collector, dataPoint string, fn colly.HTMLCallback) {
+	c.OnHTML("html", func(page *colly.HTMLElement) {
+		ForEach(page, dataPoint, func(_ int, e *colly.HTMLElement) {
+			fn(e)
+		})
+	})
+}
+
+// ForEach calls fn on the elements under e matched by a data point, like e.ForEach, with the
+// first selector of its chain that matches any element
+func ForEach(e *colly.HTMLElement, dataPoint string, fn func(int, *colly.HTMLElement)) {
+	for _, selector := range SelectorChain(dataPoint) {
+		found := e.DOM.Find(selector)
+		if found.Length() == 0 {
+			continue
+		}
+		found.Each(func(i int, s *goquery.Selection) {
+			fn(i, colly.NewHTMLElementFromSelectionNode(e.Response, s, s.Get(0), i))
+		})
+		return
+	}
+}
+
+// ChildText returns the text of the elements under e matched by a data point, like e.ChildText,
+// with the first selector of its chain that finds some text
+func ChildText(e *colly.HTMLElement, dataPoint string) string {
+	for _, selector := range SelectorChain(dataPoint) {
+		if text := strings.TrimSpace(e.DOM.Find(selector).Text()); text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -15,6 +18,9 @@ type OperationTimer struct {
 	TotalTime     time.Duration
 	Log           *logrus.Logger
 	OperationType string // "repo" or "release"
+
+	// Recorder, when set, aggregates this timer's phase durations with the other items of a run
+	Recorder *PhaseRecorder
 }
 
 // NewOperationTimer creates a new timer with the given name
@@ -37,6 +43,7 @@ func (t *OperationTimer) StartScraping() time.Time {
 func (t *OperationTimer) EndScraping() time.Duration {
 	t.ScrapeTime = time.Since(t.StartTime)
 	t.logTiming("Scraping", t.ScrapeTime)
+	t.Recorder.Record(PhaseScrape, t.ScrapeTime)
 	return t.ScrapeTime
 }
 
@@ -49,6 +56,7 @@ func (t *OperationTimer) StartDatabase() time.Time {
 func (t *OperationTimer) EndDatabase(startTime time.Time) time.Duration {
 	t.DatabaseTime = time.Since(startTime)
 	t.logTiming("Database", t.DatabaseTime)
+	t.Recorder.Record(PhaseDatabase, t.DatabaseTime)
 	return t.DatabaseTime
 }
 
@@ -56,6 +64,7 @@ func (t *OperationTimer) EndDatabase(startTime time.Time) time.Duration {
 func (t *OperationTimer) End() {
 	t.TotalTime = time.Since(t.StartTime)
 	t.logTiming("Total", t.TotalTime)
+	t.Recorder.Record(PhaseTotal, t.TotalTime)
 
 	// Log overall summary
 	t.Log.WithFields(logrus.Fields{
@@ -77,3 +86,110 @@ func (t *OperationTimer) logTiming(phase string, duration time.Duration) {
 		"type":      t.OperationType,
 	}).Info("Timing information")
 }
+
+// Phases recorded by OperationTimer
+const (
+	PhaseScrape   = "scrape"
+	PhaseDatabase = "db"
+	PhaseTotal    = "total"
+)
+
+// PhaseStats summarizes the durations recorded for one phase
+type PhaseStats struct {
+	Count int
+	Total time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// PhaseRecorder aggregates per-phase durations across the items of a run, so the run summary
+// can report percentiles instead of only per-item durations. It is safe for concurrent use,
+// and a nil recorder ignores everything.
+type PhaseRecorder struct {
+	mu        sync.Mutex
+	durations map[string][]time.Duration
+	phases    []string
+}
+
+// NewPhaseRecorder creates an empty recorder
+func NewPhaseRecorder() *PhaseRecorder {
+	return &PhaseRecorder{
+		durations: make(map[string][]time.Duration),
+	}
+}
+
+// Record adds one item's duration for a phase
+func (r *PhaseRecorder) Record(phase string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.durations[phase]; !ok {
+		r.phases = append(r.phases, phase)
+	}
+	r.durations[phase] = append(r.durations[phase], duration)
+}
+
+// Stats returns the count, total, percentiles and maximum of a phase
+func (r *PhaseRecorder) Stats(phase string) PhaseStats {
+	if r == nil {
+		return PhaseStats{}
+	}
+
+	r.mu.Lock()
+	sorted := append([]time.Duration(nil), r.durations[phase]...)
+	r.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return PhaseStats{}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats := PhaseStats{
+		Count: len(sorted),
+		P50:   percentile(sorted, 50),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+	for _, d := range sorted {
+		stats.Total += d
+	}
+	return stats
+}
+
+// Fields returns "<phase>_p50_ms", "<phase>_p95_ms", "<phase>_p99_ms" and "<phase>_max_ms"
+// for every recorded phase, to be added to a run summary log entry
+func (r *PhaseRecorder) Fields() logrus.Fields {
+	fields := logrus.Fields{}
+	if r == nil {
+		return fields
+	}
+
+	r.mu.Lock()
+	phases := append([]string(nil), r.phases...)
+	r.mu.Unlock()
+
+	for _, phase := range phases {
+		stats := r.Stats(phase)
+		fields[fmt.Sprintf("%s_p50_ms", phase)] = stats.P50.Milliseconds()
+		fields[fmt.Sprintf("%s_p95_ms", phase)] = stats.P95.Milliseconds()
+		fields[fmt.Sprintf("%s_p99_ms", phase)] = stats.P99.Milliseconds()
+		fields[fmt.Sprintf("%s_max_ms", phase)] = stats.Max.Milliseconds()
+	}
+	return fields
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
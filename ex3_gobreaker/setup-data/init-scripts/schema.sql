@@ -1,21 +1,249 @@
 CREATE TABLE IF NOT EXISTS repositories (
-	id SERIAL PRIMARY KEY,
+	id BIGSERIAL PRIMARY KEY,
 	userName TEXT NOT NULL,
-	repoName TEXT NOT NULL
+	repoName TEXT NOT NULL,
+	defaultBranch TEXT NOT NULL DEFAULT '',
+	policy TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	stars BIGINT NOT NULL DEFAULT 0,
+	forks BIGINT NOT NULL DEFAULT 0,
+	language TEXT NOT NULL DEFAULT '',
+	topics TEXT NOT NULL DEFAULT '',
+	license TEXT NOT NULL DEFAULT '',
+	enrichedAt TIMESTAMPTZ,
+	status TEXT NOT NULL DEFAULT 'active',
+	statusAt TIMESTAMPTZ,
+	retryAt TIMESTAMPTZ,
+	nextCrawlAt TIMESTAMPTZ,
+	notFoundCount INTEGER NOT NULL DEFAULT 0,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	deletedAt TIMESTAMPTZ
+);
+
+-- The unique indexes fail on databases holding duplicate rows from before them; "crawler migrate"
+-- merges the duplicates into the row with the lowest id, then creates the indexes.
+-- GitHub names are case-insensitive, so are tracked repositories
+CREATE UNIQUE INDEX IF NOT EXISTS repositories_name_key ON repositories (LOWER(userName), LOWER(repoName));
+CREATE INDEX IF NOT EXISTS repositories_deletedat_idx ON repositories (deletedAt);
+
+-- Crawl limits of repositories too large to crawl whole, set through the API
+CREATE TABLE IF NOT EXISTS repo_policies (
+	repoID BIGINT PRIMARY KEY,
+	maxReleases INTEGER NOT NULL DEFAULT 0,
+	maxCommitsPerRelease INTEGER NOT NULL DEFAULT 0,
+	skipCommits BOOLEAN NOT NULL DEFAULT FALSE,
+	requestsPerMinute DOUBLE PRECISION NOT NULL DEFAULT 0,
+	updatedAt TIMESTAMPTZ NOT NULL,
+	FOREIGN KEY (repoID) REFERENCES repositories(id)
 );
 
 CREATE TABLE IF NOT EXISTS releases (
-	id SERIAL PRIMARY KEY,
+	id BIGSERIAL PRIMARY KEY,
 	tagName TEXT NOT NULL,
 	content TEXT NOT NULL,
-	repoID INTEGER NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	publishedAt TIMESTAMPTZ,
+	author TEXT NOT NULL DEFAULT '',
+	prerelease BOOLEAN NOT NULL DEFAULT FALSE,
+	createdAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	repoID BIGINT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'active',
+	statusAt TIMESTAMPTZ,
+	tombstonedAt TIMESTAMPTZ,
+	FOREIGN KEY (repoID) REFERENCES repositories(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS releases_repoid_tagname_key ON releases (repoID, tagName);
+
+CREATE TABLE IF NOT EXISTS release_assets (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	downloadCount BIGINT NOT NULL DEFAULT 0,
+	releaseID BIGINT NOT NULL,
+	FOREIGN KEY (releaseID) REFERENCES releases(id)
+);
+
+CREATE TABLE IF NOT EXISTS release_note_items (
+	id BIGSERIAL PRIMARY KEY,
+	releaseID BIGINT NOT NULL,
+	position INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	section TEXT NOT NULL DEFAULT '',
+	text TEXT NOT NULL,
+	FOREIGN KEY (releaseID) REFERENCES releases(id)
+);
+
+CREATE INDEX IF NOT EXISTS release_note_items_releaseid_idx ON release_note_items (releaseID);
+
+CREATE TABLE IF NOT EXISTS release_note_references (
+	id BIGSERIAL PRIMARY KEY,
+	releaseID BIGINT NOT NULL,
+	repo TEXT NOT NULL DEFAULT '',
+	number BIGINT NOT NULL,
+	type TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY (releaseID) REFERENCES releases(id)
+);
+
+CREATE INDEX IF NOT EXISTS release_note_references_releaseid_idx ON release_note_references (releaseID);
+
+CREATE TABLE IF NOT EXISTS release_note_mentions (
+	id BIGSERIAL PRIMARY KEY,
+	releaseID BIGINT NOT NULL,
+	username TEXT NOT NULL,
+	FOREIGN KEY (releaseID) REFERENCES releases(id)
+);
+
+CREATE INDEX IF NOT EXISTS release_note_mentions_releaseid_idx ON release_note_mentions (releaseID);
+CREATE INDEX IF NOT EXISTS release_note_mentions_username_idx ON release_note_mentions (username);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	commitSHA TEXT NOT NULL,
+	repoID BIGINT NOT NULL,
+	FOREIGN KEY (repoID) REFERENCES repositories(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tags_repoid_name_key ON tags (repoID, name);
+
+CREATE TABLE IF NOT EXISTS visits (
+	id BIGSERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	method TEXT NOT NULL,
+	statusCode INTEGER NOT NULL DEFAULT 0,
+	bytes BIGINT NOT NULL DEFAULT 0,
+	durationMs BIGINT NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	visitedAt TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS visits_visitedat_idx ON visits (visitedAt);
+
+CREATE TABLE IF NOT EXISTS cached_pages (
+	url TEXT PRIMARY KEY,
+	etag TEXT NOT NULL DEFAULT '',
+	lastModified TEXT NOT NULL DEFAULT '',
+	contentType TEXT NOT NULL DEFAULT '',
+	body BYTEA NOT NULL,
+	fetchedAt TIMESTAMPTZ NOT NULL,
+	checkedAt TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS cached_pages_checkedat_idx ON cached_pages (checkedAt);
+
+CREATE TABLE IF NOT EXISTS watchlists (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS watchlist_repos (
+	watchlistID BIGINT NOT NULL,
+	repoID BIGINT NOT NULL,
+	PRIMARY KEY (watchlistID, repoID),
+	FOREIGN KEY (watchlistID) REFERENCES watchlists(id),
 	FOREIGN KEY (repoID) REFERENCES repositories(id)
 );
 
+CREATE TABLE IF NOT EXISTS watchlist_keywords (
+	id BIGSERIAL PRIMARY KEY,
+	watchlistID BIGINT NOT NULL,
+	keyword TEXT NOT NULL,
+	createdAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (watchlistID) REFERENCES watchlists(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS watchlist_keywords_key ON watchlist_keywords (watchlistID, keyword);
+
 CREATE TABLE IF NOT EXISTS commits (
-	id SERIAL PRIMARY KEY,
+	id BIGSERIAL PRIMARY KEY,
 	hash TEXT NOT NULL,
 	message TEXT NOT NULL,
-	releaseID INTEGER NOT NULL,
+	filesChanged INTEGER,
+	additions INTEGER,
+	deletions INTEGER,
+	releaseID BIGINT NOT NULL,
 	FOREIGN KEY (releaseID) REFERENCES releases(id)
-);
\ No newline at end of file
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS commits_releaseid_hash_key ON commits (releaseID, hash);
+
+CREATE TABLE IF NOT EXISTS crawl_jobs (
+	id BIGSERIAL PRIMARY KEY,
+	kind TEXT NOT NULL,
+	payload TEXT NOT NULL DEFAULT '{}',
+	status TEXT NOT NULL,
+	progress TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	workerID TEXT NOT NULL DEFAULT '',
+	createdAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	startedAt TIMESTAMPTZ,
+	finishedAt TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS crawl_jobs_status_idx ON crawl_jobs (status, id);
+
+CREATE TABLE IF NOT EXISTS crawl_runs (
+	id BIGSERIAL PRIMARY KEY,
+	source TEXT NOT NULL,
+	endpoint TEXT NOT NULL,
+	apiKey TEXT NOT NULL DEFAULT '',
+	startedAt TIMESTAMPTZ NOT NULL,
+	durationMs BIGINT NOT NULL DEFAULT 0,
+	statusCode INTEGER NOT NULL DEFAULT 0,
+	found INTEGER NOT NULL DEFAULT 0,
+	saved INTEGER NOT NULL DEFAULT 0,
+	errored INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS crawl_runs_startedat_idx ON crawl_runs (startedAt);
+
+CREATE TABLE IF NOT EXISTS crawl_errors (
+	id BIGSERIAL PRIMARY KEY,
+	entityType TEXT NOT NULL,
+	entityID BIGINT NOT NULL,
+	message TEXT NOT NULL DEFAULT '',
+	httpStatus INTEGER NOT NULL DEFAULT 0,
+	retryCount INTEGER NOT NULL DEFAULT 0,
+	firstFailedAt TIMESTAMPTZ NOT NULL,
+	lastFailedAt TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS crawl_errors_entity_key ON crawl_errors (entityType, entityID);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id BIGSERIAL PRIMARY KEY,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL DEFAULT '{}',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	lastError TEXT NOT NULL DEFAULT '',
+	createdAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	nextAttemptAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	lockedUntil TIMESTAMPTZ,
+	deliveredAt TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_due_idx ON outbox_events (nextAttemptAt) WHERE deliveredAt IS NULL;
+
+CREATE TABLE IF NOT EXISTS crawl_schedules (
+	name TEXT PRIMARY KEY,
+	expression TEXT NOT NULL,
+	nextRunAt TIMESTAMPTZ NOT NULL,
+	lastRunAt TIMESTAMPTZ,
+	lastStatus TEXT NOT NULL DEFAULT '',
+	lastError TEXT NOT NULL DEFAULT '',
+	updatedAt TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS crawl_workers (
+	id TEXT PRIMARY KEY,
+	host TEXT NOT NULL DEFAULT '',
+	workers INTEGER NOT NULL DEFAULT 0,
+	running BIGINT NOT NULL DEFAULT 0,
+	processed BIGINT NOT NULL DEFAULT 0,
+	failed BIGINT NOT NULL DEFAULT 0,
+	startedAt TIMESTAMPTZ NOT NULL,
+	heartbeatAt TIMESTAMPTZ NOT NULL
+);